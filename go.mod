@@ -0,0 +1,15 @@
+module github.com/giogam/Gopher-Wallet
+
+go 1.25.0
+
+require (
+	github.com/stretchr/testify v1.9.0
+	golang.org/x/crypto v0.54.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/sys v0.47.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
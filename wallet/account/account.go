@@ -0,0 +1,299 @@
+package account
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/giogam/Gopher-Wallet/wallet/address"
+	"github.com/giogam/Gopher-Wallet/wallet/coinselect"
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/script"
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+// chains of an account
+const (
+	// External is the chain of receive addresses
+	External uint32 = 0
+	// Internal is the chain of change addresses
+	Internal uint32 = 1
+)
+
+var (
+	// ErrUnsupportedType is returned for address types an account cannot
+	// derive
+	ErrUnsupportedType = errors.New("account: unsupported address type")
+	// ErrInvalidChain is returned for chains other than external and
+	// internal
+	ErrInvalidChain = errors.New("account: invalid chain")
+	// ErrUnknownUtxo is returned when an output is not owned by the account
+	ErrUnknownUtxo = errors.New("account: unknown utxo")
+)
+
+// purposes maps the address types to their BIP44 style purpose
+var purposes = map[address.Type]uint32{
+	address.P2PKH:  44,
+	address.P2SH:   49,
+	address.P2WPKH: 84,
+	address.P2TR:   86,
+}
+
+// inputSizes are the spend sizes in vbytes of the outputs of each type
+var inputSizes = map[address.Type]int{
+	address.P2PKH:  coinselect.P2PKHInputSize,
+	address.P2SH:   coinselect.P2SHP2WPKHInputSize,
+	address.P2WPKH: coinselect.P2WPKHInputSize,
+	address.P2TR:   coinselect.P2TRInputSize,
+}
+
+// Account is a BIP44, BIP49, BIP84 or BIP86 account, P2SH accounts pay to
+// nested P2WPKH. Key is the account level key, Fingerprint and Path locate
+// it from the master key. Next holds the first unused index of each chain.
+type Account struct {
+	Type        address.Type
+	Net         *address.Network
+	Key         *hdwallet.ExtendedKey
+	Fingerprint []byte
+	Path        []uint32
+	Next        [2]uint32
+	Utxos       []*Utxo
+
+	// scripts maps the output scripts of the used keys to their chain
+	// and index, derived holds the number of keys of each chain in it
+	scripts map[string]keyIndex
+	derived [2]uint32
+}
+
+// keyIndex locates a key of the account
+type keyIndex struct {
+	chain uint32
+	index uint32
+}
+
+// Utxo is an unspent output paying to the key at Chain and Index, PrevTx
+// is the funding transaction required to spend non segwit outputs. Height
+// is the height of the block confirming it, 0 while unconfirmed.
+type Utxo struct {
+	OutPoint transaction.OutPoint
+	Value    int64
+	PkScript []byte
+	Chain    uint32
+	Index    uint32
+	Height   int32
+	PrevTx   *transaction.Tx
+}
+
+// Confirmed reports whether the output is in a block
+func (u *Utxo) Confirmed() bool {
+	return u.Height > 0
+}
+
+// New derives account index of type t from master following the path
+// m/purpose'/coin'/index', the coin type is 0 on mainnet and 1 otherwise
+func New(master *hdwallet.ExtendedKey, t address.Type, index uint32, net *address.Network) (*Account, error) {
+
+	purpose, ok := purposes[t]
+	if !ok {
+		return nil, ErrUnsupportedType
+	}
+
+	coinType := uint32(1)
+	if net == address.MainNet {
+		coinType = 0
+	}
+
+	path := []uint32{
+		purpose + hdwallet.HardenedKeyStart,
+		coinType + hdwallet.HardenedKeyStart,
+		index + hdwallet.HardenedKeyStart,
+	}
+
+	key, err := master.DerivePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Account{Type: t, Net: net, Key: key, Fingerprint: master.Fingerprint(), Path: path}, nil
+}
+
+// NewWatchOnly returns an account for an extended public key found at
+// path from the master key with the given fingerprint
+func NewWatchOnly(key *hdwallet.ExtendedKey, fingerprint []byte, path []uint32, t address.Type, net *address.Network) (*Account, error) {
+
+	if _, ok := purposes[t]; !ok {
+		return nil, ErrUnsupportedType
+	}
+
+	pub, err := key.Neuter()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Account{Type: t, Net: net, Key: pub, Fingerprint: fingerprint, Path: path}, nil
+}
+
+// KeyPath returns the path from the master key of the key at chain and
+// index
+func (a *Account) KeyPath(chain, index uint32) []uint32 {
+	return append(append([]uint32{}, a.Path...), chain, index)
+}
+
+// PubKey returns the public key at chain and index
+func (a *Account) PubKey(chain, index uint32) (*secp256k1.PublicKey, error) {
+
+	if chain != External && chain != Internal {
+		return nil, ErrInvalidChain
+	}
+
+	key, err := a.Key.DerivePath([]uint32{chain, index})
+	if err != nil {
+		return nil, err
+	}
+
+	return key.PubKey()
+}
+
+// Address returns the address at chain and index
+func (a *Account) Address(chain, index uint32) (*address.Address, error) {
+
+	pub, err := a.PubKey(chain, index)
+	if err != nil {
+		return nil, err
+	}
+
+	switch a.Type {
+	case address.P2PKH:
+		return address.NewP2PKHFromPubKey(pub.SerializeCompressed(), a.Net)
+	case address.P2SH:
+		return address.NewP2SHP2WPKHFromPubKey(pub.SerializeCompressed(), a.Net)
+	case address.P2WPKH:
+		return address.NewP2WPKHFromPubKey(pub.SerializeCompressed(), a.Net)
+	case address.P2TR:
+		return address.NewP2TRFromInternalKey(pub, nil, a.Net)
+	}
+
+	return nil, ErrUnsupportedType
+}
+
+// PkScript returns the output script at chain and index
+func (a *Account) PkScript(chain, index uint32) ([]byte, error) {
+
+	addr, err := a.Address(chain, index)
+	if err != nil {
+		return nil, err
+	}
+
+	return script.PayToAddress(addr)
+}
+
+// NextAddress returns the first unused address of chain and marks it used
+func (a *Account) NextAddress(chain uint32) (*address.Address, error) {
+
+	if chain != External && chain != Internal {
+		return nil, ErrInvalidChain
+	}
+
+	addr, err := a.Address(chain, a.Next[chain])
+	if err != nil {
+		return nil, err
+	}
+
+	a.Next[chain]++
+
+	return addr, nil
+}
+
+// MarkUsed records that the key at chain and index has been used
+func (a *Account) MarkUsed(chain, index uint32) {
+
+	if chain <= Internal && index >= a.Next[chain] {
+		a.Next[chain] = index + 1
+	}
+}
+
+// KeyIndex returns the chain and index of the used key paying to pkScript,
+// the scripts of keys marked used since the last lookup are derived once
+func (a *Account) KeyIndex(pkScript []byte) (uint32, uint32, bool) {
+
+	if a.scripts == nil {
+		a.scripts = make(map[string]keyIndex)
+	}
+
+	for _, chain := range []uint32{Internal, External} {
+		for ; a.derived[chain] < a.Next[chain]; a.derived[chain]++ {
+			own, err := a.PkScript(chain, a.derived[chain])
+			if err != nil {
+				break
+			}
+			a.scripts[string(own)] = keyIndex{chain, a.derived[chain]}
+		}
+	}
+
+	k, ok := a.scripts[string(pkScript)]
+
+	return k.chain, k.index, ok
+}
+
+// AddUtxo records an output paying to the key at chain and index
+func (a *Account) AddUtxo(u *Utxo) error {
+
+	pkScript, err := a.PkScript(u.Chain, u.Index)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(pkScript, u.PkScript) {
+		return ErrUnknownUtxo
+	}
+
+	a.Utxos = append(a.Utxos, u)
+	a.MarkUsed(u.Chain, u.Index)
+
+	return nil
+}
+
+// Utxo returns the output spent by op
+func (a *Account) Utxo(op transaction.OutPoint) (*Utxo, error) {
+
+	for _, u := range a.Utxos {
+		if u.OutPoint == op {
+			return u, nil
+		}
+	}
+
+	return nil, ErrUnknownUtxo
+}
+
+// Balance returns the value of the unspent outputs
+func (a *Account) Balance() int64 {
+
+	var balance int64
+	for _, u := range a.Utxos {
+		balance += u.Value
+	}
+
+	return balance
+}
+
+// InputSize returns the spend size in vbytes of the outputs of the account
+func (a *Account) InputSize() int {
+	return inputSizes[a.Type]
+}
+
+// Coin returns u as a coin selection candidate, P2SH outputs are sized as
+// the nested P2WPKH they commit to
+func (a *Account) Coin(u *Utxo) *coinselect.Coin {
+	return coinselect.NewCoinWithSize(u.OutPoint, u.Value, u.PkScript, a.InputSize())
+}
+
+// Coins returns the unspent outputs as coin selection candidates
+func (a *Account) Coins() ([]*coinselect.Coin, error) {
+
+	coins := make([]*coinselect.Coin, len(a.Utxos))
+	for i, u := range a.Utxos {
+		coins[i] = a.Coin(u)
+	}
+
+	return coins, nil
+}
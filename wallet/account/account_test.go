@@ -0,0 +1,142 @@
+package account
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/giogam/Gopher-Wallet/wallet/address"
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/mnemonic"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+func testMaster(t *testing.T) *hdwallet.ExtendedKey {
+
+	seed := mnemonic.NewSeed(strings.Split(testMnemonic, " "), "")
+	master, err := hdwallet.NewMasterKey(seed, hdwallet.MainnetPrivate)
+	assert.NoError(t, err)
+
+	return master
+}
+
+func TestAddress(t *testing.T) {
+	master := testMaster(t)
+
+	tests := []struct {
+		t       address.Type
+		chain   uint32
+		index   uint32
+		address string
+	}{
+		{address.P2PKH, External, 0, "1LqBGSKuX5yYUonjxT5qGfpUsXKYYWeabA"},
+		{address.P2SH, External, 0, "37VucYSaXLCAsxYyAPfbSi9eh4iEcbShgf"},
+		{address.P2WPKH, External, 0, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"},
+		{address.P2WPKH, External, 1, "bc1qnjg0jd8228aq7egyzacy8cys3knf9xvrerkf9g"},
+		{address.P2WPKH, Internal, 0, "bc1q8c6fshw2dlwun7ekn9qwf37cu2rn755upcp6el"},
+		{address.P2TR, External, 0, "bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr"},
+		{address.P2TR, External, 1, "bc1p4qhjn9zdvkux4e44uhx8tc55attvtyu358kutcqkudyccelu0was9fqzwh"},
+		{address.P2TR, Internal, 0, "bc1p3qkhfews2uk44qtvauqyr2ttdsw7svhkl9nkm9s9c3x4ax5h60wqwruhk7"},
+	}
+
+	for _, test := range tests {
+		a, err := New(master, test.t, 0, address.MainNet)
+		assert.NoError(t, err)
+
+		addr, err := a.Address(test.chain, test.index)
+		assert.NoError(t, err)
+		assert.Equal(t, test.address, addr.String())
+
+		// a watch only account derives the same addresses
+		w, err := NewWatchOnly(a.Key, a.Fingerprint, a.Path, test.t, address.MainNet)
+		assert.NoError(t, err)
+		assert.False(t, w.Key.IsPrivate)
+		addr, err = w.Address(test.chain, test.index)
+		assert.NoError(t, err)
+		assert.Equal(t, test.address, addr.String())
+	}
+
+	_, err := New(master, address.P2WSH, 0, address.MainNet)
+	assert.Equal(t, ErrUnsupportedType, err)
+
+	a, _ := New(master, address.P2WPKH, 0, address.TestNet)
+	assert.Equal(t, []uint32{84 + hdwallet.HardenedKeyStart, 1 + hdwallet.HardenedKeyStart, hdwallet.HardenedKeyStart}, a.Path)
+	assert.Equal(t, append(a.Path, 1, 5), a.KeyPath(Internal, 5))
+
+	_, err = a.Address(2, 0)
+	assert.Equal(t, ErrInvalidChain, err)
+}
+
+func TestNextAddress(t *testing.T) {
+	a, _ := New(testMaster(t), address.P2WPKH, 0, address.MainNet)
+
+	addr, err := a.NextAddress(External)
+	assert.NoError(t, err)
+	assert.Equal(t, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", addr.String())
+
+	addr, _ = a.NextAddress(External)
+	assert.Equal(t, "bc1qnjg0jd8228aq7egyzacy8cys3knf9xvrerkf9g", addr.String())
+	assert.Equal(t, [2]uint32{2, 0}, a.Next)
+
+	a.MarkUsed(Internal, 4)
+	a.MarkUsed(External, 0)
+	assert.Equal(t, [2]uint32{2, 5}, a.Next)
+
+	pkScript, _ := a.PkScript(Internal, 3)
+	chain, index, ok := a.KeyIndex(pkScript)
+	assert.True(t, ok)
+	assert.Equal(t, Internal, chain)
+	assert.Equal(t, uint32(3), index)
+
+	// unused keys are not looked up
+	pkScript, _ = a.PkScript(External, 2)
+	_, _, ok = a.KeyIndex(pkScript)
+	assert.False(t, ok)
+
+	// until they are marked used
+	a.MarkUsed(External, 2)
+	chain, index, ok = a.KeyIndex(pkScript)
+	assert.True(t, ok)
+	assert.Equal(t, External, chain)
+	assert.Equal(t, uint32(2), index)
+}
+
+func TestUtxos(t *testing.T) {
+	a, _ := New(testMaster(t), address.P2WPKH, 0, address.MainNet)
+
+	pkScript, _ := a.PkScript(External, 3)
+	hash := transaction.DoubleHashH([]byte("funding"))
+	u := &Utxo{OutPoint: *transaction.NewOutPoint(&hash, 1), Value: 50000, PkScript: pkScript, Chain: External, Index: 3}
+
+	assert.NoError(t, a.AddUtxo(u))
+	assert.Equal(t, uint32(4), a.Next[External])
+	assert.Equal(t, int64(50000), a.Balance())
+
+	found, err := a.Utxo(u.OutPoint)
+	assert.NoError(t, err)
+	assert.Equal(t, u, found)
+
+	_, err = a.Utxo(transaction.OutPoint{})
+	assert.Equal(t, ErrUnknownUtxo, err)
+
+	// the script must pay to the recorded key
+	assert.Equal(t, ErrUnknownUtxo, a.AddUtxo(&Utxo{PkScript: pkScript, Chain: External, Index: 4}))
+
+	coins, err := a.Coins()
+	assert.NoError(t, err)
+	assert.Len(t, coins, 1)
+	assert.Equal(t, 68, coins[0].InputSize)
+
+	// P2SH accounts pay to nested P2WPKH
+	nested, _ := New(testMaster(t), address.P2SH, 0, address.MainNet)
+	pkScript, _ = nested.PkScript(External, 0)
+	u = &Utxo{OutPoint: *transaction.NewOutPoint(&hash, 2), Value: 50000, PkScript: pkScript}
+	assert.NoError(t, nested.AddUtxo(u))
+
+	coins, err = nested.Coins()
+	assert.NoError(t, err)
+	assert.Len(t, coins, 1)
+	assert.Equal(t, 91, coins[0].InputSize)
+}
@@ -0,0 +1,258 @@
+package address
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"strings"
+
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+)
+
+// Network holds the address prefixes of a Bitcoin network
+type Network struct {
+	Name         string
+	PubKeyHashID byte
+	ScriptHashID byte
+	Bech32HRP    string
+}
+
+var (
+	// MainNet is the Bitcoin main network
+	MainNet = &Network{Name: "mainnet", PubKeyHashID: 0x00, ScriptHashID: 0x05, Bech32HRP: "bc"}
+	// TestNet is the Bitcoin test network, signet shares its prefixes
+	TestNet = &Network{Name: "testnet", PubKeyHashID: 0x6f, ScriptHashID: 0xc4, Bech32HRP: "tb"}
+	// RegTest is the Bitcoin regression test network
+	RegTest = &Network{Name: "regtest", PubKeyHashID: 0x6f, ScriptHashID: 0xc4, Bech32HRP: "bcrt"}
+)
+
+// Type is the kind of output an address pays to
+type Type int
+
+const (
+	// P2PKH pays to the hash of a public key
+	P2PKH Type = iota + 1
+	// P2SH pays to the hash of a redeem script
+	P2SH
+	// P2WPKH pays to a version 0 witness public key hash
+	P2WPKH
+	// P2WSH pays to a version 0 witness script hash
+	P2WSH
+	// P2TR pays to a version 1 taproot output key
+	P2TR
+	// WitnessUnknown pays to a witness program of a future version
+	WitnessUnknown
+)
+
+var typeNames = map[Type]string{
+	P2PKH:          "p2pkh",
+	P2SH:           "p2sh",
+	P2WPKH:         "p2wpkh",
+	P2WSH:          "p2wsh",
+	P2TR:           "p2tr",
+	WitnessUnknown: "witness_unknown",
+}
+
+// String returns the name of the address type
+func (t Type) String() string {
+
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+
+	return "unknown"
+}
+
+var (
+	// ErrInvalidAddress is returned when an address cannot be decoded
+	ErrInvalidAddress = errors.New("address: invalid address")
+	// ErrWrongNetwork is returned when an address belongs to another network
+	ErrWrongNetwork = errors.New("address: address is for another network")
+	// ErrInvalidHashLength is returned when a hash has the wrong size
+	ErrInvalidHashLength = errors.New("address: invalid hash length")
+	// ErrUncompressedKey is returned when a segwit address is requested for
+	// an uncompressed public key
+	ErrUncompressedKey = errors.New("address: segwit requires a compressed public key")
+)
+
+// Address is a decoded Bitcoin address, Program holds the public key hash,
+// the script hash or the witness program depending on the type
+type Address struct {
+	Type    Type
+	Version byte
+	Program []byte
+	Net     *Network
+}
+
+// NewP2PKH returns the P2PKH address of a 20 bytes public key hash
+func NewP2PKH(hash []byte, net *Network) (*Address, error) {
+
+	if len(hash) != 20 {
+		return nil, ErrInvalidHashLength
+	}
+
+	return &Address{Type: P2PKH, Program: hash, Net: net}, nil
+}
+
+// NewP2SH returns the P2SH address of a 20 bytes script hash
+func NewP2SH(hash []byte, net *Network) (*Address, error) {
+
+	if len(hash) != 20 {
+		return nil, ErrInvalidHashLength
+	}
+
+	return &Address{Type: P2SH, Program: hash, Net: net}, nil
+}
+
+// NewWitness returns the segwit address of a witness program, the type is
+// inferred from the version and the program length
+func NewWitness(version byte, program []byte, net *Network) (*Address, error) {
+
+	if err := checkWitnessProgram(version, program); err != nil {
+		return nil, err
+	}
+
+	t := WitnessUnknown
+
+	switch {
+	case version == 0 && len(program) == 20:
+		t = P2WPKH
+	case version == 0 && len(program) == 32:
+		t = P2WSH
+	case version == 1 && len(program) == 32:
+		t = P2TR
+	}
+
+	return &Address{Type: t, Version: version, Program: program, Net: net}, nil
+}
+
+// NewP2PKHFromPubKey returns the P2PKH address of a serialized public key,
+// compressed and uncompressed keys yield different addresses
+func NewP2PKHFromPubKey(pub []byte, net *Network) (*Address, error) {
+
+	if _, err := secp256k1.ParsePubKey(pub); err != nil {
+		return nil, err
+	}
+
+	return NewP2PKH(hdwallet.Hash160(pub), net)
+}
+
+// NewP2WPKHFromPubKey returns the native segwit address of a compressed
+// public key
+func NewP2WPKHFromPubKey(pub []byte, net *Network) (*Address, error) {
+
+	if err := checkCompressed(pub); err != nil {
+		return nil, err
+	}
+
+	return NewWitness(0, hdwallet.Hash160(pub), net)
+}
+
+// NewP2SHP2WPKHFromPubKey returns the nested segwit address of a compressed
+// public key, a P2SH address wrapping a P2WPKH redeem script
+func NewP2SHP2WPKHFromPubKey(pub []byte, net *Network) (*Address, error) {
+
+	if err := checkCompressed(pub); err != nil {
+		return nil, err
+	}
+
+	redeem := append([]byte{0x00, 0x14}, hdwallet.Hash160(pub)...)
+
+	return NewP2SH(hdwallet.Hash160(redeem), net)
+}
+
+// NewP2SHFromScript returns the P2SH address of a redeem script
+func NewP2SHFromScript(script []byte, net *Network) (*Address, error) {
+	return NewP2SH(hdwallet.Hash160(script), net)
+}
+
+// NewP2WSHFromScript returns the P2WSH address of a witness script
+func NewP2WSHFromScript(script []byte, net *Network) (*Address, error) {
+
+	hash := sha256.Sum256(script)
+
+	return NewWitness(0, hash[:], net)
+}
+
+// NewP2TRFromInternalKey returns the taproot address committing to the
+// internal key and the optional script tree merkle root, a nil merkleRoot
+// gives the BIP86 key path only address
+func NewP2TRFromInternalKey(internal *secp256k1.PublicKey, merkleRoot []byte, net *Network) (*Address, error) {
+
+	output, err := hdwallet.TaprootTweakPubKey(internal, merkleRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWitness(1, output.SerializeXOnly(), net)
+}
+
+// Decode parses a Base58Check or bech32 address of the given network
+func Decode(addr string, net *Network) (*Address, error) {
+
+	if strings.HasPrefix(strings.ToLower(addr), net.Bech32HRP+"1") {
+		version, program, err := SegwitDecode(net.Bech32HRP, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewWitness(version, program, net)
+	}
+
+	version, payload, err := hdwallet.B58CheckDecodeStrict(addr)
+	if err != nil {
+		return nil, ErrInvalidAddress
+	}
+
+	if len(payload) != 20 {
+		return nil, ErrInvalidHashLength
+	}
+
+	switch byte(version) {
+	case net.PubKeyHashID:
+		return NewP2PKH(payload, net)
+	case net.ScriptHashID:
+		return NewP2SH(payload, net)
+	}
+
+	return nil, ErrWrongNetwork
+}
+
+// String returns the encoded address
+func (a *Address) String() string {
+
+	switch a.Type {
+	case P2PKH:
+		str, _ := hdwallet.B58CheckEncode(int(a.Net.PubKeyHashID), a.Program)
+		return str
+	case P2SH:
+		str, _ := hdwallet.B58CheckEncode(int(a.Net.ScriptHashID), a.Program)
+		return str
+	}
+
+	str, _ := SegwitEncode(a.Net.Bech32HRP, a.Version, a.Program)
+
+	return str
+}
+
+// IsSegwit reports whether the address pays to a witness program
+func (a *Address) IsSegwit() bool {
+	return a.Type >= P2WPKH
+}
+
+// IsEqual reports whether two addresses pay to the same output
+func (a *Address) IsEqual(b *Address) bool {
+	return a.Type == b.Type && a.Version == b.Version && bytes.Equal(a.Program, b.Program)
+}
+
+func checkCompressed(pub []byte) error {
+
+	if len(pub) != secp256k1.PubKeyCompressedLen {
+		return ErrUncompressedKey
+	}
+
+	_, err := secp256k1.ParsePubKey(pub)
+
+	return err
+}
@@ -0,0 +1,106 @@
+package address
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+	"github.com/stretchr/testify/assert"
+)
+
+type addresstest struct {
+	address string
+	net     *Network
+	kind    Type
+	program string
+}
+
+func addressTestVector() []addresstest {
+	return []addresstest{
+		{"1MirQ9bwyQcGVJPwKUgapu5ouK2E2Ey4gX", MainNet, P2PKH, "e34cce70c86373273efcc54ce7d2a491bb4a0e84"},
+		{"12MzCDwodF9G1e7jfwLXfR164RNtx4BRVG", MainNet, P2PKH, "0ef030107fd26e0b6bf40512bca2ceb1dd80adaa"},
+		{"mrX9vMRYLfVy1BnZbc5gZjuyaqH3ZW2ZHz", TestNet, P2PKH, "78b316a08647d5b77283e512d3603f1f1c8de68f"},
+		{"3QJmV3qfvL9SuYo34YihAf3sRCW3qSinyC", MainNet, P2SH, "f815b036d9bbbce5e9f2a00abd1bf3dc91e95510"},
+		{"2NBFNJTktNa7GZusGbDbGKRZTxdK9VVez3n", TestNet, P2SH, "c579342c2c4c9220205e2cdc285617040c924a0a"},
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", MainNet, P2WPKH, "751e76e8199196d454941c45d1b3a323f1433bd6"},
+		{"bc1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3qccfmv3", MainNet, P2WSH, "1863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262"},
+		{"tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx", TestNet, P2WPKH, "751e76e8199196d454941c45d1b3a323f1433bd6"},
+		{"bc1paardr2nczq0rx5rqpfwnvpzm497zvux64y0f7wjgcs7xuuuh2nnqwr2d5c", MainNet, P2TR, "ef46d1aa78101e3350600a5d36045ba97c2670daa91e9f3a48c43c6e739754e6"},
+		{"bcrt1qw508d6qejxtdg4y5r3zarvary0c5xw7kygt080", RegTest, P2WPKH, "751e76e8199196d454941c45d1b3a323f1433bd6"},
+		{"bc1zw508d6qejxtdg4y5r3zarvaryvaxxpcs", MainNet, WitnessUnknown, "751e76e8199196d454941c45d1b3a323"},
+	}
+}
+
+func TestDecode(t *testing.T) {
+	for _, test := range addressTestVector() {
+		addr, err := Decode(test.address, test.net)
+		assert.NoError(t, err, test.address)
+		assert.Equal(t, test.kind, addr.Type, test.address)
+		assert.Equal(t, test.program, hex.EncodeToString(addr.Program), test.address)
+		assert.Equal(t, test.address, addr.String())
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	_, err := Decode("1MirQ9bwyQcGVJPwKUgapu5ouK2E2Ey4gX", TestNet)
+	assert.Equal(t, ErrWrongNetwork, err)
+
+	_, err = Decode("1MirQ9bwyQcGVJPwKUgapu5ouK2E2Ey4gY", MainNet)
+	assert.Equal(t, ErrInvalidAddress, err)
+
+	_, err = Decode("tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx", MainNet)
+	assert.Error(t, err)
+
+	_, err = Decode("bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5", MainNet)
+	assert.Equal(t, ErrInvalidBech32Checksum, err)
+}
+
+type pubkeytest struct {
+	pubKey  string
+	kind    Type
+	net     *Network
+	address string
+}
+
+func pubKeyTestVector() []pubkeytest {
+	return []pubkeytest{
+		// secret key 1, compressed and uncompressed
+		{"0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798", P2PKH, MainNet, "1BgGZ9tcN4rm9KBzDn7KprQz87SZ26SAMH"},
+		{"0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8", P2PKH, MainNet, "1EHNa6Q4Jz2uvNExL497mE43ikXhwF6kZm"},
+		// BIP84 m/84'/0'/0'/0/0
+		{"0330d54fd0dd420a6e5f8d3624f5f3482cae350f79d5f0753bf5beef9c2d91af3c", P2WPKH, MainNet, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"},
+		// BIP49 m/49'/1'/0'/0/0
+		{"03a1af804ac108a8a51782198c2d034b28bf90c8803f5a53f76276fa69a4eae77f", P2SH, TestNet, "2Mww8dCYPUpKHofjgcXcBCEGmniw9CoaiD2"},
+		// BIP86 m/86'/0'/0'/0/0
+		{"03cc8a4bc64d897bddc5fbc2f670f7a8ba0b386779106cf1223c6fc5d7cd6fc115", P2TR, MainNet, "bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr"},
+	}
+}
+
+func TestFromPubKey(t *testing.T) {
+	for _, test := range pubKeyTestVector() {
+		pub, _ := hex.DecodeString(test.pubKey)
+
+		var addr *Address
+		var err error
+
+		switch test.kind {
+		case P2PKH:
+			addr, err = NewP2PKHFromPubKey(pub, test.net)
+		case P2WPKH:
+			addr, err = NewP2WPKHFromPubKey(pub, test.net)
+		case P2SH:
+			addr, err = NewP2SHP2WPKHFromPubKey(pub, test.net)
+		case P2TR:
+			key, perr := secp256k1.ParsePubKey(pub)
+			assert.NoError(t, perr)
+			addr, err = NewP2TRFromInternalKey(key, nil, test.net)
+		}
+
+		assert.NoError(t, err, test.address)
+		assert.Equal(t, test.address, addr.String())
+	}
+
+	uncompressed, _ := hex.DecodeString(pubKeyTestVector()[1].pubKey)
+	_, err := NewP2WPKHFromPubKey(uncompressed, MainNet)
+	assert.Equal(t, ErrUncompressedKey, err)
+}
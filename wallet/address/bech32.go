@@ -0,0 +1,285 @@
+package address
+
+import (
+	"errors"
+	"strings"
+)
+
+// Encoding is the checksum variant of a bech32 string
+type Encoding int
+
+const (
+	// Bech32 is the BIP173 checksum used by witness version 0
+	Bech32 Encoding = iota + 1
+	// Bech32m is the BIP350 checksum used by witness versions 1 to 16
+	Bech32m
+)
+
+const (
+	bech32Charset  = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	bech32Const    = 1
+	bech32mConst   = 0x2bc830a3
+	bech32MaxLen   = 90
+	checksumLen    = 6
+	minProgramLen  = 2
+	maxProgramLen  = 40
+	maxWitnessVers = 16
+)
+
+var (
+	// ErrInvalidBech32 is returned when a string is not valid bech32
+	ErrInvalidBech32 = errors.New("address: invalid bech32 string")
+	// ErrMixedCase is returned when a bech32 string mixes upper and lower case
+	ErrMixedCase = errors.New("address: mixed case bech32 string")
+	// ErrInvalidBech32Checksum is returned when the checksum does not match
+	ErrInvalidBech32Checksum = errors.New("address: invalid bech32 checksum")
+	// ErrInvalidPadding is returned when a 5 to 8 bits conversion has
+	// non zero or excessive padding
+	ErrInvalidPadding = errors.New("address: invalid padding")
+	// ErrInvalidWitnessVersion is returned for witness versions above 16
+	ErrInvalidWitnessVersion = errors.New("address: invalid witness version")
+	// ErrInvalidWitnessProgram is returned when the witness program length
+	// is invalid for its version
+	ErrInvalidWitnessProgram = errors.New("address: invalid witness program")
+	// ErrWrongEncoding is returned when the checksum variant does not match
+	// the witness version
+	ErrWrongEncoding = errors.New("address: wrong bech32 variant for witness version")
+)
+
+// Bech32Encode encodes the 5 bits groups in data with the human readable
+// part hrp and the checksum variant enc
+func Bech32Encode(hrp string, data []byte, enc Encoding) (string, error) {
+
+	hrp = strings.ToLower(hrp)
+
+	if len(hrp)+len(data)+1+checksumLen > bech32MaxLen {
+		return "", ErrInvalidBech32
+	}
+
+	for _, d := range data {
+		if d > 31 {
+			return "", ErrInvalidBech32
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(hrp)
+	sb.WriteByte('1')
+
+	for _, d := range data {
+		sb.WriteByte(bech32Charset[d])
+	}
+	for _, d := range bech32Checksum(hrp, data, enc) {
+		sb.WriteByte(bech32Charset[d])
+	}
+
+	return sb.String(), nil
+}
+
+// Bech32Decode decodes a bech32 or bech32m string returning the lower case
+// human readable part, the 5 bits groups of the data part and the detected
+// checksum variant
+func Bech32Decode(s string) (string, []byte, Encoding, error) {
+
+	if len(s) > bech32MaxLen {
+		return "", nil, 0, ErrInvalidBech32
+	}
+
+	lower := strings.ToLower(s)
+	if lower != s && strings.ToUpper(s) != s {
+		return "", nil, 0, ErrMixedCase
+	}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] < 33 || s[i] > 126 {
+			return "", nil, 0, ErrInvalidBech32
+		}
+	}
+
+	pos := strings.LastIndexByte(lower, '1')
+	if pos < 1 || pos+checksumLen+1 > len(lower) {
+		return "", nil, 0, ErrInvalidBech32
+	}
+
+	hrp := lower[:pos]
+
+	data := make([]byte, 0, len(lower)-pos-1)
+	for i := pos + 1; i < len(lower); i++ {
+		d := strings.IndexByte(bech32Charset, lower[i])
+		if d < 0 {
+			return "", nil, 0, ErrInvalidBech32
+		}
+		data = append(data, byte(d))
+	}
+
+	var enc Encoding
+
+	switch bech32Polymod(append(hrpExpand(hrp), data...)) {
+	case bech32Const:
+		enc = Bech32
+	case bech32mConst:
+		enc = Bech32m
+	default:
+		return "", nil, 0, ErrInvalidBech32Checksum
+	}
+
+	return hrp, data[:len(data)-checksumLen], enc, nil
+}
+
+// ConvertBits regroups data from groups of fromBits bits to groups of
+// toBits bits, pad adds zero bits to complete the last group
+func ConvertBits(data []byte, fromBits, toBits uint, pad bool) ([]byte, error) {
+
+	var acc, bits uint
+	maxv := uint(1)<<toBits - 1
+
+	out := make([]byte, 0, len(data)*int(fromBits)/int(toBits)+1)
+
+	for _, d := range data {
+		if uint(d)>>fromBits != 0 {
+			return nil, ErrInvalidPadding
+		}
+
+		acc = acc<<fromBits | uint(d)
+		bits += fromBits
+
+		for bits >= toBits {
+			bits -= toBits
+			out = append(out, byte(acc>>bits&maxv))
+		}
+	}
+
+	if pad {
+		if bits > 0 {
+			out = append(out, byte(acc<<(toBits-bits)&maxv))
+		}
+	} else if bits >= fromBits || acc<<(toBits-bits)&maxv != 0 {
+		return nil, ErrInvalidPadding
+	}
+
+	return out, nil
+}
+
+// SegwitEncode encodes a witness program as a BIP173/BIP350 address
+func SegwitEncode(hrp string, version byte, program []byte) (string, error) {
+
+	if err := checkWitnessProgram(version, program); err != nil {
+		return "", err
+	}
+
+	enc := Bech32
+	if version > 0 {
+		enc = Bech32m
+	}
+
+	data, err := ConvertBits(program, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+
+	return Bech32Encode(hrp, append([]byte{version}, data...), enc)
+}
+
+// SegwitDecode decodes a BIP173/BIP350 address for the human readable part
+// hrp returning its witness version and program
+func SegwitDecode(hrp string, addr string) (byte, []byte, error) {
+
+	h, data, enc, err := Bech32Decode(addr)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if h != strings.ToLower(hrp) || len(data) < 1 {
+		return 0, nil, ErrInvalidBech32
+	}
+
+	version := data[0]
+	if version > maxWitnessVers {
+		return 0, nil, ErrInvalidWitnessVersion
+	}
+
+	if (version == 0 && enc != Bech32) || (version != 0 && enc != Bech32m) {
+		return 0, nil, ErrWrongEncoding
+	}
+
+	program, err := ConvertBits(data[1:], 5, 8, false)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if err := checkWitnessProgram(version, program); err != nil {
+		return 0, nil, err
+	}
+
+	return version, program, nil
+}
+
+// checkWitnessProgram validates the program length for the witness version
+func checkWitnessProgram(version byte, program []byte) error {
+
+	if version > maxWitnessVers {
+		return ErrInvalidWitnessVersion
+	}
+
+	if len(program) < minProgramLen || len(program) > maxProgramLen {
+		return ErrInvalidWitnessProgram
+	}
+
+	if version == 0 && len(program) != 20 && len(program) != 32 {
+		return ErrInvalidWitnessProgram
+	}
+
+	return nil
+}
+
+func bech32Checksum(hrp string, data []byte, enc Encoding) []byte {
+
+	c := uint32(bech32Const)
+	if enc == Bech32m {
+		c = bech32mConst
+	}
+
+	values := append(hrpExpand(hrp), data...)
+	values = append(values, make([]byte, checksumLen)...)
+
+	mod := bech32Polymod(values) ^ c
+
+	checksum := make([]byte, checksumLen)
+	for i := range checksum {
+		checksum[i] = byte(mod >> uint(5*(5-i)) & 31)
+	}
+
+	return checksum
+}
+
+func bech32Polymod(values []byte) uint32 {
+
+	gen := []uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+
+	for _, v := range values {
+		b := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (b>>uint(i))&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+
+	return chk
+}
+
+func hrpExpand(hrp string) []byte {
+
+	out := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]>>5)
+	}
+	out = append(out, 0)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]&31)
+	}
+
+	return out
+}
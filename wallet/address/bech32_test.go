@@ -0,0 +1,144 @@
+package address
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type bech32test struct {
+	str      string
+	encoding Encoding
+	valid    bool
+}
+
+func bech32TestVector() []bech32test {
+	return []bech32test{
+		{"A12UEL5L", Bech32, true},
+		{"a12uel5l", Bech32, true},
+		{"an83characterlonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1tt5tgs", Bech32, true},
+		{"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw", Bech32, true},
+		{"11qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqc8247j", Bech32, true},
+		{"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w", Bech32, true},
+		{"?1ezyfcl", Bech32, true},
+		{"A1LQFN3A", Bech32m, true},
+		{"a1lqfn3a", Bech32m, true},
+		{"abcdef1l7aum6echk45nj3s0wdvt2fg8x9yrzpqzd3ryx", Bech32m, true},
+		{"split1checkupstagehandshakeupstreamerranterredcaperredlc445v", Bech32m, true},
+		{"?1v759aa", Bech32m, true},
+		{"\x201nwldj5", 0, false},
+		{"\x7f1axkwrx", 0, false},
+		{"an84characterslonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1569pvx", 0, false},
+		{"pzry9x0s0muk", 0, false},
+		{"1pzry9x0s0muk", 0, false},
+		{"x1b4n0q5v", 0, false},
+		{"li1dgmt3", 0, false},
+		{"de1lg7wt\xff", 0, false},
+		{"A1G7SGD8", 0, false},
+		{"10a06t8", 0, false},
+		{"1qzzfhee", 0, false},
+		{"a12UEL5L", 0, false},
+	}
+}
+
+type segwittest struct {
+	address      string
+	scriptPubKey string
+}
+
+func segwitTestVector() []segwittest {
+	return []segwittest{
+		{"BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", "0014751e76e8199196d454941c45d1b3a323f1433bd6"},
+		{"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7", "00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262"},
+		{"bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kt5nd6y", "5128751e76e8199196d454941c45d1b3a323f1433bd6751e76e8199196d454941c45d1b3a323f1433bd6"},
+		{"BC1SW50QGDZ25J", "6002751e"},
+		{"bc1zw508d6qejxtdg4y5r3zarvaryvaxxpcs", "5210751e76e8199196d454941c45d1b3a323"},
+		{"tb1qqqqqp399et2xygdj5xreqhjjvcmzhxw4aywxecjdzew6hylgvsesrxh6hy", "0020000000c4a5cad46221b2a187905e5266362b99d5e91c6ce24d165dab93e86433"},
+		{"tb1pqqqqp399et2xygdj5xreqhjjvcmzhxw4aywxecjdzew6hylgvsesf3hn0c", "5120000000c4a5cad46221b2a187905e5266362b99d5e91c6ce24d165dab93e86433"},
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", "512079be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"},
+	}
+}
+
+func invalidSegwitTestVector() []string {
+	return []string{
+		"tc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq5zuyut",
+		"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqh2y7hd",
+		"tb1z0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqglt7rf",
+		"BC1S0XLXVLHEMJA6C4DQV22UAPCTQUPFHLXM9H8Z3K2E72Q4K9HCZ7VQ54WELL",
+		"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kemeawh",
+		"tb1q0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq24jc47",
+		"bc1p38j9r5y49hruaue7wxjce0updqjuyyx0kh56v8s25huc6995vvpql3jow4",
+		"BC130XLXVLHEMJA6C4DQV22UAPCTQUPFHLXM9H8Z3K2E72Q4K9HCZ7VQ7ZWS8R",
+		"bc1pw5dgrnzv",
+		"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7v8n0nx0muaewav253zgeav",
+		"BC1QR508D6QEJXTDG4Y5R3ZARVARYV98GJ9P",
+		"tb1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq47Zagq",
+		"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7v07qwwzcrf",
+		"tb1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vpggkg4j",
+		"bc1gmk9yu",
+	}
+}
+
+func TestBech32Decode(t *testing.T) {
+	for _, test := range bech32TestVector() {
+		hrp, data, enc, err := Bech32Decode(test.str)
+
+		if !test.valid {
+			assert.Error(t, err, test.str)
+			continue
+		}
+
+		assert.NoError(t, err, test.str)
+		assert.Equal(t, test.encoding, enc, test.str)
+
+		str, err := Bech32Encode(hrp, data, enc)
+		assert.NoError(t, err)
+		assert.Equal(t, strings.ToLower(test.str), str)
+	}
+}
+
+func TestSegwitDecode(t *testing.T) {
+	for _, test := range segwitTestVector() {
+		hrp := "bc"
+		if strings.HasPrefix(strings.ToLower(test.address), "tb") {
+			hrp = "tb"
+		}
+
+		version, program, err := SegwitDecode(hrp, test.address)
+		assert.NoError(t, err, test.address)
+
+		script, _ := hex.DecodeString(test.scriptPubKey)
+
+		op := version
+		if version > 0 {
+			op += 0x50
+		}
+		assert.Equal(t, op, script[0])
+		assert.Equal(t, program, script[2:])
+
+		addr, err := SegwitEncode(hrp, version, program)
+		assert.NoError(t, err)
+		assert.Equal(t, strings.ToLower(test.address), addr)
+	}
+
+	for _, addr := range invalidSegwitTestVector() {
+		_, _, errMain := SegwitDecode("bc", addr)
+		_, _, errTest := SegwitDecode("tb", addr)
+		assert.True(t, errMain != nil && errTest != nil, addr)
+	}
+}
+
+func TestConvertBits(t *testing.T) {
+	data, err := ConvertBits([]byte{0xff}, 8, 5, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{31, 28}, data)
+
+	data, err = ConvertBits([]byte{31, 28}, 5, 8, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xff}, data)
+
+	_, err = ConvertBits([]byte{31, 29}, 5, 8, false)
+	assert.Equal(t, ErrInvalidPadding, err)
+}
@@ -0,0 +1,255 @@
+package bitcoind
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/giogam/Gopher-Wallet/wallet/chain"
+	"github.com/giogam/Gopher-Wallet/wallet/coinselect"
+	"github.com/giogam/Gopher-Wallet/wallet/script"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+// scanResult is the result of scantxoutset
+type scanResult struct {
+	Success  bool `json:"success"`
+	Unspents []struct {
+		TxID   string  `json:"txid"`
+		Vout   uint32  `json:"vout"`
+		Amount float64 `json:"amount"`
+		Height int32   `json:"height"`
+	} `json:"unspents"`
+}
+
+// received is an entry of listreceivedbyaddress
+type received struct {
+	Address string   `json:"address"`
+	TxIDs   []string `json:"txids"`
+}
+
+// walletTx is the result of gettransaction
+type walletTx struct {
+	Confirmations int64  `json:"confirmations"`
+	BlockHeight   int32  `json:"blockheight"`
+	Hex           string `json:"hex"`
+}
+
+// blockHeader is the verbose result of getblockheader
+type blockHeader struct {
+	Height int32 `json:"height"`
+}
+
+// feeEstimate is the result of estimatesmartfee, FeeRate is absent when
+// the node has no estimate
+type feeEstimate struct {
+	FeeRate *float64 `json:"feerate"`
+}
+
+// satoshis converts an amount in BTC to satoshis
+func satoshis(btc float64) int64 {
+	return int64(math.Round(btc * 1e8))
+}
+
+// Unspent scans the UTXO set for the outputs paying to pkScript, the scan
+// takes minutes on mainnet and only one can run at a time
+func (c *Client) Unspent(pkScript []byte) ([]*chain.Utxo, error) {
+
+	var result scanResult
+	desc := "raw(" + hex.EncodeToString(pkScript) + ")"
+	if err := c.call(false, "scantxoutset", []interface{}{"start", []string{desc}}, &result); err != nil {
+		return nil, err
+	}
+	if !result.Success {
+		return nil, ErrInvalidResponse
+	}
+
+	utxos := make([]*chain.Utxo, len(result.Unspents))
+	for i, u := range result.Unspents {
+		hash, err := transaction.NewHashFromStr(u.TxID)
+		if err != nil {
+			return nil, ErrInvalidResponse
+		}
+		utxos[i] = &chain.Utxo{
+			OutPoint: *transaction.NewOutPoint(&hash, u.Vout),
+			Value:    satoshis(u.Amount),
+			Height:   u.Height,
+		}
+	}
+
+	return utxos, nil
+}
+
+// UsedScripts reports whether each script received a transaction, the
+// account descriptors must have been imported into the wallet
+func (c *Client) UsedScripts(pkScripts [][]byte) ([]bool, error) {
+
+	var entries []received
+	if err := c.call(true, "listreceivedbyaddress", []interface{}{0, false, true}, &entries); err != nil {
+		return nil, err
+	}
+
+	addrs := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		addrs[e.Address] = len(e.TxIDs) != 0
+	}
+
+	used := make([]bool, len(pkScripts))
+	for i, pkScript := range pkScripts {
+		addr, err := script.ExtractAddress(pkScript, c.cfg.Net)
+		if err != nil {
+			return nil, fmt.Errorf("bitcoind: script %d: %w", i, err)
+		}
+		used[i] = addrs[addr.String()]
+	}
+
+	return used, nil
+}
+
+// History returns the wallet transactions paying to pkScript, confirmed
+// ones first in block order. The account descriptors must have been
+// imported into the wallet.
+func (c *Client) History(pkScript []byte) ([]*chain.HistoryItem, error) {
+
+	addr, err := script.ExtractAddress(pkScript, c.cfg.Net)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []received
+	if err := c.call(true, "listreceivedbyaddress", []interface{}{0, false, true, addr.String()}, &entries); err != nil {
+		return nil, err
+	}
+
+	var txids []string
+	for _, e := range entries {
+		if e.Address == addr.String() {
+			txids = append(txids, e.TxIDs...)
+		}
+	}
+
+	txs := make([]walletTx, len(txids))
+	calls := make([]*call, len(txids))
+	for i, txid := range txids {
+		calls[i] = &call{"gettransaction", []interface{}{txid, true}, &txs[i]}
+	}
+	if err := c.batch(true, calls); err != nil {
+		return nil, err
+	}
+
+	history := make([]*chain.HistoryItem, len(txids))
+	for i, txid := range txids {
+		hash, err := transaction.NewHashFromStr(txid)
+		if err != nil {
+			return nil, ErrInvalidResponse
+		}
+		history[i] = &chain.HistoryItem{TxHash: hash}
+		if txs[i].Confirmations > 0 {
+			history[i].Height = txs[i].BlockHeight
+		}
+	}
+
+	sort.SliceStable(history, func(i, j int) bool {
+		hi, hj := history[i].Height, history[j].Height
+		return hi > 0 && (hj <= 0 || hi < hj)
+	})
+
+	return history, nil
+}
+
+// Transaction returns the transaction with the given id, transactions
+// outside the mempool and the wallet need a node running with txindex
+func (c *Client) Transaction(hash *transaction.Hash) (*transaction.Tx, error) {
+
+	var raw string
+	err := c.call(false, "getrawtransaction", []interface{}{hash.String()}, &raw)
+	if isCode(err, rpcInvalidAddressOrKey) && c.cfg.Wallet != "" {
+		var tx walletTx
+		err = c.call(true, "gettransaction", []interface{}{hash.String(), true}, &tx)
+		raw = tx.Hex
+	}
+	if isCode(err, rpcInvalidAddressOrKey) {
+		return nil, chain.ErrTxNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := hex.DecodeString(raw)
+	if err != nil {
+		return nil, ErrInvalidResponse
+	}
+
+	tx, err := transaction.NewTxFromBytes(b)
+	if err != nil || tx.TxHash() != *hash {
+		return nil, ErrInvalidResponse
+	}
+
+	return tx, nil
+}
+
+// Broadcast submits tx to the mempool of the node which relays it
+func (c *Client) Broadcast(tx *transaction.Tx) error {
+
+	var txid string
+	if err := c.call(false, "sendrawtransaction", []interface{}{hex.EncodeToString(tx.Bytes())}, &txid); err != nil {
+		if !errors.Is(err, ErrRPC) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", chain.ErrBroadcast, err)
+	}
+
+	if txid != tx.TxHash().String() {
+		return ErrInvalidResponse
+	}
+
+	return nil
+}
+
+// BestBlock returns the height and header of the chain tip
+func (c *Client) BestBlock() (int32, *transaction.BlockHeader, error) {
+
+	var hash string
+	if err := c.call(false, "getbestblockhash", nil, &hash); err != nil {
+		return 0, nil, err
+	}
+
+	var verbose blockHeader
+	var raw string
+	if err := c.batch(false, []*call{
+		{"getblockheader", []interface{}{hash, true}, &verbose},
+		{"getblockheader", []interface{}{hash, false}, &raw},
+	}); err != nil {
+		return 0, nil, err
+	}
+
+	b, err := hex.DecodeString(raw)
+	if err != nil {
+		return 0, nil, ErrInvalidResponse
+	}
+
+	header, err := transaction.NewBlockHeaderFromBytes(b)
+	if err != nil || header.BlockHash().String() != hash {
+		return 0, nil, ErrInvalidResponse
+	}
+
+	return verbose.Height, header, nil
+}
+
+// EstimateFee returns the fee rate estimated by the node for confirmation
+// within target blocks
+func (c *Client) EstimateFee(target int) (coinselect.FeeRate, error) {
+
+	var estimate feeEstimate
+	if err := c.call(false, "estimatesmartfee", []interface{}{target}, &estimate); err != nil {
+		return 0, err
+	}
+
+	if estimate.FeeRate == nil {
+		return 0, chain.ErrNoFeeEstimate
+	}
+
+	return coinselect.FeeRate(satoshis(*estimate.FeeRate)), nil
+}
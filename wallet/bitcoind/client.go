@@ -0,0 +1,304 @@
+package bitcoind
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/giogam/Gopher-Wallet/wallet/address"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+// DefaultTimeout bounds the RPC requests
+const DefaultTimeout = 30 * time.Second
+
+// RPC error codes of bitcoind
+const (
+	rpcInvalidAddressOrKey = -5
+)
+
+var (
+	// ErrUnauthorized is returned when bitcoind rejects the credentials
+	ErrUnauthorized = errors.New("bitcoind: invalid rpc credentials")
+	// ErrRPC matches the errors returned by bitcoind
+	ErrRPC = errors.New("bitcoind: rpc error")
+	// ErrInvalidResponse is returned when a response cannot be decoded
+	ErrInvalidResponse = errors.New("bitcoind: invalid response")
+	// ErrInvalidCookie is returned when the cookie file is not user:pass
+	ErrInvalidCookie = errors.New("bitcoind: invalid cookie file")
+	// ErrNoWallet is returned by the calls needing a wallet when
+	// Config.Wallet is empty
+	ErrNoWallet = errors.New("bitcoind: no wallet configured")
+)
+
+// Config holds the RPC server address and credentials. The cookie file is
+// read on every request, so that a restarted node can be reached, when
+// User is empty. Wallet names the watch only wallet the accounts are
+// imported into. OnBlock is called with the hash of every new block when
+// ZMQBlock is the zmqpubhashblock endpoint of the node.
+type Config struct {
+	// Host is the host:port of the RPC server
+	Host       string
+	User       string
+	Pass       string
+	CookieFile string
+	Wallet     string
+	Net        *address.Network
+	Timeout    time.Duration
+	ZMQBlock   string
+	OnBlock    func(hash transaction.Hash)
+}
+
+// Client calls the JSON-RPC interface of bitcoind over HTTP
+type Client struct {
+	cfg    Config
+	http   *http.Client
+	nextID uint64
+
+	// zmq is the connection of the block subscriber, quit stops it
+	mu   sync.Mutex
+	zmq  *zmqConn
+	quit chan struct{}
+	done chan struct{}
+}
+
+// request is a JSON-RPC request
+type request struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+// response is a JSON-RPC response
+type response struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+// rpcError is an error returned by bitcoind, it matches ErrRPC
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("bitcoind: rpc error %d: %s", e.Code, e.Message)
+}
+
+func (e *rpcError) Is(target error) bool {
+	return target == ErrRPC
+}
+
+// isCode reports whether err is a bitcoind error with the given code
+func isCode(err error, code int) bool {
+
+	var e *rpcError
+
+	return errors.As(err, &e) && e.Code == code
+}
+
+// call is a request and the value its result is decoded into
+type call struct {
+	method string
+	params []interface{}
+	result interface{}
+}
+
+// New returns a client of the node, the block subscriber is started when
+// ZMQBlock is set
+func New(cfg Config) (*Client, error) {
+
+	if cfg.ZMQBlock != "" && !strings.HasPrefix(cfg.ZMQBlock, "tcp://") {
+		return nil, ErrInvalidEndpoint
+	}
+
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.Net == nil {
+		cfg.Net = address.MainNet
+	}
+
+	c := &Client{
+		cfg:  cfg,
+		http: &http.Client{Timeout: cfg.Timeout},
+		quit: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	if cfg.ZMQBlock != "" {
+		go c.subscribeBlocks()
+	} else {
+		close(c.done)
+	}
+
+	return c, nil
+}
+
+// Close stops the block subscriber
+func (c *Client) Close() error {
+
+	c.mu.Lock()
+	select {
+	case <-c.quit:
+		c.mu.Unlock()
+		return nil
+	default:
+	}
+	close(c.quit)
+	if c.zmq != nil {
+		c.zmq.Close()
+	}
+	c.mu.Unlock()
+
+	<-c.done
+
+	return nil
+}
+
+// credentials returns the user and password of the RPC server
+func (c *Client) credentials() (string, string, error) {
+
+	if c.cfg.User != "" || c.cfg.CookieFile == "" {
+		return c.cfg.User, c.cfg.Pass, nil
+	}
+
+	cookie, err := os.ReadFile(c.cfg.CookieFile)
+	if err != nil {
+		return "", "", err
+	}
+
+	parts := strings.SplitN(strings.TrimSpace(string(cookie)), ":", 2)
+	if len(parts) != 2 {
+		return "", "", ErrInvalidCookie
+	}
+
+	return parts[0], parts[1], nil
+}
+
+// call sends a request to the node, or to the wallet when wallet is true
+func (c *Client) call(wallet bool, method string, params []interface{}, result interface{}) error {
+
+	req := c.request(method, params)
+
+	var resp response
+	if err := c.post(wallet, req, &resp); err != nil {
+		return err
+	}
+
+	return decode(&resp, result)
+}
+
+// batch sends the requests in a single JSON-RPC batch
+func (c *Client) batch(wallet bool, calls []*call) error {
+
+	if len(calls) == 0 {
+		return nil
+	}
+
+	reqs := make([]*request, len(calls))
+	for i, call := range calls {
+		reqs[i] = c.request(call.method, call.params)
+	}
+
+	var resps []*response
+	if err := c.post(wallet, reqs, &resps); err != nil {
+		return err
+	}
+
+	byID := make(map[uint64]*response, len(resps))
+	for _, resp := range resps {
+		byID[resp.ID] = resp
+	}
+
+	for i, call := range calls {
+		resp, ok := byID[reqs[i].ID]
+		if !ok {
+			return ErrInvalidResponse
+		}
+		if err := decode(resp, call.result); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) request(method string, params []interface{}) *request {
+
+	if params == nil {
+		params = []interface{}{}
+	}
+
+	return &request{JSONRPC: "1.0", ID: atomic.AddUint64(&c.nextID, 1), Method: method, Params: params}
+}
+
+// post sends body and decodes the response into v, bitcoind answers RPC
+// errors with an HTTP error status and a JSON body
+func (c *Client) post(wallet bool, body interface{}, v interface{}) error {
+
+	endpoint := "http://" + c.cfg.Host + "/"
+	if wallet {
+		if c.cfg.Wallet == "" {
+			return ErrNoWallet
+		}
+		endpoint += "wallet/" + url.PathEscape(c.cfg.Wallet)
+	}
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	user, pass, err := c.credentials()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(user, pass)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return ErrUnauthorized
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidResponse, resp.Status)
+	}
+
+	return nil
+}
+
+// decode returns the error of resp or decodes its result into result
+func decode(resp *response, result interface{}) error {
+
+	if resp.Error != nil {
+		return resp.Error
+	}
+
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return ErrInvalidResponse
+	}
+
+	return nil
+}
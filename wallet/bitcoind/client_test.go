@@ -0,0 +1,306 @@
+package bitcoind
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/giogam/Gopher-Wallet/wallet/account"
+	"github.com/giogam/Gopher-Wallet/wallet/address"
+	"github.com/giogam/Gopher-Wallet/wallet/chain"
+	"github.com/giogam/Gopher-Wallet/wallet/coinselect"
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/mnemonic"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	txid        = "0f167d1385a84d1518cfee208b653fc9163b605ccf1b75347e2850b3e2eb19f3"
+	mempoolTxid = "8657a12001deff6f9b6f007d11c515c69ba94ed10622b966e1fe5e393687235a"
+	unknownTxid = "1111111111111111111111111111111111111111111111111111111111111111"
+)
+
+// recorded is an RPC call recorded from a regtest node and its response
+type recorded struct {
+	Wallet string          `json:"wallet"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  json.RawMessage `json:"error"`
+}
+
+// newReplayServer returns an HTTP stand-in of bitcoind answering the
+// recorded calls of testdata/rpc.json
+func newReplayServer(t *testing.T, user, pass string) *httptest.Server {
+
+	data, err := os.ReadFile("testdata/rpc.json")
+	assert.NoError(t, err)
+
+	var calls []recorded
+	assert.NoError(t, json.Unmarshal(data, &calls))
+
+	replay := func(wallet string, req *request) (map[string]interface{}, bool) {
+		params, _ := json.Marshal(req.Params)
+		for _, call := range calls {
+			if call.Wallet == wallet && call.Method == req.Method && canonical(call.Params) == canonical(params) {
+				if call.Error != nil {
+					return map[string]interface{}{"id": req.ID, "result": nil, "error": call.Error}, false
+				}
+				return map[string]interface{}{"id": req.ID, "result": call.Result, "error": nil}, true
+			}
+		}
+		return map[string]interface{}{"id": req.ID, "result": nil, "error": map[string]interface{}{"code": -32601, "message": "Method not found"}}, false
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, p, ok := r.BasicAuth(); !ok || u != user || p != pass {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		wallet := strings.TrimPrefix(r.URL.Path, "/wallet/")
+		if wallet == r.URL.Path {
+			wallet = ""
+		}
+
+		var body json.RawMessage
+		json.NewDecoder(r.Body).Decode(&body)
+
+		if strings.HasPrefix(string(body), "[") {
+			var reqs []*request
+			json.Unmarshal(body, &reqs)
+			resps := make([]interface{}, len(reqs))
+			for i := range reqs {
+				// answered in reverse order as batches may be
+				resps[len(reqs)-1-i], _ = replay(wallet, reqs[i])
+			}
+			json.NewEncoder(w).Encode(resps)
+			return
+		}
+
+		var req request
+		json.Unmarshal(body, &req)
+		resp, ok := replay(wallet, &req)
+		if !ok {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+// canonical returns the JSON encoding of data with sorted keys
+func canonical(data []byte) string {
+
+	var v interface{}
+	json.Unmarshal(data, &v)
+	b, _ := json.Marshal(v)
+
+	return string(b)
+}
+
+func newTestClient(t *testing.T, wallet string) (*Client, func()) {
+
+	server := newReplayServer(t, "alice", "secret")
+	c, err := New(Config{
+		Host:   strings.TrimPrefix(server.URL, "http://"),
+		User:   "alice",
+		Pass:   "secret",
+		Wallet: wallet,
+		Net:    address.RegTest,
+	})
+	assert.NoError(t, err)
+
+	return c, func() {
+		c.Close()
+		server.Close()
+	}
+}
+
+func testAccount() *account.Account {
+
+	seed := mnemonic.NewSeed(strings.Split("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", " "), "")
+	master, _ := hdwallet.NewMasterKey(seed, hdwallet.TestnetPrivate)
+	a, _ := account.New(master, address.P2WPKH, 0, address.RegTest)
+
+	return a
+}
+
+func TestBestBlock(t *testing.T) {
+
+	c, done := newTestClient(t, "")
+	defer done()
+
+	height, header, err := c.BestBlock()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(0), height)
+	assert.Equal(t, "0f9188f13cb7b2c71f2a335e3a4fc328bf5beb436012afca590b1a11466e2206", header.BlockHash().String())
+	assert.Equal(t, uint32(0x207fffff), header.Bits)
+}
+
+func TestEstimateFee(t *testing.T) {
+
+	c, done := newTestClient(t, "")
+	defer done()
+
+	rate, err := c.EstimateFee(6)
+	assert.NoError(t, err)
+	assert.Equal(t, coinselect.FeeRate(12345), rate)
+
+	_, err = c.EstimateFee(1)
+	assert.Equal(t, chain.ErrNoFeeEstimate, err)
+
+	_, err = c.EstimateFee(2)
+	assert.True(t, errors.Is(err, ErrRPC))
+}
+
+func TestUnspent(t *testing.T) {
+
+	c, done := newTestClient(t, "")
+	defer done()
+
+	pkScript, _ := testAccount().PkScript(account.External, 0)
+	utxos, err := c.Unspent(pkScript)
+	assert.NoError(t, err)
+
+	hash, _ := transaction.NewHashFromStr(txid)
+	assert.Equal(t, []*chain.Utxo{{OutPoint: *transaction.NewOutPoint(&hash, 1), Value: 395019, Height: 105}}, utxos)
+}
+
+func TestTransaction(t *testing.T) {
+
+	c, done := newTestClient(t, "watch")
+	defer done()
+
+	for _, id := range []string{txid, mempoolTxid} {
+		hash, _ := transaction.NewHashFromStr(id)
+		tx, err := c.Transaction(&hash)
+		assert.NoError(t, err)
+		assert.Equal(t, id, tx.TxHash().String())
+	}
+
+	hash, _ := transaction.NewHashFromStr(unknownTxid)
+	_, err := c.Transaction(&hash)
+	assert.Equal(t, chain.ErrTxNotFound, err)
+
+	// without a wallet only the node is asked
+	node, done := newTestClient(t, "")
+	defer done()
+	hash, _ = transaction.NewHashFromStr(mempoolTxid)
+	_, err = node.Transaction(&hash)
+	assert.Equal(t, chain.ErrTxNotFound, err)
+}
+
+func TestBroadcast(t *testing.T) {
+
+	c, done := newTestClient(t, "")
+	defer done()
+
+	raw, _ := hex.DecodeString("01000000000101a53352d5135766f03076597418263da2d9c958315968fea823529467481ff9cd1300000000ffffffff010b070600000000001600149ddac6f39d51e0398e532a22c41ba189406a852302463043021f4d2381dc97f182abd8185f51753018523212f5ddc07cc4e63a8dc03658da190220608b5c4d92b86b6de7d78ef23a2fa735bcb59b914a48b0e187c5e7569a18197001210307ead084807eb76346df6977000c89392f45c76425b26181f521d7f370066a8f00000000")
+	tx, _ := transaction.NewTxFromBytes(raw)
+	assert.NoError(t, c.Broadcast(tx))
+
+	tx.Version = 2
+	err := c.Broadcast(tx)
+	assert.True(t, errors.Is(err, chain.ErrBroadcast))
+	assert.Contains(t, err.Error(), "bad-txns-inputs-missingorspent")
+}
+
+func TestUsedScripts(t *testing.T) {
+
+	c, done := newTestClient(t, "watch")
+	defer done()
+
+	a := testAccount()
+	var scripts [][]byte
+	for index := uint32(0); index < 3; index++ {
+		pkScript, _ := a.PkScript(account.External, index)
+		scripts = append(scripts, pkScript)
+	}
+
+	var backend chain.Backend = c
+	used, err := backend.UsedScripts(scripts)
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true, false, true}, used)
+
+	_, err = c.UsedScripts([][]byte{{0x6a}})
+	assert.Error(t, err)
+
+	node, done := newTestClient(t, "")
+	defer done()
+	_, err = node.UsedScripts(scripts)
+	assert.Equal(t, ErrNoWallet, err)
+}
+
+func TestHistory(t *testing.T) {
+
+	c, done := newTestClient(t, "watch")
+	defer done()
+
+	pkScript, _ := testAccount().PkScript(account.External, 0)
+	history, err := c.History(pkScript)
+	assert.NoError(t, err)
+
+	confirmed, _ := transaction.NewHashFromStr(txid)
+	unconfirmed, _ := transaction.NewHashFromStr(mempoolTxid)
+	assert.Equal(t, []*chain.HistoryItem{{TxHash: confirmed, Height: 105}, {TxHash: unconfirmed, Height: 0}}, history)
+}
+
+func TestImportDescriptors(t *testing.T) {
+
+	c, done := newTestClient(t, "watch")
+	defer done()
+
+	assert.NoError(t, c.CreateWatchOnlyWallet())
+
+	reqs, err := AccountImports(testAccount(), 20, 1700000000)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(reqs))
+	assert.Equal(t, "wpkh([73c5da0a/84h/1h/0h]tpubDC8msFGeGuwnKG9Upg7DM2b4DaRqg3CUZa5g8v2SRQ6K4NSkxUgd7HsL2XVWbVm39yBA4LAxysQAm397zwQSQoQgewGiYZqrA9DsP4zbQ1M/0/*)#evh9fu0w", reqs[0].Desc)
+	assert.Equal(t, [2]uint32{0, 19}, reqs[1].Range)
+	assert.True(t, reqs[1].Internal)
+	assert.NoError(t, c.ImportDescriptors(reqs))
+
+	bad := *reqs[0]
+	bad.Internal = true
+	err = c.ImportDescriptors([]*ImportRequest{&bad})
+	assert.True(t, errors.Is(err, ErrImportFailed))
+	assert.Contains(t, err.Error(), "descriptor 0")
+}
+
+func TestCredentials(t *testing.T) {
+
+	server := newReplayServer(t, "__cookie__", "0123abcd")
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	cookie := filepath.Join(t.TempDir(), ".cookie")
+	assert.NoError(t, os.WriteFile(cookie, []byte("__cookie__:0123abcd"), 0600))
+
+	c, err := New(Config{Host: host, CookieFile: cookie})
+	assert.NoError(t, err)
+	_, _, err = c.BestBlock()
+	assert.NoError(t, err)
+
+	// the cookie is read again after a restart of the node
+	assert.NoError(t, os.WriteFile(cookie, []byte("__cookie__:"+base64.StdEncoding.EncodeToString([]byte("new"))), 0600))
+	_, _, err = c.BestBlock()
+	assert.Equal(t, ErrUnauthorized, err)
+
+	assert.NoError(t, os.WriteFile(cookie, []byte("garbage"), 0600))
+	_, _, err = c.BestBlock()
+	assert.Equal(t, ErrInvalidCookie, err)
+
+	c, _ = New(Config{Host: host, User: "alice", Pass: "wrong"})
+	_, _, err = c.BestBlock()
+	assert.Equal(t, ErrUnauthorized, err)
+
+	_, err = New(Config{Host: host, ZMQBlock: "ipc:///tmp/bitcoind"})
+	assert.Equal(t, ErrInvalidEndpoint, err)
+}
@@ -0,0 +1,132 @@
+package bitcoind
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/giogam/Gopher-Wallet/wallet/account"
+	"github.com/giogam/Gopher-Wallet/wallet/descriptor"
+	"github.com/giogam/Gopher-Wallet/wallet/discovery"
+	"github.com/giogam/Gopher-Wallet/wallet/multisig"
+)
+
+// ErrImportFailed is returned when bitcoind rejects a descriptor
+var ErrImportFailed = errors.New("bitcoind: descriptor import failed")
+
+// ImportRequest is a descriptor as given to importdescriptors. Range is
+// the index range watched, Timestamp the time in seconds since the epoch
+// from which the chain is rescanned for the descriptor transactions.
+type ImportRequest struct {
+	Desc      string    `json:"desc"`
+	Active    bool      `json:"active"`
+	Range     [2]uint32 `json:"range"`
+	NextIndex uint32    `json:"next_index"`
+	Timestamp int64     `json:"timestamp"`
+	Internal  bool      `json:"internal"`
+}
+
+// importResult is the result of importdescriptors for one request
+type importResult struct {
+	Success bool      `json:"success"`
+	Error   *rpcError `json:"error"`
+}
+
+// AccountImports returns the import requests of the external and internal
+// chains of a, gap addresses are watched past the first unused one and a
+// zero gap selects discovery.DefaultGapLimit
+func AccountImports(a *account.Account, gap uint32, timestamp int64) ([]*ImportRequest, error) {
+
+	var descs [2]*descriptor.Descriptor
+	for _, chain := range []uint32{account.External, account.Internal} {
+		d, err := descriptor.FromAccount(a, chain)
+		if err != nil {
+			return nil, err
+		}
+		descs[chain] = d
+	}
+
+	return imports(descs, a.Next, gap, timestamp)
+}
+
+// MultisigImports returns the import requests of the external and internal
+// chains of a multisig account
+func MultisigImports(a *multisig.Account, gap uint32, timestamp int64) ([]*ImportRequest, error) {
+
+	var descs [2]*descriptor.Descriptor
+	for _, chain := range []uint32{account.External, account.Internal} {
+		d, err := a.Descriptor(chain)
+		if err != nil {
+			return nil, err
+		}
+		descs[chain] = d
+	}
+
+	return imports(descs, a.Next, gap, timestamp)
+}
+
+// imports returns the requests importing the public descriptors of both
+// chains
+func imports(descs [2]*descriptor.Descriptor, next [2]uint32, gap uint32, timestamp int64) ([]*ImportRequest, error) {
+
+	if gap == 0 {
+		gap = discovery.DefaultGapLimit
+	}
+
+	reqs := make([]*ImportRequest, len(descs))
+	for chain, d := range descs {
+		public, err := d.Neuter()
+		if err != nil {
+			return nil, err
+		}
+		reqs[chain] = &ImportRequest{
+			Desc:      public.String(),
+			Active:    true,
+			Range:     [2]uint32{0, next[chain] + gap - 1},
+			NextIndex: next[chain],
+			Timestamp: timestamp,
+			Internal:  uint32(chain) == account.Internal,
+		}
+	}
+
+	return reqs, nil
+}
+
+// CreateWatchOnlyWallet creates the descriptor wallet Config.Wallet
+// without private keys
+func (c *Client) CreateWatchOnlyWallet() error {
+
+	if c.cfg.Wallet == "" {
+		return ErrNoWallet
+	}
+
+	var result interface{}
+
+	return c.call(false, "createwallet", []interface{}{c.cfg.Wallet, true, true, "", false, true}, &result)
+}
+
+// ImportDescriptors imports the descriptors into the wallet, the call
+// returns once the chain is rescanned from the oldest timestamp
+func (c *Client) ImportDescriptors(reqs []*ImportRequest) error {
+
+	var results []importResult
+	if err := c.call(true, "importdescriptors", []interface{}{reqs}, &results); err != nil {
+		return err
+	}
+
+	if len(results) != len(reqs) {
+		return ErrInvalidResponse
+	}
+
+	for i, result := range results {
+		if result.Success {
+			continue
+		}
+		message := "unknown error"
+		if result.Error != nil {
+			message = result.Error.Message
+		}
+		return fmt.Errorf("%w: descriptor %d: %s", ErrImportFailed, i, message)
+	}
+
+	return nil
+}
@@ -0,0 +1,227 @@
+package bitcoind
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+const (
+	// zmqGreetingSize is the size of the ZMTP 3.0 greeting
+	zmqGreetingSize = 64
+	// maxFrameSize bounds the frames read, hashblock messages are small
+	maxFrameSize = 1 << 20
+
+	frameMore    = 0x01
+	frameLong    = 0x02
+	frameCommand = 0x04
+
+	topicHashBlock = "hashblock"
+)
+
+var (
+	// ErrInvalidEndpoint is returned when the ZMQ endpoint is not
+	// tcp://host:port
+	ErrInvalidEndpoint = errors.New("bitcoind: invalid zmq endpoint")
+	// ErrZMTP is returned when the ZMQ publisher breaks the protocol
+	ErrZMTP = errors.New("bitcoind: zmq protocol error")
+)
+
+// zmqRetryDelay is the delay between connections to the ZMQ publisher
+var zmqRetryDelay = 5 * time.Second
+
+// zmqConn is a ZMTP 3.0 connection of a SUB socket with the NULL security
+// mechanism, which is what bitcoind publishes with
+type zmqConn struct {
+	net.Conn
+}
+
+// dialZMQ connects to a publisher and subscribes to topic
+func dialZMQ(endpoint, topic string, timeout time.Duration) (*zmqConn, error) {
+
+	addr := strings.TrimPrefix(endpoint, "tcp://")
+	if addr == endpoint {
+		return nil, ErrInvalidEndpoint
+	}
+
+	nc, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &zmqConn{nc}
+
+	c.SetDeadline(time.Now().Add(timeout))
+	if err := c.handshake(); err != nil {
+		c.Close()
+		return nil, err
+	}
+	if err := c.writeFrame(0, append([]byte{1}, topic...)); err != nil {
+		c.Close()
+		return nil, err
+	}
+	c.SetDeadline(time.Time{})
+
+	return c, nil
+}
+
+// handshake exchanges the greetings and READY commands
+func (c *zmqConn) handshake() error {
+
+	greeting := make([]byte, zmqGreetingSize)
+	greeting[0], greeting[9] = 0xff, 0x7f
+	greeting[10], greeting[11] = 3, 0
+	copy(greeting[12:32], "NULL")
+	if _, err := c.Write(greeting); err != nil {
+		return err
+	}
+
+	peer := make([]byte, zmqGreetingSize)
+	if _, err := io.ReadFull(c, peer); err != nil {
+		return err
+	}
+	if peer[0] != 0xff || peer[9] != 0x7f || peer[10] < 3 || string(bytes.TrimRight(peer[12:32], "\x00")) != "NULL" {
+		return ErrZMTP
+	}
+
+	ready := []byte("\x05READY\x0bSocket-Type")
+	ready = binary.BigEndian.AppendUint32(ready, 3)
+	ready = append(ready, "SUB"...)
+	if err := c.writeFrame(frameCommand, ready); err != nil {
+		return err
+	}
+
+	flags, body, err := c.readFrame()
+	if err != nil {
+		return err
+	}
+	if flags&frameCommand == 0 || !bytes.HasPrefix(body, []byte("\x05READY")) {
+		return ErrZMTP
+	}
+
+	return nil
+}
+
+// writeFrame writes a frame with the given flags
+func (c *zmqConn) writeFrame(flags byte, body []byte) error {
+
+	frame := []byte{flags}
+	if len(body) > 255 {
+		frame[0] |= frameLong
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(body)))
+	} else {
+		frame = append(frame, byte(len(body)))
+	}
+
+	_, err := c.Write(append(frame, body...))
+
+	return err
+}
+
+// readFrame reads a frame and returns its flags and body
+func (c *zmqConn) readFrame() (byte, []byte, error) {
+
+	var header [9]byte
+	if _, err := io.ReadFull(c, header[:2]); err != nil {
+		return 0, nil, err
+	}
+
+	flags, size := header[0], uint64(header[1])
+	if flags&frameLong != 0 {
+		if _, err := io.ReadFull(c, header[2:]); err != nil {
+			return 0, nil, err
+		}
+		size = binary.BigEndian.Uint64(header[1:])
+	}
+	if size > maxFrameSize {
+		return 0, nil, ErrZMTP
+	}
+
+	body := make([]byte, size)
+	if _, err := io.ReadFull(c, body); err != nil {
+		return 0, nil, err
+	}
+
+	return flags, body, nil
+}
+
+// readMessage returns the frames of the next message, commands are
+// skipped
+func (c *zmqConn) readMessage() ([][]byte, error) {
+
+	var frames [][]byte
+	for {
+		flags, body, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		if flags&frameCommand != 0 {
+			continue
+		}
+
+		frames = append(frames, body)
+		if flags&frameMore == 0 {
+			return frames, nil
+		}
+	}
+}
+
+// subscribeBlocks delivers the hashblock notifications to OnBlock until
+// the client is closed, reconnecting when the connection is lost
+func (c *Client) subscribeBlocks() {
+
+	defer close(c.done)
+
+	for {
+		conn, err := dialZMQ(c.cfg.ZMQBlock, topicHashBlock, c.cfg.Timeout)
+		if err == nil {
+			c.mu.Lock()
+			select {
+			case <-c.quit:
+				conn.Close()
+				c.mu.Unlock()
+				return
+			default:
+			}
+			c.zmq = conn
+			c.mu.Unlock()
+
+			c.receiveBlocks(conn)
+			conn.Close()
+		}
+
+		select {
+		case <-time.After(zmqRetryDelay):
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+// receiveBlocks reads hashblock messages, the topic, the block hash in
+// display order and a sequence number, until conn fails
+func (c *Client) receiveBlocks(conn *zmqConn) {
+
+	for {
+		frames, err := conn.readMessage()
+		if err != nil {
+			return
+		}
+		if len(frames) < 2 || string(frames[0]) != topicHashBlock || len(frames[1]) != transaction.HashSize {
+			continue
+		}
+
+		var hash transaction.Hash
+		for i, b := range frames[1] {
+			hash[transaction.HashSize-1-i] = b
+		}
+		if c.cfg.OnBlock != nil {
+			c.cfg.OnBlock(hash)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package bitcoind
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+// publish accepts a subscriber on ln as a bitcoind hashblock publisher
+// and sends it the block hashes
+func publish(t *testing.T, ln net.Listener, hashes ...string) {
+
+	conn, err := ln.Accept()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	c := &zmqConn{conn}
+
+	greeting := make([]byte, zmqGreetingSize)
+	_, err = io.ReadFull(c, greeting)
+	assert.NoError(t, err)
+	assert.Equal(t, "NULL", string(greeting[12:16]))
+
+	greeting[10], greeting[11] = 3, 1
+	c.Write(greeting)
+
+	flags, ready, err := c.readFrame()
+	assert.NoError(t, err)
+	assert.Equal(t, byte(frameCommand), flags)
+	assert.Contains(t, string(ready), "Socket-Type\x00\x00\x00\x03SUB")
+
+	body := []byte("\x05READY\x0bSocket-Type\x00\x00\x00\x03PUB")
+	c.writeFrame(frameCommand, body)
+
+	_, subscription, err := c.readFrame()
+	assert.NoError(t, err)
+	assert.Equal(t, "\x01hashblock", string(subscription))
+
+	for i, hash := range hashes {
+		h, _ := transaction.NewHashFromStr(hash)
+		display := make([]byte, transaction.HashSize)
+		for j := range h {
+			display[transaction.HashSize-1-j] = h[j]
+		}
+		seq := binary.LittleEndian.AppendUint32(nil, uint32(i))
+
+		c.writeFrame(frameMore, []byte(topicHashBlock))
+		c.writeFrame(frameMore, display)
+		c.writeFrame(0, seq)
+	}
+}
+
+func TestBlockNotifications(t *testing.T) {
+
+	zmqRetryDelay = 10 * time.Millisecond
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	blocks := make(chan string, 10)
+	c, err := New(Config{
+		Host:     "127.0.0.1:1",
+		ZMQBlock: "tcp://" + ln.Addr().String(),
+		Timeout:  time.Second,
+		OnBlock:  func(hash transaction.Hash) { blocks <- hash.String() },
+	})
+	assert.NoError(t, err)
+
+	hashes := []string{
+		"0f9188f13cb7b2c71f2a335e3a4fc328bf5beb436012afca590b1a11466e2206",
+		"54f5d4d1f9c2d3d65e7bcf4bf7b4ad3c5b03e1ed6c9b0ab4a7f8b8c4f1c3b2a1",
+		"2b8a9ae8e04a8ef2a8ff8b8fd4cd4f0ca64bb1d2a5e1bb4d1c3dd3e2e44f4fe0",
+	}
+
+	// the subscriber reconnects when the publisher goes away
+	publish(t, ln, hashes[:2]...)
+	publish(t, ln, hashes[2])
+
+	for _, hash := range hashes {
+		select {
+		case got := <-blocks:
+			assert.Equal(t, hash, got)
+		case <-time.After(2 * time.Second):
+			t.Fatal("block not notified")
+		}
+	}
+
+	assert.NoError(t, c.Close())
+}
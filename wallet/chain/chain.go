@@ -0,0 +1,56 @@
+package chain
+
+import (
+	"errors"
+
+	"github.com/giogam/Gopher-Wallet/wallet/coinselect"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+var (
+	// ErrTxNotFound is returned when a backend does not know a transaction
+	ErrTxNotFound = errors.New("chain: transaction not found")
+	// ErrNoFeeEstimate is returned when a backend has not enough data to
+	// estimate the fee rate
+	ErrNoFeeEstimate = errors.New("chain: fee estimate not available")
+	// ErrBroadcast is returned when a transaction is rejected by the network
+	ErrBroadcast = errors.New("chain: transaction rejected")
+)
+
+// HistoryItem is a transaction paying to or spending from a script,
+// Height is 0 or less while the transaction is unconfirmed
+type HistoryItem struct {
+	TxHash transaction.Hash
+	Height int32
+}
+
+// Utxo is an unspent output paying to a script, Height is 0 while the
+// transaction is unconfirmed
+type Utxo struct {
+	OutPoint transaction.OutPoint
+	Value    int64
+	Height   int32
+}
+
+// Backend is the view of the block chain a wallet needs to follow its
+// scripts, spend their outputs and broadcast transactions. It satisfies
+// discovery.Backend.
+type Backend interface {
+	// UsedScripts reports, for each output script, whether a transaction
+	// paid to it
+	UsedScripts(pkScripts [][]byte) ([]bool, error)
+	// History returns the transactions paying to or spending from
+	// pkScript, confirmed ones first in block order
+	History(pkScript []byte) ([]*HistoryItem, error)
+	// Unspent returns the unspent outputs paying to pkScript
+	Unspent(pkScript []byte) ([]*Utxo, error)
+	// Transaction returns the transaction with the given id
+	Transaction(hash *transaction.Hash) (*transaction.Tx, error)
+	// Broadcast relays a signed transaction to the network
+	Broadcast(tx *transaction.Tx) error
+	// BestBlock returns the height and header of the chain tip
+	BestBlock() (int32, *transaction.BlockHeader, error)
+	// EstimateFee returns the fee rate for confirmation within target
+	// blocks
+	EstimateFee(target int) (coinselect.FeeRate, error)
+}
@@ -0,0 +1,94 @@
+package coinselect
+
+import "sort"
+
+// bnbTotalTries bounds the number of branches explored
+const bnbTotalTries = 100000
+
+// selectBnB searches depth first, largest coins first, a selection whose
+// effective value is between target and target+costOfChange so that no
+// change is needed. Among the solutions the one with the lowest waste is
+// kept, the excess over the target counting as waste.
+func selectBnB(pool []*output, target, costOfChange int64) ([]*output, error) {
+
+	pool = append([]*output{}, pool...)
+	sort.SliceStable(pool, func(i, j int) bool {
+		return pool[i].value > pool[j].value
+	})
+
+	var available int64
+	for _, o := range pool {
+		available += o.value
+	}
+
+	if available < target {
+		return nil, ErrNoSolution
+	}
+
+	// once the fees are higher than in the long term adding coins only
+	// increases the waste, so worse branches can be cut
+	feeRateHigh := len(pool) > 0 && pool[0].fee > pool[0].longTermFee
+
+	var value, waste int64
+	var selection, best []int
+	bestWaste := int64(1<<63 - 1)
+	found := false
+
+	for try, i := 0, 0; try < bnbTotalTries; try, i = try+1, i+1 {
+		backtrack := false
+
+		switch {
+		case value+available < target || value > target+costOfChange || (waste > bestWaste && feeRateHigh):
+			backtrack = true
+
+		case value >= target:
+			if waste+value-target <= bestWaste {
+				best = append(best[:0], selection...)
+				bestWaste = waste + value - target
+				found = true
+			}
+			backtrack = true
+		}
+
+		if backtrack {
+			if len(selection) == 0 {
+				break
+			}
+
+			// the coins skipped after the last included one are available
+			// again before exploring its omission branch
+			last := selection[len(selection)-1]
+			for i--; i > last; i-- {
+				available += pool[i].value
+			}
+
+			value -= pool[i].value
+			waste -= pool[i].fee - pool[i].longTermFee
+			selection = selection[:len(selection)-1]
+			continue
+		}
+
+		o := pool[i]
+		available -= o.value
+
+		// a coin equivalent to the previous excluded one would only explore
+		// the same branches again
+		if len(selection) == 0 || i-1 == selection[len(selection)-1] ||
+			o.value != pool[i-1].value || o.fee != pool[i-1].fee {
+			selection = append(selection, i)
+			value += o.value
+			waste += o.fee - o.longTermFee
+		}
+	}
+
+	if !found {
+		return nil, ErrNoSolution
+	}
+
+	selected := make([]*output, len(best))
+	for j, i := range best {
+		selected[j] = pool[i]
+	}
+
+	return selected, nil
+}
@@ -0,0 +1,104 @@
+package coinselect
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const cent = 1000000
+
+// freeOutputs returns outputs of the given values spent without fees
+func freeOutputs(values ...int64) []*output {
+
+	outputs := make([]*output, len(values))
+	for i, v := range values {
+		outputs[i] = &output{coin: &Coin{Value: v}, value: v}
+	}
+
+	return outputs
+}
+
+func values(outputs []*output) []int64 {
+
+	var v []int64
+	for _, o := range outputs {
+		v = append(v, o.value)
+	}
+
+	return v
+}
+
+func TestSelectBnB(t *testing.T) {
+	pool := freeOutputs(1*cent, 2*cent, 3*cent, 4*cent)
+
+	tests := []struct {
+		target   int64
+		expected []int64
+	}{
+		{1 * cent, []int64{1 * cent}},
+		{2 * cent, []int64{2 * cent}},
+		{5 * cent, []int64{3 * cent, 2 * cent}},
+		{10 * cent, []int64{4 * cent, 3 * cent, 2 * cent, 1 * cent}},
+		// within the cost of change of a single coin
+		{cent / 2, []int64{1 * cent}},
+	}
+
+	for _, test := range tests {
+		selected, err := selectBnB(pool, test.target, cent/2)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, values(selected), test.target)
+	}
+
+	// no subset falls within the window
+	_, err := selectBnB(pool, cent/4, cent/2)
+	assert.Equal(t, ErrNoSolution, err)
+
+	// not enough value
+	_, err = selectBnB(pool, 11*cent, cent/2)
+	assert.Equal(t, ErrNoSolution, err)
+
+	// the cost of change is too small to accept the excess
+	_, err = selectBnB(freeOutputs(2*cent, 3*cent), 4*cent, cent/2)
+	assert.Equal(t, ErrNoSolution, err)
+}
+
+func TestSelectBnBWaste(t *testing.T) {
+	// spending now is more expensive than later so fewer inputs are less
+	// wasteful even with some excess
+	pool := []*output{
+		{value: 3 * cent, fee: 1000, longTermFee: 100},
+		{value: 2 * cent, fee: 1000, longTermFee: 100},
+		{value: 1 * cent, fee: 1000, longTermFee: 100},
+		{value: 5*cent + 500, fee: 1000, longTermFee: 100},
+	}
+
+	selected, err := selectBnB(pool, 5*cent, 1000)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{5*cent + 500}, values(selected))
+
+	// spending later is more expensive so consolidating is preferred
+	for _, o := range pool {
+		o.fee, o.longTermFee = 100, 1000
+	}
+	selected, err = selectBnB(pool, 5*cent, 1000)
+	assert.NoError(t, err)
+	assert.Len(t, selected, 2)
+}
+
+func TestSelectBnBExhaustion(t *testing.T) {
+	// equivalent coins are skipped so the search ends quickly, and the
+	// largest ones are explored first
+	var v []int64
+	for i := 0; i < 100; i++ {
+		v = append(v, 2*cent)
+	}
+	v = append(v, 1*cent+1)
+
+	selected, err := selectBnB(freeOutputs(v...), 4*cent, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2 * cent, 2 * cent}, values(selected))
+
+	_, err = selectBnB(freeOutputs(v...), 3*cent, 0)
+	assert.Equal(t, ErrNoSolution, err)
+}
@@ -0,0 +1,391 @@
+package coinselect
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/giogam/Gopher-Wallet/wallet/script"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+// spend sizes in vbytes of the standard input types, signatures are
+// assumed to be of maximum size
+const (
+	P2PKHInputSize      = 148
+	P2SHP2WPKHInputSize = 91
+	P2WPKHInputSize     = 68
+	P2TRInputSize       = 58
+)
+
+const (
+	// P2WPKHOutputSize is the size in vbytes of a P2WPKH output
+	P2WPKHOutputSize = 31
+	// ChangeLower is the default smallest change targeted by knapsack and
+	// single random draw
+	ChangeLower = 50000
+	// DefaultLongTermFeeRate is the default fee rate expected to spend the
+	// coins in the future
+	DefaultLongTermFeeRate FeeRate = 10000
+	// DustRelayFeeRate is the rate used to compute the dust threshold
+	DustRelayFeeRate FeeRate = 3000
+)
+
+var (
+	// ErrInsufficientFunds is returned when the coins cannot pay the
+	// targets and the fees
+	ErrInsufficientFunds = errors.New("coinselect: insufficient funds")
+	// ErrNoSolution is returned when an algorithm finds no selection
+	ErrNoSolution = errors.New("coinselect: no solution found")
+	// ErrUnknownInputType is returned when the spend size of a script is
+	// not known
+	ErrUnknownInputType = errors.New("coinselect: unknown input type")
+	// ErrUnknownCoin is returned when a coin to include is not available
+	ErrUnknownCoin = errors.New("coinselect: unknown coin")
+)
+
+// FeeRate is a fee rate in satoshis per 1000 vbytes
+type FeeRate int64
+
+// Fee returns the fee paid at the rate by size vbytes, rounded up
+func (r FeeRate) Fee(size int) int64 {
+
+	if r <= 0 || size <= 0 {
+		return 0
+	}
+
+	return (int64(r)*int64(size) + 999) / 1000
+}
+
+// Algorithm identifies how a selection was made
+type Algorithm int
+
+const (
+	// Manual selections only contain the coins to include
+	Manual Algorithm = iota
+	// BranchAndBound searches a changeless selection
+	BranchAndBound
+	// Knapsack approximates the smallest selection above the target
+	Knapsack
+	// SingleRandomDraw picks random coins until the target is reached
+	SingleRandomDraw
+)
+
+// String returns the name of the algorithm
+func (a Algorithm) String() string {
+
+	switch a {
+	case Manual:
+		return "manual"
+	case BranchAndBound:
+		return "bnb"
+	case Knapsack:
+		return "knapsack"
+	case SingleRandomDraw:
+		return "srd"
+	}
+
+	return "unknown"
+}
+
+// Coin is an unspent output with the size in vbytes of the input spending
+// it
+type Coin struct {
+	OutPoint  transaction.OutPoint
+	Value     int64
+	PkScript  []byte
+	InputSize int
+}
+
+// NewCoin returns the coin of a standard output, its input size is derived
+// from the script type
+func NewCoin(op transaction.OutPoint, value int64, pkScript []byte) (*Coin, error) {
+
+	size, err := InputSize(pkScript)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCoinWithSize(op, value, pkScript, size), nil
+}
+
+// NewCoinWithSize returns the coin of an output spent by an input of
+// inputSize vbytes, for scripts whose spend size is not implied by the
+// output script such as P2SH and P2WSH
+func NewCoinWithSize(op transaction.OutPoint, value int64, pkScript []byte, inputSize int) *Coin {
+	return &Coin{OutPoint: op, Value: value, PkScript: pkScript, InputSize: inputSize}
+}
+
+// InputSize returns the spend size in vbytes of a standard single key
+// output, P2SH and P2WSH outputs depend on the script they commit to and
+// return ErrUnknownInputType
+func InputSize(pkScript []byte) (int, error) {
+
+	switch {
+	case script.Classify(pkScript) == script.PubKeyHash:
+		return P2PKHInputSize, nil
+	case script.IsPayToTaproot(pkScript):
+		return P2TRInputSize, nil
+	}
+
+	if version, program, ok := script.ExtractWitnessProgram(pkScript); ok && version == 0 && len(program) == 20 {
+		return P2WPKHInputSize, nil
+	}
+
+	return 0, ErrUnknownInputType
+}
+
+// ScriptSigInputSize returns the spend size in vbytes of a non witness
+// input whose scriptSig is scriptSigSize bytes long
+func ScriptSigInputSize(scriptSigSize int) int {
+
+	// outpoint, script length, script and sequence
+	return 32 + 4 + transaction.VarIntSerializeSize(uint64(scriptSigSize)) + scriptSigSize + 4
+}
+
+// WitnessInputSize returns the spend size in vbytes of a native segwit
+// input whose serialized witness, including the item count, is
+// witnessSize bytes long, such as the maximum witness size of a
+// miniscript
+func WitnessInputSize(witnessSize int) int {
+	return ScriptSigInputSize(0) + (witnessSize+3)/4
+}
+
+// NestedWitnessInputSize returns the spend size in vbytes of a witness
+// program nested in P2SH, the redeem script is 22 bytes long for P2WPKH and
+// 34 for P2WSH
+func NestedWitnessInputSize(redeemScriptSize, witnessSize int) int {
+
+	// the scriptSig is the push of the redeem script
+	return ScriptSigInputSize(1+redeemScriptSize) + (witnessSize+3)/4
+}
+
+// Params holds the fee rates and the sizes used to evaluate selections
+type Params struct {
+	// FeeRate is the rate paid by the transaction
+	FeeRate FeeRate
+	// LongTermFeeRate is the rate expected to spend the coins later, it
+	// drives the waste metric
+	LongTermFeeRate FeeRate
+	// ChangeOutputSize is the size in vbytes of the change output
+	ChangeOutputSize int
+	// ChangeSpendSize is the size in vbytes of the input spending change
+	ChangeSpendSize int
+	// MinChange is the smallest change knapsack and single random draw
+	// try to leave
+	MinChange int64
+	// Rand is the source of the randomized algorithms
+	Rand *rand.Rand
+}
+
+// NewParams returns the parameters for a P2WPKH change at feeRate
+func NewParams(feeRate FeeRate) *Params {
+	return &Params{
+		FeeRate:          feeRate,
+		LongTermFeeRate:  DefaultLongTermFeeRate,
+		ChangeOutputSize: P2WPKHOutputSize,
+		ChangeSpendSize:  P2WPKHInputSize,
+		MinChange:        ChangeLower,
+		Rand:             rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// changeFee returns the fee paid for the change output
+func (p *Params) changeFee() int64 {
+	return p.FeeRate.Fee(p.ChangeOutputSize)
+}
+
+// costOfChange returns the cost of creating the change output and of
+// spending it later
+func (p *Params) costOfChange() int64 {
+	return p.changeFee() + p.LongTermFeeRate.Fee(p.ChangeSpendSize)
+}
+
+// minViableChange returns the smallest change worth creating, below it
+// the excess is left to the fee
+func (p *Params) minViableChange() int64 {
+
+	spendFee := p.LongTermFeeRate.Fee(p.ChangeSpendSize)
+	dust := DustRelayFeeRate.Fee(p.ChangeOutputSize + p.ChangeSpendSize)
+
+	if spendFee+1 > dust {
+		return spendFee + 1
+	}
+
+	return dust
+}
+
+// CoinControl lists the coins a selection must include or must not use
+type CoinControl struct {
+	Include []transaction.OutPoint
+	Exclude []transaction.OutPoint
+}
+
+// Selection is the result of a coin selection
+type Selection struct {
+	Coins     []*Coin
+	Algorithm Algorithm
+	// Change is the value of the change output, zero when the excess is
+	// too small and is left to the fee
+	Change int64
+	// Fee is the total fee paid by the transaction
+	Fee int64
+	// Waste is the cost of the selection compared to spending the same
+	// coins at the long term fee rate without change
+	Waste int64
+}
+
+// output is a coin with the fees of spending it now and later
+type output struct {
+	coin        *Coin
+	value       int64
+	fee         int64
+	longTermFee int64
+}
+
+func (p *Params) newOutput(c *Coin) *output {
+
+	fee := p.FeeRate.Fee(c.InputSize)
+
+	return &output{
+		coin:        c,
+		value:       c.Value - fee,
+		fee:         fee,
+		longTermFee: p.LongTermFeeRate.Fee(c.InputSize),
+	}
+}
+
+// Select chooses coins paying outputs at the fee rate of p. Branch and
+// bound, knapsack and single random draw are tried and the selection with
+// the lowest waste is returned, ties going to the one spending more coins.
+func Select(coins []*Coin, outputs []*transaction.TxOut, p *Params, control *CoinControl) (*Selection, error) {
+
+	if control == nil {
+		control = &CoinControl{}
+	}
+
+	// the target covers the outputs and the fee of everything but inputs
+	size := 10 + transaction.VarIntSerializeSize(uint64(len(outputs)))
+	var amount int64
+	for _, out := range outputs {
+		amount += out.Value
+		size += out.SerializeSize()
+	}
+	target := amount + p.FeeRate.Fee(size)
+
+	preset, pool, err := control.split(coins, p)
+	if err != nil {
+		return nil, err
+	}
+
+	var presetValue int64
+	for _, o := range preset {
+		presetValue += o.value
+	}
+
+	remaining := target - presetValue
+	if len(preset) > 0 && remaining <= 0 {
+		return p.selection(preset, nil, Manual, target, amount), nil
+	}
+
+	var positive []*output
+	for _, o := range pool {
+		if o.value > 0 {
+			positive = append(positive, o)
+		}
+	}
+
+	var best *Selection
+	consider := func(algorithm Algorithm, selected []*output, err error) {
+
+		if err != nil {
+			return
+		}
+
+		s := p.selection(preset, selected, algorithm, target, amount)
+		if best == nil || s.Waste < best.Waste || (s.Waste == best.Waste && len(s.Coins) > len(best.Coins)) {
+			best = s
+		}
+	}
+
+	selected, err := selectBnB(positive, remaining, p.costOfChange())
+	consider(BranchAndBound, selected, err)
+
+	selected, err = selectKnapsack(positive, remaining+p.changeFee(), p.MinChange, p.Rand)
+	consider(Knapsack, selected, err)
+
+	selected, err = selectSRD(positive, remaining+p.changeFee()+p.MinChange, p.Rand)
+	consider(SingleRandomDraw, selected, err)
+
+	if best == nil {
+		return nil, ErrInsufficientFunds
+	}
+
+	return best, nil
+}
+
+// split returns the coins to include and the ones available to the
+// algorithms
+func (c *CoinControl) split(coins []*Coin, p *Params) ([]*output, []*output, error) {
+
+	excluded := make(map[transaction.OutPoint]bool)
+	for _, op := range c.Exclude {
+		excluded[op] = true
+	}
+
+	included := make(map[transaction.OutPoint]bool)
+	for _, op := range c.Include {
+		included[op] = true
+	}
+
+	var preset, pool []*output
+	for _, coin := range coins {
+		switch {
+		case included[coin.OutPoint]:
+			preset = append(preset, p.newOutput(coin))
+			delete(included, coin.OutPoint)
+		case !excluded[coin.OutPoint]:
+			pool = append(pool, p.newOutput(coin))
+		}
+	}
+
+	if len(included) != 0 {
+		return nil, nil, ErrUnknownCoin
+	}
+
+	return preset, pool, nil
+}
+
+// selection builds the result spending preset and selected coins towards
+// target, amount is the value paid to the outputs
+func (p *Params) selection(preset, selected []*output, algorithm Algorithm, target, amount int64) *Selection {
+
+	s := &Selection{Algorithm: algorithm}
+
+	all := append(append([]*output{}, preset...), selected...)
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].value > all[j].value
+	})
+
+	var value, effectiveValue int64
+	for _, o := range all {
+		s.Coins = append(s.Coins, o.coin)
+		value += o.coin.Value
+		effectiveValue += o.value
+		s.Waste += o.fee - o.longTermFee
+	}
+
+	excess := effectiveValue - target
+	if change := excess - p.changeFee(); change >= p.minViableChange() {
+		s.Change = change
+		s.Waste += p.costOfChange()
+	} else {
+		s.Waste += excess
+	}
+
+	// the fee is whatever the inputs leave once outputs and change are paid
+	s.Fee = value - amount - s.Change
+
+	return s
+}
@@ -0,0 +1,188 @@
+package coinselect
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/giogam/Gopher-Wallet/wallet/script"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+func p2wpkh(b byte) []byte {
+
+	s := append([]byte{script.Op0, 20}, make([]byte, 20)...)
+	s[2] = b
+
+	return s
+}
+
+func newCoins(t *testing.T, values ...int64) []*Coin {
+
+	var coins []*Coin
+	for i, v := range values {
+		hash := transaction.DoubleHashH([]byte{byte(i)})
+		c, err := NewCoin(*transaction.NewOutPoint(&hash, 0), v, p2wpkh(byte(i)))
+		assert.NoError(t, err)
+		coins = append(coins, c)
+	}
+
+	return coins
+}
+
+func newParams(feeRate FeeRate) *Params {
+
+	p := NewParams(feeRate)
+	p.Rand = rand.New(rand.NewSource(1))
+
+	return p
+}
+
+func TestFeeRate(t *testing.T) {
+	assert.Equal(t, int64(0), FeeRate(0).Fee(100))
+	assert.Equal(t, int64(1), FeeRate(1).Fee(1))
+	assert.Equal(t, int64(680), FeeRate(10000).Fee(68))
+	assert.Equal(t, int64(58), FeeRate(1000).Fee(58))
+	assert.Equal(t, int64(145), FeeRate(2500).Fee(58))
+}
+
+func TestInputSize(t *testing.T) {
+	hash := make([]byte, 20)
+	p2pkh, _ := script.PayToPubKeyHash(hash)
+	p2sh := append(append([]byte{script.OpHash160, 20}, hash...), script.OpEqual)
+	p2tr, _ := script.PayToTaproot(make([]byte, 32))
+	p2wsh := append([]byte{script.Op0, 32}, make([]byte, 32)...)
+
+	tests := []struct {
+		pkScript []byte
+		size     int
+		err      error
+	}{
+		{p2pkh, P2PKHInputSize, nil},
+		{p2sh, 0, ErrUnknownInputType},
+		{p2wpkh(0), P2WPKHInputSize, nil},
+		{p2tr, P2TRInputSize, nil},
+		{p2wsh, 0, ErrUnknownInputType},
+		{[]byte{script.OpReturn}, 0, ErrUnknownInputType},
+	}
+
+	for _, test := range tests {
+		size, err := InputSize(test.pkScript)
+		assert.Equal(t, test.err, err)
+		assert.Equal(t, test.size, size)
+	}
+}
+
+func TestWitnessInputSize(t *testing.T) {
+	// one signature and a compressed key
+	assert.Equal(t, P2WPKHInputSize, WitnessInputSize(1+73+34))
+	// one schnorr signature
+	assert.Equal(t, P2TRInputSize, WitnessInputSize(1+65))
+	assert.Equal(t, 42, WitnessInputSize(1))
+
+	// nested P2WPKH
+	assert.Equal(t, P2SHP2WPKHInputSize, NestedWitnessInputSize(22, 1+73+34))
+	// one signature and a compressed key
+	assert.Equal(t, P2PKHInputSize+1, ScriptSigInputSize(1+73+1+33))
+}
+
+func TestNewCoin(t *testing.T) {
+	op := transaction.OutPoint{Index: 1}
+	p2sh := append(append([]byte{script.OpHash160, 20}, make([]byte, 20)...), script.OpEqual)
+
+	_, err := NewCoin(op, 1000, p2sh)
+	assert.Equal(t, ErrUnknownInputType, err)
+
+	c := NewCoinWithSize(op, 1000, p2sh, P2SHP2WPKHInputSize)
+	assert.Equal(t, P2SHP2WPKHInputSize, c.InputSize)
+	assert.Equal(t, op, c.OutPoint)
+
+	c, err = NewCoin(op, 1000, p2wpkh(0))
+	assert.NoError(t, err)
+	assert.Equal(t, P2WPKHInputSize, c.InputSize)
+}
+
+func TestSelect(t *testing.T) {
+	coins := newCoins(t, 100000, 50000, 20000)
+	p := newParams(10000)
+
+	// 42 vbytes without inputs and 68 for each input at 10 sat/vB, the two
+	// smaller coins pay the output exactly
+	outputs := []*transaction.TxOut{transaction.NewTxOut(68220, p2wpkh(0xff))}
+
+	s, err := Select(coins, outputs, p, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, BranchAndBound, s.Algorithm)
+	assert.Equal(t, []*Coin{coins[1], coins[2]}, s.Coins)
+	assert.Equal(t, int64(0), s.Change)
+	assert.Equal(t, int64(420+2*680), s.Fee)
+	assert.Equal(t, int64(0), s.Waste)
+
+	// without the smallest coin a change is needed, its creation and
+	// future spend are the waste
+	control := &CoinControl{Exclude: []transaction.OutPoint{coins[2].OutPoint}}
+	s, err = Select(coins, outputs, p, control)
+	assert.NoError(t, err)
+	assert.NotEqual(t, BranchAndBound, s.Algorithm)
+	assert.NotContains(t, s.Coins, coins[2])
+	assert.Equal(t, int64(310+680), s.Waste)
+	assert.Equal(t, int64(420+310+680*len(s.Coins)), s.Fee)
+
+	var in int64
+	for _, c := range s.Coins {
+		in += c.Value
+	}
+	assert.Equal(t, in-68220-s.Fee, s.Change)
+
+	// an included coin covering the target is enough
+	control = &CoinControl{Include: []transaction.OutPoint{coins[0].OutPoint}}
+	s, err = Select(coins, outputs, p, control)
+	assert.NoError(t, err)
+	assert.Equal(t, Manual, s.Algorithm)
+	assert.Equal(t, []*Coin{coins[0]}, s.Coins)
+	assert.Equal(t, int64(100000-68220-420-680-310), s.Change)
+	assert.Equal(t, int64(420+680+310), s.Fee)
+
+	// an included coin is completed by the algorithms
+	control = &CoinControl{Include: []transaction.OutPoint{coins[2].OutPoint}}
+	s, err = Select(coins, outputs, p, control)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Coin{coins[1], coins[2]}, s.Coins)
+
+	unknown := transaction.NewOutPoint(&transaction.Hash{}, 7)
+	_, err = Select(coins, outputs, p, &CoinControl{Include: []transaction.OutPoint{*unknown}})
+	assert.Equal(t, ErrUnknownCoin, err)
+
+	_, err = Select(coins, []*transaction.TxOut{transaction.NewTxOut(170000, p2wpkh(0xff))}, p, nil)
+	assert.Equal(t, ErrInsufficientFunds, err)
+}
+
+func TestSelectWaste(t *testing.T) {
+	coins := newCoins(t, 100000, 50000, 20000)
+	outputs := []*transaction.TxOut{transaction.NewTxOut(10000, p2wpkh(0xff))}
+
+	// above the long term rate every extra input is waste
+	p := newParams(20000)
+	s, err := Select(coins, outputs, p, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1360-680)*int64(len(s.Coins))+p.costOfChange(), s.Waste)
+
+	// below it spending inputs now saves fees later
+	p = newParams(1000)
+	s, err = Select(coins, outputs, p, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(68-680)*int64(len(s.Coins))+p.costOfChange(), s.Waste)
+	assert.Negative(t, s.Waste)
+}
+
+func TestSelectDust(t *testing.T) {
+	coins := newCoins(t, 20000)
+	p := newParams(1000)
+
+	// an excess below the dust threshold is left to the fee
+	outputs := []*transaction.TxOut{transaction.NewTxOut(20000-42-68-31-100, p2wpkh(0xff))}
+	s, err := Select(coins, outputs, p, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), s.Change)
+	assert.Equal(t, int64(42+68+31+100), s.Fee)
+}
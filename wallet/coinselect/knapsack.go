@@ -0,0 +1,120 @@
+package coinselect
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// knapsackIterations is the number of random passes looking for the best
+// subset
+const knapsackIterations = 1000
+
+// selectKnapsack returns a coin matching target exactly when one exists,
+// otherwise it approximates the smallest subset reaching target, or
+// target+minChange when that leaves a usable change, and falls back to the
+// smallest coin larger than both
+func selectKnapsack(pool []*output, target, minChange int64, r *rand.Rand) ([]*output, error) {
+
+	pool = append([]*output{}, pool...)
+	r.Shuffle(len(pool), func(i, j int) {
+		pool[i], pool[j] = pool[j], pool[i]
+	})
+
+	var lowestLarger *output
+	var applicable []*output
+	var totalLower int64
+
+	for _, o := range pool {
+		switch {
+		case o.value == target:
+			return []*output{o}, nil
+		case o.value < target+minChange:
+			applicable = append(applicable, o)
+			totalLower += o.value
+		case lowestLarger == nil || o.value < lowestLarger.value:
+			lowestLarger = o
+		}
+	}
+
+	if totalLower == target {
+		return applicable, nil
+	}
+
+	if totalLower < target {
+		if lowestLarger == nil {
+			return nil, ErrNoSolution
+		}
+		return []*output{lowestLarger}, nil
+	}
+
+	sort.SliceStable(applicable, func(i, j int) bool {
+		return applicable[i].value > applicable[j].value
+	})
+
+	included, best := approximateBestSubset(applicable, totalLower, target, r)
+	if best != target && totalLower >= target+minChange {
+		included, best = approximateBestSubset(applicable, totalLower, target+minChange, r)
+	}
+
+	// the larger coin wins when the subset leaves no usable change or is
+	// not closer to the target
+	if lowestLarger != nil && ((best != target && best < target+minChange) || lowestLarger.value <= best) {
+		return []*output{lowestLarger}, nil
+	}
+
+	var selected []*output
+	for i, o := range applicable {
+		if included[i] {
+			selected = append(selected, o)
+		}
+	}
+
+	return selected, nil
+}
+
+// approximateBestSubset runs random passes over pool, each adding coins
+// until target is reached, and returns the subset closest to target along
+// with its value
+func approximateBestSubset(pool []*output, totalLower, target int64, r *rand.Rand) ([]bool, int64) {
+
+	best := make([]bool, len(pool))
+	for i := range best {
+		best[i] = true
+	}
+	bestValue := totalLower
+
+	included := make([]bool, len(pool))
+	for rep := 0; rep < knapsackIterations && bestValue != target; rep++ {
+		for i := range included {
+			included[i] = false
+		}
+
+		var total int64
+		reached := false
+
+		// the first pass picks coins randomly, the second completes the
+		// subset with the coins left out
+		for pass := 0; pass < 2 && !reached; pass++ {
+			for i, o := range pool {
+				if pass == 0 && r.Intn(2) == 0 || pass == 1 && included[i] {
+					continue
+				}
+
+				total += o.value
+				included[i] = true
+
+				if total >= target {
+					reached = true
+					if total < bestValue {
+						bestValue = total
+						copy(best, included)
+					}
+					total -= o.value
+					included[i] = false
+				}
+			}
+		}
+	}
+
+	return best, bestValue
+}
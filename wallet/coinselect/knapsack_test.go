@@ -0,0 +1,68 @@
+package coinselect
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sum(outputs []*output) int64 {
+
+	var total int64
+	for _, o := range outputs {
+		total += o.value
+	}
+
+	return total
+}
+
+func TestSelectKnapsack(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	tests := []struct {
+		pool      []int64
+		target    int64
+		minChange int64
+		expected  int64
+	}{
+		// a coin matches exactly
+		{[]int64{1 * cent, 2 * cent, 5 * cent}, 2 * cent, cent, 2 * cent},
+		// the smaller coins add up to the target
+		{[]int64{1 * cent, 2 * cent, 50 * cent}, 3 * cent, cent, 3 * cent},
+		// the smaller coins are not enough
+		{[]int64{1 * cent, 2 * cent, 50 * cent, 20 * cent}, 4 * cent, cent, 20 * cent},
+		// a subset matches exactly
+		{[]int64{5 * cent, 9 * cent, 10 * cent, 30 * cent}, 14 * cent, 0, 14 * cent},
+		// the subsets leave too little change so the larger coin is used
+		{[]int64{6 * cent, 7 * cent, 8 * cent, 30 * cent}, 13*cent + cent/2, 10 * cent, 30 * cent},
+		// the closest subset leaving the minimum change
+		{[]int64{6 * cent, 7 * cent, 8 * cent, 100 * cent}, 13*cent + cent/2, 1 * cent, 15 * cent},
+	}
+
+	for _, test := range tests {
+		selected, err := selectKnapsack(freeOutputs(test.pool...), test.target, test.minChange, r)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, sum(selected), test.pool)
+	}
+
+	_, err := selectKnapsack(freeOutputs(1*cent, 2*cent), 4*cent, cent, r)
+	assert.Equal(t, ErrNoSolution, err)
+}
+
+func TestSelectSRD(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	pool := freeOutputs(1*cent, 2*cent, 3*cent, 4*cent, 5*cent)
+
+	for i := 0; i < 100; i++ {
+		selected, err := selectSRD(pool, 6*cent, r)
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, sum(selected), int64(6*cent))
+
+		// the last coin drawn was needed
+		assert.Less(t, sum(selected[:len(selected)-1]), int64(6*cent))
+	}
+
+	_, err := selectSRD(pool, 16*cent, r)
+	assert.Equal(t, ErrNoSolution, err)
+}
@@ -0,0 +1,21 @@
+package coinselect
+
+import "math/rand"
+
+// selectSRD adds coins in random order until target is reached
+func selectSRD(pool []*output, target int64, r *rand.Rand) ([]*output, error) {
+
+	var selected []*output
+	var value int64
+
+	for _, i := range r.Perm(len(pool)) {
+		selected = append(selected, pool[i])
+		value += pool[i].value
+
+		if value >= target {
+			return selected, nil
+		}
+	}
+
+	return nil, ErrNoSolution
+}
@@ -0,0 +1,39 @@
+package descriptor
+
+import (
+	"fmt"
+
+	"github.com/giogam/Gopher-Wallet/wallet/account"
+	"github.com/giogam/Gopher-Wallet/wallet/address"
+)
+
+// accountFormats are the descriptors of the addresses of each account type
+var accountFormats = map[address.Type]string{
+	address.P2PKH:  "pkh(%s)",
+	address.P2SH:   "sh(wpkh(%s))",
+	address.P2WPKH: "wpkh(%s)",
+	address.P2TR:   "tr(%s)",
+}
+
+// FromAccount returns the descriptor of the addresses of chain of a, it
+// holds the private account key unless the account is watch only
+func FromAccount(a *account.Account, chain uint32) (*Descriptor, error) {
+
+	if chain != account.External && chain != account.Internal {
+		return nil, account.ErrInvalidChain
+	}
+
+	format, ok := accountFormats[a.Type]
+	if !ok {
+		return nil, account.ErrUnsupportedType
+	}
+
+	key := &Key{
+		Origin:   &KeyOrigin{Fingerprint: a.Fingerprint, Path: a.Path},
+		Extended: a.Key,
+		Path:     []uint32{chain},
+		Wildcard: UnhardenedWildcard,
+	}
+
+	return Parse(fmt.Sprintf(format, key.String()), a.Net)
+}
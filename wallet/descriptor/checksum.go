@@ -0,0 +1,108 @@
+package descriptor
+
+import "strings"
+
+const (
+	// ChecksumLen is the number of characters of a descriptor checksum
+	ChecksumLen = 8
+
+	inputCharset    = "0123456789()[],'/*abcdefgh@:$%{}IJKLMNOPQRSTUVWXYZ&+-.;<=>?!^_|~ijklmnopqrstuvwxyzABCDEFGH`#\"\\ "
+	checksumCharset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+)
+
+var checksumGenerator = [5]uint64{0xf5dee51989, 0xa9fdca3312, 0x1bab10e32d, 0x3706b1677a, 0x644d626ffd}
+
+// Checksum returns the BIP380 checksum of a descriptor without its
+// checksum
+func Checksum(desc string) (string, error) {
+
+	var symbols, groups []uint64
+	for _, c := range desc {
+		pos := strings.IndexRune(inputCharset, c)
+		if pos < 0 {
+			return "", ErrInvalidCharacter
+		}
+
+		// the low 5 bits of each character and its group, one symbol for
+		// every three groups
+		symbols = append(symbols, uint64(pos&31))
+		groups = append(groups, uint64(pos>>5))
+		if len(groups) == 3 {
+			symbols = append(symbols, groups[0]*9+groups[1]*3+groups[2])
+			groups = groups[:0]
+		}
+	}
+
+	switch len(groups) {
+	case 1:
+		symbols = append(symbols, groups[0])
+	case 2:
+		symbols = append(symbols, groups[0]*3+groups[1])
+	}
+
+	symbols = append(symbols, make([]uint64, ChecksumLen)...)
+	c := polymod(symbols) ^ 1
+
+	checksum := make([]byte, ChecksumLen)
+	for i := range checksum {
+		checksum[i] = checksumCharset[(c>>(5*(7-i)))&31]
+	}
+
+	return string(checksum), nil
+}
+
+// AddChecksum returns desc followed by its checksum
+func AddChecksum(desc string) (string, error) {
+
+	checksum, err := Checksum(desc)
+	if err != nil {
+		return "", err
+	}
+
+	return desc + "#" + checksum, nil
+}
+
+// splitChecksum separates a descriptor from its checksum and verifies it,
+// the checksum is optional
+func splitChecksum(s string) (string, error) {
+
+	parts := strings.Split(s, "#")
+	switch len(parts) {
+	case 1:
+		return s, nil
+	case 2:
+	default:
+		return "", ErrInvalidChecksum
+	}
+
+	if len(parts[1]) != ChecksumLen {
+		return "", ErrInvalidChecksum
+	}
+
+	checksum, err := Checksum(parts[0])
+	if err != nil {
+		return "", err
+	}
+
+	if checksum != parts[1] {
+		return "", ErrInvalidChecksum
+	}
+
+	return parts[0], nil
+}
+
+func polymod(symbols []uint64) uint64 {
+
+	chk := uint64(1)
+	for _, value := range symbols {
+		top := chk >> 35
+		chk = (chk&0x7ffffffff)<<5 ^ value
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				chk ^= checksumGenerator[i]
+			}
+		}
+	}
+
+	return chk
+}
@@ -0,0 +1,52 @@
+package descriptor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecksum(t *testing.T) {
+	tests := []struct {
+		desc     string
+		checksum string
+	}{
+		{"raw(deadbeef)", "89f8spxm"},
+		{"sh(multi(2,[00000000/111'/222]xprvA1RpRA33e1JQ7ifknakTFpgNXPmW2YvmhqLQYMmrj4xJXXWYpDPS3xz7iAxn8L39njGVyuoseXzU6rcxFLJ8HFsTjSyQbLYnMpCqE2VbFWc,xprv9uPDJpEQgRQfDcW7BkF7eTya6RPxXeJCqCJGHuCJ4GiRVLzkTXBAJMu2qaMWPrS7AANYqdq6vcBcBUdJCVVFceUvJFjaPdGZ2y9WACViL4L/0))", "ggrsrxfy"},
+		{"sh(multi(2,[00000000/111'/222]xpub6ERApfZwUNrhLCkDtcHTcxd75RbzS1ed54G1LkBUHQVHQKqhMkhgbmJbZRkrgZw4koxb5JaHWkY4ALHY2grBGRjaDMzQLcgJvLJuZZvRcEL,xpub68NZiKmJWnxxS6aaHmn81bvJeTESw724CRDs6HbuccFQN9Ku14VQrADWgqbhhTHBaohPX4CjNLf9fq9MYo6oDaPPLPxSb7gwQN3ih19Zm4Y/0))", "tjg09x5t"},
+	}
+
+	for _, test := range tests {
+		checksum, err := Checksum(test.desc)
+		assert.NoError(t, err)
+		assert.Equal(t, test.checksum, checksum)
+
+		desc, err := AddChecksum(test.desc)
+		assert.NoError(t, err)
+		assert.Equal(t, test.desc+"#"+test.checksum, desc)
+
+		_, err = Parse(desc, nil)
+		assert.NoError(t, err)
+	}
+
+	_, err := Checksum("raw(deadbeef)é")
+	assert.Equal(t, ErrInvalidCharacter, err)
+}
+
+func TestChecksumInvalid(t *testing.T) {
+	tests := []string{
+		"raw(deadbeef)#",
+		"raw(deadbeef)#89f8spxmx",
+		"raw(deadbeef)#89f8spx",
+		"raw(deadbeef)#89f8spxn",
+		"raw(Deadbeef)#89f8spxm",
+		"raw(deedbeef)#89f8spxm",
+		"raw(deadbeef)##9f8spxm",
+		"raw(deadbeef)#89f8spxm#89f8spxm",
+	}
+
+	for _, test := range tests {
+		_, err := Parse(test, nil)
+		assert.Equal(t, ErrInvalidChecksum, err, test)
+	}
+}
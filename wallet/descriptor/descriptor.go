@@ -0,0 +1,804 @@
+package descriptor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/giogam/Gopher-Wallet/wallet/address"
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/script"
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+)
+
+const (
+	// maxBareMultiKeys is the number of keys allowed in a bare multisig
+	maxBareMultiKeys = 3
+	// maxMultiKeys is the number of keys allowed in a P2SH multisig
+	maxMultiKeys = 16
+	// maxMultiAKeys is the number of keys allowed in multi_a
+	maxMultiAKeys = 999
+	// maxTreeDepth is the depth limit of a taproot script tree
+	maxTreeDepth = 128
+	// maxWitnessScriptSize is the standard size limit of witness scripts
+	maxWitnessScriptSize = 3600
+)
+
+var (
+	// ErrInvalidDescriptor is returned when a descriptor cannot be parsed
+	ErrInvalidDescriptor = errors.New("descriptor: invalid descriptor")
+	// ErrInvalidCharacter is returned when a descriptor contains characters
+	// outside the checksum charset
+	ErrInvalidCharacter = errors.New("descriptor: invalid character")
+	// ErrInvalidChecksum is returned when a checksum is malformed or does
+	// not match
+	ErrInvalidChecksum = errors.New("descriptor: invalid checksum")
+	// ErrInvalidContext is returned when an expression is not allowed where
+	// it appears
+	ErrInvalidContext = errors.New("descriptor: expression not allowed in this context")
+	// ErrInvalidKey is returned when a key expression cannot be parsed
+	ErrInvalidKey = errors.New("descriptor: invalid key")
+	// ErrInvalidKeyOrigin is returned when a key origin cannot be parsed
+	ErrInvalidKeyOrigin = errors.New("descriptor: invalid key origin")
+	// ErrUncompressedKey is returned for uncompressed keys in witness
+	// scripts
+	ErrUncompressedKey = errors.New("descriptor: uncompressed key in witness script")
+	// ErrInvalidThreshold is returned when a multisig threshold is out of
+	// range
+	ErrInvalidThreshold = errors.New("descriptor: invalid multisig threshold")
+	// ErrTooManyKeys is returned when a multisig has more keys than its
+	// context allows
+	ErrTooManyKeys = errors.New("descriptor: too many keys")
+	// ErrScriptTooLarge is returned when a script exceeds its size limit
+	ErrScriptTooLarge = errors.New("descriptor: script too large")
+	// ErrHardenedWildcard is returned when neutering a key with a hardened
+	// wildcard
+	ErrHardenedWildcard = errors.New("descriptor: hardened wildcard requires the private key")
+	// ErrNoAddress is returned when a descriptor does not expand to exactly
+	// one address
+	ErrNoAddress = errors.New("descriptor: no single address")
+)
+
+// Type is the script expression at the root of a descriptor
+type Type int
+
+const (
+	// Pk pays to a public key
+	Pk Type = iota + 1
+	// Pkh pays to a public key hash
+	Pkh
+	// Wpkh pays to a witness public key hash
+	Wpkh
+	// Sh pays to the hash of its script
+	Sh
+	// Wsh pays to the witness hash of its script
+	Wsh
+	// Tr pays to a taproot output key
+	Tr
+	// Multi is a multisig with keys in order
+	Multi
+	// SortedMulti is a multisig with keys sorted
+	SortedMulti
+	// MultiA is a tapscript multisig with keys in order
+	MultiA
+	// SortedMultiA is a tapscript multisig with keys sorted
+	SortedMultiA
+	// Addr pays to an address
+	Addr
+	// Raw is a hex encoded script
+	Raw
+	// Combo pays to a key in every standard way
+	Combo
+)
+
+var typeNames = map[Type]string{
+	Pk:           "pk",
+	Pkh:          "pkh",
+	Wpkh:         "wpkh",
+	Sh:           "sh",
+	Wsh:          "wsh",
+	Tr:           "tr",
+	Multi:        "multi",
+	SortedMulti:  "sortedmulti",
+	MultiA:       "multi_a",
+	SortedMultiA: "sortedmulti_a",
+	Addr:         "addr",
+	Raw:          "raw",
+	Combo:        "combo",
+}
+
+// String returns the name of the expression
+func (t Type) String() string {
+
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+
+	return "unknown"
+}
+
+// context is where an expression appears
+type context int
+
+const (
+	ctxTop context = iota
+	ctxSh
+	ctxWsh
+	ctxTr
+	ctxTapscript
+)
+
+// Descriptor is an output script descriptor. Keys holds the keys of key
+// and multisig expressions, Sub the script of sh and wsh, Tree the script
+// tree of tr, Addr and Script the arguments of addr and raw.
+type Descriptor struct {
+	Type      Type
+	Keys      []*Key
+	Threshold int
+	Sub       *Descriptor
+	Tree      *TapTree
+	Addr      *address.Address
+	Script    []byte
+	Net       *address.Network
+}
+
+// TapTree is a taproot script tree, either a leaf script or a branch
+type TapTree struct {
+	Leaf        *Descriptor
+	Left, Right *TapTree
+}
+
+// Output is an output script of a descriptor with the scripts needed to
+// spend it, Address is nil for scripts without address
+type Output struct {
+	PkScript      []byte
+	RedeemScript  []byte
+	WitnessScript []byte
+	Address       *address.Address
+}
+
+// Parse parses a descriptor, the checksum is verified when present.
+// Addresses are decoded and encoded on net.
+func Parse(desc string, net *address.Network) (*Descriptor, error) {
+
+	s, err := splitChecksum(desc)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := parse(s, ctxTop, net)
+	if err != nil {
+		return nil, err
+	}
+	d.Net = net
+
+	return d, nil
+}
+
+// parse parses a script expression in ctx
+func parse(s string, ctx context, net *address.Network) (*Descriptor, error) {
+
+	name, args, err := call(s)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Descriptor{}
+	for t, n := range typeNames {
+		if n == name {
+			d.Type = t
+		}
+	}
+
+	switch d.Type {
+	case Pk, Pkh:
+		if d.Type == Pkh && ctx == ctxTapscript {
+			return nil, ErrInvalidContext
+		}
+		if len(args) != 1 {
+			return nil, ErrInvalidDescriptor
+		}
+		if err := d.parseKeys(args, ctx); err != nil {
+			return nil, err
+		}
+
+	case Wpkh:
+		if ctx != ctxTop && ctx != ctxSh {
+			return nil, ErrInvalidContext
+		}
+		if len(args) != 1 {
+			return nil, ErrInvalidDescriptor
+		}
+		if err := d.parseKeys(args, ctxWsh); err != nil {
+			return nil, err
+		}
+
+	case Combo:
+		if ctx != ctxTop {
+			return nil, ErrInvalidContext
+		}
+		if len(args) != 1 {
+			return nil, ErrInvalidDescriptor
+		}
+		if err := d.parseKeys(args, ctx); err != nil {
+			return nil, err
+		}
+
+	case Sh, Wsh:
+		if (d.Type == Sh && ctx != ctxTop) || (d.Type == Wsh && ctx != ctxTop && ctx != ctxSh) {
+			return nil, ErrInvalidContext
+		}
+		if len(args) != 1 {
+			return nil, ErrInvalidDescriptor
+		}
+		sub := ctxSh
+		if d.Type == Wsh {
+			sub = ctxWsh
+		}
+		if d.Sub, err = parse(args[0], sub, net); err != nil {
+			return nil, err
+		}
+		if err := d.checkSub(); err != nil {
+			return nil, err
+		}
+
+	case Tr:
+		if ctx != ctxTop {
+			return nil, ErrInvalidContext
+		}
+		if len(args) != 1 && len(args) != 2 {
+			return nil, ErrInvalidDescriptor
+		}
+		if err := d.parseKeys(args[:1], ctxTr); err != nil {
+			return nil, err
+		}
+		if len(args) == 2 {
+			if d.Tree, err = parseTree(args[1], 0, net); err != nil {
+				return nil, err
+			}
+		}
+
+	case Multi, SortedMulti, MultiA, SortedMultiA:
+		tapscript := d.Type == MultiA || d.Type == SortedMultiA
+		if tapscript != (ctx == ctxTapscript) {
+			return nil, ErrInvalidContext
+		}
+		if err := d.parseMulti(args, ctx); err != nil {
+			return nil, err
+		}
+
+	case Addr:
+		if ctx != ctxTop {
+			return nil, ErrInvalidContext
+		}
+		if len(args) != 1 {
+			return nil, ErrInvalidDescriptor
+		}
+		if d.Addr, err = address.Decode(args[0], net); err != nil {
+			return nil, err
+		}
+
+	case Raw:
+		if ctx != ctxTop {
+			return nil, ErrInvalidContext
+		}
+		if len(args) != 1 {
+			return nil, ErrInvalidDescriptor
+		}
+		if d.Script, err = hex.DecodeString(args[0]); err != nil {
+			return nil, ErrInvalidDescriptor
+		}
+
+	default:
+		return nil, ErrInvalidDescriptor
+	}
+
+	return d, nil
+}
+
+// call splits an expression name(arg,...) into its name and arguments
+func call(s string) (string, []string, error) {
+
+	open := strings.IndexByte(s, '(')
+	if open <= 0 || !strings.HasSuffix(s, ")") {
+		return "", nil, ErrInvalidDescriptor
+	}
+
+	args, err := split(s[open+1 : len(s)-1])
+	if err != nil {
+		return "", nil, err
+	}
+
+	return s[:open], args, nil
+}
+
+// split splits a list at its top level commas
+func split(s string) ([]string, error) {
+
+	var args []string
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(', '{', '[':
+			depth++
+		case ')', '}', ']':
+			depth--
+			if depth < 0 {
+				return nil, ErrInvalidDescriptor
+			}
+		case ',':
+			if depth == 0 {
+				args = append(args, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	if depth != 0 {
+		return nil, ErrInvalidDescriptor
+	}
+
+	return append(args, s[start:]), nil
+}
+
+// parseKeys parses key expressions in ctx
+func (d *Descriptor) parseKeys(args []string, ctx context) error {
+
+	for _, arg := range args {
+		k, err := parseKey(arg, ctx)
+		if err != nil {
+			return err
+		}
+		d.Keys = append(d.Keys, k)
+	}
+
+	return nil
+}
+
+// parseMulti parses the threshold and the keys of a multisig expression
+func (d *Descriptor) parseMulti(args []string, ctx context) error {
+
+	if len(args) < 2 {
+		return ErrInvalidDescriptor
+	}
+
+	threshold, err := strconv.Atoi(args[0])
+	if err != nil || threshold < 1 || threshold > len(args)-1 {
+		return ErrInvalidThreshold
+	}
+	d.Threshold = threshold
+
+	limit := maxMultiKeys
+	switch ctx {
+	case ctxTop:
+		limit = maxBareMultiKeys
+	case ctxWsh:
+		limit = script.MaxPubKeysPerMultiSig
+	case ctxTapscript:
+		limit = maxMultiAKeys
+	}
+	if len(args)-1 > limit {
+		return ErrTooManyKeys
+	}
+
+	return d.parseKeys(args[1:], ctx)
+}
+
+// checkSub checks the script of sh and wsh expressions against their size
+// limits
+func (d *Descriptor) checkSub() error {
+
+	if d.Sub.Type == Tr || d.Sub.Type == Combo || d.Sub.Type == Addr || d.Sub.Type == Raw {
+		return ErrInvalidContext
+	}
+
+	limit := script.MaxElementSize
+	if d.Type == Wsh {
+		limit = maxWitnessScriptSize
+	}
+
+	// keys have the same size at every index
+	sub, err := d.Sub.script(0)
+	if err != nil {
+		return err
+	}
+	if len(sub) > limit {
+		return ErrScriptTooLarge
+	}
+
+	return nil
+}
+
+// parseTree parses a script tree, branches are written {left,right}
+func parseTree(s string, depth int, net *address.Network) (*TapTree, error) {
+
+	if depth > maxTreeDepth {
+		return nil, ErrInvalidDescriptor
+	}
+
+	if !strings.HasPrefix(s, "{") {
+		leaf, err := parse(s, ctxTapscript, net)
+		if err != nil {
+			return nil, err
+		}
+		return &TapTree{Leaf: leaf}, nil
+	}
+
+	if !strings.HasSuffix(s, "}") {
+		return nil, ErrInvalidDescriptor
+	}
+
+	children, err := split(s[1 : len(s)-1])
+	if err != nil || len(children) != 2 {
+		return nil, ErrInvalidDescriptor
+	}
+
+	t := &TapTree{}
+	if t.Left, err = parseTree(children[0], depth+1, net); err != nil {
+		return nil, err
+	}
+	if t.Right, err = parseTree(children[1], depth+1, net); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// IsRange reports whether the descriptor has keys ending with a wildcard
+func (d *Descriptor) IsRange() bool {
+
+	for _, k := range d.Keys {
+		if k.IsRange() {
+			return true
+		}
+	}
+
+	if d.Sub != nil && d.Sub.IsRange() {
+		return true
+	}
+
+	return d.Tree != nil && d.Tree.isRange()
+}
+
+func (t *TapTree) isRange() bool {
+
+	if t.Leaf != nil {
+		return t.Leaf.IsRange()
+	}
+
+	return t.Left.isRange() || t.Right.isRange()
+}
+
+// pubKeys returns the serialized keys at index, sorted for sorted
+// multisigs
+func (d *Descriptor) pubKeys(index uint32) ([][]byte, error) {
+
+	pubs := make([][]byte, len(d.Keys))
+	for i, k := range d.Keys {
+		pub, err := k.Derive(index)
+		if err != nil {
+			return nil, err
+		}
+		pubs[i] = pub
+	}
+
+	if d.Type == SortedMulti || d.Type == SortedMultiA {
+		sort.Slice(pubs, func(i, j int) bool {
+			return bytes.Compare(pubs[i], pubs[j]) < 0
+		})
+	}
+
+	return pubs, nil
+}
+
+// script returns the script of the expression at index, combo expands to
+// several scripts and has none
+func (d *Descriptor) script(index uint32) ([]byte, error) {
+
+	pubs, err := d.pubKeys(index)
+	if err != nil {
+		return nil, err
+	}
+
+	switch d.Type {
+	case Pk:
+		return script.NewBuilder().AddData(pubs[0]).AddOp(script.OpCheckSig).Script()
+
+	case Pkh:
+		return script.PayToPubKeyHash(hdwallet.Hash160(pubs[0]))
+
+	case Wpkh:
+		return script.PayToWitnessPubKeyHash(hdwallet.Hash160(pubs[0]))
+
+	case Sh:
+		sub, err := d.Sub.script(index)
+		if err != nil {
+			return nil, err
+		}
+		return script.PayToScriptHash(hdwallet.Hash160(sub))
+
+	case Wsh:
+		sub, err := d.Sub.script(index)
+		if err != nil {
+			return nil, err
+		}
+		hash := sha256.Sum256(sub)
+		return script.PayToWitnessScriptHash(hash[:])
+
+	case Tr:
+		internal, err := secp256k1.ParseXOnlyPubKey(pubs[0])
+		if err != nil {
+			return nil, err
+		}
+		var merkleRoot []byte
+		if d.Tree != nil {
+			if merkleRoot, err = d.Tree.hash(index); err != nil {
+				return nil, err
+			}
+		}
+		outputKey, err := hdwallet.TaprootTweakPubKey(internal, merkleRoot)
+		if err != nil {
+			return nil, err
+		}
+		return script.PayToTaproot(outputKey.SerializeXOnly())
+
+	case Multi, SortedMulti:
+		return script.MultiSigScript(d.Threshold, pubs)
+
+	case MultiA, SortedMultiA:
+		b := script.NewBuilder()
+		for i, pub := range pubs {
+			b.AddData(pub)
+			if i == 0 {
+				b.AddOp(script.OpCheckSig)
+			} else {
+				b.AddOp(script.OpCheckSigAdd)
+			}
+		}
+		return b.AddInt64(int64(d.Threshold)).AddOp(script.OpNumEqual).Script()
+
+	case Addr:
+		return script.PayToAddress(d.Addr)
+
+	case Raw:
+		return d.Script, nil
+	}
+
+	return nil, ErrInvalidDescriptor
+}
+
+// hash returns the merkle root of the tree at index
+func (t *TapTree) hash(index uint32) ([]byte, error) {
+
+	if t.Leaf != nil {
+		leaf, err := t.Leaf.script(index)
+		if err != nil {
+			return nil, err
+		}
+		return hdwallet.TapLeafHash(hdwallet.BaseLeafVersion, leaf), nil
+	}
+
+	left, err := t.Left.hash(index)
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := t.Right.hash(index)
+	if err != nil {
+		return nil, err
+	}
+
+	return hdwallet.TapBranchHash(left, right), nil
+}
+
+// Expand returns the outputs of the descriptor at index, one for every
+// descriptor but combo
+func (d *Descriptor) Expand(index uint32) ([]*Output, error) {
+
+	if d.Type != Combo {
+		out, err := d.output(index)
+		if err != nil {
+			return nil, err
+		}
+		return []*Output{out}, nil
+	}
+
+	// combo pays to the key, its hash and, for compressed keys, to its
+	// witness hash directly and nested
+	var outputs []*Output
+	types := []Type{Pk, Pkh}
+	if !d.Keys[0].Uncompressed() {
+		types = append(types, Wpkh, Sh)
+	}
+
+	for _, t := range types {
+		sub := &Descriptor{Type: t, Keys: d.Keys, Net: d.Net}
+		if t == Sh {
+			sub.Keys = nil
+			sub.Sub = &Descriptor{Type: Wpkh, Keys: d.Keys}
+		}
+		out, err := sub.output(index)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, out)
+	}
+
+	return outputs, nil
+}
+
+// output returns the output of a descriptor other than combo at index
+func (d *Descriptor) output(index uint32) (*Output, error) {
+
+	pkScript, err := d.script(index)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &Output{PkScript: pkScript}
+
+	switch d.Type {
+	case Sh:
+		if out.RedeemScript, err = d.Sub.script(index); err != nil {
+			return nil, err
+		}
+		if d.Sub.Type == Wsh {
+			if out.WitnessScript, err = d.Sub.Sub.script(index); err != nil {
+				return nil, err
+			}
+		}
+	case Wsh:
+		if out.WitnessScript, err = d.Sub.script(index); err != nil {
+			return nil, err
+		}
+	}
+
+	if addr, err := script.ExtractAddress(pkScript, d.Net); err == nil {
+		out.Address = addr
+	}
+
+	return out, nil
+}
+
+// Address returns the address of the descriptor at index
+func (d *Descriptor) Address(index uint32) (*address.Address, error) {
+
+	outputs, err := d.Expand(index)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(outputs) != 1 || outputs[0].Address == nil {
+		return nil, ErrNoAddress
+	}
+
+	return outputs[0].Address, nil
+}
+
+// IsPrivate reports whether the descriptor holds private keys
+func (d *Descriptor) IsPrivate() bool {
+
+	for _, k := range d.Keys {
+		if k.IsPrivate() {
+			return true
+		}
+	}
+
+	if d.Sub != nil && d.Sub.IsPrivate() {
+		return true
+	}
+
+	return d.Tree != nil && d.Tree.isPrivate()
+}
+
+func (t *TapTree) isPrivate() bool {
+
+	if t.Leaf != nil {
+		return t.Leaf.IsPrivate()
+	}
+
+	return t.Left.isPrivate() || t.Right.isPrivate()
+}
+
+// Neuter returns the descriptor with every private key replaced by its
+// public key, as imported by watch-only wallets
+func (d *Descriptor) Neuter() (*Descriptor, error) {
+
+	n := *d
+	n.Keys = make([]*Key, len(d.Keys))
+	for i, k := range d.Keys {
+		pub, err := k.Neuter()
+		if err != nil {
+			return nil, err
+		}
+		n.Keys[i] = pub
+	}
+
+	var err error
+	if d.Sub != nil {
+		if n.Sub, err = d.Sub.Neuter(); err != nil {
+			return nil, err
+		}
+	}
+
+	if d.Tree != nil {
+		if n.Tree, err = d.Tree.neuter(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &n, nil
+}
+
+func (t *TapTree) neuter() (*TapTree, error) {
+
+	if t.Leaf != nil {
+		leaf, err := t.Leaf.Neuter()
+		if err != nil {
+			return nil, err
+		}
+		return &TapTree{Leaf: leaf}, nil
+	}
+
+	left, err := t.Left.neuter()
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := t.Right.neuter()
+	if err != nil {
+		return nil, err
+	}
+
+	return &TapTree{Left: left, Right: right}, nil
+}
+
+// String returns the descriptor followed by its checksum
+func (d *Descriptor) String() string {
+
+	s := d.expression()
+	checksum, _ := Checksum(s)
+
+	return s + "#" + checksum
+}
+
+// expression returns the descriptor without checksum
+func (d *Descriptor) expression() string {
+
+	var args []string
+
+	switch d.Type {
+	case Sh, Wsh:
+		args = append(args, d.Sub.expression())
+	case Multi, SortedMulti, MultiA, SortedMultiA:
+		args = append(args, strconv.Itoa(d.Threshold))
+	case Addr:
+		args = append(args, d.Addr.String())
+	case Raw:
+		args = append(args, hex.EncodeToString(d.Script))
+	}
+
+	for _, k := range d.Keys {
+		args = append(args, k.String())
+	}
+
+	if d.Tree != nil {
+		args = append(args, d.Tree.String())
+	}
+
+	return d.Type.String() + "(" + strings.Join(args, ",") + ")"
+}
+
+// String returns the script tree expression
+func (t *TapTree) String() string {
+
+	if t.Leaf != nil {
+		return t.Leaf.expression()
+	}
+
+	return "{" + t.Left.String() + "," + t.Right.String() + "}"
+}
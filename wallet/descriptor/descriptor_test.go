@@ -0,0 +1,296 @@
+package descriptor
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/giogam/Gopher-Wallet/wallet/account"
+	"github.com/giogam/Gopher-Wallet/wallet/address"
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/mnemonic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		desc    string
+		scripts []string
+		address string
+	}{
+		{
+			"pk(0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798)",
+			[]string{"210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798ac"},
+			"",
+		},
+		{
+			"pkh(02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5)",
+			[]string{"76a91406afd46bcdfd22ef94ac122aa11f241244a37ecc88ac"},
+			"1cMh228HTCiwS8ZsaakH8A8wze1JR5ZsP",
+		},
+		{
+			"wpkh(02f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9)",
+			[]string{"00147dd65592d0ab2fe0d0257d571abf032cd9db93dc"},
+			"bc1q0ht9tyks4vh7p5p904t340cr9nvahy7u3re7zg",
+		},
+		{
+			"sh(wpkh(03fff97bd5755eeea420453a14355235d382f6472f8568a18b2f057a1460297556))",
+			[]string{"a914cc6ffbc0bf31af759451068f90ba7a0272b6b33287"},
+			"3LKyvRN6SmYXGBNn8fcQvYxW9MGKtwcinN",
+		},
+		{
+			"tr(a34b99f22c790c4e36b2b3c2c35a36db06226e41c692fc82b8b56ac1c540c5bd)",
+			[]string{"512077aab6e066f8a7419c5ab714c12c67d25007ed55a43cadcacb4d7a970a093f11"},
+			"bc1pw74tdcrxlzn5r8z6ku2vztr86fgq0m245s72mjktf4afwzsf8ugs0gs8zu",
+		},
+		{
+			"tr(L4rK1yDtCWekvXuE6oXD9jCYfFNV2cWRpVuPLBcCU2z8TrisoyY1,pk(669b8afcec803a0d323e9a17f3ea8e68e8abe5a278020a929adbec52421adbd0))",
+			[]string{"512017cf18db381d836d8923b1bdb246cfcd818da1a9f0e6e7907f187f0b2f937754"},
+			"bc1pzl833kecrkpkmzfrkx7my3k0ekqcmgdf7rnw0yrlrplsktunwa2q7vxsg5",
+		},
+		{
+			"multi(1,022f8bde4d1a07209355b4a7250a5c5128e88b84bddc619ab7cba8d569b240efe4,025cbdf0646e5db4eaa398f365f2ea7a0e3d419b7e0330e39ce92bddedcac4f9bc)",
+			[]string{"5121022f8bde4d1a07209355b4a7250a5c5128e88b84bddc619ab7cba8d569b240efe421025cbdf0646e5db4eaa398f365f2ea7a0e3d419b7e0330e39ce92bddedcac4f9bc52ae"},
+			"",
+		},
+		{
+			"sh(multi(2,[00000000/111'/222]xpub6ERApfZwUNrhLCkDtcHTcxd75RbzS1ed54G1LkBUHQVHQKqhMkhgbmJbZRkrgZw4koxb5JaHWkY4ALHY2grBGRjaDMzQLcgJvLJuZZvRcEL,xpub68NZiKmJWnxxS6aaHmn81bvJeTESw724CRDs6HbuccFQN9Ku14VQrADWgqbhhTHBaohPX4CjNLf9fq9MYo6oDaPPLPxSb7gwQN3ih19Zm4Y/0))",
+			[]string{"a91445a9a622a8b0a1269944be477640eedc447bbd8487"},
+			"383MnCNZkvUjsBMroHZ9sSGbatoFPMxYV8",
+		},
+		{
+			"combo(0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798)",
+			[]string{
+				"210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798ac",
+				"76a914751e76e8199196d454941c45d1b3a323f1433bd688ac",
+				"0014751e76e8199196d454941c45d1b3a323f1433bd6",
+				"a914bcfeb728b584253d5f3f70bcb780e9ef218a68f487",
+			},
+			"",
+		},
+		{
+			"combo(04a34b99f22c790c4e36b2b3c2c35a36db06226e41c692fc82b8b56ac1c540c5bd5b8dec5235a0fa8722476c7709c02559e3aa73aa03918ba2d492eea75abea235)",
+			[]string{
+				"4104a34b99f22c790c4e36b2b3c2c35a36db06226e41c692fc82b8b56ac1c540c5bd5b8dec5235a0fa8722476c7709c02559e3aa73aa03918ba2d492eea75abea235ac",
+				"76a914b5bd079c4d57cc7fc28ecf8213a6b791625b818388ac",
+			},
+			"",
+		},
+		{
+			"addr(bc1q0ht9tyks4vh7p5p904t340cr9nvahy7u3re7zg)",
+			[]string{"00147dd65592d0ab2fe0d0257d571abf032cd9db93dc"},
+			"bc1q0ht9tyks4vh7p5p904t340cr9nvahy7u3re7zg",
+		},
+		{
+			"raw(6a0568656c6c6f)",
+			[]string{"6a0568656c6c6f"},
+			"",
+		},
+	}
+
+	for _, test := range tests {
+		d, err := Parse(test.desc, address.MainNet)
+		if !assert.NoError(t, err, test.desc) {
+			continue
+		}
+		assert.False(t, d.IsRange())
+
+		outputs, err := d.Expand(0)
+		assert.NoError(t, err)
+
+		var scripts []string
+		for _, out := range outputs {
+			scripts = append(scripts, hex.EncodeToString(out.PkScript))
+		}
+		assert.Equal(t, test.scripts, scripts, test.desc)
+
+		addr, err := d.Address(0)
+		if test.address == "" {
+			assert.Equal(t, ErrNoAddress, err, test.desc)
+		} else if assert.NoError(t, err, test.desc) {
+			assert.Equal(t, test.address, addr.String())
+		}
+
+		// the descriptor round trips
+		parsed, err := Parse(d.String(), address.MainNet)
+		assert.NoError(t, err)
+		assert.Equal(t, d.String(), parsed.String())
+	}
+}
+
+func TestExpandScripts(t *testing.T) {
+	d, err := Parse("sh(wsh(multi(1,03f28773c2d975288bc7d1d205c3748651b075fbc6610e58cddeeddf8f19405aa8,03499fdf9e895e719cfd64e67f07d38e3226aa7b63678949e6e49b241a60e823e4)))", address.MainNet)
+	assert.NoError(t, err)
+
+	outputs, err := d.Expand(0)
+	assert.NoError(t, err)
+
+	witnessScript := "512103f28773c2d975288bc7d1d205c3748651b075fbc6610e58cddeeddf8f19405aa82103499fdf9e895e719cfd64e67f07d38e3226aa7b63678949e6e49b241a60e823e452ae"
+	assert.Equal(t, witnessScript, hex.EncodeToString(outputs[0].WitnessScript))
+	assert.Equal(t, "0020", hex.EncodeToString(outputs[0].RedeemScript[:2]))
+	assert.Equal(t, "a914", hex.EncodeToString(outputs[0].PkScript[:2]))
+
+	// multi_a leaves are verified as threshold tapscripts
+	d, err = Parse("tr(a34b99f22c790c4e36b2b3c2c35a36db06226e41c692fc82b8b56ac1c540c5bd,{pk(669b8afcec803a0d323e9a17f3ea8e68e8abe5a278020a929adbec52421adbd0),sortedmulti_a(1,669b8afcec803a0d323e9a17f3ea8e68e8abe5a278020a929adbec52421adbd0,a34b99f22c790c4e36b2b3c2c35a36db06226e41c692fc82b8b56ac1c540c5bd)})", address.MainNet)
+	assert.NoError(t, err)
+	assert.Equal(t, SortedMultiA, d.Tree.Right.Leaf.Type)
+
+	leaf, err := d.Tree.Right.Leaf.script(0)
+	assert.NoError(t, err)
+	assert.Equal(t, "20669b8afcec803a0d323e9a17f3ea8e68e8abe5a278020a929adbec52421adbd0ac20a34b99f22c790c4e36b2b3c2c35a36db06226e41c692fc82b8b56ac1c540c5bdba519c", hex.EncodeToString(leaf))
+}
+
+func TestDerive(t *testing.T) {
+	// BIP32 test vector 1, chain m/0H/1/2H/2/1000000000
+	d, err := Parse("pkh(xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi/0'/1/2h/2/1000000000)", address.MainNet)
+	assert.NoError(t, err)
+
+	pub, err := d.Keys[0].Derive(0)
+	assert.NoError(t, err)
+	assert.Equal(t, "022a471424da5e657499d1ff51cb43c47481a03b1e77f951fe64cec9f5a48f7011", hex.EncodeToString(pub))
+
+	origin, err := d.Keys[0].KeyOrigin(0)
+	assert.NoError(t, err)
+	assert.Equal(t, "3442193e", hex.EncodeToString(origin.Fingerprint))
+	assert.Equal(t, []uint32{hdwallet.HardenedKeyStart, 1, 2 + hdwallet.HardenedKeyStart, 2, 1000000000}, origin.Path)
+
+	// the hardened steps move to the origin of the public key
+	assert.True(t, d.IsPrivate())
+	n, err := d.Neuter()
+	assert.NoError(t, err)
+	assert.False(t, n.IsPrivate())
+	assert.Equal(t, "pkh([3442193e/0h/1/2h]xpub6D4BDPcP2GT577Vvch3R8wDkScZWzQzMMUm3PWbmWvVJrZwQY4VUNgqFJPMM3No2dFDFGTsxxpG5uJh7n7epu4trkrX7x7DogT5Uv6fcLW5/2/1000000000)", n.expression())
+
+	neutered, _ := n.Keys[0].Derive(0)
+	assert.Equal(t, pub, neutered)
+	neuteredOrigin, _ := n.Keys[0].KeyOrigin(0)
+	assert.Equal(t, origin, neuteredOrigin)
+}
+
+func TestAccountDescriptors(t *testing.T) {
+	seed := mnemonic.NewSeed(strings.Split("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", " "), "")
+	master, _ := hdwallet.NewMasterKey(seed, hdwallet.MainnetPrivate)
+
+	tests := []struct {
+		t      address.Type
+		format string
+	}{
+		{address.P2PKH, "pkh(%s/%d/*)"},
+		{address.P2SH, "sh(wpkh(%s/%d/*))"},
+		{address.P2WPKH, "wpkh(%s/%d/*)"},
+		{address.P2TR, "tr(%s/%d/*)"},
+	}
+
+	for _, test := range tests {
+		a, err := account.New(master, test.t, 0, address.MainNet)
+		assert.NoError(t, err)
+
+		xpub, _ := a.Key.Neuter()
+		origin := (&Key{Origin: &KeyOrigin{Fingerprint: a.Fingerprint, Path: a.Path}, Extended: xpub}).String()
+
+		for _, chain := range []uint32{account.External, account.Internal} {
+			desc := fmt.Sprintf(test.format, origin, chain)
+			d, err := Parse(desc, address.MainNet)
+			assert.NoError(t, err, desc)
+			assert.True(t, d.IsRange())
+
+			for index := uint32(0); index < 3; index++ {
+				want, _ := a.Address(chain, index)
+				got, err := d.Address(index)
+				assert.NoError(t, err)
+				assert.Equal(t, want.String(), got.String(), desc)
+			}
+
+			exported, err := FromAccount(a, chain)
+			assert.NoError(t, err)
+			assert.True(t, exported.IsPrivate())
+			neutered, _ := exported.Neuter()
+			assert.Equal(t, d.String(), neutered.String())
+		}
+
+		_, err = FromAccount(a, 2)
+		assert.Equal(t, account.ErrInvalidChain, err)
+	}
+}
+
+func TestNeuter(t *testing.T) {
+	d, err := Parse("wsh(multi(1,xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi/1/*,L4rK1yDtCWekvXuE6oXD9jCYfFNV2cWRpVuPLBcCU2z8TrisoyY1))", address.MainNet)
+	assert.NoError(t, err)
+
+	n, err := d.Neuter()
+	assert.NoError(t, err)
+	assert.Equal(t, "wsh(multi(1,xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8/1/*,03a34b99f22c790c4e36b2b3c2c35a36db06226e41c692fc82b8b56ac1c540c5bd))", n.expression())
+
+	for index := uint32(0); index < 3; index++ {
+		want, _ := d.Address(index)
+		got, _ := n.Address(index)
+		assert.Equal(t, want, got)
+	}
+
+	d, _ = Parse("wpkh(xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi/1/*h)", address.MainNet)
+	_, err = d.Neuter()
+	assert.Equal(t, ErrHardenedWildcard, err)
+
+	// the private key is available for signing
+	priv, err := d.Keys[0].PrivateKey(5)
+	assert.NoError(t, err)
+	pub, _ := d.Keys[0].Derive(5)
+	assert.Equal(t, pub, priv.PubKey().SerializeCompressed())
+}
+
+func TestParseInvalid(t *testing.T) {
+	const (
+		pub    = "03a34b99f22c790c4e36b2b3c2c35a36db06226e41c692fc82b8b56ac1c540c5bd"
+		xonly  = "a34b99f22c790c4e36b2b3c2c35a36db06226e41c692fc82b8b56ac1c540c5bd"
+		uncomp = "04a34b99f22c790c4e36b2b3c2c35a36db06226e41c692fc82b8b56ac1c540c5bd5b8dec5235a0fa8722476c7709c02559e3aa73aa03918ba2d492eea75abea235"
+		xpub   = "xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8"
+	)
+
+	tests := []struct {
+		desc string
+		err  error
+	}{
+		{"pkh(" + pub, ErrInvalidDescriptor},
+		{"foo(" + pub + ")", ErrInvalidDescriptor},
+		{"pkh(" + pub + "," + pub + ")", ErrInvalidDescriptor},
+		{"pkh(" + xonly + ")", ErrInvalidKey},
+		{"pkh(" + pub[:64] + ")", ErrInvalidKey},
+		{"pkh([deadbef/0]" + pub + ")", ErrInvalidKeyOrigin},
+		{"pkh([deadbeef/0" + pub + ")", ErrInvalidDescriptor},
+		{"pkh([deadbeef/x]" + pub + ")", hdwallet.ErrInvalidPath},
+		{"pkh(" + xpub + "/1'/*)", hdwallet.ErrDeriveHardenedFromPublic},
+		{"pkh(" + xpub + "/*h)", hdwallet.ErrDeriveHardenedFromPublic},
+		{"pkh(" + xpub + "/01)", hdwallet.ErrInvalidPath},
+		{"pkh(" + xpub + "/2147483648)", hdwallet.ErrInvalidPath},
+		{"pkh(" + xpub + "/*/1)", hdwallet.ErrInvalidPath},
+		{"wpkh(" + uncomp + ")", ErrUncompressedKey},
+		{"wsh(pk(" + uncomp + "))", ErrUncompressedKey},
+		{"sh(wpkh(" + uncomp + "))", ErrUncompressedKey},
+		{"wpkh(5KYZdUEo39z3FPrtuX2QbbwGnNP5zTd7yyr2SC1j299sBCnWjss)", ErrUncompressedKey},
+		{"wsh(wpkh(" + pub + "))", ErrInvalidContext},
+		{"sh(sh(pkh(" + pub + ")))", ErrInvalidContext},
+		{"wsh(wsh(pkh(" + pub + ")))", ErrInvalidContext},
+		{"sh(tr(" + xonly + "))", ErrInvalidContext},
+		{"sh(addr(bc1q0ht9tyks4vh7p5p904t340cr9nvahy7u3re7zg))", ErrInvalidContext},
+		{"sh(combo(" + pub + "))", ErrInvalidContext},
+		{"tr(" + xonly + ",multi(1," + xonly + "))", ErrInvalidContext},
+		{"tr(" + xonly + ",pkh(" + xonly + "))", ErrInvalidContext},
+		{"wsh(multi_a(1," + pub + "))", ErrInvalidContext},
+		{"multi(0," + pub + ")", ErrInvalidThreshold},
+		{"multi(2," + pub + ")", ErrInvalidThreshold},
+		{"multi(x," + pub + ")", ErrInvalidThreshold},
+		{"multi(1," + pub + "," + pub + "," + pub + "," + pub + ")", ErrTooManyKeys},
+		{"sh(multi(1" + strings.Repeat(","+pub, 16) + "))", ErrScriptTooLarge},
+		{"wsh(multi(1" + strings.Repeat(","+pub, 21) + "))", ErrTooManyKeys},
+		{"tr(" + xonly + ",{pk(" + xonly + ")})", ErrInvalidDescriptor},
+		{"tr(" + xonly + ",{pk(" + xonly + "),pk(" + xonly + ")}", ErrInvalidDescriptor},
+		{"raw(zz)", ErrInvalidDescriptor},
+		{"addr(bc1q0ht9tyks4vh7p5p904t340cr9nvahy7u3re7zh)", address.ErrInvalidBech32Checksum},
+	}
+
+	for _, test := range tests {
+		_, err := Parse(test.desc, address.MainNet)
+		assert.Equal(t, test.err, err, test.desc)
+	}
+}
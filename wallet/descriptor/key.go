@@ -0,0 +1,428 @@
+package descriptor
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+)
+
+// Wildcard is the derivation of the last step of a ranged key
+type Wildcard int
+
+const (
+	// NoWildcard keys are not ranged
+	NoWildcard Wildcard = iota
+	// UnhardenedWildcard keys end with /*
+	UnhardenedWildcard
+	// HardenedWildcard keys end with /*h
+	HardenedWildcard
+)
+
+// KeyOrigin is the fingerprint of the master key and the path deriving a
+// key from it
+type KeyOrigin struct {
+	Fingerprint []byte
+	Path        []uint32
+}
+
+// Key is a key expression, a fixed public or private key or an extended key
+// followed by a derivation path and an optional wildcard. XOnly keys are
+// serialized as 32 bytes, as they are inside tr().
+type Key struct {
+	Origin   *KeyOrigin
+	PubKey   []byte
+	PrivKey  []byte
+	Extended *hdwallet.ExtendedKey
+	Path     []uint32
+	Wildcard Wildcard
+	XOnly    bool
+	// wifVersion and compressed are the encoding of a WIF private key
+	wifVersion int
+	compressed bool
+}
+
+// parseKey parses a key expression, witness contexts reject uncompressed
+// keys and x-only keys are only allowed in taproot
+func parseKey(s string, ctx context) (*Key, error) {
+
+	k := &Key{XOnly: ctx == ctxTr || ctx == ctxTapscript}
+
+	if strings.HasPrefix(s, "[") {
+		end := strings.IndexByte(s, ']')
+		if end < 0 {
+			return nil, ErrInvalidKeyOrigin
+		}
+
+		origin, err := parseOrigin(s[1:end])
+		if err != nil {
+			return nil, err
+		}
+		k.Origin = origin
+		s = s[end+1:]
+	}
+
+	if b, err := hex.DecodeString(s); err == nil {
+		return k, k.setPubKey(b, ctx)
+	}
+
+	parts := strings.Split(s, "/")
+
+	if key, compressed, version, err := hdwallet.DecodeWIF(parts[0]); err == nil {
+		if len(parts) > 1 {
+			return nil, ErrInvalidKey
+		}
+		if !compressed && ctx != ctxTop && ctx != ctxSh {
+			return nil, ErrUncompressedKey
+		}
+		k.PrivKey, k.compressed, k.wifVersion = key, compressed, version
+		return k, nil
+	}
+
+	extended, err := hdwallet.ParseExtendedKey(parts[0])
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+	k.Extended = extended
+
+	steps := parts[1:]
+	if n := len(steps); n > 0 {
+		switch steps[n-1] {
+		case "*":
+			k.Wildcard = UnhardenedWildcard
+			steps = steps[:n-1]
+		case "*'", "*h", "*H":
+			k.Wildcard = HardenedWildcard
+			steps = steps[:n-1]
+		}
+	}
+
+	if k.Path, err = parseSteps(steps); err != nil {
+		return nil, err
+	}
+
+	if !extended.IsPrivate && k.hardened() {
+		return nil, hdwallet.ErrDeriveHardenedFromPublic
+	}
+
+	return k, nil
+}
+
+// parseOrigin parses the content of a key origin, a fingerprint followed
+// by the path
+func parseOrigin(s string) (*KeyOrigin, error) {
+
+	parts := strings.Split(s, "/")
+
+	fingerprint, err := hex.DecodeString(parts[0])
+	if err != nil || len(fingerprint) != 4 {
+		return nil, ErrInvalidKeyOrigin
+	}
+
+	path, err := parseSteps(parts[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeyOrigin{Fingerprint: fingerprint, Path: path}, nil
+}
+
+// parseSteps parses derivation steps, ' h and H mark hardened ones
+func parseSteps(steps []string) ([]uint32, error) {
+
+	path := make([]uint32, 0, len(steps))
+	for _, step := range steps {
+		hardened := false
+		if strings.HasSuffix(step, "'") || strings.HasSuffix(step, "h") || strings.HasSuffix(step, "H") {
+			hardened = true
+			step = step[:len(step)-1]
+		}
+
+		// no sign, no leading zero
+		if step == "" || step[0] == '+' || (len(step) > 1 && step[0] == '0') {
+			return nil, hdwallet.ErrInvalidPath
+		}
+
+		i, err := strconv.ParseUint(step, 10, 32)
+		if err != nil || i >= hdwallet.HardenedKeyStart {
+			return nil, hdwallet.ErrInvalidPath
+		}
+
+		if hardened {
+			i += hdwallet.HardenedKeyStart
+		}
+		path = append(path, uint32(i))
+	}
+
+	return path, nil
+}
+
+// setPubKey checks a fixed public key against the context
+func (k *Key) setPubKey(b []byte, ctx context) error {
+
+	switch {
+	case len(b) == 32 && k.XOnly:
+		if _, err := secp256k1.ParseXOnlyPubKey(b); err != nil {
+			return ErrInvalidKey
+		}
+
+	case len(b) == secp256k1.PubKeyCompressedLen:
+		if _, err := secp256k1.ParsePubKey(b); err != nil {
+			return ErrInvalidKey
+		}
+
+	case len(b) == 65 && b[0] == 0x04:
+		if ctx != ctxTop && ctx != ctxSh {
+			return ErrUncompressedKey
+		}
+		if _, err := secp256k1.ParsePubKey(b); err != nil {
+			return ErrInvalidKey
+		}
+
+	default:
+		return ErrInvalidKey
+	}
+
+	k.PubKey = b
+
+	return nil
+}
+
+// hardened reports whether deriving the key requires the private key
+func (k *Key) hardened() bool {
+
+	if k.Wildcard == HardenedWildcard {
+		return true
+	}
+
+	for _, i := range k.Path {
+		if i >= hdwallet.HardenedKeyStart {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsRange reports whether the key ends with a wildcard
+func (k *Key) IsRange() bool {
+	return k.Wildcard != NoWildcard
+}
+
+// Uncompressed reports whether the key is serialized uncompressed
+func (k *Key) Uncompressed() bool {
+	return len(k.PubKey) == 65 || (k.PrivKey != nil && !k.compressed)
+}
+
+// path returns the derivation path of the extended key at index
+func (k *Key) path(index uint32) []uint32 {
+
+	path := append([]uint32{}, k.Path...)
+	switch k.Wildcard {
+	case UnhardenedWildcard:
+		path = append(path, index)
+	case HardenedWildcard:
+		path = append(path, index+hdwallet.HardenedKeyStart)
+	}
+
+	return path
+}
+
+// Derive returns the serialized public key at index, the index is ignored
+// by keys without wildcard
+func (k *Key) Derive(index uint32) ([]byte, error) {
+
+	if len(k.PubKey) == 32 {
+		return k.PubKey, nil
+	}
+
+	var pub *secp256k1.PublicKey
+	switch {
+	case k.PubKey != nil:
+		var err error
+		if pub, err = secp256k1.ParsePubKey(k.PubKey); err != nil {
+			return nil, err
+		}
+
+	case k.PrivKey != nil:
+		priv, err := secp256k1.PrivKeyFromBytes(k.PrivKey)
+		if err != nil {
+			return nil, err
+		}
+		pub = priv.PubKey()
+
+	default:
+		child, err := k.Extended.DerivePath(k.path(index))
+		if err != nil {
+			return nil, err
+		}
+		if pub, err = child.PubKey(); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case k.XOnly:
+		return pub.SerializeXOnly(), nil
+	case k.Uncompressed():
+		return pub.SerializeUncompressed(), nil
+	}
+
+	return pub.SerializeCompressed(), nil
+}
+
+// PrivateKey returns the private key at index
+func (k *Key) PrivateKey(index uint32) (*secp256k1.PrivateKey, error) {
+
+	if k.PrivKey != nil {
+		return secp256k1.PrivKeyFromBytes(k.PrivKey)
+	}
+
+	if k.Extended == nil || !k.Extended.IsPrivate {
+		return nil, hdwallet.ErrNotPrivate
+	}
+
+	child, err := k.Extended.DerivePath(k.path(index))
+	if err != nil {
+		return nil, err
+	}
+
+	return child.PrivKey()
+}
+
+// KeyOrigin returns the fingerprint of the master key and the full path of
+// the key at index. Without origin the key is its own master.
+func (k *Key) KeyOrigin(index uint32) (*KeyOrigin, error) {
+
+	var origin KeyOrigin
+	if k.Origin != nil {
+		origin.Fingerprint = k.Origin.Fingerprint
+		origin.Path = append(origin.Path, k.Origin.Path...)
+	}
+
+	if k.Extended != nil {
+		if k.Origin == nil {
+			origin.Fingerprint = k.Extended.Fingerprint()
+		}
+		origin.Path = append(origin.Path, k.path(index)...)
+		return &origin, nil
+	}
+
+	if k.Origin == nil {
+		pub, err := k.Derive(index)
+		if err != nil {
+			return nil, err
+		}
+		if k.XOnly {
+			// the fingerprint of the even y key
+			pub = append([]byte{0x02}, pub...)
+		}
+		origin.Fingerprint = hdwallet.Hash160(pub)[:4]
+	}
+
+	return &origin, nil
+}
+
+// IsPrivate reports whether the key holds a private key
+func (k *Key) IsPrivate() bool {
+	return k.PrivKey != nil || (k.Extended != nil && k.Extended.IsPrivate)
+}
+
+// Neuter returns the key with its private key replaced by the public key.
+// Hardened steps of an extended private key are derived and moved to the
+// origin, a hardened wildcard cannot be neutered.
+func (k *Key) Neuter() (*Key, error) {
+
+	n := *k
+
+	if k.PrivKey != nil {
+		pub, err := k.Derive(0)
+		if err != nil {
+			return nil, err
+		}
+		n.PubKey, n.PrivKey = pub, nil
+		return &n, nil
+	}
+
+	if k.Extended == nil || !k.Extended.IsPrivate {
+		return &n, nil
+	}
+
+	if k.Wildcard == HardenedWildcard {
+		return nil, ErrHardenedWildcard
+	}
+
+	last := 0
+	for i, step := range k.Path {
+		if step >= hdwallet.HardenedKeyStart {
+			last = i + 1
+		}
+	}
+
+	extended, err := k.Extended.DerivePath(k.Path[:last])
+	if err != nil {
+		return nil, err
+	}
+	if n.Extended, err = extended.Neuter(); err != nil {
+		return nil, err
+	}
+
+	if last > 0 {
+		origin := &KeyOrigin{Fingerprint: k.Extended.Fingerprint()}
+		if k.Origin != nil {
+			origin.Fingerprint = k.Origin.Fingerprint
+			origin.Path = append(origin.Path, k.Origin.Path...)
+		}
+		origin.Path = append(origin.Path, k.Path[:last]...)
+		n.Origin = origin
+	}
+	n.Path = append([]uint32{}, k.Path[last:]...)
+
+	return &n, nil
+}
+
+// String returns the key expression, hardened steps are marked with h
+func (k *Key) String() string {
+
+	var sb strings.Builder
+
+	if k.Origin != nil {
+		sb.WriteString("[" + hex.EncodeToString(k.Origin.Fingerprint))
+		writeSteps(&sb, k.Origin.Path)
+		sb.WriteString("]")
+	}
+
+	switch {
+	case k.PubKey != nil:
+		sb.WriteString(hex.EncodeToString(k.PubKey))
+	case k.PrivKey != nil:
+		wif, _ := hdwallet.EncodeWIF(k.PrivKey, k.compressed, k.wifVersion)
+		sb.WriteString(wif)
+	default:
+		sb.WriteString(k.Extended.String())
+		writeSteps(&sb, k.Path)
+	}
+
+	switch k.Wildcard {
+	case UnhardenedWildcard:
+		sb.WriteString("/*")
+	case HardenedWildcard:
+		sb.WriteString("/*h")
+	}
+
+	return sb.String()
+}
+
+func writeSteps(sb *strings.Builder, path []uint32) {
+
+	for _, step := range path {
+		sb.WriteString("/")
+		if step >= hdwallet.HardenedKeyStart {
+			sb.WriteString(strconv.FormatUint(uint64(step-hdwallet.HardenedKeyStart), 10) + "h")
+		} else {
+			sb.WriteString(strconv.FormatUint(uint64(step), 10))
+		}
+	}
+}
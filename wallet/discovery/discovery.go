@@ -0,0 +1,141 @@
+package discovery
+
+import (
+	"errors"
+
+	"github.com/giogam/Gopher-Wallet/wallet/account"
+	"github.com/giogam/Gopher-Wallet/wallet/address"
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+)
+
+// DefaultGapLimit is the number of consecutive unused addresses after
+// which a chain is considered fully scanned, as in BIP44
+const DefaultGapLimit = 20
+
+// ErrBackendResponse is returned when a backend answers for a different
+// number of scripts than asked
+var ErrBackendResponse = errors.New("discovery: backend returned an unexpected number of results")
+
+// Backend tells whether output scripts have been used on chain
+type Backend interface {
+	// UsedScripts reports, for each output script, whether a transaction
+	// paid to it
+	UsedScripts(pkScripts [][]byte) ([]bool, error)
+}
+
+// AccountTypes are the address types of the BIP44, BIP49, BIP84 and BIP86
+// accounts scanned by ScanAll
+var AccountTypes = []address.Type{address.P2PKH, address.P2SH, address.P2WPKH, address.P2TR}
+
+// Scanner discovers the used accounts and addresses of a wallet
+type Scanner struct {
+	Backend  Backend
+	GapLimit uint32
+}
+
+// NewScanner returns a scanner querying backend, a zero gapLimit selects
+// DefaultGapLimit
+func NewScanner(backend Backend, gapLimit uint32) *Scanner {
+
+	if gapLimit == 0 {
+		gapLimit = DefaultGapLimit
+	}
+
+	return &Scanner{Backend: backend, GapLimit: gapLimit}
+}
+
+// ScanAll discovers the used accounts of every account type of master
+func (s *Scanner) ScanAll(master *hdwallet.ExtendedKey, net *address.Network) ([]*account.Account, error) {
+
+	var accounts []*account.Account
+	for _, t := range AccountTypes {
+		found, err := s.Scan(master, t, net)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, found...)
+	}
+
+	return accounts, nil
+}
+
+// Scan discovers the accounts of type t of master, accounts are scanned in
+// order until one whose external chain was never used
+func (s *Scanner) Scan(master *hdwallet.ExtendedKey, t address.Type, net *address.Network) ([]*account.Account, error) {
+
+	var accounts []*account.Account
+	for index := uint32(0); index < hdwallet.HardenedKeyStart; index++ {
+		a, err := account.New(master, t, index, net)
+		if err != nil {
+			return nil, err
+		}
+
+		used, err := s.ScanAccount(a)
+		if err != nil {
+			return nil, err
+		}
+		if !used {
+			break
+		}
+		accounts = append(accounts, a)
+	}
+
+	return accounts, nil
+}
+
+// ScanAccount walks both chains of a and marks the addresses found used,
+// it reports whether the external chain was used
+func (s *Scanner) ScanAccount(a *account.Account) (bool, error) {
+
+	used, err := s.scanChain(a, account.External)
+	if err != nil || !used {
+		return false, err
+	}
+
+	if _, err := s.scanChain(a, account.Internal); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// scanChain queries the addresses of chain until GapLimit consecutive
+// ones are unused
+func (s *Scanner) scanChain(a *account.Account, chain uint32) (bool, error) {
+
+	// next is the index following the last used address
+	used, next := false, uint32(0)
+	for index := uint32(0); index < next+s.GapLimit; {
+		count := next + s.GapLimit - index
+
+		pkScripts := make([][]byte, count)
+		for i := range pkScripts {
+			pkScript, err := a.PkScript(chain, index+uint32(i))
+			if err != nil {
+				return false, err
+			}
+			pkScripts[i] = pkScript
+		}
+
+		results, err := s.Backend.UsedScripts(pkScripts)
+		if err != nil {
+			return false, err
+		}
+		if len(results) != len(pkScripts) {
+			return false, ErrBackendResponse
+		}
+
+		for i, result := range results {
+			if result {
+				used, next = true, index+uint32(i)+1
+			}
+		}
+		index += count
+	}
+
+	if used {
+		a.MarkUsed(chain, next-1)
+	}
+
+	return used, nil
+}
@@ -0,0 +1,138 @@
+package discovery
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/giogam/Gopher-Wallet/wallet/account"
+	"github.com/giogam/Gopher-Wallet/wallet/address"
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/mnemonic"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+func testMaster(t *testing.T) *hdwallet.ExtendedKey {
+
+	seed := mnemonic.NewSeed(strings.Split(testMnemonic, " "), "")
+	master, err := hdwallet.NewMasterKey(seed, hdwallet.MainnetPrivate)
+	assert.NoError(t, err)
+
+	return master
+}
+
+// use records a payment to the address of type t at account, chain and
+// index
+func use(t *testing.T, b *MemoryBackend, master *hdwallet.ExtendedKey, at address.Type, index, chain, key uint32) {
+
+	a, err := account.New(master, at, index, address.MainNet)
+	assert.NoError(t, err)
+	pkScript, err := a.PkScript(chain, key)
+	assert.NoError(t, err)
+
+	tx := transaction.NewTx(2)
+	tx.AddTxOut(transaction.NewTxOut(10000, pkScript))
+	b.AddTx(tx)
+}
+
+func TestScan(t *testing.T) {
+	master := testMaster(t)
+	b := NewMemoryBackend()
+
+	// account 0 has a gap of 18 between its receive addresses
+	use(t, b, master, address.P2WPKH, 0, account.External, 0)
+	use(t, b, master, address.P2WPKH, 0, account.External, 5)
+	use(t, b, master, address.P2WPKH, 0, account.External, 24)
+	use(t, b, master, address.P2WPKH, 0, account.Internal, 3)
+	// index 21 of account 1 is beyond the gap limit
+	use(t, b, master, address.P2WPKH, 1, account.External, 0)
+	use(t, b, master, address.P2WPKH, 1, account.External, 21)
+	// account 3 is not reached as account 2 is unused
+	use(t, b, master, address.P2WPKH, 3, account.External, 0)
+
+	s := NewScanner(b, 0)
+	assert.Equal(t, uint32(DefaultGapLimit), s.GapLimit)
+
+	accounts, err := s.Scan(master, address.P2WPKH, address.MainNet)
+	assert.NoError(t, err)
+	if assert.Len(t, accounts, 2) {
+		assert.Equal(t, [2]uint32{25, 4}, accounts[0].Next)
+		assert.Equal(t, [2]uint32{1, 0}, accounts[1].Next)
+	}
+
+	// both chains of the used accounts up to the gap and the external
+	// chain of the unused one
+	assert.Equal(t, (25+20)+(4+20)+(1+20)+20+20, b.Queries)
+
+	// a larger gap finds the last address of account 1
+	accounts, err = NewScanner(b, 25).Scan(master, address.P2WPKH, address.MainNet)
+	assert.NoError(t, err)
+	if assert.Len(t, accounts, 2) {
+		assert.Equal(t, [2]uint32{22, 0}, accounts[1].Next)
+	}
+}
+
+func TestScanAll(t *testing.T) {
+	master := testMaster(t)
+	b := NewMemoryBackend()
+
+	use(t, b, master, address.P2PKH, 0, account.External, 2)
+	use(t, b, master, address.P2TR, 0, account.External, 0)
+	use(t, b, master, address.P2TR, 1, account.External, 4)
+	// change alone does not make an account used
+	use(t, b, master, address.P2SH, 0, account.Internal, 0)
+
+	accounts, err := NewScanner(b, 5).ScanAll(master, address.MainNet)
+	assert.NoError(t, err)
+	if assert.Len(t, accounts, 3) {
+		assert.Equal(t, address.P2PKH, accounts[0].Type)
+		assert.Equal(t, [2]uint32{3, 0}, accounts[0].Next)
+		assert.Equal(t, address.P2TR, accounts[1].Type)
+		assert.Equal(t, address.P2TR, accounts[2].Type)
+		assert.Equal(t, [2]uint32{5, 0}, accounts[2].Next)
+	}
+
+	accounts, err = NewScanner(NewMemoryBackend(), 5).ScanAll(master, address.MainNet)
+	assert.NoError(t, err)
+	assert.Empty(t, accounts)
+}
+
+// failingBackend answers the first queries then fails
+type failingBackend struct {
+	answers int
+	short   bool
+}
+
+var errBackend = errors.New("backend down")
+
+func (b *failingBackend) UsedScripts(pkScripts [][]byte) ([]bool, error) {
+
+	if b.answers == 0 {
+		return nil, errBackend
+	}
+	b.answers--
+
+	used := make([]bool, len(pkScripts))
+	used[0] = true
+	if b.short {
+		return used[1:], nil
+	}
+
+	return used, nil
+}
+
+func TestScanErrors(t *testing.T) {
+	master := testMaster(t)
+
+	_, err := NewScanner(&failingBackend{answers: 1}, 5).Scan(master, address.P2WPKH, address.MainNet)
+	assert.Equal(t, errBackend, err)
+
+	_, err = NewScanner(&failingBackend{answers: 1, short: true}, 5).Scan(master, address.P2WPKH, address.MainNet)
+	assert.Equal(t, ErrBackendResponse, err)
+
+	_, err = NewScanner(NewMemoryBackend(), 5).Scan(master, address.P2WSH, address.MainNet)
+	assert.Equal(t, account.ErrUnsupportedType, err)
+}
@@ -0,0 +1,41 @@
+package discovery
+
+import "github.com/giogam/Gopher-Wallet/wallet/transaction"
+
+// MemoryBackend is a backend holding the used scripts in memory
+type MemoryBackend struct {
+	used map[string]bool
+	// Queries counts the scripts asked about
+	Queries int
+}
+
+// NewMemoryBackend returns a backend without any used script
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{used: make(map[string]bool)}
+}
+
+// Add records that pkScript was paid to
+func (b *MemoryBackend) Add(pkScript []byte) {
+	b.used[string(pkScript)] = true
+}
+
+// AddTx records the scripts paid to by the outputs of tx
+func (b *MemoryBackend) AddTx(tx *transaction.Tx) {
+
+	for _, out := range tx.TxOut {
+		b.Add(out.PkScript)
+	}
+}
+
+// UsedScripts reports which scripts were recorded
+func (b *MemoryBackend) UsedScripts(pkScripts [][]byte) ([]bool, error) {
+
+	b.Queries += len(pkScripts)
+
+	used := make([]bool, len(pkScripts))
+	for i, pkScript := range pkScripts {
+		used[i] = b.used[string(pkScript)]
+	}
+
+	return used, nil
+}
@@ -0,0 +1,573 @@
+package electrum
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+const (
+	// ProtocolVersion is the version of the Electrum protocol negotiated
+	// with servers
+	ProtocolVersion = "1.4"
+	// DefaultTimeout bounds dialing and waiting for responses
+	DefaultTimeout = 30 * time.Second
+	// DefaultReconnectDelay is the delay before the second reconnection
+	// attempt, it doubles after each failure up to maxReconnectDelay
+	DefaultReconnectDelay = time.Second
+
+	maxReconnectDelay = time.Minute
+	clientName        = "gopher-wallet"
+	// notificationQueue is the number of notifications buffered before
+	// the connection reader blocks
+	notificationQueue = 1024
+)
+
+var (
+	// ErrClosed is returned when using a closed client
+	ErrClosed = errors.New("electrum: client closed")
+	// ErrDisconnected is returned when the connection is lost and cannot
+	// be restored before the timeout
+	ErrDisconnected = errors.New("electrum: disconnected from server")
+	// ErrTimeout is returned when the server does not answer in time
+	ErrTimeout = errors.New("electrum: request timed out")
+	// ErrServer is returned, with the server message, when a request fails
+	ErrServer = errors.New("electrum: server error")
+	// ErrInvalidResponse is returned when a result cannot be decoded
+	ErrInvalidResponse = errors.New("electrum: invalid response")
+)
+
+// Config holds the server address and the options of a client. OnScript
+// and OnHeader are called, one at a time on a goroutine of the client,
+// when a subscribed script status or the chain tip changes, including
+// changes missed while reconnecting.
+type Config struct {
+	// Addr is the host:port of the server
+	Addr string
+	// TLS enables TLS when not nil
+	TLS            *tls.Config
+	Timeout        time.Duration
+	ReconnectDelay time.Duration
+	OnScript       func(scriptHash, status string)
+	OnHeader       func(height int32, header *transaction.BlockHeader)
+}
+
+// Client is a connection to an Electrum server speaking JSON-RPC over TCP
+// or TLS. Requests are pipelined on the connection, which is restored in
+// the background with its subscriptions when lost.
+type Client struct {
+	cfg    Config
+	nextID uint64
+
+	mu   sync.Mutex
+	conn *conn
+	// ready is closed once conn is set
+	ready  chan struct{}
+	closed bool
+	quit   chan struct{}
+
+	// scripts are the subscribed script hashes and their last status,
+	// tip is the last height notified, -1 until headers are subscribed
+	scripts map[string]string
+	tip     int32
+
+	notes chan func()
+}
+
+// request is a JSON-RPC request
+type request struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+// message is a response, with an ID, or a notification
+type message struct {
+	ID     *uint64         `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// call is a request and the value its result is decoded into
+type call struct {
+	method string
+	params []interface{}
+	result interface{}
+}
+
+// Dial connects to the server and negotiates the protocol version
+func Dial(cfg Config) (*Client, error) {
+
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.ReconnectDelay == 0 {
+		cfg.ReconnectDelay = DefaultReconnectDelay
+	}
+
+	c := &Client{
+		cfg:     cfg,
+		ready:   make(chan struct{}),
+		quit:    make(chan struct{}),
+		scripts: make(map[string]string),
+		tip:     -1,
+		notes:   make(chan func(), notificationQueue),
+	}
+
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+
+	go c.dispatch()
+
+	return c, nil
+}
+
+// Close closes the connection and stops reconnecting
+func (c *Client) Close() error {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return ErrClosed
+	}
+	c.closed = true
+	close(c.quit)
+
+	if c.conn != nil {
+		return c.conn.Close()
+	}
+
+	return nil
+}
+
+// dispatch runs the notification callbacks until the client is closed
+func (c *Client) dispatch() {
+
+	for {
+		select {
+		case note := <-c.notes:
+			note()
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+// notify queues a callback
+func (c *Client) notify(note func()) {
+
+	select {
+	case c.notes <- note:
+	case <-c.quit:
+	}
+}
+
+// dial opens a TCP or TLS connection to the server
+func (c *Client) dial() (net.Conn, error) {
+
+	dialer := &net.Dialer{Timeout: c.cfg.Timeout}
+	if c.cfg.TLS != nil {
+		return tls.DialWithDialer(dialer, "tcp", c.cfg.Addr, c.cfg.TLS)
+	}
+
+	return dialer.Dial("tcp", c.cfg.Addr)
+}
+
+// connect opens a connection, negotiates the protocol and restores the
+// subscriptions before making it the client connection
+func (c *Client) connect() error {
+
+	nc, err := c.dial()
+	if err != nil {
+		return err
+	}
+
+	conn := newConn(nc)
+	go c.read(conn)
+
+	var version []string
+	if err := c.send(conn, []*call{{"server.version", []interface{}{clientName, ProtocolVersion}, &version}}); err != nil {
+		conn.Close()
+		return err
+	}
+
+	if err := c.resubscribe(conn); err != nil {
+		conn.Close()
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	select {
+	case <-conn.done:
+		return ErrDisconnected
+	default:
+	}
+
+	if c.closed {
+		conn.Close()
+		return ErrClosed
+	}
+
+	c.conn = conn
+	close(c.ready)
+
+	return nil
+}
+
+// resubscribe restores the header and script subscriptions on conn, the
+// changes that happened while disconnected are notified
+func (c *Client) resubscribe(conn *conn) error {
+
+	c.mu.Lock()
+	headers := c.tip >= 0
+	hashes := make([]string, 0, len(c.scripts))
+	for hash := range c.scripts {
+		hashes = append(hashes, hash)
+	}
+	c.mu.Unlock()
+
+	if headers {
+		var tip headerNotification
+		if err := c.send(conn, []*call{{"blockchain.headers.subscribe", nil, &tip}}); err != nil {
+			return err
+		}
+		if err := c.headerChanged(&tip); err != nil {
+			return err
+		}
+	}
+
+	calls := make([]*call, len(hashes))
+	statuses := make([]*string, len(hashes))
+	for i, hash := range hashes {
+		calls[i] = &call{"blockchain.scripthash.subscribe", []interface{}{hash}, &statuses[i]}
+	}
+	if err := c.send(conn, calls); err != nil {
+		return err
+	}
+
+	for i, hash := range hashes {
+		c.scriptChanged(hash, statuses[i])
+	}
+
+	return nil
+}
+
+// headerChanged records the chain tip and notifies it when it changed
+func (c *Client) headerChanged(tip *headerNotification) error {
+
+	header, err := tip.header()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	changed := c.tip != tip.Height
+	c.tip = tip.Height
+	c.mu.Unlock()
+
+	if changed && c.cfg.OnHeader != nil {
+		c.notify(func() { c.cfg.OnHeader(tip.Height, header) })
+	}
+
+	return nil
+}
+
+// scriptChanged records the status of a subscribed script and notifies it
+// when it changed, a nil status is the one of an unused script
+func (c *Client) scriptChanged(hash string, status *string) {
+
+	s := ""
+	if status != nil {
+		s = *status
+	}
+
+	c.mu.Lock()
+	old, ok := c.scripts[hash]
+	c.scripts[hash] = s
+	c.mu.Unlock()
+
+	if ok && old != s && c.cfg.OnScript != nil {
+		c.notify(func() { c.cfg.OnScript(hash, s) })
+	}
+}
+
+// read delivers the responses and notifications received on conn until
+// it fails, then starts reconnecting
+func (c *Client) read(conn *conn) {
+
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			break
+		}
+
+		var msg message
+		if err := json.Unmarshal(line, &msg); err != nil {
+			break
+		}
+
+		if msg.ID != nil {
+			conn.deliver(*msg.ID, &msg)
+		} else if msg.Method != "" {
+			c.handleNotification(&msg)
+		}
+	}
+
+	conn.shutdown()
+	c.disconnected(conn)
+}
+
+// handleNotification processes a subscription notification
+func (c *Client) handleNotification(msg *message) {
+
+	switch msg.Method {
+	case "blockchain.headers.subscribe":
+		var params []headerNotification
+		if json.Unmarshal(msg.Params, &params) == nil && len(params) == 1 {
+			c.headerChanged(&params[0])
+		}
+
+	case "blockchain.scripthash.subscribe":
+		var params []*string
+		if json.Unmarshal(msg.Params, &params) == nil && len(params) == 2 && params[0] != nil {
+			c.mu.Lock()
+			_, ok := c.scripts[*params[0]]
+			c.mu.Unlock()
+			if ok {
+				c.scriptChanged(*params[0], params[1])
+			}
+		}
+	}
+}
+
+// disconnected starts reconnecting when conn was the client connection
+func (c *Client) disconnected(conn *conn) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn != conn {
+		return
+	}
+	c.conn = nil
+	c.ready = make(chan struct{})
+
+	if !c.closed {
+		go c.reconnect()
+	}
+}
+
+// reconnect connects again, waiting longer after each failure
+func (c *Client) reconnect() {
+
+	delay := c.cfg.ReconnectDelay
+	for {
+		err := c.connect()
+		if err == nil || err == ErrClosed {
+			return
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-c.quit:
+			return
+		}
+
+		if delay *= 2; delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+// current returns the client connection, waiting for a reconnection
+func (c *Client) current() (*conn, error) {
+
+	timeout := time.After(c.cfg.Timeout)
+	for {
+		c.mu.Lock()
+		conn, ready, closed := c.conn, c.ready, c.closed
+		c.mu.Unlock()
+
+		if closed {
+			return nil, ErrClosed
+		}
+		if conn != nil {
+			return conn, nil
+		}
+
+		select {
+		case <-ready:
+		case <-timeout:
+			return nil, ErrDisconnected
+		}
+	}
+}
+
+// call sends a request and decodes its result into result
+func (c *Client) call(method string, params []interface{}, result interface{}) error {
+	return c.batch([]*call{{method, params, result}})
+}
+
+// batch pipelines the requests, they are sent again once if the
+// connection is lost before all the responses are received
+func (c *Client) batch(calls []*call) error {
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		var conn *conn
+		if conn, err = c.current(); err != nil {
+			return err
+		}
+		if err = c.send(conn, calls); err != ErrDisconnected {
+			return err
+		}
+	}
+
+	return err
+}
+
+// send writes all the requests on conn before waiting for the responses
+func (c *Client) send(conn *conn, calls []*call) error {
+
+	ids := make([]uint64, len(calls))
+	responses := make([]chan *message, len(calls))
+	defer func() {
+		for _, id := range ids {
+			conn.forget(id)
+		}
+	}()
+
+	for i, call := range calls {
+		ids[i] = atomic.AddUint64(&c.nextID, 1)
+
+		ch, err := conn.write(&request{JSONRPC: "2.0", ID: ids[i], Method: call.method, Params: call.params}, c.cfg.Timeout)
+		if err != nil {
+			return err
+		}
+		responses[i] = ch
+	}
+
+	timeout := time.After(c.cfg.Timeout)
+	for i, call := range calls {
+		var msg *message
+		select {
+		case msg = <-responses[i]:
+		case <-conn.done:
+			select {
+			case msg = <-responses[i]:
+			default:
+				return ErrDisconnected
+			}
+		case <-timeout:
+			return ErrTimeout
+		}
+
+		if msg.Error != nil {
+			return fmt.Errorf("%w: %s", ErrServer, msg.Error.Message)
+		}
+		if err := json.Unmarshal(msg.Result, call.result); err != nil {
+			return ErrInvalidResponse
+		}
+	}
+
+	return nil
+}
+
+// conn is a connection and its requests waiting for a response
+type conn struct {
+	net.Conn
+
+	// wmu serializes the writes, mu guards pending
+	wmu     sync.Mutex
+	mu      sync.Mutex
+	pending map[uint64]chan *message
+	// done is closed when the connection is lost
+	done chan struct{}
+}
+
+func newConn(nc net.Conn) *conn {
+	return &conn{Conn: nc, pending: make(map[uint64]chan *message), done: make(chan struct{})}
+}
+
+// write sends req and returns the channel receiving its response
+func (c *conn) write(req *request, timeout time.Duration) (chan *message, error) {
+
+	b, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan *message, 1)
+
+	c.mu.Lock()
+	select {
+	case <-c.done:
+		c.mu.Unlock()
+		return nil, ErrDisconnected
+	default:
+	}
+	c.pending[req.ID] = ch
+	c.mu.Unlock()
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	c.SetWriteDeadline(time.Now().Add(timeout))
+	if _, err := c.Write(append(b, '\n')); err != nil {
+		c.Close()
+		return nil, ErrDisconnected
+	}
+
+	return ch, nil
+}
+
+// deliver passes a response to the request waiting for it
+func (c *conn) deliver(id uint64, msg *message) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if ch, ok := c.pending[id]; ok {
+		ch <- msg
+		delete(c.pending, id)
+	}
+}
+
+// forget drops a request that is no longer waited for
+func (c *conn) forget(id uint64) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.pending, id)
+}
+
+// shutdown closes the connection and wakes the waiting requests
+func (c *conn) shutdown() {
+
+	c.Close()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	close(c.done)
+}
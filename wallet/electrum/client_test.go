@@ -0,0 +1,328 @@
+package electrum
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/giogam/Gopher-Wallet/wallet/account"
+	"github.com/giogam/Gopher-Wallet/wallet/address"
+	"github.com/giogam/Gopher-Wallet/wallet/chain"
+	"github.com/giogam/Gopher-Wallet/wallet/coinselect"
+	"github.com/giogam/Gopher-Wallet/wallet/discovery"
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	genesisHeader = "0100000000000000000000000000000000000000000000000000000000000000000000003ba3edfd7a7b12b27ac72c3e67768f617fc81bc3888a51323a9fb8aa4b1e5e4a29ab5f49ffff001d1dac2b7c"
+	// block 1 of mainnet
+	block1Header = "010000006fe28c0ab6f1b372c1a6a246ae63f74f931e8365e15a089c68d6190000000000982051fd1e4ba744bbbe680e1fee14677ba1a3c3540bf7b1cdb606e857233e0e61bc6649ffff001d01e36299"
+	// P2WPKH spend from segnet block 23157
+	rawTx = "01000000000101a53352d5135766f03076597418263da2d9c958315968fea823529467481ff9cd1300000000ffffffff010b070600000000001600149ddac6f39d51e0398e532a22c41ba189406a852302463043021f4d2381dc97f182abd8185f51753018523212f5ddc07cc4e63a8dc03658da190220608b5c4d92b86b6de7d78ef23a2fa735bcb59b914a48b0e187c5e7569a18197001210307ead084807eb76346df6977000c89392f45c76425b26181f521d7f370066a8f00000000"
+	txid  = "0f167d1385a84d1518cfee208b653fc9163b605ccf1b75347e2850b3e2eb19f3"
+)
+
+// pkScript is the P2PKH script of 1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa
+var pkScript, _ = hex.DecodeString("76a91462e907b15cbf27d5425399ebf6f0fb50ebb88f1888ac")
+
+func dial(t *testing.T, s *fakeServer, cfg Config) *Client {
+
+	cfg.Addr = s.addr()
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 2 * time.Second
+	}
+	if cfg.ReconnectDelay == 0 {
+		cfg.ReconnectDelay = 10 * time.Millisecond
+	}
+
+	c, err := Dial(cfg)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	return c
+}
+
+func TestScriptHash(t *testing.T) {
+	assert.Equal(t, "8b01df4e368ea28f8dc0423bcf7a4923e3a12d307c875e47a0cfbf90b5c39161", ScriptHash(pkScript))
+}
+
+func TestClient(t *testing.T) {
+
+	s := newFakeServer(nil)
+	defer s.close()
+
+	hash := ScriptHash(pkScript)
+	s.txs[txid] = rawTx
+	s.history[hash] = []historyItem{{TxHash: txid, Height: 23157}}
+	s.unspent[hash] = []unspentItem{{TxHash: txid, TxPos: 0, Height: 23157, Value: 395019}}
+	s.fee = 0.00012345
+
+	c := dial(t, s, Config{})
+	defer c.Close()
+
+	var backend chain.Backend = c
+	id, _ := transaction.NewHashFromStr(txid)
+
+	history, err := backend.History(pkScript)
+	assert.NoError(t, err)
+	assert.Equal(t, []*chain.HistoryItem{{TxHash: id, Height: 23157}}, history)
+
+	utxos, err := backend.Unspent(pkScript)
+	assert.NoError(t, err)
+	assert.Equal(t, []*chain.Utxo{{OutPoint: *transaction.NewOutPoint(&id, 0), Value: 395019, Height: 23157}}, utxos)
+
+	history, err = backend.History([]byte{0x51})
+	assert.NoError(t, err)
+	assert.Empty(t, history)
+
+	tx, err := backend.Transaction(&id)
+	assert.NoError(t, err)
+	assert.Equal(t, txid, tx.TxHash().String())
+
+	unknown := transaction.DoubleHashH([]byte("unknown"))
+	_, err = backend.Transaction(&unknown)
+	assert.Equal(t, chain.ErrTxNotFound, err)
+
+	assert.NoError(t, backend.Broadcast(tx))
+	s.mu.Lock()
+	s.reject = "bad-txns-inputs-missingorspent"
+	s.mu.Unlock()
+	err = backend.Broadcast(tx)
+	assert.True(t, errors.Is(err, chain.ErrBroadcast))
+	assert.Contains(t, err.Error(), "bad-txns-inputs-missingorspent")
+
+	height, header, err := backend.BestBlock()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(0), height)
+	assert.Equal(t, "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f", header.BlockHash().String())
+
+	rate, err := backend.EstimateFee(6)
+	assert.NoError(t, err)
+	assert.Equal(t, coinselect.FeeRate(12345), rate)
+	s.mu.Lock()
+	s.fee = -1
+	s.mu.Unlock()
+	_, err = backend.EstimateFee(6)
+	assert.Equal(t, chain.ErrNoFeeEstimate, err)
+
+	assert.NoError(t, c.Ping())
+	assert.NoError(t, c.Close())
+	assert.Equal(t, ErrClosed, c.Ping())
+}
+
+func TestClientPipelining(t *testing.T) {
+
+	s := newFakeServer(nil)
+	defer s.close()
+
+	scripts := make([][]byte, 100)
+	for i := range scripts {
+		scripts[i] = []byte{0x51, byte(i)}
+		if i%3 == 0 {
+			s.history[ScriptHash(scripts[i])] = []historyItem{{TxHash: txid, Height: int32(i)}}
+		}
+	}
+
+	c := dial(t, s, Config{})
+	defer c.Close()
+
+	used, err := c.UsedScripts(scripts)
+	assert.NoError(t, err)
+	for i := range scripts {
+		assert.Equal(t, i%3 == 0, used[i])
+	}
+	assert.Equal(t, len(scripts), s.count("blockchain.scripthash.get_history"))
+
+	// the client is a discovery backend
+	master, _ := hdwallet.NewMasterKey(bytes.Repeat([]byte{1}, 32), hdwallet.MainnetPrivate)
+	a, err := account.New(master, address.P2WPKH, 0, address.MainNet)
+	assert.NoError(t, err)
+	used3, _ := a.PkScript(account.External, 3)
+	s.mu.Lock()
+	s.history[ScriptHash(used3)] = []historyItem{{TxHash: txid, Height: 1}}
+	s.mu.Unlock()
+
+	found, err := discovery.NewScanner(c, 5).ScanAccount(a)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, [2]uint32{4, 0}, a.Next)
+}
+
+func TestClientNotifications(t *testing.T) {
+
+	s := newFakeServer(nil)
+	defer s.close()
+
+	headers := make(chan interface{}, 10)
+	scripts := make(chan interface{}, 10)
+	c := dial(t, s, Config{
+		OnHeader: func(height int32, header *transaction.BlockHeader) { headers <- height },
+		OnScript: func(hash, status string) { scripts <- hash },
+	})
+	defer c.Close()
+
+	status, err := c.Subscribe(pkScript)
+	assert.NoError(t, err)
+	assert.Equal(t, "", status)
+	_, _, err = c.BestBlock()
+	assert.NoError(t, err)
+
+	s.setTip(headerNotification{Height: 1, Hex: block1Header})
+	assert.Equal(t, int32(1), receive(t, headers))
+
+	s.setHistory(ScriptHash(pkScript), []historyItem{{TxHash: txid, Height: 0}})
+	assert.Equal(t, ScriptHash(pkScript), receive(t, scripts))
+
+	// scripts that are not subscribed are ignored
+	s.setHistory(ScriptHash([]byte{0x51}), []historyItem{{TxHash: txid, Height: 0}})
+	s.setHistory(ScriptHash(pkScript), []historyItem{{TxHash: txid, Height: 1}})
+	assert.Equal(t, ScriptHash(pkScript), receive(t, scripts))
+
+	status, err = c.Subscribe(pkScript)
+	assert.NoError(t, err)
+	assert.NotEqual(t, "", status)
+}
+
+func TestClientReconnect(t *testing.T) {
+
+	s := newFakeServer(nil)
+	defer s.close()
+
+	headers := make(chan interface{}, 10)
+	scripts := make(chan interface{}, 10)
+	c := dial(t, s, Config{
+		Timeout:  500 * time.Millisecond,
+		OnHeader: func(height int32, header *transaction.BlockHeader) { headers <- height },
+		OnScript: func(hash, status string) { scripts <- hash },
+	})
+	defer c.Close()
+
+	_, err := c.Subscribe(pkScript)
+	assert.NoError(t, err)
+	_, _, err = c.BestBlock()
+	assert.NoError(t, err)
+
+	// changes made while the connection is down are notified once the
+	// subscriptions are restored
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+		delete(s.conns, conn)
+	}
+	s.history[ScriptHash(pkScript)] = []historyItem{{TxHash: txid, Height: 1}}
+	s.tip = headerNotification{Height: 1, Hex: block1Header}
+	s.mu.Unlock()
+
+	assert.Equal(t, int32(1), receive(t, headers))
+	assert.Equal(t, ScriptHash(pkScript), receive(t, scripts))
+	assert.Equal(t, 2, s.count("server.version"))
+	assert.Equal(t, 2, s.count("blockchain.scripthash.subscribe"))
+
+	// a request interrupted by the disconnection is sent again
+	s.mu.Lock()
+	s.silent = true
+	s.mu.Unlock()
+	done := make(chan error)
+	go func() {
+		_, err := c.History(pkScript)
+		done <- err
+	}()
+	for s.count("blockchain.scripthash.get_history") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	s.mu.Lock()
+	s.silent = false
+	s.mu.Unlock()
+	s.drop()
+	assert.NoError(t, <-done)
+	assert.Equal(t, 2, s.count("blockchain.scripthash.get_history"))
+
+	// requests fail once the server is gone for longer than the timeout
+	s.close()
+	_, err = c.History(pkScript)
+	assert.Error(t, err)
+}
+
+func TestClientTimeout(t *testing.T) {
+
+	s := newFakeServer(nil)
+	defer s.close()
+
+	c := dial(t, s, Config{Timeout: 100 * time.Millisecond})
+	defer c.Close()
+
+	s.mu.Lock()
+	s.silent = true
+	s.mu.Unlock()
+
+	_, err := c.History(pkScript)
+	assert.Equal(t, ErrTimeout, err)
+}
+
+func TestClientTLS(t *testing.T) {
+
+	cert, pool := selfSignedCert(t)
+	s := newFakeServer(&tls.Config{Certificates: []tls.Certificate{cert}})
+	defer s.close()
+
+	c := dial(t, s, Config{TLS: &tls.Config{RootCAs: pool}})
+	defer c.Close()
+
+	height, _, err := c.BestBlock()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(0), height)
+
+	_, err = Dial(Config{Addr: s.addr(), TLS: &tls.Config{}, Timeout: time.Second})
+	assert.Error(t, err)
+}
+
+// receive waits for a value from a notification callback
+func receive(t *testing.T, ch chan interface{}) interface{} {
+
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(2 * time.Second):
+		t.Fatal("notification not received")
+	}
+
+	return nil
+}
+
+// selfSignedCert returns a certificate for 127.0.0.1 and a pool trusting
+// it
+func selfSignedCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
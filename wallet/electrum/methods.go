@@ -0,0 +1,234 @@
+package electrum
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/giogam/Gopher-Wallet/wallet/chain"
+	"github.com/giogam/Gopher-Wallet/wallet/coinselect"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+// headerNotification is the chain tip sent by headers.subscribe
+type headerNotification struct {
+	Height int32  `json:"height"`
+	Hex    string `json:"hex"`
+}
+
+// header decodes the tip header
+func (n *headerNotification) header() (*transaction.BlockHeader, error) {
+
+	raw, err := hex.DecodeString(n.Hex)
+	if err != nil {
+		return nil, ErrInvalidResponse
+	}
+
+	header, err := transaction.NewBlockHeaderFromBytes(raw)
+	if err != nil {
+		return nil, ErrInvalidResponse
+	}
+
+	return header, nil
+}
+
+// historyItem is an entry of get_history, the height is 0 for unconfirmed
+// transactions and -1 when they have unconfirmed inputs
+type historyItem struct {
+	TxHash string `json:"tx_hash"`
+	Height int32  `json:"height"`
+}
+
+// unspentItem is an entry of listunspent
+type unspentItem struct {
+	TxHash string `json:"tx_hash"`
+	TxPos  uint32 `json:"tx_pos"`
+	Height int32  `json:"height"`
+	Value  int64  `json:"value"`
+}
+
+// ScriptHash returns the script hash identifying pkScript on Electrum
+// servers, the byte reversed SHA256 of the script in hex
+func ScriptHash(pkScript []byte) string {
+
+	hash := sha256.Sum256(pkScript)
+	for i, j := 0, len(hash)-1; i < j; i, j = i+1, j-1 {
+		hash[i], hash[j] = hash[j], hash[i]
+	}
+
+	return hex.EncodeToString(hash[:])
+}
+
+// Ping keeps the connection alive, servers drop idle clients
+func (c *Client) Ping() error {
+
+	var result interface{}
+
+	return c.call("server.ping", nil, &result)
+}
+
+// Subscribe subscribes to the status of pkScript and returns it, OnScript
+// is called when it changes. The status is empty for an unused script.
+func (c *Client) Subscribe(pkScript []byte) (string, error) {
+
+	hash := ScriptHash(pkScript)
+
+	var status *string
+	if err := c.call("blockchain.scripthash.subscribe", []interface{}{hash}, &status); err != nil {
+		return "", err
+	}
+
+	c.scriptChanged(hash, status)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.scripts[hash], nil
+}
+
+// History returns the transactions paying to or spending from pkScript
+func (c *Client) History(pkScript []byte) ([]*chain.HistoryItem, error) {
+
+	var items []historyItem
+	if err := c.call("blockchain.scripthash.get_history", []interface{}{ScriptHash(pkScript)}, &items); err != nil {
+		return nil, err
+	}
+
+	history := make([]*chain.HistoryItem, len(items))
+	for i, item := range items {
+		hash, err := transaction.NewHashFromStr(item.TxHash)
+		if err != nil {
+			return nil, ErrInvalidResponse
+		}
+		history[i] = &chain.HistoryItem{TxHash: hash, Height: item.Height}
+	}
+
+	return history, nil
+}
+
+// UsedScripts reports whether each script has a history, the requests are
+// pipelined
+func (c *Client) UsedScripts(pkScripts [][]byte) ([]bool, error) {
+
+	calls := make([]*call, len(pkScripts))
+	results := make([][]historyItem, len(pkScripts))
+	for i, pkScript := range pkScripts {
+		calls[i] = &call{"blockchain.scripthash.get_history", []interface{}{ScriptHash(pkScript)}, &results[i]}
+	}
+
+	if err := c.batch(calls); err != nil {
+		return nil, err
+	}
+
+	used := make([]bool, len(pkScripts))
+	for i, items := range results {
+		used[i] = len(items) != 0
+	}
+
+	return used, nil
+}
+
+// Unspent returns the unspent outputs paying to pkScript
+func (c *Client) Unspent(pkScript []byte) ([]*chain.Utxo, error) {
+
+	var items []unspentItem
+	if err := c.call("blockchain.scripthash.listunspent", []interface{}{ScriptHash(pkScript)}, &items); err != nil {
+		return nil, err
+	}
+
+	utxos := make([]*chain.Utxo, len(items))
+	for i, item := range items {
+		hash, err := transaction.NewHashFromStr(item.TxHash)
+		if err != nil {
+			return nil, ErrInvalidResponse
+		}
+		utxos[i] = &chain.Utxo{
+			OutPoint: *transaction.NewOutPoint(&hash, item.TxPos),
+			Value:    item.Value,
+			Height:   item.Height,
+		}
+	}
+
+	return utxos, nil
+}
+
+// Transaction returns the transaction with the given id
+func (c *Client) Transaction(hash *transaction.Hash) (*transaction.Tx, error) {
+
+	var raw string
+	if err := c.call("blockchain.transaction.get", []interface{}{hash.String()}, &raw); err != nil {
+		if errors.Is(err, ErrServer) {
+			return nil, chain.ErrTxNotFound
+		}
+		return nil, err
+	}
+
+	b, err := hex.DecodeString(raw)
+	if err != nil {
+		return nil, ErrInvalidResponse
+	}
+
+	tx, err := transaction.NewTxFromBytes(b)
+	if err != nil || tx.TxHash() != *hash {
+		return nil, ErrInvalidResponse
+	}
+
+	return tx, nil
+}
+
+// Broadcast relays tx to the network through the server
+func (c *Client) Broadcast(tx *transaction.Tx) error {
+
+	var txid string
+	if err := c.call("blockchain.transaction.broadcast", []interface{}{hex.EncodeToString(tx.Bytes())}, &txid); err != nil {
+		if errors.Is(err, ErrServer) {
+			return fmt.Errorf("%w: %v", chain.ErrBroadcast, err)
+		}
+		return err
+	}
+
+	if txid != tx.TxHash().String() {
+		return ErrInvalidResponse
+	}
+
+	return nil
+}
+
+// BestBlock subscribes to the chain tip and returns it, OnHeader is called
+// when a block is found
+func (c *Client) BestBlock() (int32, *transaction.BlockHeader, error) {
+
+	var tip headerNotification
+	if err := c.call("blockchain.headers.subscribe", nil, &tip); err != nil {
+		return 0, nil, err
+	}
+
+	header, err := tip.header()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	c.mu.Lock()
+	c.tip = tip.Height
+	c.mu.Unlock()
+
+	return tip.Height, header, nil
+}
+
+// EstimateFee returns the fee rate the server estimates for confirmation
+// within target blocks
+func (c *Client) EstimateFee(target int) (coinselect.FeeRate, error) {
+
+	var btcPerKvB float64
+	if err := c.call("blockchain.estimatefee", []interface{}{target}, &btcPerKvB); err != nil {
+		return 0, err
+	}
+
+	if btcPerKvB < 0 {
+		return 0, chain.ErrNoFeeEstimate
+	}
+
+	return coinselect.FeeRate(math.Round(btcPerKvB * 1e8)), nil
+}
@@ -0,0 +1,262 @@
+package electrum
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"math/rand"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+// fakeServer is an in-process Electrum server answering from its state,
+// requests are handled concurrently so responses come out of order
+type fakeServer struct {
+	ln net.Listener
+
+	mu       sync.Mutex
+	conns    map[*serverConn]bool
+	accepted int
+	requests map[string]int
+	silent   bool
+
+	txs     map[string]string
+	history map[string][]historyItem
+	unspent map[string][]unspentItem
+	tip     headerNotification
+	fee     float64
+	reject  string
+}
+
+type serverConn struct {
+	net.Conn
+	wmu sync.Mutex
+}
+
+func newFakeServer(config *tls.Config) *fakeServer {
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	if config != nil {
+		ln = tls.NewListener(ln, config)
+	}
+
+	s := &fakeServer{
+		ln:       ln,
+		conns:    make(map[*serverConn]bool),
+		requests: make(map[string]int),
+		txs:      make(map[string]string),
+		history:  make(map[string][]historyItem),
+		unspent:  make(map[string][]unspentItem),
+		tip:      headerNotification{Height: 0, Hex: genesisHeader},
+		fee:      -1,
+	}
+	go s.serve()
+
+	return s
+}
+
+func (s *fakeServer) addr() string {
+	return s.ln.Addr().String()
+}
+
+func (s *fakeServer) close() {
+
+	s.ln.Close()
+	s.drop()
+}
+
+// drop closes the client connections
+func (s *fakeServer) drop() {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for conn := range s.conns {
+		conn.Close()
+		delete(s.conns, conn)
+	}
+}
+
+func (s *fakeServer) count(method string) int {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests[method]
+}
+
+func (s *fakeServer) serve() {
+
+	for {
+		nc, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		conn := &serverConn{Conn: nc}
+
+		s.mu.Lock()
+		s.conns[conn] = true
+		s.accepted++
+		s.mu.Unlock()
+
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeServer) handle(conn *serverConn) {
+
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+
+		var req struct {
+			ID     uint64            `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(line, &req); err != nil {
+			return
+		}
+
+		go func() {
+			resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+			result, message := s.result(req.Method, req.Params)
+			if message != "" {
+				resp["error"] = map[string]interface{}{"code": 1, "message": message}
+			} else {
+				resp["result"] = result
+			}
+
+			s.mu.Lock()
+			silent := s.silent
+			s.mu.Unlock()
+			if !silent {
+				conn.send(resp)
+			}
+		}()
+	}
+}
+
+func (c *serverConn) send(msg interface{}) {
+
+	b, _ := json.Marshal(msg)
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	c.Write(append(b, '\n'))
+}
+
+// result returns the result of a request or an error message
+func (s *fakeServer) result(method string, params []json.RawMessage) (interface{}, string) {
+
+	var arg string
+	if len(params) > 0 {
+		json.Unmarshal(params[0], &arg)
+	}
+
+	if method == "blockchain.scripthash.get_history" {
+		time.Sleep(time.Duration(rand.Intn(3)) * time.Millisecond)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests[method]++
+
+	switch method {
+	case "server.version":
+		return []string{"fake 1.0", ProtocolVersion}, ""
+	case "server.ping":
+		return nil, ""
+	case "blockchain.headers.subscribe":
+		return s.tip, ""
+	case "blockchain.scripthash.subscribe":
+		return s.status(arg), ""
+	case "blockchain.scripthash.get_history":
+		return append([]historyItem{}, s.history[arg]...), ""
+	case "blockchain.scripthash.listunspent":
+		return append([]unspentItem{}, s.unspent[arg]...), ""
+	case "blockchain.transaction.get":
+		if raw, ok := s.txs[arg]; ok {
+			return raw, ""
+		}
+		return nil, "No such mempool or blockchain transaction"
+	case "blockchain.transaction.broadcast":
+		if s.reject != "" {
+			return nil, s.reject
+		}
+		b, _ := hex.DecodeString(arg)
+		tx, err := transaction.NewTxFromBytes(b)
+		if err != nil {
+			return nil, "TX decode failed"
+		}
+		return tx.TxHash().String(), ""
+	case "blockchain.estimatefee":
+		return s.fee, ""
+	}
+
+	return nil, "unknown method " + method
+}
+
+// status returns the status of a script hash as defined by the protocol
+func (s *fakeServer) status(hash string) *string {
+
+	items := s.history[hash]
+	if len(items) == 0 {
+		return nil
+	}
+
+	var concat string
+	for _, item := range items {
+		concat += item.TxHash + ":" + strconv.Itoa(int(item.Height)) + ":"
+	}
+	sum := sha256.Sum256([]byte(concat))
+	status := hex.EncodeToString(sum[:])
+
+	return &status
+}
+
+// notify sends a notification to every client
+func (s *fakeServer) notify(method string, params ...interface{}) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for conn := range s.conns {
+		conn.send(map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params})
+	}
+}
+
+// setHistory replaces the history of a script hash and notifies it
+func (s *fakeServer) setHistory(hash string, items []historyItem) {
+
+	s.mu.Lock()
+	s.history[hash] = items
+	status := s.status(hash)
+	s.mu.Unlock()
+
+	s.notify("blockchain.scripthash.subscribe", hash, status)
+}
+
+// setTip changes the chain tip and notifies it
+func (s *fakeServer) setTip(tip headerNotification) {
+
+	s.mu.Lock()
+	s.tip = tip
+	s.mu.Unlock()
+
+	s.notify("blockchain.headers.subscribe", tip)
+}
@@ -0,0 +1,70 @@
+package gcs
+
+import (
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+// BIP158 basic filter parameters
+const (
+	BasicP = 19
+	BasicM = 784931
+
+	opReturn = 0x6a
+)
+
+// BlockKey returns the key of the filters of a block, the first 16 bytes
+// of its hash
+func BlockKey(hash *transaction.Hash) [KeySize]byte {
+
+	var key [KeySize]byte
+	copy(key[:], hash[:KeySize])
+
+	return key
+}
+
+// BuildBasicFilter returns the BIP158 basic filter of block, it holds the
+// output scripts of the block but OP_RETURN ones and the scripts of the
+// outputs spent by the block, prevOutScripts
+func BuildBasicFilter(block *transaction.Block, prevOutScripts [][]byte) (*Filter, error) {
+
+	seen := make(map[string]bool)
+	var items [][]byte
+	add := func(script []byte) {
+		if len(script) == 0 || seen[string(script)] {
+			return
+		}
+		seen[string(script)] = true
+		items = append(items, script)
+	}
+
+	for _, tx := range block.Transactions {
+		for _, out := range tx.TxOut {
+			if len(out.PkScript) != 0 && out.PkScript[0] != opReturn {
+				add(out.PkScript)
+			}
+		}
+	}
+	for _, script := range prevOutScripts {
+		add(script)
+	}
+
+	hash := block.BlockHash()
+
+	return Build(BasicP, BasicM, BlockKey(&hash), items)
+}
+
+// FromBasicBytes parses a serialized basic filter
+func FromBasicBytes(b []byte) (*Filter, error) {
+	return FromBytes(BasicP, BasicM, b)
+}
+
+// FilterHash returns the hash of a serialized filter
+func FilterHash(filter []byte) transaction.Hash {
+	return transaction.DoubleHashH(filter)
+}
+
+// FilterHeader returns the header of a filter chaining its hash to the
+// header of the filter of the previous block
+func FilterHeader(filterHash, prevHeader *transaction.Hash) transaction.Hash {
+	return transaction.DoubleHashH(append(filterHash[:], prevHeader[:]...))
+}
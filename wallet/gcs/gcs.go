@@ -0,0 +1,223 @@
+package gcs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"math/bits"
+	"sort"
+
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+// KeySize is the size of the SipHash key of a filter
+const KeySize = 16
+
+var (
+	// ErrTooManyItems is returned when a filter would hold 2^32 items or
+	// more
+	ErrTooManyItems = errors.New("gcs: too many items")
+	// ErrInvalidFilter is returned when a serialized filter cannot be
+	// decoded
+	ErrInvalidFilter = errors.New("gcs: invalid filter")
+)
+
+// Filter is a Golomb-coded set of N items hashed into [0, N*M) with the
+// deltas of the sorted values Golomb-Rice coded with parameter P
+type Filter struct {
+	n    uint32
+	p    uint8
+	m    uint64
+	data []byte
+}
+
+// Build returns the filter of the items under key
+func Build(p uint8, m uint64, key [KeySize]byte, items [][]byte) (*Filter, error) {
+
+	if uint64(len(items)) >= math.MaxUint32 {
+		return nil, ErrTooManyItems
+	}
+
+	f := &Filter{n: uint32(len(items)), p: p, m: m}
+
+	values := f.hashItems(key, items)
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+
+	var w bitWriter
+	last := uint64(0)
+	for _, v := range values {
+		delta := v - last
+		last = v
+		for q := delta >> p; q > 0; q-- {
+			w.writeBit(1)
+		}
+		w.writeBit(0)
+		w.writeBits(delta, p)
+	}
+	f.data = w.bytes
+
+	return f, nil
+}
+
+// FromBytes parses a serialized filter with the parameters p and m
+func FromBytes(p uint8, m uint64, b []byte) (*Filter, error) {
+
+	r := bytes.NewReader(b)
+
+	n, err := transaction.ReadVarInt(r)
+	if err != nil || n >= math.MaxUint32 {
+		return nil, ErrInvalidFilter
+	}
+
+	return &Filter{n: uint32(n), p: p, m: m, data: b[len(b)-r.Len():]}, nil
+}
+
+// N returns the number of items of the filter
+func (f *Filter) N() uint32 {
+	return f.n
+}
+
+// Bytes returns the serialized filter, the item count followed by the
+// coded values
+func (f *Filter) Bytes() []byte {
+
+	var buf bytes.Buffer
+	transaction.WriteVarInt(&buf, uint64(f.n))
+	buf.Write(f.data)
+
+	return buf.Bytes()
+}
+
+// Match reports whether item may be in the filter
+func (f *Filter) Match(key [KeySize]byte, item []byte) (bool, error) {
+	return f.MatchAny(key, [][]byte{item})
+}
+
+// MatchAny reports whether any of the items may be in the filter, false
+// positives happen with a probability of 1/M per item
+func (f *Filter) MatchAny(key [KeySize]byte, items [][]byte) (bool, error) {
+
+	if f.n == 0 || len(items) == 0 {
+		return false, nil
+	}
+
+	queries := f.hashItems(key, items)
+	sort.Slice(queries, func(i, j int) bool { return queries[i] < queries[j] })
+
+	r := bitReader{data: f.data}
+	value := uint64(0)
+	for i := uint32(0); i < f.n; i++ {
+		delta, err := f.readValue(&r)
+		if err != nil {
+			return false, err
+		}
+		value += delta
+
+		for len(queries) > 0 && queries[0] < value {
+			queries = queries[1:]
+		}
+		if len(queries) == 0 {
+			return false, nil
+		}
+		if queries[0] == value {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// readValue reads a Golomb-Rice coded delta
+func (f *Filter) readValue(r *bitReader) (uint64, error) {
+
+	q := uint64(0)
+	for {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, ErrInvalidFilter
+		}
+		if bit == 0 {
+			break
+		}
+		q++
+	}
+
+	rem, err := r.readBits(f.p)
+	if err != nil {
+		return 0, ErrInvalidFilter
+	}
+
+	return q<<f.p | rem, nil
+}
+
+// hashItems maps the items to [0, N*M) with SipHash keyed by key
+func (f *Filter) hashItems(key [KeySize]byte, items [][]byte) []uint64 {
+
+	k0 := binary.LittleEndian.Uint64(key[:8])
+	k1 := binary.LittleEndian.Uint64(key[8:])
+	nm := uint64(f.n) * f.m
+
+	values := make([]uint64, len(items))
+	for i, item := range items {
+		values[i], _ = bits.Mul64(sipHash(k0, k1, item), nm)
+	}
+
+	return values
+}
+
+// bitWriter appends bits to a byte slice, most significant bit first
+type bitWriter struct {
+	bytes []byte
+	used  uint8
+}
+
+func (w *bitWriter) writeBit(bit byte) {
+
+	if w.used == 0 {
+		w.bytes = append(w.bytes, 0)
+	}
+	w.bytes[len(w.bytes)-1] |= bit << (7 - w.used)
+	w.used = (w.used + 1) % 8
+}
+
+// writeBits writes the n low bits of v
+func (w *bitWriter) writeBits(v uint64, n uint8) {
+
+	for i := int(n) - 1; i >= 0; i-- {
+		w.writeBit(byte(v>>uint(i)) & 1)
+	}
+}
+
+// bitReader reads bits from a byte slice, most significant bit first
+type bitReader struct {
+	data []byte
+	pos  uint64
+}
+
+func (r *bitReader) readBit() (byte, error) {
+
+	if r.pos >= uint64(len(r.data))*8 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	bit := r.data[r.pos/8] >> (7 - r.pos%8) & 1
+	r.pos++
+
+	return bit, nil
+}
+
+// readBits reads n bits as an integer
+func (r *bitReader) readBits(n uint8) (uint64, error) {
+
+	v := uint64(0)
+	for i := uint8(0); i < n; i++ {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		v = v<<1 | uint64(bit)
+	}
+
+	return v, nil
+}
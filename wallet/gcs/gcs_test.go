@@ -0,0 +1,145 @@
+package gcs
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBasicFilter(t *testing.T) {
+
+	// testnet genesis block, the first BIP158 test vector
+	raw, _ := hex.DecodeString("0100000000000000000000000000000000000000000000000000000000000000000000003ba3edfd7a7b12b27ac72c3e67768f617fc81bc3888a51323a9fb8aa4b1e5e4adae5494dffff001d1aa4ae180101000000010000000000000000000000000000000000000000000000000000000000000000ffffffff4d04ffff001d0104455468652054696d65732030332f4a616e2f32303039204368616e63656c6c6f72206f6e206272696e6b206f66207365636f6e64206261696c6f757420666f722062616e6b73ffffffff0100f2052a01000000434104678afdb0fe5548271967f1a67130b7105cd6a828e03909a67962e0ea1f61deb649f6bc3f4cef38c4f35504e51ec112de5c384df7ba0b8d578a4c702b6bf11d5fac00000000")
+	block, err := transaction.NewBlockFromBytes(raw)
+	assert.NoError(t, err)
+	hash := block.BlockHash()
+	assert.Equal(t, "000000000933ea01ad0ee984209779baaec3ced90fa3f408719526f8d77f4943", hash.String())
+
+	f, err := BuildBasicFilter(block, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "019dfca8", hex.EncodeToString(f.Bytes()))
+
+	filterHash := FilterHash(f.Bytes())
+	header := FilterHeader(&filterHash, &transaction.Hash{})
+	assert.Equal(t, "21584579b7eb08997773e5aeff3a7f932700042d0ed2a6129012b7d7ae81b750", header.String())
+
+	parsed, err := FromBasicBytes(f.Bytes())
+	assert.NoError(t, err)
+	match, err := parsed.Match(BlockKey(&hash), block.Transactions[0].TxOut[0].PkScript)
+	assert.NoError(t, err)
+	assert.True(t, match)
+	match, err = parsed.Match(BlockKey(&hash), []byte{0x51})
+	assert.NoError(t, err)
+	assert.False(t, match)
+}
+
+func TestBasicFilterReference(t *testing.T) {
+	data, err := os.ReadFile("testdata/basic_filters.json")
+	assert.NoError(t, err)
+
+	var tests [][]interface{}
+	assert.NoError(t, json.Unmarshal(data, &tests))
+
+	var prevHeader transaction.Hash
+	for i, test := range tests {
+		if len(test) < 8 {
+			continue
+		}
+		notes := test[7].(string)
+
+		raw, _ := hex.DecodeString(test[2].(string))
+		block, err := transaction.NewBlockFromBytes(raw)
+		assert.NoError(t, err, notes)
+		hash := block.BlockHash()
+		assert.Equal(t, test[1].(string), hash.String(), notes)
+
+		var prevOutScripts [][]byte
+		for _, s := range test[3].([]interface{}) {
+			script, _ := hex.DecodeString(s.(string))
+			prevOutScripts = append(prevOutScripts, script)
+		}
+
+		// the headers chain from the first vector
+		if i > 1 {
+			assert.Equal(t, test[4].(string), prevHeader.String(), notes)
+		}
+		prevHeader, _ = transaction.NewHashFromStr(test[4].(string))
+
+		f, err := BuildBasicFilter(block, prevOutScripts)
+		assert.NoError(t, err, notes)
+		assert.Equal(t, test[5].(string), hex.EncodeToString(f.Bytes()), notes)
+
+		filterHash := FilterHash(f.Bytes())
+		prevHeader = FilterHeader(&filterHash, &prevHeader)
+		assert.Equal(t, test[6].(string), prevHeader.String(), notes)
+
+		parsed, err := FromBasicBytes(f.Bytes())
+		assert.NoError(t, err, notes)
+		for _, tx := range block.Transactions {
+			for _, out := range tx.TxOut {
+				if len(out.PkScript) == 0 || out.PkScript[0] == opReturn {
+					continue
+				}
+				match, err := parsed.Match(BlockKey(&hash), out.PkScript)
+				assert.NoError(t, err, notes)
+				assert.True(t, match, notes)
+			}
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+
+	rnd := rand.New(rand.NewSource(1))
+	var key [KeySize]byte
+	rnd.Read(key[:])
+
+	items := make([][]byte, 1000)
+	for i := range items {
+		items[i] = make([]byte, 22)
+		rnd.Read(items[i])
+	}
+
+	f, err := Build(BasicP, BasicM, key, items)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(len(items)), f.N())
+
+	parsed, err := FromBasicBytes(f.Bytes())
+	assert.NoError(t, err)
+
+	for _, item := range items {
+		match, err := parsed.Match(key, item)
+		assert.NoError(t, err)
+		assert.True(t, match)
+	}
+
+	others := make([][]byte, 1000)
+	for i := range others {
+		others[i] = make([]byte, 22)
+		rnd.Read(others[i])
+	}
+	match, err := parsed.MatchAny(key, others)
+	assert.NoError(t, err)
+	assert.False(t, match)
+
+	match, err = parsed.MatchAny(key, append(others, items[500]))
+	assert.NoError(t, err)
+	assert.True(t, match)
+
+	// a truncated filter fails to decode
+	truncated, _ := FromBasicBytes(f.Bytes()[:100])
+	_, err = truncated.MatchAny(key, others)
+	assert.Equal(t, ErrInvalidFilter, err)
+
+	empty, err := Build(BasicP, BasicM, key, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0}, empty.Bytes())
+	match, err = empty.Match(key, items[0])
+	assert.NoError(t, err)
+	assert.False(t, match)
+}
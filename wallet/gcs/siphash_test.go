@@ -0,0 +1,28 @@
+package gcs
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSipHash(t *testing.T) {
+
+	// vectors of the SipHash reference implementation, the key is 00..0f
+	// and the message 00..len-1
+	key := make([]byte, 16)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	k0, k1 := binary.LittleEndian.Uint64(key[:8]), binary.LittleEndian.Uint64(key[8:])
+
+	msg := make([]byte, 15)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+
+	assert.Equal(t, uint64(0x726fdb47dd0e0e31), sipHash(k0, k1, nil))
+	assert.Equal(t, uint64(0x74f839c593dc67fd), sipHash(k0, k1, msg[:1]))
+	assert.Equal(t, uint64(0xa129ca6149be45e5), sipHash(k0, k1, msg))
+}
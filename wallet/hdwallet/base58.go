@@ -1,6 +1,7 @@
 package hdwallet
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"math"
 	"strings"
@@ -111,3 +112,29 @@ func B58CheckDecode(data string) (int, []byte, []byte) {
 		encoded[1 : len(encoded)-4],
 		encoded[len(encoded)-4:]
 }
+
+// B58CheckDecodeStrict decodes a Base58Check string verifying its alphabet,
+// its minimum length and its checksum
+func B58CheckDecodeStrict(data string) (int, []byte, error) {
+
+	for i := 0; i < len(data); i++ {
+		if strings.IndexByte(b58alphabet, data[i]) < 0 {
+			return 0, nil, ErrInvalidBase58
+		}
+	}
+
+	if _, raw := decode(data); len(raw) < 5 {
+		return 0, nil, ErrInvalidLength
+	}
+
+	version, payload, checksum := B58CheckDecode(data)
+
+	hash := sha256.Sum256(append([]byte{byte(version)}, payload...))
+	hash = sha256.Sum256(hash[:])
+
+	if !bytes.Equal(hash[:4], checksum) {
+		return 0, nil, ErrInvalidChecksum
+	}
+
+	return version, payload, nil
+}
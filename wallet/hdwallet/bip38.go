@@ -0,0 +1,451 @@
+package hdwallet
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	bip38Version       = 0x01
+	bip38NonECMultiply = 0x42
+	bip38ECMultiply    = 0x43
+
+	bip38FlagNonECMultiply = 0xc0
+	bip38FlagCompressed    = 0x20
+	bip38FlagLotSequence   = 0x04
+
+	bip38PayloadLen     = 38
+	intermediatePayload = 48
+	confirmationPayload = 50
+	bip38MaxLot         = 1048575
+	bip38MaxSequence    = 4095
+	p2pkhMainnet        = 0x00
+	p2pkhTestnet        = 0x6f
+	intermediateVersion = 0x2c
+	confirmationVersion = 0x64
+)
+
+var (
+	intermediateMagic            = []byte{0xe9, 0xb3, 0xe1, 0xff, 0x39, 0xe2}
+	intermediateMagicLotSequence = byte(0x51)
+	intermediateMagicNoLot       = byte(0x53)
+	confirmationMagic            = []byte{0x3b, 0xf6, 0xa8, 0x9a}
+)
+
+var (
+	// ErrInvalidBIP38 is returned when an encrypted key is malformed
+	ErrInvalidBIP38 = errors.New("hdwallet: invalid BIP38 encrypted key")
+	// ErrInvalidIntermediate is returned when an intermediate code is malformed
+	ErrInvalidIntermediate = errors.New("hdwallet: invalid BIP38 intermediate code")
+	// ErrInvalidConfirmation is returned when a confirmation code is malformed
+	ErrInvalidConfirmation = errors.New("hdwallet: invalid BIP38 confirmation code")
+	// ErrInvalidPassphrase is returned when the address hash does not match
+	ErrInvalidPassphrase = errors.New("hdwallet: invalid passphrase")
+	// ErrInvalidLotSequence is returned when lot or sequence are out of range
+	ErrInvalidLotSequence = errors.New("hdwallet: invalid lot or sequence number")
+)
+
+// BIP38Encrypt encrypts a WIF private key with a passphrase using the non
+// EC-multiply method. The passphrase should be NFC normalized by the caller
+func BIP38Encrypt(wif string, passphrase string) (string, error) {
+
+	key, compressed, version, err := DecodeWIF(wif)
+	if err != nil {
+		return "", err
+	}
+
+	priv, err := secp256k1.PrivKeyFromBytes(key)
+	if err != nil {
+		return "", err
+	}
+
+	flag := byte(bip38FlagNonECMultiply)
+	if compressed {
+		flag |= bip38FlagCompressed
+	}
+
+	addrHash := addressHash(priv.PubKey(), compressed, version)
+
+	derived, err := scrypt.Key([]byte(passphrase), addrHash, 16384, 8, 8, 64)
+	if err != nil {
+		return "", err
+	}
+
+	half1 := aesEncrypt(xorBytes(key[:16], derived[:16]), derived[32:])
+	half2 := aesEncrypt(xorBytes(key[16:], derived[16:32]), derived[32:])
+
+	payload := []byte{bip38NonECMultiply, flag}
+	payload = append(payload, addrHash...)
+	payload = append(payload, half1...)
+	payload = append(payload, half2...)
+
+	encrypted, _ := B58CheckEncode(bip38Version, payload)
+
+	return encrypted, nil
+}
+
+// BIP38Decrypt decrypts a BIP38 encrypted key, either EC-multiplied or not,
+// returning the WIF private key for the given network version
+func BIP38Decrypt(encrypted string, passphrase string, version int) (string, error) {
+
+	v, payload, err := B58CheckDecodeStrict(encrypted)
+	if err != nil {
+		return "", err
+	}
+
+	if v != bip38Version || len(payload) != bip38PayloadLen {
+		return "", ErrInvalidBIP38
+	}
+
+	var key []byte
+
+	switch payload[0] {
+	case bip38NonECMultiply:
+		key, err = bip38DecryptNonEC(payload, passphrase, version)
+	case bip38ECMultiply:
+		key, err = bip38DecryptEC(payload, passphrase, version)
+	default:
+		err = ErrInvalidBIP38
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return EncodeWIF(key, payload[1]&bip38FlagCompressed != 0, version)
+}
+
+// NewIntermediateCode builds the intermediate code that allows a third party
+// to generate encrypted keys for the owner of passphrase. Lot and sequence
+// numbers are embedded when useLotSequence is set
+func NewIntermediateCode(passphrase string, useLotSequence bool, lot, sequence int) (string, error) {
+
+	var ownerSalt, ownerEntropy []byte
+
+	magic := intermediateMagicNoLot
+
+	if useLotSequence {
+		if lot < 0 || lot > bip38MaxLot || sequence < 0 || sequence > bip38MaxSequence {
+			return "", ErrInvalidLotSequence
+		}
+
+		ownerSalt = make([]byte, 4)
+		if _, err := rand.Read(ownerSalt); err != nil {
+			return "", err
+		}
+
+		lotSequence := make([]byte, 4)
+		binary.BigEndian.PutUint32(lotSequence, uint32(lot*4096+sequence))
+
+		ownerEntropy = append(append([]byte{}, ownerSalt...), lotSequence...)
+		magic = intermediateMagicLotSequence
+	} else {
+		ownerSalt = make([]byte, 8)
+		if _, err := rand.Read(ownerSalt); err != nil {
+			return "", err
+		}
+
+		ownerEntropy = ownerSalt
+	}
+
+	passFactor, err := bip38PassFactor(passphrase, ownerEntropy, useLotSequence)
+	if err != nil {
+		return "", err
+	}
+
+	x, y := secp256k1.ScalarBaseMultSecret(passFactor)
+	passPoint := (&secp256k1.PublicKey{X: x, Y: y}).SerializeCompressed()
+
+	payload := append(append([]byte{}, intermediateMagic...), magic)
+	payload = append(payload, ownerEntropy...)
+	payload = append(payload, passPoint...)
+
+	code, _ := B58CheckEncode(intermediateVersion, payload)
+
+	return code, nil
+}
+
+// BIP38EncryptedKeyFromIntermediate generates a new EC-multiplied encrypted key
+// from an intermediate code, returning the encrypted key, its confirmation
+// code and the P2PKH address of the key. seedb must be 24 random bytes
+func BIP38EncryptedKeyFromIntermediate(intermediate string, seedb []byte, compressed bool, version int) (string, string, string, error) {
+
+	if len(seedb) != 24 {
+		return "", "", "", ErrInvalidLength
+	}
+
+	v, payload, err := B58CheckDecodeStrict(intermediate)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	if v != intermediateVersion || len(payload) != intermediatePayload ||
+		!bytes.Equal(payload[:6], intermediateMagic) ||
+		(payload[6] != intermediateMagicLotSequence && payload[6] != intermediateMagicNoLot) {
+		return "", "", "", ErrInvalidIntermediate
+	}
+
+	ownerEntropy := payload[7:15]
+	passPoint, err := secp256k1.ParsePubKey(payload[15:])
+	if err != nil {
+		return "", "", "", ErrInvalidIntermediate
+	}
+
+	flag := byte(0)
+	if compressed {
+		flag |= bip38FlagCompressed
+	}
+	if payload[6] == intermediateMagicLotSequence {
+		flag |= bip38FlagLotSequence
+	}
+
+	factorB := DoubleSha256(seedb)
+	if !validPrivateKey(factorB) {
+		return "", "", "", ErrInvalidPrivateKey
+	}
+
+	x, y := secp256k1.ScalarMultSecret(passPoint.X, passPoint.Y, factorB)
+	pub := &secp256k1.PublicKey{X: x, Y: y}
+
+	address := p2pkhAddress(pub, compressed, version)
+	addrHash := DoubleSha256([]byte(address))[:4]
+
+	derived, err := scrypt.Key(passPoint.SerializeCompressed(), append(append([]byte{}, addrHash...), ownerEntropy...), 1024, 1, 1, 64)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	part1 := aesEncrypt(xorBytes(seedb[:16], derived[:16]), derived[32:])
+	part2 := aesEncrypt(xorBytes(append(append([]byte{}, part1[8:]...), seedb[16:]...), derived[16:32]), derived[32:])
+
+	encPayload := []byte{bip38ECMultiply, flag}
+	encPayload = append(encPayload, addrHash...)
+	encPayload = append(encPayload, ownerEntropy...)
+	encPayload = append(encPayload, part1[:8]...)
+	encPayload = append(encPayload, part2...)
+
+	encrypted, _ := B58CheckEncode(bip38Version, encPayload)
+
+	bx, by := secp256k1.ScalarBaseMultSecret(factorB)
+	pointB := (&secp256k1.PublicKey{X: bx, Y: by}).SerializeCompressed()
+
+	encPointB := []byte{pointB[0] ^ (derived[63] & 0x01)}
+	encPointB = append(encPointB, aesEncrypt(xorBytes(pointB[1:17], derived[:16]), derived[32:])...)
+	encPointB = append(encPointB, aesEncrypt(xorBytes(pointB[17:], derived[16:32]), derived[32:])...)
+
+	cfrmPayload := append(append([]byte{}, confirmationMagic...), flag)
+	cfrmPayload = append(cfrmPayload, addrHash...)
+	cfrmPayload = append(cfrmPayload, ownerEntropy...)
+	cfrmPayload = append(cfrmPayload, encPointB...)
+
+	confirmation, _ := B58CheckEncode(confirmationVersion, cfrmPayload)
+
+	return encrypted, confirmation, address, nil
+}
+
+// VerifyConfirmationCode checks a confirmation code against the passphrase
+// and returns the P2PKH address of the respective encrypted key
+func VerifyConfirmationCode(confirmation string, passphrase string, version int) (string, error) {
+
+	v, payload, err := B58CheckDecodeStrict(confirmation)
+	if err != nil {
+		return "", err
+	}
+
+	if v != confirmationVersion || len(payload) != confirmationPayload ||
+		!bytes.Equal(payload[:4], confirmationMagic) {
+		return "", ErrInvalidConfirmation
+	}
+
+	flag := payload[4]
+	addrHash := payload[5:9]
+	ownerEntropy := payload[9:17]
+	encPointB := payload[17:]
+
+	passFactor, err := bip38PassFactor(passphrase, ownerEntropy, flag&bip38FlagLotSequence != 0)
+	if err != nil {
+		return "", err
+	}
+
+	x, y := secp256k1.ScalarBaseMultSecret(passFactor)
+	passPoint := (&secp256k1.PublicKey{X: x, Y: y}).SerializeCompressed()
+
+	derived, err := scrypt.Key(passPoint, append(append([]byte{}, addrHash...), ownerEntropy...), 1024, 1, 1, 64)
+	if err != nil {
+		return "", err
+	}
+
+	pointB := []byte{encPointB[0] ^ (derived[63] & 0x01)}
+	pointB = append(pointB, xorBytes(aesDecrypt(encPointB[1:17], derived[32:]), derived[:16])...)
+	pointB = append(pointB, xorBytes(aesDecrypt(encPointB[17:], derived[32:]), derived[16:32])...)
+
+	pb, err := secp256k1.ParsePubKey(pointB)
+	if err != nil {
+		return "", ErrInvalidPassphrase
+	}
+
+	px, py := secp256k1.ScalarMultSecret(pb.X, pb.Y, passFactor)
+
+	address := p2pkhAddress(&secp256k1.PublicKey{X: px, Y: py}, flag&bip38FlagCompressed != 0, version)
+	if !bytes.Equal(DoubleSha256([]byte(address))[:4], addrHash) {
+		return "", ErrInvalidPassphrase
+	}
+
+	return address, nil
+}
+
+// bip38DecryptNonEC decrypts the payload of a non EC-multiplied key
+func bip38DecryptNonEC(payload []byte, passphrase string, version int) ([]byte, error) {
+
+	flag := payload[1]
+	addrHash := payload[2:6]
+
+	if flag&^bip38FlagCompressed != bip38FlagNonECMultiply {
+		return nil, ErrInvalidBIP38
+	}
+
+	derived, err := scrypt.Key([]byte(passphrase), addrHash, 16384, 8, 8, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	key := xorBytes(aesDecrypt(payload[6:22], derived[32:]), derived[:16])
+	key = append(key, xorBytes(aesDecrypt(payload[22:38], derived[32:]), derived[16:32])...)
+
+	priv, err := secp256k1.PrivKeyFromBytes(key)
+	if err != nil {
+		return nil, ErrInvalidPassphrase
+	}
+
+	if !bytes.Equal(addressHash(priv.PubKey(), flag&bip38FlagCompressed != 0, version), addrHash) {
+		return nil, ErrInvalidPassphrase
+	}
+
+	return key, nil
+}
+
+// bip38DecryptEC decrypts the payload of an EC-multiplied key
+func bip38DecryptEC(payload []byte, passphrase string, version int) ([]byte, error) {
+
+	flag := payload[1]
+	addrHash := payload[2:6]
+	ownerEntropy := payload[6:14]
+	part1 := payload[14:22]
+	part2 := payload[22:38]
+
+	if flag&^(bip38FlagCompressed|bip38FlagLotSequence) != 0 {
+		return nil, ErrInvalidBIP38
+	}
+
+	passFactor, err := bip38PassFactor(passphrase, ownerEntropy, flag&bip38FlagLotSequence != 0)
+	if err != nil {
+		return nil, err
+	}
+
+	x, y := secp256k1.ScalarBaseMultSecret(passFactor)
+	passPoint := (&secp256k1.PublicKey{X: x, Y: y}).SerializeCompressed()
+
+	derived, err := scrypt.Key(passPoint, append(append([]byte{}, addrHash...), ownerEntropy...), 1024, 1, 1, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted2 := xorBytes(aesDecrypt(part2, derived[32:]), derived[16:32])
+	encrypted1 := append(append([]byte{}, part1...), decrypted2[:8]...)
+	seedb := xorBytes(aesDecrypt(encrypted1, derived[32:]), derived[:16])
+	seedb = append(seedb, decrypted2[8:]...)
+
+	factorB := new(big.Int).SetBytes(DoubleSha256(seedb))
+	d := new(big.Int).SetBytes(passFactor)
+	d.Mul(d, factorB)
+	d.Mod(d, secp256k1.N)
+
+	priv, err := secp256k1.PrivKeyFromBytes(secp256k1.PaddedBytes(d, 32))
+	if err != nil {
+		return nil, ErrInvalidPassphrase
+	}
+
+	if !bytes.Equal(addressHash(priv.PubKey(), flag&bip38FlagCompressed != 0, version), addrHash) {
+		return nil, ErrInvalidPassphrase
+	}
+
+	return priv.Serialize(), nil
+}
+
+// bip38PassFactor derives the EC-multiply pass factor from the owner entropy
+func bip38PassFactor(passphrase string, ownerEntropy []byte, lotSequence bool) ([]byte, error) {
+
+	ownerSalt := ownerEntropy
+	if lotSequence {
+		ownerSalt = ownerEntropy[:4]
+	}
+
+	preFactor, err := scrypt.Key([]byte(passphrase), ownerSalt, 16384, 8, 8, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	if !lotSequence {
+		return preFactor, nil
+	}
+
+	return DoubleSha256(append(preFactor, ownerEntropy...)), nil
+}
+
+// addressHash returns the first 4 bytes of the double SHA256 of the address
+func addressHash(pub *secp256k1.PublicKey, compressed bool, version int) []byte {
+	return DoubleSha256([]byte(p2pkhAddress(pub, compressed, version)))[:4]
+}
+
+// p2pkhAddress returns the P2PKH address of pub for the WIF network version
+func p2pkhAddress(pub *secp256k1.PublicKey, compressed bool, version int) string {
+
+	serialized := pub.SerializeUncompressed()
+	if compressed {
+		serialized = pub.SerializeCompressed()
+	}
+
+	addrVersion := p2pkhMainnet
+	if version == WIFTestnet {
+		addrVersion = p2pkhTestnet
+	}
+
+	address, _ := B58CheckEncode(addrVersion, Hash160(serialized))
+
+	return address
+}
+
+func aesEncrypt(block, key []byte) []byte {
+
+	cipher, _ := aes.NewCipher(key)
+	out := make([]byte, len(block))
+	cipher.Encrypt(out, block)
+
+	return out
+}
+
+func aesDecrypt(block, key []byte) []byte {
+
+	cipher, _ := aes.NewCipher(key)
+	out := make([]byte, len(block))
+	cipher.Decrypt(out, block)
+
+	return out
+}
+
+func xorBytes(a, b []byte) []byte {
+
+	out := make([]byte, len(a))
+	for i := range a {
+		out[i] = a[i] ^ b[i]
+	}
+
+	return out
+}
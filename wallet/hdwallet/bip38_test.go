@@ -0,0 +1,157 @@
+package hdwallet
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+	"github.com/stretchr/testify/assert"
+)
+
+type bip38test struct {
+	passphrase   string
+	encrypted    string
+	wif          string
+	address      string
+	confirmation string
+}
+
+func bip38TestVector() []bip38test {
+	return []bip38test{
+		// no compression, no EC multiply
+		{
+			passphrase: "TestingOneTwoThree",
+			encrypted:  "6PRVWUbkzzsbcVac2qwfssoUJAN1Xhrg6bNk8J7Nzm5H7kxEbn2Nh2ZoGg",
+			wif:        "5KN7MzqK5wt2TP1fQCYyHBtDrXdJuXbUzm4A9rKAteGu3Qi5CVR",
+		},
+		{
+			passphrase: "Satoshi",
+			encrypted:  "6PRNFFkZc2NZ6dJqFfhRoFNMR9Lnyj7dYGrzdgXXVMXcxoKTePPX1dWByq",
+			wif:        "5HtasZ6ofTHP6HCwTqTkLDuLQisYPah7aUnSKfC7h4hMUVw2gi5",
+		},
+		{
+			// NFC normalized form of "ϓ\u0000\U00010400\U0001F4A9"
+			passphrase: "ϓ\u0000\U00010400\U0001F4A9",
+			encrypted:  "6PRW5o9FLp4gJDDVqJQKJFTpMvdsSGJxMYHtHaQBF3ooa8mwD69bapcDQn",
+			wif:        "5Jajm8eQ22H3pGWLEVCXyvND8dQZhiQhoLJNKjYXk9roUFTMSZ4",
+		},
+		// compression, no EC multiply
+		{
+			passphrase: "TestingOneTwoThree",
+			encrypted:  "6PYNKZ1EAgYgmQfmNVamxyXVWHzK5s6DGhwP4J5o44cvXdoY7sRzhtpUeo",
+			wif:        "L44B5gGEpqEDRS9vVPz7QT35jcBG2r3CZwSwQ4fCewXAhAhqGVpP",
+		},
+		{
+			passphrase: "Satoshi",
+			encrypted:  "6PYLtMnXvfG3oJde97zRyLYFZCYizPU5T3LwgdYJz1fRhh16bU7u6PPmY7",
+			wif:        "KwYgW8gcxj1JWJXhPSu4Fqwzfhp5Yfi42mdYmMa4XqK7NJxXUSK7",
+		},
+		// EC multiply, no compression, no lot/sequence numbers
+		{
+			passphrase: "TestingOneTwoThree",
+			encrypted:  "6PfQu77ygVyJLZjfvMLyhLMQbYnu5uguoJJ4kMCLqWwPEdfpwANVS76gTX",
+			wif:        "5K4caxezwjGCGfnoPTZ8tMcJBLB7Jvyjv4xxeacadhq8nLisLR2",
+			address:    "1PE6TQi6HTVNz5DLwB1LcpMBALubfuN2z2",
+		},
+		{
+			passphrase: "Satoshi",
+			encrypted:  "6PfLGnQs6VZnrNpmVKfjotbnQuaJK4KZoPFrAjx1JMJUa1Ft8gnf5WxfKd",
+			wif:        "5KJ51SgxWaAYR13zd9ReMhJpwrcX47xTJh2D3fGPG9CM8vkv5sH",
+			address:    "1CqzrtZC6mXSAhoxtFwVjz8LtwLJjDYU3V",
+		},
+		// EC multiply, no compression, lot/sequence numbers
+		{
+			passphrase:   "MOLON LABE",
+			encrypted:    "6PgNBNNzDkKdhkT6uJntUXwwzQV8Rr2tZcbkDcuC9DZRsS6AtHts4Ypo1j",
+			wif:          "5JLdxTtcTHcfYcmJsNVy1v2PMDx432JPoYcBTVVRHpPaxUrdtf8",
+			address:      "1Jscj8ALrYu2y9TD8NrpvDBugPedmbj4Yh",
+			confirmation: "cfrm38V8aXBn7JWA1ESmFMUn6erxeBGZGAxJPY4e36S9QWkzZKtaVqLNMgnifETYw7BPwWC9aPD",
+		},
+		{
+			passphrase:   "ΜΟΛΩΝ ΛΑΒΕ",
+			encrypted:    "6PgGWtx25kUg8QWvwuJAgorN6k9FbE25rv5dMRwu5SKMnfpfVe5mar2ngH",
+			wif:          "5KMKKuUmAkiNbA3DazMQiLfDq47qs8MAEThm4yL8R2PhV1ov33D",
+			address:      "1Lurmih3KruL4xDB5FmHof38yawNtP9oGf",
+			confirmation: "cfrm38V8G4qq2ywYEFfWLD5Cc6msj9UwsG2Mj4Z6QdGJAFQpdatZLavkgRd1i4iBMdRngDqDs51",
+		},
+	}
+}
+
+func TestBIP38Encrypt(t *testing.T) {
+	for _, test := range bip38TestVector() {
+		if test.address != "" {
+			continue
+		}
+
+		encrypted, err := BIP38Encrypt(test.wif, test.passphrase)
+		assert.NoError(t, err)
+		assert.Equal(t, test.encrypted, encrypted)
+	}
+}
+
+func TestBIP38Decrypt(t *testing.T) {
+	for _, test := range bip38TestVector() {
+		wif, err := BIP38Decrypt(test.encrypted, test.passphrase, WIFMainnet)
+		assert.NoError(t, err)
+		assert.Equal(t, test.wif, wif)
+	}
+}
+
+func TestBIP38DecryptWrongPassphrase(t *testing.T) {
+	test := bip38TestVector()[0]
+
+	_, err := BIP38Decrypt(test.encrypted, "wrong", WIFMainnet)
+	assert.Equal(t, ErrInvalidPassphrase, err)
+}
+
+func TestBIP38VerifyConfirmationCode(t *testing.T) {
+	for _, test := range bip38TestVector() {
+		if test.confirmation == "" {
+			continue
+		}
+
+		address, err := VerifyConfirmationCode(test.confirmation, test.passphrase, WIFMainnet)
+		assert.NoError(t, err)
+		assert.Equal(t, test.address, address)
+
+		_, err = VerifyConfirmationCode(test.confirmation, "wrong", WIFMainnet)
+		assert.Equal(t, ErrInvalidPassphrase, err)
+	}
+}
+
+func TestBIP38Intermediate(t *testing.T) {
+	for _, lotSequence := range []bool{false, true} {
+		intermediate, err := NewIntermediateCode("TestingOneTwoThree", lotSequence, 263183, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "passphrase", intermediate[:10])
+
+		seedb := make([]byte, 24)
+		rand.Read(seedb)
+
+		encrypted, confirmation, address, err := BIP38EncryptedKeyFromIntermediate(intermediate, seedb, true, WIFMainnet)
+		assert.NoError(t, err)
+		assert.Equal(t, "6P", encrypted[:2])
+		assert.Equal(t, "cfrm38", confirmation[:6])
+
+		confirmed, err := VerifyConfirmationCode(confirmation, "TestingOneTwoThree", WIFMainnet)
+		assert.NoError(t, err)
+		assert.Equal(t, address, confirmed)
+
+		wif, err := BIP38Decrypt(encrypted, "TestingOneTwoThree", WIFMainnet)
+		assert.NoError(t, err)
+
+		key, compressed, _, err := DecodeWIF(wif)
+		assert.NoError(t, err)
+		assert.True(t, compressed)
+
+		// the decrypted key must control the address the third party saw
+		priv, err := secp256k1.PrivKeyFromBytes(key)
+		assert.NoError(t, err)
+		assert.Equal(t, address, p2pkhAddress(priv.PubKey(), true, WIFMainnet))
+	}
+}
+
+func TestBIP38IntermediateInvalidLot(t *testing.T) {
+	_, err := NewIntermediateCode("TestingOneTwoThree", true, bip38MaxLot+1, 0)
+	assert.Equal(t, ErrInvalidLotSequence, err)
+}
@@ -0,0 +1,375 @@
+package hdwallet
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+)
+
+const (
+	// HardenedKeyStart is the index of the first hardened child key
+	HardenedKeyStart = 0x80000000
+
+	extendedKeyLen = 78
+	minSeedLen     = 16
+	maxSeedLen     = 64
+)
+
+var (
+	// MainnetPrivate is the version of mainnet extended private keys (xprv)
+	MainnetPrivate = []byte{0x04, 0x88, 0xad, 0xe4}
+	// MainnetPublic is the version of mainnet extended public keys (xpub)
+	MainnetPublic = []byte{0x04, 0x88, 0xb2, 0x1e}
+	// TestnetPrivate is the version of testnet extended private keys (tprv)
+	TestnetPrivate = []byte{0x04, 0x35, 0x83, 0x94}
+	// TestnetPublic is the version of testnet extended public keys (tpub)
+	TestnetPublic = []byte{0x04, 0x35, 0x87, 0xcf}
+
+	masterKey = []byte("Bitcoin seed")
+)
+
+var (
+	// ErrInvalidSeed is returned when the seed length is out of range
+	ErrInvalidSeed = errors.New("hdwallet: invalid seed length")
+	// ErrUnusableSeed is returned when a seed yields an invalid master key
+	ErrUnusableSeed = errors.New("hdwallet: unusable seed")
+	// ErrDeriveHardenedFromPublic is returned when deriving a hardened child
+	// from an extended public key
+	ErrDeriveHardenedFromPublic = errors.New("hdwallet: cannot derive a hardened key from a public key")
+	// ErrDeriveBeyondMaxDepth is returned when deriving a child of a key at
+	// the maximum depth of 255
+	ErrDeriveBeyondMaxDepth = errors.New("hdwallet: cannot derive a key beyond the maximum depth")
+	// ErrInvalidChild is returned when the derived child key is invalid and
+	// the next index should be used
+	ErrInvalidChild = errors.New("hdwallet: invalid child, use next index")
+	// ErrNotPrivate is returned when a private key is requested from an
+	// extended public key
+	ErrNotPrivate = errors.New("hdwallet: not a private extended key")
+	// ErrInvalidExtendedKey is returned when an extended key cannot be parsed
+	ErrInvalidExtendedKey = errors.New("hdwallet: invalid extended key")
+	// ErrInvalidPath is returned when a derivation path cannot be parsed
+	ErrInvalidPath = errors.New("hdwallet: invalid derivation path")
+)
+
+// ExtendedKey is a BIP32 extended private or public key
+type ExtendedKey struct {
+	Version     []byte
+	Depth       uint8
+	ParentFP    []byte
+	ChildNumber uint32
+	ChainCode   []byte
+	Key         []byte
+	IsPrivate   bool
+}
+
+// NewMasterKey generates the master extended private key from a seed, the
+// version selects the network of the serialized key
+func NewMasterKey(seed []byte, version []byte) (*ExtendedKey, error) {
+
+	if len(seed) < minSeedLen || len(seed) > maxSeedLen {
+		return nil, ErrInvalidSeed
+	}
+
+	mac := hmac.New(sha512.New, masterKey)
+	mac.Write(seed)
+	i := mac.Sum(nil)
+
+	if !validPrivateKey(i[:32]) {
+		return nil, ErrUnusableSeed
+	}
+
+	return &ExtendedKey{
+		Version:     version,
+		Depth:       0,
+		ParentFP:    []byte{0x00, 0x00, 0x00, 0x00},
+		ChildNumber: 0,
+		ChainCode:   i[32:],
+		Key:         i[:32],
+		IsPrivate:   true,
+	}, nil
+}
+
+// Child derives the child extended key at index i, indexes starting from
+// HardenedKeyStart derive hardened children
+func (k *ExtendedKey) Child(i uint32) (*ExtendedKey, error) {
+
+	hardened := i >= HardenedKeyStart
+
+	if hardened && !k.IsPrivate {
+		return nil, ErrDeriveHardenedFromPublic
+	}
+
+	if k.Depth == math.MaxUint8 {
+		return nil, ErrDeriveBeyondMaxDepth
+	}
+
+	pub, err := k.PubKey()
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	if hardened {
+		data = append([]byte{0x00}, k.Key...)
+	} else {
+		data = pub.SerializeCompressed()
+	}
+
+	index := make([]byte, 4)
+	binary.BigEndian.PutUint32(index, i)
+	data = append(data, index...)
+
+	mac := hmac.New(sha512.New, k.ChainCode)
+	mac.Write(data)
+	l := mac.Sum(nil)
+
+	il := new(big.Int).SetBytes(l[:32])
+	if il.Cmp(secp256k1.N) >= 0 {
+		return nil, ErrInvalidChild
+	}
+
+	var childKey []byte
+
+	if k.IsPrivate {
+		il.Add(il, new(big.Int).SetBytes(k.Key))
+		il.Mod(il, secp256k1.N)
+
+		if il.Sign() == 0 {
+			return nil, ErrInvalidChild
+		}
+
+		childKey = secp256k1.PaddedBytes(il, 32)
+	} else {
+		x, y := secp256k1.ScalarBaseMult(l[:32])
+		x, y = secp256k1.Add(x, y, pub.X, pub.Y)
+
+		if x.Sign() == 0 && y.Sign() == 0 {
+			return nil, ErrInvalidChild
+		}
+
+		childKey = (&secp256k1.PublicKey{X: x, Y: y}).SerializeCompressed()
+	}
+
+	return &ExtendedKey{
+		Version:     k.Version,
+		Depth:       k.Depth + 1,
+		ParentFP:    k.Fingerprint(),
+		ChildNumber: i,
+		ChainCode:   l[32:],
+		Key:         childKey,
+		IsPrivate:   k.IsPrivate,
+	}, nil
+}
+
+// DerivePath derives the descendant of the key following the given path
+func (k *ExtendedKey) DerivePath(path []uint32) (*ExtendedKey, error) {
+
+	key := k
+
+	for _, i := range path {
+		child, err := key.Child(i)
+		if err != nil {
+			return nil, err
+		}
+		key = child
+	}
+
+	return key, nil
+}
+
+// Derive derives the descendant of the key following a path in the
+// "m/44'/0'/0'/0/1" notation, h and H are accepted as hardened markers
+func (k *ExtendedKey) Derive(path string) (*ExtendedKey, error) {
+
+	indexes, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return k.DerivePath(indexes)
+}
+
+// Neuter returns the extended public key of the extended key
+func (k *ExtendedKey) Neuter() (*ExtendedKey, error) {
+
+	if !k.IsPrivate {
+		return k, nil
+	}
+
+	pub, err := k.PubKey()
+	if err != nil {
+		return nil, err
+	}
+
+	version := MainnetPublic
+	if bytes.Equal(k.Version, TestnetPrivate) {
+		version = TestnetPublic
+	}
+
+	return &ExtendedKey{
+		Version:     version,
+		Depth:       k.Depth,
+		ParentFP:    k.ParentFP,
+		ChildNumber: k.ChildNumber,
+		ChainCode:   k.ChainCode,
+		Key:         pub.SerializeCompressed(),
+		IsPrivate:   false,
+	}, nil
+}
+
+// PrivKey returns the private key of an extended private key
+func (k *ExtendedKey) PrivKey() (*secp256k1.PrivateKey, error) {
+
+	if !k.IsPrivate {
+		return nil, ErrNotPrivate
+	}
+
+	return secp256k1.PrivKeyFromBytes(k.Key)
+}
+
+// PubKey returns the public key of the extended key
+func (k *ExtendedKey) PubKey() (*secp256k1.PublicKey, error) {
+
+	if !k.IsPrivate {
+		return secp256k1.ParsePubKey(k.Key)
+	}
+
+	priv, err := secp256k1.PrivKeyFromBytes(k.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	return priv.PubKey(), nil
+}
+
+// Fingerprint returns the first 4 bytes of the HASH160 of the public key
+func (k *ExtendedKey) Fingerprint() []byte {
+
+	pub, err := k.PubKey()
+	if err != nil {
+		return nil
+	}
+
+	return Hash160(pub.SerializeCompressed())[:4]
+}
+
+// WIF returns the private key in Wallet Import Format for the given version
+func (k *ExtendedKey) WIF(version int) (string, error) {
+
+	if !k.IsPrivate {
+		return "", ErrNotPrivate
+	}
+
+	return EncodeWIF(k.Key, true, version)
+}
+
+// String returns the Base58Check serialization of the extended key
+func (k *ExtendedKey) String() string {
+
+	data := make([]byte, 0, extendedKeyLen)
+	data = append(data, k.Version[1:]...)
+	data = append(data, k.Depth)
+	data = append(data, k.ParentFP...)
+
+	index := make([]byte, 4)
+	binary.BigEndian.PutUint32(index, k.ChildNumber)
+	data = append(data, index...)
+	data = append(data, k.ChainCode...)
+
+	if k.IsPrivate {
+		data = append(data, 0x00)
+	}
+	data = append(data, k.Key...)
+
+	str, _ := B58CheckEncode(int(k.Version[0]), data)
+
+	return str
+}
+
+// ParseExtendedKey parses a Base58Check serialized extended key
+func ParseExtendedKey(key string) (*ExtendedKey, error) {
+
+	v, payload, err := B58CheckDecodeStrict(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(payload) != extendedKeyLen-1 {
+		return nil, ErrInvalidExtendedKey
+	}
+
+	version := append([]byte{byte(v)}, payload[:3]...)
+
+	k := &ExtendedKey{
+		Version:     version,
+		Depth:       payload[3],
+		ParentFP:    payload[4:8],
+		ChildNumber: binary.BigEndian.Uint32(payload[8:12]),
+		ChainCode:   payload[12:44],
+	}
+
+	switch {
+	case bytes.Equal(version, MainnetPrivate), bytes.Equal(version, TestnetPrivate):
+		if payload[44] != 0x00 || !validPrivateKey(payload[45:]) {
+			return nil, ErrInvalidExtendedKey
+		}
+		k.Key = payload[45:]
+		k.IsPrivate = true
+
+	case bytes.Equal(version, MainnetPublic), bytes.Equal(version, TestnetPublic):
+		if _, err := secp256k1.ParsePubKey(payload[44:]); err != nil || len(payload[44:]) != secp256k1.PubKeyCompressedLen {
+			return nil, ErrInvalidExtendedKey
+		}
+		k.Key = payload[44:]
+
+	default:
+		return nil, ErrInvalidExtendedKey
+	}
+
+	if k.Depth == 0 && (k.ChildNumber != 0 || !bytes.Equal(k.ParentFP, []byte{0, 0, 0, 0})) {
+		return nil, ErrInvalidExtendedKey
+	}
+
+	return k, nil
+}
+
+// ParsePath parses a derivation path in the "m/44'/0'/0'/0/1" notation
+func ParsePath(path string) ([]uint32, error) {
+
+	parts := strings.Split(path, "/")
+	if len(parts) == 0 || parts[0] != "m" {
+		return nil, ErrInvalidPath
+	}
+
+	indexes := make([]uint32, 0, len(parts)-1)
+
+	for _, p := range parts[1:] {
+		hardened := false
+
+		if strings.HasSuffix(p, "'") || strings.HasSuffix(p, "h") || strings.HasSuffix(p, "H") {
+			hardened = true
+			p = p[:len(p)-1]
+		}
+
+		i, err := strconv.ParseUint(p, 10, 32)
+		if err != nil || i >= HardenedKeyStart {
+			return nil, ErrInvalidPath
+		}
+
+		if hardened {
+			i += HardenedKeyStart
+		}
+
+		indexes = append(indexes, uint32(i))
+	}
+
+	return indexes, nil
+}
@@ -0,0 +1,227 @@
+package hdwallet
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type bip32test struct {
+	seed string
+	path string
+	pub  string
+	priv string
+}
+
+func bip32TestVector() []bip32test {
+	seed1 := "000102030405060708090a0b0c0d0e0f"
+	seed2 := "fffcf9f6f3f0edeae7e4e1dedbd8d5d2cfccc9c6c3c0bdbab7b4b1aeaba8a5a29f9c999693908d8a8784817e7b7875726f6c696663605d5a5754514e4b484542"
+
+	return []bip32test{
+		{
+			seed: seed1,
+			path: "m",
+			pub:  "xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8",
+			priv: "xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi",
+		},
+		{
+			seed: seed1,
+			path: "m/0'",
+			pub:  "xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw",
+			priv: "xprv9uHRZZhk6KAJC1avXpDAp4MDc3sQKNxDiPvvkX8Br5ngLNv1TxvUxt4cV1rGL5hj6KCesnDYUhd7oWgT11eZG7XnxHrnYeSvkzY7d2bhkJ7",
+		},
+		{
+			seed: seed1,
+			path: "m/0'/1",
+			pub:  "xpub6ASuArnXKPbfEwhqN6e3mwBcDTgzisQN1wXN9BJcM47sSikHjJf3UFHKkNAWbWMiGj7Wf5uMash7SyYq527Hqck2AxYysAA7xmALppuCkwQ",
+			priv: "xprv9wTYmMFdV23N2TdNG573QoEsfRrWKQgWeibmLntzniatZvR9BmLnvSxqu53Kw1UmYPxLgboyZQaXwTCg8MSY3H2EU4pWcQDnRnrVA1xe8fs",
+		},
+		{
+			seed: seed1,
+			path: "m/0'/1/2'",
+			pub:  "xpub6D4BDPcP2GT577Vvch3R8wDkScZWzQzMMUm3PWbmWvVJrZwQY4VUNgqFJPMM3No2dFDFGTsxxpG5uJh7n7epu4trkrX7x7DogT5Uv6fcLW5",
+			priv: "xprv9z4pot5VBttmtdRTWfWQmoH1taj2axGVzFqSb8C9xaxKymcFzXBDptWmT7FwuEzG3ryjH4ktypQSAewRiNMjANTtpgP4mLTj34bhnZX7UiM",
+		},
+		{
+			seed: seed1,
+			path: "m/0'/1/2'/2",
+			pub:  "xpub6FHa3pjLCk84BayeJxFW2SP4XRrFd1JYnxeLeU8EqN3vDfZmbqBqaGJAyiLjTAwm6ZLRQUMv1ZACTj37sR62cfN7fe5JnJ7dh8zL4fiyLHV",
+			priv: "xprvA2JDeKCSNNZky6uBCviVfJSKyQ1mDYahRjijr5idH2WwLsEd4Hsb2Tyh8RfQMuPh7f7RtyzTtdrbdqqsunu5Mm3wDvUAKRHSC34sJ7in334",
+		},
+		{
+			seed: seed1,
+			path: "m/0'/1/2'/2/1000000000",
+			pub:  "xpub6H1LXWLaKsWFhvm6RVpEL9P4KfRZSW7abD2ttkWP3SSQvnyA8FSVqNTEcYFgJS2UaFcxupHiYkro49S8yGasTvXEYBVPamhGW6cFJodrTHy",
+			priv: "xprvA41z7zogVVwxVSgdKUHDy1SKmdb533PjDz7J6N6mV6uS3ze1ai8FHa8kmHScGpWmj4WggLyQjgPie1rFSruoUihUZREPSL39UNdE3BBDu76",
+		},
+		{
+			seed: seed2,
+			path: "m",
+			pub:  "xpub661MyMwAqRbcFW31YEwpkMuc5THy2PSt5bDMsktWQcFF8syAmRUapSCGu8ED9W6oDMSgv6Zz8idoc4a6mr8BDzTJY47LJhkJ8UB7WEGuduB",
+			priv: "xprv9s21ZrQH143K31xYSDQpPDxsXRTUcvj2iNHm5NUtrGiGG5e2DtALGdso3pGz6ssrdK4PFmM8NSpSBHNqPqm55Qn3LqFtT2emdEXVYsCzC2U",
+		},
+		{
+			seed: seed2,
+			path: "m/0",
+			pub:  "xpub69H7F5d8KSRgmmdJg2KhpAK8SR3DjMwAdkxj3ZuxV27CprR9LgpeyGmXUbC6wb7ERfvrnKZjXoUmmDznezpbZb7ap6r1D3tgFxHmwMkQTPH",
+			priv: "xprv9vHkqa6EV4sPZHYqZznhT2NPtPCjKuDKGY38FBWLvgaDx45zo9WQRUT3dKYnjwih2yJD9mkrocEZXo1ex8G81dwSM1fwqWpWkeS3v86pgKt",
+		},
+		{
+			seed: seed2,
+			path: "m/0/2147483647'",
+			pub:  "xpub6ASAVgeehLbnwdqV6UKMHVzgqAG8Gr6riv3Fxxpj8ksbH9ebxaEyBLZ85ySDhKiLDBrQSARLq1uNRts8RuJiHjaDMBU4Zn9h8LZNnBC5y4a",
+			priv: "xprv9wSp6B7kry3Vj9m1zSnLvN3xH8RdsPP1Mh7fAaR7aRLcQMKTR2vidYEeEg2mUCTAwCd6vnxVrcjfy2kRgVsFawNzmjuHc2YmYRmagcEPdU9",
+		},
+		{
+			seed: seed2,
+			path: "m/0/2147483647'/1",
+			pub:  "xpub6DF8uhdarytz3FWdA8TvFSvvAh8dP3283MY7p2V4SeE2wyWmG5mg5EwVvmdMVCQcoNJxGoWaU9DCWh89LojfZ537wTfunKau47EL2dhHKon",
+			priv: "xprv9zFnWC6h2cLgpmSA46vutJzBcfJ8yaJGg8cX1e5StJh45BBciYTRXSd25UEPVuesF9yog62tGAQtHjXajPPdbRCHuWS6T8XA2ECKADdw4Ef",
+		},
+		{
+			seed: seed2,
+			path: "m/0/2147483647'/1/2147483646'",
+			pub:  "xpub6ERApfZwUNrhLCkDtcHTcxd75RbzS1ed54G1LkBUHQVHQKqhMkhgbmJbZRkrgZw4koxb5JaHWkY4ALHY2grBGRjaDMzQLcgJvLJuZZvRcEL",
+			priv: "xprvA1RpRA33e1JQ7ifknakTFpgNXPmW2YvmhqLQYMmrj4xJXXWYpDPS3xz7iAxn8L39njGVyuoseXzU6rcxFLJ8HFsTjSyQbLYnMpCqE2VbFWc",
+		},
+		{
+			seed: seed2,
+			path: "m/0/2147483647'/1/2147483646'/2",
+			pub:  "xpub6FnCn6nSzZAw5Tw7cgR9bi15UV96gLZhjDstkXXxvCLsUXBGXPdSnLFbdpq8p9HmGsApME5hQTZ3emM2rnY5agb9rXpVGyy3bdW6EEgAtqt",
+			priv: "xprvA2nrNbFZABcdryreWet9Ea4LvTJcGsqrMzxHx98MMrotbir7yrKCEXw7nadnHM8Dq38EGfSh6dqA9QWTyefMLEcBYJUuekgW4BYPJcr9E7j",
+		},
+	}
+}
+
+func TestBIP32Derive(t *testing.T) {
+	for _, test := range bip32TestVector() {
+		seed, err := hex.DecodeString(test.seed)
+		assert.NoError(t, err)
+
+		master, err := NewMasterKey(seed, MainnetPrivate)
+		assert.NoError(t, err)
+
+		key, err := master.Derive(test.path)
+		assert.NoError(t, err)
+		assert.Equal(t, test.priv, key.String(), test.path)
+
+		pub, err := key.Neuter()
+		assert.NoError(t, err)
+		assert.Equal(t, test.pub, pub.String(), test.path)
+	}
+}
+
+func TestBIP32PublicDerive(t *testing.T) {
+	test := bip32TestVector()[4]
+
+	parent, err := ParseExtendedKey(bip32TestVector()[3].pub)
+	assert.NoError(t, err)
+
+	child, err := parent.Child(2)
+	assert.NoError(t, err)
+	assert.Equal(t, test.pub, child.String())
+
+	_, err = parent.Child(HardenedKeyStart)
+	assert.Equal(t, ErrDeriveHardenedFromPublic, err)
+
+	_, err = parent.PrivKey()
+	assert.Equal(t, ErrNotPrivate, err)
+}
+
+func TestParseExtendedKey(t *testing.T) {
+	for _, test := range bip32TestVector() {
+		priv, err := ParseExtendedKey(test.priv)
+		assert.NoError(t, err)
+		assert.True(t, priv.IsPrivate)
+		assert.Equal(t, test.priv, priv.String())
+
+		pub, err := ParseExtendedKey(test.pub)
+		assert.NoError(t, err)
+		assert.False(t, pub.IsPrivate)
+		assert.Equal(t, test.pub, pub.String())
+	}
+}
+
+func TestParseExtendedKeyInvalid(t *testing.T) {
+	// bad checksum
+	_, err := ParseExtendedKey("xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EBygr15")
+	assert.Equal(t, ErrInvalidChecksum, err)
+
+	_, err = ParseExtendedKey("xpub1234")
+	assert.Error(t, err)
+
+	valid, _ := ParseExtendedKey(bip32TestVector()[0].priv)
+
+	// public version with a private key
+	k := *valid
+	k.Version = MainnetPublic
+	_, err = ParseExtendedKey(k.String())
+	assert.Equal(t, ErrInvalidExtendedKey, err)
+
+	// zero depth with non zero child number
+	k = *valid
+	k.ChildNumber = 1
+	_, err = ParseExtendedKey(k.String())
+	assert.Equal(t, ErrInvalidExtendedKey, err)
+
+	// private key out of range
+	k = *valid
+	k.Key = make([]byte, 32)
+	_, err = ParseExtendedKey(k.String())
+	assert.Equal(t, ErrInvalidExtendedKey, err)
+
+	// unknown version
+	k = *valid
+	k.Version = []byte{0x04, 0xb2, 0x47, 0x46}
+	_, err = ParseExtendedKey(k.String())
+	assert.Equal(t, ErrInvalidExtendedKey, err)
+}
+
+func TestParsePath(t *testing.T) {
+	path, err := ParsePath("m/86'/0h/0H/1/2")
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{HardenedKeyStart + 86, HardenedKeyStart, HardenedKeyStart, 1, 2}, path)
+
+	path, err = ParsePath("m")
+	assert.NoError(t, err)
+	assert.Empty(t, path)
+
+	for _, test := range []string{"", "n/0", "m/x", "m/2147483648", "m/0''", "m//1"} {
+		_, err = ParsePath(test)
+		assert.Equal(t, ErrInvalidPath, err, test)
+	}
+}
+
+func TestNewMasterKeyInvalidSeed(t *testing.T) {
+	_, err := NewMasterKey(make([]byte, 15), MainnetPrivate)
+	assert.Equal(t, ErrInvalidSeed, err)
+
+	_, err = NewMasterKey(make([]byte, 65), MainnetPrivate)
+	assert.Equal(t, ErrInvalidSeed, err)
+}
+
+func TestChildMaxDepth(t *testing.T) {
+	seed, _ := hex.DecodeString(bip32TestVector()[0].seed)
+	master, _ := NewMasterKey(seed, MainnetPrivate)
+
+	key, err := master.DerivePath(make([]uint32, 255))
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(255), key.Depth)
+
+	_, err = key.Child(0)
+	assert.Equal(t, ErrDeriveBeyondMaxDepth, err)
+}
+
+func TestExtendedKeyWIF(t *testing.T) {
+	seed, _ := hex.DecodeString(bip32TestVector()[0].seed)
+	master, _ := NewMasterKey(seed, MainnetPrivate)
+
+	wif, err := master.WIF(WIFMainnet)
+	assert.NoError(t, err)
+
+	key, compressed, _, err := DecodeWIF(wif)
+	assert.NoError(t, err)
+	assert.True(t, compressed)
+	assert.Equal(t, master.Key, key)
+}
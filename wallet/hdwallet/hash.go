@@ -0,0 +1,26 @@
+package hdwallet
+
+import (
+	"crypto/sha256"
+
+	"golang.org/x/crypto/ripemd160"
+)
+
+// Hash160 returns RIPEMD160(SHA256(data))
+func Hash160(data []byte) []byte {
+
+	hash := sha256.Sum256(data)
+	h := ripemd160.New()
+	h.Write(hash[:])
+
+	return h.Sum(nil)
+}
+
+// DoubleSha256 returns SHA256(SHA256(data))
+func DoubleSha256(data []byte) []byte {
+
+	hash := sha256.Sum256(data)
+	hash = sha256.Sum256(hash[:])
+
+	return hash[:]
+}
@@ -0,0 +1,39 @@
+package hdwallet
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type hashtest struct {
+	input   string
+	hash160 string
+	sha256d string
+}
+
+func hashTestVector() []hashtest {
+	return []hashtest{
+		{
+			input:   "",
+			hash160: "b472a266d0bd89c13706a4132ccfb16f7c3b9fcb",
+			sha256d: "5df6e0e2761359d30a8275058e299fcc0381534545f55cf43e41983f5d4c9456",
+		},
+		{
+			input:   "0250863ad64a87ae8a2fe83c1af1a8403cb53f53e486d8511dad8a04887e5b2352",
+			hash160: "f54a5851e9372b87810a8e60cdd2e7cfd80b6e31",
+			sha256d: "f96f26c87cf635e0503596967ee25cd32dce2a6784279f302121468ad3520f94",
+		},
+	}
+}
+
+func TestHash160(t *testing.T) {
+	for _, test := range hashTestVector() {
+		input, err := hex.DecodeString(test.input)
+		assert.NoError(t, err)
+
+		assert.Equal(t, test.hash160, hex.EncodeToString(Hash160(input)))
+		assert.Equal(t, test.sha256d, hex.EncodeToString(DoubleSha256(input)))
+	}
+}
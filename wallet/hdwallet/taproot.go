@@ -0,0 +1,141 @@
+package hdwallet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+)
+
+// BaseLeafVersion is the leaf version of BIP342 tapscript leaves
+const BaseLeafVersion = 0xc0
+
+// ErrInvalidTweak is returned when a taproot tweak is out of range or
+// produces the point at infinity
+var ErrInvalidTweak = errors.New("hdwallet: invalid taproot tweak")
+
+// TapLeafHash returns the BIP341 hash of a script tree leaf
+func TapLeafHash(leafVersion byte, script []byte) []byte {
+
+	data := append([]byte{leafVersion}, compactSize(uint64(len(script)))...)
+
+	return secp256k1.TaggedHash("TapLeaf", data, script)
+}
+
+// TapBranchHash returns the BIP341 hash of a script tree branch, the two
+// children are sorted lexicographically
+func TapBranchHash(a, b []byte) []byte {
+
+	if bytes.Compare(a, b) > 0 {
+		a, b = b, a
+	}
+
+	return secp256k1.TaggedHash("TapBranch", a, b)
+}
+
+// TaprootTweak returns the BIP341 tweak of an internal key, merkleRoot is
+// nil for key path only outputs
+func TaprootTweak(internal *secp256k1.PublicKey, merkleRoot []byte) []byte {
+	return secp256k1.TaggedHash("TapTweak", internal.SerializeXOnly(), merkleRoot)
+}
+
+// TaprootTweakPubKey returns the output key Q = P + tG where P is the even y
+// lift of the internal key, the parity of Q is needed by script path spends
+func TaprootTweakPubKey(internal *secp256k1.PublicKey, merkleRoot []byte) (*secp256k1.PublicKey, error) {
+
+	p, err := secp256k1.ParseXOnlyPubKey(internal.SerializeXOnly())
+	if err != nil {
+		return nil, err
+	}
+
+	t := TaprootTweak(internal, merkleRoot)
+	if new(big.Int).SetBytes(t).Cmp(secp256k1.N) >= 0 {
+		return nil, ErrInvalidTweak
+	}
+
+	tx, ty := secp256k1.ScalarBaseMult(t)
+	qx, qy := secp256k1.Add(p.X, p.Y, tx, ty)
+
+	if qx.Sign() == 0 && qy.Sign() == 0 {
+		return nil, ErrInvalidTweak
+	}
+
+	return &secp256k1.PublicKey{X: qx, Y: qy}, nil
+}
+
+// TaprootTweakPrivKey returns the private key of the output key obtained by
+// tweaking the public key of key with merkleRoot
+func TaprootTweakPrivKey(key *secp256k1.PrivateKey, merkleRoot []byte) (*secp256k1.PrivateKey, error) {
+
+	d := new(big.Int).Set(key.D)
+	if !key.HasEvenY() {
+		d.Sub(secp256k1.N, d)
+	}
+
+	t := new(big.Int).SetBytes(TaprootTweak(key.PubKey(), merkleRoot))
+	if t.Cmp(secp256k1.N) >= 0 {
+		return nil, ErrInvalidTweak
+	}
+
+	d.Add(d, t)
+	d.Mod(d, secp256k1.N)
+
+	if d.Sign() == 0 {
+		return nil, ErrInvalidTweak
+	}
+
+	return secp256k1.PrivKeyFromBytes(secp256k1.PaddedBytes(d, 32))
+}
+
+// TaprootOutputKey returns the BIP86 style taproot output key committing to
+// the public key of the extended key and to the optional merkle root
+func (k *ExtendedKey) TaprootOutputKey(merkleRoot []byte) (*secp256k1.PublicKey, error) {
+
+	pub, err := k.PubKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return TaprootTweakPubKey(pub, merkleRoot)
+}
+
+// TaprootSign produces a BIP340 key path signature of a taproot sighash with
+// the tweaked private key of the extended key
+func (k *ExtendedKey) TaprootSign(hash []byte, merkleRoot []byte, auxRand []byte) ([]byte, error) {
+
+	priv, err := k.PrivKey()
+	if err != nil {
+		return nil, err
+	}
+
+	tweaked, err := TaprootTweakPrivKey(priv, merkleRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	return secp256k1.SchnorrSign(tweaked, hash, auxRand)
+}
+
+// compactSize encodes n as a Bitcoin variable length integer
+func compactSize(n uint64) []byte {
+
+	switch {
+	case n < 0xfd:
+		return []byte{byte(n)}
+	case n <= 0xffff:
+		b := []byte{0xfd, 0, 0}
+		binary.LittleEndian.PutUint16(b[1:], uint16(n))
+		return b
+	case n <= 0xffffffff:
+		b := []byte{0xfe, 0, 0, 0, 0}
+		binary.LittleEndian.PutUint32(b[1:], uint32(n))
+		return b
+	}
+
+	b := []byte{0xff, 0, 0, 0, 0, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint64(b[1:], n)
+
+	return b
+}
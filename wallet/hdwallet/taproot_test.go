@@ -0,0 +1,134 @@
+package hdwallet
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/giogam/Gopher-Wallet/wallet/mnemonic"
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+	"github.com/stretchr/testify/assert"
+)
+
+type taproottest struct {
+	internalKey string
+	leafScript  string
+	merkleRoot  string
+	tweak       string
+	outputKey   string
+}
+
+// taprootTestVector contains the scriptPubKey vectors of BIP341
+func taprootTestVector() []taproottest {
+	return []taproottest{
+		{
+			internalKey: "d6889cb081036e0faefa3a35157ad71086b123b2b144b649798b494c300a961d",
+			tweak:       "b86e7be8f39bab32a6f2c0443abbc210f0edac0e2c53d501b36b64437d9c6c70",
+			outputKey:   "53a1f6e454df1aa2776a2814a721372d6258050de330b3c6d10ee8f4e0dda343",
+		},
+		{
+			internalKey: "187791b6f712a8ea41c8ecdd0ee77fab3e85263b37e1ec18a3651926b3a6cf27",
+			leafScript:  "20d85a959b0290bf19bb89ed43c916be835475d013da4b362117393e25a48229b8ac",
+			merkleRoot:  "5b75adecf53548f3ec6ad7d78383bf84cc57b55a3127c72b9a2481752dd88b21",
+			tweak:       "cbd8679ba636c1110ea247542cfbd964131a6be84f873f7f3b62a777528ed001",
+			outputKey:   "147c9c57132f6e7ecddba9800bb0c4449251c92a1e60371ee77557b6620f3ea3",
+		},
+	}
+}
+
+func TestTaprootTweak(t *testing.T) {
+	for _, test := range taprootTestVector() {
+		internal, err := secp256k1.ParseXOnlyPubKey(mustDecode(test.internalKey))
+		assert.NoError(t, err)
+
+		var root []byte
+		if test.leafScript != "" {
+			root = TapLeafHash(BaseLeafVersion, mustDecode(test.leafScript))
+			assert.Equal(t, test.merkleRoot, hex.EncodeToString(root))
+		}
+
+		assert.Equal(t, test.tweak, hex.EncodeToString(TaprootTweak(internal, root)))
+
+		output, err := TaprootTweakPubKey(internal, root)
+		assert.NoError(t, err)
+		assert.Equal(t, test.outputKey, hex.EncodeToString(output.SerializeXOnly()))
+	}
+}
+
+type bip86test struct {
+	path        string
+	internalKey string
+	outputKey   string
+}
+
+func bip86TestVector() []bip86test {
+	return []bip86test{
+		{
+			path:        "m/86'/0'/0'/0/0",
+			internalKey: "cc8a4bc64d897bddc5fbc2f670f7a8ba0b386779106cf1223c6fc5d7cd6fc115",
+			outputKey:   "a60869f0dbcf1dc659c9cecbaf8050135ea9e8cdc487053f1dc6880949dc684c",
+		},
+		{
+			path:        "m/86'/0'/0'/0/1",
+			internalKey: "83dfe85a3151d2517290da461fe2815591ef69f2b18a2ce63f01697a8b313145",
+			outputKey:   "a82f29944d65b86ae6b5e5cc75e294ead6c59391a1edc5e016e3498c67fc7bbb",
+		},
+		{
+			path:        "m/86'/0'/0'/1/0",
+			internalKey: "399f1b2f4393f29a18c937859c5dd8a77350103157eb880f02e8c08214277cef",
+			outputKey:   "882d74e5d0572d5a816cef0041a96b6c1de832f6f9676d9605c44d5e9a97d3dc",
+		},
+	}
+}
+
+func TestBIP86(t *testing.T) {
+	words := strings.Split("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", " ")
+	master, err := NewMasterKey(mnemonic.NewSeed(words, ""), MainnetPrivate)
+	assert.NoError(t, err)
+
+	for _, test := range bip86TestVector() {
+		key, err := master.Derive(test.path)
+		assert.NoError(t, err)
+
+		pub, err := key.PubKey()
+		assert.NoError(t, err)
+		assert.Equal(t, test.internalKey, hex.EncodeToString(pub.SerializeXOnly()))
+
+		output, err := key.TaprootOutputKey(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, test.outputKey, hex.EncodeToString(output.SerializeXOnly()))
+
+		// a key path signature must verify against the output key
+		hash := secp256k1.TaggedHash("TapSighash", []byte(test.path))
+		sig, err := key.TaprootSign(hash, nil, nil)
+		assert.NoError(t, err)
+		assert.True(t, secp256k1.SchnorrVerify(output.SerializeXOnly(), hash, sig))
+	}
+}
+
+func TestTaprootTweakPrivKey(t *testing.T) {
+	key, err := secp256k1.NewPrivateKey()
+	assert.NoError(t, err)
+
+	leafA := TapLeafHash(BaseLeafVersion, []byte{0x51})
+	leafB := TapLeafHash(BaseLeafVersion, []byte{0x52})
+	root := TapBranchHash(leafA, leafB)
+	assert.Equal(t, root, TapBranchHash(leafB, leafA))
+
+	for _, merkleRoot := range [][]byte{nil, root} {
+		tweaked, err := TaprootTweakPrivKey(key, merkleRoot)
+		assert.NoError(t, err)
+
+		output, err := TaprootTweakPubKey(key.PubKey(), merkleRoot)
+		assert.NoError(t, err)
+		assert.True(t, tweaked.PubKey().IsEqual(output))
+	}
+}
+
+func mustDecode(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
@@ -0,0 +1,100 @@
+package hdwallet
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+)
+
+const (
+	// WIFMainnet is the version byte of mainnet WIF private keys
+	WIFMainnet = 0x80
+	// WIFTestnet is the version byte of testnet WIF private keys
+	WIFTestnet = 0xef
+
+	wifKeyLen        = 32
+	wifCompressedTag = 0x01
+)
+
+var (
+	// ErrInvalidWIFVersion is returned when the WIF version byte is unknown
+	ErrInvalidWIFVersion = errors.New("hdwallet: invalid WIF version")
+	// ErrInvalidLength is returned when a decoded payload has a wrong size
+	ErrInvalidLength = errors.New("hdwallet: invalid length")
+	// ErrInvalidWIFFlag is returned when the compression flag is not 0x01
+	ErrInvalidWIFFlag = errors.New("hdwallet: invalid WIF compression flag")
+	// ErrInvalidChecksum is returned when a Base58Check checksum does not match
+	ErrInvalidChecksum = errors.New("hdwallet: invalid checksum")
+	// ErrInvalidBase58 is returned when a string contains non Base58 characters
+	ErrInvalidBase58 = errors.New("hdwallet: invalid base58 string")
+	// ErrInvalidPrivateKey is returned when a private key is out of range
+	ErrInvalidPrivateKey = errors.New("hdwallet: invalid private key")
+)
+
+// EncodeWIF encodes a 32 bytes private key in Wallet Import Format
+func EncodeWIF(key []byte, compressed bool, version int) (string, error) {
+
+	if version != WIFMainnet && version != WIFTestnet {
+		return "", ErrInvalidWIFVersion
+	}
+
+	if !validPrivateKey(key) {
+		return "", ErrInvalidPrivateKey
+	}
+
+	payload := append([]byte{}, key...)
+	if compressed {
+		payload = append(payload, wifCompressedTag)
+	}
+
+	wif, _ := B58CheckEncode(version, payload)
+
+	return wif, nil
+}
+
+// DecodeWIF decodes a Wallet Import Format string returning the private key,
+// whether the respective public key is compressed and the version byte
+func DecodeWIF(wif string) ([]byte, bool, int, error) {
+
+	version, payload, err := B58CheckDecodeStrict(wif)
+	if err != nil {
+		return nil, false, 0, err
+	}
+
+	if version != WIFMainnet && version != WIFTestnet {
+		return nil, false, 0, ErrInvalidWIFVersion
+	}
+
+	compressed := false
+
+	switch len(payload) {
+	case wifKeyLen:
+	case wifKeyLen + 1:
+		if payload[wifKeyLen] != wifCompressedTag {
+			return nil, false, 0, ErrInvalidWIFFlag
+		}
+		compressed = true
+	default:
+		return nil, false, 0, ErrInvalidLength
+	}
+
+	key := payload[:wifKeyLen]
+	if !validPrivateKey(key) {
+		return nil, false, 0, ErrInvalidPrivateKey
+	}
+
+	return key, compressed, version, nil
+}
+
+// validPrivateKey checks that key is a 32 bytes integer in [1, n-1]
+func validPrivateKey(key []byte) bool {
+
+	if len(key) != wifKeyLen {
+		return false
+	}
+
+	k := new(big.Int).SetBytes(key)
+
+	return k.Sign() > 0 && k.Cmp(secp256k1.N) < 0
+}
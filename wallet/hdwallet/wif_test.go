@@ -0,0 +1,159 @@
+package hdwallet
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type wiftest struct {
+	key        string
+	version    int
+	compressed bool
+	wif        string
+}
+
+func wifTestVector() []wiftest {
+	return []wiftest{
+		{
+			key:        "0c28fca386c7a227600b2fe50b7cae11ec86d3bf1fbe471be89827e19d72aa1d",
+			version:    WIFMainnet,
+			compressed: false,
+			wif:        "5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTJ",
+		},
+		{
+			key:        "0c28fca386c7a227600b2fe50b7cae11ec86d3bf1fbe471be89827e19d72aa1d",
+			version:    WIFMainnet,
+			compressed: true,
+			wif:        "KwdMAjGmerYanjeui5SHS7JkmpZvVipYvB2LJGU1ZxJwYvP98617",
+		},
+		{
+			key:        "0c28fca386c7a227600b2fe50b7cae11ec86d3bf1fbe471be89827e19d72aa1d",
+			version:    WIFTestnet,
+			compressed: false,
+			wif:        "91gGn1HgSap6CbU12F6z3pJri26xzp7Ay1VW6NHCoEayNXwRpu2",
+		},
+		{
+			key:        "0c28fca386c7a227600b2fe50b7cae11ec86d3bf1fbe471be89827e19d72aa1d",
+			version:    WIFTestnet,
+			compressed: true,
+			wif:        "cMzLdeGd5vEqxB8B6VFQoRopQ3sLAAvEzDAoQgvX54xwofSWj1fx",
+		},
+		{
+			key:        "0000000000000000000000000000000000000000000000000000000000000001",
+			version:    WIFMainnet,
+			compressed: false,
+			wif:        "5HpHagT65TZzG1PH3CSu63k8DbpvD8s5ip4nEB3kEsreAnchuDf",
+		},
+		{
+			key:        "0000000000000000000000000000000000000000000000000000000000000001",
+			version:    WIFMainnet,
+			compressed: true,
+			wif:        "KwDiBf89QgGbjEhKnhXJuH7LrciVrZi3qYjgd9M7rFU73sVHnoWn",
+		},
+		{
+			key:        "fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364140",
+			version:    WIFMainnet,
+			compressed: true,
+			wif:        "L5oLkpV3aqBjhki6LmvChTCV6odsp4SXM6FfU2Gppt5kFLaHLuZ9",
+		},
+		{
+			key:        "fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364140",
+			version:    WIFTestnet,
+			compressed: false,
+			wif:        "93XfLeifX7KMMtUGa7xouxtnFWSSUyzNPgjrJ6Npsyahfqjy7oJ",
+		},
+	}
+}
+
+type wifInvalidtest struct {
+	wif string
+	err error
+}
+
+func wifInvalidTestVector() []wifInvalidtest {
+	return []wifInvalidtest{
+		{
+			// bad checksum
+			wif: "5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTj",
+			err: ErrInvalidChecksum,
+		},
+		{
+			// non base58 character
+			wif: "5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvy0J",
+			err: ErrInvalidBase58,
+		},
+		{
+			wif: "",
+			err: ErrInvalidLength,
+		},
+		{
+			// unknown version 0x81
+			wif: "5KrPNVvAhnRBNMYRJUq58YMfyUMyVMQrQhhfFtcbT9rK67poC3F",
+			err: ErrInvalidWIFVersion,
+		},
+		{
+			// 31 bytes key
+			wif: "yPoVP5njSzmEVK4VJGRWWAwqnwCyLPRcMm5XyrKgY1DE64xhu",
+			err: ErrInvalidLength,
+		},
+		{
+			// compression flag 0x02
+			wif: "KwdMAjGmerYanjeui5SHS7JkmpZvVipYvB2LJGU1ZxJwYvWxyf5d",
+			err: ErrInvalidWIFFlag,
+		},
+		{
+			// key equal to the curve order
+			wif: "L5oLkpV3aqBjhki6LmvChTCV6odsp4SXM6FfU2Gppt5kFqRzExJJ",
+			err: ErrInvalidPrivateKey,
+		},
+		{
+			// zero key
+			wif: "5HpHagT65TZzG1PH3CSu63k8DbpvD8s5ip4nEB3kEsreAbuatmU",
+			err: ErrInvalidPrivateKey,
+		},
+	}
+}
+
+func TestWIFEncode(t *testing.T) {
+	for _, test := range wifTestVector() {
+		key, err := hex.DecodeString(test.key)
+		assert.NoError(t, err)
+
+		wif, err := EncodeWIF(key, test.compressed, test.version)
+		assert.NoError(t, err)
+		assert.Equal(t, test.wif, wif)
+	}
+}
+
+func TestWIFDecode(t *testing.T) {
+	for _, test := range wifTestVector() {
+		key, compressed, version, err := DecodeWIF(test.wif)
+		assert.NoError(t, err)
+
+		assert.Equal(t, test.key, hex.EncodeToString(key))
+		assert.Equal(t, test.compressed, compressed)
+		assert.Equal(t, test.version, version)
+	}
+}
+
+func TestWIFDecodeInvalid(t *testing.T) {
+	for _, test := range wifInvalidTestVector() {
+		_, _, _, err := DecodeWIF(test.wif)
+		assert.Equal(t, test.err, err, test.wif)
+	}
+}
+
+func TestWIFEncodeInvalid(t *testing.T) {
+	key, _ := hex.DecodeString("0c28fca386c7a227600b2fe50b7cae11ec86d3bf1fbe471be89827e19d72aa1d")
+
+	_, err := EncodeWIF(key, true, 0x00)
+	assert.Equal(t, ErrInvalidWIFVersion, err)
+
+	_, err = EncodeWIF(key[:31], true, WIFMainnet)
+	assert.Equal(t, ErrInvalidPrivateKey, err)
+
+	_, err = EncodeWIF(make([]byte, 32), true, WIFMainnet)
+	assert.Equal(t, ErrInvalidPrivateKey, err)
+}
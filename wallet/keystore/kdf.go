@@ -0,0 +1,102 @@
+package keystore
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// key derivation functions
+const (
+	Argon2id = "argon2id"
+	Scrypt   = "scrypt"
+)
+
+const (
+	keySize  = 32
+	saltSize = 16
+
+	// limits on the parameters read from files, so that a crafted file
+	// cannot exhaust memory, 4 GiB of memory and 64 passes
+	maxArgon2Memory = 4 * 1024 * 1024
+	maxArgon2Time   = 64
+	maxScryptMemory = 4 * 1024 * 1024 * 1024
+)
+
+var (
+	// DefaultArgon2id are the RFC 9106 second recommended parameters,
+	// 64 MiB of memory
+	DefaultArgon2id = KDFParams{Name: Argon2id, Time: 3, Memory: 64 * 1024, Threads: 4}
+	// DefaultScrypt uses 128 MiB of memory
+	DefaultScrypt = KDFParams{Name: Scrypt, N: 1 << 17, R: 8, P: 1}
+)
+
+// ErrInvalidKDF is returned for an unknown key derivation function or
+// parameters out of bounds
+var ErrInvalidKDF = errors.New("keystore: invalid key derivation parameters")
+
+// KDFParams are a password based key derivation function and its costs.
+// Argon2id uses Time passes over Memory KiB with Threads lanes, scrypt
+// uses the cost N, a power of two, the block size R and parallelism P.
+type KDFParams struct {
+	Name    string `json:"name"`
+	Time    uint32 `json:"time,omitempty"`
+	Memory  uint32 `json:"memory,omitempty"`
+	Threads uint8  `json:"threads,omitempty"`
+	N       int    `json:"n,omitempty"`
+	R       int    `json:"r,omitempty"`
+	P       int    `json:"p,omitempty"`
+}
+
+// validate checks the parameters are usable and within the limits
+func (p KDFParams) validate() error {
+
+	switch p.Name {
+	case Argon2id:
+		if p.Time == 0 || p.Time > maxArgon2Time || p.Threads == 0 ||
+			p.Memory < 8*uint32(p.Threads) || p.Memory > maxArgon2Memory {
+			return ErrInvalidKDF
+		}
+
+	case Scrypt:
+		if p.N <= 1 || p.N&(p.N-1) != 0 || p.R <= 0 || p.P <= 0 ||
+			uint64(p.R)*uint64(p.P) >= 1<<30 || 128*uint64(p.N)*uint64(p.R) > maxScryptMemory {
+			return ErrInvalidKDF
+		}
+
+	default:
+		return ErrInvalidKDF
+	}
+
+	return nil
+}
+
+// derive returns the key derived from password and salt
+func (p KDFParams) derive(password string, salt []byte) ([]byte, error) {
+
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+
+	if p.Name == Argon2id {
+		return argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, keySize), nil
+	}
+
+	return scrypt.Key([]byte(password), salt, p.N, p.R, p.P, keySize)
+}
+
+// weaker reports whether p costs less than target in time or memory, a
+// function other than the target's is always replaced
+func (p KDFParams) weaker(target KDFParams) bool {
+
+	if p.Name != target.Name {
+		return true
+	}
+
+	if p.Name == Argon2id {
+		return p.Time < target.Time || p.Memory < target.Memory
+	}
+
+	return p.N < target.N || p.R < target.R || p.P < target.P
+}
@@ -0,0 +1,79 @@
+package keystore
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// cheap parameters keep the tests fast
+var (
+	testArgon2id = KDFParams{Name: Argon2id, Time: 1, Memory: 64, Threads: 1}
+	testScrypt   = KDFParams{Name: Scrypt, N: 1024, R: 8, P: 1}
+)
+
+func TestValidate(t *testing.T) {
+
+	tests := []struct {
+		params KDFParams
+		valid  bool
+	}{
+		{DefaultArgon2id, true},
+		{DefaultScrypt, true},
+		{testArgon2id, true},
+		{testScrypt, true},
+		{KDFParams{Name: "pbkdf2"}, false},
+		{KDFParams{Name: Argon2id, Time: 0, Memory: 64, Threads: 1}, false},
+		{KDFParams{Name: Argon2id, Time: 1, Memory: 64, Threads: 0}, false},
+		{KDFParams{Name: Argon2id, Time: 1, Memory: 8, Threads: 2}, false},
+		{KDFParams{Name: Argon2id, Time: 1, Memory: maxArgon2Memory + 1, Threads: 1}, false},
+		{KDFParams{Name: Argon2id, Time: maxArgon2Time + 1, Memory: 64, Threads: 1}, false},
+		{KDFParams{Name: Scrypt, N: 1000, R: 8, P: 1}, false},
+		{KDFParams{Name: Scrypt, N: 1, R: 8, P: 1}, false},
+		{KDFParams{Name: Scrypt, N: 1024, R: 0, P: 1}, false},
+		{KDFParams{Name: Scrypt, N: 1 << 23, R: 8, P: 1}, false},
+	}
+
+	for _, tt := range tests {
+		err := tt.params.validate()
+		if tt.valid {
+			assert.NoError(t, err, tt.params)
+		} else {
+			assert.ErrorIs(t, err, ErrInvalidKDF, tt.params)
+		}
+	}
+}
+
+func TestDerive(t *testing.T) {
+
+	// RFC 7914 test vector, truncated to the key size
+	key, err := KDFParams{Name: Scrypt, N: 1024, R: 8, P: 16}.derive("password", []byte("NaCl"))
+	assert.NoError(t, err)
+	assert.Equal(t, "fdbabe1c9d3472007856e7190d01e9fe7c6ad7cbc8237830e77376634b373162", hex.EncodeToString(key))
+
+	a, err := testArgon2id.derive("password", []byte("somesaltsomesalt"))
+	assert.NoError(t, err)
+	assert.Len(t, a, keySize)
+	b, err := testArgon2id.derive("passwore", []byte("somesaltsomesalt"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, a, b)
+
+	_, err = KDFParams{Name: "none"}.derive("password", nil)
+	assert.ErrorIs(t, err, ErrInvalidKDF)
+}
+
+func TestWeaker(t *testing.T) {
+
+	assert.False(t, DefaultArgon2id.weaker(DefaultArgon2id))
+	assert.True(t, testArgon2id.weaker(DefaultArgon2id))
+	assert.True(t, DefaultScrypt.weaker(DefaultArgon2id))
+	assert.True(t, DefaultArgon2id.weaker(DefaultScrypt))
+	assert.True(t, testScrypt.weaker(DefaultScrypt))
+	assert.False(t, DefaultScrypt.weaker(testScrypt))
+
+	// more lanes do not make a derivation stronger
+	more := DefaultArgon2id
+	more.Threads = 8
+	assert.False(t, DefaultArgon2id.weaker(more))
+}
@@ -0,0 +1,301 @@
+package keystore
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	// Version is the version of the keystore format written
+	Version = 1
+
+	cipherName = "xchacha20-poly1305"
+)
+
+var (
+	// ErrInvalidFile is returned when a keystore cannot be parsed
+	ErrInvalidFile = errors.New("keystore: invalid keystore file")
+	// ErrUnsupportedVersion is returned for keystores of unknown versions
+	ErrUnsupportedVersion = errors.New("keystore: unsupported keystore version")
+	// ErrWrongPassword is returned when a keystore cannot be decrypted,
+	// either the password is wrong or the file was modified
+	ErrWrongPassword = errors.New("keystore: wrong password or corrupted keystore")
+)
+
+// header is the part of a keystore in clear, it is authenticated along
+// with the encrypted wallet
+type header struct {
+	Version int       `json:"version"`
+	Cipher  string    `json:"cipher"`
+	KDF     KDFParams `json:"kdf"`
+	Salt    string    `json:"salt"`
+}
+
+// file is a keystore, the wallet encrypted with a key derived from a
+// password
+type file struct {
+	header
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// Encrypt returns the keystore holding w encrypted with password, with a
+// key derived with params
+func Encrypt(w *Wallet, password string, params KDFParams) ([]byte, error) {
+
+	plaintext, err := json.Marshal(w)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(plaintext)
+
+	salt := make([]byte, saltSize)
+	nonce := make([]byte, chacha20poly1305.NonceSizeX)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	f := file{
+		header: header{Version: Version, Cipher: cipherName, KDF: params, Salt: hex.EncodeToString(salt)},
+		Nonce:  hex.EncodeToString(nonce),
+	}
+	aad, err := json.Marshal(f.header)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := params.derive(password, salt)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(key)
+
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	f.Ciphertext = hex.EncodeToString(aead.Seal(nil, nonce, plaintext, aad))
+
+	return json.MarshalIndent(f, "", "  ")
+}
+
+// Decrypt returns the wallet of a keystore
+func Decrypt(data []byte, password string) (*Wallet, error) {
+
+	f, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	salt, err := hex.DecodeString(f.Salt)
+	if err != nil || len(salt) < saltSize {
+		return nil, ErrInvalidFile
+	}
+	nonce, err := hex.DecodeString(f.Nonce)
+	if err != nil || len(nonce) != chacha20poly1305.NonceSizeX {
+		return nil, ErrInvalidFile
+	}
+	ciphertext, err := hex.DecodeString(f.Ciphertext)
+	if err != nil {
+		return nil, ErrInvalidFile
+	}
+	aad, err := json.Marshal(f.header)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := f.KDF.derive(password, salt)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(key)
+
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, ErrWrongPassword
+	}
+	defer zero(plaintext)
+
+	var w Wallet
+	if err := json.Unmarshal(plaintext, &w); err != nil {
+		return nil, ErrInvalidFile
+	}
+
+	return &w, nil
+}
+
+// KDF returns the key derivation parameters of a keystore
+func KDF(data []byte) (KDFParams, error) {
+
+	f, err := parse(data)
+	if err != nil {
+		return KDFParams{}, err
+	}
+
+	return f.KDF, nil
+}
+
+// NeedsUpgrade reports whether a keystore has an older version or a key
+// derivation weaker than params, it does not need the password
+func NeedsUpgrade(data []byte, params KDFParams) (bool, error) {
+
+	f, err := parse(data)
+	if err != nil {
+		return false, err
+	}
+
+	return f.Version < Version || f.KDF.weaker(params), nil
+}
+
+// parse reads the clear part of a keystore and checks it is supported
+func parse(data []byte) (*file, error) {
+
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, ErrInvalidFile
+	}
+
+	if f.Version < 1 || f.Version > Version {
+		return nil, ErrUnsupportedVersion
+	}
+	if f.Cipher != cipherName {
+		return nil, ErrInvalidFile
+	}
+	if err := f.KDF.validate(); err != nil {
+		return nil, err
+	}
+
+	return &f, nil
+}
+
+// Write atomically replaces the keystore at path with w encrypted with
+// password, either the previous or the new keystore is left on failure
+func Write(path string, w *Wallet, password string, params KDFParams) error {
+
+	data, err := Encrypt(w, password, params)
+	if err != nil {
+		return err
+	}
+
+	return writeFile(path, data)
+}
+
+// Read returns the wallet of the keystore at path
+func Read(path string, password string) (*Wallet, error) {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Decrypt(data, password)
+}
+
+// ChangePassword re-encrypts the keystore at path with newPassword, with
+// the same key derivation parameters and a new salt
+func ChangePassword(path string, oldPassword, newPassword string) error {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	w, err := Decrypt(data, oldPassword)
+	if err != nil {
+		return err
+	}
+	params, err := KDF(data)
+	if err != nil {
+		return err
+	}
+
+	return Write(path, w, newPassword, params)
+}
+
+// Upgrade re-encrypts the keystore at path with params at the current
+// version if NeedsUpgrade, it reports whether the keystore was rewritten
+func Upgrade(path string, password string, params KDFParams) (bool, error) {
+
+	if err := params.validate(); err != nil {
+		return false, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	upgrade, err := NeedsUpgrade(data, params)
+	if err != nil || !upgrade {
+		return false, err
+	}
+
+	w, err := Decrypt(data, password)
+	if err != nil {
+		return false, err
+	}
+
+	return true, Write(path, w, password, params)
+}
+
+// writeFile writes data to a temporary file next to path, syncs it and
+// renames it over path
+func writeFile(path string, data []byte) error {
+
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+name+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	// the rename is durable once the directory is synced, which some
+	// platforms do not support
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	return nil
+}
+
+// zero overwrites a secret once it is no longer needed
+func zero(b []byte) {
+
+	for i := range b {
+		b[i] = 0
+	}
+}
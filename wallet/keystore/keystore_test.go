@@ -0,0 +1,199 @@
+package keystore
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testWallet(t *testing.T) *Wallet {
+
+	meta, err := NewAccount("spending", testAccount(t))
+	assert.NoError(t, err)
+
+	return &Wallet{Mnemonic: strings.Fields(testMnemonic), PassphraseHint: "the usual", Accounts: []*Account{meta}}
+}
+
+// edit rewrites the fields of a keystore
+func edit(t *testing.T, data []byte, change func(map[string]interface{})) []byte {
+
+	var fields map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &fields))
+	change(fields)
+	data, err := json.Marshal(fields)
+	assert.NoError(t, err)
+
+	return data
+}
+
+func TestEncrypt(t *testing.T) {
+
+	w := testWallet(t)
+
+	for _, params := range []KDFParams{testArgon2id, testScrypt} {
+		data, err := Encrypt(w, "correct horse", params)
+		assert.NoError(t, err)
+		assert.NotContains(t, string(data), "abandon")
+		assert.NotContains(t, string(data), "xpub")
+
+		decrypted, err := Decrypt(data, "correct horse")
+		assert.NoError(t, err)
+		assert.Equal(t, w, decrypted)
+
+		kdf, err := KDF(data)
+		assert.NoError(t, err)
+		assert.Equal(t, params, kdf)
+
+		_, err = Decrypt(data, "wrong horse")
+		assert.ErrorIs(t, err, ErrWrongPassword)
+
+		// a fresh salt and nonce are used for each encryption
+		again, err := Encrypt(w, "correct horse", params)
+		assert.NoError(t, err)
+		assert.NotEqual(t, data, again)
+	}
+}
+
+func TestDecryptErrors(t *testing.T) {
+
+	data, err := Encrypt(testWallet(t), "pw", testArgon2id)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		change func(map[string]interface{})
+		err    error
+	}{
+		{"weakened kdf", func(f map[string]interface{}) { f["kdf"].(map[string]interface{})["time"] = 2 }, ErrWrongPassword},
+		{"other salt", func(f map[string]interface{}) { f["salt"] = strings.Repeat("00", saltSize) }, ErrWrongPassword},
+		{"modified ciphertext", func(f map[string]interface{}) {
+			c := f["ciphertext"].(string)
+			f["ciphertext"] = "00" + c[2:]
+		}, ErrWrongPassword},
+		{"newer version", func(f map[string]interface{}) { f["version"] = Version + 1 }, ErrUnsupportedVersion},
+		{"no version", func(f map[string]interface{}) { delete(f, "version") }, ErrUnsupportedVersion},
+		{"other cipher", func(f map[string]interface{}) { f["cipher"] = "aes-256-gcm" }, ErrInvalidFile},
+		{"short nonce", func(f map[string]interface{}) { f["nonce"] = "0011" }, ErrInvalidFile},
+		{"short salt", func(f map[string]interface{}) { f["salt"] = "0011" }, ErrInvalidFile},
+		{"bad ciphertext", func(f map[string]interface{}) { f["ciphertext"] = "zz" }, ErrInvalidFile},
+		{"huge memory", func(f map[string]interface{}) { f["kdf"].(map[string]interface{})["memory"] = maxArgon2Memory * 2 }, ErrInvalidKDF},
+	}
+
+	for _, tt := range tests {
+		_, err := Decrypt(edit(t, data, tt.change), "pw")
+		assert.ErrorIs(t, err, tt.err, tt.name)
+	}
+
+	_, err = Decrypt([]byte("not a keystore"), "pw")
+	assert.ErrorIs(t, err, ErrInvalidFile)
+}
+
+func TestReadWrite(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "wallet.json")
+	w := testWallet(t)
+
+	assert.NoError(t, Write(path, w, "pw", testArgon2id))
+	read, err := Read(path, "pw")
+	assert.NoError(t, err)
+	assert.Equal(t, w, read)
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// a failed write leaves the keystore as it was
+	before, _ := os.ReadFile(path)
+	assert.ErrorIs(t, Write(path, &Wallet{Seed: []byte{1}}, "pw", KDFParams{Name: "none"}), ErrInvalidKDF)
+	after, _ := os.ReadFile(path)
+	assert.Equal(t, before, after)
+
+	w.Accounts[0].Next = [2]uint32{7, 3}
+	assert.NoError(t, Write(path, w, "pw", testArgon2id))
+	read, err = Read(path, "pw")
+	assert.NoError(t, err)
+	assert.Equal(t, [2]uint32{7, 3}, read.Accounts[0].Next)
+
+	// no temporary file is left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	_, err = Read(filepath.Join(t.TempDir(), "missing.json"), "pw")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.Error(t, Write(filepath.Join(t.TempDir(), "missing", "wallet.json"), w, "pw", testArgon2id))
+}
+
+func TestChangePassword(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "wallet.json")
+	w := testWallet(t)
+	assert.NoError(t, Write(path, w, "old", testScrypt))
+	before, _ := os.ReadFile(path)
+
+	assert.ErrorIs(t, ChangePassword(path, "wrong", "new"), ErrWrongPassword)
+	assert.NoError(t, ChangePassword(path, "old", "new"))
+
+	_, err := Read(path, "old")
+	assert.ErrorIs(t, err, ErrWrongPassword)
+	read, err := Read(path, "new")
+	assert.NoError(t, err)
+	assert.Equal(t, w, read)
+
+	after, _ := os.ReadFile(path)
+	kdf, err := KDF(after)
+	assert.NoError(t, err)
+	assert.Equal(t, testScrypt, kdf)
+
+	var f1, f2 file
+	assert.NoError(t, json.Unmarshal(before, &f1))
+	assert.NoError(t, json.Unmarshal(after, &f2))
+	assert.NotEqual(t, f1.Salt, f2.Salt)
+}
+
+func TestUpgrade(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "wallet.json")
+	w := testWallet(t)
+	assert.NoError(t, Write(path, w, "pw", testScrypt))
+
+	stronger := testArgon2id
+	stronger.Time = 2
+
+	data, _ := os.ReadFile(path)
+	upgrade, err := NeedsUpgrade(data, stronger)
+	assert.NoError(t, err)
+	assert.True(t, upgrade)
+
+	upgraded, err := Upgrade(path, "wrong", stronger)
+	assert.ErrorIs(t, err, ErrWrongPassword)
+	assert.False(t, upgraded)
+	after, _ := os.ReadFile(path)
+	assert.Equal(t, data, after)
+
+	_, err = Upgrade(path, "pw", KDFParams{Name: Argon2id})
+	assert.ErrorIs(t, err, ErrInvalidKDF)
+
+	upgraded, err = Upgrade(path, "pw", stronger)
+	assert.NoError(t, err)
+	assert.True(t, upgraded)
+	data, _ = os.ReadFile(path)
+	kdf, err := KDF(data)
+	assert.NoError(t, err)
+	assert.Equal(t, stronger, kdf)
+	read, err := Read(path, "pw")
+	assert.NoError(t, err)
+	assert.Equal(t, w, read)
+
+	// weaker parameters do not downgrade the keystore
+	upgraded, err = Upgrade(path, "pw", testArgon2id)
+	assert.NoError(t, err)
+	assert.False(t, upgraded)
+	upgrade, err = NeedsUpgrade(data, stronger)
+	assert.NoError(t, err)
+	assert.False(t, upgrade)
+}
@@ -0,0 +1,125 @@
+package keystore
+
+import (
+	"encoding/hex"
+	"errors"
+
+	"github.com/giogam/Gopher-Wallet/wallet/account"
+	"github.com/giogam/Gopher-Wallet/wallet/address"
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/mnemonic"
+)
+
+var (
+	// ErrNoSecret is returned when a wallet holds neither a mnemonic nor
+	// a seed
+	ErrNoSecret = errors.New("keystore: wallet has no mnemonic or seed")
+	// ErrInvalidAccount is returned when stored account metadata cannot be
+	// restored
+	ErrInvalidAccount = errors.New("keystore: invalid account")
+)
+
+// networks are the networks accounts may be stored for, by name
+var networks = map[string]*address.Network{
+	address.MainNet.Name: address.MainNet,
+	address.TestNet.Name: address.TestNet,
+	address.RegTest.Name: address.RegTest,
+}
+
+// accountTypes are the address types of single key accounts, by name
+var accountTypes = map[string]address.Type{
+	address.P2PKH.String():  address.P2PKH,
+	address.P2SH.String():   address.P2SH,
+	address.P2WPKH.String(): address.P2WPKH,
+	address.P2TR.String():   address.P2TR,
+}
+
+// Wallet is the content of a keystore, the BIP39 mnemonic or the seed of
+// the wallet, a hint of its BIP39 passphrase and its accounts
+type Wallet struct {
+	Mnemonic       []string   `json:"mnemonic,omitempty"`
+	Seed           []byte     `json:"seed,omitempty"`
+	PassphraseHint string     `json:"passphrase_hint,omitempty"`
+	Accounts       []*Account `json:"accounts,omitempty"`
+}
+
+// Account is the metadata of an account and its extended public key,
+// enough to watch it without the seed
+type Account struct {
+	Name        string    `json:"name"`
+	Type        string    `json:"type"`
+	Network     string    `json:"network"`
+	Fingerprint string    `json:"fingerprint"`
+	Path        []uint32  `json:"path"`
+	Xpub        string    `json:"xpub"`
+	Next        [2]uint32 `json:"next"`
+}
+
+// MasterSeed returns the seed of the wallet, derived from the mnemonic
+// and passphrase when the wallet has a mnemonic
+func (w *Wallet) MasterSeed(passphrase string) ([]byte, error) {
+
+	if len(w.Mnemonic) > 0 {
+		return mnemonic.NewSeed(w.Mnemonic, passphrase), nil
+	}
+	if len(w.Seed) > 0 {
+		return append([]byte{}, w.Seed...), nil
+	}
+
+	return nil, ErrNoSecret
+}
+
+// NewAccount returns the metadata of a, its key is stored neutered
+func NewAccount(name string, a *account.Account) (*Account, error) {
+
+	if _, ok := accountTypes[a.Type.String()]; !ok {
+		return nil, ErrInvalidAccount
+	}
+	if _, ok := networks[a.Net.Name]; !ok {
+		return nil, ErrInvalidAccount
+	}
+
+	xpub, err := a.Key.Neuter()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Account{
+		Name:        name,
+		Type:        a.Type.String(),
+		Network:     a.Net.Name,
+		Fingerprint: hex.EncodeToString(a.Fingerprint),
+		Path:        append([]uint32{}, a.Path...),
+		Xpub:        xpub.String(),
+		Next:        a.Next,
+	}, nil
+}
+
+// Account returns the watch only account described by the metadata
+func (a *Account) Account() (*account.Account, error) {
+
+	t, ok := accountTypes[a.Type]
+	if !ok {
+		return nil, ErrInvalidAccount
+	}
+	net, ok := networks[a.Network]
+	if !ok {
+		return nil, ErrInvalidAccount
+	}
+	fingerprint, err := hex.DecodeString(a.Fingerprint)
+	if err != nil || len(fingerprint) != 4 {
+		return nil, ErrInvalidAccount
+	}
+	key, err := hdwallet.ParseExtendedKey(a.Xpub)
+	if err != nil || key.IsPrivate {
+		return nil, ErrInvalidAccount
+	}
+
+	acct, err := account.NewWatchOnly(key, fingerprint, append([]uint32{}, a.Path...), t, net)
+	if err != nil {
+		return nil, err
+	}
+	acct.Next = a.Next
+
+	return acct, nil
+}
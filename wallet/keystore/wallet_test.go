@@ -0,0 +1,77 @@
+package keystore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/giogam/Gopher-Wallet/wallet/account"
+	"github.com/giogam/Gopher-Wallet/wallet/address"
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/mnemonic"
+	"github.com/stretchr/testify/assert"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+func testAccount(t *testing.T) *account.Account {
+
+	master, err := hdwallet.NewMasterKey(mnemonic.NewSeed(strings.Fields(testMnemonic), ""), hdwallet.MainnetPrivate)
+	assert.NoError(t, err)
+	a, err := account.New(master, address.P2WPKH, 0, address.MainNet)
+	assert.NoError(t, err)
+
+	return a
+}
+
+func TestMasterSeed(t *testing.T) {
+
+	words := strings.Fields(testMnemonic)
+	seed, err := (&Wallet{Mnemonic: words, Seed: []byte{1}}).MasterSeed("TREZOR")
+	assert.NoError(t, err)
+	assert.Equal(t, mnemonic.NewSeed(words, "TREZOR"), seed)
+
+	seed, err = (&Wallet{Seed: []byte{1, 2, 3}}).MasterSeed("")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{1, 2, 3}, seed)
+
+	_, err = (&Wallet{}).MasterSeed("")
+	assert.ErrorIs(t, err, ErrNoSecret)
+}
+
+func TestAccount(t *testing.T) {
+
+	a := testAccount(t)
+	a.Next = [2]uint32{5, 2}
+
+	meta, err := NewAccount("savings", a)
+	assert.NoError(t, err)
+	assert.Equal(t, "p2wpkh", meta.Type)
+	assert.Equal(t, "mainnet", meta.Network)
+	assert.Equal(t, "73c5da0a", meta.Fingerprint)
+	assert.True(t, strings.HasPrefix(meta.Xpub, "xpub"))
+
+	watch, err := meta.Account()
+	assert.NoError(t, err)
+	assert.False(t, watch.Key.IsPrivate)
+	assert.Equal(t, a.Path, watch.Path)
+	assert.Equal(t, [2]uint32{5, 2}, watch.Next)
+	addr, err := watch.Address(account.External, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", addr.String())
+
+	for _, change := range []func(*Account){
+		func(a *Account) { a.Type = "p2wsh" },
+		func(a *Account) { a.Network = "signet" },
+		func(a *Account) { a.Fingerprint = "73c5" },
+		func(a *Account) { a.Xpub = "xpub" },
+	} {
+		bad := *meta
+		change(&bad)
+		_, err := bad.Account()
+		assert.ErrorIs(t, err, ErrInvalidAccount)
+	}
+
+	a.Type = address.P2WSH
+	_, err = NewAccount("multisig", a)
+	assert.ErrorIs(t, err, ErrInvalidAccount)
+}
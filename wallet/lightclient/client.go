@@ -0,0 +1,419 @@
+package lightclient
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/giogam/Gopher-Wallet/wallet/gcs"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+const (
+	// MaxHeaders is the largest number of headers a peer returns at once
+	MaxHeaders = 2000
+	// MaxFilters is the largest number of filters requested at once
+	MaxFilters = 1000
+	// CheckpointInterval is the number of blocks between the filter
+	// headers of checkpoints
+	CheckpointInterval = 1000
+)
+
+var (
+	// ErrNotSynced is returned when the filter headers of the requested
+	// blocks are not known
+	ErrNotSynced = errors.New("lightclient: filter headers not synced")
+	// ErrUnexpectedResponse is returned when a response of the peer is not
+	// for the requested blocks
+	ErrUnexpectedResponse = errors.New("lightclient: unexpected response")
+	// ErrCheckpointMismatch is returned when a filter header does not match
+	// its checkpoint
+	ErrCheckpointMismatch = errors.New("lightclient: filter header checkpoint mismatch")
+	// ErrCheckpointConflict is returned when the check peers disagree with
+	// the peer on the filter headers of the chain
+	ErrCheckpointConflict = errors.New("lightclient: peers disagree on filter checkpoints")
+	// ErrUncheckedPeer is returned when the filter headers of the peer
+	// could be checked against neither checkpoints nor check peers
+	ErrUncheckedPeer = errors.New("lightclient: no checkpoints or check peers to check the peer against")
+	// ErrFilterHeaderMismatch is returned when filter headers do not
+	// connect to the known ones
+	ErrFilterHeaderMismatch = errors.New("lightclient: filter headers do not connect")
+	// ErrFilterMismatch is returned when a filter does not match its
+	// filter header
+	ErrFilterMismatch = errors.New("lightclient: filter does not match its header")
+	// ErrInvalidBlock is returned when a block does not match its header
+	ErrInvalidBlock = errors.New("lightclient: block does not match its header")
+)
+
+// Peer is a full node serving block headers, BIP158 basic filters with
+// their BIP157 filter headers, and blocks. The client checks everything
+// it returns.
+type Peer interface {
+	// Headers returns up to MaxHeaders headers following the first hash
+	// of locator known to the peer
+	Headers(locator []transaction.Hash) ([]*transaction.BlockHeader, error)
+	// FilterCheckpoints returns the filter headers of every
+	// CheckpointInterval blocks up to the block stop
+	FilterCheckpoints(stop *transaction.Hash) ([]transaction.Hash, error)
+	// FilterHashes returns the filter header of the block before start and
+	// the filter hashes of the blocks from start to the block stop
+	FilterHashes(start int32, stop *transaction.Hash) (*transaction.Hash, []transaction.Hash, error)
+	// Filters returns the filters of the blocks from start to the block
+	// stop
+	Filters(start int32, stop *transaction.Hash) ([]*BlockFilter, error)
+	// Block returns a block with its witnesses
+	Block(hash *transaction.Hash) (*transaction.Block, error)
+	Close() error
+}
+
+// BlockFilter is the serialized basic filter of a block
+type BlockFilter struct {
+	BlockHash transaction.Hash
+	Filter    []byte
+}
+
+// Config holds the network and the peers of a client. Peer serves the
+// chain, CheckPeers are other peers, preferably run by other parties, whose
+// filter checkpoints must agree with the ones of Peer. TrustPeer accepts
+// the filter headers of a lone Peer on a network without checkpoints past
+// genesis, it is meant for a node run by the user.
+type Config struct {
+	Params     *Params
+	Peer       Peer
+	CheckPeers []Peer
+	TrustPeer  bool
+}
+
+// Match is a block whose filter matched the scanned scripts
+type Match struct {
+	Height int32
+	Block  *transaction.Block
+}
+
+// Client follows the chain with the most work through the block headers
+// of a peer, and the BIP157 filter headers committing to their BIP158
+// filters, so that the blocks relevant to a wallet are found from the
+// filters. The headers are checked against the proof of work, the filters
+// and blocks against the filter headers. The filter headers themselves are
+// only checked against the checkpoints of the network and the check peers:
+// with neither, a peer serving the headers of the chain can hide
+// transactions by committing to filters it made up, so it must be trusted.
+type Client struct {
+	cfg Config
+
+	mu    sync.Mutex
+	chain *headerChain
+	// filterHeaders are the basic filter headers of the chain from
+	// genesis, they may stop below its tip
+	filterHeaders []transaction.Hash
+}
+
+// New returns a client for the network of cfg, it starts from genesis.
+// It refuses a lone peer that would go unchecked unless it is trusted.
+func New(cfg Config) (*Client, error) {
+
+	if len(cfg.CheckPeers) == 0 && !cfg.TrustPeer && !hasCheckpoints(cfg.Params) {
+		return nil, ErrUncheckedPeer
+	}
+
+	filter, err := gcs.BuildBasicFilter(cfg.Params.Genesis, nil)
+	if err != nil {
+		return nil, err
+	}
+	filterHash := gcs.FilterHash(filter.Bytes())
+	header := gcs.FilterHeader(&filterHash, &transaction.Hash{})
+	if checkpoint, ok := cfg.Params.FilterCheckpoints[0]; ok && checkpoint != header {
+		return nil, ErrCheckpointMismatch
+	}
+
+	return &Client{
+		cfg:           cfg,
+		chain:         newHeaderChain(cfg.Params),
+		filterHeaders: []transaction.Hash{header},
+	}, nil
+}
+
+// hasCheckpoints reports whether params have filter checkpoints past
+// genesis, which every peer commits to
+func hasCheckpoints(params *Params) bool {
+
+	for height := range params.FilterCheckpoints {
+		if height > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Close closes the peers
+func (c *Client) Close() error {
+
+	err := c.cfg.Peer.Close()
+	for _, p := range c.cfg.CheckPeers {
+		if closeErr := p.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
+// BestBlock returns the height and the header of the tip of the chain
+func (c *Client) BestBlock() (int32, *transaction.BlockHeader, error) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tip := c.chain.tip()
+
+	return tip, c.chain.headers[tip], nil
+}
+
+// FilterHeader returns the basic filter header of the block at height
+func (c *Client) FilterHeader(height int32) (*transaction.Hash, error) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if height < 0 || int(height) >= len(c.filterHeaders) {
+		return nil, ErrNotSynced
+	}
+	header := c.filterHeaders[height]
+
+	return &header, nil
+}
+
+// Sync downloads the headers of the peer and the filter headers of the
+// chain with the most work
+func (c *Client) Sync() error {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.syncHeaders(); err != nil {
+		return err
+	}
+
+	return c.syncFilterHeaders()
+}
+
+func (c *Client) syncHeaders() error {
+
+	for {
+		headers, err := c.cfg.Peer.Headers(c.chain.locator())
+		if err != nil {
+			return err
+		}
+
+		tip := c.chain.tip()
+		fork, err := c.chain.connect(headers)
+		if err != nil {
+			return err
+		}
+		// the filter headers of the blocks left by a reorg are dropped
+		if int(fork)+1 < len(c.filterHeaders) {
+			c.filterHeaders = c.filterHeaders[:fork+1]
+		}
+
+		if len(headers) < MaxHeaders || (fork == tip && c.chain.tip() == tip) {
+			return nil
+		}
+	}
+}
+
+func (c *Client) syncFilterHeaders() error {
+
+	tip := c.chain.tip()
+	if int(tip) < len(c.filterHeaders) {
+		return nil
+	}
+
+	stopHash := c.chain.hashes[tip]
+	filterHeaders, err := c.cfg.Peer.FilterCheckpoints(&stopHash)
+	if err != nil {
+		return err
+	}
+	if len(filterHeaders) != int(tip)/CheckpointInterval {
+		return ErrUnexpectedResponse
+	}
+
+	for _, p := range c.cfg.CheckPeers {
+		others, err := p.FilterCheckpoints(&stopHash)
+		if err != nil {
+			return err
+		}
+		if len(others) != len(filterHeaders) {
+			return ErrCheckpointConflict
+		}
+		for i := range others {
+			if others[i] != filterHeaders[i] {
+				return fmt.Errorf("lightclient: height %d: %w", (i+1)*CheckpointInterval, ErrCheckpointConflict)
+			}
+		}
+	}
+
+	// checkpoints[h] is the filter header the peer commits to at height h
+	checkpoints := make(map[int32]transaction.Hash, len(filterHeaders))
+	for i, header := range filterHeaders {
+		height := int32(i+1) * CheckpointInterval
+		if known, ok := c.cfg.Params.FilterCheckpoints[height]; ok && known != header {
+			return fmt.Errorf("lightclient: height %d: %w", height, ErrCheckpointMismatch)
+		}
+		if int(height) < len(c.filterHeaders) && c.filterHeaders[height] != header {
+			return fmt.Errorf("lightclient: height %d: %w", height, ErrCheckpointMismatch)
+		}
+		checkpoints[height] = header
+	}
+
+	// the filter hashes are requested one checkpoint interval at a time
+	for start := int32(len(c.filterHeaders)); start <= tip; start = int32(len(c.filterHeaders)) {
+		stop := (start/CheckpointInterval + 1) * CheckpointInterval
+		if stop > tip {
+			stop = tip
+		}
+
+		stopHash := c.chain.hashes[stop]
+		prev, filterHashes, err := c.cfg.Peer.FilterHashes(start, &stopHash)
+		if err != nil {
+			return err
+		}
+		if len(filterHashes) != int(stop-start+1) {
+			return ErrUnexpectedResponse
+		}
+		if *prev != c.filterHeaders[start-1] {
+			return fmt.Errorf("lightclient: height %d: %w", start, ErrFilterHeaderMismatch)
+		}
+
+		headers := make([]transaction.Hash, len(filterHashes))
+		header := *prev
+		for i := range filterHashes {
+			header = gcs.FilterHeader(&filterHashes[i], &header)
+			headers[i] = header
+		}
+		if checkpoint, ok := checkpoints[stop]; ok && checkpoint != header {
+			return fmt.Errorf("lightclient: height %d: %w", stop, ErrCheckpointMismatch)
+		}
+		if known, ok := c.cfg.Params.FilterCheckpoints[stop]; ok && known != header {
+			return fmt.Errorf("lightclient: height %d: %w", stop, ErrCheckpointMismatch)
+		}
+
+		c.filterHeaders = append(c.filterHeaders, headers...)
+	}
+
+	return nil
+}
+
+// Rescan matches the filters of the blocks from startHeight to the tip
+// against pkScripts and returns the matching blocks in height order. The
+// filters match both the outputs paying to the scripts and the inputs
+// spending them.
+func (c *Client) Rescan(pkScripts [][]byte, startHeight int32) ([]*Match, error) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tip := c.chain.tip()
+	if int(tip) >= len(c.filterHeaders) {
+		return nil, ErrNotSynced
+	}
+	if startHeight < 0 {
+		startHeight = 0
+	}
+	if len(pkScripts) == 0 {
+		return nil, nil
+	}
+
+	var heights []int32
+	for start := startHeight; start <= tip; start += MaxFilters {
+		stop := start + MaxFilters - 1
+		if stop > tip {
+			stop = tip
+		}
+
+		matched, err := c.matchFilters(pkScripts, start, stop)
+		if err != nil {
+			return nil, err
+		}
+		heights = append(heights, matched...)
+	}
+
+	matches := make([]*Match, 0, len(heights))
+	for _, height := range heights {
+		block, err := c.fetchBlock(height)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, &Match{Height: height, Block: block})
+	}
+
+	return matches, nil
+}
+
+// matchFilters returns the heights from start to stop whose filter
+// matches pkScripts
+func (c *Client) matchFilters(pkScripts [][]byte, start, stop int32) ([]int32, error) {
+
+	stopHash := c.chain.hashes[stop]
+	filters, err := c.cfg.Peer.Filters(start, &stopHash)
+	if err != nil {
+		return nil, err
+	}
+	if len(filters) != int(stop-start+1) {
+		return nil, ErrUnexpectedResponse
+	}
+
+	var matched []int32
+	for i, f := range filters {
+		height := start + int32(i)
+		if f.BlockHash != c.chain.hashes[height] {
+			return nil, ErrUnexpectedResponse
+		}
+
+		var prev transaction.Hash
+		if height > 0 {
+			prev = c.filterHeaders[height-1]
+		}
+		filterHash := gcs.FilterHash(f.Filter)
+		if gcs.FilterHeader(&filterHash, &prev) != c.filterHeaders[height] {
+			return nil, fmt.Errorf("lightclient: height %d: %w", height, ErrFilterMismatch)
+		}
+
+		filter, err := gcs.FromBasicBytes(f.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("lightclient: height %d: %w", height, err)
+		}
+		ok, err := filter.MatchAny(gcs.BlockKey(&f.BlockHash), pkScripts)
+		if err != nil {
+			return nil, fmt.Errorf("lightclient: height %d: %w", height, err)
+		}
+		if ok {
+			matched = append(matched, height)
+		}
+	}
+
+	return matched, nil
+}
+
+// fetchBlock downloads the block at height with its witnesses, checking
+// the block against its header and the witnesses against the coinbase
+func (c *Client) fetchBlock(height int32) (*transaction.Block, error) {
+
+	hash := c.chain.hashes[height]
+	block, err := c.cfg.Peer.Block(&hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if block.BlockHash() != hash || block.MerkleRoot() != block.Header.MerkleRoot {
+		return nil, fmt.Errorf("lightclient: height %d: %w", height, ErrInvalidBlock)
+	}
+
+	// the txids leave the witnesses out, they are committed to by the
+	// coinbase
+	if err := block.CheckWitnessCommitment(); err != nil {
+		return nil, fmt.Errorf("lightclient: height %d: %w: %w", height, ErrInvalidBlock, err)
+	}
+
+	return block, nil
+}
@@ -0,0 +1,506 @@
+package lightclient
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/giogam/Gopher-Wallet/wallet/gcs"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	minerScript  = append([]byte{0x00, 0x14}, bytes.Repeat([]byte{0x01}, 20)...)
+	walletScript = append([]byte{0x00, 0x14}, bytes.Repeat([]byte{0x02}, 20)...)
+	otherScript  = append([]byte{0x00, 0x14}, bytes.Repeat([]byte{0x03}, 20)...)
+)
+
+// testChain is a regtest chain with the filters of its blocks
+type testChain struct {
+	tag           byte
+	blocks        []*transaction.Block
+	filters       [][]byte
+	filterHeaders []transaction.Hash
+	// scripts are the scripts of the outputs created, to build filters
+	scripts map[transaction.OutPoint][]byte
+}
+
+func newTestChain(t *testing.T) *testChain {
+
+	c := &testChain{scripts: make(map[transaction.OutPoint][]byte)}
+	c.addBlock(t, RegTestParams.Genesis)
+
+	return c
+}
+
+// fork returns a copy of the chain up to height, tag makes its blocks
+// differ from the ones of c
+func (c *testChain) fork(height int32, tag byte) *testChain {
+
+	f := &testChain{
+		tag:           tag,
+		blocks:        append([]*transaction.Block(nil), c.blocks[:height+1]...),
+		filters:       append([][]byte(nil), c.filters[:height+1]...),
+		filterHeaders: append([]transaction.Hash(nil), c.filterHeaders[:height+1]...),
+		scripts:       make(map[transaction.OutPoint][]byte),
+	}
+	for op, script := range c.scripts {
+		f.scripts[op] = script
+	}
+
+	return f
+}
+
+func (c *testChain) addBlock(t *testing.T, block *transaction.Block) {
+
+	var prevOutScripts [][]byte
+	for _, tx := range block.Transactions {
+		if !tx.IsCoinBase() {
+			for _, in := range tx.TxIn {
+				prevOutScripts = append(prevOutScripts, c.scripts[in.PreviousOutPoint])
+			}
+		}
+		hash := tx.TxHash()
+		for i, out := range tx.TxOut {
+			c.scripts[*transaction.NewOutPoint(&hash, uint32(i))] = out.PkScript
+		}
+	}
+
+	filter, err := gcs.BuildBasicFilter(block, prevOutScripts)
+	assert.NoError(t, err)
+
+	prev := transaction.Hash{}
+	if len(c.filterHeaders) > 0 {
+		prev = c.filterHeaders[len(c.filterHeaders)-1]
+	}
+	filterHash := gcs.FilterHash(filter.Bytes())
+
+	c.blocks = append(c.blocks, block)
+	c.filters = append(c.filters, filter.Bytes())
+	c.filterHeaders = append(c.filterHeaders, gcs.FilterHeader(&filterHash, &prev))
+}
+
+// withFilter returns a copy of the chain where the block at height has the
+// given filter, as served by a peer hiding its transactions
+func (c *testChain) withFilter(height int32, filter []byte) *testChain {
+
+	f := c.fork(int32(len(c.blocks)-1), c.tag)
+	f.filters[height] = filter
+	for h := height; h < int32(len(f.blocks)); h++ {
+		filterHash := gcs.FilterHash(f.filters[h])
+		f.filterHeaders[h] = gcs.FilterHeader(&filterHash, &f.filterHeaders[h-1])
+	}
+
+	return f
+}
+
+// mine adds a block with a coinbase paying minerScript and txs
+func (c *testChain) mine(t *testing.T, txs ...*transaction.Tx) *transaction.Block {
+
+	height := len(c.blocks)
+	coinbase := transaction.NewTx(2)
+	coinbase.AddTxIn(transaction.NewTxIn(transaction.NewOutPoint(&transaction.Hash{}, 0xffffffff),
+		[]byte{0x03, byte(height), byte(height >> 8), c.tag}, nil))
+	coinbase.AddTxOut(transaction.NewTxOut(50e8, minerScript))
+
+	block := &transaction.Block{Transactions: append([]*transaction.Tx{coinbase}, txs...)}
+	for _, tx := range txs {
+		if tx.HasWitness() {
+			reserved := make([]byte, transaction.HashSize)
+			root := block.WitnessMerkleRoot()
+			commitment := transaction.WitnessCommitment(root[:], reserved)
+			coinbase.TxIn[0].Witness = transaction.Witness{reserved}
+			coinbase.AddTxOut(transaction.NewTxOut(0, append([]byte{0x6a, 0x24, 0xaa, 0x21, 0xa9, 0xed}, commitment[:]...)))
+			break
+		}
+	}
+	prev := &c.blocks[height-1].Header
+	block.Header = *mine(prev, block.MerkleRoot(), prev.Timestamp+600, 0x207fffff)
+	c.addBlock(t, block)
+
+	return block
+}
+
+// pay returns a transaction spending output index of prev to script
+func pay(prev *transaction.Tx, index uint32, script []byte) *transaction.Tx {
+
+	hash := prev.TxHash()
+	tx := transaction.NewTx(2)
+	tx.AddTxIn(transaction.NewTxIn(transaction.NewOutPoint(&hash, index), nil, [][]byte{{0x01}}))
+	tx.AddTxOut(transaction.NewTxOut(prev.TxOut[index].Value-1000, script))
+
+	return tx
+}
+
+// testPeer serves a chain from memory, the tamper functions may change
+// its responses
+type testPeer struct {
+	chain  *testChain
+	closed bool
+
+	tamperHeaders     func([]*transaction.BlockHeader)
+	tamperCheckpoints func([]transaction.Hash) []transaction.Hash
+	tamperHashes      func(prev *transaction.Hash, hashes []transaction.Hash)
+	tamperFilters     func([]*BlockFilter) []*BlockFilter
+	tamperBlock       func(*transaction.Block)
+}
+
+// height returns the height of the block hash in the chain, or -1
+func (p *testPeer) height(hash *transaction.Hash) int32 {
+
+	for i, block := range p.chain.blocks {
+		if block.BlockHash() == *hash {
+			return int32(i)
+		}
+	}
+
+	return -1
+}
+
+func (p *testPeer) Headers(locator []transaction.Hash) ([]*transaction.BlockHeader, error) {
+
+	start := int32(0)
+	for i := range locator {
+		if h := p.height(&locator[i]); h >= 0 {
+			start = h + 1
+			break
+		}
+	}
+
+	var headers []*transaction.BlockHeader
+	for h := start; h < int32(len(p.chain.blocks)) && len(headers) < MaxHeaders; h++ {
+		header := p.chain.blocks[h].Header
+		headers = append(headers, &header)
+	}
+	if p.tamperHeaders != nil {
+		p.tamperHeaders(headers)
+	}
+
+	return headers, nil
+}
+
+func (p *testPeer) FilterCheckpoints(stop *transaction.Hash) ([]transaction.Hash, error) {
+
+	var checkpoints []transaction.Hash
+	for h, last := int32(CheckpointInterval), p.height(stop); h <= last; h += CheckpointInterval {
+		checkpoints = append(checkpoints, p.chain.filterHeaders[h])
+	}
+	if p.tamperCheckpoints != nil {
+		checkpoints = p.tamperCheckpoints(checkpoints)
+	}
+
+	return checkpoints, nil
+}
+
+func (p *testPeer) FilterHashes(start int32, stop *transaction.Hash) (*transaction.Hash, []transaction.Hash, error) {
+
+	prev := p.chain.filterHeaders[start-1]
+	var hashes []transaction.Hash
+	for h, last := start, p.height(stop); h <= last; h++ {
+		hashes = append(hashes, gcs.FilterHash(p.chain.filters[h]))
+	}
+	if p.tamperHashes != nil {
+		p.tamperHashes(&prev, hashes)
+	}
+
+	return &prev, hashes, nil
+}
+
+func (p *testPeer) Filters(start int32, stop *transaction.Hash) ([]*BlockFilter, error) {
+
+	var filters []*BlockFilter
+	for h, last := start, p.height(stop); h <= last; h++ {
+		filters = append(filters, &BlockFilter{BlockHash: p.chain.blocks[h].BlockHash(), Filter: p.chain.filters[h]})
+	}
+	if p.tamperFilters != nil {
+		filters = p.tamperFilters(filters)
+	}
+
+	return filters, nil
+}
+
+func (p *testPeer) Block(hash *transaction.Hash) (*transaction.Block, error) {
+
+	block := *p.chain.blocks[p.height(hash)]
+	if p.tamperBlock != nil {
+		p.tamperBlock(&block)
+	}
+
+	return &block, nil
+}
+
+func (p *testPeer) Close() error {
+
+	p.closed = true
+
+	return nil
+}
+
+// testChainWithPayments returns a chain of 2100 blocks, walletScript is
+// paid at height 5 and spent at height 8
+func testChainWithPayments(t *testing.T) (*testChain, *transaction.Tx, *transaction.Tx) {
+
+	c := newTestChain(t)
+	for h := 1; h <= 4; h++ {
+		c.mine(t)
+	}
+	payment := pay(c.blocks[1].Transactions[0], 0, walletScript)
+	spend := pay(payment, 0, otherScript)
+	c.mine(t, payment)
+	c.mine(t)
+	c.mine(t)
+	c.mine(t, spend)
+	for h := 9; h <= 2100; h++ {
+		c.mine(t)
+	}
+
+	return c, payment, spend
+}
+
+func newTestClient(t *testing.T, p Peer) *Client {
+
+	c, err := New(Config{Params: RegTestParams, Peer: p, TrustPeer: true})
+	assert.NoError(t, err)
+
+	return c
+}
+
+func TestSyncAndRescan(t *testing.T) {
+
+	chain, payment, spend := testChainWithPayments(t)
+	p := &testPeer{chain: chain}
+	c := newTestClient(t, p)
+
+	_, err := c.FilterHeader(1)
+	assert.ErrorIs(t, err, ErrNotSynced)
+
+	assert.NoError(t, c.Sync())
+
+	height, header, err := c.BestBlock()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2100), height)
+	assert.Equal(t, chain.blocks[2100].Header, *header)
+	for _, h := range []int32{0, 1000, 2100} {
+		filterHeader, err := c.FilterHeader(h)
+		assert.NoError(t, err)
+		assert.Equal(t, chain.filterHeaders[h], *filterHeader)
+	}
+
+	matches, err := c.Rescan([][]byte{walletScript}, 0)
+	assert.NoError(t, err)
+	assert.Len(t, matches, 2)
+	assert.Equal(t, int32(5), matches[0].Height)
+	assert.Equal(t, payment.TxHash(), matches[0].Block.Transactions[1].TxHash())
+	assert.Equal(t, int32(8), matches[1].Height)
+	assert.Equal(t, spend.TxHash(), matches[1].Block.Transactions[1].TxHash())
+	assert.Equal(t, spend.TxIn[0].Witness, matches[1].Block.Transactions[1].TxIn[0].Witness)
+
+	matches, err = c.Rescan([][]byte{walletScript, otherScript}, 6)
+	assert.NoError(t, err)
+	assert.Len(t, matches, 1)
+	assert.Equal(t, int32(8), matches[0].Height)
+
+	// syncing again with nothing new is a no-op
+	assert.NoError(t, c.Sync())
+	height, _, _ = c.BestBlock()
+	assert.Equal(t, int32(2100), height)
+
+	assert.NoError(t, c.Close())
+	assert.True(t, p.closed)
+}
+
+func TestReorgSync(t *testing.T) {
+
+	chain, _, _ := testChainWithPayments(t)
+	p := &testPeer{chain: chain}
+	c := newTestClient(t, p)
+	assert.NoError(t, c.Sync())
+
+	// a longer branch from height 2095 pays walletScript at 2098
+	fork := chain.fork(2095, 1)
+	fork.mine(t)
+	fork.mine(t)
+	payment := pay(fork.blocks[1000].Transactions[0], 0, walletScript)
+	fork.mine(t, payment)
+	for i := 0; i < 5; i++ {
+		fork.mine(t)
+	}
+	p.chain = fork
+
+	assert.NoError(t, c.Sync())
+	height, header, err := c.BestBlock()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2103), height)
+	assert.Equal(t, fork.blocks[2103].Header, *header)
+	filterHeader, err := c.FilterHeader(2097)
+	assert.NoError(t, err)
+	assert.Equal(t, fork.filterHeaders[2097], *filterHeader)
+
+	matches, err := c.Rescan([][]byte{walletScript}, 2000)
+	assert.NoError(t, err)
+	assert.Len(t, matches, 1)
+	assert.Equal(t, int32(2098), matches[0].Height)
+	assert.Equal(t, payment.TxHash(), matches[0].Block.Transactions[1].TxHash())
+}
+
+func TestTamperedPeer(t *testing.T) {
+
+	chain, _, _ := testChainWithPayments(t)
+
+	tests := []struct {
+		name   string
+		tamper func(p *testPeer)
+		rescan bool
+		err    error
+	}{
+		{
+			name: "filter hash",
+			tamper: func(p *testPeer) {
+				p.tamperHashes = func(prev *transaction.Hash, hashes []transaction.Hash) {
+					if *prev == chain.filterHeaders[0] {
+						hashes[5] = transaction.Hash{1}
+					}
+				}
+			},
+			err: ErrCheckpointMismatch,
+		},
+		{
+			name: "previous filter header",
+			tamper: func(p *testPeer) {
+				p.tamperHashes = func(prev *transaction.Hash, hashes []transaction.Hash) {
+					*prev = transaction.Hash{1}
+				}
+			},
+			err: ErrFilterHeaderMismatch,
+		},
+		{
+			name: "checkpoint",
+			tamper: func(p *testPeer) {
+				p.tamperCheckpoints = func(checkpoints []transaction.Hash) []transaction.Hash {
+					return checkpoints[1:]
+				}
+			},
+			err: ErrUnexpectedResponse,
+		},
+		{
+			name: "header",
+			tamper: func(p *testPeer) {
+				p.tamperHeaders = func(headers []*transaction.BlockHeader) {
+					if len(headers) > 10 {
+						headers[10].PrevBlock = transaction.Hash{1}
+					}
+				}
+			},
+			err: ErrOrphanHeader,
+		},
+		{
+			name: "filter",
+			tamper: func(p *testPeer) {
+				p.tamperFilters = func(filters []*BlockFilter) []*BlockFilter {
+					filters[5].Filter = chain.filters[4]
+					return filters
+				}
+			},
+			rescan: true,
+			err:    ErrFilterMismatch,
+		},
+		{
+			name: "missing filter",
+			tamper: func(p *testPeer) {
+				p.tamperFilters = func(filters []*BlockFilter) []*BlockFilter {
+					return filters[1:]
+				}
+			},
+			rescan: true,
+			err:    ErrUnexpectedResponse,
+		},
+		{
+			name: "block",
+			tamper: func(p *testPeer) {
+				p.tamperBlock = func(block *transaction.Block) {
+					block.Transactions = block.Transactions[:1]
+				}
+			},
+			rescan: true,
+			err:    ErrInvalidBlock,
+		},
+		{
+			name: "witness",
+			tamper: func(p *testPeer) {
+				p.tamperBlock = func(block *transaction.Block) {
+					block.Transactions = append([]*transaction.Tx{}, block.Transactions...)
+					tx := block.Transactions[1].Copy()
+					tx.TxIn[0].Witness = transaction.Witness{{0x02}}
+					block.Transactions[1] = tx
+				}
+			},
+			rescan: true,
+			err:    transaction.ErrWitnessCommitment,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			p := &testPeer{chain: chain}
+			c := newTestClient(t, p)
+
+			if tt.rescan {
+				assert.NoError(t, c.Sync())
+				tt.tamper(p)
+				_, err := c.Rescan([][]byte{walletScript}, 0)
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+
+			tt.tamper(p)
+			assert.ErrorIs(t, c.Sync(), tt.err)
+		})
+	}
+}
+
+func TestGenesisCheckpoints(t *testing.T) {
+	for _, params := range []*Params{MainNetParams, TestNetParams, RegTestParams} {
+		_, err := New(Config{Params: params, Peer: &testPeer{}, TrustPeer: true})
+		assert.NoError(t, err, params.Name)
+	}
+}
+
+func TestCheckPeers(t *testing.T) {
+
+	chain, _, _ := testChainWithPayments(t)
+	honest := &testPeer{chain: chain}
+	liar := &testPeer{chain: chain.withFilter(5, chain.filters[4])}
+
+	// a lone peer is refused unless trusted
+	_, err := New(Config{Params: RegTestParams, Peer: liar})
+	assert.Equal(t, ErrUncheckedPeer, err)
+
+	// a trusted peer committing to made up filters hides the payment
+	c := newTestClient(t, liar)
+	assert.NoError(t, c.Sync())
+	matches, err := c.Rescan([][]byte{walletScript}, 0)
+	assert.NoError(t, err)
+	assert.Len(t, matches, 1)
+
+	c, err = New(Config{Params: RegTestParams, Peer: liar, CheckPeers: []Peer{honest}})
+	assert.NoError(t, err)
+	assert.ErrorIs(t, c.Sync(), ErrCheckpointConflict)
+
+	// so is a lone peer on a network with checkpoints past genesis
+	params := *RegTestParams
+	params.FilterCheckpoints = map[int32]transaction.Hash{1000: chain.filterHeaders[1000]}
+	c, err = New(Config{Params: &params, Peer: liar})
+	assert.NoError(t, err)
+	assert.ErrorIs(t, c.Sync(), ErrCheckpointMismatch)
+
+	c, err = New(Config{Params: RegTestParams, Peer: honest, CheckPeers: []Peer{&testPeer{chain: chain}}})
+	assert.NoError(t, err)
+	assert.NoError(t, c.Sync())
+	matches, err = c.Rescan([][]byte{walletScript}, 0)
+	assert.NoError(t, err)
+	assert.Len(t, matches, 2)
+
+	assert.NoError(t, c.Close())
+	assert.True(t, c.cfg.CheckPeers[0].(*testPeer).closed)
+}
@@ -0,0 +1,311 @@
+package lightclient
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+const (
+	// medianTimeBlocks is the number of blocks whose median timestamp a
+	// new block must exceed
+	medianTimeBlocks = 11
+	// maxFutureBlockTime is how far in the future a block may be
+	maxFutureBlockTime = 2 * time.Hour
+)
+
+var (
+	// ErrOrphanHeader is returned when headers do not connect to the chain
+	ErrOrphanHeader = errors.New("lightclient: header does not connect to the chain")
+	// ErrBadProofOfWork is returned when the hash of a header is above its
+	// target or the target is above the limit
+	ErrBadProofOfWork = errors.New("lightclient: header hash above target")
+	// ErrBadDifficulty is returned when the target of a header is not the
+	// one required at its height
+	ErrBadDifficulty = errors.New("lightclient: unexpected header difficulty")
+	// ErrTimeTooOld is returned when a header is not later than the median
+	// time of the previous blocks
+	ErrTimeTooOld = errors.New("lightclient: header timestamp too old")
+	// ErrTimeTooNew is returned when a header is too far in the future
+	ErrTimeTooNew = errors.New("lightclient: header timestamp too far in the future")
+)
+
+var bigOne = big.NewInt(1)
+
+// CompactToBig returns the target encoded in the compact bits of a header
+func CompactToBig(compact uint32) *big.Int {
+
+	mantissa := int64(compact & 0x007fffff)
+	exponent := uint(compact >> 24)
+
+	var n *big.Int
+	if exponent <= 3 {
+		n = big.NewInt(mantissa >> (8 * (3 - exponent)))
+	} else {
+		n = new(big.Int).Lsh(big.NewInt(mantissa), 8*(exponent-3))
+	}
+
+	if compact&0x00800000 != 0 {
+		n.Neg(n)
+	}
+
+	return n
+}
+
+// BigToCompact returns the compact encoding of a positive target
+func BigToCompact(n *big.Int) uint32 {
+
+	if n.Sign() <= 0 {
+		return 0
+	}
+
+	exponent := uint(len(n.Bytes()))
+
+	var mantissa uint32
+	if exponent <= 3 {
+		mantissa = uint32(n.Uint64()) << (8 * (3 - exponent))
+	} else {
+		mantissa = uint32(new(big.Int).Rsh(n, 8*(exponent-3)).Uint64())
+	}
+
+	if mantissa&0x00800000 != 0 {
+		mantissa >>= 8
+		exponent++
+	}
+
+	return uint32(exponent)<<24 | mantissa
+}
+
+// CalcWork returns the expected number of hashes to find a block with the
+// given bits, 2^256 / (target + 1)
+func CalcWork(bits uint32) *big.Int {
+
+	target := CompactToBig(bits)
+	if target.Sign() <= 0 {
+		return new(big.Int)
+	}
+
+	return new(big.Int).Div(new(big.Int).Lsh(bigOne, 256), target.Add(target, bigOne))
+}
+
+// hashToBig returns the hash of a block as a number
+func hashToBig(hash *transaction.Hash) *big.Int {
+
+	var b [transaction.HashSize]byte
+	for i := range hash {
+		b[transaction.HashSize-1-i] = hash[i]
+	}
+
+	return new(big.Int).SetBytes(b[:])
+}
+
+// checkProofOfWork checks that the hash of header is below its target
+func checkProofOfWork(header *transaction.BlockHeader, limit *big.Int) error {
+
+	target := CompactToBig(header.Bits)
+	if target.Sign() <= 0 || target.Cmp(limit) > 0 {
+		return ErrBadProofOfWork
+	}
+
+	hash := header.BlockHash()
+	if hashToBig(&hash).Cmp(target) > 0 {
+		return ErrBadProofOfWork
+	}
+
+	return nil
+}
+
+// headerChain is the chain of validated headers with the most work, the
+// header at height h is headers[h]
+type headerChain struct {
+	params  *Params
+	headers []*transaction.BlockHeader
+	hashes  []transaction.Hash
+	// work is the cumulative work up to each height
+	work   []*big.Int
+	height map[transaction.Hash]int32
+}
+
+func newHeaderChain(params *Params) *headerChain {
+
+	genesis := params.Genesis.Header
+	hash := genesis.BlockHash()
+
+	return &headerChain{
+		params:  params,
+		headers: []*transaction.BlockHeader{&genesis},
+		hashes:  []transaction.Hash{hash},
+		work:    []*big.Int{CalcWork(genesis.Bits)},
+		height:  map[transaction.Hash]int32{hash: 0},
+	}
+}
+
+// tip returns the height of the chain
+func (c *headerChain) tip() int32 {
+	return int32(len(c.headers) - 1)
+}
+
+// locator returns the hashes of the chain from the tip back to genesis,
+// spaced exponentially after the last ten
+func (c *headerChain) locator() []transaction.Hash {
+
+	var locator []transaction.Hash
+
+	step := int32(1)
+	for h := c.tip(); h > 0; h -= step {
+		locator = append(locator, c.hashes[h])
+		if len(locator) >= 10 {
+			step *= 2
+		}
+	}
+
+	return append(locator, c.hashes[0])
+}
+
+// connect validates consecutive headers and makes them part of the chain
+// when they give it more work. It returns the height of the last block
+// kept from the previous chain, which is below its tip on a reorg.
+func (c *headerChain) connect(headers []*transaction.BlockHeader) (int32, error) {
+
+	if len(headers) == 0 {
+		return c.tip(), nil
+	}
+
+	fork, ok := c.height[headers[0].PrevBlock]
+	if !ok {
+		return 0, ErrOrphanHeader
+	}
+
+	// skip the headers already in the chain
+	for len(headers) > 0 && fork < c.tip() && c.hashes[fork+1] == headers[0].BlockHash() {
+		headers = headers[1:]
+		fork++
+	}
+	if len(headers) == 0 {
+		return c.tip(), nil
+	}
+	if headers[0].PrevBlock != c.hashes[fork] {
+		return 0, ErrOrphanHeader
+	}
+
+	branch := make([]*transaction.BlockHeader, 0, len(headers))
+	ancestor := func(h int32) *transaction.BlockHeader {
+		if h <= fork {
+			return c.headers[h]
+		}
+		return branch[h-fork-1]
+	}
+
+	work := new(big.Int).Set(c.work[fork])
+	for i, header := range headers {
+		height := fork + int32(i) + 1
+		if i > 0 && header.PrevBlock != headers[i-1].BlockHash() {
+			return 0, ErrOrphanHeader
+		}
+		if err := c.check(header, height, ancestor); err != nil {
+			return 0, err
+		}
+		branch = append(branch, header)
+		work.Add(work, CalcWork(header.Bits))
+	}
+
+	if work.Cmp(c.work[c.tip()]) <= 0 {
+		return c.tip(), nil
+	}
+
+	for _, hash := range c.hashes[fork+1:] {
+		delete(c.height, hash)
+	}
+	c.headers = c.headers[:fork+1]
+	c.hashes = c.hashes[:fork+1]
+	c.work = c.work[:fork+1]
+
+	for _, header := range branch {
+		hash := header.BlockHash()
+		c.height[hash] = int32(len(c.headers))
+		c.headers = append(c.headers, header)
+		c.hashes = append(c.hashes, hash)
+		c.work = append(c.work, new(big.Int).Add(c.work[len(c.work)-1], CalcWork(header.Bits)))
+	}
+
+	return fork, nil
+}
+
+// check validates the header at height, ancestor returns the headers of
+// its branch below it
+func (c *headerChain) check(header *transaction.BlockHeader, height int32, ancestor func(int32) *transaction.BlockHeader) error {
+
+	if err := checkProofOfWork(header, c.params.PowLimit); err != nil {
+		return err
+	}
+
+	if header.Bits != c.nextBits(height, header.Timestamp, ancestor) {
+		return ErrBadDifficulty
+	}
+
+	var times []uint32
+	for h := height - 1; h >= 0 && h >= height-medianTimeBlocks; h-- {
+		times = append(times, ancestor(h).Timestamp)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	if header.Timestamp <= times[len(times)/2] {
+		return ErrTimeTooOld
+	}
+
+	if time.Unix(int64(header.Timestamp), 0).After(time.Now().Add(maxFutureBlockTime)) {
+		return ErrTimeTooNew
+	}
+
+	return nil
+}
+
+// nextBits returns the bits required for the block at height with the
+// given timestamp
+func (c *headerChain) nextBits(height int32, timestamp uint32, ancestor func(int32) *transaction.BlockHeader) uint32 {
+
+	p := c.params
+	prev := ancestor(height - 1)
+	if p.NoRetargeting {
+		return prev.Bits
+	}
+
+	interval := p.retargetInterval()
+	if height%interval != 0 {
+		if !p.ReduceMinDifficulty {
+			return prev.Bits
+		}
+
+		// a block late enough may have the minimum difficulty, others have
+		// the difficulty of the last block that did not
+		if int64(timestamp) > int64(prev.Timestamp)+int64(2*p.TargetSpacing/time.Second) {
+			return p.PowLimitBits
+		}
+		h := height - 1
+		for h > 0 && h%interval != 0 && ancestor(h).Bits == p.PowLimitBits {
+			h--
+		}
+		return ancestor(h).Bits
+	}
+
+	first := ancestor(height - interval)
+	timespan := int64(p.TargetTimespan / time.Second)
+	actual := int64(prev.Timestamp) - int64(first.Timestamp)
+	if actual < timespan/4 {
+		actual = timespan / 4
+	}
+	if actual > timespan*4 {
+		actual = timespan * 4
+	}
+
+	target := CompactToBig(prev.Bits)
+	target.Mul(target, big.NewInt(actual))
+	target.Div(target, big.NewInt(timespan))
+	if target.Cmp(p.PowLimit) > 0 {
+		target.Set(p.PowLimit)
+	}
+
+	return BigToCompact(target)
+}
@@ -0,0 +1,254 @@
+package lightclient
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+// mine returns a header on top of prev with a hash below its target
+func mine(prev *transaction.BlockHeader, root transaction.Hash, timestamp, bits uint32) *transaction.BlockHeader {
+
+	h := &transaction.BlockHeader{Version: 4, PrevBlock: prev.BlockHash(), MerkleRoot: root, Timestamp: timestamp, Bits: bits}
+	for checkProofOfWork(h, powLimit(256)) != nil {
+		h.Nonce++
+	}
+
+	return h
+}
+
+// testParams returns regtest like params with retargets every four
+// blocks, the genesis block has the given bits
+func testParams(bits uint32) *Params {
+
+	return &Params{
+		Name:           "test",
+		Genesis:        &transaction.Block{Header: transaction.BlockHeader{Version: 1, Timestamp: 1600000000, Bits: bits}},
+		PowLimit:       powLimit(255),
+		PowLimitBits:   0x207fffff,
+		TargetTimespan: 4 * time.Minute,
+		TargetSpacing:  time.Minute,
+	}
+}
+
+func TestCompact(t *testing.T) {
+
+	for _, bits := range []uint32{0x1d00ffff, 0x1b0404cb, 0x207fffff, 0x03123456} {
+		assert.Equal(t, bits, BigToCompact(CompactToBig(bits)))
+	}
+
+	assert.Equal(t, "ffff0000000000000000000000000000000000000000000000000000", CompactToBig(0x1d00ffff).Text(16))
+	assert.Equal(t, uint32(0x207fffff), BigToCompact(powLimit(255)))
+	assert.Equal(t, uint32(0x1d00ffff), BigToCompact(powLimit(224)))
+	assert.Equal(t, big.NewInt(0x100010001), CalcWork(0x1d00ffff))
+	assert.Equal(t, big.NewInt(2), CalcWork(0x207fffff))
+}
+
+func TestConnectMainNet(t *testing.T) {
+
+	c := newHeaderChain(MainNetParams)
+	assert.Equal(t, "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f", c.hashes[0].String())
+
+	raw, _ := hex.DecodeString("010000006fe28c0ab6f1b372c1a6a246ae63f74f931e8365e15a089c68d6190000000000982051fd1e4ba744bbbe680e1fee14677ba1a3c3540bf7b1cdb606e857233e0e61bc6649ffff001d01e36299")
+	block1, err := transaction.NewBlockHeaderFromBytes(raw)
+	assert.NoError(t, err)
+
+	bad := *block1
+	bad.Nonce++
+	_, err = c.connect([]*transaction.BlockHeader{&bad})
+	assert.ErrorIs(t, err, ErrBadProofOfWork)
+
+	bad = *block1
+	bad.Bits = 0x1e00ffff
+	_, err = c.connect([]*transaction.BlockHeader{&bad})
+	assert.ErrorIs(t, err, ErrBadProofOfWork)
+
+	bad = *block1
+	bad.PrevBlock[0]++
+	_, err = c.connect([]*transaction.BlockHeader{&bad})
+	assert.ErrorIs(t, err, ErrOrphanHeader)
+
+	fork, err := c.connect([]*transaction.BlockHeader{block1})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(0), fork)
+	assert.Equal(t, int32(1), c.tip())
+	assert.Equal(t, "00000000839a8e6886ab5951d76f411475428afc90947ee320161bbf18eb6048", c.hashes[1].String())
+	assert.Equal(t, big.NewInt(2*0x100010001), c.work[1])
+
+	// connecting a known header is a no-op
+	fork, err = c.connect([]*transaction.BlockHeader{block1})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), fork)
+	assert.Equal(t, int32(1), c.tip())
+}
+
+func TestRetarget(t *testing.T) {
+
+	params := testParams(0x207fffff)
+	genesis := &params.Genesis.Header
+	ts := genesis.Timestamp
+
+	tests := []struct {
+		name    string
+		spacing uint32
+		// bits is the required target at height 4
+		bits uint32
+	}{
+		{"fast", 30, BigToCompact(new(big.Int).Div(new(big.Int).Mul(CompactToBig(0x207fffff), big.NewInt(90)), big.NewInt(240)))},
+		{"clamped fast", 1, BigToCompact(new(big.Int).Div(CompactToBig(0x207fffff), big.NewInt(4)))},
+		{"slow", 600, 0x207fffff},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			c := newHeaderChain(params)
+			headers := []*transaction.BlockHeader{genesis}
+			for i := uint32(1); i < 4; i++ {
+				headers = append(headers, mine(headers[i-1], transaction.Hash{}, ts+i*tt.spacing, 0x207fffff))
+			}
+			_, err := c.connect(headers[1:])
+			assert.NoError(t, err)
+
+			// the retarget block must have the adjusted target
+			if tt.bits != 0x207fffff {
+				_, err = c.connect([]*transaction.BlockHeader{mine(headers[3], transaction.Hash{}, ts+4*tt.spacing, 0x207fffff)})
+				assert.ErrorIs(t, err, ErrBadDifficulty)
+			}
+
+			next := mine(headers[3], transaction.Hash{}, ts+4*tt.spacing, tt.bits)
+			_, err = c.connect([]*transaction.BlockHeader{next})
+			assert.NoError(t, err)
+			assert.Equal(t, int32(4), c.tip())
+
+			// the target holds until the next retarget
+			_, err = c.connect([]*transaction.BlockHeader{mine(next, transaction.Hash{}, ts+5*tt.spacing, 0x207fffff)})
+			if tt.bits != 0x207fffff {
+				assert.ErrorIs(t, err, ErrBadDifficulty)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestMinDifficulty(t *testing.T) {
+
+	params := testParams(0x2000ffff)
+	params.TargetTimespan = time.Hour
+	params.ReduceMinDifficulty = true
+	c := newHeaderChain(params)
+	ts := params.Genesis.Header.Timestamp
+
+	h1 := mine(&params.Genesis.Header, transaction.Hash{}, ts+60, 0x2000ffff)
+	// a block more than twice the spacing after its parent may have the
+	// lowest difficulty
+	h2 := mine(h1, transaction.Hash{}, ts+300, 0x207fffff)
+	_, err := c.connect([]*transaction.BlockHeader{h1, h2})
+	assert.NoError(t, err)
+
+	_, err = c.connect([]*transaction.BlockHeader{mine(h1, transaction.Hash{}, ts+120, 0x207fffff)})
+	assert.ErrorIs(t, err, ErrBadDifficulty)
+
+	// the next block on time returns to the last regular difficulty
+	_, err = c.connect([]*transaction.BlockHeader{mine(h2, transaction.Hash{}, ts+360, 0x207fffff)})
+	assert.ErrorIs(t, err, ErrBadDifficulty)
+	_, err = c.connect([]*transaction.BlockHeader{mine(h2, transaction.Hash{}, ts+360, 0x2000ffff)})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), c.tip())
+}
+
+func TestTimestamps(t *testing.T) {
+
+	c := newHeaderChain(RegTestParams)
+	ts := RegTestParams.Genesis.Header.Timestamp
+
+	headers := []*transaction.BlockHeader{&RegTestParams.Genesis.Header}
+	for i := uint32(1); i <= 3; i++ {
+		headers = append(headers, mine(headers[i-1], transaction.Hash{}, ts+i*600, 0x207fffff))
+	}
+	_, err := c.connect(headers[1:])
+	assert.NoError(t, err)
+
+	// the median of the last four timestamps is the one of block 2
+	_, err = c.connect([]*transaction.BlockHeader{mine(headers[3], transaction.Hash{}, ts+1200, 0x207fffff)})
+	assert.ErrorIs(t, err, ErrTimeTooOld)
+	_, err = c.connect([]*transaction.BlockHeader{mine(headers[3], transaction.Hash{}, ts+1201, 0x207fffff)})
+	assert.NoError(t, err)
+
+	future := uint32(time.Now().Add(3 * time.Hour).Unix())
+	_, err = c.connect([]*transaction.BlockHeader{mine(headers[3], transaction.Hash{}, future, 0x207fffff)})
+	assert.ErrorIs(t, err, ErrTimeTooNew)
+}
+
+func TestReorg(t *testing.T) {
+
+	c := newHeaderChain(RegTestParams)
+	genesis := &RegTestParams.Genesis.Header
+	ts := genesis.Timestamp
+
+	var a, b []*transaction.BlockHeader
+	prevA, prevB := genesis, genesis
+	for i := uint32(1); i <= 3; i++ {
+		prevA = mine(prevA, transaction.Hash{1}, ts+i*600, 0x207fffff)
+		a = append(a, prevA)
+		prevB = mine(prevB, transaction.Hash{2}, ts+i*600, 0x207fffff)
+		b = append(b, prevB)
+	}
+
+	fork, err := c.connect(a[:2])
+	assert.NoError(t, err)
+	assert.Equal(t, int32(0), fork)
+
+	// a branch with as much work does not replace the chain
+	fork, err = c.connect(b[:2])
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), fork)
+	assert.Equal(t, a[1].BlockHash(), c.hashes[2])
+
+	fork, err = c.connect(b)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(0), fork)
+	assert.Equal(t, int32(3), c.tip())
+	assert.Equal(t, b[2].BlockHash(), c.hashes[3])
+	_, ok := c.height[a[0].BlockHash()]
+	assert.False(t, ok)
+
+	// the dropped branch no longer connects
+	_, err = c.connect(a[2:])
+	assert.ErrorIs(t, err, ErrOrphanHeader)
+
+	// nor do headers following a known one without extending it
+	x := []*transaction.BlockHeader{b[0]}
+	prev := a[0]
+	for i := uint32(2); i <= 5; i++ {
+		prev = mine(prev, transaction.Hash{3}, ts+i*600, 0x207fffff)
+		x = append(x, prev)
+	}
+	_, err = c.connect(x)
+	assert.ErrorIs(t, err, ErrOrphanHeader)
+	assert.Equal(t, b[2].BlockHash(), c.hashes[c.tip()])
+}
+
+func TestLocator(t *testing.T) {
+
+	c := newHeaderChain(RegTestParams)
+	prev := &RegTestParams.Genesis.Header
+	var headers []*transaction.BlockHeader
+	for i := uint32(1); i <= 20; i++ {
+		prev = mine(prev, transaction.Hash{}, prev.Timestamp+600, 0x207fffff)
+		headers = append(headers, prev)
+	}
+	_, err := c.connect(headers)
+	assert.NoError(t, err)
+
+	var heights []int32
+	for _, hash := range c.locator() {
+		heights = append(heights, c.height[hash])
+	}
+	assert.Equal(t, []int32{20, 19, 18, 17, 16, 15, 14, 13, 12, 11, 9, 5, 0}, heights)
+}
@@ -0,0 +1,125 @@
+package lightclient
+
+import (
+	"encoding/hex"
+	"math/big"
+	"time"
+
+	"github.com/giogam/Gopher-Wallet/wallet/address"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+	"github.com/giogam/Gopher-Wallet/wallet/wire"
+)
+
+// genesisCoinbase is the coinbase transaction of the genesis block of
+// every network
+const genesisCoinbase = "01000000010000000000000000000000000000000000000000000000000000000000000000ffffffff4d04ffff001d0104455468652054696d65732030332f4a616e2f32303039204368616e63656c6c6f72206f6e206272696e6b206f66207365636f6e64206261696c6f757420666f722062616e6b73ffffffff0100f2052a01000000434104678afdb0fe5548271967f1a67130b7105cd6a828e03909a67962e0ea1f61deb649f6bc3f4cef38c4f35504e51ec112de5c384df7ba0b8d578a4c702b6bf11d5fac00000000"
+
+// Params are the consensus rules and the network parameters of a chain
+type Params struct {
+	Name        string
+	Net         wire.BitcoinNet
+	Address     *address.Network
+	DefaultPort string
+	Genesis     *transaction.Block
+
+	// PowLimit is the highest target, PowLimitBits its compact form
+	PowLimit     *big.Int
+	PowLimitBits uint32
+	// the difficulty is adjusted every TargetTimespan / TargetSpacing
+	// blocks unless NoRetargeting is set
+	TargetTimespan time.Duration
+	TargetSpacing  time.Duration
+	NoRetargeting  bool
+	// ReduceMinDifficulty allows blocks found more than twice the target
+	// spacing after their parent to have the lowest difficulty, as on
+	// testnet
+	ReduceMinDifficulty bool
+
+	// FilterCheckpoints are known basic filter headers by height, the
+	// filter headers served by peers must match them
+	FilterCheckpoints map[int32]transaction.Hash
+}
+
+// retargetInterval returns the number of blocks between difficulty
+// adjustments
+func (p *Params) retargetInterval() int32 {
+	return int32(p.TargetTimespan / p.TargetSpacing)
+}
+
+// genesisBlock returns the genesis block with the given header fields
+func genesisBlock(timestamp, bits, nonce uint32) *transaction.Block {
+
+	raw, _ := hex.DecodeString(genesisCoinbase)
+	coinbase, _ := transaction.NewTxFromBytes(raw)
+
+	block := &transaction.Block{
+		Header:       transaction.BlockHeader{Version: 1, Timestamp: timestamp, Bits: bits, Nonce: nonce},
+		Transactions: []*transaction.Tx{coinbase},
+	}
+	block.Header.MerkleRoot = block.MerkleRoot()
+
+	return block
+}
+
+// hashFromStr parses a hash known to be valid
+func hashFromStr(s string) transaction.Hash {
+
+	hash, _ := transaction.NewHashFromStr(s)
+
+	return hash
+}
+
+// powLimit returns the target 2^bits - 1
+func powLimit(bits uint) *big.Int {
+	return new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), bits), big.NewInt(1))
+}
+
+var (
+	// MainNetParams are the parameters of the main network
+	MainNetParams = &Params{
+		Name:           "mainnet",
+		Net:            wire.MainNet,
+		Address:        address.MainNet,
+		DefaultPort:    "8333",
+		Genesis:        genesisBlock(1231006505, 0x1d00ffff, 2083236893),
+		PowLimit:       powLimit(224),
+		PowLimitBits:   0x1d00ffff,
+		TargetTimespan: 14 * 24 * time.Hour,
+		TargetSpacing:  10 * time.Minute,
+		FilterCheckpoints: map[int32]transaction.Hash{
+			0: hashFromStr("02c2392180d0ce2b5b6f8b08d39a11ffe831c673311a3ecf77b97fc3f0303c9f"),
+		},
+	}
+
+	// TestNetParams are the parameters of testnet3
+	TestNetParams = &Params{
+		Name:                "testnet3",
+		Net:                 wire.TestNet3,
+		Address:             address.TestNet,
+		DefaultPort:         "18333",
+		Genesis:             genesisBlock(1296688602, 0x1d00ffff, 414098458),
+		PowLimit:            powLimit(224),
+		PowLimitBits:        0x1d00ffff,
+		TargetTimespan:      14 * 24 * time.Hour,
+		TargetSpacing:       10 * time.Minute,
+		ReduceMinDifficulty: true,
+		FilterCheckpoints: map[int32]transaction.Hash{
+			0: hashFromStr("21584579b7eb08997773e5aeff3a7f932700042d0ed2a6129012b7d7ae81b750"),
+		},
+	}
+
+	// RegTestParams are the parameters of the regression test network
+	RegTestParams = &Params{
+		Name:                "regtest",
+		Net:                 wire.RegTest,
+		Address:             address.RegTest,
+		DefaultPort:         "18444",
+		Genesis:             genesisBlock(1296688602, 0x207fffff, 2),
+		PowLimit:            powLimit(255),
+		PowLimitBits:        0x207fffff,
+		TargetTimespan:      14 * 24 * time.Hour,
+		TargetSpacing:       10 * time.Minute,
+		NoRetargeting:       true,
+		ReduceMinDifficulty: true,
+	}
+)
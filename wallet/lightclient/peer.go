@@ -0,0 +1,160 @@
+package lightclient
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+	"github.com/giogam/Gopher-Wallet/wallet/wire"
+)
+
+const (
+	// DefaultUserAgent is the user agent sent to peers
+	DefaultUserAgent = "/gopher-wallet/"
+
+	// requiredServices are the services a peer must advertise
+	requiredServices = wire.SFNodeWitness | wire.SFNodeCompactFilters
+)
+
+var (
+	// ErrNoCompactFilters is returned when the peer does not serve witness
+	// blocks and compact block filters
+	ErrNoCompactFilters = errors.New("lightclient: peer does not serve compact filters")
+	// ErrHandshake is returned when the peer does not complete the version
+	// handshake
+	ErrHandshake = errors.New("lightclient: version handshake failed")
+)
+
+// netPeer is a peer reached through the peer to peer protocol
+type netPeer struct {
+	peer *wire.Peer
+}
+
+// Dial connects to the full node at addr, which must serve witness blocks
+// and compact filters. The timeout bounds dialing, the handshake and
+// waiting for each response, it defaults to wire.DefaultPeerTimeout.
+func Dial(addr string, params *Params, timeout time.Duration) (Peer, error) {
+
+	if timeout == 0 {
+		timeout = wire.DefaultPeerTimeout
+	}
+
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	p := wire.NewPeer(conn, wire.PeerConfig{
+		Net:              params.Net,
+		Services:         wire.SFNodeWitness,
+		RequiredServices: requiredServices,
+		UserAgent:        DefaultUserAgent,
+		Timeout:          timeout,
+	})
+	if err := p.Handshake(); err != nil {
+		if errors.Is(err, wire.ErrMissingServices) {
+			return nil, fmt.Errorf("%w: %w", ErrNoCompactFilters, err)
+		}
+		return nil, fmt.Errorf("%w: %w", ErrHandshake, err)
+	}
+
+	return &netPeer{peer: p}, nil
+}
+
+func (p *netPeer) Headers(locator []transaction.Hash) ([]*transaction.BlockHeader, error) {
+
+	msg, err := p.peer.Request(&wire.MsgGetHeaders{
+		ProtocolVersion: wire.ProtocolVersion,
+		Locator:         locator,
+	}, wire.CmdHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	return msg.(*wire.MsgHeaders).Headers, nil
+}
+
+func (p *netPeer) FilterCheckpoints(stop *transaction.Hash) ([]transaction.Hash, error) {
+
+	msg, err := p.peer.Request(&wire.MsgGetCFCheckpt{FilterType: wire.FilterBasic, StopHash: *stop}, wire.CmdCFCheckpt)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpt := msg.(*wire.MsgCFCheckpt)
+	if checkpt.FilterType != wire.FilterBasic || checkpt.StopHash != *stop {
+		return nil, ErrUnexpectedResponse
+	}
+
+	return checkpt.FilterHeaders, nil
+}
+
+func (p *netPeer) FilterHashes(start int32, stop *transaction.Hash) (*transaction.Hash, []transaction.Hash, error) {
+
+	msg, err := p.peer.Request(&wire.MsgGetCFHeaders{
+		FilterType:  wire.FilterBasic,
+		StartHeight: uint32(start),
+		StopHash:    *stop,
+	}, wire.CmdCFHeaders)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cfheaders := msg.(*wire.MsgCFHeaders)
+	if cfheaders.FilterType != wire.FilterBasic || cfheaders.StopHash != *stop {
+		return nil, nil, ErrUnexpectedResponse
+	}
+
+	return &cfheaders.PrevFilterHeader, cfheaders.FilterHashes, nil
+}
+
+// Filters reads the cfilter messages answering the request up to the one
+// of the block stop
+func (p *netPeer) Filters(start int32, stop *transaction.Hash) ([]*BlockFilter, error) {
+
+	err := p.peer.Send(&wire.MsgGetCFilters{
+		FilterType:  wire.FilterBasic,
+		StartHeight: uint32(start),
+		StopHash:    *stop,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var filters []*BlockFilter
+	for len(filters) < MaxFilters {
+		msg, err := p.peer.Receive(wire.CmdCFilter)
+		if err != nil {
+			return nil, err
+		}
+
+		cfilter := msg.(*wire.MsgCFilter)
+		if cfilter.FilterType != wire.FilterBasic {
+			return nil, ErrUnexpectedResponse
+		}
+		filters = append(filters, &BlockFilter{BlockHash: cfilter.BlockHash, Filter: cfilter.Filter})
+		if cfilter.BlockHash == *stop {
+			return filters, nil
+		}
+	}
+
+	return nil, ErrUnexpectedResponse
+}
+
+func (p *netPeer) Block(hash *transaction.Hash) (*transaction.Block, error) {
+
+	msg, err := p.peer.Request(&wire.MsgGetData{
+		InvList: []*wire.InvVect{{Type: wire.InvTypeWitnessBlock, Hash: *hash}},
+	}, wire.CmdBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	return &msg.(*wire.MsgBlock).Block, nil
+}
+
+func (p *netPeer) Close() error {
+	return p.peer.Close()
+}
@@ -0,0 +1,139 @@
+package lightclient
+
+import (
+	"net"
+	"testing"
+
+	"github.com/giogam/Gopher-Wallet/wallet/wire"
+	"github.com/stretchr/testify/assert"
+)
+
+// startNode serves the chain of p on the peer to peer protocol, it pings
+// the client and sends it an unknown message after the handshake
+func startNode(t *testing.T, p *testPeer, magic wire.BitcoinNet, services uint64) (string, chan uint64) {
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	pongs := make(chan uint64, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+
+		peer := wire.NewPeer(conn, wire.PeerConfig{Net: magic, Services: services})
+		defer peer.Close()
+		if peer.Handshake() != nil {
+			return
+		}
+		peer.Send(&wire.MsgPing{Nonce: 7})
+		peer.Send(&wire.MsgUnknown{Cmd: "sendaddrv2"})
+
+		for {
+			msg, err := peer.Read()
+			if err != nil {
+				return
+			}
+			for _, r := range respond(p, msg, pongs) {
+				if peer.Send(r) != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	return ln.Addr().String(), pongs
+}
+
+// respond returns the answers of p to msg
+func respond(p *testPeer, msg wire.Message, pongs chan uint64) []wire.Message {
+
+	switch m := msg.(type) {
+	case *wire.MsgPong:
+		pongs <- m.Nonce
+
+	case *wire.MsgGetHeaders:
+		headers, _ := p.Headers(m.Locator)
+		return []wire.Message{&wire.MsgHeaders{Headers: headers}}
+
+	case *wire.MsgGetCFCheckpt:
+		checkpoints, _ := p.FilterCheckpoints(&m.StopHash)
+		return []wire.Message{&wire.MsgCFCheckpt{FilterType: m.FilterType, StopHash: m.StopHash, FilterHeaders: checkpoints}}
+
+	case *wire.MsgGetCFHeaders:
+		prev, hashes, _ := p.FilterHashes(int32(m.StartHeight), &m.StopHash)
+		return []wire.Message{&wire.MsgCFHeaders{FilterType: m.FilterType, StopHash: m.StopHash, PrevFilterHeader: *prev, FilterHashes: hashes}}
+
+	case *wire.MsgGetCFilters:
+		filters, _ := p.Filters(int32(m.StartHeight), &m.StopHash)
+		var msgs []wire.Message
+		for _, f := range filters {
+			msgs = append(msgs, &wire.MsgCFilter{FilterType: m.FilterType, BlockHash: f.BlockHash, Filter: f.Filter})
+		}
+		return msgs
+
+	case *wire.MsgGetData:
+		var msgs []wire.Message
+		for _, inv := range m.InvList {
+			block, _ := p.Block(&inv.Hash)
+			msgs = append(msgs, &wire.MsgBlock{Block: *block})
+		}
+		return msgs
+	}
+
+	return nil
+}
+
+func TestDial(t *testing.T) {
+
+	chain, payment, _ := testChainWithPayments(t)
+	addr, pongs := startNode(t, &testPeer{chain: chain}, wire.RegTest, wire.SFNodeNetwork|wire.SFNodeWitness|wire.SFNodeCompactFilters)
+
+	peer, err := Dial(addr, RegTestParams, 0)
+	assert.NoError(t, err)
+	c := newTestClient(t, peer)
+	defer c.Close()
+
+	assert.NoError(t, c.Sync())
+	assert.Equal(t, uint64(7), <-pongs)
+
+	height, _, err := c.BestBlock()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2100), height)
+	filterHeader, err := c.FilterHeader(2100)
+	assert.NoError(t, err)
+	assert.Equal(t, chain.filterHeaders[2100], *filterHeader)
+
+	matches, err := c.Rescan([][]byte{walletScript}, 0)
+	assert.NoError(t, err)
+	assert.Len(t, matches, 2)
+	assert.Equal(t, payment.TxHash(), matches[0].Block.Transactions[1].TxHash())
+
+	// a range longer than MaxFilters is not read to the end
+	stop := chain.blocks[1500].BlockHash()
+	_, err = peer.Filters(0, &stop)
+	assert.ErrorIs(t, err, ErrUnexpectedResponse)
+	assert.NoError(t, peer.Close())
+	_, err = peer.Block(&stop)
+	assert.ErrorIs(t, err, wire.ErrPeerClosed)
+}
+
+func TestNoCompactFilters(t *testing.T) {
+
+	addr, _ := startNode(t, &testPeer{chain: newTestChain(t)}, wire.RegTest, wire.SFNodeNetwork|wire.SFNodeWitness)
+
+	_, err := Dial(addr, RegTestParams, 0)
+	assert.ErrorIs(t, err, ErrNoCompactFilters)
+	assert.ErrorIs(t, err, wire.ErrMissingServices)
+}
+
+func TestDialWrongNetwork(t *testing.T) {
+
+	addr, _ := startNode(t, &testPeer{chain: newTestChain(t)}, wire.MainNet, wire.SFNodeWitness|wire.SFNodeCompactFilters)
+
+	_, err := Dial(addr, RegTestParams, 0)
+	assert.ErrorIs(t, err, ErrHandshake)
+	assert.ErrorIs(t, err, wire.ErrWrongNetwork)
+}
@@ -0,0 +1,522 @@
+package message
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+
+	"github.com/giogam/Gopher-Wallet/wallet/address"
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/script"
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+// Format is the encoding of a BIP322 signature
+type Format int
+
+const (
+	// FormatSimple encodes only the witness stack of the to_sign input
+	FormatSimple Format = iota + 1
+	// FormatFull encodes the whole to_sign transaction
+	FormatFull
+)
+
+// verifyFlags are the script rules a BIP322 to_sign transaction is checked
+// against
+const verifyFlags = script.StandardVerifyFlags
+
+var (
+	// ErrUnsupportedScript is returned when a challenge or a proof of funds
+	// input uses a script the BIP322 signer does not understand
+	ErrUnsupportedScript = errors.New("message: unsupported script")
+	// ErrUnsupportedFormat is returned when the simple format is requested
+	// for an address that needs a scriptSig
+	ErrUnsupportedFormat = errors.New("message: unsupported signature format for address")
+	// ErrNotEnoughKeys is returned when the keys cannot satisfy a script
+	ErrNotEnoughKeys = errors.New("message: not enough keys to satisfy script")
+	// ErrInvalidToSign is returned when a full signature is not a valid
+	// BIP322 to_sign transaction for the message and address
+	ErrInvalidToSign = errors.New("message: invalid to_sign transaction")
+	// ErrUnknownUtxo is returned when a proof of funds input cannot be
+	// resolved
+	ErrUnknownUtxo = errors.New("message: unknown proof of funds input")
+)
+
+// Utxo is an unspent output referenced by a proof of funds signature, Hash
+// is the funding transaction id in internal byte order
+type Utxo struct {
+	Hash     []byte
+	Index    uint32
+	Value    int64
+	PkScript []byte
+}
+
+// UtxoFetcher resolves the outputs spent by proof of funds inputs
+type UtxoFetcher func(hash []byte, index uint32) (*Utxo, error)
+
+// BIP322Hash returns the tagged hash of the message committed to by the
+// to_spend transaction
+func BIP322Hash(message string) []byte {
+	return secp256k1.TaggedHash("BIP0322-signed-message", []byte(message))
+}
+
+// SignBIP322 signs message with key on behalf of a single key address,
+// P2PKH and P2SH-P2WPKH need a scriptSig and only support FormatFull
+func SignBIP322(key *secp256k1.PrivateKey, addr *address.Address, message string, format Format) (string, error) {
+	return signBIP322([]*secp256k1.PrivateKey{key}, nil, addr, message, format, nil)
+}
+
+// SignBIP322Script signs message on behalf of a P2WSH or P2SH-P2WSH address
+// locked by a single key or a multisig witness script
+func SignBIP322Script(keys []*secp256k1.PrivateKey, witnessScript []byte, addr *address.Address, message string, format Format) (string, error) {
+	return signBIP322(keys, witnessScript, addr, message, format, nil)
+}
+
+// SignBIP322ProofOfFunds signs message on behalf of addr and additionally
+// spends the given utxos, locked to the same address, in the full format
+func SignBIP322ProofOfFunds(key *secp256k1.PrivateKey, addr *address.Address, message string, utxos []*Utxo) (string, error) {
+	return signBIP322([]*secp256k1.PrivateKey{key}, nil, addr, message, FormatFull, utxos)
+}
+
+// VerifyBIP322 reports whether signature, in the simple or the full format,
+// proves control of addr for message
+func VerifyBIP322(addr *address.Address, message string, signature string) (bool, error) {
+	return VerifyBIP322ProofOfFunds(addr, message, signature, nil)
+}
+
+// VerifyBIP322ProofOfFunds verifies a signature that may spend additional
+// inputs, fetch resolves the outputs they spend
+func VerifyBIP322ProofOfFunds(addr *address.Address, message string, signature string, fetch UtxoFetcher) (bool, error) {
+
+	raw, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false, ErrInvalidSignature
+	}
+
+	challenge, err := challengeScript(addr)
+	if err != nil {
+		return false, err
+	}
+
+	toSpend := newToSpend(challenge, message)
+
+	toSign, err := transaction.NewTxFromBytes(raw)
+	if err != nil {
+		witness, err := parseWitness(raw)
+		if err != nil {
+			return false, ErrInvalidSignature
+		}
+
+		toSign = newToSign(toSpend)
+		toSign.TxIn[0].Witness = witness
+	}
+
+	if err := checkToSign(toSign, toSpend); err != nil {
+		return false, err
+	}
+
+	prevOuts := transaction.PrevOutputMap{toSign.TxIn[0].PreviousOutPoint: toSpend.TxOut[0]}
+
+	for _, in := range toSign.TxIn[1:] {
+		if fetch == nil {
+			return false, ErrUnknownUtxo
+		}
+
+		op := in.PreviousOutPoint
+		utxo, err := fetch(op.Hash[:], op.Index)
+		if err != nil || utxo == nil {
+			return false, ErrUnknownUtxo
+		}
+
+		prevOuts[op] = transaction.NewTxOut(utxo.Value, utxo.PkScript)
+	}
+
+	if err := script.VerifyTx(toSign, prevOuts, verifyFlags); err != nil {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func signBIP322(keys []*secp256k1.PrivateKey, witnessScript []byte, addr *address.Address, message string, format Format, utxos []*Utxo) (string, error) {
+
+	challenge, err := challengeScript(addr)
+	if err != nil {
+		return "", err
+	}
+
+	if format == FormatSimple && !addr.IsSegwit() {
+		return "", ErrUnsupportedFormat
+	}
+
+	toSpend := newToSpend(challenge, message)
+	toSign := newToSign(toSpend)
+
+	prevOuts := transaction.PrevOutputMap{toSign.TxIn[0].PreviousOutPoint: toSpend.TxOut[0]}
+
+	for _, utxo := range utxos {
+		if !bytes.Equal(utxo.PkScript, challenge) {
+			return "", ErrKeyMismatch
+		}
+
+		var hash transaction.Hash
+		copy(hash[:], utxo.Hash)
+
+		in := &transaction.TxIn{PreviousOutPoint: transaction.OutPoint{Hash: hash, Index: utxo.Index}}
+		toSign.AddTxIn(in)
+		prevOuts[in.PreviousOutPoint] = transaction.NewTxOut(utxo.Value, utxo.PkScript)
+	}
+
+	hashes := transaction.NewSigHashCache(toSign, prevOuts)
+
+	for i, in := range toSign.TxIn {
+		if err := signInput(toSign, i, hashes, keys, witnessScript, prevOuts[in.PreviousOutPoint]); err != nil {
+			return "", err
+		}
+	}
+
+	if format == FormatSimple {
+		return base64.StdEncoding.EncodeToString(serializeWitness(toSign.TxIn[0].Witness)), nil
+	}
+
+	return base64.StdEncoding.EncodeToString(toSign.Bytes()), nil
+}
+
+// newToSpend builds the virtual transaction whose only output is locked by
+// the message challenge
+func newToSpend(challenge []byte, message string) *transaction.Tx {
+
+	scriptSig, _ := script.NewBuilder().AddOp(script.Op0).AddData(BIP322Hash(message)).Script()
+
+	tx := transaction.NewTx(0)
+	tx.AddTxIn(&transaction.TxIn{
+		PreviousOutPoint: transaction.OutPoint{Index: transaction.MaxPrevOutIndex},
+		SignatureScript:  scriptSig,
+	})
+	tx.AddTxOut(transaction.NewTxOut(0, challenge))
+
+	return tx
+}
+
+// newToSign builds the unsigned virtual transaction spending to_spend
+func newToSign(toSpend *transaction.Tx) *transaction.Tx {
+
+	tx := transaction.NewTx(0)
+	tx.AddTxIn(&transaction.TxIn{PreviousOutPoint: transaction.OutPoint{Hash: toSpend.TxHash()}})
+	tx.AddTxOut(transaction.NewTxOut(0, []byte{script.OpReturn}))
+
+	return tx
+}
+
+// checkToSign validates the BIP322 constraints on a decoded to_sign
+func checkToSign(toSign, toSpend *transaction.Tx) error {
+
+	if len(toSign.TxIn) == 0 {
+		return ErrInvalidToSign
+	}
+
+	first := toSign.TxIn[0].PreviousOutPoint
+	if first.Hash != toSpend.TxHash() || first.Index != 0 {
+		return ErrInvalidToSign
+	}
+
+	if len(toSign.TxOut) != 1 || toSign.TxOut[0].Value != 0 ||
+		!bytes.Equal(toSign.TxOut[0].PkScript, []byte{script.OpReturn}) {
+		return ErrInvalidToSign
+	}
+
+	return nil
+}
+
+// signInput fills the scriptSig and witness of input i, which spends prev
+func signInput(tx *transaction.Tx, i int, hashes *transaction.SigHashCache, keys []*secp256k1.PrivateKey, witnessScript []byte, prev *transaction.TxOut) error {
+
+	in := tx.TxIn[i]
+	pkScript := prev.PkScript
+
+	switch script.Classify(pkScript) {
+	case script.PubKeyHash:
+		key, pub := findKey(keys, pkScript[3:23])
+		if key == nil {
+			return ErrKeyMismatch
+		}
+
+		hash, err := transaction.LegacySigHash(tx, i, pkScript, transaction.SigHashAll)
+		if err != nil {
+			return err
+		}
+
+		sig, err := ecdsaSign(key, hash)
+		if err != nil {
+			return err
+		}
+
+		in.SignatureScript, err = script.NewBuilder().AddData(sig).AddData(pub).Script()
+
+		return err
+
+	case script.ScriptHash:
+		if witnessScript != nil {
+			hash := sha256.Sum256(witnessScript)
+			redeem, err := script.PayToWitnessScriptHash(hash[:])
+			if err != nil {
+				return err
+			}
+
+			if !bytes.Equal(hdwallet.Hash160(redeem), pkScript[2:22]) {
+				return ErrKeyMismatch
+			}
+
+			if in.Witness, err = signWitnessScript(hashes, i, keys, witnessScript, prev.Value); err != nil {
+				return err
+			}
+
+			in.SignatureScript, err = script.NewBuilder().AddData(redeem).Script()
+
+			return err
+		}
+
+		key, pub := findKey(keys, nil)
+		if key == nil {
+			return ErrKeyMismatch
+		}
+
+		redeem, err := script.PayToWitnessPubKeyHash(hdwallet.Hash160(pub))
+		if err != nil {
+			return err
+		}
+
+		if !bytes.Equal(hdwallet.Hash160(redeem), pkScript[2:22]) {
+			return ErrKeyMismatch
+		}
+
+		if in.Witness, err = signWitnessKeyHash(hashes, i, key, pub, prev.Value); err != nil {
+			return err
+		}
+
+		in.SignatureScript, err = script.NewBuilder().AddData(redeem).Script()
+
+		return err
+
+	case script.WitnessV0PubKeyHash:
+		key, pub := findKey(keys, pkScript[2:])
+		if key == nil {
+			return ErrKeyMismatch
+		}
+
+		witness, err := signWitnessKeyHash(hashes, i, key, pub, prev.Value)
+		if err != nil {
+			return err
+		}
+
+		in.Witness = witness
+
+	case script.WitnessV0ScriptHash:
+		hash := sha256.Sum256(witnessScript)
+		if !bytes.Equal(hash[:], pkScript[2:]) {
+			return ErrKeyMismatch
+		}
+
+		witness, err := signWitnessScript(hashes, i, keys, witnessScript, prev.Value)
+		if err != nil {
+			return err
+		}
+
+		in.Witness = witness
+
+	case script.WitnessV1Taproot:
+		for _, key := range keys {
+			tweaked, err := hdwallet.TaprootTweakPrivKey(key, nil)
+			if err != nil {
+				return err
+			}
+
+			if !bytes.Equal(tweaked.SerializeXOnly(), pkScript[2:]) {
+				continue
+			}
+
+			hash, err := hashes.TaprootSigHash(i, transaction.SigHashDefault, nil)
+			if err != nil {
+				return err
+			}
+
+			sig, err := secp256k1.SchnorrSign(tweaked, hash, nil)
+			if err != nil {
+				return err
+			}
+
+			in.Witness = transaction.Witness{sig}
+
+			return nil
+		}
+
+		return ErrKeyMismatch
+
+	default:
+		return ErrUnsupportedScript
+	}
+
+	return nil
+}
+
+// signWitnessKeyHash returns the P2WPKH witness of input i
+func signWitnessKeyHash(hashes *transaction.SigHashCache, i int, key *secp256k1.PrivateKey, pub []byte, amount int64) (transaction.Witness, error) {
+
+	scriptCode, err := script.PayToPubKeyHash(hdwallet.Hash160(pub))
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := hashes.WitnessV0SigHash(i, scriptCode, amount, transaction.SigHashAll)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := ecdsaSign(key, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction.Witness{sig, pub}, nil
+}
+
+// signWitnessScript returns the P2WSH witness of input i for a single key
+// or a multisig witness script
+func signWitnessScript(hashes *transaction.SigHashCache, i int, keys []*secp256k1.PrivateKey, witnessScript []byte, amount int64) (transaction.Witness, error) {
+
+	required, pubKeys, ok := parseCheckSigScript(witnessScript)
+	if !ok {
+		return nil, ErrUnsupportedScript
+	}
+
+	hash, err := hashes.WitnessV0SigHash(i, witnessScript, amount, transaction.SigHashAll)
+	if err != nil {
+		return nil, err
+	}
+
+	var sigs [][]byte
+	for _, pub := range pubKeys {
+		if len(sigs) == required {
+			break
+		}
+
+		for _, key := range keys {
+			if !bytes.Equal(key.PubKey().SerializeCompressed(), pub) {
+				continue
+			}
+
+			sig, err := ecdsaSign(key, hash)
+			if err != nil {
+				return nil, err
+			}
+			sigs = append(sigs, sig)
+		}
+	}
+
+	if len(sigs) < required {
+		return nil, ErrNotEnoughKeys
+	}
+
+	if script.Classify(witnessScript) == script.MultiSig {
+		// CHECKMULTISIG pops an extra element that must be empty
+		sigs = append([][]byte{{}}, sigs...)
+	}
+
+	return append(transaction.Witness(sigs), witnessScript), nil
+}
+
+// ecdsaSign returns a DER signature followed by the SIGHASH_ALL byte
+func ecdsaSign(key *secp256k1.PrivateKey, hash []byte) ([]byte, error) {
+
+	sig, err := secp256k1.Sign(key, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(sig.Serialize(), byte(transaction.SigHashAll)), nil
+}
+
+// findKey returns the key, and its serialized public key, whose HASH160
+// matches hash, a nil hash selects the first compressed key
+func findKey(keys []*secp256k1.PrivateKey, hash []byte) (*secp256k1.PrivateKey, []byte) {
+
+	for _, key := range keys {
+		pub := key.PubKey()
+
+		for _, serialized := range [][]byte{pub.SerializeCompressed(), pub.SerializeUncompressed()} {
+			if hash == nil || bytes.Equal(hdwallet.Hash160(serialized), hash) {
+				return key, serialized
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// challengeScript returns the scriptPubKey paid to by addr
+func challengeScript(addr *address.Address) ([]byte, error) {
+
+	challenge, err := script.PayToAddress(addr)
+	if err != nil {
+		return nil, ErrUnsupportedAddress
+	}
+
+	return challenge, nil
+}
+
+// parseCheckSigScript matches "<pubkey> CHECKSIG" and
+// "m <pubkey>... n CHECKMULTISIG" witness scripts
+func parseCheckSigScript(witnessScript []byte) (int, [][]byte, bool) {
+
+	switch script.Classify(witnessScript) {
+	case script.PubKey:
+		return 1, [][]byte{witnessScript[1 : len(witnessScript)-1]}, true
+	case script.MultiSig:
+		m, pubKeys, err := script.ExtractMultiSig(witnessScript)
+		return m, pubKeys, err == nil
+	}
+
+	return 0, nil, false
+}
+
+// serializeWitness encodes a witness stack as count followed by items
+func serializeWitness(witness transaction.Witness) []byte {
+
+	var buf bytes.Buffer
+
+	transaction.WriteVarInt(&buf, uint64(len(witness)))
+	for _, item := range witness {
+		transaction.WriteVarBytes(&buf, item)
+	}
+
+	return buf.Bytes()
+}
+
+// parseWitness decodes a serialized witness stack, all of data must be
+// consumed
+func parseWitness(data []byte) (transaction.Witness, error) {
+
+	r := bytes.NewReader(data)
+
+	count, err := transaction.ReadVarInt(r)
+	if err != nil || count > uint64(r.Len()) {
+		return nil, ErrInvalidSignature
+	}
+
+	witness := make(transaction.Witness, 0, count)
+	for k := uint64(0); k < count; k++ {
+		item, err := transaction.ReadVarBytes(r, uint64(r.Len()))
+		if err != nil {
+			return nil, ErrInvalidSignature
+		}
+		witness = append(witness, item)
+	}
+
+	if r.Len() != 0 {
+		return nil, ErrInvalidSignature
+	}
+
+	return witness, nil
+}
@@ -0,0 +1,318 @@
+package message
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"github.com/giogam/Gopher-Wallet/wallet/address"
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/script"
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+const bip322WIF = "L3VFeEujGtevx9w18HD1fhRbCH67Az2dpCymeRE1SoPK6XQtaN2k"
+
+type bip322test struct {
+	address   string
+	message   string
+	signature string
+}
+
+func bip322TestVector() []bip322test {
+	return []bip322test{
+		{
+			address:   "bc1q9vza2e8x573nczrlzms0wvx3gsqjx7vavgkx0l",
+			message:   "",
+			signature: "AkcwRAIgM2gBAQqvZX15ZiysmKmQpDrG83avLIT492QBzLnQIxYCIBaTpOaD20qRlEylyxFSeEA2ba9YOixpX8z46TSDtS40ASECx/EgAxlkQpQ9hYjgGu6EBCPMVPwVIVJqO4XCsMvViHI=",
+		},
+		{
+			address:   "bc1q9vza2e8x573nczrlzms0wvx3gsqjx7vavgkx0l",
+			message:   "Hello World",
+			signature: "AkcwRAIgZRfIY3p7/DoVTty6YZbWS71bc5Vct9p9Fia83eRmw2QCICK/ENGfwLtptFluMGs2KsqoNSk89pO7F29zJLUx9a/sASECx/EgAxlkQpQ9hYjgGu6EBCPMVPwVIVJqO4XCsMvViHI=",
+		},
+		{
+			address:   "bc1ppv609nr0vr25u07u95waq5lucwfm6tde4nydujnu8npg4q75mr5sxq8lt3",
+			message:   "Hello World",
+			signature: "AUHd69PrJQEv+oKTfZ8l+WROBHuy9HKrbFCJu7U1iK2iiEy1vMU5EfMtjc+VSHM7aU0SDbak5IUZRVno2P5mjSafAQ==",
+		},
+	}
+}
+
+func bip322Key(t *testing.T) *secp256k1.PrivateKey {
+
+	raw, _, _, err := hdwallet.DecodeWIF(bip322WIF)
+	assert.NoError(t, err)
+
+	key, err := secp256k1.PrivKeyFromBytes(raw)
+	assert.NoError(t, err)
+
+	return key
+}
+
+func TestBIP322Hash(t *testing.T) {
+	assert.Equal(t, "c90c269c4f8fcbe6880f72a721ddfbf1914268a794cbb21cfafee13770ae19f1", hex.EncodeToString(BIP322Hash("")))
+	assert.Equal(t, "f0eb03b1a75ac6d9847f55c624a99169b5dccba2a31f5b23bea77ba270de0a7a", hex.EncodeToString(BIP322Hash("Hello World")))
+}
+
+func TestBIP322Transactions(t *testing.T) {
+	addr, err := address.Decode("bc1q9vza2e8x573nczrlzms0wvx3gsqjx7vavgkx0l", address.MainNet)
+	assert.NoError(t, err)
+
+	challenge, err := challengeScript(addr)
+	assert.NoError(t, err)
+
+	for _, test := range []struct {
+		message string
+		toSpend string
+		toSign  string
+	}{
+		{"", "c5680aa69bb8d860bf82d4e9cd3504b55dde018de765a91bb566283c545a99a7", "1e9654e951a5ba44c8604c4de6c67fd78a27e81dcadcfe1edf638ba3aaebaed6"},
+		{"Hello World", "b79d196740ad5217771c1098fc4a4b51e0535c32236c71f1ea4d61a2d603352b", "88737ae86f2077145f93cc4b153ae9a1cb8d56afa511988c149c5c8c9d93bddf"},
+	} {
+		toSpend := newToSpend(challenge, test.message)
+		toSign := newToSign(toSpend)
+
+		toSpendHash := toSpend.TxHash()
+		toSignHash := toSign.TxHash()
+		assert.Equal(t, test.toSpend, toSpendHash.String())
+		assert.Equal(t, test.toSign, toSignHash.String())
+	}
+}
+
+func TestVerifyBIP322(t *testing.T) {
+	for _, test := range bip322TestVector() {
+		addr, err := address.Decode(test.address, address.MainNet)
+		assert.NoError(t, err)
+
+		ok, err := VerifyBIP322(addr, test.message, test.signature)
+		assert.NoError(t, err)
+		assert.True(t, ok, test.address)
+
+		ok, err = VerifyBIP322(addr, test.message+"!", test.signature)
+		assert.NoError(t, err)
+		assert.False(t, ok, test.address)
+	}
+}
+
+func TestSignBIP322(t *testing.T) {
+	key := bip322Key(t)
+	pub := key.PubKey().SerializeCompressed()
+
+	p2pkh, _ := address.NewP2PKHFromPubKey(pub, address.MainNet)
+	nested, _ := address.NewP2SHP2WPKHFromPubKey(pub, address.MainNet)
+	native, _ := address.NewP2WPKHFromPubKey(pub, address.MainNet)
+	taproot, _ := address.NewP2TRFromInternalKey(key.PubKey(), nil, address.MainNet)
+
+	for _, test := range []struct {
+		addr   *address.Address
+		format Format
+	}{
+		{p2pkh, FormatFull},
+		{nested, FormatFull},
+		{native, FormatSimple},
+		{native, FormatFull},
+		{taproot, FormatSimple},
+		{taproot, FormatFull},
+	} {
+		sig, err := SignBIP322(key, test.addr, "Hello World", test.format)
+		assert.NoError(t, err, test.addr.String())
+
+		ok, err := VerifyBIP322(test.addr, "Hello World", sig)
+		assert.NoError(t, err)
+		assert.True(t, ok, test.addr.String())
+	}
+
+	_, err := SignBIP322(key, p2pkh, "Hello World", FormatSimple)
+	assert.Equal(t, ErrUnsupportedFormat, err)
+
+	other, _ := address.Decode("bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", address.MainNet)
+	_, err = SignBIP322(key, other, "Hello World", FormatSimple)
+	assert.Equal(t, ErrKeyMismatch, err)
+}
+
+func TestSignBIP322Script(t *testing.T) {
+	keys := make([]*secp256k1.PrivateKey, 3)
+	for i := range keys {
+		key, err := secp256k1.NewPrivateKey()
+		assert.NoError(t, err)
+		keys[i] = key
+	}
+
+	// 2-of-3 multisig
+	pubs := make([][]byte, len(keys))
+	for i, key := range keys {
+		pubs[i] = key.PubKey().SerializeCompressed()
+	}
+	multisig, err := script.MultiSigScript(2, pubs)
+	assert.NoError(t, err)
+
+	addr, err := address.NewP2WSHFromScript(multisig, address.MainNet)
+	assert.NoError(t, err)
+
+	sig, err := SignBIP322Script([]*secp256k1.PrivateKey{keys[0], keys[2]}, multisig, addr, "multisig", FormatSimple)
+	assert.NoError(t, err)
+
+	ok, err := VerifyBIP322(addr, "multisig", sig)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	_, err = SignBIP322Script(keys[:1], multisig, addr, "multisig", FormatSimple)
+	assert.Equal(t, ErrNotEnoughKeys, err)
+
+	// nested in P2SH
+	nested, err := address.NewP2SHFromScript(p2wsh(multisig), address.MainNet)
+	assert.NoError(t, err)
+
+	sig, err = SignBIP322Script(keys[1:], multisig, nested, "nested", FormatFull)
+	assert.NoError(t, err)
+
+	ok, err = VerifyBIP322(nested, "nested", sig)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	_, err = VerifyBIP322(nested, "multisig", sig)
+	assert.Equal(t, ErrInvalidToSign, err)
+
+	// single key witness script
+	single, err := script.PayToPubKey(keys[1].PubKey().SerializeCompressed())
+	assert.NoError(t, err)
+
+	addr, err = address.NewP2WSHFromScript(single, address.MainNet)
+	assert.NoError(t, err)
+
+	sig, err = SignBIP322Script(keys[1:2], single, addr, "single", FormatFull)
+	assert.NoError(t, err)
+
+	ok, err = VerifyBIP322(addr, "single", sig)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestBIP322ProofOfFunds(t *testing.T) {
+	key := bip322Key(t)
+	addr, _ := address.NewP2WPKHFromPubKey(key.PubKey().SerializeCompressed(), address.MainNet)
+	challenge, _ := challengeScript(addr)
+
+	utxo := &Utxo{
+		Hash:     BIP322Hash("funding"),
+		Index:    1,
+		Value:    50000,
+		PkScript: challenge,
+	}
+
+	sig, err := SignBIP322ProofOfFunds(key, addr, "Hello World", []*Utxo{utxo})
+	assert.NoError(t, err)
+
+	fetch := func(hash []byte, index uint32) (*Utxo, error) {
+		return utxo, nil
+	}
+
+	ok, err := VerifyBIP322ProofOfFunds(addr, "Hello World", sig, fetch)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	_, err = VerifyBIP322(addr, "Hello World", sig)
+	assert.Equal(t, ErrUnknownUtxo, err)
+
+	// a different amount changes the signed digest
+	wrong := *utxo
+	wrong.Value++
+	ok, err = VerifyBIP322ProofOfFunds(addr, "Hello World", sig, func(hash []byte, index uint32) (*Utxo, error) {
+		return &wrong, nil
+	})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestVerifyBIP322TapscriptSpend(t *testing.T) {
+	internal := bip322Key(t)
+	leafKey, err := secp256k1.NewPrivateKey()
+	assert.NoError(t, err)
+
+	leaf, err := script.NewBuilder().AddData(leafKey.PubKey().SerializeXOnly()).AddOp(script.OpCheckSig).Script()
+	assert.NoError(t, err)
+	leafHash := hdwallet.TapLeafHash(0xc0, leaf)
+
+	addr, err := address.NewP2TRFromInternalKey(internal.PubKey(), leafHash, address.MainNet)
+	assert.NoError(t, err)
+
+	outputKey, err := hdwallet.TaprootTweakPubKey(internal.PubKey(), leafHash)
+	assert.NoError(t, err)
+	control := append([]byte{0xc0 | byte(outputKey.Y.Bit(0))}, internal.PubKey().SerializeXOnly()...)
+
+	toSpend, toSign := bip322Transactions(t, addr, "tapscript")
+	hashes := transaction.NewSigHashCache(toSign, transaction.PrevOutputMap{toSign.TxIn[0].PreviousOutPoint: toSpend.TxOut[0]})
+
+	hash, err := hashes.TaprootSigHash(0, transaction.SigHashDefault, transaction.NewTapscriptSpend(leafHash))
+	assert.NoError(t, err)
+	sig, err := secp256k1.SchnorrSign(leafKey, hash, nil)
+	assert.NoError(t, err)
+
+	signature := base64.StdEncoding.EncodeToString(serializeWitness(transaction.Witness{sig, leaf, control}))
+
+	ok, err := VerifyBIP322(addr, "tapscript", signature)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = VerifyBIP322(addr, "keypath", signature)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestVerifyBIP322SigHashTypes(t *testing.T) {
+	key := bip322Key(t)
+	pub := key.PubKey().SerializeCompressed()
+
+	addr, err := address.NewP2WPKHFromPubKey(pub, address.MainNet)
+	assert.NoError(t, err)
+
+	toSpend, toSign := bip322Transactions(t, addr, "anyonecanpay")
+	hashes := transaction.NewSigHashCache(toSign, nil)
+
+	scriptCode, err := script.PayToPubKeyHash(hdwallet.Hash160(pub))
+	assert.NoError(t, err)
+
+	hashType := transaction.SigHashAll | transaction.SigHashAnyOneCanPay
+	hash, err := hashes.WitnessV0SigHash(0, scriptCode, toSpend.TxOut[0].Value, hashType)
+	assert.NoError(t, err)
+
+	sig, err := secp256k1.Sign(key, hash)
+	assert.NoError(t, err)
+
+	witness := transaction.Witness{append(sig.Serialize(), byte(hashType)), pub}
+	signature := base64.StdEncoding.EncodeToString(serializeWitness(witness))
+
+	ok, err := VerifyBIP322(addr, "anyonecanpay", signature)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// the sighash byte is committed to by the signature
+	witness[0][len(witness[0])-1] = byte(transaction.SigHashAll)
+	signature = base64.StdEncoding.EncodeToString(serializeWitness(witness))
+
+	ok, err = VerifyBIP322(addr, "anyonecanpay", signature)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func bip322Transactions(t *testing.T, addr *address.Address, message string) (*transaction.Tx, *transaction.Tx) {
+
+	challenge, err := challengeScript(addr)
+	assert.NoError(t, err)
+
+	toSpend := newToSpend(challenge, message)
+
+	return toSpend, newToSign(toSpend)
+}
+
+func p2wsh(witnessScript []byte) []byte {
+
+	hash := sha256.Sum256(witnessScript)
+	pkScript, _ := script.PayToWitnessScriptHash(hash[:])
+
+	return pkScript
+}
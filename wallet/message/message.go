@@ -0,0 +1,161 @@
+package message
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"math/big"
+
+	"github.com/giogam/Gopher-Wallet/wallet/address"
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+// magic is the prefix committed to by every signed message
+const magic = "Bitcoin Signed Message:\n"
+
+// BIP137 header bytes, the recovery id is added to each base
+const (
+	headerP2PKHUncompressed = 27
+	headerP2PKHCompressed   = 31
+	headerP2SHP2WPKH        = 35
+	headerP2WPKH            = 39
+	headerMax               = 42
+)
+
+var (
+	// ErrInvalidSignature is returned when a signature cannot be decoded
+	ErrInvalidSignature = errors.New("message: invalid signature")
+	// ErrUnsupportedAddress is returned for address types without a
+	// BIP137 header, such as P2WSH and P2TR
+	ErrUnsupportedAddress = errors.New("message: unsupported address type")
+	// ErrKeyMismatch is returned when the signing key does not control the
+	// address
+	ErrKeyMismatch = errors.New("message: key does not match address")
+)
+
+// Hash returns the double SHA256 digest of the message with the Bitcoin
+// Signed Message prefix
+func Hash(message string) []byte {
+
+	var buf bytes.Buffer
+	transaction.WriteVarBytes(&buf, []byte(magic))
+	transaction.WriteVarBytes(&buf, []byte(message))
+
+	h := transaction.DoubleHashH(buf.Bytes())
+
+	return h[:]
+}
+
+// SignMessage signs message with key on behalf of addr and returns the
+// base64 compact signature, the header byte follows BIP137 for P2PKH,
+// P2SH-P2WPKH and P2WPKH addresses
+func SignMessage(key *secp256k1.PrivateKey, addr *address.Address, message string) (string, error) {
+
+	base, err := signingHeader(key, addr)
+	if err != nil {
+		return "", err
+	}
+
+	sig, err := secp256k1.SignCompact(key, Hash(message), false)
+	if err != nil {
+		return "", err
+	}
+
+	// SignCompact sets 27 + recovery id for uncompressed keys
+	sig[0] = byte(base) + sig[0] - headerP2PKHUncompressed
+
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+// VerifyMessage reports whether signature is a valid signature of message
+// by the owner of addr. Besides the BIP137 headers it accepts the Electrum
+// convention of signing segwit addresses with P2PKH compressed headers, as
+// well as segwit headers on P2PKH addresses.
+func VerifyMessage(addr *address.Address, message string, signature string) (bool, error) {
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil || len(sig) != secp256k1.CompactSigLen {
+		return false, ErrInvalidSignature
+	}
+
+	header := int(sig[0])
+	if header < headerP2PKHUncompressed || header > headerMax {
+		return false, ErrInvalidSignature
+	}
+
+	compressed := header >= headerP2PKHCompressed
+	recID := (header - headerP2PKHUncompressed) & 3
+
+	s := &secp256k1.Signature{
+		R: new(big.Int).SetBytes(sig[1:33]),
+		S: new(big.Int).SetBytes(sig[33:]),
+	}
+
+	pub, err := secp256k1.RecoverPubKey(s, Hash(message), recID)
+	if err != nil {
+		return false, nil
+	}
+
+	var serialized []byte
+	if compressed {
+		serialized = pub.SerializeCompressed()
+	} else {
+		serialized = pub.SerializeUncompressed()
+	}
+
+	var recovered *address.Address
+
+	switch addr.Type {
+	case address.P2PKH:
+		recovered, err = address.NewP2PKHFromPubKey(serialized, addr.Net)
+	case address.P2SH:
+		recovered, err = address.NewP2SHP2WPKHFromPubKey(serialized, addr.Net)
+	case address.P2WPKH:
+		recovered, err = address.NewP2WPKHFromPubKey(serialized, addr.Net)
+	default:
+		return false, ErrUnsupportedAddress
+	}
+
+	if err != nil {
+		return false, nil
+	}
+
+	return recovered.IsEqual(addr), nil
+}
+
+// signingHeader returns the base header byte for addr after checking that
+// key controls it
+func signingHeader(key *secp256k1.PrivateKey, addr *address.Address) (int, error) {
+
+	pub := key.PubKey()
+
+	var candidates []int
+	var derived []*address.Address
+
+	switch addr.Type {
+	case address.P2PKH:
+		c, _ := address.NewP2PKHFromPubKey(pub.SerializeCompressed(), addr.Net)
+		u, _ := address.NewP2PKHFromPubKey(pub.SerializeUncompressed(), addr.Net)
+		candidates = []int{headerP2PKHCompressed, headerP2PKHUncompressed}
+		derived = []*address.Address{c, u}
+	case address.P2SH:
+		a, _ := address.NewP2SHP2WPKHFromPubKey(pub.SerializeCompressed(), addr.Net)
+		candidates = []int{headerP2SHP2WPKH}
+		derived = []*address.Address{a}
+	case address.P2WPKH:
+		a, _ := address.NewP2WPKHFromPubKey(pub.SerializeCompressed(), addr.Net)
+		candidates = []int{headerP2WPKH}
+		derived = []*address.Address{a}
+	default:
+		return 0, ErrUnsupportedAddress
+	}
+
+	for i, a := range derived {
+		if a.IsEqual(addr) {
+			return candidates[i], nil
+		}
+	}
+
+	return 0, ErrKeyMismatch
+}
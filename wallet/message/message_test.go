@@ -0,0 +1,100 @@
+package message
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/giogam/Gopher-Wallet/wallet/address"
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testWIF     = "L4rK1yDtCWekvXuE6oXD9jCYfFNV2cWRpVuPLBcCU2z8TrisoyY1"
+	testMessage = "This is an example of a signed message."
+)
+
+func testKey(t *testing.T) *secp256k1.PrivateKey {
+
+	raw, _, _, err := hdwallet.DecodeWIF(testWIF)
+	assert.NoError(t, err)
+
+	key, err := secp256k1.PrivKeyFromBytes(raw)
+	assert.NoError(t, err)
+
+	return key
+}
+
+func TestSignMessage(t *testing.T) {
+	key := testKey(t)
+
+	addr, err := address.Decode("1F3sAm6ZtwLAUnj7d38pGFxtP3RVEvtsbV", address.MainNet)
+	assert.NoError(t, err)
+
+	sig, err := SignMessage(key, addr, testMessage)
+	assert.NoError(t, err)
+	assert.Equal(t, "H9L5yLFjti0QTHhPyFrZCT1V/MMnBtXKmoiKDZ78NDBjERki6ZTQZdSMCtkgoNmp17By9ItJr8o7ChX0XxY91nk=", sig)
+
+	ok, err := VerifyMessage(addr, testMessage, sig)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = VerifyMessage(addr, testMessage+".", sig)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestSignMessageSegwit(t *testing.T) {
+	key := testKey(t)
+	pub := key.PubKey().SerializeCompressed()
+
+	nested, err := address.NewP2SHP2WPKHFromPubKey(pub, address.MainNet)
+	assert.NoError(t, err)
+	native, err := address.NewP2WPKHFromPubKey(pub, address.MainNet)
+	assert.NoError(t, err)
+
+	for _, test := range []struct {
+		addr   *address.Address
+		header int
+	}{
+		{nested, headerP2SHP2WPKH},
+		{native, headerP2WPKH},
+	} {
+		sig, err := SignMessage(key, test.addr, testMessage)
+		assert.NoError(t, err)
+
+		raw, _ := base64.StdEncoding.DecodeString(sig)
+		recID := int(raw[0]) - test.header
+		assert.True(t, recID >= 0 && recID <= 3)
+
+		ok, err := VerifyMessage(test.addr, testMessage, sig)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		// Electrum signs segwit addresses with P2PKH compressed headers
+		raw[0] = byte(headerP2PKHCompressed + recID)
+		ok, err = VerifyMessage(test.addr, testMessage, base64.StdEncoding.EncodeToString(raw))
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	}
+}
+
+func TestSignMessageErrors(t *testing.T) {
+	key := testKey(t)
+
+	other, err := address.Decode("1MirQ9bwyQcGVJPwKUgapu5ouK2E2Ey4gX", address.MainNet)
+	assert.NoError(t, err)
+
+	_, err = SignMessage(key, other, testMessage)
+	assert.Equal(t, ErrKeyMismatch, err)
+
+	taproot, err := address.NewP2TRFromInternalKey(key.PubKey(), nil, address.MainNet)
+	assert.NoError(t, err)
+
+	_, err = SignMessage(key, taproot, testMessage)
+	assert.Equal(t, ErrUnsupportedAddress, err)
+
+	_, err = VerifyMessage(other, testMessage, "not base64")
+	assert.Equal(t, ErrInvalidSignature, err)
+}
@@ -0,0 +1,324 @@
+package miniscript
+
+import (
+	"math"
+	"sort"
+)
+
+// candidate is a compilation of a policy with its expected satisfaction
+// and dissatisfaction sizes, infinite when impossible
+type candidate struct {
+	node      *Node
+	sat, dsat float64
+}
+
+// cost returns the script size plus the satisfaction and dissatisfaction
+// sizes weighted by their probabilities
+func (c *candidate) cost(sat, dsat float64) float64 {
+
+	cost := float64(c.node.size)
+	if sat > 0 {
+		cost += sat * c.sat
+	}
+	if dsat > 0 {
+		cost += dsat * c.dsat
+	}
+
+	return cost
+}
+
+// better reports whether c is cheaper than old, ties are broken by the
+// script size then by the expression so that compilations do not depend
+// on map ordering
+func (c *candidate) better(old *candidate, sat, dsat float64) bool {
+
+	cost, oldCost := c.cost(sat, dsat), old.cost(sat, dsat)
+	switch {
+	case cost != oldCost:
+		return cost < oldCost
+	case c.node.size != old.node.size:
+		return c.node.size < old.node.size
+	}
+
+	return c.node.String() < old.node.String()
+}
+
+// expected returns a witness size as a float, infinite when invalid
+func expected(size maxInt) float64 {
+
+	if size == invalid {
+		return math.Inf(1)
+	}
+
+	return float64(size)
+}
+
+// candidates are the cheapest compilations of a policy for each type
+type candidates map[Type]*candidate
+
+// candidateTypes are the properties telling candidates apart, the others
+// do not change how they combine
+const candidateTypes = basicTypes | typeZ | typeO | typeN | typeD | typeU | typeE | typeF | typeS
+
+type compileKey struct {
+	policy    *Policy
+	sat, dsat float64
+}
+
+// compiler searches miniscripts for policies, keeping for every
+// subpolicy and probabilities the cheapest expression of each type
+type compiler struct {
+	ctx   Context
+	cache map[compileKey]candidates
+}
+
+// Compile returns the sane miniscript implementing the policy with the
+// smallest expected spend cost, the size of the script plus the size of
+// the satisfaction weighted by the probabilities of the branches
+func (p *Policy) Compile(ctx Context) (*Node, error) {
+
+	c := &compiler{ctx: ctx, cache: make(map[compileKey]candidates)}
+
+	var best *candidate
+	for _, cand := range c.compile(p, 1, 0).sorted() {
+		if cand.node.CheckSane() != nil {
+			continue
+		}
+		if best == nil || cand.better(best, 1, 0) {
+			best = cand
+		}
+	}
+
+	if best == nil {
+		return nil, ErrNoCompilation
+	}
+
+	return best.node, nil
+}
+
+// add keeps a candidate if it is the cheapest of its type, malleable
+// expressions and timelock mixes are dropped as no sane script contains
+// them
+func (cands candidates) add(cand *candidate, sat, dsat float64) bool {
+
+	if !cand.node.typ.Is(typeM | typeNoMix) {
+		return false
+	}
+
+	key := cand.node.typ & candidateTypes
+	if old, ok := cands[key]; ok && !cand.better(old, sat, dsat) {
+		return false
+	}
+	cands[key] = cand
+
+	return true
+}
+
+// sorted returns the candidates ordered by type
+func (cands candidates) sorted() []*candidate {
+
+	sorted := make([]*candidate, 0, len(cands))
+	for _, cand := range cands {
+		sorted = append(sorted, cand)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].node.typ&candidateTypes < sorted[j].node.typ&candidateTypes
+	})
+
+	return sorted
+}
+
+// compile returns the candidates of p satisfied with probability sat and
+// dissatisfied with probability dsat
+func (c *compiler) compile(p *Policy, sat, dsat float64) candidates {
+
+	key := compileKey{p, sat, dsat}
+	if cands, ok := c.cache[key]; ok {
+		return cands
+	}
+
+	ctx := c.ctx
+	cands := make(candidates)
+
+	leaf := func(frag Fragment, k uint32, keys [][]byte, data []byte) {
+		if n, err := newNode(ctx, frag, k, keys, data); err == nil {
+			cands.add(&candidate{n, expected(n.bytes.sat), expected(n.bytes.dsat)}, sat, dsat)
+		}
+	}
+	add := func(frag Fragment, esat, edsat float64, subs ...*candidate) {
+		nodes := make([]*Node, len(subs))
+		for i, sub := range subs {
+			nodes[i] = sub.node
+		}
+		if n, err := newNode(ctx, frag, 0, nil, nil, nodes...); err == nil {
+			cands.add(&candidate{n, esat, edsat}, sat, dsat)
+		}
+	}
+
+	switch p.Op {
+	case PolicyPk:
+		leaf(PkK, 0, [][]byte{p.Key}, nil)
+		leaf(PkH, 0, [][]byte{p.Key}, nil)
+	case PolicyAfter:
+		leaf(After, p.K, nil, nil)
+	case PolicyOlder:
+		leaf(Older, p.K, nil, nil)
+	case PolicySha256, PolicyHash256, PolicyRipemd160, PolicyHash160:
+		leaf(hashFragments[p.Op], 0, nil, p.Data)
+
+	case PolicyAnd:
+		zero := &candidate{just(ctx, Just0), math.Inf(1), 0}
+		for _, pair := range [][2]*Policy{{p.Subs[0], p.Subs[1]}, {p.Subs[1], p.Subs[0]}} {
+			// the first subexpression of and_v is never dissatisfied
+			for _, l := range c.compile(pair[0], sat, 0) {
+				for _, r := range c.compile(pair[1], sat, dsat) {
+					add(AndV, l.sat+r.sat, math.Inf(1), l, r)
+				}
+			}
+			for _, l := range c.compile(pair[0], sat, dsat) {
+				for _, r := range c.compile(pair[1], sat, dsat) {
+					add(AndB, l.sat+r.sat, l.dsat+r.dsat, l, r)
+					add(AndOr, l.sat+r.sat, l.dsat, l, r, zero)
+				}
+			}
+		}
+
+	case PolicyOr:
+		wx, wy := p.weights()
+		for _, pair := range []struct {
+			l, r   *Policy
+			wl, wr float64
+		}{{p.Subs[0], p.Subs[1], wx, wy}, {p.Subs[1], p.Subs[0], wy, wx}} {
+			wl, wr := pair.wl, pair.wr
+			// the left subexpression is dissatisfied whenever the right
+			// one is used, except in or_i
+			for _, l := range c.compile(pair.l, sat*wl, dsat+sat*wr) {
+				for _, r := range c.compile(pair.r, sat*wr, dsat+sat*wl) {
+					add(OrB, wl*(l.sat+r.dsat)+wr*(l.dsat+r.sat), l.dsat+r.dsat, l, r)
+					add(OrD, wl*l.sat+wr*(l.dsat+r.sat), l.dsat+r.dsat, l, r)
+					add(OrC, wl*l.sat+wr*(l.dsat+r.sat), math.Inf(1), l, r)
+				}
+			}
+			for _, l := range c.compile(pair.l, sat*wl, dsat) {
+				for _, r := range c.compile(pair.r, sat*wr, dsat) {
+					add(OrI, wl*(l.sat+2)+wr*(r.sat+1), math.Min(l.dsat+2, r.dsat+1), l, r)
+				}
+			}
+		}
+
+	case PolicyThresh:
+		c.compileThresh(p, sat, dsat, cands)
+	}
+
+	c.wrap(cands, sat, dsat)
+	c.cache[key] = cands
+
+	return cands
+}
+
+// compileThresh adds the multisig, thresh and chained and/or compilations
+// of a threshold
+func (c *compiler) compileThresh(p *Policy, sat, dsat float64, cands candidates) {
+
+	ctx, n, k := c.ctx, len(p.Subs), int(p.K)
+
+	var keys [][]byte
+	for _, sub := range p.Subs {
+		if sub.Op == PolicyPk {
+			keys = append(keys, sub.Key)
+		}
+	}
+	frag, limit := Multi, maxMultiKeys
+	if ctx == Tapscript {
+		frag, limit = MultiA, maxMultiAKeys
+	}
+	if len(keys) == n && n <= limit {
+		if m, err := newNode(ctx, frag, p.K, keys, nil); err == nil {
+			cands.add(&candidate{m, expected(m.bytes.sat), expected(m.bytes.dsat)}, sat, dsat)
+		}
+	}
+
+	// every subexpression is satisfied with probability k/n
+	subSat := sat * float64(k) / float64(n)
+	subDsat := dsat + sat*float64(n-k)/float64(n)
+	var nodes []*Node
+	esat, edsat := 0.0, 0.0
+	for i, sub := range p.Subs {
+		want := typeW | typeD | typeU
+		if i == 0 {
+			want = typeB | typeD | typeU
+		}
+		var best *candidate
+		for _, cand := range c.compile(sub, subSat, subDsat) {
+			if cand.node.typ.Is(want) && (best == nil || cand.better(best, subSat, subDsat)) {
+				best = cand
+			}
+		}
+		if best == nil {
+			nodes = nil
+			break
+		}
+		nodes = append(nodes, best.node)
+		esat += (float64(k)*best.sat + float64(n-k)*best.dsat) / float64(n)
+		edsat += best.dsat
+	}
+	if nodes != nil {
+		if t, err := newNode(ctx, Thresh, p.K, nil, nil, nodes...); err == nil {
+			cands.add(&candidate{t, esat, edsat}, sat, dsat)
+		}
+	}
+
+	// thresholds of all or one are chains of and or or
+	if n > 1 && (k == n || k == 1) {
+		op := PolicyAnd
+		if k == 1 {
+			op = PolicyOr
+		}
+		chain := p.Subs[n-1]
+		for i := n - 2; i >= 0; i-- {
+			chain = &Policy{Op: op, Subs: []*Policy{p.Subs[i], chain}}
+			if op == PolicyOr {
+				chain.Weights = []uint32{1, uint32(n - 1 - i)}
+			}
+		}
+		for _, cand := range c.compile(chain, sat, dsat) {
+			cands.add(cand, sat, dsat)
+		}
+	}
+}
+
+// wrap adds the wrapped forms of the candidates until none is cheaper
+func (c *compiler) wrap(cands candidates, sat, dsat float64) {
+
+	ctx := c.ctx
+	zero, one := just(ctx, Just0), just(ctx, Just1)
+	inf := math.Inf(1)
+
+	for changed := true; changed; {
+		changed = false
+		for _, x := range cands.sorted() {
+			wrappers := []struct {
+				frag      Fragment
+				subs      []*Node
+				sat, dsat float64
+			}{
+				{WrapA, []*Node{x.node}, x.sat, x.dsat},
+				{WrapS, []*Node{x.node}, x.sat, x.dsat},
+				{WrapC, []*Node{x.node}, x.sat, x.dsat},
+				{WrapN, []*Node{x.node}, x.sat, x.dsat},
+				{WrapD, []*Node{x.node}, x.sat + 2, 1},
+				{WrapJ, []*Node{x.node}, x.sat, 1},
+				{WrapV, []*Node{x.node}, x.sat, inf},
+				// t:, l: and u:
+				{AndV, []*Node{x.node, one}, x.sat, inf},
+				{OrI, []*Node{zero, x.node}, x.sat + 1, math.Min(2, x.dsat+1)},
+				{OrI, []*Node{x.node, zero}, x.sat + 2, math.Min(x.dsat+2, 1)},
+			}
+			for _, w := range wrappers {
+				if n, err := newNode(ctx, w.frag, 0, nil, nil, w.subs...); err == nil {
+					changed = cands.add(&candidate{n, w.sat, w.dsat}, sat, dsat) || changed
+				}
+			}
+		}
+	}
+}
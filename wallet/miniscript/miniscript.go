@@ -0,0 +1,1031 @@
+package miniscript
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/script"
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+)
+
+const (
+	lockTimeThreshold        = 500000000
+	sequenceLockTimeDisabled = 1 << 31
+	sequenceLockTimeTypeFlag = 1 << 22
+	sequenceLockTimeMask     = 0x0000ffff
+
+	// maxMultiKeys is the number of keys allowed in multi
+	maxMultiKeys = 20
+	// maxMultiAKeys is the number of keys allowed in multi_a
+	maxMultiAKeys = 999
+	// maxWitnessScriptSize is the standard size limit of P2WSH scripts
+	maxWitnessScriptSize = 3600
+	// maxOpsPerScript is the limit of executed non push opcodes in P2WSH
+	maxOpsPerScript = 201
+	// maxWitnessStackItems is the standard limit of P2WSH stack elements
+	maxWitnessStackItems = 100
+	// maxStackSize is the limit of elements on the tapscript stack
+	maxStackSize = 1000
+)
+
+var (
+	// ErrInvalidMiniscript is returned when an expression cannot be parsed
+	ErrInvalidMiniscript = errors.New("miniscript: invalid expression")
+	// ErrInvalidContext is returned when a fragment is not allowed in the
+	// script context
+	ErrInvalidContext = errors.New("miniscript: fragment not allowed in this context")
+	// ErrInvalidKey is returned when a key is not valid in the context
+	ErrInvalidKey = errors.New("miniscript: invalid key")
+	// ErrInvalidTimelock is returned when a timelock is out of range
+	ErrInvalidTimelock = errors.New("miniscript: invalid timelock")
+	// ErrInvalidThreshold is returned when a threshold is out of range
+	ErrInvalidThreshold = errors.New("miniscript: invalid threshold")
+	// ErrTypeCheck is returned when subexpressions do not have the types
+	// required by their fragment
+	ErrTypeCheck = errors.New("miniscript: type check failed")
+	// ErrNotTopLevel is returned when an expression is not of type B
+	ErrNotTopLevel = errors.New("miniscript: expression is not of type B")
+	// ErrResourceLimit is returned when a script exceeds the size, opcode
+	// or stack limits of its context
+	ErrResourceLimit = errors.New("miniscript: resource limits exceeded")
+	// ErrDuplicateKey is returned when a key appears more than once
+	ErrDuplicateKey = errors.New("miniscript: duplicate key")
+	// ErrTimelockMix is returned when a satisfaction needs both height and
+	// time based timelocks
+	ErrTimelockMix = errors.New("miniscript: timelock mix")
+	// ErrMalleable is returned when a script has malleable satisfactions
+	ErrMalleable = errors.New("miniscript: malleable script")
+	// ErrNoSignature is returned when a script can be satisfied without a
+	// signature
+	ErrNoSignature = errors.New("miniscript: script does not require a signature")
+)
+
+// Context is the script version a miniscript is encoded for
+type Context int
+
+const (
+	// P2WSH scripts are witness v0 scripts using CHECKMULTISIG
+	P2WSH Context = iota
+	// Tapscript scripts are taproot leaves using x-only keys and
+	// CHECKSIGADD
+	Tapscript
+)
+
+// keySize returns the serialized size of keys in ctx
+func (ctx Context) keySize() int {
+
+	if ctx == Tapscript {
+		return 32
+	}
+
+	return secp256k1.PubKeyCompressedLen
+}
+
+// sigSize returns the largest signature in ctx with its length prefix
+func (ctx Context) sigSize() int {
+
+	if ctx == Tapscript {
+		return 1 + 65
+	}
+
+	return 1 + 72
+}
+
+// Fragment identifies a miniscript expression
+type Fragment int
+
+const (
+	// Just0 and Just1 are the constants 0 and 1
+	Just0 Fragment = iota
+	Just1
+	// PkK and PkH check a key and a key hash, leaving the key on the stack
+	PkK
+	PkH
+	// Older and After are relative and absolute timelocks
+	Older
+	After
+	// Sha256, Hash256, Ripemd160 and Hash160 require a hash preimage
+	Sha256
+	Hash256
+	Ripemd160
+	Hash160
+	// AndOr is andor(X,Y,Z), either X and Y or Z
+	AndOr
+	AndV
+	AndB
+	OrB
+	OrC
+	OrD
+	OrI
+	// Thresh requires K of its subexpressions
+	Thresh
+	// Multi and MultiA are K of N multisigs using CHECKMULTISIG and
+	// CHECKSIGADD
+	Multi
+	MultiA
+	// WrapA to WrapN are the a: s: c: d: v: j: and n: wrappers
+	WrapA
+	WrapS
+	WrapC
+	WrapD
+	WrapV
+	WrapJ
+	WrapN
+)
+
+var fragmentNames = map[Fragment]string{
+	Just0: "0", Just1: "1", PkK: "pk_k", PkH: "pk_h", Older: "older", After: "after",
+	Sha256: "sha256", Hash256: "hash256", Ripemd160: "ripemd160", Hash160: "hash160",
+	AndOr: "andor", AndV: "and_v", AndB: "and_b", OrB: "or_b", OrC: "or_c", OrD: "or_d",
+	OrI: "or_i", Thresh: "thresh", Multi: "multi", MultiA: "multi_a",
+	WrapA: "a", WrapS: "s", WrapC: "c", WrapD: "d", WrapV: "v", WrapJ: "j", WrapN: "n",
+}
+
+// String returns the name of the fragment
+func (f Fragment) String() string {
+	return fragmentNames[f]
+}
+
+// hashSize returns the size of the hash of a hash fragment
+func (f Fragment) hashSize() int {
+
+	if f == Ripemd160 || f == Hash160 {
+		return 20
+	}
+
+	return 32
+}
+
+// maxInt is a size that may not exist, -1 when it does not
+type maxInt int
+
+const invalid maxInt = -1
+
+// add returns the size of both, invalid if either is
+func (a maxInt) add(b maxInt) maxInt {
+
+	if a == invalid || b == invalid {
+		return invalid
+	}
+
+	return a + b
+}
+
+// or returns the larger of two alternatives
+func (a maxInt) or(b maxInt) maxInt {
+
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+// satSize is the largest satisfaction and dissatisfaction of a measure
+type satSize struct {
+	sat, dsat maxInt
+}
+
+// opsCount counts the non push opcodes, sat and dsat are the keys checked
+// by CHECKMULTISIG on each path
+type opsCount struct {
+	count int
+	satSize
+}
+
+// Node is a miniscript expression
+type Node struct {
+	Fragment Fragment
+	// K is the threshold of thresh, multi and multi_a, or the value of a
+	// timelock
+	K uint32
+	// Keys are the keys of pk_k, pk_h, multi and multi_a
+	Keys [][]byte
+	// Data is the hash of a hash fragment
+	Data []byte
+	Subs []*Node
+
+	ctx   Context
+	typ   Type
+	size  int
+	ops   opsCount
+	bytes satSize
+	elems satSize
+}
+
+// newNode returns a type checked expression
+func newNode(ctx Context, frag Fragment, k uint32, keys [][]byte, data []byte, subs ...*Node) (*Node, error) {
+
+	types := make([]Type, len(subs))
+	for i, sub := range subs {
+		types[i] = sub.typ
+	}
+
+	n := &Node{Fragment: frag, K: k, Keys: keys, Data: data, Subs: subs, ctx: ctx}
+	if n.typ = computeType(frag, ctx, k, types); n.typ == 0 {
+		return nil, ErrTypeCheck
+	}
+
+	n.size = n.scriptSize()
+	n.ops, n.bytes, n.elems = n.measure()
+
+	return n, nil
+}
+
+// wrap applies wrapper letters to n, the last letter first
+func wrap(ctx Context, wrappers string, n *Node) (*Node, error) {
+
+	var err error
+	for i := len(wrappers) - 1; i >= 0; i-- {
+		switch wrappers[i] {
+		case 'a':
+			n, err = newNode(ctx, WrapA, 0, nil, nil, n)
+		case 's':
+			n, err = newNode(ctx, WrapS, 0, nil, nil, n)
+		case 'c':
+			n, err = newNode(ctx, WrapC, 0, nil, nil, n)
+		case 'd':
+			n, err = newNode(ctx, WrapD, 0, nil, nil, n)
+		case 'v':
+			n, err = newNode(ctx, WrapV, 0, nil, nil, n)
+		case 'j':
+			n, err = newNode(ctx, WrapJ, 0, nil, nil, n)
+		case 'n':
+			n, err = newNode(ctx, WrapN, 0, nil, nil, n)
+		case 't':
+			n, err = newNode(ctx, AndV, 0, nil, nil, n, just(ctx, Just1))
+		case 'l':
+			n, err = newNode(ctx, OrI, 0, nil, nil, just(ctx, Just0), n)
+		case 'u':
+			n, err = newNode(ctx, OrI, 0, nil, nil, n, just(ctx, Just0))
+		default:
+			return nil, ErrInvalidMiniscript
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return n, nil
+}
+
+// just returns the constant 0 or 1
+func just(ctx Context, frag Fragment) *Node {
+
+	n, _ := newNode(ctx, frag, 0, nil, nil)
+
+	return n
+}
+
+// Parse parses a miniscript expression for ctx, keys are hex encoded
+// compressed keys in P2WSH and x-only keys in tapscript
+func Parse(s string, ctx Context) (*Node, error) {
+
+	if strings.ContainsAny(s, " \t\n") {
+		return nil, ErrInvalidMiniscript
+	}
+
+	return parse(s, ctx)
+}
+
+func parse(s string, ctx Context) (*Node, error) {
+
+	if colon := strings.IndexByte(s, ':'); colon >= 0 && !strings.Contains(s[:colon], "(") {
+		if colon == 0 {
+			return nil, ErrInvalidMiniscript
+		}
+		sub, err := parse(s[colon+1:], ctx)
+		if err != nil {
+			return nil, err
+		}
+		return wrap(ctx, s[:colon], sub)
+	}
+
+	switch s {
+	case "0":
+		return just(ctx, Just0), nil
+	case "1":
+		return just(ctx, Just1), nil
+	}
+
+	name, args, err := call(s)
+	if err != nil {
+		return nil, err
+	}
+
+	switch name {
+	case "pk", "pkh", "pk_k", "pk_h":
+		if len(args) != 1 {
+			return nil, ErrInvalidMiniscript
+		}
+		key, err := parseKey(args[0], ctx)
+		if err != nil {
+			return nil, err
+		}
+		frag := PkK
+		if strings.HasPrefix(name, "pkh") || name == "pk_h" {
+			frag = PkH
+		}
+		n, err := newNode(ctx, frag, 0, [][]byte{key}, nil)
+		if err != nil || name == "pk_k" || name == "pk_h" {
+			return n, err
+		}
+		return newNode(ctx, WrapC, 0, nil, nil, n)
+
+	case "older", "after":
+		if len(args) != 1 {
+			return nil, ErrInvalidMiniscript
+		}
+		k, err := parseNumber(args[0])
+		if err != nil || k == 0 || k >= sequenceLockTimeDisabled {
+			return nil, ErrInvalidTimelock
+		}
+		frag := Older
+		if name == "after" {
+			frag = After
+		}
+		return newNode(ctx, frag, k, nil, nil)
+
+	case "sha256", "hash256", "ripemd160", "hash160":
+		frag := map[string]Fragment{"sha256": Sha256, "hash256": Hash256, "ripemd160": Ripemd160, "hash160": Hash160}[name]
+		if len(args) != 1 {
+			return nil, ErrInvalidMiniscript
+		}
+		data, err := hex.DecodeString(args[0])
+		if err != nil || len(data) != frag.hashSize() {
+			return nil, ErrInvalidMiniscript
+		}
+		return newNode(ctx, frag, 0, nil, data)
+
+	case "multi", "multi_a":
+		frag, limit := Multi, maxMultiKeys
+		if name == "multi_a" {
+			frag, limit = MultiA, maxMultiAKeys
+		}
+		if (frag == Multi) != (ctx == P2WSH) {
+			return nil, ErrInvalidContext
+		}
+		if len(args) < 2 {
+			return nil, ErrInvalidMiniscript
+		}
+		k, err := parseNumber(args[0])
+		if err != nil || k == 0 || int(k) > len(args)-1 || len(args)-1 > limit {
+			return nil, ErrInvalidThreshold
+		}
+		keys := make([][]byte, len(args)-1)
+		for i, arg := range args[1:] {
+			if keys[i], err = parseKey(arg, ctx); err != nil {
+				return nil, err
+			}
+		}
+		return newNode(ctx, frag, k, keys, nil)
+
+	case "thresh":
+		if len(args) < 2 {
+			return nil, ErrInvalidMiniscript
+		}
+		k, err := parseNumber(args[0])
+		if err != nil || k == 0 || int(k) > len(args)-1 {
+			return nil, ErrInvalidThreshold
+		}
+		subs, err := parseSubs(args[1:], ctx)
+		if err != nil {
+			return nil, err
+		}
+		return newNode(ctx, Thresh, k, nil, nil, subs...)
+	}
+
+	frags := map[string]Fragment{
+		"andor": AndOr, "and_n": AndOr, "and_v": AndV, "and_b": AndB,
+		"or_b": OrB, "or_c": OrC, "or_d": OrD, "or_i": OrI,
+	}
+	frag, ok := frags[name]
+	if !ok {
+		return nil, ErrInvalidMiniscript
+	}
+
+	want := 2
+	if name == "andor" {
+		want = 3
+	}
+	if len(args) != want {
+		return nil, ErrInvalidMiniscript
+	}
+
+	subs, err := parseSubs(args, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if name == "and_n" {
+		subs = append(subs, just(ctx, Just0))
+	}
+
+	return newNode(ctx, frag, 0, nil, nil, subs...)
+}
+
+func parseSubs(args []string, ctx Context) ([]*Node, error) {
+
+	subs := make([]*Node, len(args))
+	for i, arg := range args {
+		var err error
+		if subs[i], err = parse(arg, ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return subs, nil
+}
+
+// parseKey parses a hex encoded key of ctx
+func parseKey(s string, ctx Context) ([]byte, error) {
+
+	key, err := hex.DecodeString(s)
+	if err != nil || len(key) != ctx.keySize() {
+		return nil, ErrInvalidKey
+	}
+
+	if ctx == Tapscript {
+		_, err = secp256k1.ParseXOnlyPubKey(key)
+	} else {
+		_, err = secp256k1.ParsePubKey(key)
+	}
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+
+	return key, nil
+}
+
+// parseNumber parses a decimal without sign or leading zero
+func parseNumber(s string) (uint32, error) {
+
+	if s == "" || s[0] == '+' || (len(s) > 1 && s[0] == '0') {
+		return 0, ErrInvalidMiniscript
+	}
+
+	n, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, ErrInvalidMiniscript
+	}
+
+	return uint32(n), nil
+}
+
+// call splits name(args...) into the name and its arguments
+func call(s string) (string, []string, error) {
+
+	open := strings.IndexByte(s, '(')
+	if open <= 0 || !strings.HasSuffix(s, ")") {
+		return "", nil, ErrInvalidMiniscript
+	}
+
+	args, err := split(s[open+1 : len(s)-1])
+	if err != nil {
+		return "", nil, err
+	}
+
+	return s[:open], args, nil
+}
+
+// split splits a list at its top level commas
+func split(s string) ([]string, error) {
+
+	var args []string
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, ErrInvalidMiniscript
+			}
+		case ',':
+			if depth == 0 {
+				args = append(args, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	if depth != 0 {
+		return nil, ErrInvalidMiniscript
+	}
+
+	return append(args, s[start:]), nil
+}
+
+// String returns the expression, c:pk_k and c:pk_h are written pk and pkh
+func (n *Node) String() string {
+
+	var wrappers strings.Builder
+	for {
+		if n.Fragment == WrapC && (n.Subs[0].Fragment == PkK || n.Subs[0].Fragment == PkH) {
+			break
+		}
+		letter, sub := n.wrapper()
+		if sub == nil {
+			break
+		}
+		wrappers.WriteByte(letter)
+		n = sub
+	}
+
+	if wrappers.Len() == 0 {
+		return n.expression()
+	}
+
+	return wrappers.String() + ":" + n.expression()
+}
+
+// wrapper returns the letter and the wrapped expression when n is written
+// as a wrapper
+func (n *Node) wrapper() (byte, *Node) {
+
+	switch n.Fragment {
+	case WrapA, WrapS, WrapC, WrapD, WrapV, WrapJ, WrapN:
+		return n.Fragment.String()[0], n.Subs[0]
+	case AndV:
+		if n.Subs[1].Fragment == Just1 {
+			return 't', n.Subs[0]
+		}
+	case OrI:
+		if n.Subs[0].Fragment == Just0 {
+			return 'l', n.Subs[1]
+		}
+		if n.Subs[1].Fragment == Just0 {
+			return 'u', n.Subs[0]
+		}
+	}
+
+	return 0, nil
+}
+
+// expression returns n without its wrappers
+func (n *Node) expression() string {
+
+	var args []string
+	name := n.Fragment.String()
+
+	switch n.Fragment {
+	case Just0, Just1:
+		return name
+	case WrapC:
+		// only reached for c:pk_k and c:pk_h
+		name = map[Fragment]string{PkK: "pk", PkH: "pkh"}[n.Subs[0].Fragment]
+		args = append(args, hex.EncodeToString(n.Subs[0].Keys[0]))
+	case PkK, PkH:
+		args = append(args, hex.EncodeToString(n.Keys[0]))
+	case Older, After:
+		args = append(args, strconv.FormatUint(uint64(n.K), 10))
+	case Sha256, Hash256, Ripemd160, Hash160:
+		args = append(args, hex.EncodeToString(n.Data))
+	case Multi, MultiA:
+		args = append(args, strconv.FormatUint(uint64(n.K), 10))
+		for _, key := range n.Keys {
+			args = append(args, hex.EncodeToString(key))
+		}
+	case Thresh:
+		args = append(args, strconv.FormatUint(uint64(n.K), 10))
+		fallthrough
+	default:
+		subs := n.Subs
+		if n.Fragment == AndOr && subs[2].Fragment == Just0 {
+			name, subs = "and_n", subs[:2]
+		}
+		for _, sub := range subs {
+			args = append(args, sub.String())
+		}
+	}
+
+	return name + "(" + strings.Join(args, ",") + ")"
+}
+
+// pushInt returns the minimal push of n
+func pushInt(n int64) []byte {
+
+	s, _ := script.NewBuilder().AddInt64(n).Script()
+
+	return s
+}
+
+// pushData returns the push of data
+func pushData(data []byte) []byte {
+
+	s, _ := script.NewBuilder().AddData(data).Script()
+
+	return s
+}
+
+// scriptSize returns the size of the script of n from the sizes of its
+// subexpressions
+func (n *Node) scriptSize() int {
+
+	size := 0
+	for _, sub := range n.Subs {
+		size += sub.size
+	}
+
+	switch n.Fragment {
+	case Just0, Just1:
+		return 1
+	case PkK:
+		return 1 + n.ctx.keySize()
+	case PkH:
+		return 3 + 21
+	case Older, After:
+		return 1 + len(pushInt(int64(n.K)))
+	case Sha256, Hash256, Ripemd160, Hash160:
+		return 4 + 2 + 1 + n.Fragment.hashSize()
+	case Multi:
+		return 1 + len(pushInt(int64(len(n.Keys)))) + len(pushInt(int64(n.K))) + (1+n.ctx.keySize())*len(n.Keys)
+	case MultiA:
+		return (1+n.ctx.keySize()+1)*len(n.Keys) + len(pushInt(int64(n.K))) + 1
+	case AndV:
+		return size
+	case WrapV:
+		if n.Subs[0].typ.Is(typeX) {
+			return size + 1
+		}
+		return size
+	case WrapS, WrapC, WrapN, AndB, OrB:
+		return size + 1
+	case WrapA, OrC:
+		return size + 2
+	case WrapD, OrD, OrI, AndOr:
+		return size + 3
+	case WrapJ:
+		return size + 4
+	case Thresh:
+		return size + len(n.Subs) + len(pushInt(int64(n.K)))
+	}
+
+	return size
+}
+
+// measure returns the opcode count and the largest satisfaction and
+// dissatisfaction of n, in bytes and in stack elements
+func (n *Node) measure() (opsCount, satSize, satSize) {
+
+	var x, y, z *Node
+	switch len(n.Subs) {
+	case 3:
+		z = n.Subs[2]
+		fallthrough
+	case 2:
+		y = n.Subs[1]
+		fallthrough
+	case 1:
+		x = n.Subs[0]
+	}
+
+	sig, key := maxInt(n.ctx.sigSize()), maxInt(1+n.ctx.keySize())
+	keys, k := maxInt(len(n.Keys)), maxInt(n.K)
+
+	switch n.Fragment {
+	case Just0:
+		return opsCount{0, satSize{invalid, 0}}, satSize{invalid, 0}, satSize{invalid, 0}
+	case Just1:
+		return opsCount{0, satSize{0, invalid}}, satSize{0, invalid}, satSize{0, invalid}
+	case PkK:
+		return opsCount{0, satSize{0, 0}}, satSize{sig, 1}, satSize{1, 1}
+	case PkH:
+		return opsCount{3, satSize{0, 0}}, satSize{sig + key, 1 + key}, satSize{2, 2}
+	case Older, After:
+		return opsCount{1, satSize{0, invalid}}, satSize{0, invalid}, satSize{0, invalid}
+	case Sha256, Hash256, Ripemd160, Hash160:
+		// any other 32 byte element dissatisfies
+		return opsCount{4, satSize{0, 0}}, satSize{1 + 32, 1 + 32}, satSize{1, 1}
+	case Multi:
+		return opsCount{1, satSize{keys, keys}}, satSize{1 + k*sig, 1 + k}, satSize{1 + k, 1 + k}
+	case MultiA:
+		return opsCount{int(keys) + 1, satSize{0, 0}}, satSize{k*sig + keys - k, keys}, satSize{keys, keys}
+
+	case WrapA, WrapS, WrapC, WrapN:
+		extra := 1
+		if n.Fragment == WrapA {
+			extra = 2
+		}
+		return opsCount{extra + x.ops.count, x.ops.satSize}, x.bytes, x.elems
+	case WrapD:
+		return opsCount{3 + x.ops.count, satSize{x.ops.sat, 0}},
+			satSize{x.bytes.sat.add(2), 1}, satSize{x.elems.sat.add(1), 1}
+	case WrapJ:
+		return opsCount{4 + x.ops.count, satSize{x.ops.sat, 0}}, satSize{x.bytes.sat, 1}, satSize{x.elems.sat, 1}
+	case WrapV:
+		count := x.ops.count
+		if x.typ.Is(typeX) {
+			count++
+		}
+		return opsCount{count, satSize{x.ops.sat, invalid}}, satSize{x.bytes.sat, invalid}, satSize{x.elems.sat, invalid}
+
+	case AndV:
+		m := func(a, b satSize) satSize { return satSize{a.sat.add(b.sat), invalid} }
+		return opsCount{x.ops.count + y.ops.count, m(x.ops.satSize, y.ops.satSize)}, m(x.bytes, y.bytes), m(x.elems, y.elems)
+	case AndB:
+		m := func(a, b satSize) satSize { return satSize{a.sat.add(b.sat), a.dsat.add(b.dsat)} }
+		return opsCount{1 + x.ops.count + y.ops.count, m(x.ops.satSize, y.ops.satSize)}, m(x.bytes, y.bytes), m(x.elems, y.elems)
+	case OrB:
+		m := func(a, b satSize) satSize {
+			return satSize{a.sat.add(b.dsat).or(a.dsat.add(b.sat)), a.dsat.add(b.dsat)}
+		}
+		return opsCount{1 + x.ops.count + y.ops.count, m(x.ops.satSize, y.ops.satSize)}, m(x.bytes, y.bytes), m(x.elems, y.elems)
+	case OrC, OrD:
+		extra := 3
+		if n.Fragment == OrC {
+			extra = 2
+		}
+		m := func(a, b satSize) satSize {
+			dsat := a.dsat.add(b.dsat)
+			if n.Fragment == OrC {
+				dsat = invalid
+			}
+			return satSize{a.sat.or(a.dsat.add(b.sat)), dsat}
+		}
+		return opsCount{extra + x.ops.count + y.ops.count, m(x.ops.satSize, y.ops.satSize)}, m(x.bytes, y.bytes), m(x.elems, y.elems)
+	case OrI:
+		// the left branch is selected by a 1, the right one by an empty
+		// element
+		m := func(a, b satSize, left, right maxInt) satSize {
+			return satSize{a.sat.add(left).or(b.sat.add(right)), a.dsat.add(left).or(b.dsat.add(right))}
+		}
+		ops := m(x.ops.satSize, y.ops.satSize, 0, 0)
+		return opsCount{3 + x.ops.count + y.ops.count, ops}, m(x.bytes, y.bytes, 2, 1), m(x.elems, y.elems, 1, 1)
+	case AndOr:
+		m := func(a, b, c satSize) satSize {
+			return satSize{a.sat.add(b.sat).or(a.dsat.add(c.sat)), a.dsat.add(c.dsat)}
+		}
+		return opsCount{3 + x.ops.count + y.ops.count + z.ops.count, m(x.ops.satSize, y.ops.satSize, z.ops.satSize)},
+			m(x.bytes, y.bytes, z.bytes), m(x.elems, y.elems, z.elems)
+
+	case Thresh:
+		count := 0
+		ops, bytes, elems := []satSize{}, []satSize{}, []satSize{}
+		for _, sub := range n.Subs {
+			count += sub.ops.count + 1
+			ops, bytes, elems = append(ops, sub.ops.satSize), append(bytes, sub.bytes), append(elems, sub.elems)
+		}
+		return opsCount{count, thresh(int(k), ops)}, thresh(int(k), bytes), thresh(int(k), elems)
+	}
+
+	return opsCount{}, satSize{}, satSize{}
+}
+
+// thresh returns the largest satisfaction of k of subs and their
+// dissatisfaction
+func thresh(k int, subs []satSize) satSize {
+
+	// sats[j] is the largest satisfaction of j of the subexpressions so far
+	sats := []maxInt{0}
+	for _, sub := range subs {
+		next := []maxInt{sats[0].add(sub.dsat)}
+		for j := 1; j < len(sats); j++ {
+			next = append(next, sats[j].add(sub.dsat).or(sats[j-1].add(sub.sat)))
+		}
+		next = append(next, sats[len(sats)-1].add(sub.sat))
+		sats = next
+	}
+
+	return satSize{sats[k], sats[0]}
+}
+
+// Script returns the script of n
+func (n *Node) Script() []byte {
+
+	var subs [][]byte
+	for _, sub := range n.Subs {
+		subs = append(subs, sub.Script())
+	}
+
+	cat := func(parts ...[]byte) []byte { return bytes.Join(parts, nil) }
+	op := func(ops ...byte) []byte { return ops }
+
+	switch n.Fragment {
+	case Just0:
+		return op(script.Op0)
+	case Just1:
+		return op(script.Op1)
+	case PkK:
+		return pushData(n.Keys[0])
+	case PkH:
+		return cat(op(script.OpDup, script.OpHash160), pushData(hdwallet.Hash160(n.Keys[0])), op(script.OpEqualVerify))
+	case Older:
+		return cat(pushInt(int64(n.K)), op(script.OpCheckSequenceVerify))
+	case After:
+		return cat(pushInt(int64(n.K)), op(script.OpCheckLockTimeVerify))
+	case Sha256, Hash256, Ripemd160, Hash160:
+		hashOp := map[Fragment]byte{Sha256: script.OpSha256, Hash256: script.OpHash256, Ripemd160: script.OpRipemd160, Hash160: script.OpHash160}
+		return cat(op(script.OpSize), pushInt(32), op(script.OpEqualVerify, hashOp[n.Fragment]), pushData(n.Data), op(script.OpEqual))
+	case Multi:
+		s := pushInt(int64(n.K))
+		for _, key := range n.Keys {
+			s = append(s, pushData(key)...)
+		}
+		return cat(s, pushInt(int64(len(n.Keys))), op(script.OpCheckMultiSig))
+	case MultiA:
+		s := cat(pushData(n.Keys[0]), op(script.OpCheckSig))
+		for _, key := range n.Keys[1:] {
+			s = cat(s, pushData(key), op(script.OpCheckSigAdd))
+		}
+		return cat(s, pushInt(int64(n.K)), op(script.OpNumEqual))
+
+	case WrapA:
+		return cat(op(script.OpToAltStack), subs[0], op(script.OpFromAltStack))
+	case WrapS:
+		return cat(op(script.OpSwap), subs[0])
+	case WrapC:
+		return cat(subs[0], op(script.OpCheckSig))
+	case WrapD:
+		return cat(op(script.OpDup, script.OpIf), subs[0], op(script.OpEndIf))
+	case WrapV:
+		if n.Subs[0].typ.Is(typeX) {
+			return cat(subs[0], op(script.OpVerify))
+		}
+		// the last opcode is CHECKSIG, CHECKMULTISIG, EQUAL or NUMEQUAL,
+		// each followed by its VERIFY variant
+		s := subs[0]
+		s[len(s)-1]++
+		return s
+	case WrapJ:
+		return cat(op(script.OpSize, script.Op0NotEqual, script.OpIf), subs[0], op(script.OpEndIf))
+	case WrapN:
+		return cat(subs[0], op(script.Op0NotEqual))
+
+	case AndV:
+		return cat(subs[0], subs[1])
+	case AndB:
+		return cat(subs[0], subs[1], op(script.OpBoolAnd))
+	case OrB:
+		return cat(subs[0], subs[1], op(script.OpBoolOr))
+	case OrC:
+		return cat(subs[0], op(script.OpNotIf), subs[1], op(script.OpEndIf))
+	case OrD:
+		return cat(subs[0], op(script.OpIfDup, script.OpNotIf), subs[1], op(script.OpEndIf))
+	case OrI:
+		return cat(op(script.OpIf), subs[0], op(script.OpElse), subs[1], op(script.OpEndIf))
+	case AndOr:
+		return cat(subs[0], op(script.OpNotIf), subs[2], op(script.OpElse), subs[1], op(script.OpEndIf))
+	case Thresh:
+		s := subs[0]
+		for _, sub := range subs[1:] {
+			s = cat(s, sub, op(script.OpAdd))
+		}
+		return cat(s, pushInt(int64(n.K)), op(script.OpEqual))
+	}
+
+	return nil
+}
+
+// Context returns the script context of n
+func (n *Node) Context() Context {
+	return n.ctx
+}
+
+// Type returns the type of n
+func (n *Node) Type() Type {
+	return n.typ
+}
+
+// ScriptSize returns the size in bytes of the script
+func (n *Node) ScriptSize() int {
+	return n.size
+}
+
+// Ops returns the largest number of non push opcodes executed by a
+// satisfaction, counted as done by the P2WSH limit
+func (n *Node) Ops() (int, bool) {
+
+	if n.ops.sat == invalid {
+		return 0, false
+	}
+
+	return n.ops.count + int(n.ops.sat), true
+}
+
+// MaxSatisfactionSize returns the largest size of the satisfaction
+// elements including their length prefixes, false when n cannot be
+// satisfied
+func (n *Node) MaxSatisfactionSize() (int, bool) {
+	return int(n.bytes.sat), n.bytes.sat != invalid
+}
+
+// MaxSatisfactionElems returns the largest number of elements of a
+// satisfaction
+func (n *Node) MaxSatisfactionElems() (int, bool) {
+	return int(n.elems.sat), n.elems.sat != invalid
+}
+
+// MaxWitnessSize returns the largest serialized witness spending the
+// script, the element count, the satisfaction and the script itself. In
+// tapscript the count includes the control block, whose size is left to
+// the caller as it depends on the script tree.
+func (n *Node) MaxWitnessSize() (int, bool) {
+
+	sat, ok := n.MaxSatisfactionSize()
+	if !ok {
+		return 0, false
+	}
+
+	items := int(n.elems.sat) + 1
+	if n.ctx == Tapscript {
+		items++
+	}
+
+	return varIntSize(items) + sat + varIntSize(n.size) + n.size, true
+}
+
+func varIntSize(n int) int {
+
+	switch {
+	case n < 0xfd:
+		return 1
+	case n <= 0xffff:
+		return 3
+	}
+
+	return 5
+}
+
+// NeedsSignature reports whether every satisfaction requires a signature
+func (n *Node) NeedsSignature() bool {
+	return n.typ.Is(typeS)
+}
+
+// IsNonMalleable reports whether a third party cannot turn a
+// satisfaction into another valid one
+func (n *Node) IsNonMalleable() bool {
+	return n.typ.Is(typeM)
+}
+
+// HasTimelockMix reports whether some satisfaction needs both height and
+// time based timelocks, which no transaction can satisfy
+func (n *Node) HasTimelockMix() bool {
+	return !n.typ.Is(typeNoMix)
+}
+
+// CheckSane returns an error when n is not a script the wallet should
+// use, it must be of type B, within the limits of its context, free of
+// duplicate keys and timelock mixes, non malleable and require a signature
+func (n *Node) CheckSane() error {
+
+	if !n.typ.Is(typeB) {
+		return ErrNotTopLevel
+	}
+
+	if !n.withinLimits() {
+		return ErrResourceLimit
+	}
+
+	seen := make(map[string]bool)
+	for _, key := range n.keys() {
+		if seen[string(key)] {
+			return ErrDuplicateKey
+		}
+		seen[string(key)] = true
+	}
+
+	switch {
+	case n.HasTimelockMix():
+		return ErrTimelockMix
+	case !n.IsNonMalleable():
+		return ErrMalleable
+	case !n.NeedsSignature():
+		return ErrNoSignature
+	}
+
+	return nil
+}
+
+// withinLimits reports whether the script and its satisfactions fit the
+// standard limits of the context
+func (n *Node) withinLimits() bool {
+
+	elems, ok := n.MaxSatisfactionElems()
+	if !ok {
+		return true
+	}
+
+	if n.ctx == Tapscript {
+		return elems <= maxStackSize
+	}
+
+	ops, _ := n.Ops()
+
+	return n.size <= maxWitnessScriptSize && ops <= maxOpsPerScript && elems <= maxWitnessStackItems
+}
+
+// keys returns the keys of n in script order
+func (n *Node) keys() [][]byte {
+
+	keys := append([][]byte{}, n.Keys...)
+	for _, sub := range n.Subs {
+		keys = append(keys, sub.keys()...)
+	}
+
+	return keys
+}
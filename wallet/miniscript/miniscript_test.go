@@ -0,0 +1,247 @@
+package miniscript
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/giogam/Gopher-Wallet/wallet/script"
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+	"github.com/stretchr/testify/assert"
+)
+
+// testKeys returns the private keys 1 to 5, the replacer of the names A
+// to E by their public keys in ctx and the reverse replacer
+func testKeys(ctx Context) ([]*secp256k1.PrivateKey, *strings.Replacer, *strings.Replacer) {
+
+	var keys []*secp256k1.PrivateKey
+	var names, pubs []string
+	for i := 1; i <= 5; i++ {
+		raw := make([]byte, 32)
+		raw[31] = byte(i)
+		key, _ := secp256k1.PrivKeyFromBytes(raw)
+		keys = append(keys, key)
+
+		pub := key.PubKey().SerializeCompressed()
+		if ctx == Tapscript {
+			pub = key.PubKey().SerializeXOnly()
+		}
+		names = append(names, string(rune('A'+i-1)), hex.EncodeToString(pub))
+		pubs = append(pubs, hex.EncodeToString(pub), string(rune('A'+i-1)))
+	}
+
+	return keys, strings.NewReplacer(names...), strings.NewReplacer(pubs...)
+}
+
+const testHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		ms  string
+		ctx Context
+		typ Type
+	}{
+		{"pk(A)", P2WSH, typeB | typeO | typeN | typeD | typeU | typeE | typeS | typeM},
+		{"pkh(A)", P2WSH, typeB | typeN | typeD | typeU | typeE | typeS | typeM},
+		{"v:pk(A)", P2WSH, typeV | typeO | typeN | typeF | typeS | typeM},
+		{"and_v(v:pk(A),or_d(pk(B),older(144)))", P2WSH, typeB | typeN | typeS | typeM | typeH},
+		{"or_b(pk(A),s:pk(B))", P2WSH, typeB | typeD | typeU | typeE | typeS | typeM},
+		{"and_n(pk(A),after(1000))", P2WSH, typeB | typeO | typeD | typeE | typeS | typeM | typeJ},
+		{"t:or_c(pk(A),v:pk(B))", P2WSH, typeB | typeU | typeF | typeS | typeM},
+		{"thresh(2,pk(A),s:pk(B),sln:older(10))", P2WSH, typeB | typeD | typeU | typeM},
+		{"multi(2,A,B,C)", P2WSH, typeB | typeD | typeU | typeE | typeS | typeM},
+		{"multi_a(2,A,B,C)", Tapscript, typeB | typeD | typeU | typeE | typeS | typeM},
+		{"and_v(v:sha256(" + testHash + "),pk(A))", P2WSH, typeB | typeN | typeU | typeS},
+		{"dv:older(1)", P2WSH, typeB | typeO | typeN | typeD | typeE | typeM},
+		{"dv:older(1)", Tapscript, typeB | typeO | typeN | typeD | typeU | typeE | typeM},
+		{"andor(pk(A),older(10),pk(B))", P2WSH, typeB | typeD | typeE | typeS | typeM},
+	}
+
+	for _, test := range tests {
+		_, names, _ := testKeys(test.ctx)
+		ms := names.Replace(test.ms)
+
+		n, err := Parse(ms, test.ctx)
+		if !assert.NoError(t, err, test.ms) {
+			continue
+		}
+
+		assert.True(t, n.Type().Is(test.typ), "%s: %s", test.ms, n.Type())
+		assert.Equal(t, n.Type()&basicTypes, test.typ&basicTypes, test.ms)
+		assert.Equal(t, ms, n.String())
+		assert.Equal(t, len(n.Script()), n.ScriptSize(), test.ms)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		ms  string
+		ctx Context
+		err error
+	}{
+		{"pk(A", P2WSH, ErrInvalidMiniscript},
+		{"pk(A) ", P2WSH, ErrInvalidMiniscript},
+		{"x:pk(A)", P2WSH, ErrInvalidMiniscript},
+		{":pk(A)", P2WSH, ErrInvalidMiniscript},
+		{"pk(A,B)", P2WSH, ErrInvalidMiniscript},
+		{"and_v(pk(A))", P2WSH, ErrInvalidMiniscript},
+		{"foo(A)", P2WSH, ErrInvalidMiniscript},
+		{"sha256(00)", P2WSH, ErrInvalidMiniscript},
+		{"pk(00)", P2WSH, ErrInvalidKey},
+		{"older(0)", P2WSH, ErrInvalidTimelock},
+		{"after(2147483648)", P2WSH, ErrInvalidTimelock},
+		{"older(01)", P2WSH, ErrInvalidTimelock},
+		{"multi(0,A,B)", P2WSH, ErrInvalidThreshold},
+		{"multi(3,A,B)", P2WSH, ErrInvalidThreshold},
+		{"thresh(3,pk(A),s:pk(B))", P2WSH, ErrInvalidThreshold},
+		{"multi_a(1,A)", P2WSH, ErrInvalidContext},
+		{"multi(1,A)", Tapscript, ErrInvalidContext},
+		{"or_b(pk(A),pk(B))", P2WSH, ErrTypeCheck},
+		{"and_v(pk(A),pk(B))", P2WSH, ErrTypeCheck},
+		{"c:older(1)", P2WSH, ErrTypeCheck},
+		{"thresh(1,s:pk(A),pk(B))", P2WSH, ErrTypeCheck},
+		{"d:v:older(1)", P2WSH, nil},
+	}
+
+	for _, test := range tests {
+		_, names, _ := testKeys(test.ctx)
+		_, err := Parse(names.Replace(test.ms), test.ctx)
+		assert.Equal(t, test.err, err, test.ms)
+	}
+
+	// x-only keys are only valid in tapscript
+	_, names, _ := testKeys(Tapscript)
+	_, err := Parse(names.Replace("pk(A)"), P2WSH)
+	assert.Equal(t, ErrInvalidKey, err)
+}
+
+func TestScript(t *testing.T) {
+	tests := []struct {
+		ms     string
+		ctx    Context
+		script string
+	}{
+		{"pk(A)", P2WSH, "A OP_CHECKSIG"},
+		{"v:pk(A)", P2WSH, "A OP_CHECKSIGVERIFY"},
+		{"pkh(A)", P2WSH, "OP_DUP OP_HASH160 751e76e8199196d454941c45d1b3a323f1433bd6 OP_EQUALVERIFY OP_CHECKSIG"},
+		{"and_v(v:pk(A),or_d(pk(B),older(144)))", P2WSH, "A OP_CHECKSIGVERIFY B OP_CHECKSIG OP_IFDUP OP_NOTIF 9000 OP_CHECKSEQUENCEVERIFY OP_ENDIF"},
+		{"multi(2,A,B,C)", P2WSH, "OP_2 A B C OP_3 OP_CHECKMULTISIG"},
+		{"v:multi(1,A)", P2WSH, "OP_1 A OP_1 OP_CHECKMULTISIGVERIFY"},
+		{"multi_a(2,A,B)", Tapscript, "A OP_CHECKSIG B OP_CHECKSIGADD OP_2 OP_NUMEQUAL"},
+		{"v:sha256(" + testHash + ")", P2WSH, "OP_SIZE 20 OP_EQUALVERIFY OP_SHA256 " + testHash + " OP_EQUALVERIFY"},
+		{"v:after(100)", P2WSH, "64 OP_CHECKLOCKTIMEVERIFY OP_VERIFY"},
+		{"or_i(pk(A),pk(B))", P2WSH, "OP_IF A OP_CHECKSIG OP_ELSE B OP_CHECKSIG OP_ENDIF"},
+		{"andor(pk(A),older(10),pk(B))", P2WSH, "A OP_CHECKSIG OP_NOTIF B OP_CHECKSIG OP_ELSE OP_10 OP_CHECKSEQUENCEVERIFY OP_ENDIF"},
+		{"thresh(2,pk(A),s:pk(B),a:pk(C))", P2WSH, "A OP_CHECKSIG OP_SWAP B OP_CHECKSIG OP_ADD OP_TOALTSTACK C OP_CHECKSIG OP_FROMALTSTACK OP_ADD OP_2 OP_EQUAL"},
+		{"and_b(pk(A),a:pk(B))", P2WSH, "A OP_CHECKSIG OP_TOALTSTACK B OP_CHECKSIG OP_FROMALTSTACK OP_BOOLAND"},
+		{"j:pk(A)", P2WSH, "OP_SIZE OP_0NOTEQUAL OP_IF A OP_CHECKSIG OP_ENDIF"},
+	}
+
+	for _, test := range tests {
+		_, names, pubs := testKeys(test.ctx)
+
+		n, err := Parse(names.Replace(test.ms), test.ctx)
+		if !assert.NoError(t, err, test.ms) {
+			continue
+		}
+
+		asm, err := script.Disassemble(n.Script())
+		assert.NoError(t, err)
+		assert.Equal(t, test.script, pubs.Replace(asm), test.ms)
+		assert.Equal(t, len(n.Script()), n.ScriptSize(), test.ms)
+	}
+}
+
+func TestCheckSane(t *testing.T) {
+	tests := []struct {
+		ms  string
+		err error
+	}{
+		{"and_v(v:pk(A),or_d(pk(B),older(144)))", nil},
+		{"or_d(multi(2,A,B,C),and_v(v:pk(D),after(100)))", nil},
+		{"v:pk(A)", ErrNotTopLevel},
+		{"and_v(v:pk(A),pk(A))", ErrDuplicateKey},
+		{"and_v(v:pk(A),and_v(v:after(500000001),after(1)))", ErrTimelockMix},
+		{"thresh(2,pk(A),s:pk(B),sln:older(10))", nil},
+		{"thresh(2,pk(A),sln:older(10),sln:older(4194305))", ErrTimelockMix},
+		{"or_b(sha256(" + testHash + "),s:pk(A))", ErrMalleable},
+		{"or_i(pk(A),older(1))", ErrNoSignature},
+	}
+
+	for _, test := range tests {
+		_, names, _ := testKeys(P2WSH)
+
+		n, err := Parse(names.Replace(test.ms), P2WSH)
+		if !assert.NoError(t, err, test.ms) {
+			continue
+		}
+		assert.Equal(t, test.err, n.CheckSane(), test.ms)
+	}
+
+	// the keys checked by the dissatisfied multisigs exceed the P2WSH
+	// opcode limit
+	keys, _, _ := testKeys(P2WSH)
+	var args []string
+	for i := 0; i < 20; i++ {
+		raw := make([]byte, 32)
+		raw[30], raw[31] = 1, byte(i)
+		key, _ := secp256k1.PrivKeyFromBytes(raw)
+		args = append(args, hex.EncodeToString(key.PubKey().SerializeCompressed()))
+	}
+	ms := "thresh(1,pk(" + hex.EncodeToString(keys[0].PubKey().SerializeCompressed()) + ")"
+	for i := 0; i < 10; i++ {
+		ms += ",a:multi(20," + strings.Join(args, ",") + ")"
+	}
+	n, err := Parse(ms+")", P2WSH)
+	assert.NoError(t, err)
+	assert.Equal(t, ErrResourceLimit, n.CheckSane())
+}
+
+func TestMaxWitnessSize(t *testing.T) {
+	tests := []struct {
+		ms    string
+		ctx   Context
+		sat   int
+		elems int
+		size  int
+	}{
+		// a signature
+		{"pk(A)", P2WSH, 73, 1, 1 + 73 + 1 + 35},
+		// a signature and the key
+		{"pkh(A)", P2WSH, 73 + 34, 2, 1 + 73 + 34 + 1 + 25},
+		// the dummy element and two signatures
+		{"multi(2,A,B,C)", P2WSH, 1 + 2*73, 3, 1 + 1 + 2*73 + 1 + 105},
+		// the signatures of A and B
+		{"and_v(v:pk(A),or_d(pk(B),older(144)))", P2WSH, 2 * 73, 2, 1 + 2*73 + 1 + 77},
+		// two signatures and an empty element, the control block is left
+		// out
+		{"multi_a(2,A,B,C)", Tapscript, 2*66 + 1, 3, 1 + 2*66 + 1 + 1 + 104},
+	}
+
+	for _, test := range tests {
+		_, names, _ := testKeys(test.ctx)
+
+		n, err := Parse(names.Replace(test.ms), test.ctx)
+		if !assert.NoError(t, err, test.ms) {
+			continue
+		}
+
+		sat, ok := n.MaxSatisfactionSize()
+		assert.True(t, ok)
+		assert.Equal(t, test.sat, sat, test.ms)
+
+		elems, ok := n.MaxSatisfactionElems()
+		assert.True(t, ok)
+		assert.Equal(t, test.elems, elems, test.ms)
+
+		size, ok := n.MaxWitnessSize()
+		assert.True(t, ok)
+		assert.Equal(t, test.size, size, test.ms)
+	}
+
+	// without satisfaction
+	n, err := Parse("and_v(v:older(1),0)", P2WSH)
+	assert.NoError(t, err)
+	_, ok := n.MaxWitnessSize()
+	assert.False(t, ok)
+}
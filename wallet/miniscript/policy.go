@@ -0,0 +1,193 @@
+package miniscript
+
+import (
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidPolicy is returned when a policy cannot be parsed
+	ErrInvalidPolicy = errors.New("miniscript: invalid policy")
+	// ErrNoCompilation is returned when no sane miniscript implements a
+	// policy
+	ErrNoCompilation = errors.New("miniscript: policy has no sane compilation")
+)
+
+// PolicyOp is the operator of a policy
+type PolicyOp int
+
+const (
+	// PolicyPk requires a signature of a key
+	PolicyPk PolicyOp = iota
+	// PolicyAfter requires an absolute timelock
+	PolicyAfter
+	// PolicyOlder requires a relative timelock
+	PolicyOlder
+	// PolicySha256 requires the preimage of a SHA256 hash
+	PolicySha256
+	// PolicyHash256 requires the preimage of a double SHA256 hash
+	PolicyHash256
+	// PolicyRipemd160 requires the preimage of a RIPEMD160 hash
+	PolicyRipemd160
+	// PolicyHash160 requires the preimage of a HASH160 hash
+	PolicyHash160
+	// PolicyAnd requires both of its subpolicies
+	PolicyAnd
+	// PolicyOr requires one of its subpolicies
+	PolicyOr
+	// PolicyThresh requires K of its subpolicies
+	PolicyThresh
+)
+
+var policyNames = map[PolicyOp]string{
+	PolicyPk: "pk", PolicyAfter: "after", PolicyOlder: "older",
+	PolicySha256: "sha256", PolicyHash256: "hash256", PolicyRipemd160: "ripemd160", PolicyHash160: "hash160",
+	PolicyAnd: "and", PolicyOr: "or", PolicyThresh: "thresh",
+}
+
+// hashFragments are the fragments of the hash policies
+var hashFragments = map[PolicyOp]Fragment{
+	PolicySha256: Sha256, PolicyHash256: Hash256, PolicyRipemd160: Ripemd160, PolicyHash160: Hash160,
+}
+
+// Policy is a spending condition written in the policy language, such as
+// or(99@pk(A),1@and(pk(B),older(1000)))
+type Policy struct {
+	Op PolicyOp
+	// K is the threshold of thresh or the value of a timelock
+	K    uint32
+	Key  []byte
+	Data []byte
+	Subs []*Policy
+	// Weights are the relative probabilities of the branches of or being
+	// used, both are 1 when not given
+	Weights []uint32
+}
+
+// ParsePolicy parses a policy whose keys are encoded as in ctx
+func ParsePolicy(s string, ctx Context) (*Policy, error) {
+
+	name, args, err := call(s)
+	if err != nil {
+		return nil, ErrInvalidPolicy
+	}
+
+	op := PolicyOp(-1)
+	for o, n := range policyNames {
+		if n == name {
+			op = o
+		}
+	}
+
+	p := &Policy{Op: op}
+	switch op {
+	case PolicyPk:
+		if len(args) != 1 {
+			return nil, ErrInvalidPolicy
+		}
+		if p.Key, err = parseKey(args[0], ctx); err != nil {
+			return nil, err
+		}
+
+	case PolicyAfter, PolicyOlder:
+		if len(args) != 1 {
+			return nil, ErrInvalidPolicy
+		}
+		p.K, err = parseNumber(args[0])
+		if err != nil || p.K == 0 || p.K >= sequenceLockTimeDisabled {
+			return nil, ErrInvalidTimelock
+		}
+
+	case PolicySha256, PolicyHash256, PolicyRipemd160, PolicyHash160:
+		if len(args) != 1 {
+			return nil, ErrInvalidPolicy
+		}
+		p.Data, err = hex.DecodeString(args[0])
+		if err != nil || len(p.Data) != hashFragments[op].hashSize() {
+			return nil, ErrInvalidPolicy
+		}
+
+	case PolicyAnd, PolicyOr:
+		if len(args) != 2 {
+			return nil, ErrInvalidPolicy
+		}
+		for _, arg := range args {
+			weight := uint32(1)
+			if at := strings.IndexByte(arg, '@'); op == PolicyOr && at >= 0 && at < strings.IndexByte(arg, '(') {
+				if weight, err = parseNumber(arg[:at]); err != nil || weight == 0 {
+					return nil, ErrInvalidPolicy
+				}
+				arg = arg[at+1:]
+			}
+			sub, err := ParsePolicy(arg, ctx)
+			if err != nil {
+				return nil, err
+			}
+			p.Subs = append(p.Subs, sub)
+			if op == PolicyOr {
+				p.Weights = append(p.Weights, weight)
+			}
+		}
+
+	case PolicyThresh:
+		if len(args) < 2 {
+			return nil, ErrInvalidPolicy
+		}
+		p.K, err = parseNumber(args[0])
+		if err != nil || p.K == 0 || int(p.K) > len(args)-1 {
+			return nil, ErrInvalidThreshold
+		}
+		for _, arg := range args[1:] {
+			sub, err := ParsePolicy(arg, ctx)
+			if err != nil {
+				return nil, err
+			}
+			p.Subs = append(p.Subs, sub)
+		}
+
+	default:
+		return nil, ErrInvalidPolicy
+	}
+
+	return p, nil
+}
+
+// String returns the policy in the policy language
+func (p *Policy) String() string {
+
+	var args []string
+	switch p.Op {
+	case PolicyPk:
+		args = append(args, hex.EncodeToString(p.Key))
+	case PolicyAfter, PolicyOlder:
+		args = append(args, strconv.FormatUint(uint64(p.K), 10))
+	case PolicySha256, PolicyHash256, PolicyRipemd160, PolicyHash160:
+		args = append(args, hex.EncodeToString(p.Data))
+	case PolicyThresh:
+		args = append(args, strconv.FormatUint(uint64(p.K), 10))
+		fallthrough
+	default:
+		for i, sub := range p.Subs {
+			arg := sub.String()
+			if p.Op == PolicyOr && len(p.Weights) == len(p.Subs) && !(p.Weights[0] == 1 && p.Weights[1] == 1) {
+				arg = strconv.FormatUint(uint64(p.Weights[i]), 10) + "@" + arg
+			}
+			args = append(args, arg)
+		}
+	}
+
+	return policyNames[p.Op] + "(" + strings.Join(args, ",") + ")"
+}
+
+// weights returns the probabilities of the branches of or
+func (p *Policy) weights() (float64, float64) {
+
+	a, b := 1.0, 1.0
+	if len(p.Weights) == 2 {
+		a, b = float64(p.Weights[0]), float64(p.Weights[1])
+	}
+
+	return a / (a + b), b / (a + b)
+}
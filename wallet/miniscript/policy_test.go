@@ -0,0 +1,105 @@
+package miniscript
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePolicy(t *testing.T) {
+	tests := []string{
+		"pk(A)",
+		"after(100)",
+		"sha256(" + testHash + ")",
+		"and(pk(A),or(pk(B),older(144)))",
+		"or(99@pk(A),1@and(pk(B),older(1000)))",
+		"thresh(2,pk(A),pk(B),pk(C))",
+	}
+
+	for _, test := range tests {
+		_, names, _ := testKeys(P2WSH)
+
+		p, err := ParsePolicy(names.Replace(test), P2WSH)
+		if !assert.NoError(t, err, test) {
+			continue
+		}
+		assert.Equal(t, names.Replace(test), p.String())
+	}
+}
+
+func TestParsePolicyInvalid(t *testing.T) {
+	tests := []struct {
+		policy string
+		ctx    Context
+		err    error
+	}{
+		{"pk()", P2WSH, ErrInvalidKey},
+		{"pk(A,B)", P2WSH, ErrInvalidPolicy},
+		{"pk(B)", Tapscript, nil},
+		{"pk(A)", P2WSH, nil},
+		{"older(0)", P2WSH, ErrInvalidTimelock},
+		{"after(2147483648)", P2WSH, ErrInvalidTimelock},
+		{"sha256(00)", P2WSH, ErrInvalidPolicy},
+		{"and(pk(A))", P2WSH, ErrInvalidPolicy},
+		{"and(1@pk(A),pk(B))", P2WSH, ErrInvalidPolicy},
+		{"or(0@pk(A),pk(B))", P2WSH, ErrInvalidPolicy},
+		{"thresh(3,pk(A),pk(B))", P2WSH, ErrInvalidThreshold},
+		{"thresh(0,pk(A))", P2WSH, ErrInvalidThreshold},
+		{"multi(1,A)", P2WSH, ErrInvalidPolicy},
+	}
+
+	for _, test := range tests {
+		_, names, _ := testKeys(test.ctx)
+
+		_, err := ParsePolicy(names.Replace(test.policy), test.ctx)
+		assert.Equal(t, test.err, err, test.policy)
+	}
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		policy  string
+		ctx     Context
+		ms      string
+		signers []int
+		older   uint32
+		after   uint32
+		err     error
+	}{
+		{"pk(A)", P2WSH, "pk(A)", []int{0}, 0, 0, nil},
+		{"and(pk(A),pk(B))", Tapscript, "and_v(v:pk(A),pk(B))", []int{0, 1}, 0, 0, nil},
+		{"or(pk(A),pk(B))", P2WSH, "or_b(pk(A),s:pk(B))", []int{1}, 0, 0, nil},
+		// the unlikely branch moves behind a cheaper satisfaction
+		{"or(99@pk(A),1@pk(B))", P2WSH, "t:or_c(pk(A),v:pkh(B))", []int{1}, 0, 0, nil},
+		{"or(pk(A),and(pk(B),older(144)))", P2WSH, "or_d(pk(A),and_v(v:pk(B),older(144)))", []int{1}, 144, 0, nil},
+		{"and(pk(A),or(pk(B),older(144)))", Tapscript, "and_v(or_c(pk(B),v:older(144)),pk(A))", []int{0}, 144, 0, nil},
+		{"thresh(3,pk(A),pk(B),pk(C),pk(D),pk(E))", P2WSH, "multi(3,A,B,C,D,E)", []int{0, 2, 4}, 0, 0, nil},
+		{"thresh(3,pk(A),pk(B),pk(C),pk(D),pk(E))", Tapscript, "multi_a(3,A,B,C,D,E)", []int{1, 2, 3}, 0, 0, nil},
+		{"thresh(2,pk(A),pk(B),older(4320))", P2WSH, "thresh(2,pk(A),s:pk(B),sln:older(4320))", []int{0}, 4320, 0, nil},
+		{"or(thresh(2,pk(A),pk(B),pk(C)),and(pk(D),after(100)))", Tapscript, "or_d(multi_a(2,A,B,C),and_v(v:pk(D),after(100)))", []int{3}, 0, 100, nil},
+		// a timelock alone is not protected by any signature
+		{"after(100)", P2WSH, "", nil, 0, 0, ErrNoCompilation},
+		{"thresh(1,pk(A),pk(B),older(10))", P2WSH, "", nil, 0, 0, ErrNoCompilation},
+	}
+
+	for _, test := range tests {
+		_, names, pubs := testKeys(test.ctx)
+
+		p, err := ParsePolicy(names.Replace(test.policy), test.ctx)
+		if !assert.NoError(t, err, test.policy) {
+			continue
+		}
+
+		n, err := p.Compile(test.ctx)
+		assert.Equal(t, test.err, err, test.policy)
+		if err != nil {
+			continue
+		}
+
+		assert.Equal(t, test.ms, pubs.Replace(n.String()), test.policy)
+		assert.NoError(t, n.CheckSane(), test.policy)
+
+		_, err = spend(t, n, test.signers, test.older, test.after, false)
+		assert.NoError(t, err, test.policy)
+	}
+}
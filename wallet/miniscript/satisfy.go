@@ -0,0 +1,305 @@
+package miniscript
+
+import (
+	"encoding/hex"
+	"errors"
+)
+
+var (
+	// ErrUnsatisfiable is returned when the available signatures,
+	// preimages and timelocks do not satisfy a script
+	ErrUnsatisfiable = errors.New("miniscript: cannot satisfy script")
+	// ErrMalleableSatisfaction is returned when the only satisfactions
+	// found could be modified by a third party
+	ErrMalleableSatisfaction = errors.New("miniscript: satisfaction is malleable")
+)
+
+// Satisfier holds what is available to satisfy a script
+type Satisfier struct {
+	// Signatures maps hex encoded keys to their signature, including the
+	// sighash byte
+	Signatures map[string][]byte
+	// Preimages maps hex encoded hashes to their preimage
+	Preimages map[string][]byte
+	// Sequence and LockTime are those of the spending input and
+	// transaction, they decide which timelocks are satisfied. The
+	// transaction version must be 2 for relative timelocks.
+	Sequence uint32
+	LockTime uint32
+}
+
+// checkOlder reports whether the input sequence satisfies older(k)
+func (s *Satisfier) checkOlder(k uint32) bool {
+
+	if s.Sequence&sequenceLockTimeDisabled != 0 {
+		return false
+	}
+
+	const mask = sequenceLockTimeTypeFlag | sequenceLockTimeMask
+	if s.Sequence&sequenceLockTimeTypeFlag != k&sequenceLockTimeTypeFlag {
+		return false
+	}
+
+	return k&mask <= s.Sequence&mask
+}
+
+// checkAfter reports whether the lock time satisfies after(k), the input
+// must not be final
+func (s *Satisfier) checkAfter(k uint32) bool {
+
+	if (s.LockTime < lockTimeThreshold) != (k < lockTimeThreshold) {
+		return false
+	}
+
+	return k <= s.LockTime
+}
+
+// stack is a candidate witness, elements are ordered bottom first
+type stack struct {
+	available bool
+	// hasSig reports whether a signature is part of the stack, a third
+	// party cannot create it
+	hasSig    bool
+	malleable bool
+	size      int
+	elems     [][]byte
+}
+
+var unavailable = stack{}
+
+// empty is the stack without elements
+func empty() stack {
+	return stack{available: true}
+}
+
+// push returns the stack of a single element
+func push(elem []byte) stack {
+	return stack{available: true, size: varIntSize(len(elem)) + len(elem), elems: [][]byte{elem}}
+}
+
+// sigStack returns the stack of a signature, if available
+func sigStack(sig []byte, ok bool) stack {
+
+	if !ok {
+		return unavailable
+	}
+
+	st := push(sig)
+	st.hasSig = true
+
+	return st
+}
+
+// cat returns a with b on top
+func cat(a, b stack) stack {
+
+	if !a.available || !b.available {
+		return unavailable
+	}
+
+	return stack{
+		available: true,
+		hasSig:    a.hasSig || b.hasSig,
+		malleable: a.malleable || b.malleable,
+		size:      a.size + b.size,
+		elems:     append(append([][]byte{}, a.elems...), b.elems...),
+	}
+}
+
+// setMalleable returns a marked malleable
+func (a stack) setMalleable(malleable bool) stack {
+
+	a.malleable = a.malleable || malleable
+
+	return a
+}
+
+// choose returns the best of two alternatives, one with a signature is
+// avoided when the other does not need one as a third party could use
+// the latter instead
+func choose(a, b stack) stack {
+
+	switch {
+	case !a.available:
+		return b
+	case !b.available:
+		return a
+	case !a.hasSig && b.hasSig:
+		return a
+	case !b.hasSig && a.hasSig:
+		return b
+	case !a.hasSig && !b.hasSig:
+		// anyone can create either of them
+		a.malleable, b.malleable = true, true
+	case b.malleable && !a.malleable:
+		return a
+	case a.malleable && !b.malleable:
+		return b
+	}
+
+	if a.size <= b.size {
+		return a
+	}
+
+	return b
+}
+
+var (
+	zero = []byte{}
+	one  = []byte{1}
+)
+
+// Satisfy returns the smallest non malleable satisfaction of n, the
+// witness elements preceding the script
+func (n *Node) Satisfy(s *Satisfier) ([][]byte, error) {
+
+	_, sat := n.produce(s)
+
+	switch {
+	case !sat.available:
+		return nil, ErrUnsatisfiable
+	case sat.malleable || !sat.hasSig:
+		return nil, ErrMalleableSatisfaction
+	}
+
+	return sat.elems, nil
+}
+
+// produce returns the best dissatisfaction and satisfaction of n
+func (n *Node) produce(s *Satisfier) (stack, stack) {
+
+	signature := func(key []byte) stack {
+		sig, ok := s.Signatures[hex.EncodeToString(key)]
+		return sigStack(sig, ok)
+	}
+
+	var subs []struct{ nsat, sat stack }
+	for _, sub := range n.Subs {
+		nsat, sat := sub.produce(s)
+		subs = append(subs, struct{ nsat, sat stack }{nsat, sat})
+	}
+
+	switch n.Fragment {
+	case Just0:
+		return empty(), unavailable
+	case Just1:
+		return unavailable, empty()
+
+	case PkK:
+		return push(zero), signature(n.Keys[0])
+	case PkH:
+		key := push(n.Keys[0])
+		return cat(push(zero), key), cat(signature(n.Keys[0]), key)
+
+	case Older:
+		if s.checkOlder(n.K) {
+			return unavailable, empty()
+		}
+		return unavailable, unavailable
+	case After:
+		if s.checkAfter(n.K) {
+			return unavailable, empty()
+		}
+		return unavailable, unavailable
+
+	case Sha256, Hash256, Ripemd160, Hash160:
+		// anyone can dissatisfy with a wrong preimage
+		nsat := push(make([]byte, 32)).setMalleable(true)
+		if preimage, ok := s.Preimages[hex.EncodeToString(n.Data)]; ok {
+			return nsat, push(preimage)
+		}
+		return nsat, unavailable
+
+	case Multi:
+		// sats[j] is the best stack with j signatures of the keys so far,
+		// on top of the dummy element
+		sats := []stack{push(zero)}
+		for _, key := range n.Keys {
+			sig := signature(key)
+			next := []stack{sats[0]}
+			for j := 1; j < len(sats); j++ {
+				next = append(next, choose(sats[j], cat(sats[j-1], sig)))
+			}
+			sats = append(next, cat(sats[len(sats)-1], sig))
+		}
+		nsat := push(zero)
+		for i := uint32(0); i < n.K; i++ {
+			nsat = cat(nsat, push(zero))
+		}
+		return nsat, sats[n.K]
+
+	case MultiA:
+		// the signature of the first key is on top of the stack
+		sats := []stack{empty()}
+		for i := range n.Keys {
+			sig := signature(n.Keys[len(n.Keys)-1-i])
+			next := []stack{cat(sats[0], push(zero))}
+			for j := 1; j < len(sats); j++ {
+				next = append(next, choose(cat(sats[j], push(zero)), cat(sats[j-1], sig)))
+			}
+			sats = append(next, cat(sats[len(sats)-1], sig))
+		}
+		return sats[0], sats[n.K]
+
+	case Thresh:
+		// sats[j] is the best stack satisfying j of the last subexpressions
+		sats := []stack{empty()}
+		for i := range subs {
+			sub := subs[len(subs)-1-i]
+			next := []stack{cat(sats[0], sub.nsat)}
+			for j := 1; j < len(sats); j++ {
+				next = append(next, choose(cat(sats[j], sub.nsat), cat(sats[j-1], sub.sat)))
+			}
+			sats = append(next, cat(sats[len(sats)-1], sub.sat))
+		}
+		// dissatisfactions satisfying some subexpressions are malleable
+		nsat := unavailable
+		for j := range sats {
+			if j != 0 && j != int(n.K) {
+				sats[j] = sats[j].setMalleable(true)
+			}
+			if j != int(n.K) {
+				nsat = choose(nsat, sats[j])
+			}
+		}
+		return nsat, sats[n.K]
+
+	case WrapA, WrapS, WrapC, WrapN:
+		return subs[0].nsat, subs[0].sat
+	case WrapD:
+		return push(zero), cat(subs[0].sat, push(one))
+	case WrapJ:
+		// a dissatisfaction of the subexpression with a non zero top
+		// element could replace the empty one
+		x := subs[0]
+		return push(zero).setMalleable(x.nsat.available && !x.nsat.hasSig), x.sat
+	case WrapV:
+		return unavailable, subs[0].sat
+
+	case AndV:
+		x, y := subs[0], subs[1]
+		return cat(y.nsat, x.sat), cat(y.sat, x.sat)
+	case AndB:
+		x, y := subs[0], subs[1]
+		nsat := choose(choose(cat(y.nsat, x.nsat), cat(y.sat, x.nsat).setMalleable(true)), cat(y.nsat, x.sat).setMalleable(true))
+		return nsat, cat(y.sat, x.sat)
+	case OrB:
+		x, z := subs[0], subs[1]
+		sat := choose(choose(cat(z.nsat, x.sat), cat(z.sat, x.nsat)), cat(z.sat, x.sat).setMalleable(true))
+		return cat(z.nsat, x.nsat), sat
+	case OrC:
+		x, z := subs[0], subs[1]
+		return unavailable, choose(x.sat, cat(z.sat, x.nsat))
+	case OrD:
+		x, z := subs[0], subs[1]
+		return cat(z.nsat, x.nsat), choose(x.sat, cat(z.sat, x.nsat))
+	case OrI:
+		x, z := subs[0], subs[1]
+		return choose(cat(x.nsat, push(one)), cat(z.nsat, push(zero))), choose(cat(x.sat, push(one)), cat(z.sat, push(zero)))
+	case AndOr:
+		x, y, z := subs[0], subs[1], subs[2]
+		return choose(cat(y.nsat, x.sat), cat(z.nsat, x.nsat)), choose(cat(y.sat, x.sat), cat(z.sat, x.nsat))
+	}
+
+	return unavailable, unavailable
+}
@@ -0,0 +1,157 @@
+package miniscript
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/script"
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+// testPreimage is 32 bytes long as required by the hash fragments
+var testPreimage = []byte("vault recovery secret 0123456789")
+
+// spend satisfies n with the signatures of signers and verifies the
+// witness spending its output, signers are indices of the test keys
+func spend(t *testing.T, n *Node, signers []int, sequence, lockTime uint32, preimage bool) (transaction.Witness, error) {
+
+	keys, _, _ := testKeys(n.Context())
+	leaf := n.Script()
+
+	// tapscript leaves are committed to by the last test key
+	var pkScript, leafHash, control []byte
+	if n.Context() == Tapscript {
+		leafHash = hdwallet.TapLeafHash(hdwallet.BaseLeafVersion, leaf)
+		outKey, err := hdwallet.TaprootTweakPubKey(keys[4].PubKey(), leafHash)
+		assert.NoError(t, err)
+		pkScript, _ = script.PayToTaproot(outKey.SerializeXOnly())
+		control = append([]byte{hdwallet.BaseLeafVersion | byte(outKey.Y.Bit(0))}, keys[4].PubKey().SerializeXOnly()...)
+	} else {
+		h := sha256.Sum256(leaf)
+		pkScript, _ = script.PayToWitnessScriptHash(h[:])
+	}
+
+	var prevHash transaction.Hash
+	prevOut := transaction.NewOutPoint(&prevHash, 0)
+
+	tx := transaction.NewTx(2)
+	tx.LockTime = lockTime
+	tx.AddTxIn(transaction.NewTxIn(prevOut, nil, nil))
+	tx.TxIn[0].Sequence = sequence
+	tx.AddTxOut(transaction.NewTxOut(90000, pkScript))
+	prevOuts := transaction.PrevOutputMap{*prevOut: transaction.NewTxOut(100000, pkScript)}
+	hashes := transaction.NewSigHashCache(tx, prevOuts)
+
+	s := &Satisfier{Signatures: make(map[string][]byte), Preimages: make(map[string][]byte), Sequence: sequence, LockTime: lockTime}
+	if preimage {
+		h := sha256.Sum256(testPreimage)
+		s.Preimages[hex.EncodeToString(h[:])] = testPreimage
+	}
+
+	for _, i := range signers {
+		if n.Context() == Tapscript {
+			h, err := hashes.TaprootSigHash(0, transaction.SigHashDefault, transaction.NewTapscriptSpend(leafHash))
+			assert.NoError(t, err)
+			sig, err := secp256k1.SchnorrSign(keys[i], h, make([]byte, 32))
+			assert.NoError(t, err)
+			s.Signatures[hex.EncodeToString(keys[i].PubKey().SerializeXOnly())] = sig
+			continue
+		}
+
+		h, err := hashes.WitnessV0SigHash(0, leaf, 100000, transaction.SigHashAll)
+		assert.NoError(t, err)
+		sig, err := secp256k1.Sign(keys[i], h)
+		assert.NoError(t, err)
+		s.Signatures[hex.EncodeToString(keys[i].PubKey().SerializeCompressed())] = append(sig.Serialize(), byte(transaction.SigHashAll))
+	}
+
+	sat, err := n.Satisfy(s)
+	if err != nil {
+		return nil, err
+	}
+
+	witness := append(transaction.Witness(sat), leaf)
+	if control != nil {
+		witness = append(witness, control)
+	}
+	tx.TxIn[0].Witness = witness
+
+	assert.NoError(t, script.VerifyTx(tx, prevOuts, script.StandardVerifyFlags))
+
+	return witness, nil
+}
+
+func TestSatisfy(t *testing.T) {
+	h := sha256.Sum256(testPreimage)
+	hash := hex.EncodeToString(h[:])
+
+	tests := []struct {
+		ms       string
+		ctx      Context
+		signers  []int
+		sequence uint32
+		lockTime uint32
+		preimage bool
+		elems    int
+		err      error
+	}{
+		// spent by A and B, or by A alone after 144 blocks
+		{"and_v(v:pk(A),or_d(pk(B),older(144)))", P2WSH, []int{0, 1}, 0, 0, false, 2, nil},
+		{"and_v(v:pk(A),or_d(pk(B),older(144)))", P2WSH, []int{0}, 144, 0, false, 2, nil},
+		{"and_v(v:pk(A),or_d(pk(B),older(144)))", P2WSH, []int{0}, 143, 0, false, 0, ErrUnsatisfiable},
+		{"and_v(v:pk(A),or_d(pk(B),older(144)))", P2WSH, []int{1}, 144, 0, false, 0, ErrUnsatisfiable},
+		// 2 of 3 decaying to D alone at height 100
+		{"or_d(multi(2,A,B,C),and_v(v:pk(D),after(100)))", P2WSH, []int{0, 2}, 0, 0, false, 3, nil},
+		{"or_d(multi(2,A,B,C),and_v(v:pk(D),after(100)))", P2WSH, []int{3}, 0, 100, false, 4, nil},
+		{"or_d(multi(2,A,B,C),and_v(v:pk(D),after(100)))", P2WSH, []int{3}, 0, 99, false, 0, ErrUnsatisfiable},
+		{"or_d(multi(2,A,B,C),and_v(v:pk(D),after(100)))", P2WSH, []int{0, 3}, 0, 0, false, 0, ErrUnsatisfiable},
+		// with all signatures the smaller witness, with one signature, is
+		// chosen
+		{"or_d(multi(2,A,B,C),and_v(v:pk(D),after(100)))", P2WSH, []int{0, 1, 2, 3}, 0, 100, false, 4, nil},
+		{"thresh(2,pk(A),s:pk(B),sln:older(10))", P2WSH, []int{1}, 10, 0, false, 3, nil},
+		{"thresh(2,pk(A),s:pk(B),sln:older(10))", P2WSH, []int{0, 1}, 0, 0, false, 3, nil},
+		{"andor(pk(A),older(10),pk(B))", P2WSH, []int{0}, 10, 0, false, 1, nil},
+		{"andor(pk(A),older(10),pk(B))", P2WSH, []int{1}, 0, 0, false, 2, nil},
+		{"and_v(v:sha256(" + hash + "),pk(A))", P2WSH, []int{0}, 0, 0, true, 2, nil},
+		{"and_v(v:sha256(" + hash + "),pk(A))", P2WSH, []int{0}, 0, 0, false, 0, ErrUnsatisfiable},
+		{"or_i(pkh(A),and_v(v:pk(B),after(500000001)))", P2WSH, []int{0}, 0, 0, false, 3, nil},
+		{"or_i(pkh(A),and_v(v:pk(B),after(500000001)))", P2WSH, []int{1}, 0, 500000001, false, 2, nil},
+		{"multi_a(2,A,B,C)", Tapscript, []int{0, 2}, 0, 0, false, 3, nil},
+		{"and_v(v:pk(A),or_d(pk(B),older(144)))", Tapscript, []int{0}, 144, 0, false, 2, nil},
+		// anyone could satisfy the timelock branch, the signature of A
+		// would not protect it
+		{"or_i(pk(A),older(1))", P2WSH, []int{0}, 1, 0, false, 0, ErrMalleableSatisfaction},
+	}
+
+	for _, test := range tests {
+		_, names, _ := testKeys(test.ctx)
+
+		n, err := Parse(names.Replace(test.ms), test.ctx)
+		if !assert.NoError(t, err, test.ms) {
+			continue
+		}
+
+		witness, err := spend(t, n, test.signers, test.sequence, test.lockTime, test.preimage)
+		assert.Equal(t, test.err, err, test.ms)
+		if err != nil {
+			continue
+		}
+
+		extra := 1
+		if test.ctx == Tapscript {
+			extra = 2
+		}
+		assert.Equal(t, test.elems+extra, len(witness), test.ms)
+
+		size, ok := n.MaxWitnessSize()
+		assert.True(t, ok)
+		if test.ctx == Tapscript {
+			size += 1 + len(witness[len(witness)-1])
+		}
+		assert.LessOrEqual(t, witness.SerializeSize(), size, test.ms)
+	}
+}
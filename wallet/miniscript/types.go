@@ -0,0 +1,226 @@
+package miniscript
+
+import "strings"
+
+// Type is the set of type properties of a miniscript expression, one
+// basic type and any number of modifiers
+type Type uint32
+
+// typeLetters are the names of the properties in bit order
+const typeLetters = "BVKWzondufesmxghijk"
+
+const (
+	// basic types
+	typeB Type = 1 << iota
+	typeV
+	typeK
+	typeW
+	// correctness modifiers
+	typeZ
+	typeO
+	typeN
+	typeD
+	typeU
+	// malleability modifiers
+	typeE
+	typeF
+	typeS
+	typeM
+	// typeX marks expressions whose last opcode has no VERIFY variant
+	typeX
+	// timelock kinds, relative time and height, absolute time and height
+	typeG
+	typeH
+	typeI
+	typeJ
+	// typeNoMix, k, marks expressions without timelock mix
+	typeNoMix
+)
+
+const (
+	basicTypes    = typeB | typeV | typeK | typeW
+	timelockTypes = typeG | typeH | typeI | typeJ
+)
+
+// Is reports whether t has all properties of other
+func (t Type) Is(other Type) bool {
+	return t&other == other
+}
+
+// String returns the letters of the properties
+func (t Type) String() string {
+
+	var sb strings.Builder
+	for i := range typeLetters {
+		if t&(1<<i) != 0 {
+			sb.WriteByte(typeLetters[i])
+		}
+	}
+
+	return sb.String()
+}
+
+// when returns t if cond holds
+func when(cond bool, t Type) Type {
+
+	if cond {
+		return t
+	}
+
+	return 0
+}
+
+// noMix reports whether combining x and y keeps timelocks of different
+// kinds apart
+func noMix(x, y Type) bool {
+	return !(x.Is(typeG) && y.Is(typeH) || x.Is(typeH) && y.Is(typeG) ||
+		x.Is(typeI) && y.Is(typeJ) || x.Is(typeJ) && y.Is(typeI))
+}
+
+// computeType returns the type of a fragment given the types of its
+// subexpressions, zero when the expression is invalid
+func computeType(frag Fragment, ctx Context, k uint32, subs []Type) Type {
+
+	var x, y, z Type
+	switch len(subs) {
+	case 3:
+		z = subs[2]
+		fallthrough
+	case 2:
+		y = subs[1]
+		fallthrough
+	case 1:
+		x = subs[0]
+	}
+
+	var t Type
+	switch frag {
+	case Just0:
+		t = typeB | typeZ | typeU | typeD | typeE | typeM | typeS | typeX | typeNoMix
+	case Just1:
+		t = typeB | typeZ | typeU | typeF | typeM | typeX | typeNoMix
+	case PkK:
+		t = typeK | typeO | typeN | typeU | typeD | typeE | typeM | typeS | typeX | typeNoMix
+	case PkH:
+		t = typeK | typeN | typeU | typeD | typeE | typeM | typeS | typeX | typeNoMix
+	case Older:
+		t = when(k&sequenceLockTimeTypeFlag != 0, typeG) | when(k&sequenceLockTimeTypeFlag == 0, typeH) |
+			typeB | typeZ | typeF | typeM | typeX | typeNoMix
+	case After:
+		t = when(k >= lockTimeThreshold, typeI) | when(k < lockTimeThreshold, typeJ) |
+			typeB | typeZ | typeF | typeM | typeX | typeNoMix
+	case Sha256, Hash256, Ripemd160, Hash160:
+		t = typeB | typeO | typeN | typeU | typeD | typeM | typeNoMix
+
+	case WrapA:
+		t = when(x.Is(typeB), typeW) | x&(timelockTypes|typeNoMix) |
+			x&(typeU|typeD|typeF|typeE|typeM|typeS) | typeX
+	case WrapS:
+		t = when(x.Is(typeB|typeO), typeW) | x&(timelockTypes|typeNoMix) |
+			x&(typeU|typeD|typeF|typeE|typeM|typeS|typeX)
+	case WrapC:
+		t = when(x.Is(typeK), typeB) | x&(timelockTypes|typeNoMix) |
+			x&(typeO|typeN|typeD|typeF|typeE|typeM) | typeU | typeS
+	case WrapD:
+		// MINIMALIF is consensus in tapscript only, making d: a unit there
+		t = when(x.Is(typeV|typeZ), typeB) | when(x.Is(typeZ), typeO) | when(x.Is(typeF), typeE) |
+			x&(timelockTypes|typeNoMix) | x&(typeM|typeS) | when(ctx == Tapscript, typeU) |
+			typeN | typeD | typeX
+	case WrapV:
+		t = when(x.Is(typeB), typeV) | x&(timelockTypes|typeNoMix) |
+			x&(typeZ|typeO|typeN|typeM|typeS) | typeF | typeX
+	case WrapJ:
+		t = when(x.Is(typeB|typeN), typeB) | when(x.Is(typeF), typeE) | x&(timelockTypes|typeNoMix) |
+			x&(typeO|typeU|typeM|typeS) | typeN | typeD | typeX
+	case WrapN:
+		t = x&(timelockTypes|typeNoMix) | x&(typeB|typeZ|typeO|typeN|typeD|typeF|typeE|typeM|typeS) |
+			typeU | typeX
+
+	case AndV:
+		t = when(x.Is(typeV), y&(typeK|typeV|typeB)) | x&typeN | when(x.Is(typeZ), y&typeN) |
+			when((x|y).Is(typeZ), (x|y)&typeO) | x&y&(typeD|typeM|typeZ) | (x|y)&typeS |
+			when(y.Is(typeF) || x.Is(typeS), typeF) | y&(typeU|typeX) | (x|y)&timelockTypes |
+			when((x&y).Is(typeNoMix) && noMix(x, y), typeNoMix)
+	case AndB:
+		t = when(y.Is(typeW), x&typeB) | when((x|y).Is(typeZ), (x|y)&typeO) | x&typeN |
+			when(x.Is(typeZ), y&typeN) | when((x&y).Is(typeS), x&y&typeE) | x&y&(typeD|typeZ|typeM) |
+			when((x&y).Is(typeF) || x.Is(typeS|typeF) || y.Is(typeS|typeF), typeF) | (x|y)&typeS |
+			typeU | typeX | (x|y)&timelockTypes |
+			when((x&y).Is(typeNoMix) && noMix(x, y), typeNoMix)
+	case OrB:
+		t = when(x.Is(typeB|typeD) && y.Is(typeW|typeD), typeB) | when((x|y).Is(typeZ), (x|y)&typeO) |
+			when((x|y).Is(typeS) && (x&y).Is(typeE), x&y&typeM) | x&y&(typeZ|typeS|typeE) |
+			typeD | typeU | typeX | (x|y)&timelockTypes | x&y&typeNoMix
+	case OrD:
+		t = when(x.Is(typeB|typeD|typeU), y&typeB) | when(y.Is(typeZ), x&typeO) |
+			when(x.Is(typeE) && (x|y).Is(typeS), x&y&typeM) | x&y&(typeZ|typeE|typeS) |
+			y&(typeU|typeF|typeD) | typeX | (x|y)&timelockTypes | x&y&typeNoMix
+	case OrC:
+		t = when(x.Is(typeB|typeD|typeU), y&typeV) | when(y.Is(typeZ), x&typeO) |
+			when(x.Is(typeE) && (x|y).Is(typeS), x&y&typeM) | x&y&(typeZ|typeS) |
+			typeF | typeX | (x|y)&timelockTypes | x&y&typeNoMix
+	case OrI:
+		t = x&y&(typeV|typeB|typeK|typeU|typeF|typeS) | when((x&y).Is(typeZ), typeO) |
+			when((x|y).Is(typeF), (x|y)&typeE) | when((x|y).Is(typeS), x&y&typeM) |
+			(x|y)&typeD | typeX | (x|y)&timelockTypes | x&y&typeNoMix
+	case AndOr:
+		t = when(x.Is(typeB|typeD|typeU), y&z&(typeB|typeK|typeV)) | x&y&z&typeZ |
+			when((x|(y&z)).Is(typeZ), (x|(y&z))&typeO) | y&z&typeU |
+			when(x.Is(typeS) || y.Is(typeF), z&(typeF|typeE)) | z&typeD |
+			when(x.Is(typeE) && (x|y|z).Is(typeS), x&y&z&typeM) | z&(x|y)&typeS | typeX |
+			(x|y|z)&timelockTypes |
+			when((x&y&z).Is(typeNoMix) && noMix(x, y), typeNoMix)
+
+	case Multi, MultiA:
+		t = typeB | typeU | typeD | typeE | typeM | typeS | typeNoMix
+
+	case Thresh:
+		t = threshType(k, subs)
+	}
+
+	if bits := t & basicTypes; bits == 0 || bits&(bits-1) != 0 {
+		return 0
+	}
+
+	return t
+}
+
+// threshType returns the type of thresh, the first subexpression must be
+// Bdu and the others Wdu
+func threshType(k uint32, subs []Type) Type {
+
+	allE, allM := true, true
+	args, numS := 0, 0
+	acc := typeNoMix
+	for i, t := range subs {
+		want := typeW | typeD | typeU
+		if i == 0 {
+			want = typeB | typeD | typeU
+		}
+		if !t.Is(want) {
+			return 0
+		}
+
+		allE = allE && t.Is(typeE)
+		allM = allM && t.Is(typeM)
+		if t.Is(typeS) {
+			numS++
+		}
+		switch {
+		case t.Is(typeZ):
+		case t.Is(typeO):
+			args++
+		default:
+			args += 2
+		}
+
+		// satisfying more than one subexpression mixes their timelocks
+		acc = (acc|t)&timelockTypes | when((acc&t).Is(typeNoMix) && (k <= 1 || noMix(acc, t)), typeNoMix)
+	}
+
+	n := len(subs)
+
+	return typeB | typeD | typeU | when(args == 0, typeZ) | when(args == 1, typeO) |
+		when(allE && numS == n, typeE) | when(allE && allM && numS >= n-int(k), typeM) |
+		when(numS >= n-int(k)+1, typeS) | acc
+}
@@ -0,0 +1,193 @@
+package multisig
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/giogam/Gopher-Wallet/wallet/address"
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+)
+
+// ErrInvalidConfig is returned when a wallet configuration file cannot be
+// parsed
+var ErrInvalidConfig = errors.New("multisig: invalid wallet configuration")
+
+// slip132Versions maps the SLIP132 versions of multisig extended public
+// keys, Ypub, Zpub, Upub and Vpub, to the xpub and tpub versions
+var slip132Versions = map[string][]byte{
+	"0295b43f": hdwallet.MainnetPublic,
+	"02aa7ed3": hdwallet.MainnetPublic,
+	"024289ef": hdwallet.TestnetPublic,
+	"02575483": hdwallet.TestnetPublic,
+}
+
+// Config returns the wallet configuration file registering the account on
+// Coldcard and Sparrow
+func (a *Account) Config() string {
+
+	var sb strings.Builder
+
+	sb.WriteString("# Gopher Wallet multisig setup file\n#\n")
+	sb.WriteString("Name: " + a.Name + "\n")
+	sb.WriteString("Policy: " + strconv.Itoa(a.Threshold) + " of " + strconv.Itoa(len(a.Cosigners)) + "\n")
+	sb.WriteString("Format: " + a.Type.String() + "\n")
+
+	for _, c := range a.Cosigners {
+		sb.WriteString("\nDerivation: " + formatPath(c.Path) + "\n")
+		sb.WriteString(strings.ToUpper(hex.EncodeToString(c.Fingerprint)) + ": " + c.Key.String() + "\n")
+	}
+
+	return sb.String()
+}
+
+// ParseConfig parses a Coldcard or Sparrow wallet configuration file, a
+// Derivation line applies to the keys following it. Without Format and
+// Derivation the account is a P2SH one with keys at m/45'.
+func ParseConfig(config string, net *address.Network) (*Account, error) {
+
+	var name string
+	t := P2SH
+	threshold, count := 0, 0
+	path := []uint32{45 + hdwallet.HardenedKeyStart}
+	var cosigners []*Cosigner
+
+	scanner := bufio.NewScanner(strings.NewReader(config))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		colon := strings.IndexByte(line, ':')
+		if colon < 0 {
+			return nil, ErrInvalidConfig
+		}
+		label, value := strings.TrimSpace(line[:colon]), strings.TrimSpace(line[colon+1:])
+
+		var err error
+		switch strings.ToLower(label) {
+		case "name":
+			name = value
+
+		case "policy":
+			if threshold, count, err = parsePolicy(value); err != nil {
+				return nil, err
+			}
+
+		case "derivation":
+			if path, err = hdwallet.ParsePath(value); err != nil {
+				return nil, ErrInvalidConfig
+			}
+
+		case "format":
+			if t, err = parseFormat(value); err != nil {
+				return nil, err
+			}
+
+		default:
+			fingerprint, err := hex.DecodeString(label)
+			if err != nil || len(fingerprint) != 4 {
+				return nil, ErrInvalidConfig
+			}
+			key, err := parseXpub(value)
+			if err != nil {
+				return nil, err
+			}
+			cosigners = append(cosigners, &Cosigner{Fingerprint: fingerprint, Path: path, Key: key})
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if count != len(cosigners) {
+		return nil, ErrInvalidConfig
+	}
+
+	return New(name, t, threshold, cosigners, net)
+}
+
+// parsePolicy parses a policy written as "M of N" or "M/N"
+func parsePolicy(s string) (int, int, error) {
+
+	parts := strings.Split(strings.ToLower(s), "of")
+	if len(parts) != 2 {
+		parts = strings.Split(s, "/")
+	}
+	if len(parts) != 2 {
+		return 0, 0, ErrInvalidConfig
+	}
+
+	m, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, ErrInvalidConfig
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return 0, 0, ErrInvalidConfig
+	}
+
+	return m, n, nil
+}
+
+// parseFormat parses a script type, P2WSH-P2SH is accepted for nested
+// segwit
+func parseFormat(s string) (ScriptType, error) {
+
+	s = strings.ToUpper(s)
+	if s == "P2WSH-P2SH" {
+		return P2SHP2WSH, nil
+	}
+
+	for t, name := range formats {
+		if name == s {
+			return t, nil
+		}
+	}
+
+	return 0, ErrUnsupportedType
+}
+
+// parseXpub parses an extended public key, SLIP132 multisig versions are
+// read as xpub and tpub
+func parseXpub(s string) (*hdwallet.ExtendedKey, error) {
+
+	version, payload, err := hdwallet.B58CheckDecodeStrict(s)
+	if err != nil || len(payload) < 3 {
+		return nil, ErrInvalidCosigner
+	}
+
+	full := append([]byte{byte(version)}, payload[:3]...)
+	if xpub, ok := slip132Versions[hex.EncodeToString(full)]; ok {
+		s, _ = hdwallet.B58CheckEncode(int(xpub[0]), append(append([]byte{}, xpub[1:]...), payload[3:]...))
+	}
+
+	key, err := hdwallet.ParseExtendedKey(s)
+	if err != nil || key.IsPrivate {
+		return nil, ErrInvalidCosigner
+	}
+
+	return key, nil
+}
+
+// formatPath returns path in the m/48'/0'/0'/2' notation
+func formatPath(path []uint32) string {
+
+	var sb strings.Builder
+
+	sb.WriteString("m")
+	for _, step := range path {
+		sb.WriteString("/")
+		if step >= hdwallet.HardenedKeyStart {
+			sb.WriteString(strconv.FormatUint(uint64(step-hdwallet.HardenedKeyStart), 10) + "'")
+		} else {
+			sb.WriteString(strconv.FormatUint(uint64(step), 10))
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,81 @@
+package multisig
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/giogam/Gopher-Wallet/wallet/account"
+	"github.com/giogam/Gopher-Wallet/wallet/address"
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig(t *testing.T) {
+	for _, st := range []ScriptType{P2SH, P2SHP2WSH, P2WSH} {
+		a, err := New("vault", st, 2, testCosigners(t, st, address.TestNet), address.TestNet)
+		assert.NoError(t, err)
+
+		config := a.Config()
+		assert.Contains(t, config, "Policy: 2 of 3\n")
+		assert.Contains(t, config, "Format: "+st.String()+"\n")
+
+		parsed, err := ParseConfig(config, address.TestNet)
+		if !assert.NoError(t, err, st.String()) {
+			continue
+		}
+		assert.Equal(t, a.Name, parsed.Name)
+		assert.Equal(t, a.Type, parsed.Type)
+		assert.Equal(t, a.Threshold, parsed.Threshold)
+		assert.Equal(t, a.Cosigners, parsed.Cosigners)
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	cosigners := testCosigners(t, P2WSH, address.MainNet)
+	a, _ := New("vault", P2WSH, 2, cosigners, address.MainNet)
+
+	// a shared derivation, CRLF line endings and a Zpub key
+	_, payload, _ := hdwallet.B58CheckDecodeStrict(cosigners[2].Key.String())
+	zpub, _ := hdwallet.B58CheckEncode(0x02, append([]byte{0xaa, 0x7e, 0xd3}, payload[3:]...))
+	assert.True(t, strings.HasPrefix(zpub, "Zpub"))
+
+	lines := []string{
+		"# Coldcard Multisig setup file",
+		"Name: vault",
+		"Policy: 2/3",
+		"Derivation: m/48h/0h/0h/2h",
+		"Format: p2wsh",
+		"",
+		strings.ToUpper(hex.EncodeToString(cosigners[0].Fingerprint)) + ": " + cosigners[0].Key.String(),
+		hex.EncodeToString(cosigners[1].Fingerprint) + ": " + cosigners[1].Key.String(),
+		strings.ToUpper(hex.EncodeToString(cosigners[2].Fingerprint)) + ": " + zpub,
+	}
+	parsed, err := ParseConfig(strings.Join(lines, "\r\n"), address.MainNet)
+	assert.NoError(t, err)
+	assert.Equal(t, a.Cosigners, parsed.Cosigners)
+
+	want, _ := a.Address(account.External, 7)
+	addr, _ := parsed.Address(account.External, 7)
+	assert.Equal(t, want.String(), addr.String())
+
+	tests := []struct {
+		config string
+		err    error
+	}{
+		{strings.Join(lines[:8], "\n"), ErrInvalidConfig},
+		{strings.Replace(strings.Join(lines, "\n"), "Format: p2wsh", "Format: p2tr", 1), ErrUnsupportedType},
+		{strings.Replace(strings.Join(lines, "\n"), "Policy: 2/3", "Policy: two of three", 1), ErrInvalidConfig},
+		{strings.Replace(strings.Join(lines, "\n"), "Policy: 2/3", "Policy: 4/3", 1), ErrInvalidThreshold},
+		{strings.Replace(strings.Join(lines, "\n"), "Policy: 2/3", "Policy: 0 of 3", 1), ErrInvalidThreshold},
+		{strings.Replace(strings.Join(lines, "\n"), "Derivation: m/48h/0h/0h/2h", "Derivation: m/48h/0h", 1), ErrInvalidCosigner},
+		{strings.Replace(strings.Join(lines, "\n"), "Derivation: m/48h/0h/0h/2h", "Derivation: 48h/0h/0h/2h", 1), ErrInvalidConfig},
+		{strings.Replace(strings.Join(lines, "\n"), zpub, zpub[:len(zpub)-1]+"1", 1), ErrInvalidCosigner},
+		{strings.Join(lines, "\n") + "\nvault", ErrInvalidConfig},
+	}
+
+	for _, test := range tests {
+		_, err := ParseConfig(test.config, address.MainNet)
+		assert.Equal(t, test.err, err)
+	}
+}
@@ -0,0 +1,323 @@
+package multisig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/giogam/Gopher-Wallet/wallet/account"
+	"github.com/giogam/Gopher-Wallet/wallet/address"
+	"github.com/giogam/Gopher-Wallet/wallet/coinselect"
+	"github.com/giogam/Gopher-Wallet/wallet/descriptor"
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/script"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+// ScriptType is the output type of a multisig account
+type ScriptType int
+
+const (
+	// P2SH pays to a bare multisig redeem script
+	P2SH ScriptType = iota + 1
+	// P2SHP2WSH pays to a multisig witness script nested in P2SH
+	P2SHP2WSH
+	// P2WSH pays to a multisig witness script
+	P2WSH
+)
+
+// formats are the names of the script types in wallet configuration
+// files
+var formats = map[ScriptType]string{
+	P2SH:      "P2SH",
+	P2SHP2WSH: "P2SH-P2WSH",
+	P2WSH:     "P2WSH",
+}
+
+// String returns the name of the script type
+func (t ScriptType) String() string {
+
+	if name, ok := formats[t]; ok {
+		return name
+	}
+
+	return "unknown"
+}
+
+// maxKeys are the largest numbers of cosigners of each script type, a
+// P2SH redeem script is limited to 520 bytes
+var maxKeys = map[ScriptType]int{
+	P2SH:      15,
+	P2SHP2WSH: script.MaxPubKeysPerMultiSig,
+	P2WSH:     script.MaxPubKeysPerMultiSig,
+}
+
+var (
+	// ErrUnsupportedType is returned for unknown script types
+	ErrUnsupportedType = errors.New("multisig: unsupported script type")
+	// ErrInvalidThreshold is returned when the threshold is not between 1
+	// and the number of cosigners, or there are too many cosigners
+	ErrInvalidThreshold = errors.New("multisig: invalid threshold")
+	// ErrDuplicateCosigner is returned when a key is given twice
+	ErrDuplicateCosigner = errors.New("multisig: duplicate cosigner")
+	// ErrInvalidCosigner is returned when a cosigner key is not an
+	// extended public key at the depth of its path
+	ErrInvalidCosigner = errors.New("multisig: invalid cosigner key")
+)
+
+// Path returns the derivation path of the cosigner keys of account index,
+// m/48'/coin'/index'/script' for the BIP48 script types 1' (P2SH-P2WSH)
+// and 2' (P2WSH), and the BIP45 path m/45' for P2SH
+func Path(t ScriptType, index uint32, net *address.Network) ([]uint32, error) {
+
+	coinType := uint32(1)
+	if net == address.MainNet {
+		coinType = 0
+	}
+
+	switch t {
+	case P2SH:
+		return []uint32{45 + hdwallet.HardenedKeyStart}, nil
+	case P2SHP2WSH, P2WSH:
+		return []uint32{
+			48 + hdwallet.HardenedKeyStart,
+			coinType + hdwallet.HardenedKeyStart,
+			index + hdwallet.HardenedKeyStart,
+			uint32(t-P2SH) + hdwallet.HardenedKeyStart,
+		}, nil
+	}
+
+	return nil, ErrUnsupportedType
+}
+
+// Cosigner is the extended public key a signer contributes to an account,
+// Fingerprint and Path locate it from the signer master key
+type Cosigner struct {
+	Fingerprint []byte
+	Path        []uint32
+	Key         *hdwallet.ExtendedKey
+}
+
+// NewCosigner derives the cosigner key of account index of type t from
+// master
+func NewCosigner(master *hdwallet.ExtendedKey, t ScriptType, index uint32, net *address.Network) (*Cosigner, error) {
+
+	path, err := Path(t, index, net)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := master.DerivePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pub, err := key.Neuter()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cosigner{Fingerprint: master.Fingerprint(), Path: path, Key: pub}, nil
+}
+
+// Account is an M of N multisig account paying to sortedmulti scripts,
+// the keys of every address are sorted as in BIP67. Next holds the first
+// unused index of each chain.
+type Account struct {
+	Name      string
+	Type      ScriptType
+	Threshold int
+	Cosigners []*Cosigner
+	Net       *address.Network
+	Next      [2]uint32
+}
+
+// New returns the account of threshold of cosigners paying to scripts of
+// type t
+func New(name string, t ScriptType, threshold int, cosigners []*Cosigner, net *address.Network) (*Account, error) {
+
+	limit, ok := maxKeys[t]
+	if !ok {
+		return nil, ErrUnsupportedType
+	}
+
+	if threshold < 1 || threshold > len(cosigners) || len(cosigners) > limit {
+		return nil, ErrInvalidThreshold
+	}
+
+	for i, c := range cosigners {
+		if c.Key.IsPrivate || int(c.Key.Depth) != len(c.Path) || len(c.Fingerprint) != 4 {
+			return nil, ErrInvalidCosigner
+		}
+		for _, other := range cosigners[:i] {
+			if bytes.Equal(c.Key.Key, other.Key.Key) {
+				return nil, ErrDuplicateCosigner
+			}
+		}
+	}
+
+	return &Account{Name: name, Type: t, Threshold: threshold, Cosigners: cosigners, Net: net}, nil
+}
+
+// PubKeys returns the cosigner keys at chain and index in BIP67 order
+func (a *Account) PubKeys(chain, index uint32) ([][]byte, error) {
+
+	if chain != account.External && chain != account.Internal {
+		return nil, account.ErrInvalidChain
+	}
+
+	pubs := make([][]byte, len(a.Cosigners))
+	for i, c := range a.Cosigners {
+		key, err := c.Key.DerivePath([]uint32{chain, index})
+		if err != nil {
+			return nil, err
+		}
+		pub, err := key.PubKey()
+		if err != nil {
+			return nil, err
+		}
+		pubs[i] = pub.SerializeCompressed()
+	}
+
+	sort.Slice(pubs, func(i, j int) bool {
+		return bytes.Compare(pubs[i], pubs[j]) < 0
+	})
+
+	return pubs, nil
+}
+
+// Script returns the multisig script at chain and index, the redeem
+// script of P2SH accounts and the witness script of the others
+func (a *Account) Script(chain, index uint32) ([]byte, error) {
+
+	pubs, err := a.PubKeys(chain, index)
+	if err != nil {
+		return nil, err
+	}
+
+	return script.MultiSigScript(a.Threshold, pubs)
+}
+
+// Address returns the address at chain and index
+func (a *Account) Address(chain, index uint32) (*address.Address, error) {
+
+	ms, err := a.Script(chain, index)
+	if err != nil {
+		return nil, err
+	}
+
+	switch a.Type {
+	case P2SH:
+		return address.NewP2SHFromScript(ms, a.Net)
+	case P2SHP2WSH:
+		hash := sha256.Sum256(ms)
+		redeem, err := script.PayToWitnessScriptHash(hash[:])
+		if err != nil {
+			return nil, err
+		}
+		return address.NewP2SHFromScript(redeem, a.Net)
+	case P2WSH:
+		return address.NewP2WSHFromScript(ms, a.Net)
+	}
+
+	return nil, ErrUnsupportedType
+}
+
+// InputSize returns the spend size in vbytes of the outputs of the account,
+// signatures are assumed to be of maximum size. Coins are built with
+// coinselect.NewCoinWithSize as their size is not implied by the output.
+func (a *Account) InputSize() int {
+
+	pubs := make([][]byte, len(a.Cosigners))
+	for i := range pubs {
+		pubs[i] = append([]byte{0x02}, make([]byte, 32)...)
+	}
+	ms, _ := script.MultiSigScript(a.Threshold, pubs)
+
+	// DER signature with its sighash byte
+	sig := make([]byte, 73)
+
+	if a.Type == P2SH {
+		b := script.NewBuilder().AddOp(script.Op0)
+		for i := 0; i < a.Threshold; i++ {
+			b.AddData(sig)
+		}
+		scriptSig, _ := b.AddData(ms).Script()
+
+		return coinselect.ScriptSigInputSize(len(scriptSig))
+	}
+
+	// CHECKMULTISIG pops an extra empty element
+	witness := transaction.Witness{nil}
+	for i := 0; i < a.Threshold; i++ {
+		witness = append(witness, sig)
+	}
+	witness = append(witness, ms)
+
+	if a.Type == P2SHP2WSH {
+		return coinselect.NestedWitnessInputSize(34, witness.SerializeSize())
+	}
+
+	return coinselect.WitnessInputSize(witness.SerializeSize())
+}
+
+// PkScript returns the output script at chain and index
+func (a *Account) PkScript(chain, index uint32) ([]byte, error) {
+
+	addr, err := a.Address(chain, index)
+	if err != nil {
+		return nil, err
+	}
+
+	return script.PayToAddress(addr)
+}
+
+// NextAddress returns the first unused address of chain and marks it used
+func (a *Account) NextAddress(chain uint32) (*address.Address, error) {
+
+	if chain != account.External && chain != account.Internal {
+		return nil, account.ErrInvalidChain
+	}
+
+	addr, err := a.Address(chain, a.Next[chain])
+	if err != nil {
+		return nil, err
+	}
+
+	a.Next[chain]++
+
+	return addr, nil
+}
+
+// Descriptor returns the output descriptor of the addresses of chain
+func (a *Account) Descriptor(chain uint32) (*descriptor.Descriptor, error) {
+
+	if chain != account.External && chain != account.Internal {
+		return nil, account.ErrInvalidChain
+	}
+
+	keys := make([]string, len(a.Cosigners))
+	for i, c := range a.Cosigners {
+		keys[i] = (&descriptor.Key{
+			Origin:   &descriptor.KeyOrigin{Fingerprint: c.Fingerprint, Path: c.Path},
+			Extended: c.Key,
+			Path:     []uint32{chain},
+			Wildcard: descriptor.UnhardenedWildcard,
+		}).String()
+	}
+
+	desc := "sortedmulti(" + strconv.Itoa(a.Threshold) + "," + strings.Join(keys, ",") + ")"
+	switch a.Type {
+	case P2SH:
+		desc = "sh(" + desc + ")"
+	case P2SHP2WSH:
+		desc = "sh(wsh(" + desc + "))"
+	case P2WSH:
+		desc = "wsh(" + desc + ")"
+	}
+
+	return descriptor.Parse(desc, a.Net)
+}
@@ -0,0 +1,162 @@
+package multisig
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/giogam/Gopher-Wallet/wallet/account"
+	"github.com/giogam/Gopher-Wallet/wallet/address"
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/script"
+	"github.com/stretchr/testify/assert"
+)
+
+// testCosigners returns the cosigners of account 0 of type t of three
+// masters with different seeds
+func testCosigners(t *testing.T, st ScriptType, net *address.Network) []*Cosigner {
+
+	version := hdwallet.MainnetPrivate
+	if net != address.MainNet {
+		version = hdwallet.TestnetPrivate
+	}
+
+	var cosigners []*Cosigner
+	for i := byte(1); i <= 3; i++ {
+		master, err := hdwallet.NewMasterKey(bytes.Repeat([]byte{i}, 32), version)
+		assert.NoError(t, err)
+		c, err := NewCosigner(master, st, 0, net)
+		assert.NoError(t, err)
+		cosigners = append(cosigners, c)
+	}
+
+	return cosigners
+}
+
+func TestPath(t *testing.T) {
+	h := uint32(hdwallet.HardenedKeyStart)
+
+	path, err := Path(P2WSH, 0, address.MainNet)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{48 + h, h, h, 2 + h}, path)
+
+	path, err = Path(P2SHP2WSH, 3, address.TestNet)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{48 + h, 1 + h, 3 + h, 1 + h}, path)
+
+	path, err = Path(P2SH, 0, address.MainNet)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{45 + h}, path)
+
+	_, err = Path(ScriptType(0), 0, address.MainNet)
+	assert.Equal(t, ErrUnsupportedType, err)
+}
+
+func TestAddress(t *testing.T) {
+	for _, st := range []ScriptType{P2SH, P2SHP2WSH, P2WSH} {
+		cosigners := testCosigners(t, st, address.MainNet)
+		a, err := New("vault", st, 2, cosigners, address.MainNet)
+		if !assert.NoError(t, err) {
+			continue
+		}
+
+		// cosigner order does not change the addresses
+		reversed, err := New("vault", st, 2, []*Cosigner{cosigners[2], cosigners[1], cosigners[0]}, address.MainNet)
+		assert.NoError(t, err)
+
+		for _, chain := range []uint32{account.External, account.Internal} {
+			desc, err := a.Descriptor(chain)
+			assert.NoError(t, err)
+
+			for index := uint32(0); index < 3; index++ {
+				addr, err := a.Address(chain, index)
+				assert.NoError(t, err)
+
+				want, err := desc.Address(index)
+				assert.NoError(t, err)
+				assert.Equal(t, want.String(), addr.String())
+
+				other, _ := reversed.Address(chain, index)
+				assert.Equal(t, addr.String(), other.String())
+			}
+		}
+
+		pubs, err := a.PubKeys(account.External, 0)
+		assert.NoError(t, err)
+		ms, _ := a.Script(account.External, 0)
+		m, keys, err := script.ExtractMultiSig(ms)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, m)
+		assert.Equal(t, pubs, keys)
+		assert.True(t, bytes.Compare(keys[0], keys[1]) < 0 && bytes.Compare(keys[1], keys[2]) < 0)
+	}
+
+	cosigners := testCosigners(t, P2WSH, address.MainNet)
+	a, _ := New("vault", P2WSH, 2, cosigners, address.MainNet)
+	addr, _ := a.Address(account.External, 0)
+	assert.Equal(t, address.P2WSH, addr.Type)
+
+	next, err := a.NextAddress(account.External)
+	assert.NoError(t, err)
+	assert.Equal(t, addr.String(), next.String())
+	assert.Equal(t, uint32(1), a.Next[account.External])
+
+	_, err = a.Address(2, 0)
+	assert.Equal(t, account.ErrInvalidChain, err)
+	_, err = a.NextAddress(2)
+	assert.Equal(t, account.ErrInvalidChain, err)
+}
+
+func TestInputSize(t *testing.T) {
+	for _, test := range []struct {
+		st   ScriptType
+		size int
+	}{
+		{P2SH, 299},
+		{P2SHP2WSH, 140},
+		{P2WSH, 105},
+	} {
+		a, err := New("vault", test.st, 2, testCosigners(t, test.st, address.MainNet), address.MainNet)
+		assert.NoError(t, err)
+		assert.Equal(t, test.size, a.InputSize(), test.st.String())
+	}
+}
+
+func TestNew(t *testing.T) {
+	cosigners := testCosigners(t, P2WSH, address.MainNet)
+
+	master, _ := hdwallet.NewMasterKey(bytes.Repeat([]byte{1}, 32), hdwallet.MainnetPrivate)
+	private, _ := master.Derive("m/48'/0'/0'/2'")
+	shallow, _ := master.Derive("m/48'/0'/0'")
+	shallow, _ = shallow.Neuter()
+
+	tests := []struct {
+		t         ScriptType
+		threshold int
+		cosigners []*Cosigner
+		err       error
+	}{
+		{P2WSH, 3, cosigners, nil},
+		{P2WSH, 0, cosigners, ErrInvalidThreshold},
+		{P2WSH, 4, cosigners, ErrInvalidThreshold},
+		{ScriptType(4), 2, cosigners, ErrUnsupportedType},
+		{P2WSH, 2, []*Cosigner{cosigners[0], cosigners[1], cosigners[0]}, ErrDuplicateCosigner},
+		{P2WSH, 1, []*Cosigner{{Fingerprint: cosigners[0].Fingerprint, Path: cosigners[0].Path, Key: private}}, ErrInvalidCosigner},
+		{P2WSH, 1, []*Cosigner{{Fingerprint: cosigners[0].Fingerprint, Path: cosigners[0].Path, Key: shallow}}, ErrInvalidCosigner},
+	}
+
+	for _, test := range tests {
+		_, err := New("vault", test.t, test.threshold, test.cosigners, address.MainNet)
+		assert.Equal(t, test.err, err)
+	}
+
+	// 16 keys would exceed the size of a P2SH redeem script
+	many := make([]*Cosigner, 16)
+	for i := range many {
+		key, _ := hdwallet.NewMasterKey(bytes.Repeat([]byte{byte(i + 1)}, 32), hdwallet.MainnetPrivate)
+		many[i], _ = NewCosigner(key, P2SH, 0, address.MainNet)
+	}
+	_, err := New("vault", P2SH, 2, many, address.MainNet)
+	assert.Equal(t, ErrInvalidThreshold, err)
+	_, err = New("vault", P2SH, 2, many[:15], address.MainNet)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,235 @@
+package musig2
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+)
+
+var (
+	// ErrInvalidPubKey is returned when a public key is not a compressed
+	// point of the curve
+	ErrInvalidPubKey = errors.New("musig2: invalid public key")
+	// ErrInvalidTweak is returned when a tweak is not less than the group
+	// order
+	ErrInvalidTweak = errors.New("musig2: tweak must be less than n")
+	// ErrInfinity is returned when aggregating or tweaking keys gives the
+	// point at infinity
+	ErrInfinity = errors.New("musig2: result is the point at infinity")
+	// ErrNoKeys is returned when aggregating an empty list of keys
+	ErrNoKeys = errors.New("musig2: no public keys")
+)
+
+// contribution returns err as caused by the input of signer i
+func contribution(i int, err error) error {
+	return fmt.Errorf("musig2: signer %d: %w", i, err)
+}
+
+// SortKeys returns the compressed keys sorted lexicographically as done by
+// KeySort
+func SortKeys(keys [][]byte) [][]byte {
+
+	sorted := append([][]byte{}, keys...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i], sorted[j]) < 0
+	})
+
+	return sorted
+}
+
+// KeyAggContext is the aggregate of the keys of the signers and the
+// tweaks applied to it
+type KeyAggContext struct {
+	keys [][]byte
+	// listHash commits to the keys, second is the first key different
+	// from the first one, whose coefficient is 1
+	listHash []byte
+	second   []byte
+	q        *secp256k1.PublicKey
+	// gacc and tacc accumulate the negations and the tweaks
+	gacc, tacc *big.Int
+}
+
+// AggregateKeys returns the aggregate of the compressed keys in the given
+// order
+func AggregateKeys(keys [][]byte) (*KeyAggContext, error) {
+
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+
+	points := make([]*secp256k1.PublicKey, len(keys))
+	for i, key := range keys {
+		p, err := parsePoint(key)
+		if err != nil {
+			return nil, contribution(i, ErrInvalidPubKey)
+		}
+		points[i] = p
+	}
+
+	c := &KeyAggContext{
+		keys:     keys,
+		listHash: secp256k1.TaggedHash("KeyAgg list", bytes.Join(keys, nil)),
+		second:   make([]byte, secp256k1.PubKeyCompressedLen),
+		gacc:     big.NewInt(1),
+		tacc:     new(big.Int),
+	}
+	for _, key := range keys[1:] {
+		if !bytes.Equal(key, keys[0]) {
+			c.second = key
+			break
+		}
+	}
+
+	c.q = infinity()
+	for i, p := range points {
+		c.q = add(c.q, mul(p, c.coefficient(keys[i])))
+	}
+	if isInfinity(c.q) {
+		return nil, ErrInfinity
+	}
+
+	return c, nil
+}
+
+// coefficient returns the aggregation coefficient of key
+func (c *KeyAggContext) coefficient(key []byte) *big.Int {
+
+	if bytes.Equal(key, c.second) {
+		return big.NewInt(1)
+	}
+
+	a := new(big.Int).SetBytes(secp256k1.TaggedHash("KeyAgg coefficient", c.listHash, key))
+
+	return a.Mod(a, secp256k1.N)
+}
+
+// contains reports whether key is one of the aggregated keys
+func (c *KeyAggContext) contains(key []byte) bool {
+
+	for _, k := range c.keys {
+		if bytes.Equal(k, key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Tweak returns the context with the tweak added to the aggregate key,
+// x-only tweaks are added to the even y lift of the key as done by
+// taproot
+func (c *KeyAggContext) Tweak(tweak []byte, xOnly bool) (*KeyAggContext, error) {
+
+	g := big.NewInt(1)
+	if xOnly && !c.q.HasEvenY() {
+		g.Sub(secp256k1.N, g)
+	}
+
+	t := new(big.Int).SetBytes(tweak)
+	if len(tweak) != 32 || t.Cmp(secp256k1.N) >= 0 {
+		return nil, ErrInvalidTweak
+	}
+
+	q := add(mul(c.q, g), mul(generator(), t))
+	if isInfinity(q) {
+		return nil, ErrInfinity
+	}
+
+	tweaked := *c
+	tweaked.q = q
+	tweaked.gacc = new(big.Int).Mul(g, c.gacc)
+	tweaked.gacc.Mod(tweaked.gacc, secp256k1.N)
+	tweaked.tacc = new(big.Int).Mul(g, c.tacc)
+	tweaked.tacc.Add(tweaked.tacc, t)
+	tweaked.tacc.Mod(tweaked.tacc, secp256k1.N)
+
+	return &tweaked, nil
+}
+
+// TaprootTweak returns the context with the BIP341 tweak committing to
+// merkleRoot applied, the aggregate key being the internal key. A nil
+// merkleRoot gives a key path only output.
+func (c *KeyAggContext) TaprootTweak(merkleRoot []byte) (*KeyAggContext, error) {
+	return c.Tweak(hdwallet.TaprootTweak(c.q, merkleRoot), true)
+}
+
+// PubKey returns the aggregate key
+func (c *KeyAggContext) PubKey() *secp256k1.PublicKey {
+	return c.q
+}
+
+// XOnlyPubKey returns the 32 bytes x-only aggregate key
+func (c *KeyAggContext) XOnlyPubKey() []byte {
+	return c.q.SerializeXOnly()
+}
+
+// parsePoint parses a compressed point
+func parsePoint(b []byte) (*secp256k1.PublicKey, error) {
+
+	if len(b) != secp256k1.PubKeyCompressedLen {
+		return nil, secp256k1.ErrInvalidPublicKey
+	}
+
+	return secp256k1.ParsePubKey(b)
+}
+
+// parsePointExt parses a compressed point, 33 zero bytes being the point
+// at infinity
+func parsePointExt(b []byte) (*secp256k1.PublicKey, error) {
+
+	if bytes.Equal(b, make([]byte, secp256k1.PubKeyCompressedLen)) {
+		return infinity(), nil
+	}
+
+	return parsePoint(b)
+}
+
+// serializePointExt returns the compressed point, 33 zero bytes for the
+// point at infinity
+func serializePointExt(p *secp256k1.PublicKey) []byte {
+
+	if isInfinity(p) {
+		return make([]byte, secp256k1.PubKeyCompressedLen)
+	}
+
+	return p.SerializeCompressed()
+}
+
+func infinity() *secp256k1.PublicKey {
+	return &secp256k1.PublicKey{X: new(big.Int), Y: new(big.Int)}
+}
+
+func isInfinity(p *secp256k1.PublicKey) bool {
+	return p.X.Sign() == 0 && p.Y.Sign() == 0
+}
+
+func generator() *secp256k1.PublicKey {
+	return &secp256k1.PublicKey{X: secp256k1.Gx, Y: secp256k1.Gy}
+}
+
+func add(a, b *secp256k1.PublicKey) *secp256k1.PublicKey {
+
+	x, y := secp256k1.Add(a.X, a.Y, b.X, b.Y)
+
+	return &secp256k1.PublicKey{X: x, Y: y}
+}
+
+func mul(p *secp256k1.PublicKey, k *big.Int) *secp256k1.PublicKey {
+
+	x, y := secp256k1.ScalarMult(p.X, p.Y, secp256k1.PaddedBytes(k, 32))
+
+	return &secp256k1.PublicKey{X: x, Y: y}
+}
+
+func mulSecret(p *secp256k1.PublicKey, k *big.Int) *secp256k1.PublicKey {
+
+	x, y := secp256k1.ScalarMultSecret(p.X, p.Y, secp256k1.PaddedBytes(k, 32))
+
+	return &secp256k1.PublicKey{X: x, Y: y}
+}
@@ -0,0 +1,117 @@
+package musig2
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// vectorError is the error expected by a BIP327 test vector, Signer is
+// nil when no signer is blamed
+type vectorError struct {
+	Type    string `json:"type"`
+	Signer  *int   `json:"signer"`
+	Contrib string `json:"contrib"`
+	Message string `json:"message"`
+}
+
+// loadVectors decodes the BIP327 test vectors of the given file
+func loadVectors(t *testing.T, name string, v interface{}) {
+
+	data, err := os.ReadFile("testdata/" + name)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(data, v))
+}
+
+func decodeHex(s string) []byte {
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return b
+}
+
+func decodeHexes(ss []string, indices []int) [][]byte {
+
+	var bs [][]byte
+	for _, i := range indices {
+		bs = append(bs, decodeHex(ss[i]))
+	}
+
+	return bs
+}
+
+// assertError checks that err is expected and blames the expected signer
+func assertError(t *testing.T, expected vectorError, err error, want error, comment string) {
+
+	assert.True(t, errors.Is(err, want), "%s: %v", comment, err)
+	if expected.Signer != nil {
+		assert.Contains(t, fmt.Sprint(err), fmt.Sprintf("signer %d:", *expected.Signer), comment)
+	}
+}
+
+func TestSortKeys(t *testing.T) {
+	var vectors struct {
+		PubKeys       []string `json:"pubkeys"`
+		SortedPubKeys []string `json:"sorted_pubkeys"`
+	}
+	loadVectors(t, "key_sort_vectors.json", &vectors)
+
+	keys := decodeHexes(vectors.PubKeys, []int{0, 1, 2, 3, 4})
+	assert.Equal(t, decodeHexes(vectors.SortedPubKeys, []int{0, 1, 2, 3, 4}), SortKeys(keys))
+	assert.Equal(t, decodeHex(vectors.PubKeys[0]), keys[0])
+}
+
+func TestAggregateKeys(t *testing.T) {
+	var vectors struct {
+		PubKeys    []string `json:"pubkeys"`
+		Tweaks     []string `json:"tweaks"`
+		ValidCases []struct {
+			KeyIndices []int  `json:"key_indices"`
+			Expected   string `json:"expected"`
+		} `json:"valid_test_cases"`
+		ErrorCases []struct {
+			KeyIndices   []int       `json:"key_indices"`
+			TweakIndices []int       `json:"tweak_indices"`
+			IsXOnly      []bool      `json:"is_xonly"`
+			Error        vectorError `json:"error"`
+			Comment      string      `json:"comment"`
+		} `json:"error_test_cases"`
+	}
+	loadVectors(t, "key_agg_vectors.json", &vectors)
+
+	for _, test := range vectors.ValidCases {
+		c, err := AggregateKeys(decodeHexes(vectors.PubKeys, test.KeyIndices))
+		if !assert.NoError(t, err) {
+			continue
+		}
+		assert.Equal(t, test.Expected, strings.ToUpper(hex.EncodeToString(c.XOnlyPubKey())))
+	}
+
+	for _, test := range vectors.ErrorCases {
+		c, err := AggregateKeys(decodeHexes(vectors.PubKeys, test.KeyIndices))
+		for i := 0; err == nil && i < len(test.TweakIndices); i++ {
+			c, err = c.Tweak(decodeHex(vectors.Tweaks[test.TweakIndices[i]]), test.IsXOnly[i])
+		}
+
+		want := ErrInvalidPubKey
+		switch {
+		case strings.Contains(test.Error.Message, "less than n"):
+			want = ErrInvalidTweak
+		case strings.Contains(test.Error.Message, "infinity"):
+			want = ErrInfinity
+		}
+		assertError(t, test.Error, err, want, test.Comment)
+	}
+
+	_, err := AggregateKeys(nil)
+	assert.Equal(t, ErrNoKeys, err)
+}
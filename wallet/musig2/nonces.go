@@ -0,0 +1,178 @@
+package musig2
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+)
+
+const (
+	// PubNonceLen is the length of public and aggregate nonces
+	PubNonceLen = 66
+)
+
+var (
+	// ErrInvalidPubNonce is returned when a public nonce is not two
+	// compressed points
+	ErrInvalidPubNonce = errors.New("musig2: invalid public nonce")
+	// ErrInvalidAggNonce is returned when an aggregate nonce cannot be
+	// parsed
+	ErrInvalidAggNonce = errors.New("musig2: invalid aggregate nonce")
+	// ErrInvalidSecNonce is returned when a secret nonce is out of range,
+	// which is the case once it has been used
+	ErrInvalidSecNonce = errors.New("musig2: secret nonce out of range, it may have been used")
+	// ErrInvalidSecKey is returned when a secret key is not 32 bytes
+	ErrInvalidSecKey = errors.New("musig2: invalid secret key")
+)
+
+// SecNonce is the secret nonce of a signer for a single signing session,
+// it cannot be serialized and is cleared when signing so that it is never
+// used twice
+type SecNonce struct {
+	k1, k2 *big.Int
+	pubKey []byte
+}
+
+// clear overwrites the nonce, any later use fails
+func (n *SecNonce) clear() {
+	n.k1.SetInt64(0)
+	n.k2.SetInt64(0)
+}
+
+// NonceOptions are the optional inputs of nonce generation, they make the
+// nonce unique even with bad randomness. A nil Msg is not given, an empty
+// one is signed.
+type NonceOptions struct {
+	SecKey    []byte
+	AggPubKey []byte
+	Msg       []byte
+	ExtraIn   []byte
+}
+
+// NonceGen returns a fresh secret nonce and its public nonce for the
+// signer with compressed key pubKey
+func NonceGen(pubKey []byte, opts *NonceOptions) (*SecNonce, []byte, error) {
+
+	rnd := make([]byte, 32)
+	if _, err := rand.Read(rnd); err != nil {
+		return nil, nil, err
+	}
+
+	return nonceGen(rnd, pubKey, opts)
+}
+
+// nonceGen derives the nonces from the randomness rnd
+func nonceGen(rnd []byte, pubKey []byte, opts *NonceOptions) (*SecNonce, []byte, error) {
+
+	if opts == nil {
+		opts = &NonceOptions{}
+	}
+
+	if opts.SecKey != nil {
+		if len(opts.SecKey) != 32 {
+			return nil, nil, ErrInvalidSecKey
+		}
+		aux := secp256k1.TaggedHash("MuSig/aux", rnd)
+		for i := range aux {
+			aux[i] ^= opts.SecKey[i]
+		}
+		rnd = aux
+	}
+
+	msg := []byte{0}
+	if opts.Msg != nil {
+		length := make([]byte, 8)
+		binary.BigEndian.PutUint64(length, uint64(len(opts.Msg)))
+		msg = append(append([]byte{1}, length...), opts.Msg...)
+	}
+
+	extraLen := make([]byte, 4)
+	binary.BigEndian.PutUint32(extraLen, uint32(len(opts.ExtraIn)))
+
+	k := make([]*big.Int, 2)
+	for i := range k {
+		k[i] = new(big.Int).SetBytes(secp256k1.TaggedHash("MuSig/nonce",
+			rnd,
+			[]byte{byte(len(pubKey))}, pubKey,
+			[]byte{byte(len(opts.AggPubKey))}, opts.AggPubKey,
+			msg,
+			extraLen, opts.ExtraIn,
+			[]byte{byte(i)},
+		))
+		k[i].Mod(k[i], secp256k1.N)
+		if k[i].Sign() == 0 {
+			return nil, nil, ErrInvalidSecNonce
+		}
+	}
+
+	secNonce := &SecNonce{k1: k[0], k2: k[1], pubKey: append([]byte{}, pubKey...)}
+
+	return secNonce, secNonce.pubNonce(), nil
+}
+
+// pubNonce returns the public nonce of n
+func (n *SecNonce) pubNonce() []byte {
+
+	r1 := mulSecret(generator(), n.k1).SerializeCompressed()
+	r2 := mulSecret(generator(), n.k2).SerializeCompressed()
+
+	return append(r1, r2...)
+}
+
+// AggregateNonces returns the aggregate nonce of the public nonces of the
+// signers
+func AggregateNonces(pubNonces [][]byte) ([]byte, error) {
+
+	r1, r2 := infinity(), infinity()
+	for i, nonce := range pubNonces {
+		p1, p2, err := parsePubNonce(nonce)
+		if err != nil {
+			return nil, contribution(i, err)
+		}
+		r1, r2 = add(r1, p1), add(r2, p2)
+	}
+
+	return append(serializePointExt(r1), serializePointExt(r2)...), nil
+}
+
+// parsePubNonce returns the two points of a public nonce
+func parsePubNonce(nonce []byte) (*secp256k1.PublicKey, *secp256k1.PublicKey, error) {
+
+	if len(nonce) != PubNonceLen {
+		return nil, nil, ErrInvalidPubNonce
+	}
+
+	r1, err := parsePoint(nonce[:33])
+	if err != nil {
+		return nil, nil, ErrInvalidPubNonce
+	}
+	r2, err := parsePoint(nonce[33:])
+	if err != nil {
+		return nil, nil, ErrInvalidPubNonce
+	}
+
+	return r1, r2, nil
+}
+
+// parseAggNonce returns the two points of an aggregate nonce, either may
+// be the point at infinity
+func parseAggNonce(nonce []byte) (*secp256k1.PublicKey, *secp256k1.PublicKey, error) {
+
+	if len(nonce) != PubNonceLen {
+		return nil, nil, ErrInvalidAggNonce
+	}
+
+	r1, err := parsePointExt(nonce[:33])
+	if err != nil {
+		return nil, nil, ErrInvalidAggNonce
+	}
+	r2, err := parsePointExt(nonce[33:])
+	if err != nil {
+		return nil, nil, ErrInvalidAggNonce
+	}
+
+	return r1, r2, nil
+}
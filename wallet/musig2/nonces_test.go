@@ -0,0 +1,92 @@
+package musig2
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+	"github.com/stretchr/testify/assert"
+)
+
+// optionalHex decodes s, nil when the vector leaves the value out
+func optionalHex(s *string) []byte {
+
+	if s == nil {
+		return nil
+	}
+
+	return decodeHex(*s)
+}
+
+func TestNonceGen(t *testing.T) {
+	var vectors struct {
+		TestCases []struct {
+			Rand      string  `json:"rand_"`
+			SecKey    *string `json:"sk"`
+			PubKey    string  `json:"pk"`
+			AggPubKey *string `json:"aggpk"`
+			Msg       *string `json:"msg"`
+			ExtraIn   *string `json:"extra_in"`
+			Expected  string  `json:"expected"`
+		} `json:"test_cases"`
+	}
+	loadVectors(t, "nonce_gen_vectors.json", &vectors)
+
+	for _, test := range vectors.TestCases {
+		opts := &NonceOptions{
+			SecKey:    optionalHex(test.SecKey),
+			AggPubKey: optionalHex(test.AggPubKey),
+			Msg:       optionalHex(test.Msg),
+			ExtraIn:   optionalHex(test.ExtraIn),
+		}
+
+		secNonce, pubNonce, err := nonceGen(decodeHex(test.Rand), decodeHex(test.PubKey), opts)
+		if !assert.NoError(t, err) {
+			continue
+		}
+
+		serialized := append(secp256k1.PaddedBytes(secNonce.k1, 32), secp256k1.PaddedBytes(secNonce.k2, 32)...)
+		serialized = append(serialized, secNonce.pubKey...)
+		assert.Equal(t, test.Expected, strings.ToUpper(hex.EncodeToString(serialized)))
+		assert.Equal(t, secNonce.pubNonce(), pubNonce)
+	}
+
+	// fresh randomness gives different nonces
+	key := decodeHex(vectors.TestCases[0].PubKey)
+	_, a, err := NonceGen(key, nil)
+	assert.NoError(t, err)
+	_, b, err := NonceGen(key, nil)
+	assert.NoError(t, err)
+	assert.NotEqual(t, a, b)
+
+	_, _, err = NonceGen(key, &NonceOptions{SecKey: []byte{1}})
+	assert.Equal(t, ErrInvalidSecKey, err)
+}
+
+func TestAggregateNonces(t *testing.T) {
+	var vectors struct {
+		PubNonces  []string `json:"pnonces"`
+		ValidCases []struct {
+			Indices  []int  `json:"pnonce_indices"`
+			Expected string `json:"expected"`
+		} `json:"valid_test_cases"`
+		ErrorCases []struct {
+			Indices []int       `json:"pnonce_indices"`
+			Error   vectorError `json:"error"`
+			Comment string      `json:"comment"`
+		} `json:"error_test_cases"`
+	}
+	loadVectors(t, "nonce_agg_vectors.json", &vectors)
+
+	for _, test := range vectors.ValidCases {
+		aggNonce, err := AggregateNonces(decodeHexes(vectors.PubNonces, test.Indices))
+		assert.NoError(t, err)
+		assert.Equal(t, test.Expected, strings.ToUpper(hex.EncodeToString(aggNonce)))
+	}
+
+	for _, test := range vectors.ErrorCases {
+		_, err := AggregateNonces(decodeHexes(vectors.PubNonces, test.Indices))
+		assertError(t, test.Error, err, ErrInvalidPubNonce, test.Comment)
+	}
+}
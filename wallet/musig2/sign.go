@@ -0,0 +1,181 @@
+package musig2
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+)
+
+const (
+	// PartialSigLen is the length of a partial signature
+	PartialSigLen = 32
+)
+
+var (
+	// ErrInvalidPartialSig is returned when a partial signature is out of
+	// range or does not verify
+	ErrInvalidPartialSig = errors.New("musig2: invalid partial signature")
+	// ErrSignerNotIncluded is returned when signing with a key that is not
+	// one of the aggregated keys
+	ErrSignerNotIncluded = errors.New("musig2: signer key is not one of the aggregated keys")
+	// ErrKeyMismatch is returned when a secret nonce was generated for
+	// another key
+	ErrKeyMismatch = errors.New("musig2: secret nonce was generated for another key")
+)
+
+// Session holds the values shared by the signers of a message once the
+// nonces are aggregated
+type Session struct {
+	keys     *KeyAggContext
+	aggNonce []byte
+	msg      []byte
+	// b is the nonce coefficient, r the final nonce and e the challenge
+	b, e *big.Int
+	r    *secp256k1.PublicKey
+}
+
+// NewSession returns the session signing msg with the possibly tweaked
+// aggregate key and the aggregate nonce of the signers
+func NewSession(keys *KeyAggContext, aggNonce, msg []byte) (*Session, error) {
+
+	r1, r2, err := parseAggNonce(aggNonce)
+	if err != nil {
+		return nil, err
+	}
+
+	qx := keys.XOnlyPubKey()
+
+	b := new(big.Int).SetBytes(secp256k1.TaggedHash("MuSig/noncecoef", aggNonce, qx, msg))
+	b.Mod(b, secp256k1.N)
+
+	r := add(r1, mul(r2, b))
+	if isInfinity(r) {
+		r = generator()
+	}
+
+	e := new(big.Int).SetBytes(secp256k1.TaggedHash("BIP0340/challenge", r.SerializeXOnly(), qx, msg))
+	e.Mod(e, secp256k1.N)
+
+	return &Session{keys: keys, aggNonce: aggNonce, msg: msg, b: b, e: e, r: r}, nil
+}
+
+// negation returns g, n - 1 when the aggregate key has an odd y and 1
+// otherwise
+func (s *Session) negation() *big.Int {
+
+	if s.keys.q.HasEvenY() {
+		return big.NewInt(1)
+	}
+
+	return new(big.Int).Sub(secp256k1.N, big.NewInt(1))
+}
+
+// Sign returns the partial signature of the signer with key, secNonce is
+// cleared and cannot be used again
+func (s *Session) Sign(secNonce *SecNonce, key *secp256k1.PrivateKey) ([]byte, error) {
+
+	k1, k2 := new(big.Int).Set(secNonce.k1), new(big.Int).Set(secNonce.k2)
+	secNonce.clear()
+
+	if k1.Sign() == 0 || k1.Cmp(secp256k1.N) >= 0 || k2.Sign() == 0 || k2.Cmp(secp256k1.N) >= 0 {
+		return nil, ErrInvalidSecNonce
+	}
+	pubNonce := (&SecNonce{k1: k1, k2: k2}).pubNonce()
+
+	if !s.r.HasEvenY() {
+		k1.Sub(secp256k1.N, k1)
+		k2.Sub(secp256k1.N, k2)
+	}
+
+	pubKey := key.PubKey().SerializeCompressed()
+	if !bytes.Equal(pubKey, secNonce.pubKey) {
+		return nil, ErrKeyMismatch
+	}
+	if !s.keys.contains(pubKey) {
+		return nil, ErrSignerNotIncluded
+	}
+	a := s.keys.coefficient(pubKey)
+
+	// d = g * gacc * d'
+	d := new(big.Int).Mul(s.negation(), s.keys.gacc)
+	d.Mul(d, key.D)
+	d.Mod(d, secp256k1.N)
+
+	// s = k1 + b * k2 + e * a * d
+	sig := new(big.Int).Mul(s.b, k2)
+	sig.Add(sig, k1)
+	ead := new(big.Int).Mul(s.e, a)
+	ead.Mul(ead, d)
+	sig.Add(sig, ead)
+	sig.Mod(sig, secp256k1.N)
+
+	psig := secp256k1.PaddedBytes(sig, PartialSigLen)
+	if err := s.Verify(psig, pubNonce, pubKey); err != nil {
+		return nil, err
+	}
+
+	return psig, nil
+}
+
+// Verify checks the partial signature of the signer with the public nonce
+// and compressed key given
+func (s *Session) Verify(psig, pubNonce, pubKey []byte) error {
+
+	sig := new(big.Int).SetBytes(psig)
+	if len(psig) != PartialSigLen || sig.Cmp(secp256k1.N) >= 0 {
+		return ErrInvalidPartialSig
+	}
+
+	r1, r2, err := parsePubNonce(pubNonce)
+	if err != nil {
+		return err
+	}
+	re := add(r1, mul(r2, s.b))
+	if !s.r.HasEvenY() {
+		re.X, re.Y = secp256k1.Negate(re.X, re.Y)
+	}
+
+	p, err := parsePoint(pubKey)
+	if err != nil {
+		return ErrInvalidPubKey
+	}
+	if !s.keys.contains(pubKey) {
+		return ErrSignerNotIncluded
+	}
+
+	// s * G = Re + e * a * g * gacc * P
+	g := new(big.Int).Mul(s.negation(), s.keys.gacc)
+	eag := new(big.Int).Mul(s.e, s.keys.coefficient(pubKey))
+	eag.Mul(eag, g)
+	eag.Mod(eag, secp256k1.N)
+
+	if !mul(generator(), sig).IsEqual(add(re, mul(p, eag))) {
+		return ErrInvalidPartialSig
+	}
+
+	return nil
+}
+
+// Aggregate returns the BIP340 signature of the session message by the
+// aggregate key from the partial signatures of all the signers
+func (s *Session) Aggregate(psigs [][]byte) ([]byte, error) {
+
+	sum := new(big.Int)
+	for i, psig := range psigs {
+		sig := new(big.Int).SetBytes(psig)
+		if len(psig) != PartialSigLen || sig.Cmp(secp256k1.N) >= 0 {
+			return nil, contribution(i, ErrInvalidPartialSig)
+		}
+		sum.Add(sum, sig)
+	}
+
+	// s = sum + e * g * tacc
+	et := new(big.Int).Mul(s.e, s.negation())
+	et.Mul(et, s.keys.tacc)
+	sum.Add(sum, et)
+	sum.Mod(sum, secp256k1.N)
+
+	return append(s.r.SerializeXOnly(), secp256k1.PaddedBytes(sum, 32)...), nil
+}
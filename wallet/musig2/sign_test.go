@@ -0,0 +1,355 @@
+package musig2
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/script"
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+// parseSecNonce returns the secret nonce serialized in the test vectors as
+// k1, k2 and the public key
+func parseSecNonce(b []byte) *SecNonce {
+	return &SecNonce{k1: new(big.Int).SetBytes(b[:32]), k2: new(big.Int).SetBytes(b[32:64]), pubKey: b[64:]}
+}
+
+// newSession aggregates keys, applies the tweaks and starts a session
+func newSession(keys [][]byte, tweaks [][]byte, xOnly []bool, aggNonce, msg []byte) (*Session, error) {
+
+	c, err := AggregateKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, tweak := range tweaks {
+		if c, err = c.Tweak(tweak, xOnly[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewSession(c, aggNonce, msg)
+}
+
+type signVectors struct {
+	SecKey     string   `json:"sk"`
+	PubKeys    []string `json:"pubkeys"`
+	SecNonces  []string `json:"secnonces"`
+	PubNonces  []string `json:"pnonces"`
+	AggNonces  []string `json:"aggnonces"`
+	Msgs       []string `json:"msgs"`
+	ValidCases []struct {
+		KeyIndices    []int  `json:"key_indices"`
+		NonceIndices  []int  `json:"nonce_indices"`
+		AggNonceIndex int    `json:"aggnonce_index"`
+		MsgIndex      int    `json:"msg_index"`
+		SignerIndex   int    `json:"signer_index"`
+		Expected      string `json:"expected"`
+	} `json:"valid_test_cases"`
+	SignErrorCases []struct {
+		KeyIndices    []int       `json:"key_indices"`
+		AggNonceIndex int         `json:"aggnonce_index"`
+		MsgIndex      int         `json:"msg_index"`
+		SecNonceIndex int         `json:"secnonce_index"`
+		Error         vectorError `json:"error"`
+		Comment       string      `json:"comment"`
+	} `json:"sign_error_test_cases"`
+	VerifyFailCases []struct {
+		Sig          string `json:"sig"`
+		KeyIndices   []int  `json:"key_indices"`
+		NonceIndices []int  `json:"nonce_indices"`
+		MsgIndex     int    `json:"msg_index"`
+		SignerIndex  int    `json:"signer_index"`
+		Comment      string `json:"comment"`
+	} `json:"verify_fail_test_cases"`
+	VerifyErrorCases []struct {
+		Sig          string      `json:"sig"`
+		KeyIndices   []int       `json:"key_indices"`
+		NonceIndices []int       `json:"nonce_indices"`
+		MsgIndex     int         `json:"msg_index"`
+		SignerIndex  int         `json:"signer_index"`
+		Error        vectorError `json:"error"`
+		Comment      string      `json:"comment"`
+	} `json:"verify_error_test_cases"`
+}
+
+func TestSign(t *testing.T) {
+	var vectors signVectors
+	loadVectors(t, "sign_verify_vectors.json", &vectors)
+
+	key, err := secp256k1.PrivKeyFromBytes(decodeHex(vectors.SecKey))
+	assert.NoError(t, err)
+
+	for _, test := range vectors.ValidCases {
+		keys := decodeHexes(vectors.PubKeys, test.KeyIndices)
+		pubNonces := decodeHexes(vectors.PubNonces, test.NonceIndices)
+		aggNonce := decodeHex(vectors.AggNonces[test.AggNonceIndex])
+
+		agg, err := AggregateNonces(pubNonces)
+		assert.NoError(t, err)
+		assert.Equal(t, aggNonce, agg)
+
+		s, err := newSession(keys, nil, nil, aggNonce, decodeHex(vectors.Msgs[test.MsgIndex]))
+		if !assert.NoError(t, err) {
+			continue
+		}
+
+		secNonce := parseSecNonce(decodeHex(vectors.SecNonces[0]))
+		psig, err := s.Sign(secNonce, key)
+		assert.NoError(t, err)
+		assert.Equal(t, test.Expected, strings.ToUpper(hex.EncodeToString(psig)))
+		assert.NoError(t, s.Verify(psig, pubNonces[test.SignerIndex], keys[test.SignerIndex]))
+
+		// the nonce is cleared once used
+		_, err = s.Sign(secNonce, key)
+		assert.Equal(t, ErrInvalidSecNonce, err)
+	}
+
+	for _, test := range vectors.SignErrorCases {
+		s, err := newSession(decodeHexes(vectors.PubKeys, test.KeyIndices), nil, nil,
+			decodeHex(vectors.AggNonces[test.AggNonceIndex]), decodeHex(vectors.Msgs[test.MsgIndex]))
+		if err == nil {
+			_, err = s.Sign(parseSecNonce(decodeHex(vectors.SecNonces[test.SecNonceIndex])), key)
+		}
+
+		var want error
+		switch {
+		case test.Error.Contrib == "pubkey":
+			want = ErrInvalidPubKey
+		case test.Error.Contrib == "aggnonce":
+			want = ErrInvalidAggNonce
+		case strings.Contains(test.Error.Message, "secnonce"):
+			want = ErrInvalidSecNonce
+		default:
+			want = ErrSignerNotIncluded
+		}
+		assertError(t, test.Error, err, want, test.Comment)
+	}
+
+	// a nonce generated for another key is rejected
+	other, _ := secp256k1.PrivKeyFromBytes(bytes.Repeat([]byte{1}, 32))
+	s, _ := newSession(decodeHexes(vectors.PubKeys, []int{0, 1}), nil, nil, decodeHex(vectors.AggNonces[0]), nil)
+	_, err = s.Sign(parseSecNonce(decodeHex(vectors.SecNonces[0])), other)
+	assert.Equal(t, ErrKeyMismatch, err)
+}
+
+func TestVerify(t *testing.T) {
+	var vectors signVectors
+	loadVectors(t, "sign_verify_vectors.json", &vectors)
+
+	for _, test := range vectors.VerifyFailCases {
+		keys := decodeHexes(vectors.PubKeys, test.KeyIndices)
+		pubNonces := decodeHexes(vectors.PubNonces, test.NonceIndices)
+		aggNonce, err := AggregateNonces(pubNonces)
+		assert.NoError(t, err)
+
+		s, err := newSession(keys, nil, nil, aggNonce, decodeHex(vectors.Msgs[test.MsgIndex]))
+		if !assert.NoError(t, err) {
+			continue
+		}
+		err = s.Verify(decodeHex(test.Sig), pubNonces[test.SignerIndex], keys[test.SignerIndex])
+		assert.Equal(t, ErrInvalidPartialSig, err, test.Comment)
+	}
+
+	for _, test := range vectors.VerifyErrorCases {
+		keys := decodeHexes(vectors.PubKeys, test.KeyIndices)
+		pubNonces := decodeHexes(vectors.PubNonces, test.NonceIndices)
+
+		aggNonce, err := AggregateNonces(pubNonces)
+		if err == nil {
+			_, err = newSession(keys, nil, nil, aggNonce, decodeHex(vectors.Msgs[test.MsgIndex]))
+		}
+
+		want := ErrInvalidPubKey
+		if test.Error.Contrib == "pubnonce" {
+			want = ErrInvalidPubNonce
+		}
+		assertError(t, test.Error, err, want, test.Comment)
+	}
+}
+
+func TestTweak(t *testing.T) {
+	var vectors struct {
+		SecKey     string   `json:"sk"`
+		PubKeys    []string `json:"pubkeys"`
+		SecNonce   string   `json:"secnonce"`
+		PubNonces  []string `json:"pnonces"`
+		AggNonce   string   `json:"aggnonce"`
+		Tweaks     []string `json:"tweaks"`
+		Msg        string   `json:"msg"`
+		ValidCases []struct {
+			KeyIndices   []int  `json:"key_indices"`
+			NonceIndices []int  `json:"nonce_indices"`
+			TweakIndices []int  `json:"tweak_indices"`
+			IsXOnly      []bool `json:"is_xonly"`
+			SignerIndex  int    `json:"signer_index"`
+			Expected     string `json:"expected"`
+			Comment      string `json:"comment"`
+		} `json:"valid_test_cases"`
+		ErrorCases []struct {
+			KeyIndices   []int       `json:"key_indices"`
+			TweakIndices []int       `json:"tweak_indices"`
+			IsXOnly      []bool      `json:"is_xonly"`
+			Error        vectorError `json:"error"`
+			Comment      string      `json:"comment"`
+		} `json:"error_test_cases"`
+	}
+	loadVectors(t, "tweak_vectors.json", &vectors)
+
+	key, err := secp256k1.PrivKeyFromBytes(decodeHex(vectors.SecKey))
+	assert.NoError(t, err)
+
+	for _, test := range vectors.ValidCases {
+		keys := decodeHexes(vectors.PubKeys, test.KeyIndices)
+		pubNonces := decodeHexes(vectors.PubNonces, test.NonceIndices)
+
+		s, err := newSession(keys, decodeHexes(vectors.Tweaks, test.TweakIndices), test.IsXOnly,
+			decodeHex(vectors.AggNonce), decodeHex(vectors.Msg))
+		if !assert.NoError(t, err, test.Comment) {
+			continue
+		}
+
+		psig, err := s.Sign(parseSecNonce(decodeHex(vectors.SecNonce)), key)
+		assert.NoError(t, err, test.Comment)
+		assert.Equal(t, test.Expected, strings.ToUpper(hex.EncodeToString(psig)), test.Comment)
+		assert.NoError(t, s.Verify(psig, pubNonces[test.SignerIndex], keys[test.SignerIndex]), test.Comment)
+	}
+
+	for _, test := range vectors.ErrorCases {
+		_, err := newSession(decodeHexes(vectors.PubKeys, test.KeyIndices), decodeHexes(vectors.Tweaks, test.TweakIndices),
+			test.IsXOnly, decodeHex(vectors.AggNonce), decodeHex(vectors.Msg))
+		assertError(t, test.Error, err, ErrInvalidTweak, test.Comment)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	var vectors struct {
+		PubKeys    []string `json:"pubkeys"`
+		PubNonces  []string `json:"pnonces"`
+		Tweaks     []string `json:"tweaks"`
+		PartialSig []string `json:"psigs"`
+		Msg        string   `json:"msg"`
+		ValidCases []struct {
+			AggNonce     string `json:"aggnonce"`
+			NonceIndices []int  `json:"nonce_indices"`
+			KeyIndices   []int  `json:"key_indices"`
+			TweakIndices []int  `json:"tweak_indices"`
+			IsXOnly      []bool `json:"is_xonly"`
+			PsigIndices  []int  `json:"psig_indices"`
+			Expected     string `json:"expected"`
+		} `json:"valid_test_cases"`
+		ErrorCases []struct {
+			AggNonce     string      `json:"aggnonce"`
+			KeyIndices   []int       `json:"key_indices"`
+			TweakIndices []int       `json:"tweak_indices"`
+			IsXOnly      []bool      `json:"is_xonly"`
+			PsigIndices  []int       `json:"psig_indices"`
+			Error        vectorError `json:"error"`
+			Comment      string      `json:"comment"`
+		} `json:"error_test_cases"`
+	}
+	loadVectors(t, "sig_agg_vectors.json", &vectors)
+
+	msg := decodeHex(vectors.Msg)
+
+	for _, test := range vectors.ValidCases {
+		aggNonce, err := AggregateNonces(decodeHexes(vectors.PubNonces, test.NonceIndices))
+		assert.NoError(t, err)
+		assert.Equal(t, test.AggNonce, strings.ToUpper(hex.EncodeToString(aggNonce)))
+
+		s, err := newSession(decodeHexes(vectors.PubKeys, test.KeyIndices), decodeHexes(vectors.Tweaks, test.TweakIndices),
+			test.IsXOnly, aggNonce, msg)
+		if !assert.NoError(t, err) {
+			continue
+		}
+
+		sig, err := s.Aggregate(decodeHexes(vectors.PartialSig, test.PsigIndices))
+		assert.NoError(t, err)
+		assert.Equal(t, test.Expected, strings.ToUpper(hex.EncodeToString(sig)))
+		assert.True(t, secp256k1.SchnorrVerify(s.keys.XOnlyPubKey(), msg, sig))
+	}
+
+	for _, test := range vectors.ErrorCases {
+		s, err := newSession(decodeHexes(vectors.PubKeys, test.KeyIndices), decodeHexes(vectors.Tweaks, test.TweakIndices),
+			test.IsXOnly, decodeHex(test.AggNonce), msg)
+		if !assert.NoError(t, err) {
+			continue
+		}
+
+		_, err = s.Aggregate(decodeHexes(vectors.PartialSig, test.PsigIndices))
+		assertError(t, test.Error, err, ErrInvalidPartialSig, test.Comment)
+	}
+}
+
+// TestTaprootSpend signs a key path spend of a 3 of 3 output whose keys
+// are derived from BIP32 masters
+func TestTaprootSpend(t *testing.T) {
+	var keys []*secp256k1.PrivateKey
+	var pubKeys [][]byte
+	for i := byte(1); i <= 3; i++ {
+		master, err := hdwallet.NewMasterKey(bytes.Repeat([]byte{i}, 32), hdwallet.MainnetPrivate)
+		assert.NoError(t, err)
+		child, err := master.Derive("m/86'/0'/0'/0/0")
+		assert.NoError(t, err)
+		key, err := child.PrivKey()
+		assert.NoError(t, err)
+		keys = append(keys, key)
+		pubKeys = append(pubKeys, key.PubKey().SerializeCompressed())
+	}
+
+	c, err := AggregateKeys(SortKeys(pubKeys))
+	assert.NoError(t, err)
+	c, err = c.TaprootTweak(nil)
+	assert.NoError(t, err)
+
+	// the output key is the BIP86 tweak of the aggregate key
+	internal, _ := AggregateKeys(SortKeys(pubKeys))
+	outputKey, err := hdwallet.TaprootTweakPubKey(internal.PubKey(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, outputKey.SerializeXOnly(), c.XOnlyPubKey())
+
+	pkScript, err := script.PayToTaproot(c.XOnlyPubKey())
+	assert.NoError(t, err)
+
+	var prevHash transaction.Hash
+	prevOut := transaction.NewOutPoint(&prevHash, 0)
+	tx := transaction.NewTx(2)
+	tx.AddTxIn(transaction.NewTxIn(prevOut, nil, nil))
+	tx.AddTxOut(transaction.NewTxOut(90000, pkScript))
+	prevOuts := transaction.PrevOutputMap{*prevOut: transaction.NewTxOut(100000, pkScript)}
+	msg, err := transaction.NewSigHashCache(tx, prevOuts).TaprootSigHash(0, transaction.SigHashDefault, nil)
+	assert.NoError(t, err)
+
+	// first round
+	secNonces := make([]*SecNonce, len(keys))
+	pubNonces := make([][]byte, len(keys))
+	for i, key := range keys {
+		secNonces[i], pubNonces[i], err = NonceGen(pubKeys[i], &NonceOptions{SecKey: key.Serialize(), AggPubKey: c.XOnlyPubKey(), Msg: msg})
+		assert.NoError(t, err)
+	}
+	aggNonce, err := AggregateNonces(pubNonces)
+	assert.NoError(t, err)
+
+	// second round
+	s, err := NewSession(c, aggNonce, msg)
+	assert.NoError(t, err)
+	psigs := make([][]byte, len(keys))
+	for i, key := range keys {
+		psigs[i], err = s.Sign(secNonces[i], key)
+		assert.NoError(t, err)
+		assert.NoError(t, s.Verify(psigs[i], pubNonces[i], pubKeys[i]))
+	}
+	assert.Equal(t, ErrInvalidPartialSig, s.Verify(psigs[0], pubNonces[1], pubKeys[1]))
+
+	sig, err := s.Aggregate(psigs)
+	assert.NoError(t, err)
+
+	tx.TxIn[0].Witness = transaction.Witness{sig}
+	assert.NoError(t, script.VerifyTx(tx, prevOuts, script.StandardVerifyFlags))
+}
@@ -0,0 +1,189 @@
+package psbt
+
+import (
+	"bytes"
+	"errors"
+)
+
+// ErrTxMismatch is returned when combining packets of different
+// transactions
+var ErrTxMismatch = errors.New("psbt: packets have different unsigned transactions")
+
+// Combine merges packets for the same unsigned transaction, when a field
+// is set in several packets the value of the first one is kept. The
+// TxModifiable flags of version 2 packets only allow what every packet
+// allows.
+func Combine(packets ...*Packet) (*Packet, error) {
+
+	if len(packets) == 0 {
+		return nil, ErrMissingUnsignedTx
+	}
+
+	// the first packet is copied through its serialization so that the
+	// inputs are left untouched
+	result, err := Parse(packets[0].Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	txid := result.UnsignedTx.TxHash()
+
+	for _, p := range packets[1:] {
+		if p.Version != result.Version || p.UnsignedTx.TxHash() != txid {
+			return nil, ErrTxMismatch
+		}
+
+		modifiable := ModifiableInputs | ModifiableOutputs
+		result.TxModifiable = result.TxModifiable&p.TxModifiable&modifiable |
+			(result.TxModifiable|p.TxModifiable)&^modifiable
+
+		for _, xpub := range p.XPubs {
+			if !hasXPub(result.XPubs, xpub) {
+				result.XPubs = append(result.XPubs, xpub)
+			}
+		}
+		result.Unknowns = mergeUnknowns(result.Unknowns, p.Unknowns)
+
+		for i, in := range p.Inputs {
+			result.Inputs[i].merge(in)
+		}
+
+		for i, out := range p.Outputs {
+			result.Outputs[i].merge(out)
+		}
+	}
+
+	return result, nil
+}
+
+func (in *Input) merge(other *Input) {
+
+	if in.NonWitnessUtxo == nil {
+		in.NonWitnessUtxo = other.NonWitnessUtxo
+	}
+	if in.WitnessUtxo == nil {
+		in.WitnessUtxo = other.WitnessUtxo
+	}
+
+	for _, ps := range other.PartialSigs {
+		if in.partialSig(ps.PubKey) == nil {
+			in.PartialSigs = append(in.PartialSigs, ps)
+		}
+	}
+
+	if in.SigHashType == 0 {
+		in.SigHashType = other.SigHashType
+	}
+	if in.RedeemScript == nil {
+		in.RedeemScript = other.RedeemScript
+	}
+	if in.WitnessScript == nil {
+		in.WitnessScript = other.WitnessScript
+	}
+
+	in.Bip32Derivation = mergeDerivations(in.Bip32Derivation, other.Bip32Derivation)
+
+	if len(in.FinalScriptSig) == 0 {
+		in.FinalScriptSig = other.FinalScriptSig
+	}
+	if len(in.FinalScriptWitness) == 0 {
+		in.FinalScriptWitness = other.FinalScriptWitness
+	}
+
+	if in.RequiredTimeLockTime == 0 {
+		in.RequiredTimeLockTime = other.RequiredTimeLockTime
+	}
+	if in.RequiredHeightLockTime == 0 {
+		in.RequiredHeightLockTime = other.RequiredHeightLockTime
+	}
+
+	if len(in.TaprootKeySpendSig) == 0 {
+		in.TaprootKeySpendSig = other.TaprootKeySpendSig
+	}
+	for _, s := range other.TaprootScriptSpendSigs {
+		if in.taprootScriptSig(s.XOnlyPubKey, s.LeafHash) == nil {
+			in.TaprootScriptSpendSigs = append(in.TaprootScriptSpendSigs, s)
+		}
+	}
+next:
+	for _, leaf := range other.TaprootLeafScripts {
+		for _, old := range in.TaprootLeafScripts {
+			if bytes.Equal(old.ControlBlock, leaf.ControlBlock) {
+				continue next
+			}
+		}
+		in.TaprootLeafScripts = append(in.TaprootLeafScripts, leaf)
+	}
+	in.TaprootBip32Derivation = mergeTaprootDerivations(in.TaprootBip32Derivation, other.TaprootBip32Derivation)
+	if len(in.TaprootInternalKey) == 0 {
+		in.TaprootInternalKey = other.TaprootInternalKey
+	}
+	if len(in.TaprootMerkleRoot) == 0 {
+		in.TaprootMerkleRoot = other.TaprootMerkleRoot
+	}
+
+	in.Unknowns = mergeUnknowns(in.Unknowns, other.Unknowns)
+}
+
+func (out *Output) merge(other *Output) {
+
+	if out.RedeemScript == nil {
+		out.RedeemScript = other.RedeemScript
+	}
+	if out.WitnessScript == nil {
+		out.WitnessScript = other.WitnessScript
+	}
+
+	out.Bip32Derivation = mergeDerivations(out.Bip32Derivation, other.Bip32Derivation)
+
+	if len(out.TaprootInternalKey) == 0 {
+		out.TaprootInternalKey = other.TaprootInternalKey
+	}
+	if out.TaprootTree == nil {
+		out.TaprootTree = other.TaprootTree
+	}
+	out.TaprootBip32Derivation = mergeTaprootDerivations(out.TaprootBip32Derivation, other.TaprootBip32Derivation)
+
+	out.Unknowns = mergeUnknowns(out.Unknowns, other.Unknowns)
+}
+
+func mergeDerivations(dst, src []*Bip32Derivation) []*Bip32Derivation {
+
+next:
+	for _, d := range src {
+		for _, old := range dst {
+			if bytes.Equal(old.PubKey, d.PubKey) {
+				continue next
+			}
+		}
+		dst = append(dst, d)
+	}
+
+	return dst
+}
+
+func mergeUnknowns(dst, src []*Unknown) []*Unknown {
+
+next:
+	for _, u := range src {
+		for _, old := range dst {
+			if bytes.Equal(old.Key, u.Key) {
+				continue next
+			}
+		}
+		dst = append(dst, u)
+	}
+
+	return dst
+}
+
+func hasXPub(xpubs []*XPub, xpub *XPub) bool {
+
+	for _, x := range xpubs {
+		if x.ExtendedKey.String() == xpub.ExtendedKey.String() {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,190 @@
+package psbt
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/script"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+var (
+	// ErrNotFinalizable is returned when an input lacks the signatures
+	// needed to satisfy its script
+	ErrNotFinalizable = errors.New("psbt: input cannot be finalized")
+	// ErrIncomplete is returned when extracting a packet with inputs that
+	// are not finalized
+	ErrIncomplete = errors.New("psbt: packet is not complete")
+)
+
+// FinalizeInput builds the final scriptSig and witness of input idx from
+// its partial or taproot signatures. The result is verified against the spent output
+// before the signing fields are cleared.
+func (p *Packet) FinalizeInput(idx int) error {
+
+	if idx < 0 || idx >= len(p.Inputs) {
+		return ErrIndex
+	}
+
+	in := p.Inputs[idx]
+	if in.IsFinalized() {
+		return nil
+	}
+
+	utxo, err := p.utxo(idx)
+	if err != nil {
+		return err
+	}
+
+	var scriptSig []byte
+	var witness transaction.Witness
+
+	if script.IsPayToTaproot(utxo.PkScript) {
+		if witness, err = in.satisfyTaproot(); err != nil {
+			return err
+		}
+		return p.finalize(idx, utxo, nil, witness)
+	}
+
+	scriptCode, segwit, err := p.scriptCode(idx, utxo.PkScript)
+	if err != nil {
+		return err
+	}
+
+	stack, err := in.satisfy(scriptCode)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case segwit && in.WitnessScript != nil:
+		witness = append(stack, in.WitnessScript)
+	case segwit:
+		witness = stack
+	default:
+		if scriptSig, err = pushAll(stack); err != nil {
+			return err
+		}
+	}
+
+	if in.RedeemScript != nil {
+		push, err := pushAll([][]byte{in.RedeemScript})
+		if err != nil {
+			return err
+		}
+		scriptSig = append(scriptSig, push...)
+	}
+
+	return p.finalize(idx, utxo, scriptSig, witness)
+}
+
+// finalize verifies the final scriptSig and witness of input idx against
+// the spent output before setting them and clearing the signing fields
+func (p *Packet) finalize(idx int, utxo *transaction.TxOut, scriptSig []byte, witness transaction.Witness) error {
+
+	hashes := transaction.NewSigHashCache(p.UnsignedTx, p.prevOutputs())
+	checker := script.NewTxChecker(p.UnsignedTx, idx, utxo.Value, hashes)
+	if err := script.VerifyScript(scriptSig, utxo.PkScript, witness, script.StandardVerifyFlags, checker); err != nil {
+		return err
+	}
+
+	in := p.Inputs[idx]
+	in.FinalScriptSig = scriptSig
+	in.FinalScriptWitness = witness
+	in.PartialSigs = nil
+	in.SigHashType = 0
+	in.RedeemScript = nil
+	in.WitnessScript = nil
+	in.Bip32Derivation = nil
+	in.TaprootKeySpendSig = nil
+	in.TaprootScriptSpendSigs = nil
+	in.TaprootLeafScripts = nil
+	in.TaprootBip32Derivation = nil
+	in.TaprootInternalKey = nil
+	in.TaprootMerkleRoot = nil
+
+	return nil
+}
+
+// Finalize finalizes every input, it stops at the first input that cannot
+// be finalized
+func (p *Packet) Finalize() error {
+
+	for i := range p.Inputs {
+		if err := p.FinalizeInput(i); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Extract returns the network transaction of a complete packet
+func (p *Packet) Extract() (*transaction.Tx, error) {
+
+	if !p.IsComplete() {
+		return nil, ErrIncomplete
+	}
+
+	tx := p.UnsignedTx.Copy()
+	for i, in := range p.Inputs {
+		tx.TxIn[i].SignatureScript = in.FinalScriptSig
+		tx.TxIn[i].Witness = in.FinalScriptWitness
+	}
+
+	return tx, nil
+}
+
+// satisfy returns the stack satisfying a pay to pubkey, pay to pubkey hash
+// or multisig script with the partial signatures of the input
+func (in *Input) satisfy(s []byte) ([][]byte, error) {
+
+	switch script.Classify(s) {
+	case script.PubKey:
+		pushes, _ := script.PushedData(s)
+		sig := in.partialSig(pushes[0])
+		if sig == nil {
+			return nil, ErrNotFinalizable
+		}
+		return [][]byte{sig}, nil
+
+	case script.PubKeyHash:
+		hash := s[3:23]
+		for _, ps := range in.PartialSigs {
+			if bytes.Equal(hdwallet.Hash160(ps.PubKey), hash) {
+				return [][]byte{ps.Signature, ps.PubKey}, nil
+			}
+		}
+		return nil, ErrNotFinalizable
+
+	case script.MultiSig:
+		m, pubs, _ := script.ExtractMultiSig(s)
+
+		// the extra element consumed by OP_CHECKMULTISIG comes first and
+		// the signatures follow the order of the keys
+		stack := [][]byte{nil}
+		for _, pub := range pubs {
+			if sig := in.partialSig(pub); sig != nil && len(stack) <= m {
+				stack = append(stack, sig)
+			}
+		}
+		if len(stack) <= m {
+			return nil, ErrNotFinalizable
+		}
+		return stack, nil
+	}
+
+	return nil, ErrUnsupportedScript
+}
+
+// pushAll returns the script pushing every element of stack
+func pushAll(stack [][]byte) ([]byte, error) {
+
+	b := script.NewBuilder()
+	for _, data := range stack {
+		b.AddData(data)
+	}
+
+	return b.Script()
+}
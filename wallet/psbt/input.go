@@ -0,0 +1,585 @@
+package psbt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"sort"
+
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+// input key types
+const (
+	inNonWitnessUtxo     = 0x00
+	inWitnessUtxo        = 0x01
+	inPartialSig         = 0x02
+	inSigHashType        = 0x03
+	inRedeemScript       = 0x04
+	inWitnessScript      = 0x05
+	inBip32Derivation    = 0x06
+	inFinalScriptSig     = 0x07
+	inFinalScriptWitness = 0x08
+
+	inPreviousTxid           = 0x0e
+	inOutputIndex            = 0x0f
+	inSequence               = 0x10
+	inRequiredTimeLockTime   = 0x11
+	inRequiredHeightLockTime = 0x12
+
+	inTaprootKeySig          = 0x13
+	inTaprootScriptSig       = 0x14
+	inTaprootLeafScript      = 0x15
+	inTaprootBip32Derivation = 0x16
+	inTaprootInternalKey     = 0x17
+	inTaprootMerkleRoot      = 0x18
+)
+
+// Input holds the fields of a PSBT input, a zero SigHashType is not
+// serialized. The outpoint and sequence of version 2 inputs live in the
+// unsigned transaction of the packet, a zero required locktime means no
+// requirement.
+type Input struct {
+	NonWitnessUtxo         *transaction.Tx
+	WitnessUtxo            *transaction.TxOut
+	PartialSigs            []*PartialSig
+	SigHashType            transaction.SigHashType
+	RedeemScript           []byte
+	WitnessScript          []byte
+	Bip32Derivation        []*Bip32Derivation
+	FinalScriptSig         []byte
+	FinalScriptWitness     transaction.Witness
+	RequiredTimeLockTime   uint32
+	RequiredHeightLockTime uint32
+	TaprootKeySpendSig     []byte
+	TaprootScriptSpendSigs []*TaprootScriptSpendSig
+	TaprootLeafScripts     []*TaprootLeafScript
+	TaprootBip32Derivation []*TaprootBip32Derivation
+	TaprootInternalKey     []byte
+	TaprootMerkleRoot      []byte
+	Unknowns               []*Unknown
+}
+
+// PartialSig is an ECDSA signature with its sighash byte and the public key
+// it verifies against
+type PartialSig struct {
+	PubKey    []byte
+	Signature []byte
+}
+
+// hasSigs reports whether the input carries any signature
+func (in *Input) hasSigs() bool {
+	return len(in.PartialSigs) != 0 || len(in.TaprootKeySpendSig) != 0 || len(in.TaprootScriptSpendSigs) != 0 || in.IsFinalized()
+}
+
+// IsFinalized reports whether the final scriptSig or witness is set
+func (in *Input) IsFinalized() bool {
+	return len(in.FinalScriptSig) != 0 || len(in.FinalScriptWitness) != 0
+}
+
+// partialSig returns the signature for pubKey
+func (in *Input) partialSig(pubKey []byte) []byte {
+
+	for _, ps := range in.PartialSigs {
+		if bytes.Equal(ps.PubKey, pubKey) {
+			return ps.Signature
+		}
+	}
+
+	return nil
+}
+
+// addPartialSig sets the signature of a public key replacing any previous
+func (in *Input) addPartialSig(pubKey, sig []byte) {
+
+	for _, ps := range in.PartialSigs {
+		if bytes.Equal(ps.PubKey, pubKey) {
+			ps.Signature = sig
+			return
+		}
+	}
+
+	in.PartialSigs = append(in.PartialSigs, &PartialSig{PubKey: pubKey, Signature: sig})
+}
+
+// readInput reads an input map, the outpoint and sequence of a version 2
+// input are stored in txIn which is nil for version 0
+func readInput(r *bytes.Reader, txIn *transaction.TxIn) (*Input, error) {
+
+	in := &Input{}
+	var hasTxid, hasIndex bool
+
+	err := readMap(r, func(keyType uint64, keyData, value []byte) error {
+
+		// the version 2 fields are not allowed in version 0 packets, keys of
+		// the same types with key data are unknown to both versions
+		if txIn == nil && keyType >= inPreviousTxid && keyType <= inRequiredHeightLockTime {
+			if len(keyData) == 0 {
+				return ErrInvalidKey
+			}
+			in.Unknowns = append(in.Unknowns, newUnknown(keyType, keyData, value))
+			return nil
+		}
+
+		switch keyType {
+		case inNonWitnessUtxo:
+			if len(keyData) != 0 {
+				return ErrInvalidKey
+			}
+			tx := &transaction.Tx{}
+			if err := readExact(value, tx.Deserialize); err != nil {
+				return err
+			}
+			in.NonWitnessUtxo = tx
+
+		case inWitnessUtxo:
+			if len(keyData) != 0 {
+				return ErrInvalidKey
+			}
+			out, err := parseTxOut(value)
+			if err != nil {
+				return err
+			}
+			in.WitnessUtxo = out
+
+		case inPartialSig:
+			if !validPubKey(keyData) {
+				return ErrInvalidKey
+			}
+			in.PartialSigs = append(in.PartialSigs, &PartialSig{PubKey: keyData, Signature: value})
+
+		case inSigHashType:
+			if len(keyData) != 0 {
+				return ErrInvalidKey
+			}
+			if len(value) != 4 {
+				return ErrInvalidValue
+			}
+			in.SigHashType = transaction.SigHashType(binary.LittleEndian.Uint32(value))
+
+		case inRedeemScript:
+			if len(keyData) != 0 {
+				return ErrInvalidKey
+			}
+			in.RedeemScript = value
+
+		case inWitnessScript:
+			if len(keyData) != 0 {
+				return ErrInvalidKey
+			}
+			in.WitnessScript = value
+
+		case inBip32Derivation:
+			d, err := parseBip32Derivation(keyData, value)
+			if err != nil {
+				return err
+			}
+			in.Bip32Derivation = append(in.Bip32Derivation, d)
+
+		case inFinalScriptSig:
+			if len(keyData) != 0 {
+				return ErrInvalidKey
+			}
+			in.FinalScriptSig = value
+
+		case inFinalScriptWitness:
+			if len(keyData) != 0 {
+				return ErrInvalidKey
+			}
+			witness, err := parseWitness(value)
+			if err != nil {
+				return err
+			}
+			in.FinalScriptWitness = witness
+
+		case inPreviousTxid:
+			if len(keyData) != 0 {
+				return ErrInvalidKey
+			}
+			if len(value) != transaction.HashSize {
+				return ErrInvalidValue
+			}
+			copy(txIn.PreviousOutPoint.Hash[:], value)
+			hasTxid = true
+
+		case inOutputIndex:
+			index, err := parseUint32(keyData, value)
+			if err != nil {
+				return err
+			}
+			txIn.PreviousOutPoint.Index = index
+			hasIndex = true
+
+		case inSequence:
+			sequence, err := parseUint32(keyData, value)
+			if err != nil {
+				return err
+			}
+			txIn.Sequence = sequence
+
+		case inRequiredTimeLockTime:
+			lockTime, err := parseUint32(keyData, value)
+			if err != nil {
+				return err
+			}
+			if lockTime < lockTimeThreshold {
+				return ErrInvalidValue
+			}
+			in.RequiredTimeLockTime = lockTime
+
+		case inRequiredHeightLockTime:
+			lockTime, err := parseUint32(keyData, value)
+			if err != nil {
+				return err
+			}
+			if lockTime == 0 || lockTime >= lockTimeThreshold {
+				return ErrInvalidValue
+			}
+			in.RequiredHeightLockTime = lockTime
+
+		case inTaprootKeySig:
+			if len(keyData) != 0 {
+				return ErrInvalidKey
+			}
+			if !validSchnorrSig(value) {
+				return ErrInvalidValue
+			}
+			in.TaprootKeySpendSig = value
+
+		case inTaprootScriptSig:
+			if len(keyData) != xOnlyLen+leafHashLen || !validXOnlyPubKey(keyData[:xOnlyLen]) {
+				return ErrInvalidKey
+			}
+			if !validSchnorrSig(value) {
+				return ErrInvalidValue
+			}
+			in.TaprootScriptSpendSigs = append(in.TaprootScriptSpendSigs, &TaprootScriptSpendSig{
+				XOnlyPubKey: keyData[:xOnlyLen],
+				LeafHash:    keyData[xOnlyLen:],
+				Signature:   value,
+			})
+
+		case inTaprootLeafScript:
+			if !validControlBlock(keyData) {
+				return ErrInvalidKey
+			}
+			if len(value) == 0 {
+				return ErrInvalidValue
+			}
+			in.TaprootLeafScripts = append(in.TaprootLeafScripts, &TaprootLeafScript{
+				ControlBlock: keyData,
+				Script:       value[:len(value)-1],
+				LeafVersion:  value[len(value)-1],
+			})
+
+		case inTaprootBip32Derivation:
+			d, err := parseTaprootBip32Derivation(keyData, value)
+			if err != nil {
+				return err
+			}
+			in.TaprootBip32Derivation = append(in.TaprootBip32Derivation, d)
+
+		case inTaprootInternalKey:
+			if len(keyData) != 0 {
+				return ErrInvalidKey
+			}
+			if !validXOnlyPubKey(value) {
+				return ErrInvalidValue
+			}
+			in.TaprootInternalKey = value
+
+		case inTaprootMerkleRoot:
+			if len(keyData) != 0 {
+				return ErrInvalidKey
+			}
+			if len(value) != leafHashLen {
+				return ErrInvalidValue
+			}
+			in.TaprootMerkleRoot = value
+
+		default:
+			in.Unknowns = append(in.Unknowns, newUnknown(keyType, keyData, value))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if txIn != nil && (!hasTxid || !hasIndex) {
+		return nil, ErrMissingField
+	}
+
+	return in, nil
+}
+
+// serialize writes the input map, txIn is the transaction input of a
+// version 2 packet and nil for version 0
+func (in *Input) serialize(w io.Writer, txIn *transaction.TxIn) error {
+
+	if in.NonWitnessUtxo != nil {
+		if err := writeKV(w, inNonWitnessUtxo, nil, in.NonWitnessUtxo.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	if in.WitnessUtxo != nil {
+		if err := writeKV(w, inWitnessUtxo, nil, txOutBytes(in.WitnessUtxo)); err != nil {
+			return err
+		}
+	}
+
+	// the signing fields are dropped once the input is finalized
+	if !in.IsFinalized() {
+		for _, ps := range sortedPartialSigs(in.PartialSigs) {
+			if err := writeKV(w, inPartialSig, ps.PubKey, ps.Signature); err != nil {
+				return err
+			}
+		}
+
+		if in.SigHashType != 0 {
+			if err := writeKV(w, inSigHashType, nil, uint32Bytes(uint32(in.SigHashType))); err != nil {
+				return err
+			}
+		}
+
+		if in.RedeemScript != nil {
+			if err := writeKV(w, inRedeemScript, nil, in.RedeemScript); err != nil {
+				return err
+			}
+		}
+
+		if in.WitnessScript != nil {
+			if err := writeKV(w, inWitnessScript, nil, in.WitnessScript); err != nil {
+				return err
+			}
+		}
+
+		if err := writeBip32Derivations(w, inBip32Derivation, in.Bip32Derivation); err != nil {
+			return err
+		}
+	}
+
+	if len(in.FinalScriptSig) != 0 {
+		if err := writeKV(w, inFinalScriptSig, nil, in.FinalScriptSig); err != nil {
+			return err
+		}
+	}
+
+	if len(in.FinalScriptWitness) != 0 {
+		var buf bytes.Buffer
+		transaction.WriteVarInt(&buf, uint64(len(in.FinalScriptWitness)))
+		for _, item := range in.FinalScriptWitness {
+			transaction.WriteVarBytes(&buf, item)
+		}
+		if err := writeKV(w, inFinalScriptWitness, nil, buf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	if txIn != nil {
+		if err := writeKV(w, inPreviousTxid, nil, txIn.PreviousOutPoint.Hash[:]); err != nil {
+			return err
+		}
+		if err := writeKV(w, inOutputIndex, nil, uint32Bytes(txIn.PreviousOutPoint.Index)); err != nil {
+			return err
+		}
+		if txIn.Sequence != transaction.MaxTxInSequenceNum {
+			if err := writeKV(w, inSequence, nil, uint32Bytes(txIn.Sequence)); err != nil {
+				return err
+			}
+		}
+		if in.RequiredTimeLockTime != 0 {
+			if err := writeKV(w, inRequiredTimeLockTime, nil, uint32Bytes(in.RequiredTimeLockTime)); err != nil {
+				return err
+			}
+		}
+		if in.RequiredHeightLockTime != 0 {
+			if err := writeKV(w, inRequiredHeightLockTime, nil, uint32Bytes(in.RequiredHeightLockTime)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !in.IsFinalized() {
+		if err := in.serializeTaproot(w); err != nil {
+			return err
+		}
+	}
+
+	if err := writeUnknowns(w, in.Unknowns); err != nil {
+		return err
+	}
+
+	return writeSeparator(w)
+}
+
+// serializeTaproot writes the taproot signing fields
+func (in *Input) serializeTaproot(w io.Writer) error {
+
+	if len(in.TaprootKeySpendSig) != 0 {
+		if err := writeKV(w, inTaprootKeySig, nil, in.TaprootKeySpendSig); err != nil {
+			return err
+		}
+	}
+
+	sigs := append([]*TaprootScriptSpendSig{}, in.TaprootScriptSpendSigs...)
+	sort.Slice(sigs, func(i, j int) bool {
+		return bytes.Compare(sigs[i].keyData(), sigs[j].keyData()) < 0
+	})
+	for _, s := range sigs {
+		if err := writeKV(w, inTaprootScriptSig, s.keyData(), s.Signature); err != nil {
+			return err
+		}
+	}
+
+	leaves := append([]*TaprootLeafScript{}, in.TaprootLeafScripts...)
+	sort.Slice(leaves, func(i, j int) bool {
+		return bytes.Compare(leaves[i].ControlBlock, leaves[j].ControlBlock) < 0
+	})
+	for _, leaf := range leaves {
+		value := append(append([]byte{}, leaf.Script...), leaf.LeafVersion)
+		if err := writeKV(w, inTaprootLeafScript, leaf.ControlBlock, value); err != nil {
+			return err
+		}
+	}
+
+	if err := writeTaprootBip32Derivations(w, inTaprootBip32Derivation, in.TaprootBip32Derivation); err != nil {
+		return err
+	}
+
+	if len(in.TaprootInternalKey) != 0 {
+		if err := writeKV(w, inTaprootInternalKey, nil, in.TaprootInternalKey); err != nil {
+			return err
+		}
+	}
+
+	if len(in.TaprootMerkleRoot) != 0 {
+		if err := writeKV(w, inTaprootMerkleRoot, nil, in.TaprootMerkleRoot); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func parseTxOut(value []byte) (*transaction.TxOut, error) {
+
+	if len(value) < 8 {
+		return nil, ErrInvalidValue
+	}
+
+	out := &transaction.TxOut{Value: int64(binary.LittleEndian.Uint64(value))}
+
+	err := readExact(value[8:], func(r io.Reader) error {
+
+		var err error
+		out.PkScript, err = transaction.ReadVarBytes(r, transaction.MaxVarBytes)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func txOutBytes(out *transaction.TxOut) []byte {
+
+	var buf bytes.Buffer
+
+	value := make([]byte, 8)
+	binary.LittleEndian.PutUint64(value, uint64(out.Value))
+	buf.Write(value)
+	transaction.WriteVarBytes(&buf, out.PkScript)
+
+	return buf.Bytes()
+}
+
+func parseWitness(value []byte) (transaction.Witness, error) {
+
+	var witness transaction.Witness
+
+	err := readExact(value, func(r io.Reader) error {
+
+		n, err := transaction.ReadVarInt(r)
+		if err != nil {
+			return err
+		}
+
+		for i := uint64(0); i < n; i++ {
+			item, err := transaction.ReadVarBytes(r, transaction.MaxVarBytes)
+			if err != nil {
+				return err
+			}
+			witness = append(witness, item)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return witness, nil
+}
+
+func parseBip32Derivation(keyData, value []byte) (*Bip32Derivation, error) {
+
+	if !validPubKey(keyData) {
+		return nil, ErrInvalidKey
+	}
+
+	fingerprint, path, err := parseDerivation(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Bip32Derivation{PubKey: keyData, Fingerprint: fingerprint, Path: path}, nil
+}
+
+// writeBip32Derivations writes the derivations sorted by public key
+func writeBip32Derivations(w io.Writer, keyType uint64, derivations []*Bip32Derivation) error {
+
+	sorted := append([]*Bip32Derivation{}, derivations...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].PubKey, sorted[j].PubKey) < 0
+	})
+
+	for _, d := range sorted {
+		if err := writeKV(w, keyType, d.PubKey, derivationValue(d.Fingerprint, d.Path)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sortedPartialSigs orders the signatures by the hash of their public key
+// as done by the reference implementation
+func sortedPartialSigs(sigs []*PartialSig) []*PartialSig {
+
+	sorted := append([]*PartialSig{}, sigs...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(hdwallet.Hash160(sorted[i].PubKey), hdwallet.Hash160(sorted[j].PubKey)) < 0
+	})
+
+	return sorted
+}
+
+// validPubKey reports whether b is a valid compressed or uncompressed
+// public key
+func validPubKey(b []byte) bool {
+
+	if len(b) != secp256k1.PubKeyCompressedLen && len(b) != secp256k1.PubKeyUncompressedLen {
+		return false
+	}
+
+	_, err := secp256k1.ParsePubKey(b)
+
+	return err == nil
+}
@@ -0,0 +1,188 @@
+package psbt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+// output key types
+const (
+	outRedeemScript    = 0x00
+	outWitnessScript   = 0x01
+	outBip32Derivation = 0x02
+
+	outAmount = 0x03
+	outScript = 0x04
+
+	outTaprootInternalKey     = 0x05
+	outTaprootTree            = 0x06
+	outTaprootBip32Derivation = 0x07
+)
+
+// Output holds the fields of a PSBT output, the amount and script of
+// version 2 outputs live in the unsigned transaction of the packet
+type Output struct {
+	RedeemScript           []byte
+	WitnessScript          []byte
+	Bip32Derivation        []*Bip32Derivation
+	TaprootInternalKey     []byte
+	TaprootTree            []*TaprootTreeLeaf
+	TaprootBip32Derivation []*TaprootBip32Derivation
+	Unknowns               []*Unknown
+}
+
+// readOutput reads an output map, the amount and script of a version 2
+// output are stored in txOut which is nil for version 0
+func readOutput(r *bytes.Reader, txOut *transaction.TxOut) (*Output, error) {
+
+	out := &Output{}
+	var hasAmount, hasScript bool
+
+	err := readMap(r, func(keyType uint64, keyData, value []byte) error {
+
+		// the version 2 fields are not allowed in version 0 packets, keys of
+		// the same types with key data are unknown to both versions
+		if txOut == nil && (keyType == outAmount || keyType == outScript) {
+			if len(keyData) == 0 {
+				return ErrInvalidKey
+			}
+			out.Unknowns = append(out.Unknowns, newUnknown(keyType, keyData, value))
+			return nil
+		}
+
+		switch keyType {
+		case outRedeemScript:
+			if len(keyData) != 0 {
+				return ErrInvalidKey
+			}
+			out.RedeemScript = value
+
+		case outWitnessScript:
+			if len(keyData) != 0 {
+				return ErrInvalidKey
+			}
+			out.WitnessScript = value
+
+		case outBip32Derivation:
+			d, err := parseBip32Derivation(keyData, value)
+			if err != nil {
+				return err
+			}
+			out.Bip32Derivation = append(out.Bip32Derivation, d)
+
+		case outAmount:
+			if len(keyData) != 0 {
+				return ErrInvalidKey
+			}
+			if len(value) != 8 {
+				return ErrInvalidValue
+			}
+			txOut.Value = int64(binary.LittleEndian.Uint64(value))
+			hasAmount = true
+
+		case outScript:
+			if len(keyData) != 0 {
+				return ErrInvalidKey
+			}
+			txOut.PkScript = value
+			hasScript = true
+
+		case outTaprootInternalKey:
+			if len(keyData) != 0 {
+				return ErrInvalidKey
+			}
+			if !validXOnlyPubKey(value) {
+				return ErrInvalidValue
+			}
+			out.TaprootInternalKey = value
+
+		case outTaprootTree:
+			if len(keyData) != 0 {
+				return ErrInvalidKey
+			}
+			leaves, err := parseTapTree(value)
+			if err != nil {
+				return err
+			}
+			out.TaprootTree = leaves
+
+		case outTaprootBip32Derivation:
+			d, err := parseTaprootBip32Derivation(keyData, value)
+			if err != nil {
+				return err
+			}
+			out.TaprootBip32Derivation = append(out.TaprootBip32Derivation, d)
+
+		default:
+			out.Unknowns = append(out.Unknowns, newUnknown(keyType, keyData, value))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if txOut != nil && (!hasAmount || !hasScript) {
+		return nil, ErrMissingField
+	}
+
+	return out, nil
+}
+
+// serialize writes the output map, txOut is the transaction output of a
+// version 2 packet and nil for version 0
+func (out *Output) serialize(w io.Writer, txOut *transaction.TxOut) error {
+
+	if out.RedeemScript != nil {
+		if err := writeKV(w, outRedeemScript, nil, out.RedeemScript); err != nil {
+			return err
+		}
+	}
+
+	if out.WitnessScript != nil {
+		if err := writeKV(w, outWitnessScript, nil, out.WitnessScript); err != nil {
+			return err
+		}
+	}
+
+	if err := writeBip32Derivations(w, outBip32Derivation, out.Bip32Derivation); err != nil {
+		return err
+	}
+
+	if txOut != nil {
+		value := make([]byte, 8)
+		binary.LittleEndian.PutUint64(value, uint64(txOut.Value))
+		if err := writeKV(w, outAmount, nil, value); err != nil {
+			return err
+		}
+		if err := writeKV(w, outScript, nil, txOut.PkScript); err != nil {
+			return err
+		}
+	}
+
+	if len(out.TaprootInternalKey) != 0 {
+		if err := writeKV(w, outTaprootInternalKey, nil, out.TaprootInternalKey); err != nil {
+			return err
+		}
+	}
+
+	if len(out.TaprootTree) != 0 {
+		if err := writeKV(w, outTaprootTree, nil, tapTreeValue(out.TaprootTree)); err != nil {
+			return err
+		}
+	}
+
+	if err := writeTaprootBip32Derivations(w, outTaprootBip32Derivation, out.TaprootBip32Derivation); err != nil {
+		return err
+	}
+
+	if err := writeUnknowns(w, out.Unknowns); err != nil {
+		return err
+	}
+
+	return writeSeparator(w)
+}
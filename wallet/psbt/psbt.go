@@ -0,0 +1,607 @@
+package psbt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+// global key types
+const (
+	globalUnsignedTx       = 0x00
+	globalXPub             = 0x01
+	globalTxVersion        = 0x02
+	globalFallbackLockTime = 0x03
+	globalInputCount       = 0x04
+	globalOutputCount      = 0x05
+	globalTxModifiable     = 0x06
+	globalVersion          = 0xfb
+)
+
+const xpubLen = 78
+
+// magic prefixes every serialized PSBT
+var magic = []byte{0x70, 0x73, 0x62, 0x74, 0xff}
+
+var (
+	// ErrInvalidMagic is returned when the data does not start with the
+	// PSBT magic bytes
+	ErrInvalidMagic = errors.New("psbt: invalid magic bytes")
+	// ErrInvalidKey is returned when a key is malformed for its type
+	ErrInvalidKey = errors.New("psbt: invalid key")
+	// ErrInvalidValue is returned when a value is malformed for its type
+	ErrInvalidValue = errors.New("psbt: invalid value")
+	// ErrDuplicateKey is returned when a key appears twice in a map
+	ErrDuplicateKey = errors.New("psbt: duplicate key")
+	// ErrMissingUnsignedTx is returned when the global unsigned transaction
+	// is missing
+	ErrMissingUnsignedTx = errors.New("psbt: missing unsigned transaction")
+	// ErrSignedTx is returned when the unsigned transaction has scriptSigs
+	// or witnesses
+	ErrSignedTx = errors.New("psbt: unsigned transaction has signature data")
+	// ErrUnsupportedVersion is returned for PSBT versions not supported
+	ErrUnsupportedVersion = errors.New("psbt: unsupported version")
+	// ErrTrailingBytes is returned when data follows the last output map
+	ErrTrailingBytes = errors.New("psbt: trailing bytes after packet")
+	// ErrIndex is returned when an input or output index is out of range
+	ErrIndex = errors.New("psbt: index out of range")
+	// ErrMissingField is returned when a field required by the version of
+	// the packet is missing
+	ErrMissingField = errors.New("psbt: missing required field")
+)
+
+// Packet is a partially signed bitcoin transaction. UnsignedTx is kept for
+// both versions, for version 2 it is assembled from the input and output
+// fields and its locktime is computed from the input requirements.
+type Packet struct {
+	UnsignedTx       *transaction.Tx
+	XPubs            []*XPub
+	Version          uint32
+	FallbackLockTime uint32
+	TxModifiable     byte
+	Unknowns         []*Unknown
+	Inputs           []*Input
+	Outputs          []*Output
+}
+
+// XPub is a global extended public key with the origin of its derivation
+type XPub struct {
+	ExtendedKey *hdwallet.ExtendedKey
+	Fingerprint []byte
+	Path        []uint32
+}
+
+// Bip32Derivation maps a public key to its derivation from a master key
+type Bip32Derivation struct {
+	PubKey      []byte
+	Fingerprint []byte
+	Path        []uint32
+}
+
+// Unknown is a key value pair of an unknown or proprietary type, it is
+// kept so it can be passed along unchanged
+type Unknown struct {
+	Key   []byte
+	Value []byte
+}
+
+// New creates a version 0 packet spending inputs to outputs, the sequence
+// of the inputs is set to the maximum
+func New(inputs []*transaction.OutPoint, outputs []*transaction.TxOut, version int32, lockTime uint32) (*Packet, error) {
+
+	tx := transaction.NewTx(version)
+	tx.LockTime = lockTime
+
+	for _, op := range inputs {
+		tx.AddTxIn(transaction.NewTxIn(op, nil, nil))
+	}
+	for _, out := range outputs {
+		tx.AddTxOut(transaction.NewTxOut(out.Value, out.PkScript))
+	}
+
+	return NewFromUnsignedTx(tx)
+}
+
+// NewFromUnsignedTx creates a version 0 packet with empty maps for a
+// transaction without scriptSigs and witnesses
+func NewFromUnsignedTx(tx *transaction.Tx) (*Packet, error) {
+
+	for _, in := range tx.TxIn {
+		if len(in.SignatureScript) != 0 || len(in.Witness) != 0 {
+			return nil, ErrSignedTx
+		}
+	}
+
+	p := &Packet{
+		UnsignedTx: tx.Copy(),
+		Inputs:     make([]*Input, len(tx.TxIn)),
+		Outputs:    make([]*Output, len(tx.TxOut)),
+	}
+	for i := range p.Inputs {
+		p.Inputs[i] = &Input{}
+	}
+	for i := range p.Outputs {
+		p.Outputs[i] = &Output{}
+	}
+
+	return p, nil
+}
+
+// Parse decodes a binary PSBT
+func Parse(data []byte) (*Packet, error) {
+
+	r := bytes.NewReader(data)
+
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil || !bytes.Equal(prefix[:], magic) {
+		return nil, ErrInvalidMagic
+	}
+
+	p := &Packet{}
+
+	// the version 2 fields are checked once the version is known
+	var txVersion uint32
+	var inputCount, outputCount uint64
+	var hasTxVersion, hasInputCount, hasOutputCount, hasV2Fields bool
+
+	err := readMap(r, func(keyType uint64, keyData, value []byte) error {
+
+		switch keyType {
+		case globalUnsignedTx:
+			if len(keyData) != 0 {
+				return ErrInvalidKey
+			}
+			tx := &transaction.Tx{}
+			if err := readExact(value, tx.DeserializeNoWitness); err != nil {
+				return err
+			}
+			for _, in := range tx.TxIn {
+				if len(in.SignatureScript) != 0 {
+					return ErrSignedTx
+				}
+			}
+			p.UnsignedTx = tx
+
+		case globalXPub:
+			xpub, err := parseXPub(keyData, value)
+			if err != nil {
+				return err
+			}
+			p.XPubs = append(p.XPubs, xpub)
+
+		case globalTxVersion:
+			version, err := parseUint32(keyData, value)
+			if err != nil {
+				return err
+			}
+			txVersion, hasTxVersion, hasV2Fields = version, true, true
+
+		case globalFallbackLockTime:
+			lockTime, err := parseUint32(keyData, value)
+			if err != nil {
+				return err
+			}
+			p.FallbackLockTime, hasV2Fields = lockTime, true
+
+		case globalInputCount:
+			n, err := parseVarInt(keyData, value)
+			if err != nil {
+				return err
+			}
+			inputCount, hasInputCount, hasV2Fields = n, true, true
+
+		case globalOutputCount:
+			n, err := parseVarInt(keyData, value)
+			if err != nil {
+				return err
+			}
+			outputCount, hasOutputCount, hasV2Fields = n, true, true
+
+		case globalTxModifiable:
+			if len(keyData) != 0 {
+				return ErrInvalidKey
+			}
+			if len(value) != 1 {
+				return ErrInvalidValue
+			}
+			p.TxModifiable, hasV2Fields = value[0], true
+
+		case globalVersion:
+			version, err := parseUint32(keyData, value)
+			if err != nil {
+				return err
+			}
+			if version != 0 && version != 2 {
+				return ErrUnsupportedVersion
+			}
+			p.Version = version
+
+		default:
+			p.Unknowns = append(p.Unknowns, newUnknown(keyType, keyData, value))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Version == 0 {
+		if hasV2Fields {
+			return nil, ErrInvalidKey
+		}
+		if p.UnsignedTx == nil {
+			return nil, ErrMissingUnsignedTx
+		}
+		inputCount, outputCount = uint64(len(p.UnsignedTx.TxIn)), uint64(len(p.UnsignedTx.TxOut))
+	} else {
+		if p.UnsignedTx != nil {
+			return nil, ErrInvalidKey
+		}
+		if !hasTxVersion || !hasInputCount || !hasOutputCount {
+			return nil, ErrMissingField
+		}
+		// every map takes at least its separator
+		if inputCount > uint64(r.Len()) || outputCount > uint64(r.Len())-inputCount {
+			return nil, ErrInvalidValue
+		}
+		p.UnsignedTx = transaction.NewTx(int32(txVersion))
+	}
+
+	p.Inputs = make([]*Input, inputCount)
+	for i := range p.Inputs {
+		var txIn *transaction.TxIn
+		if p.Version == 2 {
+			txIn = transaction.NewTxIn(&transaction.OutPoint{}, nil, nil)
+			p.UnsignedTx.AddTxIn(txIn)
+		}
+		if p.Inputs[i], err = readInput(r, txIn); err != nil {
+			return nil, err
+		}
+	}
+
+	p.Outputs = make([]*Output, outputCount)
+	for i := range p.Outputs {
+		var txOut *transaction.TxOut
+		if p.Version == 2 {
+			txOut = &transaction.TxOut{}
+			p.UnsignedTx.AddTxOut(txOut)
+		}
+		if p.Outputs[i], err = readOutput(r, txOut); err != nil {
+			return nil, err
+		}
+	}
+
+	if r.Len() != 0 {
+		return nil, ErrTrailingBytes
+	}
+
+	if p.Version == 2 {
+		if p.UnsignedTx.LockTime, err = p.lockTime(p.Inputs); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// ParseBase64 decodes a base64 encoded PSBT
+func ParseBase64(s string) (*Packet, error) {
+
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(data)
+}
+
+// Serialize writes the binary encoding of the packet
+func (p *Packet) Serialize(w io.Writer) error {
+
+	if _, err := w.Write(magic); err != nil {
+		return err
+	}
+
+	if p.Version == 0 {
+		var tx bytes.Buffer
+		if err := p.UnsignedTx.SerializeNoWitness(&tx); err != nil {
+			return err
+		}
+		if err := writeKV(w, globalUnsignedTx, nil, tx.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	for _, xpub := range sortedXPubs(p.XPubs) {
+		if err := writeKV(w, globalXPub, xpubBytes(xpub.ExtendedKey), derivationValue(xpub.Fingerprint, xpub.Path)); err != nil {
+			return err
+		}
+	}
+
+	if p.Version == 2 {
+		if err := p.serializeV2Globals(w); err != nil {
+			return err
+		}
+	}
+
+	if p.Version != 0 {
+		if err := writeKV(w, globalVersion, nil, uint32Bytes(p.Version)); err != nil {
+			return err
+		}
+	}
+
+	if err := writeUnknowns(w, p.Unknowns); err != nil {
+		return err
+	}
+
+	if err := writeSeparator(w); err != nil {
+		return err
+	}
+
+	for i, in := range p.Inputs {
+		var txIn *transaction.TxIn
+		if p.Version == 2 {
+			txIn = p.UnsignedTx.TxIn[i]
+		}
+		if err := in.serialize(w, txIn); err != nil {
+			return err
+		}
+	}
+
+	for i, out := range p.Outputs {
+		var txOut *transaction.TxOut
+		if p.Version == 2 {
+			txOut = p.UnsignedTx.TxOut[i]
+		}
+		if err := out.serialize(w, txOut); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Bytes returns the binary encoding of the packet
+func (p *Packet) Bytes() []byte {
+
+	var buf bytes.Buffer
+	p.Serialize(&buf)
+
+	return buf.Bytes()
+}
+
+// Base64 returns the base64 encoding of the packet
+func (p *Packet) Base64() string {
+	return base64.StdEncoding.EncodeToString(p.Bytes())
+}
+
+// IsComplete reports whether every input has been finalized
+func (p *Packet) IsComplete() bool {
+
+	for _, in := range p.Inputs {
+		if !in.IsFinalized() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// readMap reads key value pairs up to the map separator, duplicate keys
+// are rejected before being passed to handle
+func readMap(r *bytes.Reader, handle func(keyType uint64, keyData, value []byte) error) error {
+
+	seen := make(map[string]bool)
+
+	for {
+		key, err := transaction.ReadVarBytes(r, transaction.MaxVarBytes)
+		if err != nil {
+			return err
+		}
+		if len(key) == 0 {
+			return nil
+		}
+
+		value, err := transaction.ReadVarBytes(r, transaction.MaxVarBytes)
+		if err != nil {
+			return err
+		}
+
+		if seen[string(key)] {
+			return ErrDuplicateKey
+		}
+		seen[string(key)] = true
+
+		kr := bytes.NewReader(key)
+		keyType, err := transaction.ReadVarInt(kr)
+		if err != nil {
+			return ErrInvalidKey
+		}
+
+		if err := handle(keyType, key[len(key)-kr.Len():], value); err != nil {
+			return err
+		}
+	}
+}
+
+// readExact decodes value with deserialize, which must consume it all
+func readExact(value []byte, deserialize func(io.Reader) error) error {
+
+	r := bytes.NewReader(value)
+	if err := deserialize(r); err != nil {
+		return ErrInvalidValue
+	}
+
+	if r.Len() != 0 {
+		return ErrInvalidValue
+	}
+
+	return nil
+}
+
+// writeKV writes a key value pair, the key being the type followed by
+// keyData
+func writeKV(w io.Writer, keyType uint64, keyData, value []byte) error {
+
+	var key bytes.Buffer
+	transaction.WriteVarInt(&key, keyType)
+	key.Write(keyData)
+
+	if err := transaction.WriteVarBytes(w, key.Bytes()); err != nil {
+		return err
+	}
+
+	return transaction.WriteVarBytes(w, value)
+}
+
+// parseUint32 decodes a little endian 32 bits value of a key without data
+func parseUint32(keyData, value []byte) (uint32, error) {
+
+	if len(keyData) != 0 {
+		return 0, ErrInvalidKey
+	}
+
+	if len(value) != 4 {
+		return 0, ErrInvalidValue
+	}
+
+	return binary.LittleEndian.Uint32(value), nil
+}
+
+// parseVarInt decodes a compact size value of a key without data
+func parseVarInt(keyData, value []byte) (uint64, error) {
+
+	if len(keyData) != 0 {
+		return 0, ErrInvalidKey
+	}
+
+	var n uint64
+	err := readExact(value, func(r io.Reader) error {
+
+		var err error
+		n, err = transaction.ReadVarInt(r)
+
+		return err
+	})
+
+	return n, err
+}
+
+func uint32Bytes(n uint32) []byte {
+
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, n)
+
+	return b
+}
+
+// writeSeparator terminates a map
+func writeSeparator(w io.Writer) error {
+
+	_, err := w.Write([]byte{0x00})
+
+	return err
+}
+
+func newUnknown(keyType uint64, keyData, value []byte) *Unknown {
+
+	var key bytes.Buffer
+	transaction.WriteVarInt(&key, keyType)
+	key.Write(keyData)
+
+	return &Unknown{Key: key.Bytes(), Value: value}
+}
+
+// writeUnknowns writes the unknown pairs sorted by key
+func writeUnknowns(w io.Writer, unknowns []*Unknown) error {
+
+	sorted := append([]*Unknown{}, unknowns...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].Key, sorted[j].Key) < 0
+	})
+
+	for _, u := range sorted {
+		if err := transaction.WriteVarBytes(w, u.Key); err != nil {
+			return err
+		}
+		if err := transaction.WriteVarBytes(w, u.Value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseDerivation decodes a master key fingerprint followed by a path of
+// little endian indexes
+func parseDerivation(value []byte) ([]byte, []uint32, error) {
+
+	if len(value) < 4 || len(value)%4 != 0 {
+		return nil, nil, ErrInvalidValue
+	}
+
+	path := make([]uint32, 0, len(value)/4-1)
+	for i := 4; i < len(value); i += 4 {
+		path = append(path, binary.LittleEndian.Uint32(value[i:]))
+	}
+
+	return value[:4], path, nil
+}
+
+func derivationValue(fingerprint []byte, path []uint32) []byte {
+
+	value := make([]byte, 4+4*len(path))
+	copy(value, fingerprint)
+
+	for i, index := range path {
+		binary.LittleEndian.PutUint32(value[4+4*i:], index)
+	}
+
+	return value
+}
+
+// parseXPub decodes a global extended public key, keyData is the 78 bytes
+// BIP32 serialization without checksum
+func parseXPub(keyData, value []byte) (*XPub, error) {
+
+	if len(keyData) != xpubLen {
+		return nil, ErrInvalidKey
+	}
+
+	encoded, _ := hdwallet.B58CheckEncode(int(keyData[0]), keyData[1:])
+	key, err := hdwallet.ParseExtendedKey(encoded)
+	if err != nil || key.IsPrivate {
+		return nil, ErrInvalidKey
+	}
+
+	fingerprint, path, err := parseDerivation(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &XPub{ExtendedKey: key, Fingerprint: fingerprint, Path: path}, nil
+}
+
+func xpubBytes(key *hdwallet.ExtendedKey) []byte {
+
+	version, payload, _ := hdwallet.B58CheckDecodeStrict(key.String())
+
+	return append([]byte{byte(version)}, payload...)
+}
+
+func sortedXPubs(xpubs []*XPub) []*XPub {
+
+	sorted := append([]*XPub{}, xpubs...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(xpubBytes(sorted[i].ExtendedKey), xpubBytes(sorted[j].ExtendedKey)) < 0
+	})
+
+	return sorted
+}
@@ -0,0 +1,298 @@
+package psbt
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+type bip174test struct {
+	Valid   []string `json:"valid"`
+	Invalid []struct {
+		Comment string `json:"comment"`
+		Psbt    string `json:"psbt"`
+	} `json:"invalid"`
+	Creator struct {
+		Inputs  [][]interface{} `json:"inputs"`
+		Outputs [][]interface{} `json:"outputs"`
+		Psbt    string          `json:"psbt"`
+	} `json:"creator"`
+	Updater struct {
+		Master            string     `json:"master"`
+		InputDerivations  [][]string `json:"inputDerivations"`
+		OutputDerivations []string   `json:"outputDerivations"`
+		NonWitnessUtxo    string     `json:"nonWitnessUtxo"`
+		WitnessUtxo       string     `json:"witnessUtxo"`
+		Utxos             string     `json:"utxos"`
+		RedeemScripts     []string   `json:"redeemScripts"`
+		WitnessScript     string     `json:"witnessScript"`
+		Scripts           string     `json:"scripts"`
+		Derivations       string     `json:"derivations"`
+		SigHash           string     `json:"sighash"`
+		SigHashBase64     string     `json:"sighashBase64"`
+	} `json:"updater"`
+	Signer []struct {
+		Keys   []string `json:"keys"`
+		Psbt   string   `json:"psbt"`
+		Result string   `json:"result"`
+	} `json:"signer"`
+	Finalizer struct {
+		Psbt    string `json:"psbt"`
+		Result  string `json:"result"`
+		Network string `json:"network"`
+	} `json:"finalizer"`
+}
+
+func bip174TestVector(t *testing.T) *bip174test {
+
+	data, err := os.ReadFile("testdata/bip174.json")
+	assert.NoError(t, err)
+
+	var test bip174test
+	assert.NoError(t, json.Unmarshal(data, &test))
+
+	return &test
+}
+
+// parsePacket decodes a hex or base64 encoded PSBT
+func parsePacket(t *testing.T, s string) *Packet {
+
+	if strings.HasPrefix(s, "cHNidP8") {
+		p, err := ParseBase64(s)
+		assert.NoError(t, err)
+		return p
+	}
+
+	data, _ := hex.DecodeString(s)
+	p, err := Parse(data)
+	assert.NoError(t, err)
+
+	return p
+}
+
+func decodeHex(s string) []byte {
+
+	b, _ := hex.DecodeString(s)
+
+	return b
+}
+
+func TestParseValid(t *testing.T) {
+	for _, test := range bip174TestVector(t).Valid {
+		p := parsePacket(t, test)
+		assert.Equal(t, test, hex.EncodeToString(p.Bytes()))
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, test := range bip174TestVector(t).Invalid {
+		_, err := Parse(decodeHex(test.Psbt))
+		assert.Error(t, err, test.Comment)
+	}
+}
+
+func TestCreatorUpdater(t *testing.T) {
+	test := bip174TestVector(t)
+
+	var inputs []*transaction.OutPoint
+	for _, in := range test.Creator.Inputs {
+		hash, _ := transaction.NewHashFromStr(in[0].(string))
+		inputs = append(inputs, transaction.NewOutPoint(&hash, uint32(in[1].(float64))))
+	}
+
+	var outputs []*transaction.TxOut
+	for _, out := range test.Creator.Outputs {
+		outputs = append(outputs, transaction.NewTxOut(int64(out[0].(float64)), decodeHex(out[1].(string))))
+	}
+
+	p, err := New(inputs, outputs, 2, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, test.Creator.Psbt, hex.EncodeToString(p.Bytes()))
+
+	u := test.Updater
+
+	prevTx, err := transaction.NewTxFromBytes(decodeHex(u.NonWitnessUtxo))
+	assert.NoError(t, err)
+	assert.NoError(t, p.AddInNonWitnessUtxo(0, prevTx))
+	assert.Equal(t, ErrUtxoMismatch, p.AddInNonWitnessUtxo(1, prevTx))
+
+	witnessUtxo, err := parseTxOut(decodeHex(u.WitnessUtxo))
+	assert.NoError(t, err)
+	assert.NoError(t, p.AddInWitnessUtxo(1, witnessUtxo))
+	assert.Equal(t, u.Utxos, hex.EncodeToString(p.Bytes()))
+
+	for i, redeemScript := range u.RedeemScripts {
+		assert.NoError(t, p.AddInRedeemScript(i, decodeHex(redeemScript)))
+	}
+	assert.NoError(t, p.AddInWitnessScript(1, decodeHex(u.WitnessScript)))
+	assert.Equal(t, u.Scripts, hex.EncodeToString(p.Bytes()))
+
+	master, err := hdwallet.ParseExtendedKey(u.Master)
+	assert.NoError(t, err)
+
+	for i, paths := range u.InputDerivations {
+		for _, path := range paths {
+			indexes, _ := hdwallet.ParsePath(path)
+			d, err := NewBip32Derivation(master, indexes)
+			assert.NoError(t, err)
+			assert.NoError(t, p.AddInBip32Derivation(i, d))
+		}
+	}
+	for i, path := range u.OutputDerivations {
+		indexes, _ := hdwallet.ParsePath(path)
+		d, err := NewBip32Derivation(master, indexes)
+		assert.NoError(t, err)
+		assert.NoError(t, p.AddOutBip32Derivation(i, d))
+	}
+	assert.Equal(t, u.Derivations, hex.EncodeToString(p.Bytes()))
+
+	assert.Equal(t, ErrInvalidKey, p.AddOutBip32Derivation(0, &Bip32Derivation{PubKey: []byte{0x02, 0x01}}))
+	assert.Equal(t, ErrIndex, p.AddInSigHashType(2, transaction.SigHashAll))
+
+	assert.NoError(t, p.AddInSigHashType(0, transaction.SigHashAll))
+	assert.NoError(t, p.AddInSigHashType(1, transaction.SigHashAll))
+	assert.Equal(t, u.SigHash, hex.EncodeToString(p.Bytes()))
+	assert.Equal(t, u.SigHashBase64, p.Base64())
+}
+
+func TestSigner(t *testing.T) {
+	for _, test := range bip174TestVector(t).Signer {
+		p := parsePacket(t, test.Psbt)
+
+		for _, wif := range test.Keys {
+			raw, _, _, err := hdwallet.DecodeWIF(wif)
+			assert.NoError(t, err)
+			key, _ := secp256k1.PrivKeyFromBytes(raw)
+
+			signed := 0
+			for i := range p.Inputs {
+				switch err := p.SignInput(i, key); err {
+				case nil:
+					signed++
+				case ErrKeyNotFound:
+				default:
+					t.Fatal(err)
+				}
+			}
+			assert.Equal(t, 1, signed)
+		}
+
+		assert.Equal(t, test.Result, hex.EncodeToString(p.Bytes()))
+	}
+}
+
+func TestSignSigHashNotAllowed(t *testing.T) {
+	test := bip174TestVector(t).Signer[0]
+
+	raw, _, _, err := hdwallet.DecodeWIF(test.Keys[0])
+	assert.NoError(t, err)
+	key, _ := secp256k1.PrivKeyFromBytes(raw)
+
+	p := parsePacket(t, test.Psbt)
+	for i := range p.Inputs {
+		assert.NoError(t, p.AddInSigHashType(i, transaction.SigHashSingle))
+	}
+	unsigned := p.Bytes()
+
+	for i := range p.Inputs {
+		assert.Equal(t, ErrSigHashNotAllowed, p.SignInput(i, key))
+		assert.Equal(t, ErrSigHashNotAllowed, p.SignInputAllowing(i, key, transaction.SigHashNone))
+	}
+	assert.Equal(t, unsigned, p.Bytes())
+
+	signed := 0
+	for i, in := range p.Inputs {
+		if err := p.SignInputAllowing(i, key, transaction.SigHashSingle); err == ErrKeyNotFound {
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Len(t, in.PartialSigs, 1)
+
+		sig := in.PartialSigs[0].Signature
+		assert.Equal(t, byte(transaction.SigHashSingle), sig[len(sig)-1])
+		signed++
+	}
+	assert.Equal(t, 1, signed)
+}
+
+func TestSignUtxoChecks(t *testing.T) {
+	test := bip174TestVector(t).Signer[0]
+
+	raw, _, _, err := hdwallet.DecodeWIF(test.Keys[0])
+	assert.NoError(t, err)
+	key, _ := secp256k1.PrivKeyFromBytes(raw)
+
+	// a previous transaction not matching the outpoint is refused even
+	// next to a witness utxo
+	p := parsePacket(t, test.Psbt)
+	p.Inputs[1].NonWitnessUtxo = p.Inputs[0].NonWitnessUtxo
+	assert.Equal(t, ErrUtxoMismatch, p.SignInput(1, key))
+
+	// a non segwit input cannot be signed from a witness utxo alone
+	p = parsePacket(t, test.Psbt)
+	op := p.UnsignedTx.TxIn[0].PreviousOutPoint
+	p.Inputs[0].WitnessUtxo = p.Inputs[0].NonWitnessUtxo.TxOut[op.Index]
+	p.Inputs[0].NonWitnessUtxo = nil
+	assert.Equal(t, ErrMissingUtxo, p.SignInput(0, key))
+}
+
+func TestSignMaster(t *testing.T) {
+	test := bip174TestVector(t)
+
+	master, _ := hdwallet.ParseExtendedKey(test.Updater.Master)
+
+	p := parsePacket(t, test.Updater.SigHash)
+	assert.NoError(t, p.Sign(master))
+	assert.Equal(t, test.Finalizer.Psbt, hex.EncodeToString(p.Bytes()))
+
+	// keys of another master are skipped
+	other, _ := hdwallet.NewMasterKey(make([]byte, 32), hdwallet.TestnetPrivate)
+	p = parsePacket(t, test.Updater.SigHash)
+	assert.NoError(t, p.Sign(other))
+	assert.Equal(t, test.Updater.SigHash, hex.EncodeToString(p.Bytes()))
+}
+
+func TestCombine(t *testing.T) {
+	test := bip174TestVector(t)
+
+	p1 := parsePacket(t, test.Signer[0].Result)
+	p2 := parsePacket(t, test.Signer[1].Result)
+
+	combined, err := Combine(p1, p2)
+	assert.NoError(t, err)
+	assert.Equal(t, test.Finalizer.Psbt, hex.EncodeToString(combined.Bytes()))
+
+	// the inputs are not modified
+	assert.Equal(t, test.Signer[0].Result, hex.EncodeToString(p1.Bytes()))
+
+	other := parsePacket(t, test.Creator.Psbt)
+	other.UnsignedTx.LockTime++
+	_, err = Combine(p1, other)
+	assert.Equal(t, ErrTxMismatch, err)
+}
+
+func TestFinalizeExtract(t *testing.T) {
+	test := bip174TestVector(t)
+
+	p := parsePacket(t, test.Signer[0].Result)
+	assert.Equal(t, ErrNotFinalizable, p.Finalize())
+	_, err := p.Extract()
+	assert.Equal(t, ErrIncomplete, err)
+
+	p = parsePacket(t, test.Finalizer.Psbt)
+	assert.False(t, p.IsComplete())
+	assert.NoError(t, p.Finalize())
+	assert.True(t, p.IsComplete())
+	assert.Equal(t, test.Finalizer.Result, hex.EncodeToString(p.Bytes()))
+
+	tx, err := p.Extract()
+	assert.NoError(t, err)
+	assert.Equal(t, test.Finalizer.Network, hex.EncodeToString(tx.Bytes()))
+}
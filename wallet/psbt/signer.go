@@ -0,0 +1,251 @@
+package psbt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/script"
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+var (
+	// ErrScriptMismatch is returned when the redeem or witness script does
+	// not hash to the spent output
+	ErrScriptMismatch = errors.New("psbt: script does not match the spent output")
+	// ErrUnsupportedScript is returned for outputs that cannot be signed
+	ErrUnsupportedScript = errors.New("psbt: unsupported script")
+	// ErrKeyNotFound is returned when the signing key does not appear in
+	// the script spent by the input
+	ErrKeyNotFound = errors.New("psbt: key not found in script")
+	// ErrSigHashNotAllowed is returned when an input requests a sighash type
+	// the signer was not allowed to use
+	ErrSigHashNotAllowed = errors.New("psbt: sighash type not allowed")
+)
+
+// SignInput adds the signature of key to input idx, the key must appear
+// in the script spent by the input. Taproot inputs are signed for the key
+// path when key is the internal key and for every known leaf containing
+// it. Finalized inputs are left untouched. Inputs requesting a sighash type
+// other than SIGHASH_ALL or SIGHASH_DEFAULT are refused, and non segwit
+// inputs require their previous transaction.
+func (p *Packet) SignInput(idx int, key *secp256k1.PrivateKey) error {
+	return p.SignInputAllowing(idx, key)
+}
+
+// SignInputAllowing signs like SignInput and also accepts the listed
+// sighash types when requested by the input
+func (p *Packet) SignInputAllowing(idx int, key *secp256k1.PrivateKey, allowed ...transaction.SigHashType) error {
+
+	if idx < 0 || idx >= len(p.Inputs) {
+		return ErrIndex
+	}
+
+	in := p.Inputs[idx]
+	if in.IsFinalized() {
+		return nil
+	}
+
+	if !sigHashAllowed(in.SigHashType, allowed) {
+		return ErrSigHashNotAllowed
+	}
+
+	utxo, err := p.utxo(idx)
+	if err != nil {
+		return err
+	}
+
+	if script.IsPayToTaproot(utxo.PkScript) {
+		if err := p.signTaproot(idx, utxo, key, in.SigHashType); err != nil {
+			return err
+		}
+		p.updateModifiable(in.SigHashType)
+		return nil
+	}
+
+	scriptCode, segwit, err := p.scriptCode(idx, utxo.PkScript)
+	if err != nil {
+		return err
+	}
+
+	// the value of a non segwit output is not committed to by the
+	// signature, it is only known from the previous transaction
+	if !segwit && in.NonWitnessUtxo == nil {
+		return ErrMissingUtxo
+	}
+
+	pub := scriptPubKey(scriptCode, key.PubKey())
+	if pub == nil {
+		return ErrKeyNotFound
+	}
+
+	hashType := in.SigHashType
+	if hashType == 0 {
+		hashType = transaction.SigHashAll
+	}
+
+	var hash []byte
+	if segwit {
+		hashes := transaction.NewSigHashCache(p.UnsignedTx, p.prevOutputs())
+		hash, err = hashes.WitnessV0SigHash(idx, scriptCode, utxo.Value, hashType)
+	} else {
+		hash, err = transaction.LegacySigHash(p.UnsignedTx, idx, scriptCode, hashType)
+	}
+	if err != nil {
+		return err
+	}
+
+	sig, err := secp256k1.Sign(key, hash)
+	if err != nil {
+		return err
+	}
+
+	in.addPartialSig(pub, append(sig.Serialize(), byte(hashType)))
+	p.updateModifiable(hashType)
+
+	return nil
+}
+
+// Sign signs every input having a BIP32 or taproot BIP32 derivation from
+// master, the keys are derived from the recorded paths
+func (p *Packet) Sign(master *hdwallet.ExtendedKey) error {
+	return p.SignAllowing(master)
+}
+
+// SignAllowing signs like Sign and also accepts the listed sighash types
+func (p *Packet) SignAllowing(master *hdwallet.ExtendedKey, allowed ...transaction.SigHashType) error {
+
+	fingerprint := master.Fingerprint()
+
+	for i, in := range p.Inputs {
+		for _, d := range in.Bip32Derivation {
+			if !bytes.Equal(d.Fingerprint, fingerprint) {
+				continue
+			}
+
+			key, err := derivePrivKey(master, d.Path)
+			if err != nil {
+				return err
+			}
+
+			pub := key.PubKey()
+			if !bytes.Equal(pub.SerializeCompressed(), d.PubKey) && !bytes.Equal(pub.SerializeUncompressed(), d.PubKey) {
+				continue
+			}
+
+			if err := p.SignInputAllowing(i, key, allowed...); err != nil && err != ErrKeyNotFound {
+				return err
+			}
+		}
+
+		for _, d := range in.TaprootBip32Derivation {
+			if !bytes.Equal(d.Fingerprint, fingerprint) {
+				continue
+			}
+
+			key, err := derivePrivKey(master, d.Path)
+			if err != nil {
+				return err
+			}
+
+			if !bytes.Equal(key.PubKey().SerializeXOnly(), d.XOnlyPubKey) {
+				continue
+			}
+
+			if err := p.SignInputAllowing(i, key, allowed...); err != nil && err != ErrKeyNotFound {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// sigHashAllowed reports whether a signer may use hashType, SIGHASH_ALL and
+// SIGHASH_DEFAULT are always allowed
+func sigHashAllowed(hashType transaction.SigHashType, allowed []transaction.SigHashType) bool {
+
+	if hashType == transaction.SigHashDefault || hashType == transaction.SigHashAll {
+		return true
+	}
+
+	for _, t := range allowed {
+		if t == hashType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func derivePrivKey(master *hdwallet.ExtendedKey, path []uint32) (*secp256k1.PrivateKey, error) {
+
+	child, err := master.DerivePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return child.PrivKey()
+}
+
+// scriptCode resolves the script signed by input idx spending pkScript
+// and whether it is a segwit v0 spend
+func (p *Packet) scriptCode(idx int, pkScript []byte) ([]byte, bool, error) {
+
+	in := p.Inputs[idx]
+
+	if script.IsPayToScriptHash(pkScript) {
+		if in.RedeemScript == nil {
+			return nil, false, ErrScriptMismatch
+		}
+		if h := hdwallet.Hash160(in.RedeemScript); !bytes.Equal(h, pkScript[2:22]) {
+			return nil, false, ErrScriptMismatch
+		}
+		pkScript = in.RedeemScript
+	}
+
+	version, program, ok := script.ExtractWitnessProgram(pkScript)
+	if !ok {
+		return pkScript, false, nil
+	}
+
+	switch {
+	case version == 0 && len(program) == 20:
+		scriptCode, _ := script.PayToPubKeyHash(program)
+		return scriptCode, true, nil
+
+	case version == 0 && len(program) == 32:
+		if in.WitnessScript == nil {
+			return nil, false, ErrScriptMismatch
+		}
+		if h := sha256.Sum256(in.WitnessScript); !bytes.Equal(h[:], program) {
+			return nil, false, ErrScriptMismatch
+		}
+		return in.WitnessScript, true, nil
+	}
+
+	return nil, false, ErrUnsupportedScript
+}
+
+// scriptPubKey returns the serialization of pub pushed by s, either as a
+// key or as its hash
+func scriptPubKey(s []byte, pub *secp256k1.PublicKey) []byte {
+
+	pushes, err := script.PushedData(s)
+	if err != nil {
+		return nil
+	}
+
+	for _, candidate := range [][]byte{pub.SerializeCompressed(), pub.SerializeUncompressed()} {
+		hash := hdwallet.Hash160(candidate)
+		for _, data := range pushes {
+			if bytes.Equal(data, candidate) || bytes.Equal(data, hash) {
+				return candidate
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,516 @@
+package psbt
+
+import (
+	"bytes"
+	"io"
+	"sort"
+
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/script"
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+const (
+	schnorrSigLen = 64
+	xOnlyLen      = 32
+	leafHashLen   = 32
+
+	controlBaseLen  = 33
+	controlNodeLen  = 32
+	maxControlNodes = 128
+	maxTreeDepth    = 128
+)
+
+// TaprootScriptSpendSig is a signature of a key for a script path spend
+// of the leaf with the given hash
+type TaprootScriptSpendSig struct {
+	XOnlyPubKey []byte
+	LeafHash    []byte
+	Signature   []byte
+}
+
+// keyData returns the x-only key followed by the leaf hash
+func (s *TaprootScriptSpendSig) keyData() []byte {
+	return append(append([]byte{}, s.XOnlyPubKey...), s.LeafHash...)
+}
+
+// TaprootLeafScript is a leaf script with the control block proving its
+// inclusion in the output key
+type TaprootLeafScript struct {
+	ControlBlock []byte
+	Script       []byte
+	LeafVersion  byte
+}
+
+// TaprootBip32Derivation maps an x-only key to its derivation and to the
+// hashes of the leaves it appears in, no leaf hash means the internal key
+type TaprootBip32Derivation struct {
+	XOnlyPubKey []byte
+	LeafHashes  [][]byte
+	Fingerprint []byte
+	Path        []uint32
+}
+
+// TaprootTreeLeaf is a leaf of an output script tree listed in depth first
+// order
+type TaprootTreeLeaf struct {
+	Depth       byte
+	LeafVersion byte
+	Script      []byte
+}
+
+// NewTaprootBip32Derivation derives path from master and returns the
+// derivation of the resulting x-only key used in leafHashes
+func NewTaprootBip32Derivation(master *hdwallet.ExtendedKey, path []uint32, leafHashes [][]byte) (*TaprootBip32Derivation, error) {
+
+	d, err := NewBip32Derivation(master, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TaprootBip32Derivation{
+		XOnlyPubKey: d.PubKey[1:],
+		LeafHashes:  leafHashes,
+		Fingerprint: d.Fingerprint,
+		Path:        d.Path,
+	}, nil
+}
+
+// AddInTaprootBip32Derivation records the derivation of an x-only key of
+// input idx
+func (p *Packet) AddInTaprootBip32Derivation(idx int, d *TaprootBip32Derivation) error {
+
+	if idx < 0 || idx >= len(p.Inputs) {
+		return ErrIndex
+	}
+
+	if !validXOnlyPubKey(d.XOnlyPubKey) {
+		return ErrInvalidKey
+	}
+
+	p.Inputs[idx].TaprootBip32Derivation = addTaprootDerivation(p.Inputs[idx].TaprootBip32Derivation, d)
+
+	return nil
+}
+
+// AddOutTaprootBip32Derivation records the derivation of an x-only key of
+// output idx
+func (p *Packet) AddOutTaprootBip32Derivation(idx int, d *TaprootBip32Derivation) error {
+
+	if idx < 0 || idx >= len(p.Outputs) {
+		return ErrIndex
+	}
+
+	if !validXOnlyPubKey(d.XOnlyPubKey) {
+		return ErrInvalidKey
+	}
+
+	p.Outputs[idx].TaprootBip32Derivation = addTaprootDerivation(p.Outputs[idx].TaprootBip32Derivation, d)
+
+	return nil
+}
+
+// signTaproot signs the key path when key is the internal key and every
+// leaf script containing its x-only key
+func (p *Packet) signTaproot(idx int, utxo *transaction.TxOut, key *secp256k1.PrivateKey, hashType transaction.SigHashType) error {
+
+	in := p.Inputs[idx]
+	hashes := transaction.NewSigHashCache(p.UnsignedTx, p.prevOutputs())
+	xonly := key.PubKey().SerializeXOnly()
+
+	sign := func(k *secp256k1.PrivateKey, spend *transaction.TaprootSpend) ([]byte, error) {
+
+		hash, err := hashes.TaprootSigHash(idx, hashType, spend)
+		if err != nil {
+			return nil, err
+		}
+
+		sig, err := secp256k1.SchnorrSign(k, hash, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if hashType != transaction.SigHashDefault {
+			sig = append(sig, byte(hashType))
+		}
+
+		return sig, nil
+	}
+
+	signed := false
+
+	if bytes.Equal(in.TaprootInternalKey, xonly) {
+		tweaked, err := hdwallet.TaprootTweakPrivKey(key, in.TaprootMerkleRoot)
+		if err != nil {
+			return err
+		}
+
+		// the merkle root must lead to the output key being spent
+		if bytes.Equal(tweaked.PubKey().SerializeXOnly(), utxo.PkScript[2:]) {
+			sig, err := sign(tweaked, &transaction.TaprootSpend{})
+			if err != nil {
+				return err
+			}
+			in.TaprootKeySpendSig = sig
+			signed = true
+		}
+	}
+
+	for _, leaf := range in.TaprootLeafScripts {
+		if !tapscriptHasKey(leaf.Script, xonly) {
+			continue
+		}
+
+		leafHash := hdwallet.TapLeafHash(leaf.LeafVersion, leaf.Script)
+		sig, err := sign(key, transaction.NewTapscriptSpend(leafHash))
+		if err != nil {
+			return err
+		}
+
+		in.addTaprootScriptSig(xonly, leafHash, sig)
+		signed = true
+	}
+
+	if !signed {
+		return ErrKeyNotFound
+	}
+
+	return nil
+}
+
+// satisfyTaproot returns the smallest witness spending the input, the key
+// path is preferred when its signature is available
+func (in *Input) satisfyTaproot() (transaction.Witness, error) {
+
+	if len(in.TaprootKeySpendSig) != 0 {
+		return transaction.Witness{in.TaprootKeySpendSig}, nil
+	}
+
+	var best transaction.Witness
+	bestSize := 0
+
+	for _, leaf := range in.TaprootLeafScripts {
+		if leaf.LeafVersion != hdwallet.BaseLeafVersion {
+			continue
+		}
+
+		leafHash := hdwallet.TapLeafHash(leaf.LeafVersion, leaf.Script)
+		stack, err := in.satisfyTapscript(leaf.Script, leafHash)
+		if err != nil {
+			continue
+		}
+
+		witness := append(transaction.Witness(stack), leaf.Script, leaf.ControlBlock)
+		if size := witness.SerializeSize(); best == nil || size < bestSize {
+			best, bestSize = witness, size
+		}
+	}
+
+	if best == nil {
+		return nil, ErrNotFinalizable
+	}
+
+	return best, nil
+}
+
+// satisfyTapscript returns the stack satisfying a single key leaf or a
+// CHECKSIGADD threshold leaf with the script signatures of the input
+func (in *Input) satisfyTapscript(s, leafHash []byte) ([][]byte, error) {
+
+	ins, err := script.Parse(s)
+	if err != nil {
+		return nil, ErrUnsupportedScript
+	}
+
+	// <key> OP_CHECKSIG
+	if len(ins) == 2 && len(ins[0].Data) == xOnlyLen && ins[1].Opcode == script.OpCheckSig {
+		sig := in.taprootScriptSig(ins[0].Data, leafHash)
+		if sig == nil {
+			return nil, ErrNotFinalizable
+		}
+		return [][]byte{sig}, nil
+	}
+
+	// <key> OP_CHECKSIG <key> OP_CHECKSIGADD ... <m> OP_NUMEQUAL
+	n := (len(ins) - 2) / 2
+	if len(ins) < 4 || len(ins)%2 != 0 || ins[len(ins)-1].Opcode != script.OpNumEqual {
+		return nil, ErrUnsupportedScript
+	}
+
+	m, ok := scriptInt(ins[len(ins)-2])
+	if !ok || m < 1 || m > n {
+		return nil, ErrUnsupportedScript
+	}
+
+	keys := make([][]byte, n)
+	for i := range keys {
+		op := script.OpCheckSigAdd
+		if i == 0 {
+			op = script.OpCheckSig
+		}
+		if len(ins[2*i].Data) != xOnlyLen || ins[2*i+1].Opcode != op {
+			return nil, ErrUnsupportedScript
+		}
+		keys[i] = ins[2*i].Data
+	}
+
+	// the signature of the first key is consumed first so the stack lists
+	// them in reverse, keys beyond the threshold get an empty signature
+	stack := make([][]byte, n)
+	found := 0
+	for i, key := range keys {
+		if sig := in.taprootScriptSig(key, leafHash); sig != nil && found < m {
+			stack[n-1-i] = sig
+			found++
+		} else {
+			stack[n-1-i] = []byte{}
+		}
+	}
+
+	if found < m {
+		return nil, ErrNotFinalizable
+	}
+
+	return stack, nil
+}
+
+func (in *Input) taprootScriptSig(xonly, leafHash []byte) []byte {
+
+	for _, s := range in.TaprootScriptSpendSigs {
+		if bytes.Equal(s.XOnlyPubKey, xonly) && bytes.Equal(s.LeafHash, leafHash) {
+			return s.Signature
+		}
+	}
+
+	return nil
+}
+
+func (in *Input) addTaprootScriptSig(xonly, leafHash, sig []byte) {
+
+	for _, s := range in.TaprootScriptSpendSigs {
+		if bytes.Equal(s.XOnlyPubKey, xonly) && bytes.Equal(s.LeafHash, leafHash) {
+			s.Signature = sig
+			return
+		}
+	}
+
+	in.TaprootScriptSpendSigs = append(in.TaprootScriptSpendSigs, &TaprootScriptSpendSig{
+		XOnlyPubKey: xonly,
+		LeafHash:    leafHash,
+		Signature:   sig,
+	})
+}
+
+// tapscriptHasKey reports whether a leaf script pushes the x-only key
+func tapscriptHasKey(s, xonly []byte) bool {
+
+	pushes, err := script.PushedData(s)
+	if err != nil {
+		return false
+	}
+
+	for _, data := range pushes {
+		if bytes.Equal(data, xonly) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// scriptInt decodes a small positive number pushed by an instruction
+func scriptInt(in script.Instruction) (int, bool) {
+
+	if script.IsSmallInt(in.Opcode) {
+		return script.SmallInt(in.Opcode), true
+	}
+
+	if len(in.Data) == 0 || len(in.Data) > 2 || in.Data[len(in.Data)-1]&0x80 != 0 {
+		return 0, false
+	}
+
+	n := 0
+	for i := len(in.Data) - 1; i >= 0; i-- {
+		n = n<<8 | int(in.Data[i])
+	}
+
+	return n, true
+}
+
+func validXOnlyPubKey(b []byte) bool {
+
+	if len(b) != xOnlyLen {
+		return false
+	}
+
+	_, err := secp256k1.ParseXOnlyPubKey(b)
+
+	return err == nil
+}
+
+func validSchnorrSig(sig []byte) bool {
+	return len(sig) == schnorrSigLen || len(sig) == schnorrSigLen+1
+}
+
+func validControlBlock(control []byte) bool {
+
+	n := len(control) - controlBaseLen
+
+	return n >= 0 && n%controlNodeLen == 0 && n/controlNodeLen <= maxControlNodes
+}
+
+func parseTaprootBip32Derivation(keyData, value []byte) (*TaprootBip32Derivation, error) {
+
+	if !validXOnlyPubKey(keyData) {
+		return nil, ErrInvalidKey
+	}
+
+	r := bytes.NewReader(value)
+	n, err := transaction.ReadVarInt(r)
+	if err != nil || n > uint64(r.Len()/leafHashLen) {
+		return nil, ErrInvalidValue
+	}
+
+	d := &TaprootBip32Derivation{XOnlyPubKey: keyData, LeafHashes: make([][]byte, n)}
+	for i := range d.LeafHashes {
+		d.LeafHashes[i] = make([]byte, leafHashLen)
+		io.ReadFull(r, d.LeafHashes[i])
+	}
+
+	if d.Fingerprint, d.Path, err = parseDerivation(value[len(value)-r.Len():]); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func taprootDerivationValue(d *TaprootBip32Derivation) []byte {
+
+	var buf bytes.Buffer
+	transaction.WriteVarInt(&buf, uint64(len(d.LeafHashes)))
+	for _, h := range d.LeafHashes {
+		buf.Write(h)
+	}
+	buf.Write(derivationValue(d.Fingerprint, d.Path))
+
+	return buf.Bytes()
+}
+
+// writeTaprootBip32Derivations writes the derivations sorted by key
+func writeTaprootBip32Derivations(w io.Writer, keyType uint64, derivations []*TaprootBip32Derivation) error {
+
+	sorted := append([]*TaprootBip32Derivation{}, derivations...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].XOnlyPubKey, sorted[j].XOnlyPubKey) < 0
+	})
+
+	for _, d := range sorted {
+		if err := writeKV(w, keyType, d.XOnlyPubKey, taprootDerivationValue(d)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func addTaprootDerivation(derivations []*TaprootBip32Derivation, d *TaprootBip32Derivation) []*TaprootBip32Derivation {
+
+	for i, old := range derivations {
+		if bytes.Equal(old.XOnlyPubKey, d.XOnlyPubKey) {
+			derivations[i] = d
+			return derivations
+		}
+	}
+
+	return append(derivations, d)
+}
+
+func mergeTaprootDerivations(dst, src []*TaprootBip32Derivation) []*TaprootBip32Derivation {
+
+next:
+	for _, d := range src {
+		for _, old := range dst {
+			if bytes.Equal(old.XOnlyPubKey, d.XOnlyPubKey) {
+				continue next
+			}
+		}
+		dst = append(dst, d)
+	}
+
+	return dst
+}
+
+// parseTapTree decodes the leaves of an output script tree, the depths
+// must describe a complete binary tree
+func parseTapTree(value []byte) ([]*TaprootTreeLeaf, error) {
+
+	var leaves []*TaprootTreeLeaf
+
+	r := bytes.NewReader(value)
+	for r.Len() > 0 {
+		depth, _ := r.ReadByte()
+		leafVersion, err := r.ReadByte()
+		if err != nil {
+			return nil, ErrInvalidValue
+		}
+
+		s, err := transaction.ReadVarBytes(r, transaction.MaxVarBytes)
+		if err != nil {
+			return nil, ErrInvalidValue
+		}
+
+		leaves = append(leaves, &TaprootTreeLeaf{Depth: depth, LeafVersion: leafVersion, Script: s})
+	}
+
+	if !validTapTree(leaves) {
+		return nil, ErrInvalidValue
+	}
+
+	return leaves, nil
+}
+
+// validTapTree checks that leaves listed in depth first order with their
+// depth form a complete binary tree
+func validTapTree(leaves []*TaprootTreeLeaf) bool {
+
+	// pending holds the depths of the subtrees not yet paired, siblings of
+	// equal depth are merged into their parent
+	var pending []int
+
+	for _, leaf := range leaves {
+		if len(pending) == 1 && pending[0] == 0 {
+			return false
+		}
+
+		d := int(leaf.Depth)
+		if d > maxTreeDepth || leaf.LeafVersion&1 != 0 {
+			return false
+		}
+
+		for len(pending) > 0 && pending[len(pending)-1] == d {
+			pending = pending[:len(pending)-1]
+			d--
+		}
+
+		if len(pending) > 0 && pending[len(pending)-1] > d {
+			return false
+		}
+
+		pending = append(pending, d)
+	}
+
+	return len(pending) == 1 && pending[0] == 0
+}
+
+func tapTreeValue(leaves []*TaprootTreeLeaf) []byte {
+
+	var buf bytes.Buffer
+	for _, leaf := range leaves {
+		buf.WriteByte(leaf.Depth)
+		buf.WriteByte(leaf.LeafVersion)
+		transaction.WriteVarBytes(&buf, leaf.Script)
+	}
+
+	return buf.Bytes()
+}
@@ -0,0 +1,240 @@
+package psbt
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/script"
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+type bip371test struct {
+	Valid   []string `json:"valid"`
+	Invalid []struct {
+		Comment string `json:"comment"`
+		Psbt    string `json:"psbt"`
+	} `json:"invalid"`
+}
+
+func bip371TestVector(t *testing.T) *bip371test {
+
+	data, err := os.ReadFile("testdata/bip371.json")
+	assert.NoError(t, err)
+
+	var test bip371test
+	assert.NoError(t, json.Unmarshal(data, &test))
+
+	return &test
+}
+
+// taprootPacket returns a packet spending a P2TR output of internal with
+// merkleRoot to an OP_RETURN output
+func taprootPacket(t *testing.T, internal *secp256k1.PublicKey, merkleRoot []byte) *Packet {
+
+	outputKey, err := hdwallet.TaprootTweakPubKey(internal, merkleRoot)
+	assert.NoError(t, err)
+	pkScript, err := script.PayToTaproot(outputKey.SerializeXOnly())
+	assert.NoError(t, err)
+
+	hash := transaction.DoubleHashH([]byte("taproot"))
+	p, err := New([]*transaction.OutPoint{transaction.NewOutPoint(&hash, 1)}, []*transaction.TxOut{transaction.NewTxOut(90000, []byte{0x6a})}, 2, 0)
+	assert.NoError(t, err)
+
+	assert.NoError(t, p.AddInWitnessUtxo(0, transaction.NewTxOut(100000, pkScript)))
+	p.Inputs[0].TaprootInternalKey = internal.SerializeXOnly()
+	p.Inputs[0].TaprootMerkleRoot = merkleRoot
+
+	return p
+}
+
+// finalizeAndVerify finalizes p and runs the interpreter on the extracted
+// transaction
+func finalizeAndVerify(t *testing.T, p *Packet) *transaction.Tx {
+
+	prevOuts := p.prevOutputs()
+
+	// the signatures survive a round trip
+	p, err := Parse(p.Bytes())
+	assert.NoError(t, err)
+
+	assert.NoError(t, p.Finalize())
+	tx, err := p.Extract()
+	assert.NoError(t, err)
+	assert.NoError(t, script.VerifyTx(tx, prevOuts, script.StandardVerifyFlags))
+
+	return tx
+}
+
+func TestParseTaprootValid(t *testing.T) {
+	for _, test := range bip371TestVector(t).Valid {
+		p, err := ParseBase64(test)
+		assert.NoError(t, err)
+		assert.Equal(t, test, p.Base64())
+	}
+}
+
+func TestParseTaprootInvalid(t *testing.T) {
+	for _, test := range bip371TestVector(t).Invalid {
+		_, err := ParseBase64(test.Psbt)
+		assert.Error(t, err, test.Comment)
+	}
+}
+
+func TestTaprootKeySpend(t *testing.T) {
+	master, _ := hdwallet.NewMasterKey(make([]byte, 32), hdwallet.TestnetPrivate)
+	path, _ := hdwallet.ParsePath("m/86'/1'/0'/0/0")
+
+	d, err := NewTaprootBip32Derivation(master, path, nil)
+	assert.NoError(t, err)
+	internal, _ := secp256k1.ParseXOnlyPubKey(d.XOnlyPubKey)
+
+	p := taprootPacket(t, internal, nil)
+	assert.NoError(t, p.AddInTaprootBip32Derivation(0, d))
+	assert.Equal(t, ErrInvalidKey, p.AddInTaprootBip32Derivation(0, &TaprootBip32Derivation{XOnlyPubKey: d.XOnlyPubKey[1:]}))
+
+	assert.NoError(t, p.Sign(master))
+	assert.Len(t, p.Inputs[0].TaprootKeySpendSig, 64)
+
+	tx := finalizeAndVerify(t, p)
+	assert.Equal(t, transaction.Witness{p.Inputs[0].TaprootKeySpendSig}, tx.TxIn[0].Witness)
+
+	// a non default sighash type is appended to the signature
+	p = taprootPacket(t, internal, nil)
+	assert.NoError(t, p.AddInTaprootBip32Derivation(0, d))
+	assert.NoError(t, p.AddInSigHashType(0, transaction.SigHashAll))
+	assert.NoError(t, p.Sign(master))
+	assert.Len(t, p.Inputs[0].TaprootKeySpendSig, 65)
+	finalizeAndVerify(t, p)
+}
+
+func TestTaprootScriptSpend(t *testing.T) {
+	master, _ := hdwallet.NewMasterKey(make([]byte, 32), hdwallet.TestnetPrivate)
+	other, _ := hdwallet.NewMasterKey(make([]byte, 16), hdwallet.TestnetPrivate)
+
+	xonly := func(path string) []byte {
+		indexes, _ := hdwallet.ParsePath(path)
+		d, _ := NewTaprootBip32Derivation(master, indexes, nil)
+		return d.XOnlyPubKey
+	}
+	a, b, c := xonly("m/0"), xonly("m/1"), xonly("m/2")
+
+	pkLeaf, _ := script.NewBuilder().AddData(a).AddOp(script.OpCheckSig).Script()
+	multiLeaf, _ := script.NewBuilder().
+		AddData(b).AddOp(script.OpCheckSig).
+		AddData(c).AddOp(script.OpCheckSigAdd).
+		AddOp(script.Op2).AddOp(script.OpNumEqual).Script()
+
+	pkHash := hdwallet.TapLeafHash(hdwallet.BaseLeafVersion, pkLeaf)
+	multiHash := hdwallet.TapLeafHash(hdwallet.BaseLeafVersion, multiLeaf)
+	merkleRoot := hdwallet.TapBranchHash(pkHash, multiHash)
+
+	// the internal key belongs to another wallet so only the leaves can
+	// be signed
+	internal, _ := other.PubKey()
+	outputKey, _ := hdwallet.TaprootTweakPubKey(internal, merkleRoot)
+	parity := byte(0)
+	if !outputKey.HasEvenY() {
+		parity = 1
+	}
+	control := func(sibling []byte) []byte {
+		c := append([]byte{hdwallet.BaseLeafVersion | parity}, internal.SerializeXOnly()...)
+		return append(c, sibling...)
+	}
+
+	newPacket := func(paths map[string][]byte) *Packet {
+		p := taprootPacket(t, internal, merkleRoot)
+		p.Inputs[0].TaprootLeafScripts = []*TaprootLeafScript{
+			{ControlBlock: control(multiHash), Script: pkLeaf, LeafVersion: hdwallet.BaseLeafVersion},
+			{ControlBlock: control(pkHash), Script: multiLeaf, LeafVersion: hdwallet.BaseLeafVersion},
+		}
+		for path, leafHash := range paths {
+			indexes, _ := hdwallet.ParsePath(path)
+			d, err := NewTaprootBip32Derivation(master, indexes, [][]byte{leafHash})
+			assert.NoError(t, err)
+			assert.NoError(t, p.AddInTaprootBip32Derivation(0, d))
+		}
+		return p
+	}
+
+	// every key signs, the single key leaf gives the smallest witness
+	p := newPacket(map[string][]byte{"m/0": pkHash, "m/1": multiHash, "m/2": multiHash})
+	assert.NoError(t, p.Sign(master))
+	assert.Nil(t, p.Inputs[0].TaprootKeySpendSig)
+	assert.Len(t, p.Inputs[0].TaprootScriptSpendSigs, 3)
+
+	tx := finalizeAndVerify(t, p)
+	assert.Len(t, tx.TxIn[0].Witness, 3)
+	assert.Equal(t, pkLeaf, tx.TxIn[0].Witness[1])
+
+	// the threshold leaf needs both signatures
+	p = newPacket(map[string][]byte{"m/1": multiHash})
+	assert.NoError(t, p.Sign(master))
+	assert.Equal(t, ErrNotFinalizable, p.Finalize())
+
+	p = newPacket(map[string][]byte{"m/1": multiHash, "m/2": multiHash})
+	assert.NoError(t, p.Sign(master))
+	tx = finalizeAndVerify(t, p)
+	assert.Len(t, tx.TxIn[0].Witness, 4)
+	assert.Equal(t, multiLeaf, tx.TxIn[0].Witness[2])
+	assert.Equal(t, p.Inputs[0].taprootScriptSig(c, multiHash), tx.TxIn[0].Witness[0])
+}
+
+func TestTaprootCombine(t *testing.T) {
+	master, _ := hdwallet.NewMasterKey(make([]byte, 32), hdwallet.TestnetPrivate)
+	path, _ := hdwallet.ParsePath("m/86'/1'/0'/0/0")
+	d, _ := NewTaprootBip32Derivation(master, path, nil)
+	internal, _ := secp256k1.ParseXOnlyPubKey(d.XOnlyPubKey)
+
+	unsigned := taprootPacket(t, internal, nil)
+	signed := taprootPacket(t, internal, nil)
+	assert.NoError(t, signed.AddInTaprootBip32Derivation(0, d))
+	assert.NoError(t, signed.Sign(master))
+
+	combined, err := Combine(unsigned, signed)
+	assert.NoError(t, err)
+	assert.Equal(t, signed.Inputs[0].TaprootKeySpendSig, combined.Inputs[0].TaprootKeySpendSig)
+	assert.Equal(t, signed.Inputs[0].TaprootBip32Derivation, combined.Inputs[0].TaprootBip32Derivation)
+}
+
+func TestTapTree(t *testing.T) {
+	leaf := func(depth byte) *TaprootTreeLeaf {
+		return &TaprootTreeLeaf{Depth: depth, LeafVersion: hdwallet.BaseLeafVersion, Script: []byte{0x51}}
+	}
+
+	tests := []struct {
+		depths []byte
+		valid  bool
+	}{
+		{[]byte{0}, true},
+		{[]byte{1, 1}, true},
+		{[]byte{1, 2, 2}, true},
+		{[]byte{2, 2, 1}, true},
+		{[]byte{2, 2, 2, 2}, true},
+		{[]byte{1, 2, 3, 3}, true},
+		{nil, false},
+		{[]byte{1}, false},
+		{[]byte{0, 0}, false},
+		{[]byte{1, 1, 1}, false},
+		{[]byte{2, 1, 2}, false},
+		{[]byte{1, 2}, false},
+	}
+
+	for _, test := range tests {
+		var leaves []*TaprootTreeLeaf
+		for _, depth := range test.depths {
+			leaves = append(leaves, leaf(depth))
+		}
+
+		parsed, err := parseTapTree(tapTreeValue(leaves))
+		if test.valid {
+			assert.NoError(t, err, test.depths)
+			assert.Equal(t, leaves, parsed)
+		} else {
+			assert.Equal(t, ErrInvalidValue, err, test.depths)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package psbt
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+var (
+	// ErrUtxoMismatch is returned when a previous transaction does not
+	// match the outpoint spent by the input
+	ErrUtxoMismatch = errors.New("psbt: utxo does not match the spent outpoint")
+	// ErrMissingUtxo is returned when the output spent by an input is unknown
+	ErrMissingUtxo = errors.New("psbt: missing utxo")
+)
+
+// NewBip32Derivation derives path from master and returns the derivation
+// of the resulting public key
+func NewBip32Derivation(master *hdwallet.ExtendedKey, path []uint32) (*Bip32Derivation, error) {
+
+	child, err := master.DerivePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pub, err := child.PubKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Bip32Derivation{
+		PubKey:      pub.SerializeCompressed(),
+		Fingerprint: master.Fingerprint(),
+		Path:        path,
+	}, nil
+}
+
+// AddInNonWitnessUtxo sets the transaction whose output is spent by input
+// idx
+func (p *Packet) AddInNonWitnessUtxo(idx int, tx *transaction.Tx) error {
+
+	if idx < 0 || idx >= len(p.Inputs) {
+		return ErrIndex
+	}
+
+	op := p.UnsignedTx.TxIn[idx].PreviousOutPoint
+	if tx.TxHash() != op.Hash || int(op.Index) >= len(tx.TxOut) {
+		return ErrUtxoMismatch
+	}
+
+	p.Inputs[idx].NonWitnessUtxo = tx
+
+	return nil
+}
+
+// AddInWitnessUtxo sets the output spent by input idx
+func (p *Packet) AddInWitnessUtxo(idx int, out *transaction.TxOut) error {
+
+	if idx < 0 || idx >= len(p.Inputs) {
+		return ErrIndex
+	}
+
+	p.Inputs[idx].WitnessUtxo = out
+
+	return nil
+}
+
+// AddInRedeemScript sets the P2SH redeem script of input idx
+func (p *Packet) AddInRedeemScript(idx int, redeemScript []byte) error {
+
+	if idx < 0 || idx >= len(p.Inputs) {
+		return ErrIndex
+	}
+
+	p.Inputs[idx].RedeemScript = redeemScript
+
+	return nil
+}
+
+// AddInWitnessScript sets the P2WSH witness script of input idx
+func (p *Packet) AddInWitnessScript(idx int, witnessScript []byte) error {
+
+	if idx < 0 || idx >= len(p.Inputs) {
+		return ErrIndex
+	}
+
+	p.Inputs[idx].WitnessScript = witnessScript
+
+	return nil
+}
+
+// AddInSigHashType sets the sighash type signers must use for input idx
+func (p *Packet) AddInSigHashType(idx int, hashType transaction.SigHashType) error {
+
+	if idx < 0 || idx >= len(p.Inputs) {
+		return ErrIndex
+	}
+
+	p.Inputs[idx].SigHashType = hashType
+
+	return nil
+}
+
+// AddInBip32Derivation records the derivation of a key of input idx,
+// an existing derivation of the same key is replaced
+func (p *Packet) AddInBip32Derivation(idx int, d *Bip32Derivation) error {
+
+	if idx < 0 || idx >= len(p.Inputs) {
+		return ErrIndex
+	}
+
+	if !validPubKey(d.PubKey) {
+		return ErrInvalidKey
+	}
+
+	p.Inputs[idx].Bip32Derivation = addDerivation(p.Inputs[idx].Bip32Derivation, d)
+
+	return nil
+}
+
+// AddOutRedeemScript sets the P2SH redeem script of output idx
+func (p *Packet) AddOutRedeemScript(idx int, redeemScript []byte) error {
+
+	if idx < 0 || idx >= len(p.Outputs) {
+		return ErrIndex
+	}
+
+	p.Outputs[idx].RedeemScript = redeemScript
+
+	return nil
+}
+
+// AddOutWitnessScript sets the P2WSH witness script of output idx
+func (p *Packet) AddOutWitnessScript(idx int, witnessScript []byte) error {
+
+	if idx < 0 || idx >= len(p.Outputs) {
+		return ErrIndex
+	}
+
+	p.Outputs[idx].WitnessScript = witnessScript
+
+	return nil
+}
+
+// AddOutBip32Derivation records the derivation of a key of output idx,
+// change outputs are recognized by signers through it
+func (p *Packet) AddOutBip32Derivation(idx int, d *Bip32Derivation) error {
+
+	if idx < 0 || idx >= len(p.Outputs) {
+		return ErrIndex
+	}
+
+	if !validPubKey(d.PubKey) {
+		return ErrInvalidKey
+	}
+
+	p.Outputs[idx].Bip32Derivation = addDerivation(p.Outputs[idx].Bip32Derivation, d)
+
+	return nil
+}
+
+// utxo returns the output spent by input idx, the previous transaction is
+// checked against the outpoint whenever present
+func (p *Packet) utxo(idx int) (*transaction.TxOut, error) {
+
+	in := p.Inputs[idx]
+
+	if in.NonWitnessUtxo != nil {
+		op := p.UnsignedTx.TxIn[idx].PreviousOutPoint
+		if in.NonWitnessUtxo.TxHash() != op.Hash || int(op.Index) >= len(in.NonWitnessUtxo.TxOut) {
+			return nil, ErrUtxoMismatch
+		}
+		return in.NonWitnessUtxo.TxOut[op.Index], nil
+	}
+
+	if in.WitnessUtxo != nil {
+		return in.WitnessUtxo, nil
+	}
+
+	return nil, ErrMissingUtxo
+}
+
+// prevOutputs returns the known outputs spent by the transaction
+func (p *Packet) prevOutputs() transaction.PrevOutputMap {
+
+	prevOuts := make(transaction.PrevOutputMap)
+
+	for i, in := range p.UnsignedTx.TxIn {
+		if out, err := p.utxo(i); err == nil {
+			prevOuts[in.PreviousOutPoint] = out
+		}
+	}
+
+	return prevOuts
+}
+
+func addDerivation(derivations []*Bip32Derivation, d *Bip32Derivation) []*Bip32Derivation {
+
+	for i, old := range derivations {
+		if bytes.Equal(old.PubKey, d.PubKey) {
+			derivations[i] = d
+			return derivations
+		}
+	}
+
+	return append(derivations, d)
+}
@@ -0,0 +1,211 @@
+package psbt
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+// TxModifiable flags
+const (
+	// ModifiableInputs allows the constructor to add inputs
+	ModifiableInputs byte = 1 << 0
+	// ModifiableOutputs allows the constructor to add outputs
+	ModifiableOutputs byte = 1 << 1
+	// HasSigHashSingle is set once an input is signed with SIGHASH_SINGLE,
+	// its paired output index must then be preserved
+	HasSigHashSingle byte = 1 << 2
+)
+
+// lockTimeThreshold separates block heights from timestamps
+const lockTimeThreshold = 500000000
+
+var (
+	// ErrNotModifiable is returned when adding inputs or outputs the
+	// packet does not allow to be added
+	ErrNotModifiable = errors.New("psbt: transaction is not modifiable")
+	// ErrLockTimeConflict is returned when the inputs require both a height
+	// and a time locktime, or when adding an input would change the
+	// locktime of a signed transaction
+	ErrLockTimeConflict = errors.New("psbt: conflicting locktime requirements")
+)
+
+// NewV2 creates an empty version 2 packet for the constructor role,
+// modifiable holds the TxModifiable flags
+func NewV2(txVersion int32, fallbackLockTime uint32, modifiable byte) *Packet {
+
+	tx := transaction.NewTx(txVersion)
+	tx.LockTime = fallbackLockTime
+
+	return &Packet{
+		UnsignedTx:       tx,
+		Version:          2,
+		FallbackLockTime: fallbackLockTime,
+		TxModifiable:     modifiable,
+	}
+}
+
+// AddInput appends an input spending txIn.PreviousOutPoint with the
+// sequence of txIn, in may be nil. The packet must allow inputs to be
+// added and the locktime requirement of in must be compatible with the
+// other inputs.
+func (p *Packet) AddInput(txIn *transaction.TxIn, in *Input) error {
+
+	if p.Version != 2 {
+		return ErrUnsupportedVersion
+	}
+
+	if p.TxModifiable&ModifiableInputs == 0 {
+		return ErrNotModifiable
+	}
+
+	if in == nil {
+		in = &Input{}
+	}
+
+	if (in.RequiredTimeLockTime != 0 && in.RequiredTimeLockTime < lockTimeThreshold) ||
+		in.RequiredHeightLockTime >= lockTimeThreshold {
+		return ErrInvalidValue
+	}
+
+	inputs := append(append([]*Input{}, p.Inputs...), in)
+	lockTime, err := p.lockTime(inputs)
+	if err != nil {
+		return err
+	}
+
+	// signatures commit to the locktime
+	if lockTime != p.UnsignedTx.LockTime {
+		for _, other := range p.Inputs {
+			if other.hasSigs() {
+				return ErrLockTimeConflict
+			}
+		}
+	}
+
+	p.UnsignedTx.AddTxIn(&transaction.TxIn{
+		PreviousOutPoint: txIn.PreviousOutPoint,
+		Sequence:         txIn.Sequence,
+	})
+	p.UnsignedTx.LockTime = lockTime
+	p.Inputs = inputs
+
+	return nil
+}
+
+// AddOutput appends an output paying out.Value to out.PkScript, o may be
+// nil. The packet must allow outputs to be added.
+func (p *Packet) AddOutput(out *transaction.TxOut, o *Output) error {
+
+	if p.Version != 2 {
+		return ErrUnsupportedVersion
+	}
+
+	if p.TxModifiable&ModifiableOutputs == 0 {
+		return ErrNotModifiable
+	}
+
+	if o == nil {
+		o = &Output{}
+	}
+
+	p.UnsignedTx.AddTxOut(transaction.NewTxOut(out.Value, out.PkScript))
+	p.Outputs = append(p.Outputs, o)
+
+	return nil
+}
+
+// lockTime computes the locktime of a version 2 transaction with inputs.
+// Without requirements the fallback is used, otherwise the highest value
+// of the kind every constrained input supports, heights being preferred.
+func (p *Packet) lockTime(inputs []*Input) (uint32, error) {
+
+	var height, time uint32
+	heightOk, timeOk, constrained := true, true, false
+
+	for _, in := range inputs {
+		if in.RequiredHeightLockTime == 0 && in.RequiredTimeLockTime == 0 {
+			continue
+		}
+		constrained = true
+
+		if in.RequiredHeightLockTime == 0 {
+			heightOk = false
+		} else if in.RequiredHeightLockTime > height {
+			height = in.RequiredHeightLockTime
+		}
+
+		if in.RequiredTimeLockTime == 0 {
+			timeOk = false
+		} else if in.RequiredTimeLockTime > time {
+			time = in.RequiredTimeLockTime
+		}
+	}
+
+	switch {
+	case !constrained:
+		return p.FallbackLockTime, nil
+	case heightOk:
+		return height, nil
+	case timeOk:
+		return time, nil
+	}
+
+	return 0, ErrLockTimeConflict
+}
+
+// updateModifiable clears the flags a signature with hashType no longer
+// allows, as done by signers of version 2 packets
+func (p *Packet) updateModifiable(hashType transaction.SigHashType) {
+
+	if p.Version != 2 {
+		return
+	}
+
+	if hashType&transaction.SigHashAnyOneCanPay == 0 {
+		p.TxModifiable &^= ModifiableInputs
+	}
+
+	switch hashType &^ transaction.SigHashAnyOneCanPay {
+	case transaction.SigHashNone:
+	case transaction.SigHashSingle:
+		p.TxModifiable |= HasSigHashSingle
+	default:
+		p.TxModifiable &^= ModifiableOutputs
+	}
+}
+
+func (p *Packet) serializeV2Globals(w io.Writer) error {
+
+	if err := writeKV(w, globalTxVersion, nil, uint32Bytes(uint32(p.UnsignedTx.Version))); err != nil {
+		return err
+	}
+
+	if p.FallbackLockTime != 0 {
+		if err := writeKV(w, globalFallbackLockTime, nil, uint32Bytes(p.FallbackLockTime)); err != nil {
+			return err
+		}
+	}
+
+	var inputCount, outputCount bytes.Buffer
+	transaction.WriteVarInt(&inputCount, uint64(len(p.Inputs)))
+	transaction.WriteVarInt(&outputCount, uint64(len(p.Outputs)))
+
+	if err := writeKV(w, globalInputCount, nil, inputCount.Bytes()); err != nil {
+		return err
+	}
+
+	if err := writeKV(w, globalOutputCount, nil, outputCount.Bytes()); err != nil {
+		return err
+	}
+
+	if p.TxModifiable != 0 {
+		if err := writeKV(w, globalTxModifiable, nil, []byte{p.TxModifiable}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
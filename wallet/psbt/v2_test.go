@@ -0,0 +1,224 @@
+package psbt
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/script"
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+type kv struct {
+	keyType uint64
+	value   []byte
+}
+
+// rawPacket encodes the global map followed by the input and output maps
+func rawPacket(maps ...[]kv) []byte {
+
+	var buf bytes.Buffer
+	buf.Write(magic)
+
+	for _, m := range maps {
+		for _, pair := range m {
+			writeKV(&buf, pair.keyType, nil, pair.value)
+		}
+		writeSeparator(&buf)
+	}
+
+	return buf.Bytes()
+}
+
+func TestV2Constructor(t *testing.T) {
+	test := bip174TestVector(t)
+
+	var inputs []*transaction.OutPoint
+	for _, in := range test.Creator.Inputs {
+		hash, _ := transaction.NewHashFromStr(in[0].(string))
+		inputs = append(inputs, transaction.NewOutPoint(&hash, uint32(in[1].(float64))))
+	}
+
+	var outputs []*transaction.TxOut
+	for _, out := range test.Creator.Outputs {
+		outputs = append(outputs, transaction.NewTxOut(int64(out[0].(float64)), decodeHex(out[1].(string))))
+	}
+
+	v0, err := New(inputs, outputs, 2, 0)
+	assert.NoError(t, err)
+
+	p := NewV2(2, 0, ModifiableInputs|ModifiableOutputs)
+	for _, op := range inputs {
+		assert.NoError(t, p.AddInput(transaction.NewTxIn(op, nil, nil), nil))
+	}
+	for _, out := range outputs {
+		assert.NoError(t, p.AddOutput(out, nil))
+	}
+
+	assert.Equal(t, v0.UnsignedTx.TxHash(), p.UnsignedTx.TxHash())
+
+	parsed, err := Parse(p.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), parsed.Version)
+	assert.Equal(t, p.TxModifiable, parsed.TxModifiable)
+	assert.Equal(t, p.Bytes(), parsed.Bytes())
+	assert.Equal(t, v0.UnsignedTx.TxHash(), parsed.UnsignedTx.TxHash())
+
+	// the constructor role only applies to version 2
+	assert.Equal(t, ErrUnsupportedVersion, v0.AddOutput(outputs[0], nil))
+
+	fixed := NewV2(2, 0, 0)
+	assert.Equal(t, ErrNotModifiable, fixed.AddInput(transaction.NewTxIn(inputs[0], nil, nil), nil))
+	assert.Equal(t, ErrNotModifiable, fixed.AddOutput(outputs[0], nil))
+}
+
+func TestV2LockTime(t *testing.T) {
+	tests := []struct {
+		fallback uint32
+		inputs   [][2]uint32
+		lockTime uint32
+		err      error
+	}{
+		{0, nil, 0, nil},
+		{100, [][2]uint32{{0, 0}}, 100, nil},
+		{100, [][2]uint32{{0, 10000}}, 10000, nil},
+		{100, [][2]uint32{{1657048460, 0}}, 1657048460, nil},
+		{0, [][2]uint32{{1657048460, 10000}, {1657048459, 10001}}, 10001, nil},
+		{0, [][2]uint32{{1657048460, 0}, {1657048459, 10000}}, 1657048460, nil},
+		{0, [][2]uint32{{0, 10000}, {1657048459, 10001}, {0, 0}}, 10001, nil},
+		{0, [][2]uint32{{1657048460, 0}, {0, 10000}}, 0, ErrLockTimeConflict},
+	}
+
+	for i, test := range tests {
+		p := NewV2(2, test.fallback, ModifiableInputs)
+
+		var err error
+		for j, req := range test.inputs {
+			hash := transaction.DoubleHashH([]byte{byte(j)})
+			in := &Input{RequiredTimeLockTime: req[0], RequiredHeightLockTime: req[1]}
+			if err = p.AddInput(transaction.NewTxIn(transaction.NewOutPoint(&hash, 0), nil, nil), in); err != nil {
+				break
+			}
+		}
+
+		assert.Equal(t, test.err, err, i)
+		if err == nil {
+			assert.Equal(t, test.lockTime, p.UnsignedTx.LockTime, i)
+
+			parsed, err := Parse(p.Bytes())
+			assert.NoError(t, err)
+			assert.Equal(t, test.lockTime, parsed.UnsignedTx.LockTime, i)
+		}
+	}
+}
+
+func TestV2Sign(t *testing.T) {
+	master, _ := hdwallet.NewMasterKey(make([]byte, 32), hdwallet.TestnetPrivate)
+	path, _ := hdwallet.ParsePath("m/86'/1'/0'/0/0")
+	d, _ := NewTaprootBip32Derivation(master, path, nil)
+	internal, _ := secp256k1.ParseXOnlyPubKey(d.XOnlyPubKey)
+	outputKey, _ := hdwallet.TaprootTweakPubKey(internal, nil)
+	pkScript, _ := script.PayToTaproot(outputKey.SerializeXOnly())
+
+	p := NewV2(2, 0, ModifiableInputs|ModifiableOutputs)
+	hash := transaction.DoubleHashH([]byte("v2"))
+	assert.NoError(t, p.AddInput(transaction.NewTxIn(transaction.NewOutPoint(&hash, 0), nil, nil), &Input{
+		WitnessUtxo:            transaction.NewTxOut(100000, pkScript),
+		SigHashType:            transaction.SigHashAll | transaction.SigHashAnyOneCanPay,
+		TaprootInternalKey:     d.XOnlyPubKey,
+		TaprootBip32Derivation: []*TaprootBip32Derivation{d},
+	}))
+	assert.NoError(t, p.AddOutput(transaction.NewTxOut(90000, []byte{0x6a}), nil))
+
+	// anyone can pay must be explicitly allowed, it keeps inputs modifiable
+	// while the outputs are fixed
+	assert.Equal(t, ErrSigHashNotAllowed, p.Sign(master))
+	assert.NoError(t, p.SignAllowing(master, transaction.SigHashAll|transaction.SigHashAnyOneCanPay))
+	assert.Equal(t, ModifiableInputs, p.TxModifiable)
+	assert.Equal(t, ErrNotModifiable, p.AddOutput(transaction.NewTxOut(1000, []byte{0x6a}), nil))
+
+	// the signature commits to the locktime
+	other := transaction.DoubleHashH([]byte("other"))
+	txIn := transaction.NewTxIn(transaction.NewOutPoint(&other, 0), nil, nil)
+	assert.Equal(t, ErrLockTimeConflict, p.AddInput(txIn, &Input{RequiredHeightLockTime: 800000}))
+	assert.Len(t, p.Inputs, 1)
+
+	tx := finalizeAndVerify(t, p)
+	assert.Equal(t, p.UnsignedTx.TxHash(), tx.TxHash())
+
+	p.TxModifiable = ModifiableInputs | ModifiableOutputs
+	p.updateModifiable(transaction.SigHashSingle | transaction.SigHashAnyOneCanPay)
+	assert.Equal(t, ModifiableInputs|ModifiableOutputs|HasSigHashSingle, p.TxModifiable)
+	p.updateModifiable(transaction.SigHashNone)
+	assert.Equal(t, ModifiableOutputs|HasSigHashSingle, p.TxModifiable)
+	p.updateModifiable(transaction.SigHashDefault)
+	assert.Equal(t, HasSigHashSingle, p.TxModifiable)
+}
+
+func TestV2Combine(t *testing.T) {
+	hash := transaction.DoubleHashH([]byte("v2"))
+	txIn := transaction.NewTxIn(transaction.NewOutPoint(&hash, 0), nil, nil)
+
+	p1 := NewV2(2, 0, ModifiableInputs|ModifiableOutputs)
+	assert.NoError(t, p1.AddInput(txIn, nil))
+	p2, _ := Parse(p1.Bytes())
+	p2.TxModifiable = ModifiableInputs | HasSigHashSingle
+
+	combined, err := Combine(p1, p2)
+	assert.NoError(t, err)
+	assert.Equal(t, ModifiableInputs|HasSigHashSingle, combined.TxModifiable)
+
+	v0, _ := NewFromUnsignedTx(p1.UnsignedTx)
+	_, err = Combine(p1, v0)
+	assert.Equal(t, ErrTxMismatch, err)
+}
+
+func TestV2ParseInvalid(t *testing.T) {
+	tx := transaction.NewTx(2)
+	tx.AddTxIn(transaction.NewTxIn(&transaction.OutPoint{}, nil, nil))
+	tx.AddTxOut(transaction.NewTxOut(1000, []byte{0x6a}))
+	unsignedTx := tx.BytesNoWitness()
+
+	version := func(v uint32) kv { return kv{globalVersion, uint32Bytes(v)} }
+	globals := []kv{
+		{globalTxVersion, uint32Bytes(2)},
+		{globalInputCount, []byte{1}},
+		{globalOutputCount, []byte{1}},
+		version(2),
+	}
+	input := []kv{
+		{inPreviousTxid, make([]byte, 32)},
+		{inOutputIndex, uint32Bytes(0)},
+	}
+	output := []kv{
+		{outAmount, make([]byte, 8)},
+		{outScript, []byte{0x6a}},
+	}
+
+	_, err := Parse(rawPacket(globals, input, output))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		comment string
+		data    []byte
+		err     error
+	}{
+		{"version 1", rawPacket(append(globals[:3:3], version(1)), input, output), ErrUnsupportedVersion},
+		{"missing input count", rawPacket([]kv{globals[0], globals[2], globals[3]}, input, output), ErrMissingField},
+		{"unsigned tx in version 2", rawPacket(append([]kv{{globalUnsignedTx, unsignedTx}}, globals...), input, output), ErrInvalidKey},
+		{"missing output index", rawPacket(globals, input[:1], output), ErrMissingField},
+		{"missing output script", rawPacket(globals, input, output[:1]), ErrMissingField},
+		{"required height is a time", rawPacket(globals, append(input, kv{inRequiredHeightLockTime, uint32Bytes(lockTimeThreshold)}), output), ErrInvalidValue},
+		{"required time is a height", rawPacket(globals, append(input, kv{inRequiredTimeLockTime, uint32Bytes(lockTimeThreshold - 1)}), output), ErrInvalidValue},
+		{"tx version in version 0", rawPacket([]kv{{globalUnsignedTx, unsignedTx}, globals[0]}, nil, nil), ErrInvalidKey},
+		{"previous txid in version 0", rawPacket([]kv{{globalUnsignedTx, unsignedTx}}, input[:1], nil), ErrInvalidKey},
+		{"amount in version 0", rawPacket([]kv{{globalUnsignedTx, unsignedTx}}, nil, output[:1]), ErrInvalidKey},
+	}
+
+	for _, test := range tests {
+		_, err := Parse(test.data)
+		assert.Equal(t, test.err, err, test.comment)
+	}
+}
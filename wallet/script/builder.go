@@ -0,0 +1,143 @@
+package script
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// ErrScriptTooLarge is returned when a script exceeds MaxScriptSize
+var ErrScriptTooLarge = errors.New("script: script too large")
+
+// Builder assembles a script using minimal push encodings, the first
+// error is kept and returned by Script
+type Builder struct {
+	script []byte
+	err    error
+}
+
+// NewBuilder returns an empty script builder
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// AddOp appends an opcode
+func (b *Builder) AddOp(op byte) *Builder {
+
+	b.script = append(b.script, op)
+
+	return b
+}
+
+// AddOps appends a sequence of opcodes
+func (b *Builder) AddOps(ops ...byte) *Builder {
+
+	b.script = append(b.script, ops...)
+
+	return b
+}
+
+// AddData appends the minimal push of data
+func (b *Builder) AddData(data []byte) *Builder {
+
+	if len(data) > MaxElementSize && b.err == nil {
+		b.err = ErrElementTooLarge
+	}
+
+	b.script = appendPush(b.script, data)
+
+	return b
+}
+
+// AddInt64 appends the minimal push of n as a script number
+func (b *Builder) AddInt64(n int64) *Builder {
+
+	switch {
+	case n == 0:
+		return b.AddOp(Op0)
+	case n == -1:
+		return b.AddOp(Op1Negate)
+	case n >= 1 && n <= 16:
+		return b.AddOp(SmallIntOp(int(n)))
+	}
+
+	b.script = appendPush(b.script, encodeScriptNum(n))
+
+	return b
+}
+
+// Script returns the assembled script
+func (b *Builder) Script() ([]byte, error) {
+
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	if len(b.script) > MaxScriptSize {
+		return nil, ErrScriptTooLarge
+	}
+
+	return b.script, nil
+}
+
+// appendPush appends the smallest push of data, single bytes matching a
+// small integer use the corresponding opcode
+func appendPush(script []byte, data []byte) []byte {
+
+	n := len(data)
+
+	switch {
+	case n == 0:
+		return append(script, Op0)
+	case n == 1 && data[0] >= 1 && data[0] <= 16:
+		return append(script, SmallIntOp(int(data[0])))
+	case n == 1 && data[0] == 0x81:
+		return append(script, Op1Negate)
+	case n <= 0x4b:
+		script = append(script, byte(n))
+	case n <= 0xff:
+		script = append(script, OpPushData1, byte(n))
+	case n <= 0xffff:
+		var l [2]byte
+		binary.LittleEndian.PutUint16(l[:], uint16(n))
+		script = append(append(script, OpPushData2), l[:]...)
+	default:
+		var l [4]byte
+		binary.LittleEndian.PutUint32(l[:], uint32(n))
+		script = append(append(script, OpPushData4), l[:]...)
+	}
+
+	return append(script, data...)
+}
+
+// encodeScriptNum returns the minimal little endian sign magnitude encoding
+// of n
+func encodeScriptNum(n int64) []byte {
+
+	if n == 0 {
+		return nil
+	}
+
+	neg := n < 0
+	abs := uint64(n)
+	if neg {
+		abs = uint64(-n)
+	}
+
+	var out []byte
+	for abs > 0 {
+		out = append(out, byte(abs))
+		abs >>= 8
+	}
+
+	if out[len(out)-1]&0x80 != 0 {
+		extra := byte(0x00)
+		if neg {
+			extra = 0x80
+		}
+		out = append(out, extra)
+	} else if neg {
+		out[len(out)-1] |= 0x80
+	}
+
+	return out
+}
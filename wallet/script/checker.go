@@ -0,0 +1,350 @@
+package script
+
+import (
+	"math/big"
+
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+const (
+	lockTimeThreshold = 500000000
+
+	sequenceLockTimeDisabled = 1 << 31
+	sequenceLockTimeTypeFlag = 1 << 22
+	sequenceLockTimeMask     = 0x0000ffff
+)
+
+// SignatureChecker validates signatures and time locks against the
+// transaction spending the evaluated script
+type SignatureChecker interface {
+	// CheckECDSASignature verifies a DER signature followed by its sighash
+	// byte, scriptCode is the script committed to by the digest
+	CheckECDSASignature(sig, pubKey, scriptCode []byte, version SigVersion) bool
+	// CheckSchnorrSignature verifies a BIP340 signature with an optional
+	// sighash byte against a 32 bytes public key
+	CheckSchnorrSignature(sig, pubKey []byte, version SigVersion, spend *transaction.TaprootSpend) error
+	// CheckLockTime verifies an OP_CHECKLOCKTIMEVERIFY argument
+	CheckLockTime(lockTime int64) bool
+	// CheckSequence verifies an OP_CHECKSEQUENCEVERIFY argument
+	CheckSequence(sequence int64) bool
+}
+
+// TxChecker is the SignatureChecker of an input of a transaction
+type TxChecker struct {
+	tx     *transaction.Tx
+	idx    int
+	amount int64
+	hashes *transaction.SigHashCache
+}
+
+// NewTxChecker returns the checker of input idx spending amount satoshis,
+// hashes must have been built for tx and may be shared across inputs
+func NewTxChecker(tx *transaction.Tx, idx int, amount int64, hashes *transaction.SigHashCache) *TxChecker {
+	return &TxChecker{tx: tx, idx: idx, amount: amount, hashes: hashes}
+}
+
+// CheckECDSASignature implements SignatureChecker
+func (c *TxChecker) CheckECDSASignature(sig, pubKey, scriptCode []byte, version SigVersion) bool {
+
+	if len(sig) == 0 {
+		return false
+	}
+
+	pub, err := parsePubKeyLax(pubKey)
+	if err != nil {
+		return false
+	}
+
+	hashType := transaction.SigHashType(sig[len(sig)-1])
+
+	var hash []byte
+	if version == SigVersionWitnessV0 {
+		hash, err = c.hashes.WitnessV0SigHash(c.idx, scriptCode, c.amount, hashType)
+	} else {
+		hash, err = transaction.LegacySigHash(c.tx, c.idx, scriptCode, hashType)
+	}
+	if err != nil {
+		return false
+	}
+
+	return parseDERLax(sig[:len(sig)-1]).Verify(hash, pub)
+}
+
+// CheckSchnorrSignature implements SignatureChecker
+func (c *TxChecker) CheckSchnorrSignature(sig, pubKey []byte, version SigVersion, spend *transaction.TaprootSpend) error {
+
+	hashType := transaction.SigHashDefault
+
+	switch len(sig) {
+	case 64:
+	case 65:
+		if sig[64] == byte(transaction.SigHashDefault) {
+			return ErrSchnorrSigHashType
+		}
+		hashType = transaction.SigHashType(sig[64])
+		sig = sig[:64]
+	default:
+		return ErrSchnorrSigSize
+	}
+
+	hash, err := c.hashes.TaprootSigHash(c.idx, hashType, spend)
+	switch err {
+	case nil:
+	case transaction.ErrInvalidSigHashType, transaction.ErrSigHashSingleIndex:
+		return ErrSchnorrSigHashType
+	default:
+		return err
+	}
+
+	if !secp256k1.SchnorrVerify(pubKey, hash, sig) {
+		return ErrSchnorrSig
+	}
+
+	return nil
+}
+
+// CheckLockTime implements SignatureChecker
+func (c *TxChecker) CheckLockTime(lockTime int64) bool {
+
+	txLockTime := int64(c.tx.LockTime)
+
+	// height and time locks cannot be compared
+	if (txLockTime < lockTimeThreshold) != (lockTime < lockTimeThreshold) {
+		return false
+	}
+
+	if lockTime > txLockTime {
+		return false
+	}
+
+	// a final input disables the transaction lock time
+	return c.tx.TxIn[c.idx].Sequence != transaction.MaxTxInSequenceNum
+}
+
+// CheckSequence implements SignatureChecker
+func (c *TxChecker) CheckSequence(sequence int64) bool {
+
+	txSequence := int64(c.tx.TxIn[c.idx].Sequence)
+
+	if uint32(c.tx.Version) < 2 || txSequence&sequenceLockTimeDisabled != 0 {
+		return false
+	}
+
+	const mask = sequenceLockTimeTypeFlag | sequenceLockTimeMask
+	txMasked := txSequence & mask
+	masked := sequence & mask
+
+	if (txMasked < sequenceLockTimeTypeFlag) != (masked < sequenceLockTimeTypeFlag) {
+		return false
+	}
+
+	return masked <= txMasked
+}
+
+// parsePubKeyLax parses a public key as done by consensus, hybrid keys
+// with a 0x06 or 0x07 prefix are accepted when their parity matches
+func parsePubKeyLax(b []byte) (*secp256k1.PublicKey, error) {
+
+	if len(b) == secp256k1.PubKeyUncompressedLen && (b[0] == 0x06 || b[0] == 0x07) {
+		uncompressed := append([]byte{0x04}, b[1:]...)
+
+		pub, err := secp256k1.ParsePubKey(uncompressed)
+		if err != nil || pub.Y.Bit(0) != uint(b[0]&1) {
+			return nil, secp256k1.ErrInvalidPublicKey
+		}
+
+		return pub, nil
+	}
+
+	return secp256k1.ParsePubKey(b)
+}
+
+// parseDERLax parses the loosely encoded signatures accepted before BIP66,
+// unparsable or out of range values yield a signature that never verifies
+func parseDERLax(der []byte) *secp256k1.Signature {
+
+	invalid := &secp256k1.Signature{R: new(big.Int), S: new(big.Int)}
+	pos := 0
+
+	if pos == len(der) || der[pos] != 0x30 {
+		return invalid
+	}
+	pos++
+
+	// the sequence length is skipped
+	if pos == len(der) {
+		return invalid
+	}
+	lenByte := int(der[pos])
+	pos++
+	if lenByte&0x80 != 0 {
+		lenByte -= 0x80
+		if lenByte > len(der)-pos {
+			return invalid
+		}
+		pos += lenByte
+	}
+
+	r, pos, ok := parseDERLaxInt(der, pos)
+	if !ok {
+		return invalid
+	}
+
+	s, _, ok := parseDERLaxInt(der, pos)
+	if !ok {
+		return invalid
+	}
+
+	if len(r) > 32 || len(s) > 32 {
+		return invalid
+	}
+
+	sig := &secp256k1.Signature{R: new(big.Int).SetBytes(r), S: new(big.Int).SetBytes(s)}
+	if sig.R.Cmp(secp256k1.N) >= 0 || sig.S.Cmp(secp256k1.N) >= 0 {
+		return invalid
+	}
+
+	return sig
+}
+
+// parseDERLaxInt reads an integer element returning its value without
+// leading zeros and the position following it
+func parseDERLaxInt(der []byte, pos int) ([]byte, int, bool) {
+
+	if pos == len(der) || der[pos] != 0x02 {
+		return nil, 0, false
+	}
+	pos++
+
+	if pos == len(der) {
+		return nil, 0, false
+	}
+	lenByte := int(der[pos])
+	pos++
+
+	n := lenByte
+	if lenByte&0x80 != 0 {
+		lenByte -= 0x80
+		if lenByte > len(der)-pos {
+			return nil, 0, false
+		}
+		for lenByte > 0 && der[pos] == 0 {
+			pos++
+			lenByte--
+		}
+		if lenByte >= 8 {
+			return nil, 0, false
+		}
+		n = 0
+		for ; lenByte > 0; lenByte-- {
+			n = n<<8 | int(der[pos])
+			pos++
+		}
+	}
+
+	if n > len(der)-pos {
+		return nil, 0, false
+	}
+
+	v := der[pos : pos+n]
+	for len(v) > 0 && v[0] == 0 {
+		v = v[1:]
+	}
+
+	return v, pos + n, true
+}
+
+// isValidSignatureEncoding reports whether sig is a BIP66 strict DER
+// signature followed by a sighash byte
+func isValidSignatureEncoding(sig []byte) bool {
+
+	// 0x30 [total-len] 0x02 [R-len] [R] 0x02 [S-len] [S] [sighash]
+	if len(sig) < 9 || len(sig) > 73 {
+		return false
+	}
+
+	if sig[0] != 0x30 || int(sig[1]) != len(sig)-3 {
+		return false
+	}
+
+	lenR := int(sig[3])
+	if 5+lenR >= len(sig) {
+		return false
+	}
+
+	lenS := int(sig[5+lenR])
+	if lenR+lenS+7 != len(sig) {
+		return false
+	}
+
+	if sig[2] != 0x02 || lenR == 0 || sig[4]&0x80 != 0 {
+		return false
+	}
+
+	if lenR > 1 && sig[4] == 0x00 && sig[5]&0x80 == 0 {
+		return false
+	}
+
+	if sig[lenR+4] != 0x02 || lenS == 0 || sig[lenR+6]&0x80 != 0 {
+		return false
+	}
+
+	return !(lenS > 1 && sig[lenR+6] == 0x00 && sig[lenR+7]&0x80 == 0)
+}
+
+// checkSignatureEncoding applies the DER, low S and sighash type rules
+// selected by flags, empty signatures are always accepted
+func checkSignatureEncoding(sig []byte, flags VerifyFlags) error {
+
+	if len(sig) == 0 {
+		return nil
+	}
+
+	if flags&(VerifyDERSignatures|VerifyLowS|VerifyStrictEncoding) != 0 && !isValidSignatureEncoding(sig) {
+		return ErrSigDER
+	}
+
+	if flags&VerifyLowS != 0 && !parseDERLax(sig[:len(sig)-1]).IsLowS() {
+		return ErrSigHighS
+	}
+
+	if flags&VerifyStrictEncoding != 0 {
+		base := transaction.SigHashType(sig[len(sig)-1]) &^ transaction.SigHashAnyOneCanPay
+		if base < transaction.SigHashAll || base > transaction.SigHashSingle {
+			return ErrSigHashType
+		}
+	}
+
+	return nil
+}
+
+// checkPubKeyEncoding applies the public key rules selected by flags
+func checkPubKeyEncoding(pub []byte, flags VerifyFlags, version SigVersion) error {
+
+	if flags&VerifyStrictEncoding != 0 && !isCompressedOrUncompressedPubKey(pub) {
+		return ErrPubKeyType
+	}
+
+	if flags&VerifyWitnessPubKeyType != 0 && version == SigVersionWitnessV0 && !isCompressedPubKey(pub) {
+		return ErrWitnessPubKeyType
+	}
+
+	return nil
+}
+
+func isCompressedOrUncompressedPubKey(pub []byte) bool {
+
+	switch {
+	case len(pub) == 33:
+		return pub[0] == 0x02 || pub[0] == 0x03
+	case len(pub) == 65:
+		return pub[0] == 0x04
+	}
+
+	return false
+}
+
+func isCompressedPubKey(pub []byte) bool {
+	return len(pub) == 33 && (pub[0] == 0x02 || pub[0] == 0x03)
+}
@@ -0,0 +1,899 @@
+package script
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+	"golang.org/x/crypto/ripemd160"
+)
+
+const (
+	// MaxOpsPerScript is the largest number of non push opcodes in a legacy
+	// or segwit v0 script
+	MaxOpsPerScript = 201
+	// MaxStackSize is the largest number of elements on both stacks
+	MaxStackSize = 1000
+
+	// validationWeightPerSigOp is the tapscript budget consumed by each
+	// signature check
+	validationWeightPerSigOp = 50
+	// validationWeightOffset is added to the witness size to get the
+	// tapscript budget
+	validationWeightOffset = 50
+
+	annexTag             = 0x50
+	taprootLeafMask      = 0xfe
+	taprootControlBase   = 33
+	taprootControlNode   = 32
+	taprootControlMaxLen = taprootControlBase + 128*taprootControlNode
+)
+
+// engine evaluates a single script with the rules of its signature version
+type engine struct {
+	flags   VerifyFlags
+	checker SignatureChecker
+	version SigVersion
+
+	// spend and weightLeft are only used by tapscript
+	spend      *transaction.TaprootSpend
+	weightLeft int64
+
+	opCount int
+}
+
+// eval runs script on st
+func (e *engine) eval(st *stack, script []byte) error {
+
+	legacy := e.version == SigVersionBase || e.version == SigVersionWitnessV0
+
+	if legacy && len(script) > MaxScriptSize {
+		return ErrScriptSize
+	}
+
+	ins, parseErr := Parse(script)
+
+	var alt stack
+	var cond []bool
+	codeStart := 0
+	e.opCount = 0
+	requireMinimal := e.flags&VerifyMinimalData != 0
+
+	if e.spend != nil {
+		e.spend.CodeSepPos = 0xffffffff
+	}
+
+	for pos, in := range ins {
+		op := in.Opcode
+		exec := true
+		for _, c := range cond {
+			exec = exec && c
+		}
+
+		if len(in.Data) > MaxElementSize {
+			return ErrPushSize
+		}
+
+		if legacy && op > Op16 {
+			e.opCount++
+			if e.opCount > MaxOpsPerScript {
+				return ErrOpCount
+			}
+		}
+
+		if isDisabled(op) {
+			return ErrDisabledOpcode
+		}
+
+		if op == OpCodeSeparator && e.version == SigVersionBase && e.flags&VerifyConstScriptCode != 0 {
+			return ErrOpCodeSeparator
+		}
+
+		if exec && op <= OpPushData4 {
+			if requireMinimal && !isMinimalPush(in) {
+				return ErrMinimalData
+			}
+			data := in.Data
+			if data == nil {
+				data = []byte{}
+			}
+			st.push(data)
+		} else if exec || (op >= OpIf && op <= OpEndIf) {
+			end := len(script)
+			if pos+1 < len(ins) {
+				end = ins[pos+1].Offset
+			}
+
+			var err error
+			switch op {
+			case OpIf, OpNotIf:
+				value := false
+				if exec {
+					if len(*st) < 1 {
+						return ErrUnbalancedConditional
+					}
+					top := st.top(-1)
+					if e.version == SigVersionTapscript && !isMinimalIf(top) {
+						return ErrTapscriptMinimalIf
+					}
+					if e.version == SigVersionWitnessV0 && e.flags&VerifyMinimalIf != 0 && !isMinimalIf(top) {
+						return ErrMinimalIf
+					}
+					value = asBool(top)
+					if op == OpNotIf {
+						value = !value
+					}
+					st.pop()
+				}
+				cond = append(cond, value)
+
+			case OpElse:
+				if len(cond) == 0 {
+					return ErrUnbalancedConditional
+				}
+				cond[len(cond)-1] = !cond[len(cond)-1]
+
+			case OpEndIf:
+				if len(cond) == 0 {
+					return ErrUnbalancedConditional
+				}
+				cond = cond[:len(cond)-1]
+
+			case OpCodeSeparator:
+				codeStart = end
+				if e.spend != nil {
+					e.spend.CodeSepPos = uint32(pos)
+				}
+
+			case OpCheckSig, OpCheckSigVerify:
+				err = e.opCheckSig(st, script[codeStart:], op == OpCheckSigVerify)
+
+			case OpCheckSigAdd:
+				if legacy {
+					return ErrBadOpcode
+				}
+				err = e.opCheckSigAdd(st)
+
+			case OpCheckMultiSig, OpCheckMultiSigVerify:
+				if e.version == SigVersionTapscript {
+					return ErrTapscriptCheckMultiSig
+				}
+				err = e.opCheckMultiSig(st, script[codeStart:], op == OpCheckMultiSigVerify)
+
+			case OpToAltStack:
+				if len(*st) < 1 {
+					return ErrInvalidStackOperation
+				}
+				alt.push(st.pop())
+
+			case OpFromAltStack:
+				if len(alt) < 1 {
+					return ErrInvalidAltStackOperation
+				}
+				st.push(alt.pop())
+
+			default:
+				err = e.execute(st, op, requireMinimal)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(*st)+len(alt) > MaxStackSize {
+			return ErrStackSize
+		}
+	}
+
+	if parseErr != nil {
+		return ErrBadOpcode
+	}
+
+	if len(cond) != 0 {
+		return ErrUnbalancedConditional
+	}
+
+	return nil
+}
+
+// execute runs the opcodes that only operate on the main stack
+func (e *engine) execute(st *stack, op byte, requireMinimal bool) error {
+
+	num := func(i int) (scriptNum, error) {
+		return makeScriptNum(st.top(i), requireMinimal, defaultScriptNumLen)
+	}
+
+	need := func(n int) error {
+		if len(*st) < n {
+			return ErrInvalidStackOperation
+		}
+		return nil
+	}
+
+	switch op {
+	case Op1Negate, Op1, Op2, Op3, Op4, Op5, Op6, Op7, Op8, Op9, Op10, Op11, Op12, Op13, Op14, Op15, Op16:
+		st.push(scriptNum(int(op) - int(Op1) + 1).Bytes())
+
+	case OpNop:
+
+	case OpCheckLockTimeVerify:
+		if e.flags&VerifyCheckLockTimeVerify == 0 {
+			return e.upgradableNop()
+		}
+		if err := need(1); err != nil {
+			return err
+		}
+		// five bytes allow lock times up to 2^39-1
+		lockTime, err := makeScriptNum(st.top(-1), requireMinimal, 5)
+		if err != nil {
+			return err
+		}
+		if lockTime < 0 {
+			return ErrNegativeLockTime
+		}
+		if !e.checker.CheckLockTime(int64(lockTime)) {
+			return ErrUnsatisfiedLockTime
+		}
+
+	case OpCheckSequenceVerify:
+		if e.flags&VerifyCheckSequenceVerify == 0 {
+			return e.upgradableNop()
+		}
+		if err := need(1); err != nil {
+			return err
+		}
+		sequence, err := makeScriptNum(st.top(-1), requireMinimal, 5)
+		if err != nil {
+			return err
+		}
+		if sequence < 0 {
+			return ErrNegativeLockTime
+		}
+		if sequence&sequenceLockTimeDisabled == 0 && !e.checker.CheckSequence(int64(sequence)) {
+			return ErrUnsatisfiedLockTime
+		}
+
+	case OpNop1, OpNop4, OpNop5, OpNop6, OpNop7, OpNop8, OpNop9, OpNop10:
+		return e.upgradableNop()
+
+	case OpVerify:
+		if err := need(1); err != nil {
+			return err
+		}
+		if !asBool(st.top(-1)) {
+			return ErrVerify
+		}
+		st.pop()
+
+	case OpReturn:
+		return ErrOpReturn
+
+	case Op2Drop:
+		if err := need(2); err != nil {
+			return err
+		}
+		st.pop()
+		st.pop()
+
+	case Op2Dup:
+		if err := need(2); err != nil {
+			return err
+		}
+		a, b := st.top(-2), st.top(-1)
+		st.push(a)
+		st.push(b)
+
+	case Op3Dup:
+		if err := need(3); err != nil {
+			return err
+		}
+		a, b, c := st.top(-3), st.top(-2), st.top(-1)
+		st.push(a)
+		st.push(b)
+		st.push(c)
+
+	case Op2Over:
+		if err := need(4); err != nil {
+			return err
+		}
+		a, b := st.top(-4), st.top(-3)
+		st.push(a)
+		st.push(b)
+
+	case Op2Rot:
+		if err := need(6); err != nil {
+			return err
+		}
+		a, b := st.top(-6), st.top(-5)
+		st.erase(-6)
+		st.erase(-5)
+		st.push(a)
+		st.push(b)
+
+	case Op2Swap:
+		if err := need(4); err != nil {
+			return err
+		}
+		s := *st
+		n := len(s)
+		s[n-4], s[n-2] = s[n-2], s[n-4]
+		s[n-3], s[n-1] = s[n-1], s[n-3]
+
+	case OpIfDup:
+		if err := need(1); err != nil {
+			return err
+		}
+		if top := st.top(-1); asBool(top) {
+			st.push(top)
+		}
+
+	case OpDepth:
+		st.push(scriptNum(len(*st)).Bytes())
+
+	case OpDrop:
+		if err := need(1); err != nil {
+			return err
+		}
+		st.pop()
+
+	case OpDup:
+		if err := need(1); err != nil {
+			return err
+		}
+		st.push(st.top(-1))
+
+	case OpNip:
+		if err := need(2); err != nil {
+			return err
+		}
+		st.erase(-2)
+
+	case OpOver:
+		if err := need(2); err != nil {
+			return err
+		}
+		st.push(st.top(-2))
+
+	case OpPick, OpRoll:
+		if err := need(2); err != nil {
+			return err
+		}
+		n, err := num(-1)
+		if err != nil {
+			return err
+		}
+		st.pop()
+		idx := int(n.Int32())
+		if idx < 0 || idx >= len(*st) {
+			return ErrInvalidStackOperation
+		}
+		v := st.top(-idx - 1)
+		if op == OpRoll {
+			st.erase(-idx - 1)
+		}
+		st.push(v)
+
+	case OpRot:
+		if err := need(3); err != nil {
+			return err
+		}
+		v := st.top(-3)
+		st.erase(-3)
+		st.push(v)
+
+	case OpSwap:
+		if err := need(2); err != nil {
+			return err
+		}
+		s := *st
+		n := len(s)
+		s[n-2], s[n-1] = s[n-1], s[n-2]
+
+	case OpTuck:
+		if err := need(2); err != nil {
+			return err
+		}
+		st.insert(-2, st.top(-1))
+
+	case OpSize:
+		if err := need(1); err != nil {
+			return err
+		}
+		st.push(scriptNum(len(st.top(-1))).Bytes())
+
+	case OpEqual, OpEqualVerify:
+		if err := need(2); err != nil {
+			return err
+		}
+		equal := bytes.Equal(st.pop(), st.pop())
+		st.push(fromBool(equal))
+		if op == OpEqualVerify {
+			if !equal {
+				return ErrEqualVerify
+			}
+			st.pop()
+		}
+
+	case Op1Add, Op1Sub, OpNegate, OpAbs, OpNot, Op0NotEqual:
+		if err := need(1); err != nil {
+			return err
+		}
+		n, err := num(-1)
+		if err != nil {
+			return err
+		}
+		switch op {
+		case Op1Add:
+			n++
+		case Op1Sub:
+			n--
+		case OpNegate:
+			n = -n
+		case OpAbs:
+			if n < 0 {
+				n = -n
+			}
+		case OpNot:
+			n = boolNum(n == 0)
+		case Op0NotEqual:
+			n = boolNum(n != 0)
+		}
+		st.pop()
+		st.push(n.Bytes())
+
+	case OpAdd, OpSub, OpBoolAnd, OpBoolOr, OpNumEqual, OpNumEqualVerify, OpNumNotEqual,
+		OpLessThan, OpGreaterThan, OpLessThanOrEqual, OpGreaterThanOrEqual, OpMin, OpMax:
+		if err := need(2); err != nil {
+			return err
+		}
+		a, err := num(-2)
+		if err != nil {
+			return err
+		}
+		b, err := num(-1)
+		if err != nil {
+			return err
+		}
+		var n scriptNum
+		switch op {
+		case OpAdd:
+			n = a + b
+		case OpSub:
+			n = a - b
+		case OpBoolAnd:
+			n = boolNum(a != 0 && b != 0)
+		case OpBoolOr:
+			n = boolNum(a != 0 || b != 0)
+		case OpNumEqual, OpNumEqualVerify:
+			n = boolNum(a == b)
+		case OpNumNotEqual:
+			n = boolNum(a != b)
+		case OpLessThan:
+			n = boolNum(a < b)
+		case OpGreaterThan:
+			n = boolNum(a > b)
+		case OpLessThanOrEqual:
+			n = boolNum(a <= b)
+		case OpGreaterThanOrEqual:
+			n = boolNum(a >= b)
+		case OpMin:
+			n = a
+			if b < a {
+				n = b
+			}
+		case OpMax:
+			n = a
+			if b > a {
+				n = b
+			}
+		}
+		st.pop()
+		st.pop()
+		st.push(n.Bytes())
+		if op == OpNumEqualVerify {
+			if n == 0 {
+				return ErrNumEqualVerify
+			}
+			st.pop()
+		}
+
+	case OpWithin:
+		if err := need(3); err != nil {
+			return err
+		}
+		x, err := num(-3)
+		if err != nil {
+			return err
+		}
+		min, err := num(-2)
+		if err != nil {
+			return err
+		}
+		max, err := num(-1)
+		if err != nil {
+			return err
+		}
+		st.pop()
+		st.pop()
+		st.pop()
+		st.push(fromBool(min <= x && x < max))
+
+	case OpRipemd160, OpSha1, OpSha256, OpHash160, OpHash256:
+		if err := need(1); err != nil {
+			return err
+		}
+		v := st.pop()
+		var h []byte
+		switch op {
+		case OpRipemd160:
+			r := ripemd160.New()
+			r.Write(v)
+			h = r.Sum(nil)
+		case OpSha1:
+			s := sha1.Sum(v)
+			h = s[:]
+		case OpSha256:
+			s := sha256.Sum256(v)
+			h = s[:]
+		case OpHash160:
+			h = hdwallet.Hash160(v)
+		case OpHash256:
+			h = hdwallet.DoubleSha256(v)
+		}
+		st.push(h)
+
+	default:
+		return ErrBadOpcode
+	}
+
+	return nil
+}
+
+// opCheckSig runs OP_CHECKSIG and OP_CHECKSIGVERIFY
+func (e *engine) opCheckSig(st *stack, scriptCode []byte, verify bool) error {
+
+	if len(*st) < 2 {
+		return ErrInvalidStackOperation
+	}
+
+	sig, pub := st.top(-2), st.top(-1)
+
+	ok, err := e.checkSig(sig, pub, scriptCode)
+	if err != nil {
+		return err
+	}
+
+	st.pop()
+	st.pop()
+	st.push(fromBool(ok))
+
+	if verify {
+		if !ok {
+			return ErrCheckSigVerify
+		}
+		st.pop()
+	}
+
+	return nil
+}
+
+// opCheckSigAdd runs the BIP342 OP_CHECKSIGADD
+func (e *engine) opCheckSigAdd(st *stack) error {
+
+	if len(*st) < 3 {
+		return ErrInvalidStackOperation
+	}
+
+	sig, pub := st.top(-3), st.top(-1)
+
+	n, err := makeScriptNum(st.top(-2), e.flags&VerifyMinimalData != 0, defaultScriptNumLen)
+	if err != nil {
+		return err
+	}
+
+	ok, err := e.checkSig(sig, pub, nil)
+	if err != nil {
+		return err
+	}
+
+	st.pop()
+	st.pop()
+	st.pop()
+	if ok {
+		n++
+	}
+	st.push(n.Bytes())
+
+	return nil
+}
+
+// checkSig verifies a single signature with the rules of the signature
+// version, a failed signature is only an error when policy requires it
+func (e *engine) checkSig(sig, pub, scriptCode []byte) (bool, error) {
+
+	if e.version == SigVersionTapscript {
+		return e.checkSigTapscript(sig, pub)
+	}
+
+	if e.version == SigVersionBase {
+		var found bool
+		scriptCode, found = findAndDelete(scriptCode, pushOf(sig))
+		if found && e.flags&VerifyConstScriptCode != 0 {
+			return false, ErrSigFindAndDelete
+		}
+	}
+
+	if err := checkSignatureEncoding(sig, e.flags); err != nil {
+		return false, err
+	}
+
+	if err := checkPubKeyEncoding(pub, e.flags, e.version); err != nil {
+		return false, err
+	}
+
+	ok := e.checker.CheckECDSASignature(sig, pub, scriptCode, e.version)
+	if !ok && e.flags&VerifyNullFail != 0 && len(sig) != 0 {
+		return false, ErrNullFail
+	}
+
+	return ok, nil
+}
+
+// checkSigTapscript verifies a BIP340 signature, an empty signature is a
+// failed check and every other signature must be valid
+func (e *engine) checkSigTapscript(sig, pub []byte) (bool, error) {
+
+	success := len(sig) != 0
+	if success {
+		e.weightLeft -= validationWeightPerSigOp
+		if e.weightLeft < 0 {
+			return false, ErrTapscriptValidationWeight
+		}
+	}
+
+	switch len(pub) {
+	case 0:
+		return false, ErrPubKeyType
+	case 32:
+		if success {
+			if err := e.checker.CheckSchnorrSignature(sig, pub, SigVersionTapscript, e.spend); err != nil {
+				return false, err
+			}
+		}
+	default:
+		if e.flags&VerifyDiscourageUpgradablePubKeyType != 0 {
+			return false, ErrDiscourageUpgradablePubKey
+		}
+	}
+
+	return success, nil
+}
+
+// opCheckMultiSig runs OP_CHECKMULTISIG and OP_CHECKMULTISIGVERIFY, each
+// key counts as an operation
+func (e *engine) opCheckMultiSig(st *stack, scriptCode []byte, verify bool) error {
+
+	requireMinimal := e.flags&VerifyMinimalData != 0
+
+	i := 1
+	if len(*st) < i {
+		return ErrInvalidStackOperation
+	}
+
+	n, err := makeScriptNum(st.top(-i), requireMinimal, defaultScriptNumLen)
+	if err != nil {
+		return err
+	}
+	keyCount := int(n.Int32())
+	if keyCount < 0 || keyCount > MaxPubKeysPerMultiSig {
+		return ErrPubKeyCount
+	}
+	e.opCount += keyCount
+	if e.opCount > MaxOpsPerScript {
+		return ErrOpCount
+	}
+
+	i++
+	ikey := i
+	// ikey2 is the position of the last non signature item on the stack,
+	// used to apply NULLFAIL to the signatures only
+	ikey2 := keyCount + 2
+	i += keyCount
+	if len(*st) < i {
+		return ErrInvalidStackOperation
+	}
+
+	n, err = makeScriptNum(st.top(-i), requireMinimal, defaultScriptNumLen)
+	if err != nil {
+		return err
+	}
+	sigCount := int(n.Int32())
+	if sigCount < 0 || sigCount > keyCount {
+		return ErrSigCount
+	}
+
+	i++
+	isig := i
+	i += sigCount
+	if len(*st) < i {
+		return ErrInvalidStackOperation
+	}
+
+	if e.version == SigVersionBase {
+		for k := 0; k < sigCount; k++ {
+			var found bool
+			scriptCode, found = findAndDelete(scriptCode, pushOf(st.top(-isig-k)))
+			if found && e.flags&VerifyConstScriptCode != 0 {
+				return ErrSigFindAndDelete
+			}
+		}
+	}
+
+	success := true
+	for success && sigCount > 0 {
+		sig, pub := st.top(-isig), st.top(-ikey)
+
+		if err := checkSignatureEncoding(sig, e.flags); err != nil {
+			return err
+		}
+		if err := checkPubKeyEncoding(pub, e.flags, e.version); err != nil {
+			return err
+		}
+
+		if e.checker.CheckECDSASignature(sig, pub, scriptCode, e.version) {
+			isig++
+			sigCount--
+		}
+		ikey++
+		keyCount--
+
+		// not enough keys left for the remaining signatures
+		if sigCount > keyCount {
+			success = false
+		}
+	}
+
+	for ; i > 1; i-- {
+		if !success && e.flags&VerifyNullFail != 0 && ikey2 == 0 && len(st.top(-1)) != 0 {
+			return ErrNullFail
+		}
+		if ikey2 > 0 {
+			ikey2--
+		}
+		st.pop()
+	}
+
+	// the extra element consumed because of an off by one in the original
+	// implementation
+	if len(*st) < 1 {
+		return ErrInvalidStackOperation
+	}
+	if e.flags&VerifyNullDummy != 0 && len(st.top(-1)) != 0 {
+		return ErrSigNullDummy
+	}
+	st.pop()
+
+	st.push(fromBool(success))
+
+	if verify {
+		if !success {
+			return ErrCheckMultiSigVerify
+		}
+		st.pop()
+	}
+
+	return nil
+}
+
+func (e *engine) upgradableNop() error {
+
+	if e.flags&VerifyDiscourageUpgradableNops != 0 {
+		return ErrDiscourageUpgradableNops
+	}
+
+	return nil
+}
+
+func isDisabled(op byte) bool {
+
+	switch op {
+	case OpCat, OpSubStr, OpLeft, OpRight, OpInvert, OpAnd, OpOr, OpXor,
+		Op2Mul, Op2Div, OpMul, OpDiv, OpMod, OpLShift, OpRShift:
+		return true
+	}
+
+	return false
+}
+
+// isOpSuccess reports whether op is one of the BIP342 OP_SUCCESSx opcodes
+func isOpSuccess(op byte) bool {
+	return op == 80 || op == 98 || (op >= 126 && op <= 129) ||
+		(op >= 131 && op <= 134) || (op >= 137 && op <= 138) ||
+		(op >= 141 && op <= 142) || (op >= 149 && op <= 153) ||
+		(op >= 187 && op <= 254)
+}
+
+// isMinimalPush reports whether a push uses the smallest possible opcode
+func isMinimalPush(in Instruction) bool {
+
+	data := in.Data
+	n := len(data)
+
+	switch {
+	case n == 0:
+		return in.Opcode == Op0
+	case n == 1 && data[0] >= 1 && data[0] <= 16:
+		return false
+	case n == 1 && data[0] == 0x81:
+		return false
+	case n <= 0x4b:
+		return int(in.Opcode) == n
+	case n <= 0xff:
+		return in.Opcode == OpPushData1
+	case n <= 0xffff:
+		return in.Opcode == OpPushData2
+	}
+
+	return true
+}
+
+func isMinimalIf(b []byte) bool {
+	return len(b) == 0 || (len(b) == 1 && b[0] == 1)
+}
+
+func boolNum(v bool) scriptNum {
+
+	if v {
+		return 1
+	}
+
+	return 0
+}
+
+// pushOf returns the push of data as serialized by the reference client,
+// without the small integer opcodes
+func pushOf(data []byte) []byte {
+
+	if len(data) == 1 && (data[0] >= 1 && data[0] <= 16 || data[0] == 0x81) {
+		return append([]byte{1}, data...)
+	}
+
+	return appendPush(nil, data)
+}
+
+// findAndDelete removes every occurrence of the serialized push sig that
+// starts on an opcode boundary, parsing resumes after each removed match
+func findAndDelete(script, sig []byte) ([]byte, bool) {
+
+	if len(sig) == 0 {
+		return script, false
+	}
+
+	out := make([]byte, 0, len(script))
+	found := false
+	pc, kept := 0, 0
+
+	for {
+		out = append(out, script[kept:pc]...)
+		for len(script)-pc >= len(sig) && bytes.Equal(script[pc:pc+len(sig)], sig) {
+			pc += len(sig)
+			found = true
+		}
+		kept = pc
+
+		n, ok := opSize(script[pc:])
+		if !ok {
+			break
+		}
+		pc += n
+	}
+
+	if !found {
+		return script, false
+	}
+
+	return append(out, script[kept:]...), true
+}
@@ -0,0 +1,250 @@
+package script
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+// referenceErrors maps each error name of the reference client tests to the
+// engine error, UNKNOWN_ERROR is raised for invalid script numbers
+var referenceErrors = map[string]error{
+	"EVAL_FALSE":                            ErrEvalFalse,
+	"OP_RETURN":                             ErrOpReturn,
+	"SCRIPT_SIZE":                           ErrScriptSize,
+	"PUSH_SIZE":                             ErrPushSize,
+	"OP_COUNT":                              ErrOpCount,
+	"STACK_SIZE":                            ErrStackSize,
+	"SIG_COUNT":                             ErrSigCount,
+	"PUBKEY_COUNT":                          ErrPubKeyCount,
+	"VERIFY":                                ErrVerify,
+	"EQUALVERIFY":                           ErrEqualVerify,
+	"CHECKMULTISIGVERIFY":                   ErrCheckMultiSigVerify,
+	"CHECKSIGVERIFY":                        ErrCheckSigVerify,
+	"NUMEQUALVERIFY":                        ErrNumEqualVerify,
+	"BAD_OPCODE":                            ErrBadOpcode,
+	"DISABLED_OPCODE":                       ErrDisabledOpcode,
+	"INVALID_STACK_OPERATION":               ErrInvalidStackOperation,
+	"INVALID_ALTSTACK_OPERATION":            ErrInvalidAltStackOperation,
+	"UNBALANCED_CONDITIONAL":                ErrUnbalancedConditional,
+	"NEGATIVE_LOCKTIME":                     ErrNegativeLockTime,
+	"UNSATISFIED_LOCKTIME":                  ErrUnsatisfiedLockTime,
+	"SIG_HASHTYPE":                          ErrSigHashType,
+	"SIG_DER":                               ErrSigDER,
+	"MINIMALDATA":                           ErrMinimalData,
+	"SIG_PUSHONLY":                          ErrSigPushOnly,
+	"SIG_HIGH_S":                            ErrSigHighS,
+	"SIG_NULLDUMMY":                         ErrSigNullDummy,
+	"PUBKEYTYPE":                            ErrPubKeyType,
+	"CLEANSTACK":                            ErrCleanStack,
+	"MINIMALIF":                             ErrMinimalIf,
+	"NULLFAIL":                              ErrNullFail,
+	"DISCOURAGE_UPGRADABLE_NOPS":            ErrDiscourageUpgradableNops,
+	"DISCOURAGE_UPGRADABLE_WITNESS_PROGRAM": ErrDiscourageUpgradableWitness,
+	"WITNESS_PROGRAM_WRONG_LENGTH":          ErrWitnessProgramWrongLength,
+	"WITNESS_PROGRAM_WITNESS_EMPTY":         ErrWitnessProgramWitnessEmpty,
+	"WITNESS_PROGRAM_MISMATCH":              ErrWitnessProgramMismatch,
+	"WITNESS_MALLEATED":                     ErrWitnessMalleated,
+	"WITNESS_MALLEATED_P2SH":                ErrWitnessMalleatedP2SH,
+	"WITNESS_UNEXPECTED":                    ErrWitnessUnexpected,
+	"WITNESS_PUBKEYTYPE":                    ErrWitnessPubKeyType,
+	"UNKNOWN_ERROR":                         ErrScriptNum,
+}
+
+var referenceFlags = map[string]VerifyFlags{
+	"":                                      0,
+	"NONE":                                  0,
+	"P2SH":                                  VerifyP2SH,
+	"STRICTENC":                             VerifyStrictEncoding,
+	"DERSIG":                                VerifyDERSignatures,
+	"LOW_S":                                 VerifyLowS,
+	"NULLDUMMY":                             VerifyNullDummy,
+	"SIGPUSHONLY":                           VerifySigPushOnly,
+	"MINIMALDATA":                           VerifyMinimalData,
+	"DISCOURAGE_UPGRADABLE_NOPS":            VerifyDiscourageUpgradableNops,
+	"CLEANSTACK":                            VerifyCleanStack,
+	"CHECKLOCKTIMEVERIFY":                   VerifyCheckLockTimeVerify,
+	"CHECKSEQUENCEVERIFY":                   VerifyCheckSequenceVerify,
+	"WITNESS":                               VerifyWitness,
+	"DISCOURAGE_UPGRADABLE_WITNESS_PROGRAM": VerifyDiscourageUpgradableWitnessProgram,
+	"MINIMALIF":                             VerifyMinimalIf,
+	"NULLFAIL":                              VerifyNullFail,
+	"WITNESS_PUBKEYTYPE":                    VerifyWitnessPubKeyType,
+}
+
+func parseFlags(t *testing.T, s string) VerifyFlags {
+
+	var flags VerifyFlags
+	for _, name := range strings.Split(s, ",") {
+		f, ok := referenceFlags[name]
+		if !ok {
+			t.Fatalf("unknown flag %s", name)
+		}
+		flags |= f
+	}
+
+	return flags
+}
+
+// parseShortForm assembles the script notation of the reference tests
+func parseShortForm(t *testing.T, s string) []byte {
+
+	ops := make(map[string]byte)
+	for op, name := range opcodeNames {
+		if op < OpNop && op != OpReserved {
+			continue
+		}
+		ops[name] = op
+		ops[strings.TrimPrefix(name, "OP_")] = op
+	}
+	ops["NOP2"] = OpCheckLockTimeVerify
+	ops["NOP3"] = OpCheckSequenceVerify
+
+	var script []byte
+	for _, tok := range strings.Fields(s) {
+		if n, err := strconv.ParseInt(tok, 10, 64); err == nil {
+			b := NewBuilder().AddInt64(n)
+			script = append(script, b.script...)
+			continue
+		}
+
+		if strings.HasPrefix(tok, "0x") {
+			raw, err := hex.DecodeString(tok[2:])
+			if err != nil {
+				t.Fatalf("bad hex %s", tok)
+			}
+			script = append(script, raw...)
+			continue
+		}
+
+		if len(tok) >= 2 && tok[0] == '\'' && tok[len(tok)-1] == '\'' {
+			script = append(script, pushOf([]byte(tok[1:len(tok)-1]))...)
+			continue
+		}
+
+		op, ok := ops[tok]
+		if !ok {
+			t.Fatalf("unknown token %s", tok)
+		}
+		script = append(script, op)
+	}
+
+	return script
+}
+
+// spendingTx returns the transaction spending the single output of a
+// coinbase paying to scriptPubKey, as built by the reference tests
+func spendingTx(scriptSig, scriptPubKey []byte, witness transaction.Witness, amount int64) (*transaction.Tx, *transaction.TxOut) {
+
+	credit := transaction.NewTx(1)
+	credit.AddTxIn(transaction.NewTxIn(transaction.NewOutPoint(&transaction.Hash{}, transaction.MaxPrevOutIndex), []byte{Op0, Op0}, nil))
+	credit.AddTxOut(transaction.NewTxOut(amount, scriptPubKey))
+
+	hash := credit.TxHash()
+
+	spend := transaction.NewTx(1)
+	spend.AddTxIn(transaction.NewTxIn(transaction.NewOutPoint(&hash, 0), scriptSig, witness))
+	spend.AddTxOut(transaction.NewTxOut(amount, nil))
+
+	return spend, credit.TxOut[0]
+}
+
+func TestScriptReference(t *testing.T) {
+	data, err := os.ReadFile("testdata/script_tests.json")
+	assert.NoError(t, err)
+
+	var tests [][]interface{}
+	assert.NoError(t, json.Unmarshal(data, &tests))
+
+	for _, test := range tests {
+		if len(test) < 4 {
+			continue
+		}
+
+		var witness transaction.Witness
+		var amount int64
+
+		if items, ok := test[0].([]interface{}); ok {
+			for _, item := range items[:len(items)-1] {
+				raw, _ := hex.DecodeString(item.(string))
+				witness = append(witness, raw)
+			}
+			amount = int64(math.Round(items[len(items)-1].(float64) * 1e8))
+			test = test[1:]
+		}
+
+		scriptSig := parseShortForm(t, test[0].(string))
+		scriptPubKey := parseShortForm(t, test[1].(string))
+		flags := parseFlags(t, test[2].(string))
+		expected := test[3].(string)
+
+		tx, prev := spendingTx(scriptSig, scriptPubKey, witness, amount)
+		prevOuts := transaction.PrevOutputMap{tx.TxIn[0].PreviousOutPoint: prev}
+		checker := NewTxChecker(tx, 0, amount, transaction.NewSigHashCache(tx, prevOuts))
+
+		err := VerifyScript(scriptSig, scriptPubKey, witness, flags, checker)
+
+		if expected == "OK" {
+			assert.NoError(t, err, "%v", test)
+			continue
+		}
+
+		assert.True(t, errors.Is(err, referenceErrors[expected]), "%v: %v", test, err)
+	}
+}
+
+func TestTxReference(t *testing.T) {
+	for _, file := range []string{"testdata/tx_valid.json", "testdata/tx_invalid.json"} {
+		valid := file == "testdata/tx_valid.json"
+
+		data, err := os.ReadFile(file)
+		assert.NoError(t, err)
+
+		var tests [][]interface{}
+		assert.NoError(t, json.Unmarshal(data, &tests))
+
+		for _, test := range tests {
+			inputs, ok := test[0].([]interface{})
+			if !ok {
+				continue
+			}
+
+			raw, _ := hex.DecodeString(test[1].(string))
+			flags := parseFlags(t, test[2].(string))
+
+			tx, err := transaction.NewTxFromBytes(raw)
+			if err == nil {
+				prevOuts := make(transaction.PrevOutputMap)
+				for _, input := range inputs {
+					in := input.([]interface{})
+					hash, _ := transaction.NewHashFromStr(in[0].(string))
+					idx := uint32(int64(in[1].(float64)))
+
+					var amount int64
+					if len(in) > 3 {
+						amount = int64(in[3].(float64))
+					}
+
+					op := transaction.NewOutPoint(&hash, idx)
+					prevOuts[*op] = transaction.NewTxOut(amount, parseShortForm(t, in[2].(string)))
+				}
+
+				err = VerifyTx(tx, prevOuts, flags)
+			}
+
+			if valid {
+				assert.NoError(t, err, "%v", test)
+			} else {
+				assert.Error(t, err, "%v", test)
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Script evaluation errors, they mirror the error codes of the reference
+// client so that failures can be matched against its test vectors
+var (
+	ErrEvalFalse                   = errors.New("script: script evaluated without error but finished with a false or empty top stack element")
+	ErrOpReturn                    = errors.New("script: OP_RETURN was encountered")
+	ErrScriptSize                  = errors.New("script: script is too big")
+	ErrPushSize                    = errors.New("script: push value size limit exceeded")
+	ErrOpCount                     = errors.New("script: operation limit exceeded")
+	ErrStackSize                   = errors.New("script: stack size limit exceeded")
+	ErrSigCount                    = errors.New("script: signature count negative or greater than pubkey count")
+	ErrPubKeyCount                 = errors.New("script: pubkey count negative or limit exceeded")
+	ErrVerify                      = errors.New("script: script failed an OP_VERIFY operation")
+	ErrEqualVerify                 = errors.New("script: script failed an OP_EQUALVERIFY operation")
+	ErrCheckMultiSigVerify         = errors.New("script: script failed an OP_CHECKMULTISIGVERIFY operation")
+	ErrCheckSigVerify              = errors.New("script: script failed an OP_CHECKSIGVERIFY operation")
+	ErrNumEqualVerify              = errors.New("script: script failed an OP_NUMEQUALVERIFY operation")
+	ErrBadOpcode                   = errors.New("script: opcode missing or not understood")
+	ErrDisabledOpcode              = errors.New("script: attempted to use a disabled opcode")
+	ErrInvalidStackOperation       = errors.New("script: operation not valid with the current stack size")
+	ErrInvalidAltStackOperation    = errors.New("script: operation not valid with the current altstack size")
+	ErrUnbalancedConditional       = errors.New("script: invalid OP_IF construction")
+	ErrNegativeLockTime            = errors.New("script: negative locktime")
+	ErrUnsatisfiedLockTime         = errors.New("script: locktime requirement not satisfied")
+	ErrSigHashType                 = errors.New("script: signature hash type missing or not understood")
+	ErrSigDER                      = errors.New("script: non-canonical DER signature")
+	ErrMinimalData                 = errors.New("script: data push larger than necessary")
+	ErrSigPushOnly                 = errors.New("script: only push operators allowed in signatures")
+	ErrSigHighS                    = errors.New("script: non-canonical signature, S value is unnecessarily high")
+	ErrSigNullDummy                = errors.New("script: dummy CHECKMULTISIG argument must be zero")
+	ErrPubKeyType                  = errors.New("script: public key is neither compressed or uncompressed")
+	ErrCleanStack                  = errors.New("script: stack size must be exactly one after execution")
+	ErrMinimalIf                   = errors.New("script: OP_IF/NOTIF argument must be minimal")
+	ErrNullFail                    = errors.New("script: signature must be zero for failed CHECK(MULTI)SIG operation")
+	ErrDiscourageUpgradableNops    = errors.New("script: NOPx reserved for soft-fork upgrades")
+	ErrDiscourageUpgradableWitness = errors.New("script: witness version reserved for soft-fork upgrades")
+	ErrDiscourageUpgradableTaproot = errors.New("script: taproot version reserved for soft-fork upgrades")
+	ErrDiscourageOpSuccess         = errors.New("script: OP_SUCCESSx reserved for soft-fork upgrades")
+	ErrDiscourageUpgradablePubKey  = errors.New("script: public key version reserved for soft-fork upgrades")
+	ErrWitnessProgramWrongLength   = errors.New("script: witness program has incorrect length")
+	ErrWitnessProgramWitnessEmpty  = errors.New("script: witness program was passed an empty witness")
+	ErrWitnessProgramMismatch      = errors.New("script: witness program hash mismatch")
+	ErrWitnessMalleated            = errors.New("script: witness requires empty scriptSig")
+	ErrWitnessMalleatedP2SH        = errors.New("script: witness requires only-redeemscript scriptSig")
+	ErrWitnessUnexpected           = errors.New("script: witness provided for non-witness script")
+	ErrWitnessPubKeyType           = errors.New("script: using non-compressed keys in segwit")
+	ErrSchnorrSigSize              = errors.New("script: invalid schnorr signature size")
+	ErrSchnorrSigHashType          = errors.New("script: invalid schnorr signature hash type")
+	ErrSchnorrSig                  = errors.New("script: invalid schnorr signature")
+	ErrTaprootWrongControlSize     = errors.New("script: invalid taproot control block size")
+	ErrTapscriptValidationWeight   = errors.New("script: too much signature validation relative to witness weight")
+	ErrTapscriptCheckMultiSig      = errors.New("script: OP_CHECKMULTISIG(VERIFY) is not available in tapscript")
+	ErrTapscriptMinimalIf          = errors.New("script: OP_IF/NOTIF argument must be minimal in tapscript")
+	ErrOpCodeSeparator             = errors.New("script: using OP_CODESEPARATOR in non-witness script")
+	ErrSigFindAndDelete            = errors.New("script: signature is found in scriptCode")
+	ErrScriptNum                   = errors.New("script: invalid script number")
+	ErrScriptNumOverflow           = fmt.Errorf("%w: overflow", ErrScriptNum)
+	ErrScriptNumNonMinimal         = fmt.Errorf("%w: non-minimal encoding", ErrScriptNum)
+	ErrMissingSpentOutput          = errors.New("script: spent output not found")
+	ErrInputIndex                  = errors.New("script: input index out of range")
+)
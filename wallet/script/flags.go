@@ -0,0 +1,80 @@
+package script
+
+// VerifyFlags selects the consensus and policy rules enforced by the
+// interpreter
+type VerifyFlags uint32
+
+const (
+	// VerifyP2SH evaluates BIP16 redeem scripts
+	VerifyP2SH VerifyFlags = 1 << iota
+	// VerifyStrictEncoding requires strict signature and public key
+	// encodings
+	VerifyStrictEncoding
+	// VerifyDERSignatures requires BIP66 strict DER signatures
+	VerifyDERSignatures
+	// VerifyLowS requires signatures with a low S value
+	VerifyLowS
+	// VerifyNullDummy requires the BIP147 CHECKMULTISIG dummy to be empty
+	VerifyNullDummy
+	// VerifySigPushOnly requires signature scripts to only push data
+	VerifySigPushOnly
+	// VerifyMinimalData requires minimal pushes and numbers
+	VerifyMinimalData
+	// VerifyDiscourageUpgradableNops fails on the reserved NOP opcodes
+	VerifyDiscourageUpgradableNops
+	// VerifyCleanStack requires a single stack element after evaluation
+	VerifyCleanStack
+	// VerifyCheckLockTimeVerify enables BIP65 OP_CHECKLOCKTIMEVERIFY
+	VerifyCheckLockTimeVerify
+	// VerifyCheckSequenceVerify enables BIP112 OP_CHECKSEQUENCEVERIFY
+	VerifyCheckSequenceVerify
+	// VerifyWitness evaluates BIP141 witness programs
+	VerifyWitness
+	// VerifyDiscourageUpgradableWitnessProgram fails on unknown witness
+	// versions
+	VerifyDiscourageUpgradableWitnessProgram
+	// VerifyMinimalIf requires minimal OP_IF arguments in segwit v0
+	VerifyMinimalIf
+	// VerifyNullFail requires failed signatures to be empty
+	VerifyNullFail
+	// VerifyWitnessPubKeyType requires compressed keys in segwit v0
+	VerifyWitnessPubKeyType
+	// VerifyConstScriptCode fails on OP_CODESEPARATOR and signatures found
+	// in legacy scripts
+	VerifyConstScriptCode
+	// VerifyTaproot evaluates BIP341 and BIP342 spends
+	VerifyTaproot
+	// VerifyDiscourageUpgradableTaprootVersion fails on unknown leaf
+	// versions
+	VerifyDiscourageUpgradableTaprootVersion
+	// VerifyDiscourageOpSuccess fails on OP_SUCCESSx opcodes
+	VerifyDiscourageOpSuccess
+	// VerifyDiscourageUpgradablePubKeyType fails on unknown tapscript
+	// public key types
+	VerifyDiscourageUpgradablePubKeyType
+)
+
+// MandatoryVerifyFlags are the consensus rules active on the main chain
+const MandatoryVerifyFlags = VerifyP2SH | VerifyDERSignatures | VerifyNullDummy |
+	VerifyCheckLockTimeVerify | VerifyCheckSequenceVerify | VerifyWitness | VerifyTaproot
+
+// StandardVerifyFlags are the rules enforced by the reference client when
+// relaying transactions
+const StandardVerifyFlags = MandatoryVerifyFlags | VerifyStrictEncoding | VerifyMinimalData |
+	VerifyDiscourageUpgradableNops | VerifyCleanStack | VerifyDiscourageUpgradableWitnessProgram |
+	VerifyLowS | VerifyMinimalIf | VerifyNullFail | VerifyWitnessPubKeyType | VerifyConstScriptCode |
+	VerifyDiscourageUpgradableTaprootVersion | VerifyDiscourageOpSuccess | VerifyDiscourageUpgradablePubKeyType
+
+// SigVersion is the signature scheme of the script being evaluated
+type SigVersion int
+
+const (
+	// SigVersionBase is used by legacy and P2SH scripts
+	SigVersionBase SigVersion = iota
+	// SigVersionWitnessV0 is used by BIP143 witness scripts
+	SigVersionWitnessV0
+	// SigVersionTaproot is used by BIP341 key path spends
+	SigVersionTaproot
+	// SigVersionTapscript is used by BIP342 script path spends
+	SigVersionTapscript
+)
@@ -0,0 +1,253 @@
+package script
+
+import "fmt"
+
+// Script opcodes, 0x01 to 0x4b push the next N bytes
+const (
+	Op0                   byte = 0x00
+	OpFalse               byte = 0x00
+	OpPushData1           byte = 0x4c
+	OpPushData2           byte = 0x4d
+	OpPushData4           byte = 0x4e
+	Op1Negate             byte = 0x4f
+	OpReserved            byte = 0x50
+	Op1                   byte = 0x51
+	OpTrue                byte = 0x51
+	Op2                   byte = 0x52
+	Op3                   byte = 0x53
+	Op4                   byte = 0x54
+	Op5                   byte = 0x55
+	Op6                   byte = 0x56
+	Op7                   byte = 0x57
+	Op8                   byte = 0x58
+	Op9                   byte = 0x59
+	Op10                  byte = 0x5a
+	Op11                  byte = 0x5b
+	Op12                  byte = 0x5c
+	Op13                  byte = 0x5d
+	Op14                  byte = 0x5e
+	Op15                  byte = 0x5f
+	Op16                  byte = 0x60
+	OpNop                 byte = 0x61
+	OpVer                 byte = 0x62
+	OpIf                  byte = 0x63
+	OpNotIf               byte = 0x64
+	OpVerIf               byte = 0x65
+	OpVerNotIf            byte = 0x66
+	OpElse                byte = 0x67
+	OpEndIf               byte = 0x68
+	OpVerify              byte = 0x69
+	OpReturn              byte = 0x6a
+	OpToAltStack          byte = 0x6b
+	OpFromAltStack        byte = 0x6c
+	Op2Drop               byte = 0x6d
+	Op2Dup                byte = 0x6e
+	Op3Dup                byte = 0x6f
+	Op2Over               byte = 0x70
+	Op2Rot                byte = 0x71
+	Op2Swap               byte = 0x72
+	OpIfDup               byte = 0x73
+	OpDepth               byte = 0x74
+	OpDrop                byte = 0x75
+	OpDup                 byte = 0x76
+	OpNip                 byte = 0x77
+	OpOver                byte = 0x78
+	OpPick                byte = 0x79
+	OpRoll                byte = 0x7a
+	OpRot                 byte = 0x7b
+	OpSwap                byte = 0x7c
+	OpTuck                byte = 0x7d
+	OpCat                 byte = 0x7e
+	OpSubStr              byte = 0x7f
+	OpLeft                byte = 0x80
+	OpRight               byte = 0x81
+	OpSize                byte = 0x82
+	OpInvert              byte = 0x83
+	OpAnd                 byte = 0x84
+	OpOr                  byte = 0x85
+	OpXor                 byte = 0x86
+	OpEqual               byte = 0x87
+	OpEqualVerify         byte = 0x88
+	OpReserved1           byte = 0x89
+	OpReserved2           byte = 0x8a
+	Op1Add                byte = 0x8b
+	Op1Sub                byte = 0x8c
+	Op2Mul                byte = 0x8d
+	Op2Div                byte = 0x8e
+	OpNegate              byte = 0x8f
+	OpAbs                 byte = 0x90
+	OpNot                 byte = 0x91
+	Op0NotEqual           byte = 0x92
+	OpAdd                 byte = 0x93
+	OpSub                 byte = 0x94
+	OpMul                 byte = 0x95
+	OpDiv                 byte = 0x96
+	OpMod                 byte = 0x97
+	OpLShift              byte = 0x98
+	OpRShift              byte = 0x99
+	OpBoolAnd             byte = 0x9a
+	OpBoolOr              byte = 0x9b
+	OpNumEqual            byte = 0x9c
+	OpNumEqualVerify      byte = 0x9d
+	OpNumNotEqual         byte = 0x9e
+	OpLessThan            byte = 0x9f
+	OpGreaterThan         byte = 0xa0
+	OpLessThanOrEqual     byte = 0xa1
+	OpGreaterThanOrEqual  byte = 0xa2
+	OpMin                 byte = 0xa3
+	OpMax                 byte = 0xa4
+	OpWithin              byte = 0xa5
+	OpRipemd160           byte = 0xa6
+	OpSha1                byte = 0xa7
+	OpSha256              byte = 0xa8
+	OpHash160             byte = 0xa9
+	OpHash256             byte = 0xaa
+	OpCodeSeparator       byte = 0xab
+	OpCheckSig            byte = 0xac
+	OpCheckSigVerify      byte = 0xad
+	OpCheckMultiSig       byte = 0xae
+	OpCheckMultiSigVerify byte = 0xaf
+	OpNop1                byte = 0xb0
+	OpCheckLockTimeVerify byte = 0xb1
+	OpCheckSequenceVerify byte = 0xb2
+	OpNop4                byte = 0xb3
+	OpNop5                byte = 0xb4
+	OpNop6                byte = 0xb5
+	OpNop7                byte = 0xb6
+	OpNop8                byte = 0xb7
+	OpNop9                byte = 0xb8
+	OpNop10               byte = 0xb9
+	OpCheckSigAdd         byte = 0xba
+	OpInvalidOpcode       byte = 0xff
+)
+
+var opcodeNames = map[byte]string{
+	Op0:                   "OP_0",
+	OpPushData1:           "OP_PUSHDATA1",
+	OpPushData2:           "OP_PUSHDATA2",
+	OpPushData4:           "OP_PUSHDATA4",
+	Op1Negate:             "OP_1NEGATE",
+	OpReserved:            "OP_RESERVED",
+	Op1:                   "OP_1",
+	Op2:                   "OP_2",
+	Op3:                   "OP_3",
+	Op4:                   "OP_4",
+	Op5:                   "OP_5",
+	Op6:                   "OP_6",
+	Op7:                   "OP_7",
+	Op8:                   "OP_8",
+	Op9:                   "OP_9",
+	Op10:                  "OP_10",
+	Op11:                  "OP_11",
+	Op12:                  "OP_12",
+	Op13:                  "OP_13",
+	Op14:                  "OP_14",
+	Op15:                  "OP_15",
+	Op16:                  "OP_16",
+	OpNop:                 "OP_NOP",
+	OpVer:                 "OP_VER",
+	OpIf:                  "OP_IF",
+	OpNotIf:               "OP_NOTIF",
+	OpVerIf:               "OP_VERIF",
+	OpVerNotIf:            "OP_VERNOTIF",
+	OpElse:                "OP_ELSE",
+	OpEndIf:               "OP_ENDIF",
+	OpVerify:              "OP_VERIFY",
+	OpReturn:              "OP_RETURN",
+	OpToAltStack:          "OP_TOALTSTACK",
+	OpFromAltStack:        "OP_FROMALTSTACK",
+	Op2Drop:               "OP_2DROP",
+	Op2Dup:                "OP_2DUP",
+	Op3Dup:                "OP_3DUP",
+	Op2Over:               "OP_2OVER",
+	Op2Rot:                "OP_2ROT",
+	Op2Swap:               "OP_2SWAP",
+	OpIfDup:               "OP_IFDUP",
+	OpDepth:               "OP_DEPTH",
+	OpDrop:                "OP_DROP",
+	OpDup:                 "OP_DUP",
+	OpNip:                 "OP_NIP",
+	OpOver:                "OP_OVER",
+	OpPick:                "OP_PICK",
+	OpRoll:                "OP_ROLL",
+	OpRot:                 "OP_ROT",
+	OpSwap:                "OP_SWAP",
+	OpTuck:                "OP_TUCK",
+	OpCat:                 "OP_CAT",
+	OpSubStr:              "OP_SUBSTR",
+	OpLeft:                "OP_LEFT",
+	OpRight:               "OP_RIGHT",
+	OpSize:                "OP_SIZE",
+	OpInvert:              "OP_INVERT",
+	OpAnd:                 "OP_AND",
+	OpOr:                  "OP_OR",
+	OpXor:                 "OP_XOR",
+	OpEqual:               "OP_EQUAL",
+	OpEqualVerify:         "OP_EQUALVERIFY",
+	OpReserved1:           "OP_RESERVED1",
+	OpReserved2:           "OP_RESERVED2",
+	Op1Add:                "OP_1ADD",
+	Op1Sub:                "OP_1SUB",
+	Op2Mul:                "OP_2MUL",
+	Op2Div:                "OP_2DIV",
+	OpNegate:              "OP_NEGATE",
+	OpAbs:                 "OP_ABS",
+	OpNot:                 "OP_NOT",
+	Op0NotEqual:           "OP_0NOTEQUAL",
+	OpAdd:                 "OP_ADD",
+	OpSub:                 "OP_SUB",
+	OpMul:                 "OP_MUL",
+	OpDiv:                 "OP_DIV",
+	OpMod:                 "OP_MOD",
+	OpLShift:              "OP_LSHIFT",
+	OpRShift:              "OP_RSHIFT",
+	OpBoolAnd:             "OP_BOOLAND",
+	OpBoolOr:              "OP_BOOLOR",
+	OpNumEqual:            "OP_NUMEQUAL",
+	OpNumEqualVerify:      "OP_NUMEQUALVERIFY",
+	OpNumNotEqual:         "OP_NUMNOTEQUAL",
+	OpLessThan:            "OP_LESSTHAN",
+	OpGreaterThan:         "OP_GREATERTHAN",
+	OpLessThanOrEqual:     "OP_LESSTHANOREQUAL",
+	OpGreaterThanOrEqual:  "OP_GREATERTHANOREQUAL",
+	OpMin:                 "OP_MIN",
+	OpMax:                 "OP_MAX",
+	OpWithin:              "OP_WITHIN",
+	OpRipemd160:           "OP_RIPEMD160",
+	OpSha1:                "OP_SHA1",
+	OpSha256:              "OP_SHA256",
+	OpHash160:             "OP_HASH160",
+	OpHash256:             "OP_HASH256",
+	OpCodeSeparator:       "OP_CODESEPARATOR",
+	OpCheckSig:            "OP_CHECKSIG",
+	OpCheckSigVerify:      "OP_CHECKSIGVERIFY",
+	OpCheckMultiSig:       "OP_CHECKMULTISIG",
+	OpCheckMultiSigVerify: "OP_CHECKMULTISIGVERIFY",
+	OpNop1:                "OP_NOP1",
+	OpCheckLockTimeVerify: "OP_CHECKLOCKTIMEVERIFY",
+	OpCheckSequenceVerify: "OP_CHECKSEQUENCEVERIFY",
+	OpNop4:                "OP_NOP4",
+	OpNop5:                "OP_NOP5",
+	OpNop6:                "OP_NOP6",
+	OpNop7:                "OP_NOP7",
+	OpNop8:                "OP_NOP8",
+	OpNop9:                "OP_NOP9",
+	OpNop10:               "OP_NOP10",
+	OpCheckSigAdd:         "OP_CHECKSIGADD",
+	OpInvalidOpcode:       "OP_INVALIDOPCODE",
+}
+
+// OpcodeName returns the name of an opcode, direct pushes are named
+// OP_DATA_N and undefined opcodes OP_UNKNOWN
+func OpcodeName(op byte) string {
+
+	if name, ok := opcodeNames[op]; ok {
+		return name
+	}
+
+	if op >= 0x01 && op <= 0x4b {
+		return fmt.Sprintf("OP_DATA_%d", op)
+	}
+
+	return "OP_UNKNOWN"
+}
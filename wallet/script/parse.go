@@ -0,0 +1,214 @@
+package script
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+const (
+	// MaxScriptSize is the largest script accepted by the interpreter
+	MaxScriptSize = 10000
+	// MaxElementSize is the largest element that can be pushed on the stack
+	MaxElementSize = 520
+)
+
+var (
+	// ErrMalformedPush is returned when a push opcode runs past the end of
+	// the script
+	ErrMalformedPush = errors.New("script: malformed push")
+	// ErrElementTooLarge is returned when pushed data exceeds MaxElementSize
+	ErrElementTooLarge = errors.New("script: element too large")
+)
+
+// Instruction is a parsed opcode with the data it pushes, if any
+type Instruction struct {
+	Opcode byte
+	Data   []byte
+	// Offset is the position of the opcode in the script
+	Offset int
+}
+
+// IsPush reports whether the instruction counts as a push, OP_RESERVED is
+// included as done by the reference client
+func (in Instruction) IsPush() bool {
+	return in.Opcode <= Op16
+}
+
+// IsSmallInt reports whether the opcode is OP_0 or one of OP_1 to OP_16
+func IsSmallInt(op byte) bool {
+	return op == Op0 || (op >= Op1 && op <= Op16)
+}
+
+// SmallInt returns the value pushed by OP_0 and OP_1 to OP_16
+func SmallInt(op byte) int {
+
+	if op == Op0 {
+		return 0
+	}
+
+	return int(op - Op1 + 1)
+}
+
+// SmallIntOp returns the opcode pushing n, n must be between 0 and 16
+func SmallIntOp(n int) byte {
+
+	if n == 0 {
+		return Op0
+	}
+
+	return Op1 + byte(n-1)
+}
+
+// Parse splits a script into instructions, the instructions parsed before
+// a malformed push are returned with the error
+func Parse(script []byte) ([]Instruction, error) {
+
+	var ins []Instruction
+
+	for i := 0; i < len(script); {
+		op := script[i]
+		start := i + 1
+		var n int
+
+		switch {
+		case op >= 0x01 && op <= 0x4b:
+			n = int(op)
+		case op == OpPushData1:
+			if len(script)-start < 1 {
+				return ins, ErrMalformedPush
+			}
+			n = int(script[start])
+			start++
+		case op == OpPushData2:
+			if len(script)-start < 2 {
+				return ins, ErrMalformedPush
+			}
+			n = int(binary.LittleEndian.Uint16(script[start:]))
+			start += 2
+		case op == OpPushData4:
+			if len(script)-start < 4 {
+				return ins, ErrMalformedPush
+			}
+			l := binary.LittleEndian.Uint32(script[start:])
+			if l > uint32(len(script)) {
+				return ins, ErrMalformedPush
+			}
+			n = int(l)
+			start += 4
+		}
+
+		if len(script)-start < n {
+			return ins, ErrMalformedPush
+		}
+
+		in := Instruction{Opcode: op, Offset: i}
+		if op >= 0x01 && op <= OpPushData4 {
+			in.Data = script[start : start+n]
+		}
+		ins = append(ins, in)
+
+		i = start + n
+	}
+
+	return ins, nil
+}
+
+// opSize returns the encoded size of the first opcode of script, false when
+// the script is empty or the push runs past its end
+func opSize(script []byte) (int, bool) {
+
+	if len(script) == 0 {
+		return 0, false
+	}
+
+	n := 1
+	switch op := script[0]; {
+	case op >= 0x01 && op <= 0x4b:
+		n += int(op)
+	case op == OpPushData1:
+		if len(script) < 2 {
+			return 0, false
+		}
+		n += 1 + int(script[1])
+	case op == OpPushData2:
+		if len(script) < 3 {
+			return 0, false
+		}
+		n += 2 + int(binary.LittleEndian.Uint16(script[1:]))
+	case op == OpPushData4:
+		if len(script) < 5 {
+			return 0, false
+		}
+		l := binary.LittleEndian.Uint32(script[1:])
+		if l > uint32(len(script)) {
+			return 0, false
+		}
+		n += 4 + int(l)
+	}
+
+	if n > len(script) {
+		return 0, false
+	}
+
+	return n, true
+}
+
+// IsPushOnly reports whether a script is well formed and only pushes data
+func IsPushOnly(script []byte) bool {
+
+	ins, err := Parse(script)
+	if err != nil {
+		return false
+	}
+
+	for _, in := range ins {
+		if !in.IsPush() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// PushedData returns the data pushed by a script, small integer opcodes
+// are ignored
+func PushedData(script []byte) ([][]byte, error) {
+
+	ins, err := Parse(script)
+	if err != nil {
+		return nil, err
+	}
+
+	var data [][]byte
+	for _, in := range ins {
+		if in.Data != nil {
+			data = append(data, in.Data)
+		}
+	}
+
+	return data, nil
+}
+
+// Disassemble returns a human readable form of a script, pushed data is
+// hex encoded and a malformed tail is rendered as [error]
+func Disassemble(script []byte) (string, error) {
+
+	ins, err := Parse(script)
+
+	parts := make([]string, 0, len(ins)+1)
+	for _, in := range ins {
+		if in.Data != nil {
+			parts = append(parts, hex.EncodeToString(in.Data))
+			continue
+		}
+		parts = append(parts, OpcodeName(in.Opcode))
+	}
+
+	if err != nil {
+		parts = append(parts, "[error]")
+	}
+
+	return strings.Join(parts, " "), err
+}
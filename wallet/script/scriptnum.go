@@ -0,0 +1,84 @@
+package script
+
+import "math"
+
+// defaultScriptNumLen is the maximum size of numeric operands, the result
+// of arithmetic can be larger and is only checked when consumed again
+const defaultScriptNumLen = 4
+
+// scriptNum is a little endian sign magnitude integer used by the numeric
+// opcodes
+type scriptNum int64
+
+// makeScriptNum decodes a numeric stack element of at most maxLen bytes,
+// requireMinimal rejects encodings with superfluous bytes
+func makeScriptNum(b []byte, requireMinimal bool, maxLen int) (scriptNum, error) {
+
+	if len(b) > maxLen {
+		return 0, ErrScriptNumOverflow
+	}
+
+	if requireMinimal && len(b) > 0 {
+		// the last byte may only be zero, ignoring the sign bit, when the
+		// previous byte needs its high bit for the magnitude
+		if b[len(b)-1]&0x7f == 0 && (len(b) == 1 || b[len(b)-2]&0x80 == 0) {
+			return 0, ErrScriptNumNonMinimal
+		}
+	}
+
+	if len(b) == 0 {
+		return 0, nil
+	}
+
+	var n int64
+	for i, v := range b {
+		n |= int64(v) << uint(8*i)
+	}
+
+	if b[len(b)-1]&0x80 != 0 {
+		n &^= int64(0x80) << uint(8*(len(b)-1))
+		return scriptNum(-n), nil
+	}
+
+	return scriptNum(n), nil
+}
+
+// Bytes returns the minimal encoding of the number
+func (n scriptNum) Bytes() []byte {
+	return encodeScriptNum(int64(n))
+}
+
+// Int32 returns the number clamped to the int32 range
+func (n scriptNum) Int32() int32 {
+
+	if n > math.MaxInt32 {
+		return math.MaxInt32
+	}
+
+	if n < math.MinInt32 {
+		return math.MinInt32
+	}
+
+	return int32(n)
+}
+
+// asBool interprets a stack element as a boolean, negative zero is false
+func asBool(b []byte) bool {
+
+	for i, v := range b {
+		if v != 0 {
+			return !(i == len(b)-1 && v == 0x80)
+		}
+	}
+
+	return false
+}
+
+func fromBool(v bool) []byte {
+
+	if v {
+		return []byte{1}
+	}
+
+	return nil
+}
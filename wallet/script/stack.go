@@ -0,0 +1,37 @@
+package script
+
+// stack is the main or alternate stack of the interpreter, elements are
+// indexed from the top with top(-1) being the last pushed element
+type stack [][]byte
+
+func (s *stack) push(b []byte) {
+	*s = append(*s, b)
+}
+
+func (s *stack) pop() []byte {
+
+	b := (*s)[len(*s)-1]
+	*s = (*s)[:len(*s)-1]
+
+	return b
+}
+
+func (s stack) top(i int) []byte {
+	return s[len(s)+i]
+}
+
+// erase removes the element at top(i)
+func (s *stack) erase(i int) {
+
+	at := len(*s) + i
+	*s = append((*s)[:at], (*s)[at+1:]...)
+}
+
+// insert places b at top(i), shifting the elements above it
+func (s *stack) insert(i int, b []byte) {
+
+	at := len(*s) + i
+	*s = append(*s, nil)
+	copy((*s)[at+1:], (*s)[at:])
+	(*s)[at] = b
+}
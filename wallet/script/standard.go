@@ -0,0 +1,349 @@
+package script
+
+import (
+	"errors"
+
+	"github.com/giogam/Gopher-Wallet/wallet/address"
+)
+
+// MaxDataCarrierSize is the largest payload relayed in a null data output
+const MaxDataCarrierSize = 80
+
+// MaxPubKeysPerMultiSig is the largest number of keys in a multisig script
+const MaxPubKeysPerMultiSig = 20
+
+// Class is the standard template matched by an output script
+type Class int
+
+const (
+	// NonStandard matches no template
+	NonStandard Class = iota
+	// PubKey is <pubkey> OP_CHECKSIG
+	PubKey
+	// PubKeyHash is OP_DUP OP_HASH160 <hash> OP_EQUALVERIFY OP_CHECKSIG
+	PubKeyHash
+	// ScriptHash is OP_HASH160 <hash> OP_EQUAL
+	ScriptHash
+	// WitnessV0PubKeyHash is OP_0 <20 bytes>
+	WitnessV0PubKeyHash
+	// WitnessV0ScriptHash is OP_0 <32 bytes>
+	WitnessV0ScriptHash
+	// WitnessV1Taproot is OP_1 <32 bytes>
+	WitnessV1Taproot
+	// WitnessUnknown is a witness program with no defined meaning yet
+	WitnessUnknown
+	// MultiSig is OP_m <pubkeys> OP_n OP_CHECKMULTISIG
+	MultiSig
+	// NullData is OP_RETURN followed by pushes only
+	NullData
+)
+
+var classNames = map[Class]string{
+	NonStandard:         "nonstandard",
+	PubKey:              "pubkey",
+	PubKeyHash:          "pubkeyhash",
+	ScriptHash:          "scripthash",
+	WitnessV0PubKeyHash: "witness_v0_keyhash",
+	WitnessV0ScriptHash: "witness_v0_scripthash",
+	WitnessV1Taproot:    "witness_v1_taproot",
+	WitnessUnknown:      "witness_unknown",
+	MultiSig:            "multisig",
+	NullData:            "nulldata",
+}
+
+// String returns the name of the class as used by the reference client
+func (c Class) String() string {
+
+	if name, ok := classNames[c]; ok {
+		return name
+	}
+
+	return "unknown"
+}
+
+var (
+	// ErrInvalidPubKey is returned for keys that are not 33 or 65 bytes with
+	// a valid prefix
+	ErrInvalidPubKey = errors.New("script: invalid public key")
+	// ErrInvalidMultiSig is returned for an invalid threshold or key count
+	ErrInvalidMultiSig = errors.New("script: invalid multisig parameters")
+	// ErrTooMuchData is returned when a null data payload is too large
+	ErrTooMuchData = errors.New("script: null data payload too large")
+	// ErrNoAddress is returned when an output script has no address form
+	ErrNoAddress = errors.New("script: script has no address")
+	// ErrNotMultiSig is returned when a script is not a multisig script
+	ErrNotMultiSig = errors.New("script: not a multisig script")
+)
+
+// Classify returns the standard template matched by an output script
+func Classify(script []byte) Class {
+
+	if version, program, ok := ExtractWitnessProgram(script); ok {
+		switch {
+		case version == 0 && len(program) == 20:
+			return WitnessV0PubKeyHash
+		case version == 0 && len(program) == 32:
+			return WitnessV0ScriptHash
+		case version == 1 && len(program) == 32:
+			return WitnessV1Taproot
+		case version != 0:
+			return WitnessUnknown
+		}
+		return NonStandard
+	}
+
+	switch {
+	case isPubKeyHash(script):
+		return PubKeyHash
+	case isScriptHash(script):
+		return ScriptHash
+	case isPubKey(script):
+		return PubKey
+	case isNullData(script):
+		return NullData
+	}
+
+	if _, _, err := ExtractMultiSig(script); err == nil {
+		return MultiSig
+	}
+
+	return NonStandard
+}
+
+// ExtractWitnessProgram returns the version and program of a witness
+// output script
+func ExtractWitnessProgram(script []byte) (byte, []byte, bool) {
+
+	if len(script) < 4 || len(script) > 42 {
+		return 0, nil, false
+	}
+
+	if !IsSmallInt(script[0]) || int(script[1]) != len(script)-2 {
+		return 0, nil, false
+	}
+
+	return byte(SmallInt(script[0])), script[2:], true
+}
+
+// ExtractMultiSig returns the threshold and the public keys of a bare
+// multisig script
+func ExtractMultiSig(script []byte) (int, [][]byte, error) {
+
+	ins, err := Parse(script)
+	if err != nil || len(ins) < 4 {
+		return 0, nil, ErrNotMultiSig
+	}
+
+	last := len(ins) - 1
+	m, okM := multiSigCount(ins[0])
+	n, okN := multiSigCount(ins[last-1])
+	if ins[last].Opcode != OpCheckMultiSig || !okM || !okN {
+		return 0, nil, ErrNotMultiSig
+	}
+
+	if m < 1 || n < m || n != last-2 {
+		return 0, nil, ErrNotMultiSig
+	}
+
+	keys := make([][]byte, 0, n)
+	for _, in := range ins[1 : last-1] {
+		if !validPubKey(in.Data) || len(in.Data) != int(in.Opcode) {
+			return 0, nil, ErrNotMultiSig
+		}
+		keys = append(keys, in.Data)
+	}
+
+	return m, keys, nil
+}
+
+// PayToPubKey returns <pubkey> OP_CHECKSIG
+func PayToPubKey(pub []byte) ([]byte, error) {
+
+	if !validPubKey(pub) {
+		return nil, ErrInvalidPubKey
+	}
+
+	return NewBuilder().AddData(pub).AddOp(OpCheckSig).Script()
+}
+
+// PayToPubKeyHash returns the P2PKH script of a 20 bytes key hash
+func PayToPubKeyHash(hash []byte) ([]byte, error) {
+
+	if len(hash) != 20 {
+		return nil, address.ErrInvalidHashLength
+	}
+
+	return NewBuilder().AddOps(OpDup, OpHash160).AddData(hash).AddOps(OpEqualVerify, OpCheckSig).Script()
+}
+
+// PayToScriptHash returns the P2SH script of a 20 bytes script hash
+func PayToScriptHash(hash []byte) ([]byte, error) {
+
+	if len(hash) != 20 {
+		return nil, address.ErrInvalidHashLength
+	}
+
+	return NewBuilder().AddOp(OpHash160).AddData(hash).AddOp(OpEqual).Script()
+}
+
+// PayToWitness returns the output script of a witness program
+func PayToWitness(version byte, program []byte) ([]byte, error) {
+
+	if version > 16 || len(program) < 2 || len(program) > 40 {
+		return nil, address.ErrInvalidWitnessProgram
+	}
+
+	return append([]byte{SmallIntOp(int(version)), byte(len(program))}, program...), nil
+}
+
+// PayToWitnessPubKeyHash returns the P2WPKH script of a 20 bytes key hash
+func PayToWitnessPubKeyHash(hash []byte) ([]byte, error) {
+
+	if len(hash) != 20 {
+		return nil, address.ErrInvalidHashLength
+	}
+
+	return PayToWitness(0, hash)
+}
+
+// PayToWitnessScriptHash returns the P2WSH script of a 32 bytes script hash
+func PayToWitnessScriptHash(hash []byte) ([]byte, error) {
+
+	if len(hash) != 32 {
+		return nil, address.ErrInvalidHashLength
+	}
+
+	return PayToWitness(0, hash)
+}
+
+// PayToTaproot returns the P2TR script of a 32 bytes x-only output key
+func PayToTaproot(outputKey []byte) ([]byte, error) {
+
+	if len(outputKey) != 32 {
+		return nil, ErrInvalidPubKey
+	}
+
+	return PayToWitness(1, outputKey)
+}
+
+// MultiSigScript returns the bare m of n multisig script of pubs, keys are
+// kept in the given order
+func MultiSigScript(m int, pubs [][]byte) ([]byte, error) {
+
+	if m < 1 || m > len(pubs) || len(pubs) > MaxPubKeysPerMultiSig {
+		return nil, ErrInvalidMultiSig
+	}
+
+	b := NewBuilder().AddInt64(int64(m))
+	for _, pub := range pubs {
+		if !validPubKey(pub) {
+			return nil, ErrInvalidPubKey
+		}
+		b.AddData(pub)
+	}
+
+	return b.AddInt64(int64(len(pubs))).AddOp(OpCheckMultiSig).Script()
+}
+
+// NullDataScript returns an OP_RETURN script carrying data
+func NullDataScript(data []byte) ([]byte, error) {
+
+	if len(data) > MaxDataCarrierSize {
+		return nil, ErrTooMuchData
+	}
+
+	return NewBuilder().AddOp(OpReturn).AddData(data).Script()
+}
+
+// PayToAddress returns the output script paying to addr
+func PayToAddress(addr *address.Address) ([]byte, error) {
+
+	switch addr.Type {
+	case address.P2PKH:
+		return PayToPubKeyHash(addr.Program)
+	case address.P2SH:
+		return PayToScriptHash(addr.Program)
+	case address.P2WPKH, address.P2WSH, address.P2TR, address.WitnessUnknown:
+		return PayToWitness(addr.Version, addr.Program)
+	}
+
+	return nil, address.ErrInvalidAddress
+}
+
+// ExtractAddress returns the address of an output script on net, P2PK,
+// multisig and null data scripts have no address
+func ExtractAddress(script []byte, net *address.Network) (*address.Address, error) {
+
+	switch Classify(script) {
+	case PubKeyHash:
+		return address.NewP2PKH(script[3:23], net)
+	case ScriptHash:
+		return address.NewP2SH(script[2:22], net)
+	case WitnessV0PubKeyHash, WitnessV0ScriptHash, WitnessV1Taproot, WitnessUnknown:
+		version, program, _ := ExtractWitnessProgram(script)
+		return address.NewWitness(version, program, net)
+	}
+
+	return nil, ErrNoAddress
+}
+
+// multiSigCount decodes a key count, counts above 16 are single byte pushes
+func multiSigCount(in Instruction) (int, bool) {
+
+	if IsSmallInt(in.Opcode) {
+		return SmallInt(in.Opcode), true
+	}
+
+	if in.Opcode == 0x01 && in.Data[0] > 16 && in.Data[0] <= MaxPubKeysPerMultiSig {
+		return int(in.Data[0]), true
+	}
+
+	return 0, false
+}
+
+func isPubKeyHash(script []byte) bool {
+	return len(script) == 25 && script[0] == OpDup && script[1] == OpHash160 &&
+		script[2] == 20 && script[23] == OpEqualVerify && script[24] == OpCheckSig
+}
+
+func isScriptHash(script []byte) bool {
+	return len(script) == 23 && script[0] == OpHash160 && script[1] == 20 && script[22] == OpEqual
+}
+
+func isPubKey(script []byte) bool {
+
+	n := len(script)
+	if n < 2 || script[n-1] != OpCheckSig || int(script[0]) != n-2 {
+		return false
+	}
+
+	return validPubKey(script[1 : n-1])
+}
+
+func isNullData(script []byte) bool {
+	return len(script) >= 1 && script[0] == OpReturn && IsPushOnly(script[1:])
+}
+
+// validPubKey checks the size and the prefix of a serialized public key
+// without decoding the point
+func validPubKey(pub []byte) bool {
+
+	switch len(pub) {
+	case 33:
+		return pub[0] == 0x02 || pub[0] == 0x03
+	case 65:
+		return pub[0] == 0x04 || pub[0] == 0x06 || pub[0] == 0x07
+	}
+
+	return false
+}
+
+// IsPayToScriptHash reports whether script is a P2SH output script
+func IsPayToScriptHash(script []byte) bool {
+	return isScriptHash(script)
+}
+
+// IsPayToTaproot reports whether script is a P2TR output script
+func IsPayToTaproot(script []byte) bool {
+	return Classify(script) == WitnessV1Taproot
+}
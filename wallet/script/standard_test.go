@@ -0,0 +1,155 @@
+package script
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/giogam/Gopher-Wallet/wallet/address"
+	"github.com/stretchr/testify/assert"
+)
+
+type standardtest struct {
+	addr   string
+	script string
+	class  Class
+}
+
+func standardTestVector() []standardtest {
+	return []standardtest{
+		{
+			addr:   "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2",
+			script: "76a91477bff20c60e522dfaa3350c39b030a5d004e839a88ac",
+			class:  PubKeyHash,
+		},
+		{
+			addr:   "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy",
+			script: "a914b472a266d0bd89c13706a4132ccfb16f7c3b9fcb87",
+			class:  ScriptHash,
+		},
+		{
+			addr:   "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+			script: "0014751e76e8199196d454941c45d1b3a323f1433bd6",
+			class:  WitnessV0PubKeyHash,
+		},
+		{
+			addr:   "bc1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3qccfmv3",
+			script: "00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262",
+			class:  WitnessV0ScriptHash,
+		},
+		{
+			addr:   "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0",
+			script: "512079be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+			class:  WitnessV1Taproot,
+		},
+		{
+			addr:   "bc1sw50qgdz25j",
+			script: "6002751e",
+			class:  WitnessUnknown,
+		},
+	}
+}
+
+func TestAddressScripts(t *testing.T) {
+	for _, test := range standardTestVector() {
+		addr, err := address.Decode(test.addr, address.MainNet)
+		assert.NoError(t, err)
+
+		script, err := PayToAddress(addr)
+		assert.NoError(t, err)
+		assert.Equal(t, test.script, hex.EncodeToString(script))
+		assert.Equal(t, test.class, Classify(script))
+
+		back, err := ExtractAddress(script, address.MainNet)
+		assert.NoError(t, err)
+		assert.Equal(t, test.addr, back.String())
+	}
+}
+
+func TestClassify(t *testing.T) {
+	pub1, _ := hex.DecodeString("0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798")
+	pub2, _ := hex.DecodeString("04ae1a62fe09c5f51b13905f07f06b99a2f7159b2225f374cd378d71302fa28414e7aab37397f554a7df5f142c21c1b7303b8a0626f1baded5c72a704f7e6cd84c")
+
+	p2pk, err := PayToPubKey(pub2)
+	assert.NoError(t, err)
+	assert.Equal(t, PubKey, Classify(p2pk))
+	_, err = ExtractAddress(p2pk, address.MainNet)
+	assert.Equal(t, ErrNoAddress, err)
+
+	multi, err := MultiSigScript(1, [][]byte{pub1, pub2})
+	assert.NoError(t, err)
+	assert.Equal(t, MultiSig, Classify(multi))
+
+	m, keys, err := ExtractMultiSig(multi)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, m)
+	assert.Equal(t, [][]byte{pub1, pub2}, keys)
+
+	_, err = MultiSigScript(3, [][]byte{pub1, pub2})
+	assert.Equal(t, ErrInvalidMultiSig, err)
+
+	// key counts above 16 are pushed numbers
+	many := make([][]byte, 20)
+	for i := range many {
+		many[i] = pub1
+	}
+	multi, err = MultiSigScript(17, many)
+	assert.NoError(t, err)
+	m, keys, err = ExtractMultiSig(multi)
+	assert.NoError(t, err)
+	assert.Equal(t, 17, m)
+	assert.Len(t, keys, 20)
+
+	data, err := NullDataScript([]byte("hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, "6a0568656c6c6f", hex.EncodeToString(data))
+	assert.Equal(t, NullData, Classify(data))
+	assert.Equal(t, NullData, Classify([]byte{OpReturn}))
+
+	_, err = NullDataScript(make([]byte, MaxDataCarrierSize+1))
+	assert.Equal(t, ErrTooMuchData, err)
+
+	for _, script := range []string{
+		"",
+		// OP_RETURN followed by a non push opcode
+		"6a76",
+		// P2PKH with a 19 bytes hash
+		"76a91377bff20c60e522dfaa3350c39b030a5d004e839a88ac",
+		// witness program with a push opcode mismatch
+		"0015751e76e8199196d454941c45d1b3a323f1433bd6",
+		// version 0 program of unknown size
+		"0003751e76",
+		// invalid public key prefix
+		"210579be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798ac",
+	} {
+		raw, _ := hex.DecodeString(script)
+		assert.Equal(t, NonStandard, Classify(raw), script)
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	script, err := NewBuilder().
+		AddInt64(0).AddInt64(-1).AddInt64(16).AddInt64(17).AddInt64(-128).AddInt64(255).
+		AddData([]byte{0x81}).AddData(make([]byte, 76)).
+		Script()
+	assert.NoError(t, err)
+	assert.Equal(t, "004f60011102808002ff004f4c4c", hex.EncodeToString(script[:14]))
+
+	_, err = NewBuilder().AddData(make([]byte, MaxElementSize+1)).Script()
+	assert.Equal(t, ErrElementTooLarge, err)
+}
+
+func TestDisassemble(t *testing.T) {
+	raw, _ := hex.DecodeString("76a91477bff20c60e522dfaa3350c39b030a5d004e839a88ac00b1ba")
+
+	asm, err := Disassemble(raw)
+	assert.NoError(t, err)
+	assert.Equal(t, "OP_DUP OP_HASH160 77bff20c60e522dfaa3350c39b030a5d004e839a OP_EQUALVERIFY OP_CHECKSIG OP_0 OP_CHECKLOCKTIMEVERIFY OP_CHECKSIGADD", asm)
+
+	raw, _ = hex.DecodeString("51bb4c05aabb")
+	asm, err = Disassemble(raw)
+	assert.Equal(t, ErrMalformedPush, err)
+	assert.Equal(t, "OP_1 OP_UNKNOWN [error]", asm)
+
+	assert.True(t, IsPushOnly([]byte{Op0, Op16, 0x01, 0xff}))
+	assert.False(t, IsPushOnly([]byte{Op0, OpNop}))
+}
@@ -0,0 +1,328 @@
+package script
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+// VerifyScript verifies that scriptSig and witness satisfy scriptPubKey
+// under flags, signatures and time locks are checked by checker
+func VerifyScript(scriptSig, scriptPubKey []byte, witness transaction.Witness, flags VerifyFlags, checker SignatureChecker) error {
+
+	if flags&VerifySigPushOnly != 0 && !IsPushOnly(scriptSig) {
+		return ErrSigPushOnly
+	}
+
+	e := &engine{flags: flags, checker: checker, version: SigVersionBase}
+
+	var st stack
+	if err := e.eval(&st, scriptSig); err != nil {
+		return err
+	}
+
+	saved := append(stack{}, st...)
+
+	if err := e.eval(&st, scriptPubKey); err != nil {
+		return err
+	}
+
+	if len(st) == 0 || !asBool(st.top(-1)) {
+		return ErrEvalFalse
+	}
+
+	hadWitness := false
+
+	if flags&VerifyWitness != 0 {
+		if version, program, ok := ExtractWitnessProgram(scriptPubKey); ok {
+			hadWitness = true
+			if len(scriptSig) != 0 {
+				return ErrWitnessMalleated
+			}
+			if err := verifyWitnessProgram(witness, version, program, flags, checker, false); err != nil {
+				return err
+			}
+			// the witness program leaves a single true element, bypassing
+			// the clean stack check of the scriptSig
+			st = st[:1]
+		}
+	}
+
+	if flags&VerifyP2SH != 0 && isScriptHash(scriptPubKey) {
+		if !IsPushOnly(scriptSig) {
+			return ErrSigPushOnly
+		}
+
+		st = saved
+		redeemScript := st.pop()
+
+		if err := e.eval(&st, redeemScript); err != nil {
+			return err
+		}
+
+		if len(st) == 0 || !asBool(st.top(-1)) {
+			return ErrEvalFalse
+		}
+
+		if flags&VerifyWitness != 0 {
+			if version, program, ok := ExtractWitnessProgram(redeemScript); ok {
+				hadWitness = true
+				if !bytes.Equal(scriptSig, append([]byte{byte(len(redeemScript))}, redeemScript...)) {
+					return ErrWitnessMalleatedP2SH
+				}
+				if err := verifyWitnessProgram(witness, version, program, flags, checker, true); err != nil {
+					return err
+				}
+				st = st[:1]
+			}
+		}
+	}
+
+	if flags&VerifyCleanStack != 0 && len(st) != 1 {
+		return ErrCleanStack
+	}
+
+	if flags&VerifyWitness != 0 && !hadWitness && len(witness) != 0 {
+		return ErrWitnessUnexpected
+	}
+
+	return nil
+}
+
+// verifyWitnessProgram evaluates the witness of a segwit output
+func verifyWitnessProgram(witness transaction.Witness, version byte, program []byte, flags VerifyFlags, checker SignatureChecker, isP2SH bool) error {
+
+	st := append(stack{}, witness...)
+
+	switch {
+	case version == 0 && len(program) == 32:
+		if len(st) == 0 {
+			return ErrWitnessProgramWitnessEmpty
+		}
+		witnessScript := st.pop()
+		if h := sha256.Sum256(witnessScript); !bytes.Equal(h[:], program) {
+			return ErrWitnessProgramMismatch
+		}
+		e := &engine{flags: flags, checker: checker, version: SigVersionWitnessV0}
+		return e.executeWitnessScript(st, witnessScript)
+
+	case version == 0 && len(program) == 20:
+		if len(st) != 2 {
+			return ErrWitnessProgramMismatch
+		}
+		scriptCode, _ := PayToPubKeyHash(program)
+		e := &engine{flags: flags, checker: checker, version: SigVersionWitnessV0}
+		return e.executeWitnessScript(st, scriptCode)
+
+	case version == 0:
+		return ErrWitnessProgramWrongLength
+
+	case version == 1 && len(program) == 32 && !isP2SH:
+		if flags&VerifyTaproot == 0 {
+			return nil
+		}
+		return verifyTaproot(witness, st, program, flags, checker)
+	}
+
+	if flags&VerifyDiscourageUpgradableWitnessProgram != 0 {
+		return ErrDiscourageUpgradableWitness
+	}
+
+	return nil
+}
+
+// verifyTaproot evaluates a BIP341 key path or script path spend
+func verifyTaproot(witness transaction.Witness, st stack, program []byte, flags VerifyFlags, checker SignatureChecker) error {
+
+	if len(st) == 0 {
+		return ErrWitnessProgramWitnessEmpty
+	}
+
+	spend := &transaction.TaprootSpend{}
+
+	if last := st.top(-1); len(st) >= 2 && len(last) > 0 && last[0] == annexTag {
+		spend.Annex = st.pop()
+	}
+
+	if len(st) == 1 {
+		return checker.CheckSchnorrSignature(st[0], program, SigVersionTaproot, spend)
+	}
+
+	control := st.pop()
+	leafScript := st.pop()
+
+	if len(control) < taprootControlBase || len(control) > taprootControlMaxLen ||
+		(len(control)-taprootControlBase)%taprootControlNode != 0 {
+		return ErrTaprootWrongControlSize
+	}
+
+	leafVersion := control[0] & taprootLeafMask
+	leafHash := hdwallet.TapLeafHash(leafVersion, leafScript)
+
+	if !verifyTaprootCommitment(control, program, leafHash) {
+		return ErrWitnessProgramMismatch
+	}
+
+	if leafVersion != hdwallet.BaseLeafVersion {
+		if flags&VerifyDiscourageUpgradableTaprootVersion != 0 {
+			return ErrDiscourageUpgradableTaproot
+		}
+		return nil
+	}
+
+	spend.LeafHash = leafHash
+
+	e := &engine{
+		flags:      flags,
+		checker:    checker,
+		version:    SigVersionTapscript,
+		spend:      spend,
+		weightLeft: int64(witnessSize(witness)) + validationWeightOffset,
+	}
+
+	return e.executeWitnessScript(st, leafScript)
+}
+
+// verifyTaprootCommitment checks that the output key commits to the leaf
+// through the merkle path of the control block
+func verifyTaprootCommitment(control, program, leafHash []byte) bool {
+
+	internal, err := secp256k1.ParseXOnlyPubKey(control[1:taprootControlBase])
+	if err != nil {
+		return false
+	}
+
+	k := leafHash
+	for i := taprootControlBase; i < len(control); i += taprootControlNode {
+		k = hdwallet.TapBranchHash(k, control[i:i+taprootControlNode])
+	}
+
+	t := hdwallet.TaprootTweak(internal, k)
+	if new(big.Int).SetBytes(t).Cmp(secp256k1.N) >= 0 {
+		return false
+	}
+
+	tx, ty := secp256k1.ScalarBaseMult(t)
+	qx, qy := secp256k1.Add(internal.X, internal.Y, tx, ty)
+	if qx.Sign() == 0 && qy.Sign() == 0 {
+		return false
+	}
+
+	if !bytes.Equal(secp256k1.PaddedBytes(qx, 32), program) {
+		return false
+	}
+
+	return qy.Bit(0) == uint(control[0]&1)
+}
+
+// executeWitnessScript runs a segwit v0 or tapscript script on the witness
+// stack, the script must leave exactly one true element
+func (e *engine) executeWitnessScript(st stack, script []byte) error {
+
+	if e.version == SigVersionTapscript {
+		for rest := script; len(rest) > 0; {
+			if isOpSuccess(rest[0]) {
+				if e.flags&VerifyDiscourageOpSuccess != 0 {
+					return ErrDiscourageOpSuccess
+				}
+				return nil
+			}
+			n, ok := opSize(rest)
+			if !ok {
+				return ErrBadOpcode
+			}
+			rest = rest[n:]
+		}
+
+		if len(st) > MaxStackSize {
+			return ErrStackSize
+		}
+	}
+
+	for _, item := range st {
+		if len(item) > MaxElementSize {
+			return ErrPushSize
+		}
+	}
+
+	if err := e.eval(&st, script); err != nil {
+		return err
+	}
+
+	// witness scripts implicitly require a clean stack, version 0 scripts
+	// report a false result as the reference tests expect
+	if len(st) != 1 {
+		if e.version == SigVersionTapscript {
+			return ErrCleanStack
+		}
+		return ErrEvalFalse
+	}
+
+	if !asBool(st.top(-1)) {
+		return ErrEvalFalse
+	}
+
+	return nil
+}
+
+// witnessSize returns the serialized size of a witness stack
+func witnessSize(witness transaction.Witness) int {
+
+	n := transaction.VarIntSerializeSize(uint64(len(witness)))
+	for _, item := range witness {
+		n += transaction.VarIntSerializeSize(uint64(len(item))) + len(item)
+	}
+
+	return n
+}
+
+// VerifyInput verifies input idx of tx against the output it spends,
+// prevOuts must know every output spent by tx when taproot inputs are
+// present
+func VerifyInput(tx *transaction.Tx, idx int, prevOuts transaction.PrevOutputFetcher, hashes *transaction.SigHashCache, flags VerifyFlags) error {
+
+	if idx < 0 || idx >= len(tx.TxIn) {
+		return ErrInputIndex
+	}
+
+	in := tx.TxIn[idx]
+
+	prev := prevOuts.FetchPrevOutput(in.PreviousOutPoint)
+	if prev == nil {
+		return ErrMissingSpentOutput
+	}
+
+	if hashes == nil {
+		hashes = transaction.NewSigHashCache(tx, prevOuts)
+	}
+
+	checker := NewTxChecker(tx, idx, prev.Value, hashes)
+
+	return VerifyScript(in.SignatureScript, prev.PkScript, in.Witness, flags, checker)
+}
+
+// VerifyTx checks the sanity of tx and verifies every input, the
+// midstates are shared so the cost is linear in the transaction size. The
+// returned error wraps transaction.ErrSanity or the failure of the first
+// invalid input.
+func VerifyTx(tx *transaction.Tx, prevOuts transaction.PrevOutputFetcher, flags VerifyFlags) error {
+
+	if err := tx.CheckSanity(); err != nil {
+		return err
+	}
+
+	hashes := transaction.NewSigHashCache(tx, prevOuts)
+
+	for i := range tx.TxIn {
+		if err := VerifyInput(tx, i, prevOuts, hashes, flags); err != nil {
+			return fmt.Errorf("script: input %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,204 @@
+package script
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+// taprootSpend builds a transaction spending a P2TR output committing to
+// a single leaf script of the internal key
+type taprootSpend struct {
+	key      *secp256k1.PrivateKey
+	leaf     []byte
+	leafHash []byte
+	outKey   *secp256k1.PublicKey
+	tx       *transaction.Tx
+	prevOuts transaction.PrevOutputMap
+}
+
+func newTaprootSpend(t *testing.T, leaf func(xonly []byte) []byte) *taprootSpend {
+
+	raw, _ := hex.DecodeString("0000000000000000000000000000000000000000000000000000000000000003")
+	key, err := secp256k1.PrivKeyFromBytes(raw)
+	assert.NoError(t, err)
+
+	s := &taprootSpend{key: key}
+	s.leaf = leaf(key.PubKey().SerializeXOnly())
+	s.leafHash = hdwallet.TapLeafHash(hdwallet.BaseLeafVersion, s.leaf)
+
+	s.outKey, err = hdwallet.TaprootTweakPubKey(key.PubKey(), s.leafHash)
+	assert.NoError(t, err)
+
+	pkScript, _ := PayToTaproot(s.outKey.SerializeXOnly())
+
+	var prevHash transaction.Hash
+	prevHash[0] = 0x01
+	prevOut := transaction.NewOutPoint(&prevHash, 0)
+
+	s.tx = transaction.NewTx(2)
+	s.tx.AddTxIn(transaction.NewTxIn(prevOut, nil, nil))
+	s.tx.AddTxOut(transaction.NewTxOut(90000, pkScript))
+	s.prevOuts = transaction.PrevOutputMap{*prevOut: transaction.NewTxOut(100000, pkScript)}
+
+	return s
+}
+
+func (s *taprootSpend) controlBlock() []byte {
+
+	control := []byte{hdwallet.BaseLeafVersion | byte(s.outKey.Y.Bit(0))}
+
+	return append(control, s.key.PubKey().SerializeXOnly()...)
+}
+
+func (s *taprootSpend) scriptSig(t *testing.T, hashType transaction.SigHashType) []byte {
+
+	hashes := transaction.NewSigHashCache(s.tx, s.prevOuts)
+	h, err := hashes.TaprootSigHash(0, hashType, transaction.NewTapscriptSpend(s.leafHash))
+	assert.NoError(t, err)
+
+	sig, err := secp256k1.SchnorrSign(s.key, h, nil)
+	assert.NoError(t, err)
+
+	if hashType != transaction.SigHashDefault {
+		sig = append(sig, byte(hashType))
+	}
+
+	return sig
+}
+
+func TestVerifyTaprootKeyPath(t *testing.T) {
+	// BIP371 signed key path input
+	raw, _ := hex.DecodeString("020000000127744ababf3027fe0d6cf23a96eee2efb188ef52301954585883e69b6624b2420000000000ffffffff0148e6052a01000000160014768e1eeb4cf420866033f80aceff0f972074496900000000")
+	pkScript, _ := hex.DecodeString("51205a2c2cf5b52cf31f83ad2e8da63ff03183ecd8f609c7510ae8a48e03910a0757")
+	sig, _ := hex.DecodeString("bb53ec917bad9d906af1ba87181c48b86ace5aae2b53605a725ca74625631476fc6f5baedaf4f2ee0f477f36f58f3970d5b8273b7e497b97af2e3f125c97af34")
+
+	tx, err := transaction.NewTxFromBytes(raw)
+	assert.NoError(t, err)
+
+	prevOuts := transaction.PrevOutputMap{tx.TxIn[0].PreviousOutPoint: transaction.NewTxOut(5000000000, pkScript)}
+
+	tx.TxIn[0].Witness = transaction.Witness{sig}
+	assert.NoError(t, VerifyTx(tx, prevOuts, StandardVerifyFlags))
+
+	// the sighash byte cannot be an explicit SIGHASH_DEFAULT
+	tx.TxIn[0].Witness = transaction.Witness{append(sig, 0x00)}
+	assert.True(t, errors.Is(VerifyTx(tx, prevOuts, StandardVerifyFlags), ErrSchnorrSigHashType))
+
+	// an annex is committed to by the signature
+	tx.TxIn[0].Witness = transaction.Witness{sig, {annexTag}}
+	assert.True(t, errors.Is(VerifyTx(tx, prevOuts, StandardVerifyFlags), ErrSchnorrSig))
+
+	tx.TxIn[0].Witness = transaction.Witness{sig[:63]}
+	assert.True(t, errors.Is(VerifyTx(tx, prevOuts, StandardVerifyFlags), ErrSchnorrSigSize))
+
+	// without taproot the output is anyone can spend
+	tx.TxIn[0].Witness = transaction.Witness{sig[:63]}
+	assert.NoError(t, VerifyTx(tx, prevOuts, StandardVerifyFlags&^(VerifyTaproot|VerifyDiscourageUpgradableWitnessProgram)))
+
+	// the transaction itself must be sane
+	tx.AddTxIn(transaction.NewTxIn(&tx.TxIn[0].PreviousOutPoint, nil, nil))
+	assert.True(t, errors.Is(VerifyTx(tx, prevOuts, StandardVerifyFlags), transaction.ErrSanity))
+}
+
+func TestVerifyTapscript(t *testing.T) {
+	s := newTaprootSpend(t, func(xonly []byte) []byte {
+		leaf, _ := NewBuilder().AddData(xonly).AddOp(OpCheckSig).Script()
+		return leaf
+	})
+
+	for _, hashType := range []transaction.SigHashType{transaction.SigHashDefault, transaction.SigHashAll, transaction.SigHashSingle | transaction.SigHashAnyOneCanPay} {
+		s.tx.TxIn[0].Witness = transaction.Witness{s.scriptSig(t, hashType), s.leaf, s.controlBlock()}
+		assert.NoError(t, VerifyTx(s.tx, s.prevOuts, StandardVerifyFlags))
+	}
+
+	sig := s.scriptSig(t, transaction.SigHashDefault)
+
+	// an empty signature fails the check without error
+	s.tx.TxIn[0].Witness = transaction.Witness{nil, s.leaf, s.controlBlock()}
+	assert.True(t, errors.Is(VerifyTx(s.tx, s.prevOuts, StandardVerifyFlags), ErrEvalFalse))
+
+	// a wrong parity bit breaks the commitment
+	control := s.controlBlock()
+	control[0] ^= 1
+	s.tx.TxIn[0].Witness = transaction.Witness{sig, s.leaf, control}
+	assert.True(t, errors.Is(VerifyTx(s.tx, s.prevOuts, StandardVerifyFlags), ErrWitnessProgramMismatch))
+
+	s.tx.TxIn[0].Witness = transaction.Witness{sig, s.leaf, control[:32]}
+	assert.True(t, errors.Is(VerifyTx(s.tx, s.prevOuts, StandardVerifyFlags), ErrTaprootWrongControlSize))
+
+	// a signature over another output set is invalid
+	s.tx.TxOut[0].Value--
+	s.tx.TxIn[0].Witness = transaction.Witness{sig, s.leaf, s.controlBlock()}
+	assert.True(t, errors.Is(VerifyTx(s.tx, s.prevOuts, StandardVerifyFlags), ErrSchnorrSig))
+}
+
+func TestVerifyTapscriptRules(t *testing.T) {
+	// 1 of 1 with OP_CHECKSIGADD
+	s := newTaprootSpend(t, func(xonly []byte) []byte {
+		leaf, _ := NewBuilder().AddOp(Op0).AddData(xonly).AddOp(OpCheckSigAdd).AddOp(Op1).AddOp(OpNumEqual).Script()
+		return leaf
+	})
+	s.tx.TxIn[0].Witness = transaction.Witness{s.scriptSig(t, transaction.SigHashDefault), s.leaf, s.controlBlock()}
+	assert.NoError(t, VerifyTx(s.tx, s.prevOuts, StandardVerifyFlags))
+
+	// OP_CHECKMULTISIG is disabled
+	s = newTaprootSpend(t, func(xonly []byte) []byte {
+		leaf, _ := NewBuilder().AddOp(Op0).AddOp(Op1).AddData(xonly).AddOp(Op1).AddOp(OpCheckMultiSig).Script()
+		return leaf
+	})
+	s.tx.TxIn[0].Witness = transaction.Witness{s.scriptSig(t, transaction.SigHashDefault), s.leaf, s.controlBlock()}
+	assert.True(t, errors.Is(VerifyTx(s.tx, s.prevOuts, StandardVerifyFlags), ErrTapscriptCheckMultiSig))
+
+	// OP_SUCCESSx makes the script succeed unless discouraged
+	s = newTaprootSpend(t, func(xonly []byte) []byte {
+		return []byte{OpReturn, 0x50}
+	})
+	s.tx.TxIn[0].Witness = transaction.Witness{s.leaf, s.controlBlock()}
+	assert.NoError(t, VerifyTx(s.tx, s.prevOuts, MandatoryVerifyFlags))
+	assert.True(t, errors.Is(VerifyTx(s.tx, s.prevOuts, StandardVerifyFlags), ErrDiscourageOpSuccess))
+
+	// OP_IF arguments must be minimal
+	s = newTaprootSpend(t, func(xonly []byte) []byte {
+		return []byte{OpIf, Op1, OpEndIf}
+	})
+	s.tx.TxIn[0].Witness = transaction.Witness{{2}, s.leaf, s.controlBlock()}
+	assert.True(t, errors.Is(VerifyTx(s.tx, s.prevOuts, MandatoryVerifyFlags), ErrTapscriptMinimalIf))
+}
+
+func TestVerifyWitnessV0(t *testing.T) {
+	raw, _ := hex.DecodeString("0000000000000000000000000000000000000000000000000000000000000001")
+	key, _ := secp256k1.PrivKeyFromBytes(raw)
+	pub := key.PubKey().SerializeCompressed()
+
+	pkScript, _ := PayToWitnessPubKeyHash(hdwallet.Hash160(pub))
+
+	var prevHash transaction.Hash
+	prevOut := transaction.NewOutPoint(&prevHash, 1)
+
+	tx := transaction.NewTx(2)
+	tx.AddTxIn(transaction.NewTxIn(prevOut, nil, nil))
+	tx.AddTxOut(transaction.NewTxOut(1000, pkScript))
+	prevOuts := transaction.PrevOutputMap{*prevOut: transaction.NewTxOut(2000, pkScript)}
+
+	scriptCode, _ := PayToPubKeyHash(hdwallet.Hash160(pub))
+	h, err := transaction.NewSigHashCache(tx, prevOuts).WitnessV0SigHash(0, scriptCode, 2000, transaction.SigHashAll)
+	assert.NoError(t, err)
+
+	sig, err := secp256k1.Sign(key, h)
+	assert.NoError(t, err)
+
+	tx.TxIn[0].Witness = transaction.Witness{append(sig.Serialize(), byte(transaction.SigHashAll)), pub}
+	assert.NoError(t, VerifyTx(tx, prevOuts, StandardVerifyFlags))
+
+	// the amount is committed to
+	prevOuts[*prevOut].Value = 2001
+	assert.True(t, errors.Is(VerifyTx(tx, prevOuts, StandardVerifyFlags), ErrNullFail))
+
+	assert.Equal(t, ErrMissingSpentOutput, VerifyInput(tx, 0, transaction.PrevOutputMap{}, nil, StandardVerifyFlags))
+}
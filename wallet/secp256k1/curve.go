@@ -0,0 +1,315 @@
+package secp256k1
+
+import (
+	"crypto/subtle"
+	"math/big"
+)
+
+var (
+	// P is the prime of the field the curve is defined over
+	P, _ = new(big.Int).SetString("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f", 16)
+	// N is the order of the base point
+	N, _ = new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+	// B is the constant of the curve equation y² = x³ + 7
+	B = big.NewInt(7)
+	// Gx is the x coordinate of the base point
+	Gx, _ = new(big.Int).SetString("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798", 16)
+	// Gy is the y coordinate of the base point
+	Gy, _ = new(big.Int).SetString("483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8", 16)
+
+	// sqrtExp is (P + 1) / 4, P is congruent to 3 mod 4
+	sqrtExp = new(big.Int).Rsh(new(big.Int).Add(P, big.NewInt(1)), 2)
+)
+
+// IsOnCurve reports whether the given (x,y) lies on the curve, the point at
+// infinity is represented by (0,0) and is not considered on the curve
+func IsOnCurve(x, y *big.Int) bool {
+
+	if x.Sign() < 0 || x.Cmp(P) >= 0 || y.Sign() < 0 || y.Cmp(P) >= 0 {
+		return false
+	}
+
+	// y² = x³ + 7
+	y2 := new(big.Int).Mul(y, y)
+	y2.Mod(y2, P)
+
+	return y2.Cmp(polynomial(x)) == 0
+}
+
+// DecompressY returns the y coordinate of the point with the given x
+// coordinate and parity, false if x is not on the curve
+func DecompressY(x *big.Int, odd bool) (*big.Int, bool) {
+
+	if x.Sign() < 0 || x.Cmp(P) >= 0 {
+		return nil, false
+	}
+
+	y2 := polynomial(x)
+	y := new(big.Int).Exp(y2, sqrtExp, P)
+
+	check := new(big.Int).Mul(y, y)
+	if check.Mod(check, P).Cmp(y2) != 0 {
+		return nil, false
+	}
+
+	if (y.Bit(0) == 1) != odd {
+		y.Sub(P, y)
+	}
+
+	return y, true
+}
+
+// Add returns the sum of (x1,y1) and (x2,y2)
+func Add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+
+	z1 := zForAffine(x1, y1)
+	z2 := zForAffine(x2, y2)
+
+	return affineFromJacobian(addJacobian(x1, y1, z1, x2, y2, z2))
+}
+
+// Negate returns the inverse of (x,y)
+func Negate(x, y *big.Int) (*big.Int, *big.Int) {
+
+	if y.Sign() == 0 {
+		return new(big.Int).Set(x), new(big.Int)
+	}
+
+	return new(big.Int).Set(x), new(big.Int).Sub(P, y)
+}
+
+// ScalarMult returns k*(x,y) where k is a big endian integer, its running time
+// depends on the bits of k so it must only be used with public scalars
+func ScalarMult(x, y *big.Int, k []byte) (*big.Int, *big.Int) {
+
+	z := zForAffine(x, y)
+	rx, ry, rz := new(big.Int), new(big.Int), new(big.Int)
+
+	for _, b := range k {
+		for bit := 0; bit < 8; bit++ {
+			rx, ry, rz = doubleJacobian(rx, ry, rz)
+			if b&0x80 == 0x80 {
+				rx, ry, rz = addJacobian(x, y, z, rx, ry, rz)
+			}
+			b <<= 1
+		}
+	}
+
+	return affineFromJacobian(rx, ry, rz)
+}
+
+// ScalarBaseMult returns k*G where G is the base point, k must be public
+func ScalarBaseMult(k []byte) (*big.Int, *big.Int) {
+	return ScalarMult(Gx, Gy, k)
+}
+
+// ScalarMultSecret returns k*(x,y) with a montgomery ladder that performs the
+// same sequence of point operations whatever the value of k, it must be used
+// whenever k is a private key or a nonce. The field arithmetic of math/big
+// is not constant time so the ladder only hides the bits of k from the
+// sequence of operations, not from their timing.
+func ScalarMultSecret(x, y *big.Int, k []byte) (*big.Int, *big.Int) {
+
+	// k+N or k+2N, whichever is 257 bits long, so that the ladder always runs
+	// the same number of steps and never meets the point at infinity
+	s := new(big.Int).SetBytes(k)
+	s.Mod(s, N)
+	s.Add(s, N)
+
+	var scalar, alt [33]byte
+	s.FillBytes(scalar[:])
+	s.Add(s, N)
+	s.FillBytes(alt[:])
+	subtle.ConstantTimeCopy(1-int(scalar[0]), scalar[:], alt[:])
+
+	r0 := [3]*big.Int{new(big.Int).Set(x), new(big.Int).Set(y), zForAffine(x, y)}
+	r1 := [3]*big.Int{}
+	r1[0], r1[1], r1[2] = doubleJacobian(r0[0], r0[1], r0[2])
+
+	for i := 255; i >= 0; i-- {
+		bit := int(scalar[32-i/8]>>(i%8)) & 1
+
+		condSwap(&r0, &r1, bit)
+		r1[0], r1[1], r1[2] = addJacobian(r0[0], r0[1], r0[2], r1[0], r1[1], r1[2])
+		r0[0], r0[1], r0[2] = doubleJacobian(r0[0], r0[1], r0[2])
+		condSwap(&r0, &r1, bit)
+	}
+
+	return affineFromJacobian(r0[0], r0[1], r0[2])
+}
+
+// ScalarBaseMultSecret returns k*G for a secret scalar k
+func ScalarBaseMultSecret(k []byte) (*big.Int, *big.Int) {
+	return ScalarMultSecret(Gx, Gy, k)
+}
+
+// condSwap swaps the jacobian points a and b if swap is 1, without branching
+// on swap
+func condSwap(a, b *[3]*big.Int, swap int) {
+
+	var ab, bb, tmp [32]byte
+
+	for i := range a {
+		a[i].FillBytes(ab[:])
+		b[i].FillBytes(bb[:])
+
+		copy(tmp[:], ab[:])
+		subtle.ConstantTimeCopy(swap, ab[:], bb[:])
+		subtle.ConstantTimeCopy(swap, bb[:], tmp[:])
+
+		a[i] = new(big.Int).SetBytes(ab[:])
+		b[i] = new(big.Int).SetBytes(bb[:])
+	}
+}
+
+// polynomial returns x³ + 7 mod P
+func polynomial(x *big.Int) *big.Int {
+
+	x3 := new(big.Int).Mul(x, x)
+	x3.Mul(x3, x)
+	x3.Add(x3, B)
+
+	return x3.Mod(x3, P)
+}
+
+// zForAffine returns the jacobian z coordinate of an affine point
+func zForAffine(x, y *big.Int) *big.Int {
+
+	z := new(big.Int)
+	if x.Sign() != 0 || y.Sign() != 0 {
+		z.SetInt64(1)
+	}
+
+	return z
+}
+
+// affineFromJacobian converts (x,y,z) to affine coordinates
+func affineFromJacobian(x, y, z *big.Int) (*big.Int, *big.Int) {
+
+	if z.Sign() == 0 {
+		return new(big.Int), new(big.Int)
+	}
+
+	zinv := new(big.Int).ModInverse(z, P)
+	zinvsq := new(big.Int).Mul(zinv, zinv)
+
+	xOut := new(big.Int).Mul(x, zinvsq)
+	xOut.Mod(xOut, P)
+
+	zinvsq.Mul(zinvsq, zinv)
+	yOut := new(big.Int).Mul(y, zinvsq)
+	yOut.Mod(yOut, P)
+
+	return xOut, yOut
+}
+
+// addJacobian adds two points in jacobian coordinates
+// http://hyperelliptic.org/EFD/g1p/auto-shortw-jacobian-0.html#addition-add-2007-bl
+func addJacobian(x1, y1, z1, x2, y2, z2 *big.Int) (*big.Int, *big.Int, *big.Int) {
+
+	if z1.Sign() == 0 {
+		return new(big.Int).Set(x2), new(big.Int).Set(y2), new(big.Int).Set(z2)
+	}
+	if z2.Sign() == 0 {
+		return new(big.Int).Set(x1), new(big.Int).Set(y1), new(big.Int).Set(z1)
+	}
+
+	z1z1 := new(big.Int).Mul(z1, z1)
+	z1z1.Mod(z1z1, P)
+	z2z2 := new(big.Int).Mul(z2, z2)
+	z2z2.Mod(z2z2, P)
+
+	u1 := new(big.Int).Mul(x1, z2z2)
+	u1.Mod(u1, P)
+	u2 := new(big.Int).Mul(x2, z1z1)
+	u2.Mod(u2, P)
+
+	s1 := new(big.Int).Mul(y1, z2)
+	s1.Mul(s1, z2z2)
+	s1.Mod(s1, P)
+	s2 := new(big.Int).Mul(y2, z1)
+	s2.Mul(s2, z1z1)
+	s2.Mod(s2, P)
+
+	h := new(big.Int).Sub(u2, u1)
+	h.Mod(h, P)
+	r := new(big.Int).Sub(s2, s1)
+	r.Mod(r, P)
+
+	if h.Sign() == 0 {
+		if r.Sign() == 0 {
+			return doubleJacobian(x1, y1, z1)
+		}
+		return new(big.Int), new(big.Int), new(big.Int)
+	}
+
+	i := new(big.Int).Lsh(h, 1)
+	i.Mul(i, i)
+	j := new(big.Int).Mul(h, i)
+	r.Lsh(r, 1)
+	v := new(big.Int).Mul(u1, i)
+
+	x3 := new(big.Int).Mul(r, r)
+	x3.Sub(x3, j)
+	x3.Sub(x3, v)
+	x3.Sub(x3, v)
+	x3.Mod(x3, P)
+
+	y3 := new(big.Int).Sub(v, x3)
+	y3.Mul(y3, r)
+	s1.Mul(s1, j)
+	s1.Lsh(s1, 1)
+	y3.Sub(y3, s1)
+	y3.Mod(y3, P)
+
+	z3 := new(big.Int).Add(z1, z2)
+	z3.Mul(z3, z3)
+	z3.Sub(z3, z1z1)
+	z3.Sub(z3, z2z2)
+	z3.Mul(z3, h)
+	z3.Mod(z3, P)
+
+	return x3, y3, z3
+}
+
+// doubleJacobian doubles a point in jacobian coordinates
+// http://hyperelliptic.org/EFD/g1p/auto-shortw-jacobian-0.html#doubling-dbl-2009-l
+func doubleJacobian(x, y, z *big.Int) (*big.Int, *big.Int, *big.Int) {
+
+	if z.Sign() == 0 || y.Sign() == 0 {
+		return new(big.Int), new(big.Int), new(big.Int)
+	}
+
+	a := new(big.Int).Mul(x, x)
+	a.Mod(a, P)
+	b := new(big.Int).Mul(y, y)
+	b.Mod(b, P)
+	c := new(big.Int).Mul(b, b)
+	c.Mod(c, P)
+
+	d := new(big.Int).Add(x, b)
+	d.Mul(d, d)
+	d.Sub(d, a)
+	d.Sub(d, c)
+	d.Lsh(d, 1)
+	d.Mod(d, P)
+
+	e := new(big.Int).Mul(big.NewInt(3), a)
+	f := new(big.Int).Mul(e, e)
+
+	x3 := new(big.Int).Lsh(d, 1)
+	x3.Sub(f, x3)
+	x3.Mod(x3, P)
+
+	y3 := new(big.Int).Sub(d, x3)
+	y3.Mul(e, y3)
+	c.Lsh(c, 3)
+	y3.Sub(y3, c)
+	y3.Mod(y3, P)
+
+	z3 := new(big.Int).Mul(y, z)
+	z3.Lsh(z3, 1)
+	z3.Mod(z3, P)
+
+	return x3, y3, z3
+}
@@ -0,0 +1,125 @@
+package secp256k1
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type pointtest struct {
+	k string
+	x string
+	y string
+}
+
+func pointTestVector() []pointtest {
+	return []pointtest{
+		{
+			k: "0000000000000000000000000000000000000000000000000000000000000001",
+			x: "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+			y: "483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8",
+		},
+		{
+			k: "0000000000000000000000000000000000000000000000000000000000000002",
+			x: "c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5",
+			y: "1ae168fea63dc339a3c58419466ceaeef7f632653266d0e1236431a950cfe52a",
+		},
+		{
+			k: "0000000000000000000000000000000000000000000000000000000000000003",
+			x: "f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9",
+			y: "388f7b0f632de8140fe337e62a37f3566500a99934c2231b6cb9fd7584b8e672",
+		},
+		{
+			k: "0c28fca386c7a227600b2fe50b7cae11ec86d3bf1fbe471be89827e19d72aa1d",
+			x: "d0de0aaeaefad02b8bdc8a01a1b8b11c696bd3d66a2c5f10780d95b7df42645c",
+			y: "d85228a6fb29940e858e7e55842ae2bd115d1ed7cc0e82d934e929c97648cb0a",
+		},
+		{
+			k: "fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364140",
+			x: "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+			y: "b7c52588d95c3b9aa25b0403f1eef75702e84bb7597aabe663b82f6f04ef2777",
+		},
+	}
+}
+
+func TestScalarBaseMult(t *testing.T) {
+	for _, test := range pointTestVector() {
+		k, err := hex.DecodeString(test.k)
+		assert.NoError(t, err)
+
+		x, y := ScalarBaseMult(k)
+		assert.Equal(t, test.x, hex.EncodeToString(PaddedBytes(x, 32)))
+		assert.Equal(t, test.y, hex.EncodeToString(PaddedBytes(y, 32)))
+		assert.True(t, IsOnCurve(x, y))
+	}
+}
+
+func TestScalarBaseMultOrder(t *testing.T) {
+	x, y := ScalarBaseMult(N.Bytes())
+	assert.Equal(t, 0, x.Sign())
+	assert.Equal(t, 0, y.Sign())
+}
+
+func TestScalarMultSecret(t *testing.T) {
+	for _, test := range pointTestVector() {
+		k, err := hex.DecodeString(test.k)
+		assert.NoError(t, err)
+
+		x, y := ScalarBaseMultSecret(k)
+		assert.Equal(t, test.x, hex.EncodeToString(PaddedBytes(x, 32)))
+		assert.Equal(t, test.y, hex.EncodeToString(PaddedBytes(y, 32)))
+	}
+
+	px, py := ScalarBaseMult([]byte{7})
+	scalars := [][]byte{
+		{},
+		{1},
+		{2},
+		N.Bytes(),
+		new(big.Int).Sub(N, big.NewInt(1)).Bytes(),
+		new(big.Int).Add(N, big.NewInt(5)).Bytes(),
+		bytes.Repeat([]byte{0xff}, 32),
+		bytes.Repeat([]byte{0x5a}, 32),
+	}
+	for _, k := range scalars {
+		ex, ey := ScalarMult(px, py, new(big.Int).Mod(new(big.Int).SetBytes(k), N).Bytes())
+		x, y := ScalarMultSecret(px, py, k)
+		assert.Equal(t, ex, x, hex.EncodeToString(k))
+		assert.Equal(t, ey, y, hex.EncodeToString(k))
+	}
+}
+
+func TestAdd(t *testing.T) {
+	x2, y2 := Add(Gx, Gy, Gx, Gy)
+	x3, y3 := Add(x2, y2, Gx, Gy)
+
+	ex3, ey3 := ScalarBaseMult([]byte{3})
+	assert.Equal(t, ex3, x3)
+	assert.Equal(t, ey3, y3)
+
+	nx, ny := Negate(Gx, Gy)
+	ix, iy := Add(Gx, Gy, nx, ny)
+	assert.Equal(t, 0, ix.Sign())
+	assert.Equal(t, 0, iy.Sign())
+
+	sx, sy := Add(ix, iy, Gx, Gy)
+	assert.Equal(t, Gx, sx)
+	assert.Equal(t, Gy, sy)
+}
+
+func TestDecompressY(t *testing.T) {
+	y, ok := DecompressY(Gx, false)
+	assert.True(t, ok)
+	assert.Equal(t, Gy, y)
+
+	y, ok = DecompressY(Gx, true)
+	assert.True(t, ok)
+	assert.Equal(t, new(big.Int).Sub(P, Gy), y)
+
+	// x = 5 is not on the curve
+	_, ok = DecompressY(big.NewInt(5), false)
+	assert.False(t, ok)
+}
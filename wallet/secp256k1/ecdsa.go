@@ -0,0 +1,344 @@
+package secp256k1
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+const (
+	// CompactSigLen is the length of a compact recoverable signature
+	CompactSigLen = 65
+
+	compactHeaderBase       = 27
+	compactHeaderCompressed = 4
+
+	derSequence = 0x30
+	derInteger  = 0x02
+)
+
+var (
+	// ErrInvalidSignature is returned when a signature cannot be parsed
+	ErrInvalidSignature = errors.New("secp256k1: invalid signature")
+	// ErrInvalidHash is returned when the digest to sign is not 32 bytes
+	ErrInvalidHash = errors.New("secp256k1: invalid hash length")
+	// ErrRecovery is returned when no public key can be recovered
+	ErrRecovery = errors.New("secp256k1: unable to recover public key")
+
+	// halfN is used to check for low S values
+	halfN = new(big.Int).Rsh(N, 1)
+)
+
+// Signature is an ECDSA signature
+type Signature struct {
+	R, S *big.Int
+}
+
+// Sign signs a 32 bytes digest with a deterministic RFC6979 nonce, the
+// resulting signature always has a low S value
+func Sign(key *PrivateKey, hash []byte) (*Signature, error) {
+
+	sig, _, err := sign(key, hash)
+
+	return sig, err
+}
+
+// SignCompact produces a 65 bytes recoverable signature of the digest, the
+// header byte encodes the recovery id and whether the public key is compressed
+func SignCompact(key *PrivateKey, hash []byte, compressed bool) ([]byte, error) {
+
+	sig, recID, err := sign(key, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	header := byte(compactHeaderBase + recID)
+	if compressed {
+		header += compactHeaderCompressed
+	}
+
+	compact := append([]byte{header}, PaddedBytes(sig.R, 32)...)
+
+	return append(compact, PaddedBytes(sig.S, 32)...), nil
+}
+
+// RecoverCompact recovers the public key from a compact signature of the
+// digest, it also reports whether the signer used a compressed key
+func RecoverCompact(compact []byte, hash []byte) (*PublicKey, bool, error) {
+
+	if len(compact) != CompactSigLen {
+		return nil, false, ErrInvalidSignature
+	}
+
+	header := int(compact[0]) - compactHeaderBase
+	if header < 0 || header > 7 {
+		return nil, false, ErrInvalidSignature
+	}
+
+	sig := &Signature{
+		R: new(big.Int).SetBytes(compact[1:33]),
+		S: new(big.Int).SetBytes(compact[33:]),
+	}
+
+	pub, err := RecoverPubKey(sig, hash, header&3)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return pub, header&compactHeaderCompressed != 0, nil
+}
+
+// RecoverPubKey recovers the public key that produced sig over hash given
+// the recovery id, as described in SEC 1 v2 section 4.1.6
+func RecoverPubKey(sig *Signature, hash []byte, recID int) (*PublicKey, error) {
+
+	if len(hash) != 32 {
+		return nil, ErrInvalidHash
+	}
+
+	if !sig.inRange() || recID < 0 || recID > 3 {
+		return nil, ErrInvalidSignature
+	}
+
+	x := new(big.Int).Set(sig.R)
+	if recID&2 != 0 {
+		x.Add(x, N)
+		if x.Cmp(P) >= 0 {
+			return nil, ErrRecovery
+		}
+	}
+
+	y, ok := DecompressY(x, recID&1 == 1)
+	if !ok {
+		return nil, ErrRecovery
+	}
+
+	// Q = r⁻¹(sR - eG)
+	rInv := new(big.Int).ModInverse(sig.R, N)
+
+	e := new(big.Int).SetBytes(hash)
+	e.Neg(e)
+	e.Mod(e, N)
+
+	sx, sy := ScalarMult(x, y, sig.S.Bytes())
+	ex, ey := ScalarBaseMult(e.Bytes())
+	qx, qy := Add(sx, sy, ex, ey)
+	qx, qy = ScalarMult(qx, qy, rInv.Bytes())
+
+	if qx.Sign() == 0 && qy.Sign() == 0 {
+		return nil, ErrRecovery
+	}
+
+	return &PublicKey{X: qx, Y: qy}, nil
+}
+
+// Verify reports whether sig is a valid signature of hash by pub
+func (sig *Signature) Verify(hash []byte, pub *PublicKey) bool {
+
+	if len(hash) != 32 || !sig.inRange() {
+		return false
+	}
+
+	e := new(big.Int).SetBytes(hash)
+	w := new(big.Int).ModInverse(sig.S, N)
+
+	u1 := e.Mul(e, w)
+	u1.Mod(u1, N)
+	u2 := w.Mul(sig.R, w)
+	u2.Mod(u2, N)
+
+	x1, y1 := ScalarBaseMult(u1.Bytes())
+	x2, y2 := ScalarMult(pub.X, pub.Y, u2.Bytes())
+	x, y := Add(x1, y1, x2, y2)
+
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return false
+	}
+
+	return x.Mod(x, N).Cmp(sig.R) == 0
+}
+
+// IsLowS reports whether S is at most N/2
+func (sig *Signature) IsLowS() bool {
+	return sig.S.Cmp(halfN) <= 0
+}
+
+// Serialize returns the strict DER encoding of the signature
+func (sig *Signature) Serialize() []byte {
+
+	r := derInt(sig.R)
+	s := derInt(sig.S)
+
+	der := []byte{derSequence, byte(4 + len(r) + len(s)), derInteger, byte(len(r))}
+	der = append(der, r...)
+	der = append(der, derInteger, byte(len(s)))
+
+	return append(der, s...)
+}
+
+// ParseDERSignature parses a strict DER signature as required by BIP66
+func ParseDERSignature(der []byte) (*Signature, error) {
+
+	// 0x30 [total-len] 0x02 [R-len] [R] 0x02 [S-len] [S]
+	if len(der) < 8 || len(der) > 72 {
+		return nil, ErrInvalidSignature
+	}
+
+	if der[0] != derSequence || int(der[1]) != len(der)-2 {
+		return nil, ErrInvalidSignature
+	}
+
+	lenR := int(der[3])
+	if der[2] != derInteger || lenR == 0 || 5+lenR >= len(der) {
+		return nil, ErrInvalidSignature
+	}
+
+	lenS := int(der[5+lenR])
+	if der[4+lenR] != derInteger || lenS == 0 || 6+lenR+lenS != len(der) {
+		return nil, ErrInvalidSignature
+	}
+
+	r := der[4 : 4+lenR]
+	s := der[6+lenR:]
+
+	if !canonicalDERInt(r) || !canonicalDERInt(s) {
+		return nil, ErrInvalidSignature
+	}
+
+	sig := &Signature{R: new(big.Int).SetBytes(r), S: new(big.Int).SetBytes(s)}
+	if !sig.inRange() {
+		return nil, ErrInvalidSignature
+	}
+
+	return sig, nil
+}
+
+// sign produces a low S signature and the respective recovery id
+func sign(key *PrivateKey, hash []byte) (*Signature, int, error) {
+
+	if len(hash) != 32 {
+		return nil, 0, ErrInvalidHash
+	}
+
+	nonces := newRFC6979(key.Serialize(), hash, nil)
+
+	for {
+		k := nonces.next()
+
+		rx, ry := ScalarBaseMultSecret(PaddedBytes(k, 32))
+
+		r := new(big.Int).Mod(rx, N)
+		if r.Sign() == 0 {
+			continue
+		}
+
+		recID := int(ry.Bit(0))
+		if rx.Cmp(N) >= 0 {
+			recID |= 2
+		}
+
+		// s = k⁻¹(e + rd)
+		s := new(big.Int).Mul(r, key.D)
+		s.Add(s, new(big.Int).SetBytes(hash))
+		s.Mul(s, new(big.Int).ModInverse(k, N))
+		s.Mod(s, N)
+
+		if s.Sign() == 0 {
+			continue
+		}
+
+		if s.Cmp(halfN) > 0 {
+			s.Sub(N, s)
+			recID ^= 1
+		}
+
+		return &Signature{R: r, S: s}, recID, nil
+	}
+}
+
+func (sig *Signature) inRange() bool {
+	return sig.R.Sign() > 0 && sig.R.Cmp(N) < 0 && sig.S.Sign() > 0 && sig.S.Cmp(N) < 0
+}
+
+// derInt encodes n as the minimal positive DER integer
+func derInt(n *big.Int) []byte {
+
+	b := n.Bytes()
+	if len(b) == 0 || b[0]&0x80 != 0 {
+		b = append([]byte{0x00}, b...)
+	}
+
+	return b
+}
+
+// canonicalDERInt checks that b is a minimal positive DER integer
+func canonicalDERInt(b []byte) bool {
+
+	if b[0]&0x80 != 0 {
+		return false
+	}
+
+	return len(b) == 1 || b[0] != 0x00 || b[1]&0x80 != 0
+}
+
+// rfc6979 generates the deterministic nonces of RFC6979 section 3.2
+// using HMAC-SHA256
+type rfc6979 struct {
+	k, v  []byte
+	first bool
+}
+
+func newRFC6979(key, hash, extra []byte) *rfc6979 {
+
+	h := new(big.Int).SetBytes(hash)
+	h.Mod(h, N)
+
+	data := append(append([]byte{}, key...), PaddedBytes(h, 32)...)
+	data = append(data, extra...)
+
+	g := &rfc6979{
+		k:     make([]byte, 32),
+		v:     make([]byte, 32),
+		first: true,
+	}
+	for i := range g.v {
+		g.v[i] = 0x01
+	}
+
+	g.k = hmacSha256(g.k, g.v, []byte{0x00}, data)
+	g.v = hmacSha256(g.k, g.v)
+	g.k = hmacSha256(g.k, g.v, []byte{0x01}, data)
+	g.v = hmacSha256(g.k, g.v)
+
+	return g
+}
+
+// next returns the next candidate nonce in [1, N-1]
+func (g *rfc6979) next() *big.Int {
+
+	for {
+		if !g.first {
+			g.k = hmacSha256(g.k, g.v, []byte{0x00})
+			g.v = hmacSha256(g.k, g.v)
+		}
+		g.first = false
+
+		g.v = hmacSha256(g.k, g.v)
+
+		k := new(big.Int).SetBytes(g.v)
+		if k.Sign() > 0 && k.Cmp(N) < 0 {
+			return k
+		}
+	}
+}
+
+func hmacSha256(key []byte, data ...[]byte) []byte {
+
+	mac := hmac.New(sha256.New, key)
+	for _, d := range data {
+		mac.Write(d)
+	}
+
+	return mac.Sum(nil)
+}
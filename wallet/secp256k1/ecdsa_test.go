@@ -0,0 +1,213 @@
+package secp256k1
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ecdsatest struct {
+	key     string
+	message string
+	k       string
+	r       string
+	s       string
+}
+
+func ecdsaTestVector() []ecdsatest {
+	return []ecdsatest{
+		{
+			key:     "0000000000000000000000000000000000000000000000000000000000000001",
+			message: "Satoshi Nakamoto",
+			k:       "8f8a276c19f4149656b280621e358cce24f5f52542772691ee69063b74f15d15",
+			r:       "934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d8",
+			s:       "2442ce9d2b916064108014783e923ec36b49743e2ffa1c4496f01a512aafd9e5",
+		},
+		{
+			key:     "0000000000000000000000000000000000000000000000000000000000000001",
+			message: "All those moments will be lost in time, like tears in rain. Time to die...",
+			k:       "38aa22d72376b4dbc472e06c3ba403ee0a394da63fc58d88686c611aba98d6b3",
+			r:       "8600dbd41e348fe5c9465ab92d23e3db8b98b873beecd930736488696438cb6b",
+			s:       "547fe64427496db33bf66019dacbf0039c04199abb0122918601db38a72cfc21",
+		},
+		{
+			key:     "fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364140",
+			message: "Satoshi Nakamoto",
+			k:       "33a19b60e25fb6f4435af53a3d42d493644827367e6453928554f43e49aa6f90",
+			r:       "fd567d121db66e382991534ada77a6bd3106f0a1098c231e47993447cd6af2d0",
+			s:       "6b39cd0eb1bc8603e159ef5c20a5c8ad685a45b06ce9bebed3f153d10d93bed5",
+		},
+		{
+			key:     "f8b8af8ce3c7cca5e300d33939540c10d45ce001b8f252bfbc57ba0342904181",
+			message: "Alan Turing",
+			k:       "525a82b70e67874398067543fd84c83d30c175fdc45fdeee082fe13b1d7cfdf1",
+			r:       "7063ae83e7f62bbb171798131b4a0564b956930092b33b07b395615d9ec7e15c",
+			s:       "58dfcc1e00a35e1572f366ffe34ba0fc47db1e7189759b9fb233c5b05ab388ea",
+		},
+		{
+			key:     "0000000000000000000000000000000000000000000000000000000000000001",
+			message: "Everything should be made as simple as possible, but not simpler.",
+			k:       "ec633bd56a5774a0940cb97e27a9e4e51dc94af737596a0c5cbb3d30332d92a5",
+			r:       "33a69cd2065432a30f3d1ce4eb0d59b8ab58c74f27c41a7fdb5696ad4e6108c9",
+			s:       "6f807982866f785d3f6418d24163ddae117b7db4d5fdf0071de069fa54342262",
+		},
+		{
+			key:     "fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364140",
+			message: "Equations are more important to me, because politics is for the present, but an equation is something for eternity.",
+			k:       "9dc74cbfd383980fb4ae5d2680acddac9dac956dca65a28c80ac9c847c2374e4",
+			r:       "54c4a33c6423d689378f160a7ff8b61330444abb58fb470f96ea16d99d4a2fed",
+			s:       "07082304410efa6b2943111b6a4e0aaa7b7db55a07e9861d1fb3cb1f421044a5",
+		},
+		{
+			key:     "fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364140",
+			message: "Not only is the Universe stranger than we think, it is stranger than we can think.",
+			k:       "fd27071f01648ebbdd3e1cfbae48facc9fa97edc43bbbc9a7fdc28eae13296f5",
+			r:       "ff466a9f1b7b273e2f4c3ffe032eb2e814121ed18ef84665d0f515360dab3dd0",
+			s:       "6fc95f5132e5ecfdc8e5e6e616cc77151455d46ed48f5589b7db7771a332b283",
+		},
+		{
+			key:     "69ec59eaa1f4f2e36b639716b7c30ca86d9a5375c7b38d8918bd9c0ebc80ba64",
+			message: "How wonderful that we have met with a paradox. Now we have some hope of making progress.",
+			k:       "cd1294e7391b006541babd48cc7072139403d4a21827cc0cbcd15128180de5eb",
+			r:       "28505f57aebfcc2ff7212240876bfe85e0681b3cb8a9382c02cbe74ed27eb30b",
+			s:       "667e450950802d4523816b140acc7ae073da55941b21be332d52ef20c62355da",
+		},
+		{
+			key:     "00000000000000000000000000007246174ab1e92e9149c6e446fe194d072637",
+			message: "...if you aren't, at any given time, scandalized by code you wrote five or even three years ago, you're not learning anywhere near enough",
+			k:       "097b5c8ee22c3ea78a4d3635e0ff6fe85a1eb92ce317ded90b9e71aab2b861cb",
+			r:       "fbfe5076a15860ba8ed00e75e9bd22e05d230f02a936b653eb55b61c99dda487",
+			s:       "0e68880ebb0050fe4312b1b1eb0899e1b82da89baa5b895f612619edf34cbd37",
+		},
+		{
+			key:     "000000000000000000000000000000000000000000056916d0f9b31dc9b637f3",
+			message: "The question of whether computers can think is like the question of whether submarines can swim.",
+			k:       "19355c36c8cbcdfb2382e23b194b79f8c97bf650040fc7728dfbf6b39a97c25b",
+			r:       "cde1302d83f8dd835d89aef803c74a119f561fbaef3eb9129e45f30de86abbf9",
+			s:       "06ce643f5049ee1f27890467b77a6a8e11ec4661cc38cd8badf90115fbd03cef",
+		},
+	}
+}
+
+func TestRFC6979(t *testing.T) {
+	for _, test := range ecdsaTestVector() {
+		key, _ := hex.DecodeString(test.key)
+		hash := sha256.Sum256([]byte(test.message))
+
+		k := newRFC6979(key, hash[:], nil).next()
+		assert.Equal(t, test.k, hex.EncodeToString(PaddedBytes(k, 32)))
+	}
+}
+
+func TestSign(t *testing.T) {
+	for _, test := range ecdsaTestVector() {
+		b, _ := hex.DecodeString(test.key)
+		hash := sha256.Sum256([]byte(test.message))
+
+		key, err := PrivKeyFromBytes(b)
+		assert.NoError(t, err)
+
+		sig, err := Sign(key, hash[:])
+		assert.NoError(t, err)
+
+		assert.Equal(t, test.r, hex.EncodeToString(PaddedBytes(sig.R, 32)))
+		assert.Equal(t, test.s, hex.EncodeToString(PaddedBytes(sig.S, 32)))
+		assert.True(t, sig.IsLowS())
+		assert.True(t, sig.Verify(hash[:], key.PubKey()))
+
+		hash[0] ^= 0x01
+		assert.False(t, sig.Verify(hash[:], key.PubKey()))
+	}
+}
+
+func TestDERSignature(t *testing.T) {
+	for _, test := range ecdsaTestVector() {
+		b, _ := hex.DecodeString(test.key)
+		hash := sha256.Sum256([]byte(test.message))
+
+		key, _ := PrivKeyFromBytes(b)
+		sig, _ := Sign(key, hash[:])
+
+		parsed, err := ParseDERSignature(sig.Serialize())
+		assert.NoError(t, err)
+		assert.Equal(t, sig.R, parsed.R)
+		assert.Equal(t, sig.S, parsed.S)
+	}
+}
+
+func TestDERSignatureSerialize(t *testing.T) {
+	// r has its high bit set and needs a padding byte
+	sig, err := ParseDERSignature(mustHex("3045022100fd567d121db66e382991534ada77a6bd3106f0a1098c231e47993447cd6af2d002206b39cd0eb1bc8603e159ef5c20a5c8ad685a45b06ce9bebed3f153d10d93bed5"))
+	assert.NoError(t, err)
+	assert.Equal(t, "fd567d121db66e382991534ada77a6bd3106f0a1098c231e47993447cd6af2d0", hex.EncodeToString(sig.R.Bytes()))
+	assert.Equal(t, "3045022100fd567d121db66e382991534ada77a6bd3106f0a1098c231e47993447cd6af2d002206b39cd0eb1bc8603e159ef5c20a5c8ad685a45b06ce9bebed3f153d10d93bed5", hex.EncodeToString(sig.Serialize()))
+}
+
+func TestDERSignatureInvalid(t *testing.T) {
+	invalid := []string{
+		// too short
+		"30050201010201",
+		// wrong sequence tag
+		"3144022034",
+		// total length mismatch
+		"3046022100fd567d121db66e382991534ada77a6bd3106f0a1098c231e47993447cd6af2d002206b39cd0eb1bc8603e159ef5c20a5c8ad685a45b06ce9bebed3f153d10d93bed5",
+		// negative r
+		"30440220fd567d121db66e382991534ada77a6bd3106f0a1098c231e47993447cd6af2d002206b39cd0eb1bc8603e159ef5c20a5c8ad685a45b06ce9bebed3f153d10d93bed5",
+		// superfluous padding of s
+		"3046022100fd567d121db66e382991534ada77a6bd3106f0a1098c231e47993447cd6af2d00221006b39cd0eb1bc8603e159ef5c20a5c8ad685a45b06ce9bebed3f153d10d93bed5",
+		// zero length s
+		"3026022100fd567d121db66e382991534ada77a6bd3106f0a1098c231e47993447cd6af2d00200",
+	}
+
+	for _, test := range invalid {
+		_, err := ParseDERSignature(mustHex(test))
+		assert.Equal(t, ErrInvalidSignature, err, test)
+	}
+}
+
+func TestSignCompact(t *testing.T) {
+	for _, test := range ecdsaTestVector() {
+		b, _ := hex.DecodeString(test.key)
+		hash := sha256.Sum256([]byte(test.message))
+
+		key, _ := PrivKeyFromBytes(b)
+
+		for _, compressed := range []bool{false, true} {
+			compact, err := SignCompact(key, hash[:], compressed)
+			assert.NoError(t, err)
+			assert.Len(t, compact, CompactSigLen)
+			assert.Equal(t, test.r, hex.EncodeToString(compact[1:33]))
+
+			pub, wasCompressed, err := RecoverCompact(compact, hash[:])
+			assert.NoError(t, err)
+			assert.Equal(t, compressed, wasCompressed)
+			assert.True(t, pub.IsEqual(key.PubKey()))
+		}
+	}
+}
+
+func TestRecoverCompactInvalid(t *testing.T) {
+	hash := sha256.Sum256([]byte("Satoshi Nakamoto"))
+
+	_, _, err := RecoverCompact(make([]byte, 64), hash[:])
+	assert.Equal(t, ErrInvalidSignature, err)
+
+	compact := make([]byte, CompactSigLen)
+	compact[0] = 35
+	_, _, err = RecoverCompact(compact, hash[:])
+	assert.Equal(t, ErrInvalidSignature, err)
+
+	compact[0] = 27
+	_, _, err = RecoverCompact(compact, hash[:])
+	assert.Equal(t, ErrInvalidSignature, err)
+}
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
@@ -0,0 +1,145 @@
+package secp256k1
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+)
+
+const (
+	// PrivKeyLen is the length of a serialized private key
+	PrivKeyLen = 32
+	// PubKeyCompressedLen is the length of a compressed public key
+	PubKeyCompressedLen = 33
+	// PubKeyUncompressedLen is the length of an uncompressed public key
+	PubKeyUncompressedLen = 65
+
+	pubKeyCompressedEven = 0x02
+	pubKeyCompressedOdd  = 0x03
+	pubKeyUncompressed   = 0x04
+)
+
+var (
+	// ErrInvalidPrivateKey is returned when a private key is out of range
+	ErrInvalidPrivateKey = errors.New("secp256k1: invalid private key")
+	// ErrInvalidPublicKey is returned when a public key cannot be parsed
+	ErrInvalidPublicKey = errors.New("secp256k1: invalid public key")
+)
+
+// PublicKey is a point on the secp256k1 curve
+type PublicKey struct {
+	X, Y *big.Int
+}
+
+// PrivateKey is a secp256k1 scalar together with its public key
+type PrivateKey struct {
+	PublicKey
+	D *big.Int
+}
+
+// NewPrivateKey generates a new random private key
+func NewPrivateKey() (*PrivateKey, error) {
+
+	b := make([]byte, PrivKeyLen)
+
+	for {
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+
+		if key, err := PrivKeyFromBytes(b); err == nil {
+			return key, nil
+		}
+	}
+}
+
+// PrivKeyFromBytes returns the private key for the given 32 bytes big endian
+// scalar, which must be in [1, N-1]
+func PrivKeyFromBytes(b []byte) (*PrivateKey, error) {
+
+	if len(b) != PrivKeyLen {
+		return nil, ErrInvalidPrivateKey
+	}
+
+	d := new(big.Int).SetBytes(b)
+	if d.Sign() == 0 || d.Cmp(N) >= 0 {
+		return nil, ErrInvalidPrivateKey
+	}
+
+	x, y := ScalarBaseMultSecret(b)
+
+	return &PrivateKey{PublicKey: PublicKey{X: x, Y: y}, D: d}, nil
+}
+
+// Serialize returns the private key as a 32 bytes big endian integer
+func (k *PrivateKey) Serialize() []byte {
+	return PaddedBytes(k.D, PrivKeyLen)
+}
+
+// PubKey returns the public key of the private key
+func (k *PrivateKey) PubKey() *PublicKey {
+	return &k.PublicKey
+}
+
+// ParsePubKey parses a compressed or uncompressed public key
+func ParsePubKey(b []byte) (*PublicKey, error) {
+
+	switch {
+	case len(b) == PubKeyCompressedLen && (b[0] == pubKeyCompressedEven || b[0] == pubKeyCompressedOdd):
+		x := new(big.Int).SetBytes(b[1:])
+
+		y, ok := DecompressY(x, b[0] == pubKeyCompressedOdd)
+		if !ok {
+			return nil, ErrInvalidPublicKey
+		}
+
+		return &PublicKey{X: x, Y: y}, nil
+
+	case len(b) == PubKeyUncompressedLen && b[0] == pubKeyUncompressed:
+		x := new(big.Int).SetBytes(b[1:33])
+		y := new(big.Int).SetBytes(b[33:])
+
+		if !IsOnCurve(x, y) {
+			return nil, ErrInvalidPublicKey
+		}
+
+		return &PublicKey{X: x, Y: y}, nil
+	}
+
+	return nil, ErrInvalidPublicKey
+}
+
+// SerializeCompressed returns the 33 bytes compressed encoding of the key
+func (k *PublicKey) SerializeCompressed() []byte {
+
+	prefix := byte(pubKeyCompressedEven)
+	if k.Y.Bit(0) == 1 {
+		prefix = pubKeyCompressedOdd
+	}
+
+	return append([]byte{prefix}, PaddedBytes(k.X, 32)...)
+}
+
+// SerializeUncompressed returns the 65 bytes uncompressed encoding of the key
+func (k *PublicKey) SerializeUncompressed() []byte {
+
+	b := append([]byte{pubKeyUncompressed}, PaddedBytes(k.X, 32)...)
+
+	return append(b, PaddedBytes(k.Y, 32)...)
+}
+
+// IsEqual reports whether two public keys are the same point
+func (k *PublicKey) IsEqual(other *PublicKey) bool {
+	return k.X.Cmp(other.X) == 0 && k.Y.Cmp(other.Y) == 0
+}
+
+// PaddedBytes returns the big endian encoding of n left padded to size bytes
+func PaddedBytes(n *big.Int, size int) []byte {
+
+	b := n.Bytes()
+	if len(b) >= size {
+		return b
+	}
+
+	return append(make([]byte, size-len(b)), b...)
+}
@@ -0,0 +1,95 @@
+package secp256k1
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type keytest struct {
+	priv         string
+	compressed   string
+	uncompressed string
+}
+
+func keyTestVector() []keytest {
+	return []keytest{
+		{
+			priv:         "0c28fca386c7a227600b2fe50b7cae11ec86d3bf1fbe471be89827e19d72aa1d",
+			compressed:   "02d0de0aaeaefad02b8bdc8a01a1b8b11c696bd3d66a2c5f10780d95b7df42645c",
+			uncompressed: "04d0de0aaeaefad02b8bdc8a01a1b8b11c696bd3d66a2c5f10780d95b7df42645cd85228a6fb29940e858e7e55842ae2bd115d1ed7cc0e82d934e929c97648cb0a",
+		},
+		{
+			priv:         "0000000000000000000000000000000000000000000000000000000000000003",
+			compressed:   "02f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9",
+			uncompressed: "04f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9388f7b0f632de8140fe337e62a37f3566500a99934c2231b6cb9fd7584b8e672",
+		},
+		{
+			priv:         "fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364140",
+			compressed:   "0379be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+			uncompressed: "0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798b7c52588d95c3b9aa25b0403f1eef75702e84bb7597aabe663b82f6f04ef2777",
+		},
+	}
+}
+
+func TestPrivKeyFromBytes(t *testing.T) {
+	for _, test := range keyTestVector() {
+		b, _ := hex.DecodeString(test.priv)
+
+		key, err := PrivKeyFromBytes(b)
+		assert.NoError(t, err)
+
+		assert.Equal(t, test.priv, hex.EncodeToString(key.Serialize()))
+		assert.Equal(t, test.compressed, hex.EncodeToString(key.PubKey().SerializeCompressed()))
+		assert.Equal(t, test.uncompressed, hex.EncodeToString(key.PubKey().SerializeUncompressed()))
+	}
+}
+
+func TestPrivKeyFromBytesInvalid(t *testing.T) {
+	_, err := PrivKeyFromBytes(make([]byte, 32))
+	assert.Equal(t, ErrInvalidPrivateKey, err)
+
+	_, err = PrivKeyFromBytes(N.Bytes())
+	assert.Equal(t, ErrInvalidPrivateKey, err)
+
+	_, err = PrivKeyFromBytes([]byte{1})
+	assert.Equal(t, ErrInvalidPrivateKey, err)
+}
+
+func TestParsePubKey(t *testing.T) {
+	for _, test := range keyTestVector() {
+		compressed, _ := hex.DecodeString(test.compressed)
+		uncompressed, _ := hex.DecodeString(test.uncompressed)
+
+		a, err := ParsePubKey(compressed)
+		assert.NoError(t, err)
+
+		b, err := ParsePubKey(uncompressed)
+		assert.NoError(t, err)
+
+		assert.True(t, a.IsEqual(b))
+	}
+}
+
+func TestParsePubKeyInvalid(t *testing.T) {
+	invalid := []string{
+		"",
+		"05d0de0aaeaefad02b8bdc8a01a1b8b11c696bd3d66a2c5f10780d95b7df42645c",
+		"020000000000000000000000000000000000000000000000000000000000000005",
+		"04d0de0aaeaefad02b8bdc8a01a1b8b11c696bd3d66a2c5f10780d95b7df42645cd85228a6fb29940e858e7e55842ae2bd115d1ed7cc0e82d934e929c97648cb0b",
+	}
+
+	for _, test := range invalid {
+		b, _ := hex.DecodeString(test)
+
+		_, err := ParsePubKey(b)
+		assert.Equal(t, ErrInvalidPublicKey, err, test)
+	}
+}
+
+func TestNewPrivateKey(t *testing.T) {
+	key, err := NewPrivateKey()
+	assert.NoError(t, err)
+	assert.True(t, IsOnCurve(key.X, key.Y))
+}
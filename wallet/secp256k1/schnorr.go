@@ -0,0 +1,158 @@
+package secp256k1
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+const (
+	// SchnorrSigLen is the length of a BIP340 signature
+	SchnorrSigLen = 64
+	// XOnlyPubKeyLen is the length of a BIP340 x-only public key
+	XOnlyPubKeyLen = 32
+)
+
+var (
+	// ErrInvalidAuxRand is returned when the auxiliary randomness is not 32 bytes
+	ErrInvalidAuxRand = errors.New("secp256k1: invalid auxiliary randomness")
+	// ErrSignatureCheck is returned when a freshly produced signature does not verify
+	ErrSignatureCheck = errors.New("secp256k1: produced signature does not verify")
+)
+
+// TaggedHash returns SHA256(SHA256(tag) || SHA256(tag) || msgs) as defined by BIP340
+func TaggedHash(tag string, msgs ...[]byte) []byte {
+
+	tagHash := sha256.Sum256([]byte(tag))
+
+	h := sha256.New()
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	for _, m := range msgs {
+		h.Write(m)
+	}
+
+	return h.Sum(nil)
+}
+
+// ParseXOnlyPubKey returns the point with even y for the given 32 bytes x
+// coordinate
+func ParseXOnlyPubKey(b []byte) (*PublicKey, error) {
+
+	if len(b) != XOnlyPubKeyLen {
+		return nil, ErrInvalidPublicKey
+	}
+
+	x := new(big.Int).SetBytes(b)
+
+	y, ok := DecompressY(x, false)
+	if !ok {
+		return nil, ErrInvalidPublicKey
+	}
+
+	return &PublicKey{X: x, Y: y}, nil
+}
+
+// SerializeXOnly returns the 32 bytes x coordinate of the key
+func (k *PublicKey) SerializeXOnly() []byte {
+	return PaddedBytes(k.X, XOnlyPubKeyLen)
+}
+
+// HasEvenY reports whether the y coordinate of the key is even
+func (k *PublicKey) HasEvenY() bool {
+	return k.Y.Bit(0) == 0
+}
+
+// SchnorrSign produces a BIP340 signature of msg, auxRand must be 32 bytes
+// of fresh randomness, when nil it is read from crypto/rand
+func SchnorrSign(key *PrivateKey, msg []byte, auxRand []byte) ([]byte, error) {
+
+	if auxRand == nil {
+		auxRand = make([]byte, 32)
+		if _, err := rand.Read(auxRand); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(auxRand) != 32 {
+		return nil, ErrInvalidAuxRand
+	}
+
+	d := new(big.Int).Set(key.D)
+	if !key.HasEvenY() {
+		d.Sub(N, d)
+	}
+
+	px := key.SerializeXOnly()
+
+	t := TaggedHash("BIP0340/aux", auxRand)
+	db := PaddedBytes(d, 32)
+	for i := range t {
+		t[i] ^= db[i]
+	}
+
+	k := new(big.Int).SetBytes(TaggedHash("BIP0340/nonce", t, px, msg))
+	k.Mod(k, N)
+	if k.Sign() == 0 {
+		return nil, ErrInvalidPrivateKey
+	}
+
+	rx, ry := ScalarBaseMultSecret(PaddedBytes(k, 32))
+	if ry.Bit(0) == 1 {
+		k.Sub(N, k)
+	}
+
+	r := PaddedBytes(rx, 32)
+
+	e := new(big.Int).SetBytes(TaggedHash("BIP0340/challenge", r, px, msg))
+	e.Mod(e, N)
+
+	s := e.Mul(e, d)
+	s.Add(s, k)
+	s.Mod(s, N)
+
+	sig := append(r, PaddedBytes(s, 32)...)
+
+	if !SchnorrVerify(px, msg, sig) {
+		return nil, ErrSignatureCheck
+	}
+
+	return sig, nil
+}
+
+// SchnorrVerify reports whether sig is a valid BIP340 signature of msg for
+// the x-only public key pubKey
+func SchnorrVerify(pubKey []byte, msg []byte, sig []byte) bool {
+
+	if len(sig) != SchnorrSigLen {
+		return false
+	}
+
+	pub, err := ParseXOnlyPubKey(pubKey)
+	if err != nil {
+		return false
+	}
+
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+
+	if r.Cmp(P) >= 0 || s.Cmp(N) >= 0 {
+		return false
+	}
+
+	e := new(big.Int).SetBytes(TaggedHash("BIP0340/challenge", sig[:32], pubKey, msg))
+	e.Mod(e, N)
+	e.Sub(N, e)
+
+	// R = sG - eP
+	sx, sy := ScalarBaseMult(s.Bytes())
+	ex, ey := ScalarMult(pub.X, pub.Y, e.Bytes())
+	rx, ry := Add(sx, sy, ex, ey)
+
+	if rx.Sign() == 0 && ry.Sign() == 0 {
+		return false
+	}
+
+	return ry.Bit(0) == 0 && rx.Cmp(r) == 0
+}
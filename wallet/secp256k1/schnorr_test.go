@@ -0,0 +1,97 @@
+package secp256k1
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type schnorrtest struct {
+	index     string
+	secretKey string
+	publicKey string
+	auxRand   string
+	message   string
+	signature string
+	valid     bool
+}
+
+// schnorrTestVector loads the BIP340 test vectors from testdata
+func schnorrTestVector(t *testing.T) []schnorrtest {
+	f, err := os.Open("testdata/bip340_vectors.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests []schnorrtest
+	for _, r := range records[1:] {
+		tests = append(tests, schnorrtest{
+			index:     r[0],
+			secretKey: r[1],
+			publicKey: r[2],
+			auxRand:   r[3],
+			message:   r[4],
+			signature: r[5],
+			valid:     r[6] == "TRUE",
+		})
+	}
+
+	return tests
+}
+
+func TestSchnorrSign(t *testing.T) {
+	for _, test := range schnorrTestVector(t) {
+		if test.secretKey == "" {
+			continue
+		}
+
+		key, err := PrivKeyFromBytes(mustHex(test.secretKey))
+		assert.NoError(t, err)
+		assert.Equal(t, test.publicKey, strings.ToUpper(hex.EncodeToString(key.SerializeXOnly())))
+
+		sig, err := SchnorrSign(key, mustHex(test.message), mustHex(test.auxRand))
+		assert.NoError(t, err)
+		assert.Equal(t, test.signature, strings.ToUpper(hex.EncodeToString(sig)), test.index)
+	}
+}
+
+func TestSchnorrVerify(t *testing.T) {
+	for _, test := range schnorrTestVector(t) {
+		valid := SchnorrVerify(mustHex(test.publicKey), mustHex(test.message), mustHex(test.signature))
+		assert.Equal(t, test.valid, valid, test.index)
+	}
+}
+
+func TestSchnorrSignRandom(t *testing.T) {
+	key, err := NewPrivateKey()
+	assert.NoError(t, err)
+
+	msg := TaggedHash("test", []byte("message"))
+
+	sig, err := SchnorrSign(key, msg, nil)
+	assert.NoError(t, err)
+	assert.True(t, SchnorrVerify(key.SerializeXOnly(), msg, sig))
+
+	_, err = SchnorrSign(key, msg, []byte{0x01})
+	assert.Equal(t, ErrInvalidAuxRand, err)
+}
+
+func TestParseXOnlyPubKey(t *testing.T) {
+	pub, err := ParseXOnlyPubKey(Gx.Bytes())
+	assert.NoError(t, err)
+	assert.True(t, pub.HasEvenY())
+	assert.Equal(t, Gy, pub.Y)
+
+	_, err = ParseXOnlyPubKey(mustHex("EEFDEA4CDB677750A420FEE807EACF21EB9898AE79B9768766E4FAA04A2D4A34"))
+	assert.Equal(t, ErrInvalidPublicKey, err)
+}
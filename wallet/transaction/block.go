@@ -0,0 +1,298 @@
+package transaction
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+const (
+	// BlockHeaderSize is the size of a serialized block header
+	BlockHeaderSize = 80
+	// MaxBlockSize is the largest size of a serialized block
+	MaxBlockSize = 4000000
+
+	// minTxSize is a transaction with a single input and output
+	minTxSize = 10 + minTxInSize + minTxOutSize
+)
+
+var (
+	// ErrInvalidHeader is returned when a block header is not 80 bytes
+	ErrInvalidHeader = errors.New("transaction: invalid block header")
+	// ErrTooManyTxs is returned when a block declares more transactions
+	// than it can hold
+	ErrTooManyTxs = errors.New("transaction: too many transactions in block")
+	// ErrWitnessCommitment is returned when the coinbase of a block does
+	// not commit to the witnesses of its transactions
+	ErrWitnessCommitment = errors.New("transaction: witness commitment mismatch")
+)
+
+// witnessCommitmentHeader starts the BIP141 witness commitment output of a
+// coinbase: OP_RETURN, a 36 byte push and the commitment tag
+var witnessCommitmentHeader = []byte{0x6a, 0x24, 0xaa, 0x21, 0xa9, 0xed}
+
+// BlockHeader is the header of a block, it commits to the previous block
+// and to the transactions of the block
+type BlockHeader struct {
+	Version    int32
+	PrevBlock  Hash
+	MerkleRoot Hash
+	Timestamp  uint32
+	Bits       uint32
+	Nonce      uint32
+}
+
+// NewBlockHeaderFromBytes deserializes a block header
+func NewBlockHeaderFromBytes(data []byte) (*BlockHeader, error) {
+
+	if len(data) != BlockHeaderSize {
+		return nil, ErrInvalidHeader
+	}
+
+	h := &BlockHeader{}
+	if err := h.Deserialize(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// BlockHash returns the hash of the header identifying the block
+func (h *BlockHeader) BlockHash() Hash {
+	return DoubleHashH(h.Bytes())
+}
+
+// Bytes returns the serialization of the header
+func (h *BlockHeader) Bytes() []byte {
+
+	var buf bytes.Buffer
+	buf.Grow(BlockHeaderSize)
+	h.Serialize(&buf)
+
+	return buf.Bytes()
+}
+
+// Serialize writes the header
+func (h *BlockHeader) Serialize(w io.Writer) error {
+
+	if err := writeUint32(w, uint32(h.Version)); err != nil {
+		return err
+	}
+	if _, err := w.Write(h.PrevBlock[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(h.MerkleRoot[:]); err != nil {
+		return err
+	}
+
+	for _, n := range []uint32{h.Timestamp, h.Bits, h.Nonce} {
+		if err := writeUint32(w, n); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Deserialize reads a header
+func (h *BlockHeader) Deserialize(r io.Reader) error {
+
+	version, err := readUint32(r)
+	if err != nil {
+		return err
+	}
+	h.Version = int32(version)
+
+	if _, err := io.ReadFull(r, h.PrevBlock[:]); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(r, h.MerkleRoot[:]); err != nil {
+		return err
+	}
+
+	for _, n := range []*uint32{&h.Timestamp, &h.Bits, &h.Nonce} {
+		if *n, err = readUint32(r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Block is a block header followed by the transactions of the block
+type Block struct {
+	Header       BlockHeader
+	Transactions []*Tx
+}
+
+// NewBlockFromBytes deserializes a block, data must hold exactly one block
+func NewBlockFromBytes(data []byte) (*Block, error) {
+
+	r := bytes.NewReader(data)
+
+	b := &Block{}
+	if err := b.Deserialize(r); err != nil {
+		return nil, err
+	}
+
+	if r.Len() != 0 {
+		return nil, ErrTrailingBytes
+	}
+
+	return b, nil
+}
+
+// BlockHash returns the hash of the block header
+func (b *Block) BlockHash() Hash {
+	return b.Header.BlockHash()
+}
+
+// Bytes returns the serialization of the block including witness data
+func (b *Block) Bytes() []byte {
+
+	var buf bytes.Buffer
+	b.Serialize(&buf)
+
+	return buf.Bytes()
+}
+
+// Serialize writes the block including witness data
+func (b *Block) Serialize(w io.Writer) error {
+
+	if err := b.Header.Serialize(w); err != nil {
+		return err
+	}
+
+	if err := WriteVarInt(w, uint64(len(b.Transactions))); err != nil {
+		return err
+	}
+
+	for _, tx := range b.Transactions {
+		if err := tx.Serialize(w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Deserialize reads a block
+func (b *Block) Deserialize(r io.Reader) error {
+
+	if err := b.Header.Deserialize(r); err != nil {
+		return err
+	}
+
+	count, err := ReadVarInt(r)
+	if err != nil {
+		return err
+	}
+	if count > MaxBlockSize/minTxSize {
+		return ErrTooManyTxs
+	}
+
+	b.Transactions = make([]*Tx, count)
+	for i := range b.Transactions {
+		tx := &Tx{}
+		if err := tx.Deserialize(r); err != nil {
+			return err
+		}
+		b.Transactions[i] = tx
+	}
+
+	return nil
+}
+
+// MerkleRoot returns the root of the merkle tree of the transaction ids
+// of the block
+func (b *Block) MerkleRoot() Hash {
+
+	hashes := make([]Hash, len(b.Transactions))
+	for i, tx := range b.Transactions {
+		hashes[i] = tx.TxHash()
+	}
+
+	return CalcMerkleRoot(hashes)
+}
+
+// WitnessMerkleRoot returns the root of the merkle tree of the witness
+// transaction ids of the block, the one of the coinbase being zero
+func (b *Block) WitnessMerkleRoot() Hash {
+
+	hashes := make([]Hash, len(b.Transactions))
+	for i, tx := range b.Transactions {
+		if i > 0 {
+			hashes[i] = tx.WitnessHash()
+		}
+	}
+
+	return CalcMerkleRoot(hashes)
+}
+
+// WitnessCommitment returns the BIP141 commitment of a coinbase to the
+// witness root and the reserved value of its witness
+func WitnessCommitment(witnessRoot, reserved []byte) Hash {
+	return DoubleHashH(append(append([]byte{}, witnessRoot...), reserved...))
+}
+
+// CheckWitnessCommitment checks the BIP141 commitment of the coinbase, the
+// last output starting with the commitment header, to the witnesses of the
+// block. Blocks without such an output must not carry witnesses.
+func (b *Block) CheckWitnessCommitment() error {
+
+	if len(b.Transactions) == 0 {
+		return ErrWitnessCommitment
+	}
+	coinbase := b.Transactions[0]
+
+	var commitment []byte
+	for _, out := range coinbase.TxOut {
+		if len(out.PkScript) >= 38 && bytes.HasPrefix(out.PkScript, witnessCommitmentHeader) {
+			commitment = out.PkScript[6:38]
+		}
+	}
+
+	if commitment == nil {
+		for _, tx := range b.Transactions {
+			if tx.HasWitness() {
+				return ErrWitnessCommitment
+			}
+		}
+		return nil
+	}
+
+	witness := coinbase.TxIn[0].Witness
+	if len(witness) != 1 || len(witness[0]) != HashSize {
+		return ErrWitnessCommitment
+	}
+
+	root := b.WitnessMerkleRoot()
+	if expected := WitnessCommitment(root[:], witness[0]); !bytes.Equal(expected[:], commitment) {
+		return ErrWitnessCommitment
+	}
+
+	return nil
+}
+
+// CalcMerkleRoot returns the root of the merkle tree of hashes, the last
+// hash of a level with an odd count is paired with itself
+func CalcMerkleRoot(hashes []Hash) Hash {
+
+	if len(hashes) == 0 {
+		return Hash{}
+	}
+
+	level := append([]Hash{}, hashes...)
+	for len(level) > 1 {
+		if len(level)%2 != 0 {
+			level = append(level, level[len(level)-1])
+		}
+		next := level[:0]
+		for i := 0; i < len(level); i += 2 {
+			next = append(next, DoubleHashH(append(level[i][:], level[i+1][:]...)))
+		}
+		level = next
+	}
+
+	return level[0]
+}
@@ -0,0 +1,97 @@
+package transaction
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockHeader(t *testing.T) {
+
+	// mainnet genesis block
+	raw, _ := hex.DecodeString("0100000000000000000000000000000000000000000000000000000000000000000000003ba3edfd7a7b12b27ac72c3e67768f617fc81bc3888a51323a9fb8aa4b1e5e4a29ab5f49ffff001d1dac2b7c")
+
+	h, err := NewBlockHeaderFromBytes(raw)
+	assert.NoError(t, err)
+
+	assert.Equal(t, int32(1), h.Version)
+	assert.Equal(t, Hash{}, h.PrevBlock)
+	assert.Equal(t, "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b", h.MerkleRoot.String())
+	assert.Equal(t, uint32(1231006505), h.Timestamp)
+	assert.Equal(t, uint32(0x1d00ffff), h.Bits)
+	assert.Equal(t, uint32(2083236893), h.Nonce)
+	assert.Equal(t, "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f", h.BlockHash().String())
+	assert.Equal(t, raw, h.Bytes())
+
+	_, err = NewBlockHeaderFromBytes(raw[:79])
+	assert.Equal(t, ErrInvalidHeader, err)
+}
+
+func TestBlock(t *testing.T) {
+
+	// mainnet genesis block
+	raw, _ := hex.DecodeString("0100000000000000000000000000000000000000000000000000000000000000000000003ba3edfd7a7b12b27ac72c3e67768f617fc81bc3888a51323a9fb8aa4b1e5e4a29ab5f49ffff001d1dac2b7c0101000000010000000000000000000000000000000000000000000000000000000000000000ffffffff4d04ffff001d0104455468652054696d65732030332f4a616e2f32303039204368616e63656c6c6f72206f6e206272696e6b206f66207365636f6e64206261696c6f757420666f722062616e6b73ffffffff0100f2052a01000000434104678afdb0fe5548271967f1a67130b7105cd6a828e03909a67962e0ea1f61deb649f6bc3f4cef38c4f35504e51ec112de5c384df7ba0b8d578a4c702b6bf11d5fac00000000")
+
+	b, err := NewBlockFromBytes(raw)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(b.Transactions))
+	assert.True(t, b.Transactions[0].IsCoinBase())
+	assert.Equal(t, b.Header.MerkleRoot, b.MerkleRoot())
+	assert.NoError(t, b.CheckWitnessCommitment())
+	assert.Equal(t, "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f", b.BlockHash().String())
+	assert.Equal(t, raw, b.Bytes())
+
+	_, err = NewBlockFromBytes(append(raw, 0))
+	assert.Equal(t, ErrTrailingBytes, err)
+}
+
+func TestWitnessCommitment(t *testing.T) {
+
+	coinbase := NewTx(2)
+	coinbase.AddTxIn(NewTxIn(NewOutPoint(&Hash{}, MaxPrevOutIndex), []byte{0x01, 0x01}, nil))
+	coinbase.AddTxOut(NewTxOut(50e8, []byte{0x51}))
+
+	spend := NewTx(2)
+	spend.AddTxIn(NewTxIn(NewOutPoint(&Hash{1}, 0), nil, Witness{{0x01}}))
+	spend.AddTxOut(NewTxOut(1000, []byte{0x51}))
+
+	b := &Block{Transactions: []*Tx{coinbase, spend}}
+
+	// witnesses require a commitment
+	assert.Equal(t, ErrWitnessCommitment, b.CheckWitnessCommitment())
+
+	reserved := make([]byte, HashSize)
+	root := b.WitnessMerkleRoot()
+	commitment := WitnessCommitment(root[:], reserved)
+	coinbase.TxIn[0].Witness = Witness{reserved}
+	coinbase.AddTxOut(NewTxOut(0, append(append([]byte{}, witnessCommitmentHeader...), commitment[:]...)))
+	assert.NoError(t, b.CheckWitnessCommitment())
+
+	// altering a witness does not change the txid but breaks the commitment
+	spend.TxIn[0].Witness = Witness{{0x02}}
+	assert.Equal(t, ErrWitnessCommitment, b.CheckWitnessCommitment())
+	spend.TxIn[0].Witness = Witness{{0x01}}
+
+	coinbase.TxIn[0].Witness = nil
+	assert.Equal(t, ErrWitnessCommitment, b.CheckWitnessCommitment())
+}
+
+func TestCalcMerkleRoot(t *testing.T) {
+
+	// transactions of block 100000
+	var hashes []Hash
+	for _, s := range []string{
+		"8c14f0db3df150123e6f3dbbf30f8b955a8249b62ac1d1ff16284aefa3d06d87",
+		"fff2525b8931402dd09222c50775608f75787bd2b87e56995a7bdd30f79702c4",
+		"6359f0868171b1d194cbee1af2f16ea598ae8fad666d9b012c8ed2b79a236ec4",
+		"e9a66845e05d5abc0ad04ec80f774a7e585c6e8db975962d069a522137b80c1d",
+	} {
+		h, _ := NewHashFromStr(s)
+		hashes = append(hashes, h)
+	}
+
+	assert.Equal(t, "f3e94742aca4b5ef85488dc37c06c3282295ffec960994b2c0d5ac2a25a95766", CalcMerkleRoot(hashes).String())
+	assert.Equal(t, hashes[0], CalcMerkleRoot(hashes[:1]))
+	assert.Equal(t, Hash{}, CalcMerkleRoot(nil))
+}
@@ -0,0 +1,58 @@
+package transaction
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+)
+
+// HashSize is the size of a transaction hash
+const HashSize = 32
+
+// ErrInvalidHash is returned when a hash string cannot be decoded
+var ErrInvalidHash = errors.New("transaction: invalid hash")
+
+// Hash is a double SHA256 digest stored in internal byte order, it is
+// displayed byte reversed as done by block explorers and RPC interfaces
+type Hash [HashSize]byte
+
+// String returns the byte reversed hex encoding of the hash
+func (h Hash) String() string {
+
+	var r Hash
+	for i := range h {
+		r[HashSize-1-i] = h[i]
+	}
+
+	return hex.EncodeToString(r[:])
+}
+
+// IsEqual reports whether two hashes are equal
+func (h *Hash) IsEqual(other *Hash) bool {
+	return *h == *other
+}
+
+// NewHashFromStr decodes a byte reversed hex hash
+func NewHashFromStr(s string) (Hash, error) {
+
+	var h Hash
+
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != HashSize {
+		return h, ErrInvalidHash
+	}
+
+	for i := range b {
+		h[HashSize-1-i] = b[i]
+	}
+
+	return h, nil
+}
+
+// DoubleHashH returns the double SHA256 digest of b as a Hash
+func DoubleHashH(b []byte) Hash {
+
+	first := sha256.Sum256(b)
+
+	return Hash(sha256.Sum256(first[:]))
+}
@@ -0,0 +1,402 @@
+package transaction
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+)
+
+// SigHashType selects the parts of a transaction committed to by a signature
+type SigHashType uint32
+
+const (
+	// SigHashDefault is the taproot only type equivalent to SigHashAll
+	// without the trailing sighash byte
+	SigHashDefault SigHashType = 0x00
+	// SigHashAll commits to all inputs and outputs
+	SigHashAll SigHashType = 0x01
+	// SigHashNone commits to no output
+	SigHashNone SigHashType = 0x02
+	// SigHashSingle commits to the output with the index of the input
+	SigHashSingle SigHashType = 0x03
+	// SigHashAnyOneCanPay commits to the signed input only
+	SigHashAnyOneCanPay SigHashType = 0x80
+
+	sigHashMask = 0x1f
+)
+
+var (
+	// ErrInputIndex is returned when the input index is out of range
+	ErrInputIndex = errors.New("transaction: input index out of range")
+	// ErrInvalidSigHashType is returned for sighash types not allowed by
+	// BIP341
+	ErrInvalidSigHashType = errors.New("transaction: invalid taproot sighash type")
+	// ErrSigHashSingleIndex is returned by taproot SIGHASH_SINGLE when the
+	// input has no output with the same index
+	ErrSigHashSingleIndex = errors.New("transaction: no output for SIGHASH_SINGLE input")
+	// ErrMissingPrevOut is returned when a spent output is unknown
+	ErrMissingPrevOut = errors.New("transaction: missing previous output")
+)
+
+// PrevOutputFetcher returns the outputs spent by the inputs of a transaction
+type PrevOutputFetcher interface {
+	FetchPrevOutput(op OutPoint) *TxOut
+}
+
+// PrevOutputMap is a PrevOutputFetcher backed by a map
+type PrevOutputMap map[OutPoint]*TxOut
+
+// FetchPrevOutput returns the output spent by op, nil when unknown
+func (m PrevOutputMap) FetchPrevOutput(op OutPoint) *TxOut {
+	return m[op]
+}
+
+// TaprootSpend describes the taproot spending path of the signed input, a
+// nil LeafHash selects the key path
+type TaprootSpend struct {
+	Annex      []byte
+	LeafHash   []byte
+	CodeSepPos uint32
+	KeyVersion byte
+}
+
+// NewTapscriptSpend returns the spend description of a tapscript leaf with
+// no executed OP_CODESEPARATOR
+func NewTapscriptSpend(leafHash []byte) *TaprootSpend {
+	return &TaprootSpend{LeafHash: leafHash, CodeSepPos: 0xffffffff}
+}
+
+// SigHashCache holds the per transaction midstates of the BIP143 and BIP341
+// digests so that signing every input is linear in the transaction size.
+// The transaction must not be modified once the cache is built.
+type SigHashCache struct {
+	tx       *Tx
+	prevOuts PrevOutputFetcher
+
+	hashPrevOutsV0 []byte
+	hashSequenceV0 []byte
+	hashOutputsV0  []byte
+
+	hashPrevOutsV1 []byte
+	hashSequenceV1 []byte
+	hashOutputsV1  []byte
+	hashAmountsV1  []byte
+	hashScriptsV1  []byte
+	taprootErr     error
+}
+
+// NewSigHashCache computes the shared midstates of tx, prevOuts may be nil
+// when no taproot input is signed
+func NewSigHashCache(tx *Tx, prevOuts PrevOutputFetcher) *SigHashCache {
+
+	var prevouts, sequences, outputs, amounts, scripts bytes.Buffer
+
+	for _, in := range tx.TxIn {
+		prevouts.Write(in.PreviousOutPoint.Hash[:])
+		writeUint32(&prevouts, in.PreviousOutPoint.Index)
+		writeUint32(&sequences, in.Sequence)
+	}
+
+	for _, out := range tx.TxOut {
+		writeTxOut(&outputs, out)
+	}
+
+	c := &SigHashCache{tx: tx, prevOuts: prevOuts}
+
+	c.hashPrevOutsV1 = sha256Sum(prevouts.Bytes())
+	c.hashSequenceV1 = sha256Sum(sequences.Bytes())
+	c.hashOutputsV1 = sha256Sum(outputs.Bytes())
+
+	c.hashPrevOutsV0 = sha256Sum(c.hashPrevOutsV1)
+	c.hashSequenceV0 = sha256Sum(c.hashSequenceV1)
+	c.hashOutputsV0 = sha256Sum(c.hashOutputsV1)
+
+	if prevOuts == nil {
+		c.taprootErr = ErrMissingPrevOut
+		return c
+	}
+
+	for _, in := range tx.TxIn {
+		out := prevOuts.FetchPrevOutput(in.PreviousOutPoint)
+		if out == nil {
+			c.taprootErr = ErrMissingPrevOut
+			return c
+		}
+
+		writeUint64(&amounts, uint64(out.Value))
+		WriteVarBytes(&scripts, out.PkScript)
+	}
+
+	c.hashAmountsV1 = sha256Sum(amounts.Bytes())
+	c.hashScriptsV1 = sha256Sum(scripts.Bytes())
+
+	return c
+}
+
+// LegacySigHash returns the pre-segwit digest of input idx, subScript is the
+// script being executed. It reproduces the SIGHASH_SINGLE bug, returning the
+// value one when the input has no matching output.
+func LegacySigHash(tx *Tx, idx int, subScript []byte, hashType SigHashType) ([]byte, error) {
+
+	if idx < 0 || idx >= len(tx.TxIn) {
+		return nil, ErrInputIndex
+	}
+
+	if hashType&sigHashMask == SigHashSingle && idx >= len(tx.TxOut) {
+		one := make([]byte, 32)
+		one[0] = 0x01
+		return one, nil
+	}
+
+	cp := &Tx{Version: tx.Version, LockTime: tx.LockTime}
+	script := removeCodeSeparators(subScript)
+
+	for i, in := range tx.TxIn {
+		c := &TxIn{PreviousOutPoint: in.PreviousOutPoint, Sequence: in.Sequence}
+		if i == idx {
+			c.SignatureScript = script
+		} else if base := hashType & sigHashMask; base == SigHashNone || base == SigHashSingle {
+			c.Sequence = 0
+		}
+		cp.TxIn = append(cp.TxIn, c)
+	}
+
+	switch hashType & sigHashMask {
+	case SigHashNone:
+	case SigHashSingle:
+		for i := 0; i < idx; i++ {
+			cp.TxOut = append(cp.TxOut, &TxOut{Value: -1})
+		}
+		cp.TxOut = append(cp.TxOut, tx.TxOut[idx])
+	default:
+		cp.TxOut = tx.TxOut
+	}
+
+	if hashType&SigHashAnyOneCanPay != 0 {
+		cp.TxIn = cp.TxIn[idx : idx+1]
+	}
+
+	var buf bytes.Buffer
+	cp.SerializeNoWitness(&buf)
+	writeUint32(&buf, uint32(hashType))
+
+	h := DoubleHashH(buf.Bytes())
+
+	return h[:], nil
+}
+
+// WitnessV0SigHash returns the BIP143 digest of input idx spending amount
+// satoshis, scriptCode is the P2PKH script for P2WPKH and the witness
+// script for P2WSH
+func (c *SigHashCache) WitnessV0SigHash(idx int, scriptCode []byte, amount int64, hashType SigHashType) ([]byte, error) {
+
+	tx := c.tx
+	if idx < 0 || idx >= len(tx.TxIn) {
+		return nil, ErrInputIndex
+	}
+
+	zero := make([]byte, 32)
+	base := hashType & sigHashMask
+	anyoneCanPay := hashType&SigHashAnyOneCanPay != 0
+
+	var buf bytes.Buffer
+	writeUint32(&buf, uint32(tx.Version))
+
+	if anyoneCanPay {
+		buf.Write(zero)
+	} else {
+		buf.Write(c.hashPrevOutsV0)
+	}
+
+	if anyoneCanPay || base == SigHashSingle || base == SigHashNone {
+		buf.Write(zero)
+	} else {
+		buf.Write(c.hashSequenceV0)
+	}
+
+	in := tx.TxIn[idx]
+	buf.Write(in.PreviousOutPoint.Hash[:])
+	writeUint32(&buf, in.PreviousOutPoint.Index)
+	WriteVarBytes(&buf, scriptCode)
+	writeUint64(&buf, uint64(amount))
+	writeUint32(&buf, in.Sequence)
+
+	switch {
+	case base != SigHashSingle && base != SigHashNone:
+		buf.Write(c.hashOutputsV0)
+	case base == SigHashSingle && idx < len(tx.TxOut):
+		var out bytes.Buffer
+		writeTxOut(&out, tx.TxOut[idx])
+		h := DoubleHashH(out.Bytes())
+		buf.Write(h[:])
+	default:
+		buf.Write(zero)
+	}
+
+	writeUint32(&buf, tx.LockTime)
+	writeUint32(&buf, uint32(hashType))
+
+	h := DoubleHashH(buf.Bytes())
+
+	return h[:], nil
+}
+
+// TaprootSigHash returns the BIP341 digest of input idx, spend selects the
+// key path when nil or when its LeafHash is nil
+func (c *SigHashCache) TaprootSigHash(idx int, hashType SigHashType, spend *TaprootSpend) ([]byte, error) {
+
+	tx := c.tx
+	if idx < 0 || idx >= len(tx.TxIn) {
+		return nil, ErrInputIndex
+	}
+
+	if !validTaprootSigHash(hashType) {
+		return nil, ErrInvalidSigHashType
+	}
+
+	if c.taprootErr != nil {
+		return nil, c.taprootErr
+	}
+
+	if spend == nil {
+		spend = &TaprootSpend{}
+	}
+
+	base := hashType & sigHashMask
+	anyoneCanPay := hashType&SigHashAnyOneCanPay != 0
+
+	var buf bytes.Buffer
+
+	// epoch
+	buf.WriteByte(0x00)
+	buf.WriteByte(byte(hashType))
+	writeUint32(&buf, uint32(tx.Version))
+	writeUint32(&buf, tx.LockTime)
+
+	if !anyoneCanPay {
+		buf.Write(c.hashPrevOutsV1)
+		buf.Write(c.hashAmountsV1)
+		buf.Write(c.hashScriptsV1)
+		buf.Write(c.hashSequenceV1)
+	}
+
+	if base != SigHashNone && base != SigHashSingle {
+		buf.Write(c.hashOutputsV1)
+	}
+
+	spendType := byte(0)
+	if spend.LeafHash != nil {
+		spendType |= 2
+	}
+	if spend.Annex != nil {
+		spendType |= 1
+	}
+	buf.WriteByte(spendType)
+
+	if anyoneCanPay {
+		in := tx.TxIn[idx]
+		prev := c.prevOuts.FetchPrevOutput(in.PreviousOutPoint)
+		if prev == nil {
+			return nil, ErrMissingPrevOut
+		}
+
+		buf.Write(in.PreviousOutPoint.Hash[:])
+		writeUint32(&buf, in.PreviousOutPoint.Index)
+		writeUint64(&buf, uint64(prev.Value))
+		WriteVarBytes(&buf, prev.PkScript)
+		writeUint32(&buf, in.Sequence)
+	} else {
+		writeUint32(&buf, uint32(idx))
+	}
+
+	if spend.Annex != nil {
+		var annex bytes.Buffer
+		WriteVarBytes(&annex, spend.Annex)
+		buf.Write(sha256Sum(annex.Bytes()))
+	}
+
+	if base == SigHashSingle {
+		if idx >= len(tx.TxOut) {
+			return nil, ErrSigHashSingleIndex
+		}
+
+		var out bytes.Buffer
+		writeTxOut(&out, tx.TxOut[idx])
+		buf.Write(sha256Sum(out.Bytes()))
+	}
+
+	if spend.LeafHash != nil {
+		buf.Write(spend.LeafHash)
+		buf.WriteByte(spend.KeyVersion)
+
+		var pos [4]byte
+		binary.LittleEndian.PutUint32(pos[:], spend.CodeSepPos)
+		buf.Write(pos[:])
+	}
+
+	return secp256k1.TaggedHash("TapSighash", buf.Bytes()), nil
+}
+
+func validTaprootSigHash(hashType SigHashType) bool {
+
+	switch hashType {
+	case SigHashDefault, SigHashAll, SigHashNone, SigHashSingle,
+		SigHashAll | SigHashAnyOneCanPay, SigHashNone | SigHashAnyOneCanPay, SigHashSingle | SigHashAnyOneCanPay:
+		return true
+	}
+
+	return false
+}
+
+// removeCodeSeparators strips the OP_CODESEPARATOR opcodes of a script,
+// bytes following a malformed push are kept as they are
+func removeCodeSeparators(script []byte) []byte {
+
+	const opCodeSeparator = 0xab
+
+	out := make([]byte, 0, len(script))
+
+	for i := 0; i < len(script); {
+		op := script[i]
+		n := 1
+
+		switch {
+		case op >= 0x01 && op <= 0x4b:
+			n += int(op)
+		case op == 0x4c && i+1 < len(script):
+			n += 1 + int(script[i+1])
+		case op == 0x4d && i+2 < len(script):
+			n += 2 + int(binary.LittleEndian.Uint16(script[i+1:]))
+		case op == 0x4e && i+4 < len(script):
+			n += 4 + int(binary.LittleEndian.Uint32(script[i+1:]))
+		case op >= 0x4c && op <= 0x4e:
+			n = len(script) - i + 1
+		}
+
+		if n > len(script)-i || n < 0 {
+			return append(out, script[i:]...)
+		}
+
+		if op != opCodeSeparator {
+			out = append(out, script[i:i+n]...)
+		}
+		i += n
+	}
+
+	return out
+}
+
+func writeTxOut(buf *bytes.Buffer, out *TxOut) {
+	writeUint64(buf, uint64(out.Value))
+	WriteVarBytes(buf, out.PkScript)
+}
+
+func sha256Sum(b []byte) []byte {
+
+	h := sha256.Sum256(b)
+
+	return h[:]
+}
@@ -0,0 +1,156 @@
+package transaction
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/giogam/Gopher-Wallet/wallet/secp256k1"
+	"github.com/stretchr/testify/assert"
+)
+
+type taproottest struct {
+	tx       string
+	value    int64
+	pkScript string
+	pubKey   string
+	leafHash string
+	sig      string
+}
+
+func taprootTestVector() []taproottest {
+	return []taproottest{
+		{
+			// BIP371 key path spend
+			tx:       "020000000127744ababf3027fe0d6cf23a96eee2efb188ef52301954585883e69b6624b2420000000000ffffffff0148e6052a01000000160014768e1eeb4cf420866033f80aceff0f972074496900000000",
+			value:    5000000000,
+			pkScript: "51205a2c2cf5b52cf31f83ad2e8da63ff03183ecd8f609c7510ae8a48e03910a0757",
+			pubKey:   "5a2c2cf5b52cf31f83ad2e8da63ff03183ecd8f609c7510ae8a48e03910a0757",
+			sig:      "bb53ec917bad9d906af1ba87181c48b86ace5aae2b53605a725ca74625631476fc6f5baedaf4f2ee0f477f36f58f3970d5b8273b7e497b97af2e3f125c97af34",
+		},
+		{
+			// BIP371 script path spends
+			tx:       "02000000019bd48765230bf9a72e662001f972556e54f0c6f97feb56bcb5600d817f6995260100000000ffffffff0148e6052a0100000022512083698e458c6664e1595d75da2597de1e22ee97d798e706c4c0a4b5a9823cd74300000000",
+			value:    5000000000,
+			pkScript: "5120c2247efbfd92ac47f6f40b8d42d169175a19fa9fa10e4a25d7f35eb4dd85b692",
+			pubKey:   "2cb13ac68248de806aa6a3659cf3c03eb6821d09c8114a4e868febde865bb6d2",
+			leafHash: "cd970e15f53fc0c82f950fd560ffa919b76172be017368a89913af074f400b09",
+			sig:      "bf818d9757d6ffeb538ba057fb4c1fc4e0f5ef186e765beb564791e02af5fd3d5e2551d4e34e33d86f276b82c99c79aed3f0395a081efcd2cc2c65dd7e693d79",
+		},
+		{
+			tx:       "02000000019bd48765230bf9a72e662001f972556e54f0c6f97feb56bcb5600d817f6995260100000000ffffffff0148e6052a0100000022512083698e458c6664e1595d75da2597de1e22ee97d798e706c4c0a4b5a9823cd74300000000",
+			value:    5000000000,
+			pkScript: "5120c2247efbfd92ac47f6f40b8d42d169175a19fa9fa10e4a25d7f35eb4dd85b692",
+			pubKey:   "4320b0bf16f011b53ea7be615924aa7f27e5d29ad20ea1155d848676c3bad1b2",
+			leafHash: "115f2e490af7cc45c4f78511f36057ce5c5a5c56325a29fb44dfc203f356e1f8",
+			sig:      "e1f1ab6fabfa26b236f21833719dc1d428ab768d80f91f9988d8abef47bfb863bb1f2a529f768c15f00ce34ec283cdc07e88f8428be28f6ef64043c32911811a",
+		},
+		{
+			tx:       "02000000019bd48765230bf9a72e662001f972556e54f0c6f97feb56bcb5600d817f6995260100000000ffffffff0148e6052a0100000022512083698e458c6664e1595d75da2597de1e22ee97d798e706c4c0a4b5a9823cd74300000000",
+			value:    5000000000,
+			pkScript: "5120c2247efbfd92ac47f6f40b8d42d169175a19fa9fa10e4a25d7f35eb4dd85b692",
+			pubKey:   "fa0f7a3cef3b1d0c0a6ce7d26e17ada0b2e5c92d19efad48b41859cb8a451ca9",
+			leafHash: "6f7d62059e9497a1a4a267569d9876da60101aff38e3529b9b939ce7f91ae970",
+			sig:      "ec1f0379206461c83342285423326708ab031f0da4a253ee45aafa5b8c92034d8b605490f8cd13e00f989989b97e215faa36f12dee3693d2daccf3781c1757f6",
+		},
+	}
+}
+
+func TestLegacySigHash(t *testing.T) {
+	data, err := os.ReadFile("testdata/sighash.json")
+	assert.NoError(t, err)
+
+	var tests [][]interface{}
+	assert.NoError(t, json.Unmarshal(data, &tests))
+
+	for _, test := range tests {
+		if len(test) != 5 {
+			continue
+		}
+
+		raw, _ := hex.DecodeString(test[0].(string))
+		script, _ := hex.DecodeString(test[1].(string))
+		idx := int(test[2].(float64))
+		hashType := SigHashType(uint32(int32(test[3].(float64))))
+
+		tx, err := NewTxFromBytes(raw)
+		assert.NoError(t, err)
+
+		h, err := LegacySigHash(tx, idx, script, hashType)
+		assert.NoError(t, err)
+
+		var got Hash
+		copy(got[:], h)
+		assert.Equal(t, test[4].(string), got.String(), "%v", test)
+	}
+}
+
+func TestWitnessV0SigHash(t *testing.T) {
+	// BIP143 native P2WPKH example
+	raw, _ := hex.DecodeString("0100000002fff7f7881a8099afa6940d42d1e7f6362bec38171ea3edf433541db4e4ad969f0000000000eeffffffef51e1b804cc89d182d279655c3aa89e815b1b309fe287d9b2b55d57b90ec68a0100000000ffffffff02202cb206000000001976a9148280b37df378db99f66f85c95a783a76ac7a6d5988ac9093510d000000001976a9143bde42dbee7e4dbe6a21b2d50ce2f0167faa815988ac11000000")
+	scriptCode, _ := hex.DecodeString("76a9141d0f172a0ecb48aee1be1f2687d2963ae33f71a188ac")
+
+	tx, err := NewTxFromBytes(raw)
+	assert.NoError(t, err)
+
+	c := NewSigHashCache(tx, nil)
+
+	h, err := c.WitnessV0SigHash(1, scriptCode, 600000000, SigHashAll)
+	assert.NoError(t, err)
+	assert.Equal(t, "c37af31116d1b27caf68aae9e3ac82f1477929014d5b917657d0eb49478cb670", hex.EncodeToString(h))
+
+	_, err = c.WitnessV0SigHash(2, scriptCode, 600000000, SigHashAll)
+	assert.Equal(t, ErrInputIndex, err)
+
+	// taproot digests need every spent output
+	_, err = c.TaprootSigHash(0, SigHashDefault, nil)
+	assert.Equal(t, ErrMissingPrevOut, err)
+}
+
+func TestTaprootSigHash(t *testing.T) {
+	for _, test := range taprootTestVector() {
+		raw, _ := hex.DecodeString(test.tx)
+		pkScript, _ := hex.DecodeString(test.pkScript)
+		pubKey, _ := hex.DecodeString(test.pubKey)
+		sig, _ := hex.DecodeString(test.sig)
+
+		tx, err := NewTxFromBytes(raw)
+		assert.NoError(t, err)
+
+		prevOuts := PrevOutputMap{tx.TxIn[0].PreviousOutPoint: NewTxOut(test.value, pkScript)}
+		c := NewSigHashCache(tx, prevOuts)
+
+		var spend *TaprootSpend
+		var leafHash []byte
+		if test.leafHash != "" {
+			leafHash, _ = hex.DecodeString(test.leafHash)
+			spend = NewTapscriptSpend(leafHash)
+		}
+
+		h, err := c.TaprootSigHash(0, SigHashDefault, spend)
+		assert.NoError(t, err)
+		assert.True(t, secp256k1.SchnorrVerify(pubKey, h, sig))
+
+		// any other digest must not verify
+		for _, hashType := range []SigHashType{SigHashAll, SigHashNone, SigHashSingle, SigHashAll | SigHashAnyOneCanPay, SigHashSingle | SigHashAnyOneCanPay} {
+			h, err := c.TaprootSigHash(0, hashType, spend)
+			assert.NoError(t, err)
+			assert.False(t, secp256k1.SchnorrVerify(pubKey, h, sig))
+		}
+
+		h, err = c.TaprootSigHash(0, SigHashDefault, &TaprootSpend{Annex: []byte{0x50}, LeafHash: leafHash, CodeSepPos: 0xffffffff})
+		assert.NoError(t, err)
+		assert.False(t, secp256k1.SchnorrVerify(pubKey, h, sig))
+
+		_, err = c.TaprootSigHash(0, 0x04, spend)
+		assert.Equal(t, ErrInvalidSigHashType, err)
+	}
+}
+
+func TestRemoveCodeSeparators(t *testing.T) {
+	// a separator inside a push is data and is kept
+	script := []byte{0xab, 0x01, 0xab, 0x51, 0xab, 0x4c, 0x05, 0xab}
+
+	assert.Equal(t, []byte{0x01, 0xab, 0x51, 0x4c, 0x05, 0xab}, removeCodeSeparators(script))
+}
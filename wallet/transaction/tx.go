@@ -0,0 +1,559 @@
+package transaction
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+const (
+	// MaxTxInSequenceNum is the sequence of a final input
+	MaxTxInSequenceNum = 0xffffffff
+	// MaxPrevOutIndex is the index of the null outpoint of coinbase inputs
+	MaxPrevOutIndex = 0xffffffff
+	// WitnessScaleFactor is the weight of a non witness byte
+	WitnessScaleFactor = 4
+	// MaxMoney is the largest amount in satoshis an output may hold
+	MaxMoney = 21000000 * 100000000
+
+	// witnessMarker and witnessFlag follow the version of BIP144 segwit
+	// serializations
+	witnessMarker = 0x00
+	witnessFlag   = 0x01
+
+	maxTxSize = 4000000
+	// minTxInSize is an outpoint, an empty script and a sequence
+	minTxInSize = 41
+	// minTxOutSize is a value and an empty script
+	minTxOutSize = 9
+)
+
+var (
+	// ErrInvalidFlag is returned when the segwit flag is not 0x01
+	ErrInvalidFlag = errors.New("transaction: invalid witness flag")
+	// ErrSuperfluousWitness is returned when the segwit serialization is
+	// used for a transaction without witness data
+	ErrSuperfluousWitness = errors.New("transaction: superfluous witness record")
+	// ErrTrailingBytes is returned when data remains after a transaction
+	ErrTrailingBytes = errors.New("transaction: trailing bytes after transaction")
+	// ErrSanity is returned when a transaction fails the context free checks
+	// every valid transaction passes
+	ErrSanity = errors.New("transaction: transaction fails context free checks")
+)
+
+// OutPoint references an output of a previous transaction
+type OutPoint struct {
+	Hash  Hash
+	Index uint32
+}
+
+// NewOutPoint returns an outpoint for the given hash and index
+func NewOutPoint(hash *Hash, index uint32) *OutPoint {
+	return &OutPoint{Hash: *hash, Index: index}
+}
+
+// String returns the outpoint in the "txid:index" notation
+func (o OutPoint) String() string {
+	return o.Hash.String() + ":" + strconv.FormatUint(uint64(o.Index), 10)
+}
+
+// Witness is the stack of witness items of an input
+type Witness [][]byte
+
+// SerializeSize returns the number of bytes of the serialized witness
+func (w Witness) SerializeSize() int {
+
+	n := VarIntSerializeSize(uint64(len(w)))
+	for _, item := range w {
+		n += VarIntSerializeSize(uint64(len(item))) + len(item)
+	}
+
+	return n
+}
+
+// TxIn is a transaction input
+type TxIn struct {
+	PreviousOutPoint OutPoint
+	SignatureScript  []byte
+	Witness          Witness
+	Sequence         uint32
+}
+
+// NewTxIn returns an input spending prevOut with a final sequence
+func NewTxIn(prevOut *OutPoint, signatureScript []byte, witness [][]byte) *TxIn {
+
+	return &TxIn{
+		PreviousOutPoint: *prevOut,
+		SignatureScript:  signatureScript,
+		Witness:          witness,
+		Sequence:         MaxTxInSequenceNum,
+	}
+}
+
+// SerializeSize returns the number of non witness bytes of the input
+func (in *TxIn) SerializeSize() int {
+	return 40 + VarIntSerializeSize(uint64(len(in.SignatureScript))) + len(in.SignatureScript)
+}
+
+// TxOut is a transaction output
+type TxOut struct {
+	Value    int64
+	PkScript []byte
+}
+
+// NewTxOut returns an output paying value satoshis to pkScript
+func NewTxOut(value int64, pkScript []byte) *TxOut {
+	return &TxOut{Value: value, PkScript: pkScript}
+}
+
+// SerializeSize returns the number of bytes of the serialized output
+func (out *TxOut) SerializeSize() int {
+	return 8 + VarIntSerializeSize(uint64(len(out.PkScript))) + len(out.PkScript)
+}
+
+// Tx is a Bitcoin transaction
+type Tx struct {
+	Version  int32
+	TxIn     []*TxIn
+	TxOut    []*TxOut
+	LockTime uint32
+}
+
+// NewTx returns an empty transaction with the given version
+func NewTx(version int32) *Tx {
+	return &Tx{Version: version}
+}
+
+// NewTxFromBytes deserializes a transaction, data must hold exactly one
+// transaction
+func NewTxFromBytes(data []byte) (*Tx, error) {
+
+	r := bytes.NewReader(data)
+
+	tx := &Tx{}
+	if err := tx.Deserialize(r); err != nil {
+		return nil, err
+	}
+
+	if r.Len() != 0 {
+		return nil, ErrTrailingBytes
+	}
+
+	return tx, nil
+}
+
+// AddTxIn appends an input to the transaction
+func (tx *Tx) AddTxIn(in *TxIn) {
+	tx.TxIn = append(tx.TxIn, in)
+}
+
+// AddTxOut appends an output to the transaction
+func (tx *Tx) AddTxOut(out *TxOut) {
+	tx.TxOut = append(tx.TxOut, out)
+}
+
+// HasWitness reports whether any input carries witness data
+func (tx *Tx) HasWitness() bool {
+
+	for _, in := range tx.TxIn {
+		if len(in.Witness) != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsCoinBase reports whether the transaction is a coinbase
+func (tx *Tx) IsCoinBase() bool {
+
+	if len(tx.TxIn) != 1 {
+		return false
+	}
+
+	prevOut := tx.TxIn[0].PreviousOutPoint
+
+	return prevOut.Index == MaxPrevOutIndex && prevOut.Hash == Hash{}
+}
+
+// CheckSanity applies the context free checks of a valid transaction: it
+// has inputs and outputs, its output values are in range, it spends no
+// outpoint twice and only a coinbase, whose script has 2 to 100 bytes,
+// spends the null outpoint
+func (tx *Tx) CheckSanity() error {
+
+	if len(tx.TxIn) == 0 || len(tx.TxOut) == 0 {
+		return fmt.Errorf("%w: no inputs or outputs", ErrSanity)
+	}
+
+	if tx.SerializeSizeStripped()*WitnessScaleFactor > maxTxSize {
+		return fmt.Errorf("%w: oversize", ErrSanity)
+	}
+
+	var total int64
+	for _, out := range tx.TxOut {
+		if out.Value < 0 || out.Value > MaxMoney {
+			return fmt.Errorf("%w: output value out of range", ErrSanity)
+		}
+		total += out.Value
+		if total > MaxMoney {
+			return fmt.Errorf("%w: total output value out of range", ErrSanity)
+		}
+	}
+
+	seen := make(map[OutPoint]bool, len(tx.TxIn))
+	for _, in := range tx.TxIn {
+		if seen[in.PreviousOutPoint] {
+			return fmt.Errorf("%w: duplicate input", ErrSanity)
+		}
+		seen[in.PreviousOutPoint] = true
+	}
+
+	if tx.IsCoinBase() {
+		if n := len(tx.TxIn[0].SignatureScript); n < 2 || n > 100 {
+			return fmt.Errorf("%w: coinbase script size", ErrSanity)
+		}
+		return nil
+	}
+
+	for _, in := range tx.TxIn {
+		if in.PreviousOutPoint.Index == MaxPrevOutIndex && in.PreviousOutPoint.Hash == (Hash{}) {
+			return fmt.Errorf("%w: null previous outpoint", ErrSanity)
+		}
+	}
+
+	return nil
+}
+
+// TxHash returns the transaction id, the hash of the serialization without
+// witness data
+func (tx *Tx) TxHash() Hash {
+	return DoubleHashH(tx.BytesNoWitness())
+}
+
+// WitnessHash returns the wtxid, the hash of the serialization including
+// witness data, it equals the txid when there is no witness
+func (tx *Tx) WitnessHash() Hash {
+	return DoubleHashH(tx.Bytes())
+}
+
+// Bytes returns the serialization of the transaction, the BIP144 format is
+// used when witness data is present
+func (tx *Tx) Bytes() []byte {
+
+	var buf bytes.Buffer
+	buf.Grow(tx.SerializeSize())
+	tx.Serialize(&buf)
+
+	return buf.Bytes()
+}
+
+// BytesNoWitness returns the legacy serialization of the transaction
+func (tx *Tx) BytesNoWitness() []byte {
+
+	var buf bytes.Buffer
+	buf.Grow(tx.SerializeSizeStripped())
+	tx.SerializeNoWitness(&buf)
+
+	return buf.Bytes()
+}
+
+// Serialize writes the transaction including witness data
+func (tx *Tx) Serialize(w io.Writer) error {
+	return tx.serialize(w, tx.HasWitness())
+}
+
+// SerializeNoWitness writes the transaction without witness data
+func (tx *Tx) SerializeNoWitness(w io.Writer) error {
+	return tx.serialize(w, false)
+}
+
+func (tx *Tx) serialize(w io.Writer, witness bool) error {
+
+	if err := writeUint32(w, uint32(tx.Version)); err != nil {
+		return err
+	}
+
+	if witness {
+		if _, err := w.Write([]byte{witnessMarker, witnessFlag}); err != nil {
+			return err
+		}
+	}
+
+	if err := WriteVarInt(w, uint64(len(tx.TxIn))); err != nil {
+		return err
+	}
+
+	for _, in := range tx.TxIn {
+		if _, err := w.Write(in.PreviousOutPoint.Hash[:]); err != nil {
+			return err
+		}
+		if err := writeUint32(w, in.PreviousOutPoint.Index); err != nil {
+			return err
+		}
+		if err := WriteVarBytes(w, in.SignatureScript); err != nil {
+			return err
+		}
+		if err := writeUint32(w, in.Sequence); err != nil {
+			return err
+		}
+	}
+
+	if err := WriteVarInt(w, uint64(len(tx.TxOut))); err != nil {
+		return err
+	}
+
+	for _, out := range tx.TxOut {
+		if err := writeUint64(w, uint64(out.Value)); err != nil {
+			return err
+		}
+		if err := WriteVarBytes(w, out.PkScript); err != nil {
+			return err
+		}
+	}
+
+	if witness {
+		for _, in := range tx.TxIn {
+			if err := writeWitness(w, in.Witness); err != nil {
+				return err
+			}
+		}
+	}
+
+	return writeUint32(w, tx.LockTime)
+}
+
+// Deserialize reads a transaction in the legacy or the BIP144 format
+func (tx *Tx) Deserialize(r io.Reader) error {
+	return tx.deserialize(r, true)
+}
+
+// DeserializeNoWitness reads a transaction in the legacy format, a zero
+// input count is not taken as the BIP144 marker
+func (tx *Tx) DeserializeNoWitness(r io.Reader) error {
+	return tx.deserialize(r, false)
+}
+
+func (tx *Tx) deserialize(r io.Reader, allowWitness bool) error {
+
+	version, err := readUint32(r)
+	if err != nil {
+		return err
+	}
+	tx.Version = int32(version)
+
+	count, err := ReadVarInt(r)
+	if err != nil {
+		return err
+	}
+
+	witness := false
+	if count == 0 && allowWitness {
+		var flag [1]byte
+		if _, err := io.ReadFull(r, flag[:]); err != nil {
+			return err
+		}
+
+		if flag[0] != witnessFlag {
+			return ErrInvalidFlag
+		}
+		witness = true
+
+		if count, err = ReadVarInt(r); err != nil {
+			return err
+		}
+	}
+
+	if count > maxTxSize/minTxInSize {
+		return ErrTooLarge
+	}
+
+	tx.TxIn = make([]*TxIn, count)
+	for i := range tx.TxIn {
+		in := &TxIn{}
+
+		if _, err := io.ReadFull(r, in.PreviousOutPoint.Hash[:]); err != nil {
+			return err
+		}
+		if in.PreviousOutPoint.Index, err = readUint32(r); err != nil {
+			return err
+		}
+		if in.SignatureScript, err = ReadVarBytes(r, MaxVarBytes); err != nil {
+			return err
+		}
+		if in.Sequence, err = readUint32(r); err != nil {
+			return err
+		}
+
+		tx.TxIn[i] = in
+	}
+
+	if count, err = ReadVarInt(r); err != nil {
+		return err
+	}
+
+	if count > maxTxSize/minTxOutSize {
+		return ErrTooLarge
+	}
+
+	tx.TxOut = make([]*TxOut, count)
+	for i := range tx.TxOut {
+		out := &TxOut{}
+
+		value, err := readUint64(r)
+		if err != nil {
+			return err
+		}
+		out.Value = int64(value)
+
+		if out.PkScript, err = ReadVarBytes(r, MaxVarBytes); err != nil {
+			return err
+		}
+
+		tx.TxOut[i] = out
+	}
+
+	if witness {
+		for _, in := range tx.TxIn {
+			if in.Witness, err = readWitness(r); err != nil {
+				return err
+			}
+		}
+
+		if !tx.HasWitness() {
+			return ErrSuperfluousWitness
+		}
+	}
+
+	tx.LockTime, err = readUint32(r)
+
+	return err
+}
+
+// SerializeSize returns the size of the serialization including witness
+func (tx *Tx) SerializeSize() int {
+
+	n := tx.SerializeSizeStripped()
+
+	if tx.HasWitness() {
+		n += 2
+		for _, in := range tx.TxIn {
+			n += in.Witness.SerializeSize()
+		}
+	}
+
+	return n
+}
+
+// SerializeSizeStripped returns the size of the serialization without
+// witness data
+func (tx *Tx) SerializeSizeStripped() int {
+
+	n := 8 + VarIntSerializeSize(uint64(len(tx.TxIn))) + VarIntSerializeSize(uint64(len(tx.TxOut)))
+
+	for _, in := range tx.TxIn {
+		n += in.SerializeSize()
+	}
+
+	for _, out := range tx.TxOut {
+		n += out.SerializeSize()
+	}
+
+	return n
+}
+
+// Weight returns the BIP141 weight of the transaction
+func (tx *Tx) Weight() int {
+	return tx.SerializeSizeStripped()*(WitnessScaleFactor-1) + tx.SerializeSize()
+}
+
+// VSize returns the virtual size of the transaction, its weight divided by
+// four rounded up
+func (tx *Tx) VSize() int {
+	return (tx.Weight() + WitnessScaleFactor - 1) / WitnessScaleFactor
+}
+
+// Copy returns a deep copy of the transaction
+func (tx *Tx) Copy() *Tx {
+
+	cp := &Tx{
+		Version:  tx.Version,
+		TxIn:     make([]*TxIn, len(tx.TxIn)),
+		TxOut:    make([]*TxOut, len(tx.TxOut)),
+		LockTime: tx.LockTime,
+	}
+
+	for i, in := range tx.TxIn {
+		c := &TxIn{
+			PreviousOutPoint: in.PreviousOutPoint,
+			SignatureScript:  copyBytes(in.SignatureScript),
+			Sequence:         in.Sequence,
+		}
+
+		if in.Witness != nil {
+			c.Witness = make(Witness, len(in.Witness))
+			for j, item := range in.Witness {
+				c.Witness[j] = copyBytes(item)
+			}
+		}
+
+		cp.TxIn[i] = c
+	}
+
+	for i, out := range tx.TxOut {
+		cp.TxOut[i] = &TxOut{Value: out.Value, PkScript: copyBytes(out.PkScript)}
+	}
+
+	return cp
+}
+
+func writeWitness(w io.Writer, witness Witness) error {
+
+	if err := WriteVarInt(w, uint64(len(witness))); err != nil {
+		return err
+	}
+
+	for _, item := range witness {
+		if err := WriteVarBytes(w, item); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readWitness(r io.Reader) (Witness, error) {
+
+	count, err := ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if count > maxTxSize {
+		return nil, ErrTooLarge
+	}
+
+	// the count is not trusted to size the stack, every item costs at least
+	// one byte so the stack grows only as fast as the input is consumed
+	var witness Witness
+	for i := uint64(0); i < count; i++ {
+		item, err := ReadVarBytes(r, MaxVarBytes)
+		if err != nil {
+			return nil, err
+		}
+		witness = append(witness, item)
+	}
+
+	return witness, nil
+}
+
+func copyBytes(b []byte) []byte {
+
+	if b == nil {
+		return nil
+	}
+
+	return append([]byte{}, b...)
+}
@@ -0,0 +1,176 @@
+package transaction
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type txtest struct {
+	raw    string
+	txid   string
+	wtxid  string
+	size   int
+	weight int
+	vsize  int
+}
+
+func txTestVector() []txtest {
+	return []txtest{
+		{
+			// coinbase of block 113875
+			raw:    "01000000010000000000000000000000000000000000000000000000000000000000000000ffffffff070431dc001b0162ffffffff0100f2052a01000000434104d64bdfd09eb1c5fe295abdeb1dca4281be988e2da0b6c1c6a59dc226c28624e18175e851c96b973d81b01cc31f047834bc06d6d6edf620d184241a6aed8b63a6ac00000000",
+			txid:   "f051e59b5e2503ac626d03aaeac8ab7be2d72ba4b7e97119c5852d70d52dcb86",
+			wtxid:  "f051e59b5e2503ac626d03aaeac8ab7be2d72ba4b7e97119c5852d70d52dcb86",
+			size:   134,
+			weight: 536,
+			vsize:  134,
+		},
+		{
+			// P2WPKH spend from segnet block 23157
+			raw:    "01000000000101a53352d5135766f03076597418263da2d9c958315968fea823529467481ff9cd1300000000ffffffff010b070600000000001600149ddac6f39d51e0398e532a22c41ba189406a852302463043021f4d2381dc97f182abd8185f51753018523212f5ddc07cc4e63a8dc03658da190220608b5c4d92b86b6de7d78ef23a2fa735bcb59b914a48b0e187c5e7569a18197001210307ead084807eb76346df6977000c89392f45c76425b26181f521d7f370066a8f00000000",
+			txid:   "0f167d1385a84d1518cfee208b653fc9163b605ccf1b75347e2850b3e2eb19f3",
+			wtxid:  "0858eab78e77b6b033da30f46699996396cf48fcf625a783c85a51403e175e74",
+			size:   190,
+			weight: 436,
+			vsize:  109,
+		},
+	}
+}
+
+func TestTxSerialization(t *testing.T) {
+	for _, test := range txTestVector() {
+		raw, _ := hex.DecodeString(test.raw)
+
+		tx, err := NewTxFromBytes(raw)
+		assert.NoError(t, err)
+
+		assert.Equal(t, raw, tx.Bytes())
+		assert.Equal(t, test.txid, tx.TxHash().String())
+		assert.Equal(t, test.wtxid, tx.WitnessHash().String())
+		assert.Equal(t, test.size, tx.SerializeSize())
+		assert.Equal(t, test.weight, tx.Weight())
+		assert.Equal(t, test.vsize, tx.VSize())
+		assert.Equal(t, tx, tx.Copy())
+	}
+}
+
+func TestTxBuild(t *testing.T) {
+	raw, _ := hex.DecodeString(txTestVector()[1].raw)
+	parsed, _ := NewTxFromBytes(raw)
+
+	prevHash, err := NewHashFromStr("cdf91f4867945223a8fe68593158c9d9a23d261874597630f0665713d55233a5")
+	assert.NoError(t, err)
+
+	tx := NewTx(1)
+	tx.AddTxIn(NewTxIn(NewOutPoint(&prevHash, 19), nil, parsed.TxIn[0].Witness))
+	tx.AddTxOut(NewTxOut(395019, parsed.TxOut[0].PkScript))
+
+	assert.True(t, tx.HasWitness())
+	assert.False(t, tx.IsCoinBase())
+	assert.Equal(t, raw, tx.Bytes())
+	assert.Equal(t, 82, tx.SerializeSizeStripped())
+	assert.Equal(t, "cdf91f4867945223a8fe68593158c9d9a23d261874597630f0665713d55233a5:19", tx.TxIn[0].PreviousOutPoint.String())
+}
+
+func TestTxDeserializeErrors(t *testing.T) {
+	raw, _ := hex.DecodeString(txTestVector()[1].raw)
+
+	// non 0x01 flag
+	bad := append([]byte{}, raw...)
+	bad[5] = 0x02
+	_, err := NewTxFromBytes(bad)
+	assert.Equal(t, ErrInvalidFlag, err)
+
+	// segwit serialization with empty witnesses
+	tx, _ := NewTxFromBytes(raw)
+	tx.TxIn[0].Witness = nil
+	var buf bytes.Buffer
+	tx.serialize(&buf, true)
+	_, err = NewTxFromBytes(buf.Bytes())
+	assert.Equal(t, ErrSuperfluousWitness, err)
+
+	_, err = NewTxFromBytes(append(raw, 0x00))
+	assert.Equal(t, ErrTrailingBytes, err)
+
+	_, err = NewTxFromBytes(raw[:len(raw)-1])
+	assert.Error(t, err)
+}
+
+func TestTxCheckSanity(t *testing.T) {
+	raw, _ := hex.DecodeString(txTestVector()[1].raw)
+	tx, _ := NewTxFromBytes(raw)
+	assert.NoError(t, tx.CheckSanity())
+
+	tests := []struct {
+		name   string
+		modify func(tx *Tx)
+	}{
+		{"no inputs", func(tx *Tx) { tx.TxIn = nil }},
+		{"no outputs", func(tx *Tx) { tx.TxOut = nil }},
+		{"negative value", func(tx *Tx) { tx.TxOut[0].Value = -1 }},
+		{"value above max money", func(tx *Tx) { tx.TxOut[0].Value = MaxMoney + 1 }},
+		{"total above max money", func(tx *Tx) {
+			tx.TxOut[0].Value = MaxMoney
+			tx.AddTxOut(NewTxOut(1, nil))
+		}},
+		{"duplicate input", func(tx *Tx) { tx.AddTxIn(NewTxIn(&tx.TxIn[0].PreviousOutPoint, nil, nil)) }},
+		{"null outpoint", func(tx *Tx) { tx.AddTxIn(NewTxIn(NewOutPoint(&Hash{}, MaxPrevOutIndex), nil, nil)) }},
+		{"coinbase script size", func(tx *Tx) {
+			tx.TxIn = tx.TxIn[:1]
+			tx.TxIn[0].PreviousOutPoint = *NewOutPoint(&Hash{}, MaxPrevOutIndex)
+			tx.TxIn[0].SignatureScript = []byte{0x01}
+		}},
+	}
+
+	for _, test := range tests {
+		bad := tx.Copy()
+		test.modify(bad)
+		assert.True(t, errors.Is(bad.CheckSanity(), ErrSanity), test.name)
+	}
+}
+
+func TestTxDeserializeWitnessCount(t *testing.T) {
+	raw, _ := hex.DecodeString(txTestVector()[1].raw)
+	tx, _ := NewTxFromBytes(raw)
+
+	// a witness claiming the largest allowed item count without any item
+	var buf bytes.Buffer
+	tx.SerializeNoWitness(&buf)
+	noWitness := buf.Bytes()
+
+	forged := append([]byte{}, noWitness[:4]...)
+	forged = append(forged, witnessMarker, witnessFlag)
+	forged = append(forged, noWitness[4:len(noWitness)-4]...)
+	forged = append(forged, 0xfe, 0x00, 0x09, 0x3d, 0x00)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err := NewTxFromBytes(forged)
+	runtime.ReadMemStats(&after)
+
+	assert.Error(t, err)
+	assert.True(t, after.TotalAlloc-before.TotalAlloc < 1<<20)
+}
+
+func TestVarInt(t *testing.T) {
+	for _, n := range []uint64{0, 0xfc, 0xfd, 0xffff, 0x10000, 0xffffffff, 0x100000000} {
+		var buf bytes.Buffer
+		assert.NoError(t, WriteVarInt(&buf, n))
+		assert.Equal(t, VarIntSerializeSize(n), buf.Len())
+
+		got, err := ReadVarInt(&buf)
+		assert.NoError(t, err)
+		assert.Equal(t, n, got)
+	}
+
+	_, err := ReadVarInt(bytes.NewReader([]byte{0xfd, 0xfc, 0x00}))
+	assert.Equal(t, ErrNonCanonicalVarInt, err)
+
+	_, err = ReadVarBytes(bytes.NewReader([]byte{0x05, 1, 2, 3, 4, 5}), 4)
+	assert.Equal(t, ErrTooLarge, err)
+}
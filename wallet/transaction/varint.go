@@ -0,0 +1,163 @@
+package transaction
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// MaxVarBytes is the largest byte slice accepted by ReadVarBytes, it
+// matches the maximum size of a block
+const MaxVarBytes = 4000000
+
+var (
+	// ErrNonCanonicalVarInt is returned when a variable length integer is
+	// not minimally encoded
+	ErrNonCanonicalVarInt = errors.New("transaction: non canonical varint")
+	// ErrTooLarge is returned when a length prefix exceeds the allowed size
+	ErrTooLarge = errors.New("transaction: length exceeds maximum")
+)
+
+// WriteVarInt writes n as a Bitcoin variable length integer
+func WriteVarInt(w io.Writer, n uint64) error {
+
+	var b []byte
+
+	switch {
+	case n < 0xfd:
+		b = []byte{byte(n)}
+	case n <= 0xffff:
+		b = []byte{0xfd, 0, 0}
+		binary.LittleEndian.PutUint16(b[1:], uint16(n))
+	case n <= 0xffffffff:
+		b = []byte{0xfe, 0, 0, 0, 0}
+		binary.LittleEndian.PutUint32(b[1:], uint32(n))
+	default:
+		b = []byte{0xff, 0, 0, 0, 0, 0, 0, 0, 0}
+		binary.LittleEndian.PutUint64(b[1:], n)
+	}
+
+	_, err := w.Write(b)
+
+	return err
+}
+
+// ReadVarInt reads a canonically encoded variable length integer
+func ReadVarInt(r io.Reader) (uint64, error) {
+
+	var prefix [1]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return 0, err
+	}
+
+	var size int
+	var min uint64
+
+	switch prefix[0] {
+	case 0xfd:
+		size, min = 2, 0xfd
+	case 0xfe:
+		size, min = 4, 0x10000
+	case 0xff:
+		size, min = 8, 0x100000000
+	default:
+		return uint64(prefix[0]), nil
+	}
+
+	var b [8]byte
+	if _, err := io.ReadFull(r, b[:size]); err != nil {
+		return 0, err
+	}
+
+	n := binary.LittleEndian.Uint64(b[:])
+	if n < min {
+		return 0, ErrNonCanonicalVarInt
+	}
+
+	return n, nil
+}
+
+// VarIntSerializeSize returns the number of bytes needed to encode n
+func VarIntSerializeSize(n uint64) int {
+
+	switch {
+	case n < 0xfd:
+		return 1
+	case n <= 0xffff:
+		return 3
+	case n <= 0xffffffff:
+		return 5
+	}
+
+	return 9
+}
+
+// WriteVarBytes writes b prefixed by its length
+func WriteVarBytes(w io.Writer, b []byte) error {
+
+	if err := WriteVarInt(w, uint64(len(b))); err != nil {
+		return err
+	}
+
+	_, err := w.Write(b)
+
+	return err
+}
+
+// ReadVarBytes reads a length prefixed byte slice of at most maxAllowed bytes
+func ReadVarBytes(r io.Reader, maxAllowed uint64) ([]byte, error) {
+
+	n, err := ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if n > maxAllowed {
+		return nil, ErrTooLarge
+	}
+
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func writeUint32(w io.Writer, n uint32) error {
+
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], n)
+	_, err := w.Write(b[:])
+
+	return err
+}
+
+func writeUint64(w io.Writer, n uint64) error {
+
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], n)
+	_, err := w.Write(b[:])
+
+	return err
+}
+
+func readUint32(r io.Reader) (uint32, error) {
+
+	var b [4]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint32(b[:]), nil
+}
+
+func readUint64(r io.Reader) (uint64, error) {
+
+	var b [8]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint64(b[:]), nil
+}
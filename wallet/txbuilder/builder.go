@@ -0,0 +1,376 @@
+package txbuilder
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/giogam/Gopher-Wallet/wallet/account"
+	"github.com/giogam/Gopher-Wallet/wallet/address"
+	"github.com/giogam/Gopher-Wallet/wallet/coinselect"
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/psbt"
+	"github.com/giogam/Gopher-Wallet/wallet/script"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+const (
+	// TxVersion is the version of the built transactions
+	TxVersion = 2
+	// RBFSequence is the highest sequence signaling replaceability
+	RBFSequence = transaction.MaxTxInSequenceNum - 2
+	// FinalSequence is the sequence of non replaceable inputs, it still
+	// enables the locktime
+	FinalSequence = transaction.MaxTxInSequenceNum - 1
+)
+
+var (
+	// ErrNoRecipients is returned when building a transaction paying no one
+	ErrNoRecipients = errors.New("txbuilder: no recipients")
+	// ErrDustAmount is returned when a recipient would receive dust
+	ErrDustAmount = errors.New("txbuilder: amount is dust")
+	// ErrWrongNetwork is returned when a recipient address belongs to
+	// another network than the account
+	ErrWrongNetwork = errors.New("txbuilder: address is for another network")
+	// ErrMissingPrevTx is returned when a non segwit output is spent
+	// without its funding transaction
+	ErrMissingPrevTx = errors.New("txbuilder: missing previous transaction")
+)
+
+// Ordering selects how inputs and outputs are ordered
+type Ordering int
+
+const (
+	// RandomOrdering shuffles inputs and outputs
+	RandomOrdering Ordering = iota
+	// BIP69Ordering sorts inputs and outputs lexicographically
+	BIP69Ordering
+)
+
+// Recipient is an address and the amount it receives
+type Recipient struct {
+	Address *address.Address
+	Amount  int64
+}
+
+// Builder creates unsigned transactions spending the coins of an account.
+// Change goes to a fresh address of the internal chain and a change below
+// the dust threshold is left to the fee.
+type Builder struct {
+	Account *account.Account
+	FeeRate coinselect.FeeRate
+	// LongTermFeeRate is the rate coin selection expects to pay later
+	LongTermFeeRate coinselect.FeeRate
+	Ordering        Ordering
+	// RBF signals replaceability through the input sequences
+	RBF bool
+	// TipHeight is the height of the best block, when set the locktime
+	// is used against fee sniping
+	TipHeight   uint32
+	CoinControl *coinselect.CoinControl
+	Rand        *rand.Rand
+}
+
+// NewBuilder returns a builder spending from acct at feeRate with random
+// ordering and replaceability signaled
+func NewBuilder(acct *account.Account, feeRate coinselect.FeeRate) *Builder {
+	return &Builder{
+		Account:         acct,
+		FeeRate:         feeRate,
+		LongTermFeeRate: coinselect.DefaultLongTermFeeRate,
+		RBF:             true,
+		Rand:            rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Build selects coins paying recipients and returns the unsigned packet
+// with the UTXOs, scripts and derivations signers need
+func (b *Builder) Build(recipients []*Recipient) (*psbt.Packet, error) {
+
+	if len(recipients) == 0 {
+		return nil, ErrNoRecipients
+	}
+
+	var outputs []*transaction.TxOut
+	for _, r := range recipients {
+		if r.Address.Net != b.Account.Net {
+			return nil, ErrWrongNetwork
+		}
+
+		pkScript, err := script.PayToAddress(r.Address)
+		if err != nil {
+			return nil, err
+		}
+
+		out := transaction.NewTxOut(r.Amount, pkScript)
+		if r.Amount < DustThreshold(out) {
+			return nil, ErrDustAmount
+		}
+		outputs = append(outputs, out)
+	}
+
+	changeIndex := b.Account.Next[account.Internal]
+	changeScript, err := b.Account.PkScript(account.Internal, changeIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	coins, err := b.Account.Coins()
+	if err != nil {
+		return nil, err
+	}
+
+	selection, err := coinselect.Select(coins, outputs, b.params(changeScript), b.CoinControl)
+	if err != nil {
+		return nil, err
+	}
+
+	var change *transaction.TxOut
+	if selection.Change > 0 && selection.Change >= DustThreshold(transaction.NewTxOut(0, changeScript)) {
+		change = transaction.NewTxOut(selection.Change, changeScript)
+		outputs = append(outputs, change)
+	}
+
+	tx := transaction.NewTx(TxVersion)
+	tx.LockTime = b.lockTime()
+
+	for _, c := range selection.Coins {
+		in := transaction.NewTxIn(&c.OutPoint, nil, nil)
+		in.Sequence = b.sequence()
+		tx.AddTxIn(in)
+	}
+	for _, out := range outputs {
+		tx.AddTxOut(out)
+	}
+
+	b.order(tx)
+
+	return b.packet(tx, change, changeIndex)
+}
+
+// packet returns the packet of tx with the data signers need, change is
+// the output paying to the internal key at changeIndex, if any
+func (b *Builder) packet(tx *transaction.Tx, change *transaction.TxOut, changeIndex uint32) (*psbt.Packet, error) {
+
+	p, err := psbt.NewFromUnsignedTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, in := range tx.TxIn {
+		if err := b.updateInput(p, i, in.PreviousOutPoint); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, out := range tx.TxOut {
+		if out != change {
+			continue
+		}
+		if err := UpdateOutput(b.Account, p, i, account.Internal, changeIndex); err != nil {
+			return nil, err
+		}
+		b.Account.MarkUsed(account.Internal, changeIndex)
+	}
+
+	return p, nil
+}
+
+// params returns the coin selection parameters for a change paying to
+// changeScript
+func (b *Builder) params(changeScript []byte) *coinselect.Params {
+
+	params := coinselect.NewParams(b.FeeRate)
+	params.LongTermFeeRate = b.LongTermFeeRate
+	params.ChangeOutputSize = transaction.NewTxOut(0, changeScript).SerializeSize()
+	params.ChangeSpendSize = b.Account.InputSize()
+	params.Rand = b.Rand
+
+	return params
+}
+
+// sequence returns the sequence of new inputs
+func (b *Builder) sequence() uint32 {
+
+	if b.RBF {
+		return RBFSequence
+	}
+
+	return FinalSequence
+}
+
+// lockTime returns the tip height, sometimes a bit lower so that delayed
+// transactions do not stand out, or zero when the tip is unknown
+func (b *Builder) lockTime() uint32 {
+
+	if b.TipHeight == 0 {
+		return 0
+	}
+
+	lockTime := b.TipHeight
+	if b.Rand.Intn(10) == 0 {
+		delay := uint32(b.Rand.Intn(100))
+		if delay < lockTime {
+			lockTime -= delay
+		}
+	}
+
+	return lockTime
+}
+
+// order sorts or shuffles the inputs and outputs of tx
+func (b *Builder) order(tx *transaction.Tx) {
+
+	if b.Ordering == BIP69Ordering {
+		SortBIP69(tx)
+		return
+	}
+
+	b.Rand.Shuffle(len(tx.TxIn), func(i, j int) {
+		tx.TxIn[i], tx.TxIn[j] = tx.TxIn[j], tx.TxIn[i]
+	})
+	b.Rand.Shuffle(len(tx.TxOut), func(i, j int) {
+		tx.TxOut[i], tx.TxOut[j] = tx.TxOut[j], tx.TxOut[i]
+	})
+}
+
+// updateInput adds the spent output and the key derivation of input idx
+func (b *Builder) updateInput(p *psbt.Packet, idx int, op transaction.OutPoint) error {
+
+	u, err := b.Account.Utxo(op)
+	if err != nil {
+		return err
+	}
+
+	if u.PrevTx != nil {
+		if err := p.AddInNonWitnessUtxo(idx, u.PrevTx); err != nil {
+			return err
+		}
+	}
+
+	if b.Account.Type == address.P2PKH {
+		if u.PrevTx == nil {
+			return ErrMissingPrevTx
+		}
+	} else {
+		if err := p.AddInWitnessUtxo(idx, transaction.NewTxOut(u.Value, u.PkScript)); err != nil {
+			return err
+		}
+	}
+
+	redeemScript, d, td, err := keyFields(b.Account, u.Chain, u.Index)
+	if err != nil {
+		return err
+	}
+
+	in := p.Inputs[idx]
+	in.RedeemScript = redeemScript
+	if td != nil {
+		in.TaprootInternalKey = td.XOnlyPubKey
+		return p.AddInTaprootBip32Derivation(idx, td)
+	}
+
+	return p.AddInBip32Derivation(idx, d)
+}
+
+// UpdateOutput adds the redeem script and the key derivation of output idx
+// paying to the key of acct at chain and index, signers use them to
+// recognize change
+func UpdateOutput(acct *account.Account, p *psbt.Packet, idx int, chain, index uint32) error {
+
+	if idx < 0 || idx >= len(p.Outputs) {
+		return psbt.ErrIndex
+	}
+
+	redeemScript, d, td, err := keyFields(acct, chain, index)
+	if err != nil {
+		return err
+	}
+
+	out := p.Outputs[idx]
+	out.RedeemScript = redeemScript
+	if td != nil {
+		out.TaprootInternalKey = td.XOnlyPubKey
+		return p.AddOutTaprootBip32Derivation(idx, td)
+	}
+
+	return p.AddOutBip32Derivation(idx, d)
+}
+
+// keyFields returns the redeem script of a nested segwit key at chain and
+// index with its derivation, taproot keys get a taproot derivation instead
+func keyFields(acct *account.Account, chain, index uint32) ([]byte, *psbt.Bip32Derivation, *psbt.TaprootBip32Derivation, error) {
+
+	pub, err := acct.PubKey(chain, index)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	path := acct.KeyPath(chain, index)
+
+	if acct.Type == address.P2TR {
+		return nil, nil, &psbt.TaprootBip32Derivation{
+			XOnlyPubKey: pub.SerializeXOnly(),
+			Fingerprint: acct.Fingerprint,
+			Path:        path,
+		}, nil
+	}
+
+	var redeemScript []byte
+	if acct.Type == address.P2SH {
+		if redeemScript, err = script.PayToWitnessPubKeyHash(hdwallet.Hash160(pub.SerializeCompressed())); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	return redeemScript, &psbt.Bip32Derivation{
+		PubKey:      pub.SerializeCompressed(),
+		Fingerprint: acct.Fingerprint,
+		Path:        path,
+	}, nil, nil
+}
+
+// SortBIP69 sorts the inputs by previous transaction id, as displayed, and
+// output index, and the outputs by amount and script
+func SortBIP69(tx *transaction.Tx) {
+
+	sort.SliceStable(tx.TxIn, func(i, j int) bool {
+		a, b := tx.TxIn[i].PreviousOutPoint, tx.TxIn[j].PreviousOutPoint
+		if a.Hash != b.Hash {
+			// the hashes are displayed in reverse byte order
+			for k := transaction.HashSize - 1; k >= 0; k-- {
+				if a.Hash[k] != b.Hash[k] {
+					return a.Hash[k] < b.Hash[k]
+				}
+			}
+		}
+		return a.Index < b.Index
+	})
+
+	sort.SliceStable(tx.TxOut, func(i, j int) bool {
+		a, b := tx.TxOut[i], tx.TxOut[j]
+		if a.Value != b.Value {
+			return a.Value < b.Value
+		}
+		return bytes.Compare(a.PkScript, b.PkScript) < 0
+	})
+}
+
+// DustThreshold returns the smallest amount of out worth spending at the
+// dust relay fee rate, unspendable outputs have no threshold
+func DustThreshold(out *transaction.TxOut) int64 {
+
+	if len(out.PkScript) > 0 && out.PkScript[0] == script.OpReturn {
+		return 0
+	}
+
+	// the size of the input spending it, witness data being discounted
+	size := out.SerializeSize() + 32 + 4 + 1 + 107 + 4
+	if _, _, ok := script.ExtractWitnessProgram(out.PkScript); ok {
+		size = out.SerializeSize() + 32 + 4 + 1 + 107/4 + 4
+	}
+
+	return coinselect.DustRelayFeeRate.Fee(size)
+}
@@ -0,0 +1,233 @@
+package txbuilder
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/giogam/Gopher-Wallet/wallet/account"
+	"github.com/giogam/Gopher-Wallet/wallet/address"
+	"github.com/giogam/Gopher-Wallet/wallet/coinselect"
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/mnemonic"
+	"github.com/giogam/Gopher-Wallet/wallet/script"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+func testMaster(t *testing.T) *hdwallet.ExtendedKey {
+
+	seed := mnemonic.NewSeed(strings.Split(testMnemonic, " "), "")
+	master, err := hdwallet.NewMasterKey(seed, hdwallet.TestnetPrivate)
+	assert.NoError(t, err)
+
+	return master
+}
+
+// fundedAccount returns an account of type t with a funding transaction
+// for each value paying to its receive addresses
+func fundedAccount(t *testing.T, master *hdwallet.ExtendedKey, typ address.Type, values ...int64) *account.Account {
+
+	a, err := account.New(master, typ, 0, address.RegTest)
+	assert.NoError(t, err)
+
+	for i, v := range values {
+		pkScript, err := a.PkScript(account.External, uint32(i))
+		assert.NoError(t, err)
+
+		hash := transaction.DoubleHashH([]byte{byte(i)})
+		prevTx := transaction.NewTx(2)
+		prevTx.AddTxIn(transaction.NewTxIn(transaction.NewOutPoint(&hash, 0), nil, nil))
+		prevTx.AddTxOut(transaction.NewTxOut(v, pkScript))
+
+		txid := prevTx.TxHash()
+		assert.NoError(t, a.AddUtxo(&account.Utxo{
+			OutPoint: *transaction.NewOutPoint(&txid, 0),
+			Value:    v,
+			PkScript: pkScript,
+			Chain:    account.External,
+			Index:    uint32(i),
+			Height:   1,
+			PrevTx:   prevTx,
+		}))
+	}
+
+	return a
+}
+
+func recipient(t *testing.T, amount int64) *Recipient {
+
+	addr, err := address.NewWitness(0, make([]byte, 20), address.RegTest)
+	assert.NoError(t, err)
+
+	return &Recipient{Address: addr, Amount: amount}
+}
+
+func newBuilder(a *account.Account, feeRate coinselect.FeeRate) *Builder {
+
+	b := NewBuilder(a, feeRate)
+	b.Rand = rand.New(rand.NewSource(1))
+
+	return b
+}
+
+func TestBuild(t *testing.T) {
+	master := testMaster(t)
+
+	for _, typ := range []address.Type{address.P2PKH, address.P2SH, address.P2WPKH, address.P2TR} {
+		a := fundedAccount(t, master, typ, 100000, 200000, 300000)
+
+		b := newBuilder(a, 5000)
+		b.Ordering = BIP69Ordering
+		b.TipHeight = 800000
+
+		p, err := b.Build([]*Recipient{recipient(t, 250000), recipient(t, 30000)})
+		assert.NoError(t, err, typ)
+
+		tx := p.UnsignedTx
+		assert.Equal(t, int32(TxVersion), tx.Version)
+		assert.LessOrEqual(t, tx.LockTime, uint32(800000))
+		assert.Greater(t, tx.LockTime, uint32(800000-100))
+
+		for _, in := range tx.TxIn {
+			assert.Equal(t, uint32(RBFSequence), in.Sequence)
+		}
+
+		sorted := tx.Copy()
+		SortBIP69(sorted)
+		assert.Equal(t, sorted.TxHash(), tx.TxHash())
+
+		// the change pays to the first internal key and carries its
+		// derivation
+		changeScript, _ := a.PkScript(account.Internal, 0)
+		change := -1
+		for i, out := range tx.TxOut {
+			if string(out.PkScript) == string(changeScript) {
+				change = i
+			}
+		}
+		assert.NotEqual(t, -1, change, typ)
+		assert.Equal(t, uint32(1), a.Next[account.Internal])
+
+		path := a.KeyPath(account.Internal, 0)
+		if typ == address.P2TR {
+			assert.Equal(t, path, p.Outputs[change].TaprootBip32Derivation[0].Path)
+		} else {
+			assert.Equal(t, path, p.Outputs[change].Bip32Derivation[0].Path)
+		}
+
+		// the packet holds everything needed to sign
+		assert.NoError(t, p.Sign(master))
+		assert.NoError(t, p.Finalize(), typ)
+		signed, err := p.Extract()
+		assert.NoError(t, err)
+
+		prevOuts := make(transaction.PrevOutputMap)
+		var in, out int64
+		for _, txIn := range signed.TxIn {
+			u, _ := a.Utxo(txIn.PreviousOutPoint)
+			prevOuts[txIn.PreviousOutPoint] = transaction.NewTxOut(u.Value, u.PkScript)
+			in += u.Value
+		}
+		for _, txOut := range signed.TxOut {
+			out += txOut.Value
+		}
+		assert.NoError(t, script.VerifyTx(signed, prevOuts, script.StandardVerifyFlags))
+
+		// the fee rate is met without overpaying more than the estimated
+		// signature sizes
+		fee := in - out
+		assert.GreaterOrEqual(t, fee, int64(5*signed.VSize()), typ)
+		assert.Less(t, fee, int64(5*(signed.VSize()+2*len(signed.TxIn)+2)), typ)
+	}
+}
+
+func TestBuildOptions(t *testing.T) {
+	master := testMaster(t)
+	a := fundedAccount(t, master, address.P2WPKH, 100000, 200000)
+
+	b := newBuilder(a, 1000)
+	b.RBF = false
+	b.CoinControl = &coinselect.CoinControl{Include: []transaction.OutPoint{a.Utxos[0].OutPoint}}
+
+	p, err := b.Build([]*Recipient{recipient(t, 10000)})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0), p.UnsignedTx.LockTime)
+	assert.Len(t, p.UnsignedTx.TxIn, 1)
+	assert.Equal(t, a.Utxos[0].OutPoint, p.UnsignedTx.TxIn[0].PreviousOutPoint)
+	assert.Equal(t, uint32(FinalSequence), p.UnsignedTx.TxIn[0].Sequence)
+
+	_, err = b.Build(nil)
+	assert.Equal(t, ErrNoRecipients, err)
+
+	_, err = b.Build([]*Recipient{recipient(t, 293)})
+	assert.Equal(t, ErrDustAmount, err)
+
+	mainnet, _ := address.NewWitness(0, make([]byte, 20), address.MainNet)
+	_, err = b.Build([]*Recipient{{Address: mainnet, Amount: 10000}})
+	assert.Equal(t, ErrWrongNetwork, err)
+
+	// legacy outputs cannot be spent without their funding transaction
+	legacy := fundedAccount(t, master, address.P2PKH, 100000)
+	legacy.Utxos[0].PrevTx = nil
+	_, err = newBuilder(legacy, 1000).Build([]*Recipient{recipient(t, 10000)})
+	assert.Equal(t, ErrMissingPrevTx, err)
+}
+
+func TestBuildChangelessSpend(t *testing.T) {
+	a := fundedAccount(t, testMaster(t), address.P2WPKH, 100000)
+
+	// 11 + 31 vbytes without inputs and 68 for the input at 1 sat/vB
+	p, err := newBuilder(a, 1000).Build([]*Recipient{recipient(t, 100000-42-68)})
+	assert.NoError(t, err)
+	assert.Len(t, p.UnsignedTx.TxOut, 1)
+	assert.Equal(t, uint32(0), a.Next[account.Internal])
+}
+
+func TestSortBIP69(t *testing.T) {
+	h1, _ := transaction.NewHashFromStr("0e53ec5dfb2cb8a71fec32dc9a634a35b7e24799295ddd5278217822e0b31f57")
+	h2, _ := transaction.NewHashFromStr("26aa6e6d8b9e49bb0630aac301db6757c02e3619feb4ee0eea81eb1672947024")
+	h3, _ := transaction.NewHashFromStr("28e0fdd185542f2c6ea19030b0796051e7772b6026dd5ddccd7a2f93b73e6fc2")
+
+	tx := transaction.NewTx(2)
+	tx.AddTxIn(transaction.NewTxIn(transaction.NewOutPoint(&h3, 0), nil, nil))
+	tx.AddTxIn(transaction.NewTxIn(transaction.NewOutPoint(&h1, 1), nil, nil))
+	tx.AddTxIn(transaction.NewTxIn(transaction.NewOutPoint(&h2, 1), nil, nil))
+	tx.AddTxIn(transaction.NewTxIn(transaction.NewOutPoint(&h1, 0), nil, nil))
+	tx.AddTxOut(transaction.NewTxOut(2000, []byte{0x52}))
+	tx.AddTxOut(transaction.NewTxOut(1000, []byte{0x53}))
+	tx.AddTxOut(transaction.NewTxOut(2000, []byte{0x51}))
+
+	SortBIP69(tx)
+
+	var inputs []string
+	for _, in := range tx.TxIn {
+		inputs = append(inputs, in.PreviousOutPoint.String())
+	}
+	assert.Equal(t, []string{
+		h1.String() + ":0",
+		h1.String() + ":1",
+		h2.String() + ":1",
+		h3.String() + ":0",
+	}, inputs)
+
+	assert.Equal(t, []*transaction.TxOut{
+		transaction.NewTxOut(1000, []byte{0x53}),
+		transaction.NewTxOut(2000, []byte{0x51}),
+		transaction.NewTxOut(2000, []byte{0x52}),
+	}, tx.TxOut)
+}
+
+func TestDustThreshold(t *testing.T) {
+	hash := make([]byte, 20)
+	p2pkh, _ := script.PayToPubKeyHash(hash)
+	p2wpkh, _ := script.PayToWitnessPubKeyHash(hash)
+	p2tr, _ := script.PayToTaproot(make([]byte, 32))
+
+	assert.Equal(t, int64(546), DustThreshold(transaction.NewTxOut(0, p2pkh)))
+	assert.Equal(t, int64(294), DustThreshold(transaction.NewTxOut(0, p2wpkh)))
+	assert.Equal(t, int64(330), DustThreshold(transaction.NewTxOut(0, p2tr)))
+	assert.Equal(t, int64(0), DustThreshold(transaction.NewTxOut(0, []byte{script.OpReturn})))
+}
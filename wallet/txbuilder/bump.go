@@ -0,0 +1,306 @@
+package txbuilder
+
+import (
+	"errors"
+
+	"github.com/giogam/Gopher-Wallet/wallet/account"
+	"github.com/giogam/Gopher-Wallet/wallet/coinselect"
+	"github.com/giogam/Gopher-Wallet/wallet/psbt"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+// IncrementalRelayFeeRate is the rate a replacement pays for its own size
+// on top of the fee of the transactions it replaces
+const IncrementalRelayFeeRate coinselect.FeeRate = 1000
+
+var (
+	// ErrNotReplaceable is returned when bumping the fee of a transaction
+	// not signaling replaceability
+	ErrNotReplaceable = errors.New("txbuilder: transaction is not replaceable")
+	// ErrFeeRateTooLow is returned when the target fee rate does not exceed
+	// the one already paid
+	ErrFeeRateTooLow = errors.New("txbuilder: fee rate too low")
+	// ErrNoSpendableOutput is returned when a parent has no output paying
+	// to the account
+	ErrNoSpendableOutput = errors.New("txbuilder: no spendable output")
+)
+
+// SignalsReplacement reports whether tx opts in to replacement as defined
+// by BIP125
+func SignalsReplacement(tx *transaction.Tx) bool {
+
+	for _, in := range tx.TxIn {
+		if in.Sequence < transaction.MaxTxInSequenceNum-1 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Fee returns the fee paid by tx, all its inputs must spend outputs of acct
+func Fee(acct *account.Account, tx *transaction.Tx) (int64, error) {
+
+	var fee int64
+	for _, in := range tx.TxIn {
+		u, err := acct.Utxo(in.PreviousOutPoint)
+		if err != nil {
+			return 0, err
+		}
+		fee += u.Value
+	}
+
+	for _, out := range tx.TxOut {
+		fee -= out.Value
+	}
+
+	return fee, nil
+}
+
+// BumpFee returns a BIP125 replacement of tx, a transaction of the account
+// stuck at a lower fee rate. The change is reduced first, or dropped when
+// it would become dust, and more confirmed coins are selected when it
+// cannot pay the difference. The replacement pays the fee of tx and the
+// incremental relay fee for its own size.
+func (b *Builder) BumpFee(tx *transaction.Tx) (*psbt.Packet, error) {
+
+	if !SignalsReplacement(tx) {
+		return nil, ErrNotReplaceable
+	}
+
+	fee, err := Fee(b.Account, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(b.FeeRate)*int64(tx.VSize()) <= fee*1000 {
+		return nil, ErrFeeRateTooLow
+	}
+
+	var coins []*coinselect.Coin
+	var inputs []transaction.OutPoint
+	var value int64
+	for _, in := range tx.TxIn {
+		u, err := b.Account.Utxo(in.PreviousOutPoint)
+		if err != nil {
+			return nil, err
+		}
+		coins = append(coins, b.Account.Coin(u))
+		inputs = append(inputs, u.OutPoint)
+		value += u.Value
+	}
+
+	change := -1
+	var changeIndex uint32
+	var recipients []*transaction.TxOut
+	var paid int64
+	for i, out := range tx.TxOut {
+		if chain, index, ok := b.Account.KeyIndex(out.PkScript); ok && chain == account.Internal && change == -1 {
+			change, changeIndex = i, index
+			continue
+		}
+		recipients = append(recipients, out)
+		paid += out.Value
+	}
+
+	// the fee of the replacement of a given size
+	minFee := func(size int) int64 {
+
+		if required := fee + IncrementalRelayFeeRate.Fee(size); required > b.FeeRate.Fee(size) {
+			return required
+		}
+
+		return b.FeeRate.Fee(size)
+	}
+
+	if change != -1 {
+		out := tx.TxOut[change]
+		if remaining := value - paid - minFee(estimateSize(coins, tx.TxOut)); remaining >= DustThreshold(out) {
+			return b.replace(tx, change, remaining, changeIndex)
+		}
+		if value-paid >= minFee(estimateSize(coins, recipients)) {
+			return b.replace(tx, change, 0, changeIndex)
+		}
+	}
+
+	// a rate keeping the replacement above the fee of tx for any selection
+	// including its inputs
+	size := estimateSize(coins, recipients)
+	rate := coinselect.FeeRate((fee*1000+int64(size)-1)/int64(size)) + IncrementalRelayFeeRate
+	if rate < b.FeeRate {
+		rate = b.FeeRate
+	}
+
+	// the change keeps its key or goes to a fresh one
+	var changeScript []byte
+	if change != -1 {
+		changeScript = tx.TxOut[change].PkScript
+	} else {
+		changeIndex = b.Account.Next[account.Internal]
+		if changeScript, err = b.Account.PkScript(account.Internal, changeIndex); err != nil {
+			return nil, err
+		}
+	}
+
+	// BIP125 only allows new inputs spending confirmed outputs, which also
+	// keeps out the outputs of tx and its descendants
+	pool := append([]*coinselect.Coin{}, coins...)
+	for _, u := range b.Account.Utxos {
+		if u.Confirmed() && !containsOutPoint(inputs, u.OutPoint) {
+			pool = append(pool, b.Account.Coin(u))
+		}
+	}
+
+	control := &coinselect.CoinControl{Include: inputs}
+	if b.CoinControl != nil {
+		control.Exclude = b.CoinControl.Exclude
+	}
+
+	params := b.params(changeScript)
+	params.FeeRate = rate
+
+	selection, err := coinselect.Select(pool, recipients, params, control)
+	if err != nil {
+		return nil, err
+	}
+
+	replacement := transaction.NewTx(tx.Version)
+	replacement.LockTime = tx.LockTime
+
+	sequences := make(map[transaction.OutPoint]uint32)
+	for _, in := range tx.TxIn {
+		sequences[in.PreviousOutPoint] = in.Sequence
+	}
+	for _, c := range selection.Coins {
+		in := transaction.NewTxIn(&c.OutPoint, nil, nil)
+		in.Sequence = b.sequence()
+		if sequence, ok := sequences[c.OutPoint]; ok {
+			in.Sequence = sequence
+		}
+		replacement.AddTxIn(in)
+	}
+
+	for _, out := range recipients {
+		replacement.AddTxOut(transaction.NewTxOut(out.Value, out.PkScript))
+	}
+
+	var changeOut *transaction.TxOut
+	if selection.Change > 0 && selection.Change >= DustThreshold(transaction.NewTxOut(0, changeScript)) {
+		changeOut = transaction.NewTxOut(selection.Change, changeScript)
+		replacement.AddTxOut(changeOut)
+	}
+
+	b.order(replacement)
+
+	return b.packet(replacement, changeOut, changeIndex)
+}
+
+// containsOutPoint reports whether ops contains op
+func containsOutPoint(ops []transaction.OutPoint, op transaction.OutPoint) bool {
+
+	for _, o := range ops {
+		if o == op {
+			return true
+		}
+	}
+
+	return false
+}
+
+// replace returns the replacement of tx spending the same inputs with the
+// change output at index change set to value, or removed when value is zero
+func (b *Builder) replace(tx *transaction.Tx, change int, value int64, changeIndex uint32) (*psbt.Packet, error) {
+
+	replacement := transaction.NewTx(tx.Version)
+	replacement.LockTime = tx.LockTime
+
+	for _, in := range tx.TxIn {
+		txIn := transaction.NewTxIn(&in.PreviousOutPoint, nil, nil)
+		txIn.Sequence = in.Sequence
+		replacement.AddTxIn(txIn)
+	}
+
+	var changeOut *transaction.TxOut
+	for i, out := range tx.TxOut {
+		if i != change {
+			replacement.AddTxOut(transaction.NewTxOut(out.Value, out.PkScript))
+			continue
+		}
+		if value > 0 {
+			changeOut = transaction.NewTxOut(value, out.PkScript)
+			replacement.AddTxOut(changeOut)
+		}
+	}
+
+	return b.packet(replacement, changeOut, changeIndex)
+}
+
+// CPFP returns a child of parent spending its output paying to the account,
+// preferably its change, to a fresh change address. The child pays enough
+// for the package to reach the fee rate of the builder, parentFee is the
+// fee paid by parent which Fee computes for transactions of the account.
+func (b *Builder) CPFP(parent *transaction.Tx, parentFee int64) (*psbt.Packet, error) {
+
+	hash := parent.TxHash()
+
+	var u *account.Utxo
+	for i := range parent.TxOut {
+		candidate, err := b.Account.Utxo(*transaction.NewOutPoint(&hash, uint32(i)))
+		if err != nil {
+			continue
+		}
+		if u == nil || (candidate.Chain == account.Internal && u.Chain != account.Internal) {
+			u = candidate
+		}
+	}
+
+	if u == nil {
+		return nil, ErrNoSpendableOutput
+	}
+
+	coin := b.Account.Coin(u)
+
+	changeIndex := b.Account.Next[account.Internal]
+	changeScript, err := b.Account.PkScript(account.Internal, changeIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	change := transaction.NewTxOut(0, changeScript)
+	size := estimateSize([]*coinselect.Coin{coin}, []*transaction.TxOut{change})
+
+	fee := b.FeeRate.Fee(parent.VSize()+size) - parentFee
+	if fee < b.FeeRate.Fee(size) {
+		return nil, ErrFeeRateTooLow
+	}
+
+	change.Value = u.Value - fee
+	if change.Value < DustThreshold(change) {
+		return nil, coinselect.ErrInsufficientFunds
+	}
+
+	tx := transaction.NewTx(TxVersion)
+	tx.LockTime = b.lockTime()
+
+	in := transaction.NewTxIn(&u.OutPoint, nil, nil)
+	in.Sequence = b.sequence()
+	tx.AddTxIn(in)
+	tx.AddTxOut(change)
+
+	return b.packet(tx, change, changeIndex)
+}
+
+// estimateSize returns the size in vbytes of a transaction spending coins
+// to outputs, as estimated by coin selection
+func estimateSize(coins []*coinselect.Coin, outputs []*transaction.TxOut) int {
+
+	size := 10 + transaction.VarIntSerializeSize(uint64(len(outputs)))
+	for _, c := range coins {
+		size += c.InputSize
+	}
+	for _, out := range outputs {
+		size += out.SerializeSize()
+	}
+
+	return size
+}
@@ -0,0 +1,223 @@
+package txbuilder
+
+import (
+	"testing"
+
+	"github.com/giogam/Gopher-Wallet/wallet/account"
+	"github.com/giogam/Gopher-Wallet/wallet/address"
+	"github.com/giogam/Gopher-Wallet/wallet/coinselect"
+	"github.com/giogam/Gopher-Wallet/wallet/hdwallet"
+	"github.com/giogam/Gopher-Wallet/wallet/psbt"
+	"github.com/giogam/Gopher-Wallet/wallet/script"
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+// signTx signs and extracts p, checking the scripts of the final
+// transaction
+func signTx(t *testing.T, master *hdwallet.ExtendedKey, a *account.Account, p *psbt.Packet) *transaction.Tx {
+
+	assert.NoError(t, p.Sign(master))
+	assert.NoError(t, p.Finalize())
+	tx, err := p.Extract()
+	assert.NoError(t, err)
+
+	prevOuts := make(transaction.PrevOutputMap)
+	for _, in := range tx.TxIn {
+		u, err := a.Utxo(in.PreviousOutPoint)
+		assert.NoError(t, err)
+		prevOuts[in.PreviousOutPoint] = transaction.NewTxOut(u.Value, u.PkScript)
+	}
+	assert.NoError(t, script.VerifyTx(tx, prevOuts, script.StandardVerifyFlags))
+
+	return tx
+}
+
+// manualTx returns the signed transaction spending the first coin of a to
+// the given outputs
+func manualTx(t *testing.T, master *hdwallet.ExtendedKey, a *account.Account, outputs ...*transaction.TxOut) *transaction.Tx {
+
+	tx := transaction.NewTx(TxVersion)
+	in := transaction.NewTxIn(&a.Utxos[0].OutPoint, nil, nil)
+	in.Sequence = RBFSequence
+	tx.AddTxIn(in)
+	for _, out := range outputs {
+		tx.AddTxOut(out)
+	}
+
+	p, err := newBuilder(a, 1000).packet(tx, nil, 0)
+	assert.NoError(t, err)
+
+	return signTx(t, master, a, p)
+}
+
+// assertReplacement checks the BIP125 fee rules of replacement against tx
+func assertReplacement(t *testing.T, a *account.Account, tx, replacement *transaction.Tx, rate coinselect.FeeRate) {
+
+	fee, err := Fee(a, tx)
+	assert.NoError(t, err)
+	newFee, err := Fee(a, replacement)
+	assert.NoError(t, err)
+
+	assert.GreaterOrEqual(t, newFee, fee+IncrementalRelayFeeRate.Fee(replacement.VSize()))
+	assert.GreaterOrEqual(t, newFee, rate.Fee(replacement.VSize()))
+
+	spent := make(map[transaction.OutPoint]bool)
+	for _, in := range replacement.TxIn {
+		spent[in.PreviousOutPoint] = true
+	}
+	for _, in := range tx.TxIn {
+		assert.True(t, spent[in.PreviousOutPoint])
+	}
+}
+
+func TestBumpFeeShrinksChange(t *testing.T) {
+	master := testMaster(t)
+	a := fundedAccount(t, master, address.P2WPKH, 100000, 200000)
+
+	b := newBuilder(a, 2000)
+	p, err := b.Build([]*Recipient{recipient(t, 50000)})
+	assert.NoError(t, err)
+	tx := signTx(t, master, a, p)
+
+	b.FeeRate = 20000
+	p, err = b.BumpFee(tx)
+	assert.NoError(t, err)
+	replacement := signTx(t, master, a, p)
+
+	assertReplacement(t, a, tx, replacement, b.FeeRate)
+	assert.Equal(t, len(tx.TxIn), len(replacement.TxIn))
+	assert.Equal(t, len(tx.TxOut), len(replacement.TxOut))
+	assert.Equal(t, tx.LockTime, replacement.LockTime)
+
+	changeScript, _ := a.PkScript(account.Internal, 0)
+	for i, out := range replacement.TxOut {
+		if string(out.PkScript) == string(changeScript) {
+			assert.Less(t, out.Value, tx.TxOut[i].Value)
+		} else {
+			assert.Equal(t, tx.TxOut[i], out)
+		}
+	}
+	assert.Equal(t, uint32(1), a.Next[account.Internal])
+}
+
+func TestBumpFeeDropsChange(t *testing.T) {
+	master := testMaster(t)
+	a := fundedAccount(t, master, address.P2WPKH, 100000)
+
+	changeScript, _ := a.PkScript(account.Internal, 0)
+	a.MarkUsed(account.Internal, 0)
+	pay := recipient(t, 98800)
+	payScript, _ := script.PayToAddress(pay.Address)
+	tx := manualTx(t, master, a, transaction.NewTxOut(98800, payScript), transaction.NewTxOut(1000, changeScript))
+
+	b := newBuilder(a, 10000)
+	p, err := b.BumpFee(tx)
+	assert.NoError(t, err)
+	replacement := signTx(t, master, a, p)
+
+	assertReplacement(t, a, tx, replacement, b.FeeRate)
+	assert.Equal(t, []*transaction.TxOut{transaction.NewTxOut(98800, payScript)}, replacement.TxOut)
+}
+
+func TestBumpFeeAddsInputs(t *testing.T) {
+	master := testMaster(t)
+	a := fundedAccount(t, master, address.P2WPKH, 100000, 50000)
+
+	pay := recipient(t, 99000)
+	payScript, _ := script.PayToAddress(pay.Address)
+	tx := manualTx(t, master, a, transaction.NewTxOut(99000, payScript))
+
+	b := newBuilder(a, 20000)
+	p, err := b.BumpFee(tx)
+	assert.NoError(t, err)
+	replacement := signTx(t, master, a, p)
+
+	assertReplacement(t, a, tx, replacement, b.FeeRate)
+	assert.Len(t, replacement.TxIn, 2)
+	assert.Len(t, replacement.TxOut, 2)
+	for _, in := range replacement.TxIn {
+		assert.Equal(t, uint32(RBFSequence), in.Sequence)
+	}
+
+	// unconfirmed coins cannot be added to a replacement
+	a.Utxos[1].Height = 0
+	_, err = b.BumpFee(tx)
+	assert.Equal(t, coinselect.ErrInsufficientFunds, err)
+}
+
+func TestBumpFeeErrors(t *testing.T) {
+	master := testMaster(t)
+	a := fundedAccount(t, master, address.P2WPKH, 100000)
+
+	b := newBuilder(a, 5000)
+	p, err := b.Build([]*Recipient{recipient(t, 50000)})
+	assert.NoError(t, err)
+	tx := signTx(t, master, a, p)
+
+	_, err = b.BumpFee(tx)
+	assert.Equal(t, ErrFeeRateTooLow, err)
+
+	final := tx.Copy()
+	final.TxIn[0].Sequence = FinalSequence
+	assert.False(t, SignalsReplacement(final))
+	_, err = b.BumpFee(final)
+	assert.Equal(t, ErrNotReplaceable, err)
+
+	b.FeeRate = 1000000
+	_, err = b.BumpFee(tx)
+	assert.Equal(t, coinselect.ErrInsufficientFunds, err)
+
+	// only transactions of the account can be bumped
+	other := fundedAccount(t, master, address.P2TR, 100000)
+	_, err = newBuilder(other, 20000).BumpFee(tx)
+	assert.Equal(t, account.ErrUnknownUtxo, err)
+}
+
+func TestCPFP(t *testing.T) {
+	master := testMaster(t)
+	a := fundedAccount(t, master, address.P2TR, 100000)
+
+	b := newBuilder(a, 1000)
+	p, err := b.Build([]*Recipient{recipient(t, 30000)})
+	assert.NoError(t, err)
+	parent := signTx(t, master, a, p)
+
+	// the change of the parent is recorded once broadcast
+	changeScript, _ := a.PkScript(account.Internal, 0)
+	hash := parent.TxHash()
+	for i, out := range parent.TxOut {
+		if string(out.PkScript) == string(changeScript) {
+			assert.NoError(t, a.AddUtxo(&account.Utxo{
+				OutPoint: *transaction.NewOutPoint(&hash, uint32(i)),
+				Value:    out.Value,
+				PkScript: out.PkScript,
+				Chain:    account.Internal,
+				Index:    0,
+			}))
+		}
+	}
+
+	parentFee, err := Fee(a, parent)
+	assert.NoError(t, err)
+
+	_, err = b.CPFP(parent, parentFee)
+	assert.Equal(t, ErrFeeRateTooLow, err)
+
+	b.FeeRate = 25000
+	p, err = b.CPFP(parent, parentFee)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), a.Next[account.Internal])
+	child := signTx(t, master, a, p)
+
+	assert.Len(t, child.TxIn, 1)
+	assert.Equal(t, hash, child.TxIn[0].PreviousOutPoint.Hash)
+
+	childFee, err := Fee(a, child)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, parentFee+childFee, b.FeeRate.Fee(parent.VSize()+child.VSize()))
+
+	// the parent must pay to the account
+	_, err = newBuilder(fundedAccount(t, master, address.P2WPKH, 100000), 25000).CPFP(parent, parentFee)
+	assert.Equal(t, ErrNoSpendableOutput, err)
+}
@@ -0,0 +1,318 @@
+package wire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+// BitcoinNet is the magic identifying the messages of a network
+type BitcoinNet uint32
+
+// network magics, the first bytes of every message
+const (
+	MainNet  BitcoinNet = 0xd9b4bef9
+	TestNet3 BitcoinNet = 0x0709110b
+	RegTest  BitcoinNet = 0xdab5bffa
+	SigNet   BitcoinNet = 0x40cf030a
+)
+
+const (
+	// ProtocolVersion is the version of the protocol spoken, 70016 relays
+	// transactions by wtxid
+	ProtocolVersion = 70016
+	// MessageHeaderSize is the size of the header of every message
+	MessageHeaderSize = 24
+	// MaxMessagePayload is the largest payload accepted
+	MaxMessagePayload = 32 * 1024 * 1024
+
+	commandSize = 12
+)
+
+var (
+	// ErrWrongNetwork is returned when a message has the magic of another
+	// network
+	ErrWrongNetwork = errors.New("wire: message from another network")
+	// ErrMessageTooLarge is returned when a payload exceeds
+	// MaxMessagePayload or a message holds too many items
+	ErrMessageTooLarge = errors.New("wire: message too large")
+	// ErrInvalidChecksum is returned when the checksum of a payload does
+	// not match
+	ErrInvalidChecksum = errors.New("wire: invalid payload checksum")
+	// ErrInvalidCommand is returned when a command is not printable ASCII
+	// padded with zeros
+	ErrInvalidCommand = errors.New("wire: invalid command")
+)
+
+// Message is a message of the peer to peer protocol
+type Message interface {
+	// Command returns the command of the message header
+	Command() string
+	// Encode writes the payload of the message
+	Encode(w io.Writer) error
+	// Decode reads the payload of the message
+	Decode(r io.Reader) error
+}
+
+// MsgUnknown is a message whose command is not known, its payload is kept
+// undecoded
+type MsgUnknown struct {
+	Cmd     string
+	Payload []byte
+}
+
+// Command returns the command of the message
+func (m *MsgUnknown) Command() string {
+	return m.Cmd
+}
+
+// Encode writes the payload
+func (m *MsgUnknown) Encode(w io.Writer) error {
+
+	_, err := w.Write(m.Payload)
+
+	return err
+}
+
+// Decode reads the whole payload
+func (m *MsgUnknown) Decode(r io.Reader) error {
+
+	payload, err := io.ReadAll(r)
+	m.Payload = payload
+
+	return err
+}
+
+// newMessage returns an empty message for a command
+func newMessage(command string) Message {
+
+	switch command {
+	case CmdVersion:
+		return &MsgVersion{}
+	case CmdVerAck:
+		return &MsgVerAck{}
+	case CmdPing:
+		return &MsgPing{}
+	case CmdPong:
+		return &MsgPong{}
+	case CmdSendCmpct:
+		return &MsgSendCmpct{}
+	case CmdGetHeaders:
+		return &MsgGetHeaders{}
+	case CmdHeaders:
+		return &MsgHeaders{}
+	case CmdInv:
+		return &MsgInv{}
+	case CmdGetData:
+		return &MsgGetData{}
+	case CmdBlock:
+		return &MsgBlock{}
+	case CmdTx:
+		return &MsgTx{}
+	case CmdGetCFilters:
+		return &MsgGetCFilters{}
+	case CmdCFilter:
+		return &MsgCFilter{}
+	case CmdGetCFHeaders:
+		return &MsgGetCFHeaders{}
+	case CmdCFHeaders:
+		return &MsgCFHeaders{}
+	case CmdGetCFCheckpt:
+		return &MsgGetCFCheckpt{}
+	case CmdCFCheckpt:
+		return &MsgCFCheckpt{}
+	}
+
+	return &MsgUnknown{Cmd: command}
+}
+
+// WriteMessage writes msg with its header for the network net
+func WriteMessage(w io.Writer, msg Message, net BitcoinNet) error {
+
+	command := msg.Command()
+	if len(command) > commandSize {
+		return ErrInvalidCommand
+	}
+
+	var payload bytes.Buffer
+	if err := msg.Encode(&payload); err != nil {
+		return err
+	}
+	if payload.Len() > MaxMessagePayload {
+		return ErrMessageTooLarge
+	}
+
+	header := make([]byte, MessageHeaderSize)
+	binary.LittleEndian.PutUint32(header[0:4], uint32(net))
+	copy(header[4:16], command)
+	binary.LittleEndian.PutUint32(header[16:20], uint32(payload.Len()))
+	checksum := transaction.DoubleHashH(payload.Bytes())
+	copy(header[20:24], checksum[:4])
+
+	_, err := w.Write(append(header, payload.Bytes()...))
+
+	return err
+}
+
+// ReadMessage reads a message of the network net, messages with unknown
+// commands are returned as MsgUnknown
+func ReadMessage(r io.Reader, net BitcoinNet) (Message, error) {
+
+	header := make([]byte, MessageHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	if BitcoinNet(binary.LittleEndian.Uint32(header[0:4])) != net {
+		return nil, ErrWrongNetwork
+	}
+
+	command, err := parseCommand(header[4:16])
+	if err != nil {
+		return nil, err
+	}
+
+	length := binary.LittleEndian.Uint32(header[16:20])
+	if length > MaxMessagePayload {
+		return nil, ErrMessageTooLarge
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+
+	checksum := transaction.DoubleHashH(payload)
+	if !bytes.Equal(checksum[:4], header[20:24]) {
+		return nil, ErrInvalidChecksum
+	}
+
+	msg := newMessage(command)
+	if err := msg.Decode(bytes.NewReader(payload)); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+// parseCommand returns the command of a header, printable ASCII followed
+// by zeros
+func parseCommand(b []byte) (string, error) {
+
+	end := bytes.IndexByte(b, 0)
+	if end < 0 {
+		end = len(b)
+	}
+
+	for i, c := range b {
+		if (i < end && (c < 0x20 || c > 0x7e)) || (i >= end && c != 0) {
+			return "", ErrInvalidCommand
+		}
+	}
+
+	return string(b[:end]), nil
+}
+
+func writeUint16BE(w io.Writer, n uint16) error {
+
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], n)
+	_, err := w.Write(b[:])
+
+	return err
+}
+
+func writeUint32(w io.Writer, n uint32) error {
+
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], n)
+	_, err := w.Write(b[:])
+
+	return err
+}
+
+func writeUint64(w io.Writer, n uint64) error {
+
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], n)
+	_, err := w.Write(b[:])
+
+	return err
+}
+
+func readUint16BE(r io.Reader) (uint16, error) {
+
+	var b [2]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
+	}
+
+	return binary.BigEndian.Uint16(b[:]), nil
+}
+
+func readUint32(r io.Reader) (uint32, error) {
+
+	var b [4]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint32(b[:]), nil
+}
+
+func readUint64(r io.Reader) (uint64, error) {
+
+	var b [8]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint64(b[:]), nil
+}
+
+func readByte(r io.Reader) (byte, error) {
+
+	var b [1]byte
+	_, err := io.ReadFull(r, b[:])
+
+	return b[0], err
+}
+
+func writeHashes(w io.Writer, hashes []transaction.Hash) error {
+
+	if err := transaction.WriteVarInt(w, uint64(len(hashes))); err != nil {
+		return err
+	}
+
+	for i := range hashes {
+		if _, err := w.Write(hashes[i][:]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readHashes reads a list of at most max hashes
+func readHashes(r io.Reader, max uint64) ([]transaction.Hash, error) {
+
+	count, err := transaction.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	if count > max {
+		return nil, ErrMessageTooLarge
+	}
+
+	hashes := make([]transaction.Hash, count)
+	for i := range hashes {
+		if _, err := io.ReadFull(r, hashes[i][:]); err != nil {
+			return nil, err
+		}
+	}
+
+	return hashes, nil
+}
@@ -0,0 +1,141 @@
+package wire
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net"
+	"testing"
+
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+func testHash(s string) transaction.Hash {
+	return transaction.DoubleHashH([]byte(s))
+}
+
+// testTx returns a segwit transaction as decoded
+func testTx() *transaction.Tx {
+
+	tx := transaction.NewTx(2)
+	tx.AddTxIn(transaction.NewTxIn(transaction.NewOutPoint(&transaction.Hash{1}, 1), []byte{}, [][]byte{{1, 2}, {3}}))
+	tx.AddTxOut(transaction.NewTxOut(1000, []byte{0x51}))
+	tx, _ = transaction.NewTxFromBytes(tx.Bytes())
+
+	return tx
+}
+
+func testMessages() []Message {
+
+	genesis, _ := hex.DecodeString("0100000000000000000000000000000000000000000000000000000000000000000000003ba3edfd7a7b12b27ac72c3e67768f617fc81bc3888a51323a9fb8aa4b1e5e4a29ab5f49ffff001d1dac2b7c0101000000010000000000000000000000000000000000000000000000000000000000000000ffffffff4d04ffff001d0104455468652054696d65732030332f4a616e2f32303039204368616e63656c6c6f72206f6e206272696e6b206f66207365636f6e64206261696c6f757420666f722062616e6b73ffffffff0100f2052a01000000434104678afdb0fe5548271967f1a67130b7105cd6a828e03909a67962e0ea1f61deb649f6bc3f4cef38c4f35504e51ec112de5c384df7ba0b8d578a4c702b6bf11d5fac00000000")
+	block, _ := transaction.NewBlockFromBytes(genesis)
+
+	return []Message{
+		&MsgVersion{
+			ProtocolVersion: ProtocolVersion,
+			Services:        SFNodeNetwork | SFNodeWitness | SFNodeCompactFilters,
+			Timestamp:       1700000000,
+			AddrRecv:        NetAddress{Services: SFNodeNetwork, IP: net.ParseIP("127.0.0.1").To16(), Port: 8333},
+			AddrFrom:        NetAddress{IP: net.ParseIP("::1"), Port: 18444},
+			Nonce:           0x1122334455667788,
+			UserAgent:       "/gopher-wallet:0.1.0/",
+			StartHeight:     820000,
+			Relay:           false,
+		},
+		&MsgVerAck{},
+		&MsgPing{Nonce: 42},
+		&MsgPong{Nonce: 42},
+		&MsgSendCmpct{Announce: true, Version: 2},
+		&MsgGetHeaders{ProtocolVersion: ProtocolVersion, Locator: []transaction.Hash{testHash("a"), testHash("b")}, HashStop: testHash("c")},
+		&MsgHeaders{Headers: []*transaction.BlockHeader{&block.Header, &block.Header}},
+		&MsgInv{InvList: []*InvVect{{Type: InvTypeTx, Hash: testHash("a")}}},
+		&MsgGetData{InvList: []*InvVect{{Type: InvTypeWitnessBlock, Hash: testHash("a")}, {Type: InvTypeTx, Hash: testHash("b")}}},
+		&MsgBlock{Block: *block},
+		&MsgTx{Tx: *testTx()},
+		&MsgTx{Tx: *block.Transactions[0]},
+		&MsgGetCFilters{FilterType: FilterBasic, StartHeight: 1000, StopHash: testHash("a")},
+		&MsgCFilter{FilterType: FilterBasic, BlockHash: testHash("a"), Filter: []byte{1, 0x9d, 0xfc, 0xa8}},
+		&MsgGetCFHeaders{FilterType: FilterBasic, StartHeight: 1, StopHash: testHash("a")},
+		&MsgCFHeaders{FilterType: FilterBasic, StopHash: testHash("a"), PrevFilterHeader: testHash("b"), FilterHashes: []transaction.Hash{testHash("c")}},
+		&MsgGetCFCheckpt{FilterType: FilterBasic, StopHash: testHash("a")},
+		&MsgCFCheckpt{FilterType: FilterBasic, StopHash: testHash("a"), FilterHeaders: []transaction.Hash{testHash("b"), testHash("c")}},
+		&MsgUnknown{Cmd: "sendheaders", Payload: []byte{}},
+	}
+}
+
+func TestMessages(t *testing.T) {
+
+	for _, msg := range testMessages() {
+		var buf bytes.Buffer
+		assert.NoError(t, WriteMessage(&buf, msg, RegTest))
+
+		decoded, err := ReadMessage(&buf, RegTest)
+		assert.NoError(t, err, msg.Command())
+		assert.Equal(t, msg, decoded)
+		assert.Equal(t, 0, buf.Len())
+	}
+}
+
+func TestVerAck(t *testing.T) {
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteMessage(&buf, &MsgVerAck{}, MainNet))
+	assert.Equal(t, "f9beb4d976657261636b000000000000000000005df6e0e2", hex.EncodeToString(buf.Bytes()))
+}
+
+func TestSendCmpct(t *testing.T) {
+
+	var buf bytes.Buffer
+	assert.NoError(t, (&MsgSendCmpct{Version: 1}).Encode(&buf))
+	assert.Equal(t, "000100000000000000", hex.EncodeToString(buf.Bytes()))
+}
+
+func TestVersionWithoutRelay(t *testing.T) {
+
+	var buf bytes.Buffer
+	msg := testMessages()[0].(*MsgVersion)
+	assert.NoError(t, msg.Encode(&buf))
+
+	// nodes older than BIP37 omit the relay flag, which defaults to true
+	var decoded MsgVersion
+	assert.NoError(t, decoded.Decode(bytes.NewReader(buf.Bytes()[:buf.Len()-1])))
+	assert.True(t, decoded.Relay)
+	assert.True(t, decoded.HasService(SFNodeCompactFilters|SFNodeWitness))
+	assert.False(t, decoded.HasService(SFNodeNetworkLimited))
+}
+
+func TestReadMessageErrors(t *testing.T) {
+
+	encode := func(msg Message) []byte {
+		var buf bytes.Buffer
+		WriteMessage(&buf, msg, MainNet)
+		return buf.Bytes()
+	}
+
+	_, err := ReadMessage(bytes.NewReader(encode(&MsgPing{Nonce: 1})), TestNet3)
+	assert.Equal(t, ErrWrongNetwork, err)
+
+	corrupted := encode(&MsgPing{Nonce: 1})
+	corrupted[len(corrupted)-1] ^= 1
+	_, err = ReadMessage(bytes.NewReader(corrupted), MainNet)
+	assert.Equal(t, ErrInvalidChecksum, err)
+
+	large := encode(&MsgVerAck{})
+	large[19] = 0xff
+	_, err = ReadMessage(bytes.NewReader(large), MainNet)
+	assert.Equal(t, ErrMessageTooLarge, err)
+
+	badCommand := encode(&MsgVerAck{})
+	badCommand[14] = 'x'
+	_, err = ReadMessage(bytes.NewReader(badCommand), MainNet)
+	assert.Equal(t, ErrInvalidCommand, err)
+
+	// headers must not carry transactions
+	var payload bytes.Buffer
+	(&MsgHeaders{Headers: []*transaction.BlockHeader{{}}}).Encode(&payload)
+	b := payload.Bytes()
+	b[len(b)-1] = 1
+	assert.Equal(t, ErrHeaderTxCount, (&MsgHeaders{}).Decode(bytes.NewReader(b)))
+
+	assert.Equal(t, ErrMessageTooLarge, (&MsgGetHeaders{Locator: make([]transaction.Hash, MaxLocatorHashes+1)}).Encode(&payload))
+}
@@ -0,0 +1,282 @@
+package wire
+
+import (
+	"io"
+
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+// commands of the BIP157 messages
+const (
+	CmdGetCFilters  = "getcfilters"
+	CmdCFilter      = "cfilter"
+	CmdGetCFHeaders = "getcfheaders"
+	CmdCFHeaders    = "cfheaders"
+	CmdGetCFCheckpt = "getcfcheckpt"
+	CmdCFCheckpt    = "cfcheckpt"
+)
+
+// FilterType is the type of a compact block filter
+type FilterType uint8
+
+// FilterBasic is the BIP158 basic filter type
+const FilterBasic FilterType = 0
+
+const (
+	// MaxGetCFiltersReqRange is the largest number of filters requested
+	// at once
+	MaxGetCFiltersReqRange = 1000
+	// MaxCFHeadersPerMsg is the largest number of filter hashes in a
+	// cfheaders message
+	MaxCFHeadersPerMsg = 2000
+	// CFCheckptInterval is the number of blocks between the filter headers
+	// of a cfcheckpt message
+	CFCheckptInterval = 1000
+
+	maxCFCheckpts  = MaxMessagePayload / transaction.HashSize
+	maxFilterBytes = transaction.MaxBlockSize
+)
+
+// MsgGetCFilters asks for the filters of the blocks from StartHeight to
+// the block StopHash
+type MsgGetCFilters struct {
+	FilterType  FilterType
+	StartHeight uint32
+	StopHash    transaction.Hash
+}
+
+// Command returns the command of the message
+func (m *MsgGetCFilters) Command() string {
+	return CmdGetCFilters
+}
+
+// Encode writes the payload
+func (m *MsgGetCFilters) Encode(w io.Writer) error {
+	return writeFilterRange(w, m.FilterType, m.StartHeight, &m.StopHash)
+}
+
+// Decode reads the payload
+func (m *MsgGetCFilters) Decode(r io.Reader) error {
+	return readFilterRange(r, &m.FilterType, &m.StartHeight, &m.StopHash)
+}
+
+// MsgCFilter holds the serialized filter of a block
+type MsgCFilter struct {
+	FilterType FilterType
+	BlockHash  transaction.Hash
+	Filter     []byte
+}
+
+// Command returns the command of the message
+func (m *MsgCFilter) Command() string {
+	return CmdCFilter
+}
+
+// Encode writes the payload
+func (m *MsgCFilter) Encode(w io.Writer) error {
+
+	if _, err := w.Write(append([]byte{byte(m.FilterType)}, m.BlockHash[:]...)); err != nil {
+		return err
+	}
+
+	return transaction.WriteVarBytes(w, m.Filter)
+}
+
+// Decode reads the payload
+func (m *MsgCFilter) Decode(r io.Reader) error {
+
+	t, err := readByte(r)
+	if err != nil {
+		return err
+	}
+	m.FilterType = FilterType(t)
+
+	if _, err := io.ReadFull(r, m.BlockHash[:]); err != nil {
+		return err
+	}
+
+	m.Filter, err = transaction.ReadVarBytes(r, maxFilterBytes)
+
+	return err
+}
+
+// MsgGetCFHeaders asks for the filter hashes of the blocks from
+// StartHeight to the block StopHash
+type MsgGetCFHeaders struct {
+	FilterType  FilterType
+	StartHeight uint32
+	StopHash    transaction.Hash
+}
+
+// Command returns the command of the message
+func (m *MsgGetCFHeaders) Command() string {
+	return CmdGetCFHeaders
+}
+
+// Encode writes the payload
+func (m *MsgGetCFHeaders) Encode(w io.Writer) error {
+	return writeFilterRange(w, m.FilterType, m.StartHeight, &m.StopHash)
+}
+
+// Decode reads the payload
+func (m *MsgGetCFHeaders) Decode(r io.Reader) error {
+	return readFilterRange(r, &m.FilterType, &m.StartHeight, &m.StopHash)
+}
+
+// MsgCFHeaders holds the filter hashes of consecutive blocks and the
+// filter header preceding them, from which their headers are computed
+type MsgCFHeaders struct {
+	FilterType       FilterType
+	StopHash         transaction.Hash
+	PrevFilterHeader transaction.Hash
+	FilterHashes     []transaction.Hash
+}
+
+// Command returns the command of the message
+func (m *MsgCFHeaders) Command() string {
+	return CmdCFHeaders
+}
+
+// Encode writes the payload
+func (m *MsgCFHeaders) Encode(w io.Writer) error {
+
+	if len(m.FilterHashes) > MaxCFHeadersPerMsg {
+		return ErrMessageTooLarge
+	}
+
+	if _, err := w.Write(append([]byte{byte(m.FilterType)}, m.StopHash[:]...)); err != nil {
+		return err
+	}
+	if _, err := w.Write(m.PrevFilterHeader[:]); err != nil {
+		return err
+	}
+
+	return writeHashes(w, m.FilterHashes)
+}
+
+// Decode reads the payload
+func (m *MsgCFHeaders) Decode(r io.Reader) error {
+
+	t, err := readByte(r)
+	if err != nil {
+		return err
+	}
+	m.FilterType = FilterType(t)
+
+	if _, err := io.ReadFull(r, m.StopHash[:]); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(r, m.PrevFilterHeader[:]); err != nil {
+		return err
+	}
+
+	m.FilterHashes, err = readHashes(r, MaxCFHeadersPerMsg)
+
+	return err
+}
+
+// MsgGetCFCheckpt asks for the filter headers of every CFCheckptInterval
+// blocks up to the block StopHash
+type MsgGetCFCheckpt struct {
+	FilterType FilterType
+	StopHash   transaction.Hash
+}
+
+// Command returns the command of the message
+func (m *MsgGetCFCheckpt) Command() string {
+	return CmdGetCFCheckpt
+}
+
+// Encode writes the payload
+func (m *MsgGetCFCheckpt) Encode(w io.Writer) error {
+
+	_, err := w.Write(append([]byte{byte(m.FilterType)}, m.StopHash[:]...))
+
+	return err
+}
+
+// Decode reads the payload
+func (m *MsgGetCFCheckpt) Decode(r io.Reader) error {
+
+	t, err := readByte(r)
+	if err != nil {
+		return err
+	}
+	m.FilterType = FilterType(t)
+
+	_, err = io.ReadFull(r, m.StopHash[:])
+
+	return err
+}
+
+// MsgCFCheckpt holds the filter headers at heights CFCheckptInterval,
+// 2*CFCheckptInterval and so on up to the block StopHash
+type MsgCFCheckpt struct {
+	FilterType    FilterType
+	StopHash      transaction.Hash
+	FilterHeaders []transaction.Hash
+}
+
+// Command returns the command of the message
+func (m *MsgCFCheckpt) Command() string {
+	return CmdCFCheckpt
+}
+
+// Encode writes the payload
+func (m *MsgCFCheckpt) Encode(w io.Writer) error {
+
+	if _, err := w.Write(append([]byte{byte(m.FilterType)}, m.StopHash[:]...)); err != nil {
+		return err
+	}
+
+	return writeHashes(w, m.FilterHeaders)
+}
+
+// Decode reads the payload
+func (m *MsgCFCheckpt) Decode(r io.Reader) error {
+
+	t, err := readByte(r)
+	if err != nil {
+		return err
+	}
+	m.FilterType = FilterType(t)
+
+	if _, err := io.ReadFull(r, m.StopHash[:]); err != nil {
+		return err
+	}
+
+	m.FilterHeaders, err = readHashes(r, maxCFCheckpts)
+
+	return err
+}
+
+func writeFilterRange(w io.Writer, t FilterType, start uint32, stop *transaction.Hash) error {
+
+	if _, err := w.Write([]byte{byte(t)}); err != nil {
+		return err
+	}
+	if err := writeUint32(w, start); err != nil {
+		return err
+	}
+
+	_, err := w.Write(stop[:])
+
+	return err
+}
+
+func readFilterRange(r io.Reader, t *FilterType, start *uint32, stop *transaction.Hash) error {
+
+	b, err := readByte(r)
+	if err != nil {
+		return err
+	}
+	*t = FilterType(b)
+
+	if *start, err = readUint32(r); err != nil {
+		return err
+	}
+
+	_, err = io.ReadFull(r, stop[:])
+
+	return err
+}
@@ -0,0 +1,173 @@
+package wire
+
+import (
+	"io"
+
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+// commands of the data messages
+const (
+	CmdInv     = "inv"
+	CmdGetData = "getdata"
+	CmdBlock   = "block"
+	CmdTx      = "tx"
+)
+
+// MaxInvPerMsg is the largest number of inventory vectors in a message
+const MaxInvPerMsg = 50000
+
+// InvType is the kind of object an inventory vector references
+type InvType uint32
+
+// inventory types, the witness flag asks for the witness serialization
+const (
+	InvTypeTx            InvType = 1
+	InvTypeBlock         InvType = 2
+	InvTypeFilteredBlock InvType = 3
+	InvTypeWitnessFlag   InvType = 1 << 30
+	InvTypeWitnessTx             = InvTypeTx | InvTypeWitnessFlag
+	InvTypeWitnessBlock          = InvTypeBlock | InvTypeWitnessFlag
+)
+
+// InvVect references a transaction or a block by hash
+type InvVect struct {
+	Type InvType
+	Hash transaction.Hash
+}
+
+func writeInvList(w io.Writer, list []*InvVect) error {
+
+	if len(list) > MaxInvPerMsg {
+		return ErrMessageTooLarge
+	}
+
+	if err := transaction.WriteVarInt(w, uint64(len(list))); err != nil {
+		return err
+	}
+
+	for _, iv := range list {
+		if err := writeUint32(w, uint32(iv.Type)); err != nil {
+			return err
+		}
+		if _, err := w.Write(iv.Hash[:]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readInvList(r io.Reader) ([]*InvVect, error) {
+
+	count, err := transaction.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	if count > MaxInvPerMsg {
+		return nil, ErrMessageTooLarge
+	}
+
+	list := make([]*InvVect, count)
+	for i := range list {
+		t, err := readUint32(r)
+		if err != nil {
+			return nil, err
+		}
+		iv := &InvVect{Type: InvType(t)}
+		if _, err := io.ReadFull(r, iv.Hash[:]); err != nil {
+			return nil, err
+		}
+		list[i] = iv
+	}
+
+	return list, nil
+}
+
+// MsgInv announces transactions and blocks known to the peer
+type MsgInv struct {
+	InvList []*InvVect
+}
+
+// Command returns the command of the message
+func (m *MsgInv) Command() string {
+	return CmdInv
+}
+
+// Encode writes the payload
+func (m *MsgInv) Encode(w io.Writer) error {
+	return writeInvList(w, m.InvList)
+}
+
+// Decode reads the payload
+func (m *MsgInv) Decode(r io.Reader) error {
+
+	var err error
+	m.InvList, err = readInvList(r)
+
+	return err
+}
+
+// MsgGetData asks for the transactions and blocks of the inventory
+type MsgGetData struct {
+	InvList []*InvVect
+}
+
+// Command returns the command of the message
+func (m *MsgGetData) Command() string {
+	return CmdGetData
+}
+
+// Encode writes the payload
+func (m *MsgGetData) Encode(w io.Writer) error {
+	return writeInvList(w, m.InvList)
+}
+
+// Decode reads the payload
+func (m *MsgGetData) Decode(r io.Reader) error {
+
+	var err error
+	m.InvList, err = readInvList(r)
+
+	return err
+}
+
+// MsgBlock holds a block
+type MsgBlock struct {
+	Block transaction.Block
+}
+
+// Command returns the command of the message
+func (m *MsgBlock) Command() string {
+	return CmdBlock
+}
+
+// Encode writes the payload
+func (m *MsgBlock) Encode(w io.Writer) error {
+	return m.Block.Serialize(w)
+}
+
+// Decode reads the payload
+func (m *MsgBlock) Decode(r io.Reader) error {
+	return m.Block.Deserialize(r)
+}
+
+// MsgTx holds a transaction, with its witnesses if it has any
+type MsgTx struct {
+	Tx transaction.Tx
+}
+
+// Command returns the command of the message
+func (m *MsgTx) Command() string {
+	return CmdTx
+}
+
+// Encode writes the payload
+func (m *MsgTx) Encode(w io.Writer) error {
+	return m.Tx.Serialize(w)
+}
+
+// Decode reads the payload
+func (m *MsgTx) Decode(r io.Reader) error {
+	return m.Tx.Deserialize(r)
+}
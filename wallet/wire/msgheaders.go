@@ -0,0 +1,137 @@
+package wire
+
+import (
+	"errors"
+	"io"
+
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+// commands of the header messages
+const (
+	CmdGetHeaders = "getheaders"
+	CmdHeaders    = "headers"
+)
+
+const (
+	// MaxHeadersPerMsg is the largest number of headers in a message
+	MaxHeadersPerMsg = 2000
+	// MaxLocatorHashes is the largest number of hashes in a locator
+	MaxLocatorHashes = 101
+)
+
+// ErrHeaderTxCount is returned when a header of a headers message is not
+// followed by a zero transaction count
+var ErrHeaderTxCount = errors.New("wire: header with transactions")
+
+// MsgGetHeaders asks for the headers following the first locator hash
+// known to the peer, up to HashStop or MaxHeadersPerMsg headers
+type MsgGetHeaders struct {
+	ProtocolVersion uint32
+	Locator         []transaction.Hash
+	HashStop        transaction.Hash
+}
+
+// Command returns the command of the message
+func (m *MsgGetHeaders) Command() string {
+	return CmdGetHeaders
+}
+
+// Encode writes the payload
+func (m *MsgGetHeaders) Encode(w io.Writer) error {
+
+	if len(m.Locator) > MaxLocatorHashes {
+		return ErrMessageTooLarge
+	}
+
+	if err := writeUint32(w, m.ProtocolVersion); err != nil {
+		return err
+	}
+	if err := writeHashes(w, m.Locator); err != nil {
+		return err
+	}
+
+	_, err := w.Write(m.HashStop[:])
+
+	return err
+}
+
+// Decode reads the payload
+func (m *MsgGetHeaders) Decode(r io.Reader) error {
+
+	var err error
+	if m.ProtocolVersion, err = readUint32(r); err != nil {
+		return err
+	}
+	if m.Locator, err = readHashes(r, MaxLocatorHashes); err != nil {
+		return err
+	}
+
+	_, err = io.ReadFull(r, m.HashStop[:])
+
+	return err
+}
+
+// MsgHeaders holds consecutive block headers
+type MsgHeaders struct {
+	Headers []*transaction.BlockHeader
+}
+
+// Command returns the command of the message
+func (m *MsgHeaders) Command() string {
+	return CmdHeaders
+}
+
+// Encode writes the payload, each header is followed by a zero
+// transaction count
+func (m *MsgHeaders) Encode(w io.Writer) error {
+
+	if len(m.Headers) > MaxHeadersPerMsg {
+		return ErrMessageTooLarge
+	}
+
+	if err := transaction.WriteVarInt(w, uint64(len(m.Headers))); err != nil {
+		return err
+	}
+
+	for _, h := range m.Headers {
+		if err := h.Serialize(w); err != nil {
+			return err
+		}
+		if err := transaction.WriteVarInt(w, 0); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Decode reads the payload
+func (m *MsgHeaders) Decode(r io.Reader) error {
+
+	count, err := transaction.ReadVarInt(r)
+	if err != nil {
+		return err
+	}
+	if count > MaxHeadersPerMsg {
+		return ErrMessageTooLarge
+	}
+
+	m.Headers = make([]*transaction.BlockHeader, count)
+	for i := range m.Headers {
+		h := &transaction.BlockHeader{}
+		if err := h.Deserialize(r); err != nil {
+			return err
+		}
+		txs, err := transaction.ReadVarInt(r)
+		if err != nil {
+			return err
+		}
+		if txs != 0 {
+			return ErrHeaderTxCount
+		}
+		m.Headers[i] = h
+	}
+
+	return nil
+}
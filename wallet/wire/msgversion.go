@@ -0,0 +1,290 @@
+package wire
+
+import (
+	"io"
+	"net"
+
+	"github.com/giogam/Gopher-Wallet/wallet/transaction"
+)
+
+// service flags advertised in version messages
+const (
+	// SFNodeNetwork serves the full block chain
+	SFNodeNetwork uint64 = 1 << 0
+	// SFNodeWitness serves blocks and transactions with witness data
+	SFNodeWitness uint64 = 1 << 3
+	// SFNodeCompactFilters serves BIP157 compact block filters
+	SFNodeCompactFilters uint64 = 1 << 6
+	// SFNodeNetworkLimited serves the last 288 blocks
+	SFNodeNetworkLimited uint64 = 1 << 10
+)
+
+// commands of the handshake, negotiation and keep alive messages
+const (
+	CmdVersion   = "version"
+	CmdVerAck    = "verack"
+	CmdPing      = "ping"
+	CmdPong      = "pong"
+	CmdSendCmpct = "sendcmpct"
+)
+
+// MaxUserAgentLen is the longest user agent accepted
+const MaxUserAgentLen = 256
+
+// NetAddress is the address of a node as sent in version messages
+type NetAddress struct {
+	Services uint64
+	IP       net.IP
+	Port     uint16
+}
+
+func (a *NetAddress) encode(w io.Writer) error {
+
+	if err := writeUint64(w, a.Services); err != nil {
+		return err
+	}
+
+	ip := a.IP.To16()
+	if ip == nil {
+		ip = make(net.IP, net.IPv6len)
+	}
+	if _, err := w.Write(ip); err != nil {
+		return err
+	}
+
+	return writeUint16BE(w, a.Port)
+}
+
+func (a *NetAddress) decode(r io.Reader) error {
+
+	var err error
+	if a.Services, err = readUint64(r); err != nil {
+		return err
+	}
+
+	a.IP = make(net.IP, net.IPv6len)
+	if _, err := io.ReadFull(r, a.IP); err != nil {
+		return err
+	}
+
+	a.Port, err = readUint16BE(r)
+
+	return err
+}
+
+// MsgVersion opens a connection, it describes the node sending it
+type MsgVersion struct {
+	ProtocolVersion int32
+	Services        uint64
+	Timestamp       int64
+	AddrRecv        NetAddress
+	AddrFrom        NetAddress
+	Nonce           uint64
+	UserAgent       string
+	StartHeight     int32
+	Relay           bool
+}
+
+// Command returns the command of the message
+func (m *MsgVersion) Command() string {
+	return CmdVersion
+}
+
+// Encode writes the payload
+func (m *MsgVersion) Encode(w io.Writer) error {
+
+	if err := writeUint32(w, uint32(m.ProtocolVersion)); err != nil {
+		return err
+	}
+	if err := writeUint64(w, m.Services); err != nil {
+		return err
+	}
+	if err := writeUint64(w, uint64(m.Timestamp)); err != nil {
+		return err
+	}
+	if err := m.AddrRecv.encode(w); err != nil {
+		return err
+	}
+	if err := m.AddrFrom.encode(w); err != nil {
+		return err
+	}
+	if err := writeUint64(w, m.Nonce); err != nil {
+		return err
+	}
+	if err := transaction.WriteVarBytes(w, []byte(m.UserAgent)); err != nil {
+		return err
+	}
+	if err := writeUint32(w, uint32(m.StartHeight)); err != nil {
+		return err
+	}
+
+	relay := []byte{0}
+	if m.Relay {
+		relay[0] = 1
+	}
+	_, err := w.Write(relay)
+
+	return err
+}
+
+// Decode reads the payload, the relay flag is optional
+func (m *MsgVersion) Decode(r io.Reader) error {
+
+	version, err := readUint32(r)
+	if err != nil {
+		return err
+	}
+	m.ProtocolVersion = int32(version)
+
+	if m.Services, err = readUint64(r); err != nil {
+		return err
+	}
+	timestamp, err := readUint64(r)
+	if err != nil {
+		return err
+	}
+	m.Timestamp = int64(timestamp)
+
+	if err := m.AddrRecv.decode(r); err != nil {
+		return err
+	}
+	if err := m.AddrFrom.decode(r); err != nil {
+		return err
+	}
+	if m.Nonce, err = readUint64(r); err != nil {
+		return err
+	}
+
+	userAgent, err := transaction.ReadVarBytes(r, MaxUserAgentLen)
+	if err != nil {
+		return err
+	}
+	m.UserAgent = string(userAgent)
+
+	height, err := readUint32(r)
+	if err != nil {
+		return err
+	}
+	m.StartHeight = int32(height)
+
+	relay, err := readByte(r)
+	if err == io.EOF {
+		m.Relay = true
+		return nil
+	}
+	m.Relay = relay != 0
+
+	return err
+}
+
+// HasService reports whether the node advertises the service flag
+func (m *MsgVersion) HasService(flag uint64) bool {
+	return m.Services&flag == flag
+}
+
+// MsgVerAck acknowledges a version message
+type MsgVerAck struct{}
+
+// Command returns the command of the message
+func (m *MsgVerAck) Command() string {
+	return CmdVerAck
+}
+
+// Encode writes the empty payload
+func (m *MsgVerAck) Encode(w io.Writer) error {
+	return nil
+}
+
+// Decode reads the empty payload
+func (m *MsgVerAck) Decode(r io.Reader) error {
+	return nil
+}
+
+// MsgPing checks that a connection is alive, it is answered by a pong
+// with the same nonce
+type MsgPing struct {
+	Nonce uint64
+}
+
+// Command returns the command of the message
+func (m *MsgPing) Command() string {
+	return CmdPing
+}
+
+// Encode writes the payload
+func (m *MsgPing) Encode(w io.Writer) error {
+	return writeUint64(w, m.Nonce)
+}
+
+// Decode reads the payload
+func (m *MsgPing) Decode(r io.Reader) error {
+
+	var err error
+	m.Nonce, err = readUint64(r)
+
+	return err
+}
+
+// MsgPong answers a ping
+type MsgPong struct {
+	Nonce uint64
+}
+
+// Command returns the command of the message
+func (m *MsgPong) Command() string {
+	return CmdPong
+}
+
+// Encode writes the payload
+func (m *MsgPong) Encode(w io.Writer) error {
+	return writeUint64(w, m.Nonce)
+}
+
+// Decode reads the payload
+func (m *MsgPong) Decode(r io.Reader) error {
+
+	var err error
+	m.Nonce, err = readUint64(r)
+
+	return err
+}
+
+// MsgSendCmpct tells the peer which BIP152 compact block version is
+// supported and whether new blocks should be announced as compact blocks
+type MsgSendCmpct struct {
+	Announce bool
+	Version  uint64
+}
+
+// Command returns the command of the message
+func (m *MsgSendCmpct) Command() string {
+	return CmdSendCmpct
+}
+
+// Encode writes the payload
+func (m *MsgSendCmpct) Encode(w io.Writer) error {
+
+	announce := byte(0)
+	if m.Announce {
+		announce = 1
+	}
+	if _, err := w.Write([]byte{announce}); err != nil {
+		return err
+	}
+
+	return writeUint64(w, m.Version)
+}
+
+// Decode reads the payload
+func (m *MsgSendCmpct) Decode(r io.Reader) error {
+
+	announce, err := readByte(r)
+	if err != nil {
+		return err
+	}
+	m.Announce = announce != 0
+
+	m.Version, err = readUint64(r)
+
+	return err
+}
@@ -0,0 +1,380 @@
+package wire
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// MinProtocolVersion is the oldest protocol version of peers accepted,
+	// 70014 added compact blocks
+	MinProtocolVersion = 70014
+	// DefaultPeerTimeout bounds dialing, the handshake, writes and waiting
+	// for responses
+	DefaultPeerTimeout = 30 * time.Second
+	// DefaultIdleTimeout bounds waiting for unsolicited messages
+	DefaultIdleTimeout = 20 * time.Minute
+)
+
+var (
+	// ErrPeerClosed is returned when using a closed peer
+	ErrPeerClosed = errors.New("wire: peer closed")
+	// ErrPeerNotReady is returned when sending before the handshake
+	ErrPeerNotReady = errors.New("wire: peer handshake not done")
+	// ErrPeerTimeout is returned when the peer does not answer in time
+	ErrPeerTimeout = errors.New("wire: peer timed out")
+	// ErrHandshake is returned when the peer breaks the version handshake
+	ErrHandshake = errors.New("wire: unexpected message during handshake")
+	// ErrProtocolVersion is returned when the peer is older than
+	// MinProtocolVersion
+	ErrProtocolVersion = errors.New("wire: peer protocol version too old")
+	// ErrMissingServices is returned when the peer does not advertise the
+	// required services
+	ErrMissingServices = errors.New("wire: peer lacks required services")
+)
+
+// PeerState is the state of a connection to a peer
+type PeerState int32
+
+// states of a peer, in order
+const (
+	// PeerConnected is a connection whose handshake has not started
+	PeerConnected PeerState = iota
+	// PeerHandshake is a connection exchanging version messages
+	PeerHandshake
+	// PeerReady is a connection past the handshake
+	PeerReady
+	// PeerClosed is a closed connection
+	PeerClosed
+)
+
+// String returns the name of the state
+func (s PeerState) String() string {
+
+	switch s {
+	case PeerConnected:
+		return "connected"
+	case PeerHandshake:
+		return "handshake"
+	case PeerReady:
+		return "ready"
+	case PeerClosed:
+		return "closed"
+	}
+
+	return "unknown"
+}
+
+// PeerConfig holds the network and the options of a peer connection
+type PeerConfig struct {
+	Net BitcoinNet
+	// Services are advertised to the peer, which must advertise
+	// RequiredServices
+	Services         uint64
+	RequiredServices uint64
+	UserAgent        string
+	StartHeight      int32
+	Timeout          time.Duration
+	IdleTimeout      time.Duration
+}
+
+// Peer is a connection to a node of the network. Messages are read by one
+// goroutine at a time while they may be sent from any.
+type Peer struct {
+	conn net.Conn
+	cfg  PeerConfig
+
+	// writeMu serializes the messages written
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	state   PeerState
+	version *MsgVersion
+}
+
+// NewPeer returns a peer on conn, the handshake is not done
+func NewPeer(conn net.Conn, cfg PeerConfig) *Peer {
+
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultPeerTimeout
+	}
+	if cfg.IdleTimeout == 0 {
+		cfg.IdleTimeout = DefaultIdleTimeout
+	}
+
+	return &Peer{conn: conn, cfg: cfg}
+}
+
+// DialPeer connects to addr and does the handshake
+func DialPeer(addr string, cfg PeerConfig) (*Peer, error) {
+
+	p := NewPeer(nil, cfg)
+	conn, err := net.DialTimeout("tcp", addr, p.cfg.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	p.conn = conn
+
+	if err := p.Handshake(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Handshake exchanges version and verack messages with the peer, which
+// is closed if it fails
+func (p *Peer) Handshake() error {
+
+	p.mu.Lock()
+	state := p.state
+	if state == PeerConnected {
+		p.state = PeerHandshake
+	}
+	p.mu.Unlock()
+
+	switch state {
+	case PeerClosed:
+		return ErrPeerClosed
+	case PeerConnected:
+	default:
+		return ErrHandshake
+	}
+
+	if err := p.handshake(); err != nil {
+		p.Close()
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.state == PeerClosed {
+		return ErrPeerClosed
+	}
+	p.state = PeerReady
+
+	return nil
+}
+
+func (p *Peer) handshake() error {
+
+	var nonce [8]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return err
+	}
+
+	err := p.send(&MsgVersion{
+		ProtocolVersion: ProtocolVersion,
+		Services:        p.cfg.Services,
+		Timestamp:       time.Now().Unix(),
+		AddrRecv:        NetAddress{IP: net.IPv4zero},
+		AddrFrom:        NetAddress{Services: p.cfg.Services, IP: net.IPv4zero},
+		Nonce:           binary.LittleEndian.Uint64(nonce[:]),
+		UserAgent:       p.cfg.UserAgent,
+		StartHeight:     p.cfg.StartHeight,
+	})
+	if err != nil {
+		return err
+	}
+
+	// the version and the verack of the peer may come in any order, with
+	// feature negotiation messages in between
+	deadline := time.Now().Add(p.cfg.Timeout)
+	var version *MsgVersion
+	verack := false
+	for version == nil || !verack {
+		msg, err := p.read(deadline)
+		if err != nil {
+			return err
+		}
+
+		switch m := msg.(type) {
+		case *MsgVersion:
+			if version != nil {
+				return ErrHandshake
+			}
+			if m.ProtocolVersion < MinProtocolVersion {
+				return ErrProtocolVersion
+			}
+			if !m.HasService(p.cfg.RequiredServices) {
+				return ErrMissingServices
+			}
+			version = m
+			if err := p.send(&MsgVerAck{}); err != nil {
+				return err
+			}
+		case *MsgVerAck:
+			if version == nil || verack {
+				return ErrHandshake
+			}
+			verack = true
+		}
+	}
+
+	p.mu.Lock()
+	p.version = version
+	p.mu.Unlock()
+
+	return nil
+}
+
+// State returns the state of the connection
+func (p *Peer) State() PeerState {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.state
+}
+
+// Version returns the version message of the peer, nil before the
+// handshake
+func (p *Peer) Version() *MsgVersion {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.version
+}
+
+// Addr returns the address of the peer
+func (p *Peer) Addr() net.Addr {
+	return p.conn.RemoteAddr()
+}
+
+// Send writes msg to the peer
+func (p *Peer) Send(msg Message) error {
+
+	switch p.State() {
+	case PeerClosed:
+		return ErrPeerClosed
+	case PeerReady:
+		return p.send(msg)
+	}
+
+	return ErrPeerNotReady
+}
+
+// Read returns the next message of the peer, waiting up to the idle
+// timeout. Pings are answered and not returned.
+func (p *Peer) Read() (Message, error) {
+	return p.readReady(p.cfg.IdleTimeout, "")
+}
+
+// Receive returns the next message of the peer with the given command,
+// waiting up to the timeout in all and dropping the others
+func (p *Peer) Receive(command string) (Message, error) {
+	return p.readReady(p.cfg.Timeout, command)
+}
+
+// Request sends msg and returns the response with the given command
+func (p *Peer) Request(msg Message, command string) (Message, error) {
+
+	if err := p.Send(msg); err != nil {
+		return nil, err
+	}
+
+	return p.Receive(command)
+}
+
+// Close closes the connection
+func (p *Peer) Close() error {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.state == PeerClosed {
+		return nil
+	}
+	p.state = PeerClosed
+
+	return p.conn.Close()
+}
+
+// readReady reads the messages of a ready peer until one with command, or
+// any if command is empty, for up to timeout from the call so that a peer
+// sending other messages cannot hold it
+func (p *Peer) readReady(timeout time.Duration, command string) (Message, error) {
+
+	switch p.State() {
+	case PeerClosed:
+		return nil, ErrPeerClosed
+	case PeerReady:
+	default:
+		return nil, ErrPeerNotReady
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		msg, err := p.read(deadline)
+		if err != nil {
+			return nil, err
+		}
+
+		if ping, ok := msg.(*MsgPing); ok {
+			if err := p.send(&MsgPong{Nonce: ping.Nonce}); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if command == "" || msg.Command() == command {
+			return msg, nil
+		}
+	}
+}
+
+// send writes msg whatever the state, a failed write closes the peer
+func (p *Peer) send(msg Message) error {
+
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+
+	err := p.conn.SetWriteDeadline(time.Now().Add(p.cfg.Timeout))
+	if err == nil {
+		err = WriteMessage(p.conn, msg, p.cfg.Net)
+	}
+	if err != nil {
+		return p.fail(err)
+	}
+
+	return nil
+}
+
+// read returns the next message, a failed read closes the peer since the
+// stream may be out of step
+func (p *Peer) read(deadline time.Time) (Message, error) {
+
+	err := p.conn.SetReadDeadline(deadline)
+	if err != nil {
+		return nil, p.fail(err)
+	}
+
+	msg, err := ReadMessage(p.conn, p.cfg.Net)
+	if err != nil {
+		return nil, p.fail(err)
+	}
+
+	return msg, nil
+}
+
+// fail closes the peer after err and returns the error to report
+func (p *Peer) fail(err error) error {
+
+	if p.State() == PeerClosed {
+		return ErrPeerClosed
+	}
+	p.Close()
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrPeerTimeout
+	}
+
+	return err
+}
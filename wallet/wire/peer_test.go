@@ -0,0 +1,218 @@
+package wire
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// startLoopback listens on the loopback interface and runs serve on the
+// first connection, it returns the address to dial
+func startLoopback(t *testing.T, serve func(conn net.Conn)) string {
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		serve(conn)
+	}()
+
+	return ln.Addr().String()
+}
+
+// startTestPeer runs a peer with cfg on the loopback interface, serve is
+// called once its handshake is done
+func startTestPeer(t *testing.T, cfg PeerConfig, serve func(p *Peer)) string {
+
+	return startLoopback(t, func(conn net.Conn) {
+		p := NewPeer(conn, cfg)
+		if p.Handshake() == nil {
+			serve(p)
+		}
+	})
+}
+
+func TestPeer(t *testing.T) {
+
+	tx := &MsgTx{Tx: *testTx()}
+	pongs := make(chan uint64, 1)
+
+	addr := startTestPeer(t, PeerConfig{Net: RegTest, Services: SFNodeNetwork | SFNodeWitness, UserAgent: "/loopback/", StartHeight: 100}, func(p *Peer) {
+		p.Send(&MsgPing{Nonce: 9})
+		p.Send(&MsgSendCmpct{Version: 2})
+		for {
+			msg, err := p.Read()
+			if err != nil {
+				return
+			}
+			switch m := msg.(type) {
+			case *MsgPong:
+				pongs <- m.Nonce
+			case *MsgGetData:
+				p.Send(&MsgUnknown{Cmd: "sendheaders"})
+				p.Send(tx)
+				p.Send(&MsgInv{InvList: m.InvList})
+			}
+		}
+	})
+
+	p, err := DialPeer(addr, PeerConfig{Net: RegTest, RequiredServices: SFNodeWitness, Timeout: time.Second})
+	assert.NoError(t, err)
+	assert.Equal(t, PeerReady, p.State())
+	assert.Equal(t, "/loopback/", p.Version().UserAgent)
+	assert.Equal(t, int32(100), p.Version().StartHeight)
+	assert.Equal(t, addr, p.Addr().String())
+	assert.ErrorIs(t, p.Handshake(), ErrHandshake)
+
+	inv := []*InvVect{{Type: InvTypeWitnessTx, Hash: testHash("a")}}
+	msg, err := p.Request(&MsgGetData{InvList: inv}, CmdTx)
+	assert.NoError(t, err)
+	assert.Equal(t, tx, msg)
+	assert.Equal(t, uint64(9), <-pongs)
+
+	msg, err = p.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, &MsgInv{InvList: inv}, msg)
+
+	assert.NoError(t, p.Close())
+	assert.Equal(t, PeerClosed, p.State())
+	assert.ErrorIs(t, p.Send(&MsgPing{}), ErrPeerClosed)
+	_, err = p.Read()
+	assert.ErrorIs(t, err, ErrPeerClosed)
+	assert.NoError(t, p.Close())
+}
+
+func TestPeerHandshakeErrors(t *testing.T) {
+
+	local := PeerConfig{Net: RegTest, RequiredServices: SFNodeWitness, Timeout: 200 * time.Millisecond}
+	remote := PeerConfig{Net: RegTest, Services: SFNodeWitness}
+
+	peer := func(cfg PeerConfig) func(conn net.Conn) {
+		return func(conn net.Conn) {
+			NewPeer(conn, cfg).Handshake()
+		}
+	}
+	raw := func(msgs ...Message) func(conn net.Conn) {
+		return func(conn net.Conn) {
+			ReadMessage(conn, RegTest)
+			for _, msg := range msgs {
+				WriteMessage(conn, msg, RegTest)
+			}
+			io.Copy(io.Discard, conn)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		serve func(conn net.Conn)
+		err   error
+	}{
+		{"missing services", peer(PeerConfig{Net: RegTest, Services: SFNodeNetwork}), ErrMissingServices},
+		{"wrong network", peer(PeerConfig{Net: MainNet, Services: SFNodeWitness}), ErrWrongNetwork},
+		{"old version", raw(&MsgVersion{ProtocolVersion: 70001, Services: SFNodeWitness}), ErrProtocolVersion},
+		{"verack first", raw(&MsgVerAck{}), ErrHandshake},
+		{"two versions", raw(&MsgVersion{ProtocolVersion: ProtocolVersion, Services: SFNodeWitness}, &MsgVersion{ProtocolVersion: ProtocolVersion, Services: SFNodeWitness}), ErrHandshake},
+		{"no verack", raw(&MsgVersion{ProtocolVersion: ProtocolVersion, Services: SFNodeWitness}), ErrPeerTimeout},
+		{"silent", func(conn net.Conn) { time.Sleep(time.Second) }, ErrPeerTimeout},
+		{"ok", peer(remote), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			conn, err := net.Dial("tcp", startLoopback(t, tt.serve))
+			assert.NoError(t, err)
+
+			p := NewPeer(conn, local)
+			assert.Equal(t, PeerConnected, p.State())
+			assert.ErrorIs(t, p.Send(&MsgPing{}), ErrPeerNotReady)
+			_, err = p.Read()
+			assert.ErrorIs(t, err, ErrPeerNotReady)
+
+			err = p.Handshake()
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				assert.Equal(t, PeerClosed, p.State())
+				assert.ErrorIs(t, p.Handshake(), ErrPeerClosed)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, PeerReady, p.State())
+			p.Close()
+		})
+	}
+}
+
+func TestPeerIdleTimeout(t *testing.T) {
+
+	done := make(chan struct{})
+	addr := startTestPeer(t, PeerConfig{Net: RegTest}, func(p *Peer) { <-done })
+	defer close(done)
+
+	p, err := DialPeer(addr, PeerConfig{Net: RegTest, IdleTimeout: 100 * time.Millisecond})
+	assert.NoError(t, err)
+
+	start := time.Now()
+	_, err = p.Read()
+	assert.ErrorIs(t, err, ErrPeerTimeout)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, PeerClosed, p.State())
+
+	// a blocked read returns when the peer is closed
+	p, err = DialPeer(startTestPeer(t, PeerConfig{Net: RegTest}, func(p *Peer) { <-done }), PeerConfig{Net: RegTest})
+	assert.NoError(t, err)
+	errs := make(chan error)
+	go func() {
+		_, err := p.Receive(CmdHeaders)
+		errs <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	p.Close()
+	assert.ErrorIs(t, <-errs, ErrPeerClosed)
+}
+
+func TestPeerReceiveTimeout(t *testing.T) {
+
+	done := make(chan struct{})
+	defer close(done)
+
+	// pings and other messages do not extend the wait for the response
+	addr := startTestPeer(t, PeerConfig{Net: RegTest}, func(p *Peer) {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(20 * time.Millisecond):
+			}
+			if p.Send(&MsgPing{}) != nil || p.Send(&MsgSendCmpct{Version: 2}) != nil {
+				return
+			}
+		}
+	})
+
+	p, err := DialPeer(addr, PeerConfig{Net: RegTest, Timeout: 200 * time.Millisecond})
+	assert.NoError(t, err)
+
+	start := time.Now()
+	_, err = p.Receive(CmdHeaders)
+	assert.ErrorIs(t, err, ErrPeerTimeout)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestPeerState(t *testing.T) {
+
+	assert.Equal(t, "connected", PeerConnected.String())
+	assert.Equal(t, "handshake", PeerHandshake.String())
+	assert.Equal(t, "ready", PeerReady.String())
+	assert.Equal(t, "closed", PeerClosed.String())
+	assert.Equal(t, "unknown", PeerState(9).String())
+}